@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// AccelerationProcessor flags users whose spend in the latest window ramps up sharply
+// compared to the window before it
+type AccelerationProcessor struct {
+	Window     time.Duration
+	Multiplier decimal.Decimal
+	Floor      decimal.Decimal
+}
+
+func NewAccelerationProcessor(window time.Duration, multiplier, floor decimal.Decimal) AccelerationProcessor {
+	return AccelerationProcessor{
+		Window:     window,
+		Multiplier: multiplier,
+		Floor:      floor,
+	}
+}
+
+type accelerationBuckets struct {
+	earliest time.Time
+	latest   time.Time
+	current  decimal.Decimal
+	previous decimal.Decimal
+}
+
+// Process buckets each user's transactions into consecutive windows ending at their latest transaction.
+// A user is flagged when the latest bucket sum is above Floor and more than Multiplier times the previous
+// bucket sum. Users without any history before the latest bucket are never flagged.
+func (a AccelerationProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	users := make(map[uuid.UUID]*accelerationBuckets)
+	for _, tx := range transactions {
+		b, ok := users[tx.UserID]
+		if !ok {
+			users[tx.UserID] = &accelerationBuckets{earliest: tx.CreatedAt, latest: tx.CreatedAt}
+			continue
+		}
+		if tx.CreatedAt.Before(b.earliest) {
+			b.earliest = tx.CreatedAt
+		}
+		if tx.CreatedAt.After(b.latest) {
+			b.latest = tx.CreatedAt
+		}
+	}
+
+	// O(N), the latest transaction of each user is known so no sorting is needed
+	for _, tx := range transactions {
+		b := users[tx.UserID]
+		age := b.latest.Sub(tx.CreatedAt)
+
+		switch {
+		case age < a.Window:
+			b.current = b.current.Add(spendAmount(tx))
+		case age < 2*a.Window:
+			b.previous = b.previous.Add(spendAmount(tx))
+		}
+	}
+
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	for userID, b := range users {
+		if b.latest.Sub(b.earliest) < a.Window {
+			continue // only one bucket of history
+		}
+
+		if b.current.GreaterThan(a.Floor) && b.current.GreaterThan(b.previous.Mul(a.Multiplier)) {
+			flaggedUsers[userID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccelerationProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	tx := func(daysAgo int, amount int64) Transaction {
+		return Transaction{
+			UserID:    userID,
+			Amount:    decimal.NewFromInt(amount),
+			CreatedAt: baseTime.Add(-time.Duration(daysAgo) * 24 * time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name: "steady spender",
+			transactions: []Transaction{
+				tx(20, 1000), tx(17, 1000),
+				tx(12, 1000), tx(9, 1000),
+				tx(5, 1000), tx(0, 1000),
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "sharp ramp",
+			transactions: []Transaction{
+				tx(12, 500), tx(9, 500),
+				tx(3, 2000), tx(1, 2000), tx(0, 500),
+			},
+			wantFlagged: true,
+		},
+		{
+			name: "ramp of exactly the multiplier",
+			transactions: []Transaction{
+				tx(10, 1000),
+				tx(0, 3000),
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "ramp below the floor",
+			transactions: []Transaction{
+				tx(10, 1),
+				tx(0, 5),
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "quiet previous week above the floor",
+			transactions: []Transaction{
+				tx(20, 100),
+				tx(0, 2000),
+			},
+			wantFlagged: true,
+		},
+		{
+			name: "quiet previous week below the floor",
+			transactions: []Transaction{
+				tx(20, 100),
+				tx(0, 50),
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "only one bucket of history",
+			transactions: []Transaction{
+				tx(6, 10), tx(3, 5000), tx(0, 5000),
+			},
+			wantFlagged: false,
+		},
+	}
+
+	processor := NewAccelerationProcessor(week, decimal.NewFromInt(3), decimal.NewFromInt(100))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
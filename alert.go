@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Severity ranks how urgently an alert needs investigating
+type Severity string
+
+const (
+	SeverityLow      Severity = "low"
+	SeverityMedium   Severity = "medium"
+	SeverityHigh     Severity = "high"
+	SeverityCritical Severity = "critical"
+)
+
+// severityRank orders severities from the least to the most urgent
+var severityRank = []Severity{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// Escalate returns the next severity up, critical staying critical
+func (s Severity) Escalate() Severity {
+	i := slices.Index(severityRank, s)
+	if i < 0 || i == len(severityRank)-1 {
+		return s
+	}
+
+	return severityRank[i+1]
+}
+
+// defaultSeverity is critical for list-based rules, whose hits need no further judgement, and medium otherwise
+func defaultSeverity(processor RuleProcessor) Severity {
+	switch processor.(type) {
+	case CountryBlackListProcessor, *CounterpartyBlacklistProcessor, CounterpartyCountryProcessor, WatchlistProcessor:
+		return SeverityCritical
+	default:
+		return SeverityMedium
+	}
+}
+
+// Alert is an investigation-ready record of one rule flagging one user
+type Alert struct {
+	ID uuid.UUID `json:"id"`
+	// UserID is the flagged entity, an account ID for instance when the rule groups by ByAccount
+	UserID    uuid.UUID `json:"user_id"`
+	RuleName  string    `json:"rule_name"`
+	Severity  Severity  `json:"severity"`
+	CreatedAt time.Time `json:"created_at"`
+	// Evidence holds the user's transactions that caused the flag
+	Evidence []Transaction     `json:"evidence"`
+	Details  map[string]string `json:"details"`
+	// ConfigVersion identifies the rules config the alert was raised under, empty when the engine's
+	// rules were not loaded from one
+	ConfigVersion string `json:"config_version,omitempty"`
+}
+
+// AlertDetailer is implemented by processors that can tell which of a flagged user's transactions
+// caused the flag and describe it, e.g. the violated period or the matched countries
+type AlertDetailer interface {
+	// AlertDetails receives every transaction of the flagged user in the batch, which it must not modify
+	AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) (evidence []Transaction, details map[string]string)
+}
+
+// SeverityAware is implemented by processors that adjust the severity of an alert from what caused it,
+// e.g. escalating a velocity hit far above its threshold
+type SeverityAware interface {
+	// AlertSeverity receives the registered severity and the alert's evidence and details
+	AlertSeverity(base Severity, evidence []Transaction, details map[string]string) Severity
+}
+
+// GroupingKeyer is implemented by processors flagging entities other than users, e.g. a velocity
+// processor grouping by account. The engine gathers an alert's evidence by that key.
+type GroupingKeyer interface {
+	GroupingKey(Transaction) uuid.UUID
+}
+
+// userGroupingKeyer is implemented by GroupingKeyers that may group by user after all, whose evidence
+// the engine then takes from the run's grouping instead of grouping the batch again
+type userGroupingKeyer interface {
+	groupsByUser() bool
+}
+
+func groupsByUser(keyer GroupingKeyer) bool {
+	byUser, ok := keyer.(userGroupingKeyer)
+	return ok && byUser.groupsByUser()
+}
+
+// contextRuleProcessor is implemented by processors that report errors alongside their flagged set
+type contextRuleProcessor interface {
+	ProcessContext(context.Context, []Transaction) (map[uuid.UUID]struct{}, error)
+}
+
+// EvaluateAlerts runs every registered processor and returns one alert per flagged user and rule,
+// ordered by registration then user ID, publishing each to the registered sinks and OnFlagged
+// callback as it is produced. A processor, sink or callback failing is reported in the joined
+// error without stopping the others.
+func (r *RuleEngine) EvaluateAlerts(ctx context.Context, transactions []Transaction) ([]Alert, error) {
+	result, err := r.Evaluate(ctx, transactions)
+	return result.Alerts, err
+}
+
+// WithRuleWorkers runs up to n rules of a run at once. Alerts are still built, published and returned
+// in registration order once each rule completes, so the results do not depend on n.
+func WithRuleWorkers(n int) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.ruleWorkers = n
+	}
+}
+
+// Evaluate behaves like EvaluateAlerts, also returning the run metadata and a summary per registered rule.
+// With WithValidation, the batch is validated first and a batch failing under ValidationFail is not
+// processed at all.
+func (r *RuleEngine) Evaluate(ctx context.Context, transactions []Transaction) (EvaluationResult, error) {
+	ctx, end := startSpan(r.withTracer(ctx), "aml.evaluate")
+	result, err := r.evaluate(ctx, transactions)
+	end(map[string]any{
+		attrTransactions: len(transactions),
+		attrRules:        len(result.Rules),
+		attrAlerts:       len(result.Alerts),
+	}, err)
+
+	return result, err
+}
+
+func (r *RuleEngine) evaluate(ctx context.Context, transactions []Transaction) (EvaluationResult, error) {
+	ctx, stop := r.withProgress(ctx)
+	defer stop()
+
+	rules, configVersion := r.currentRules()
+	return r.evaluateRules(ctx, transactions, rules, configVersion, r.validation)
+}
+
+// evaluateRules runs rules, the snapshot of configVersion, validating transactions with validation first when set
+func (r *RuleEngine) evaluateRules(ctx context.Context, transactions []Transaction, rules []engineRule, configVersion string,
+	validation *ValidationPolicy,
+) (EvaluationResult, error) {
+	result := EvaluationResult{
+		RunID:            uuid.New(),
+		StartedAt:        time.Now().UTC(),
+		TransactionCount: len(transactions),
+	}
+	result.Rules = make([]RuleSummary, 0, len(rules))
+	result.ConfigVersion = configVersion
+
+	logger := r.runLogger(result.RunID)
+	if configVersion != "" {
+		logger = logger.With(slog.String("config_version", configVersion))
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "evaluation started",
+		slog.Int("transactions", len(transactions)), slog.Int("rules", len(rules)))
+
+	if validation != nil {
+		transactions, result.ValidationErrors = ValidateTransactions(transactions, *validation)
+		if len(result.ValidationErrors) > 0 {
+			logger.LogAttrs(ctx, slog.LevelWarn, "transactions failed validation",
+				slog.Int("failed_checks", len(result.ValidationErrors)),
+				slog.Int("dropped", result.TransactionCount-len(transactions)))
+		}
+		if validation.Action == ValidationFail && len(result.ValidationErrors) > 0 {
+			result.FinishedAt = time.Now().UTC()
+			err := fmt.Errorf("%w: %d failed checks", ErrInvalidBatch, len(result.ValidationErrors))
+			logger.LogAttrs(ctx, slog.LevelWarn, "batch rejected by validation", slog.Any("error", err))
+			return result, err
+		}
+	}
+
+	var duplicates int
+	if r.deduplicate {
+		transactions, duplicates = DeduplicateTransactions(transactions)
+		if duplicates > 0 {
+			logger.LogAttrs(ctx, slog.LevelInfo, "duplicate transactions dropped", slog.Int("dropped", duplicates))
+		}
+	}
+
+	// grouped once for every rule reading the batch by user and for the evidence of their alerts
+	grouped := GroupByUser(transactions)
+	progressTrackerFrom(ctx).expect(len(transactions), len(grouped.Users()), len(rules))
+	if r.metrics != nil {
+		r.metrics.ObserveBatchSize(len(transactions))
+	}
+
+	publisher := newSinkPublisher(ctx, r.sinks)
+	dispatcher := newCallbackDispatcher(ctx, r.callbacks)
+	stats := r.currentStats()
+	stats.evaluations.Add(1)
+
+	var outcomes []ruleOutcome
+	if r.ruleWorkers > 1 {
+		outcomes = runRulesConcurrently(ctx, rules, grouped, r.ruleWorkers)
+	}
+
+	var errs []error
+	var reportErr error
+	for i, rule := range rules {
+		summary := RuleSummary{Name: ruleName(rule.processor), Severity: rule.severity}
+
+		var outcome ruleOutcome
+		if outcomes != nil {
+			outcome = outcomes[i]
+		} else {
+			outcome = runTimedRule(ctx, rule.processor, grouped)
+		}
+		flaggedUsers, err := outcome.flaggedUsers, outcome.err
+		stats.rule(summary.Name).durations.record(outcome.duration)
+		logRule(ctx, logger, summary.Name, outcome)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", summary.Name, err))
+			summary.Error = err.Error()
+		}
+		if r.metrics != nil {
+			r.metrics.ObserveRuleDuration(summary.Name, outcome.duration)
+			r.metrics.IncFlagged(summary.Name, len(flaggedUsers))
+			if err != nil {
+				r.metrics.IncErrors(summary.Name)
+			}
+		}
+		summary.FlaggedUsers = len(flaggedUsers)
+		result.Rules = append(result.Rules, summary)
+
+		evidenceFor := grouped.ForUser
+		if keyer, ok := rule.processor.(GroupingKeyer); ok && !groupsByUser(keyer) {
+			byKey := groupByKey(transactions, keyer.GroupingKey)
+			evidenceFor = func(key uuid.UUID) []Transaction { return byKey[key] }
+		}
+
+		for _, userID := range FlaggedUsersOf(flaggedUsers) {
+			alert := Alert{
+				ID:            uuid.New(),
+				UserID:        userID,
+				RuleName:      summary.Name,
+				Severity:      rule.severity,
+				CreatedAt:     time.Now().UTC(),
+				Evidence:      evidenceFor(userID),
+				Details:       map[string]string{},
+				ConfigVersion: configVersion,
+			}
+			if detailer, ok := rule.processor.(AlertDetailer); ok {
+				alert.Evidence, alert.Details = detailer.AlertDetails(ctx, userID, slices.Clone(evidenceFor(userID)))
+			}
+			if aware, ok := rule.processor.(SeverityAware); ok {
+				alert.Severity = aware.AlertSeverity(rule.severity, alert.Evidence, alert.Details)
+			}
+
+			result.Alerts = append(result.Alerts, alert)
+			stats.recordAlert(alert)
+			publisher.publish(alert)
+			dispatcher.notify(alert)
+			if r.report != nil && reportErr == nil {
+				reportErr = r.report.WriteAlert(result.RunID, alert)
+			}
+		}
+	}
+
+	if err := publisher.close(); err != nil {
+		errs = append(errs, fmt.Errorf("alert sinks: %w", err))
+	}
+	if err := dispatcher.close(); err != nil {
+		errs = append(errs, err)
+	}
+
+	result.FinishedAt = time.Now().UTC()
+	result.Summary = Summarize(result.Alerts, transactions)
+	result.Summary.DuplicatesDropped = duplicates
+	result.Summary.Duration = result.FinishedAt.Sub(result.StartedAt)
+	if r.report != nil && reportErr == nil {
+		reportErr = r.report.WriteSummary(result)
+	}
+	if reportErr != nil {
+		errs = append(errs, fmt.Errorf("streaming report: %w", reportErr))
+	}
+
+	err := errors.Join(errs...)
+	attrs := []slog.Attr{
+		slog.Int("transactions", len(transactions)),
+		slog.Int("alerts", len(result.Alerts)),
+		slog.Duration("duration", result.Summary.Duration),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	logger.LogAttrs(ctx, slog.LevelInfo, "evaluation finished", attrs...)
+
+	return result, err
+}
+
+// groupByKey indexes transactions by key, keeping their order
+func groupByKey(transactions []Transaction, key func(Transaction) uuid.UUID) map[uuid.UUID][]Transaction {
+	grouped := make(map[uuid.UUID][]Transaction)
+	for _, tx := range transactions {
+		grouped[key(tx)] = append(grouped[key(tx)], tx)
+	}
+
+	return grouped
+}
+
+// ruleOutcome is what one rule returned during a run and how long it took
+type ruleOutcome struct {
+	flaggedUsers map[uuid.UUID]struct{}
+	err          error
+	duration     time.Duration
+}
+
+// runTimedRule runs a rule in its own span
+func runTimedRule(ctx context.Context, processor RuleProcessor, grouped GroupedTransactions) ruleOutcome {
+	ctx, end := startSpan(ctx, "aml.rule")
+	ctx, progress := startRule(ctx, grouped)
+	defer progress.complete()
+
+	start := time.Now()
+	flaggedUsers, err := runRule(ctx, processor, grouped)
+	end(map[string]any{
+		attrRule:         ruleName(processor),
+		attrTransactions: grouped.Len(),
+		attrFlaggedUsers: len(flaggedUsers),
+	}, err)
+
+	return ruleOutcome{flaggedUsers: flaggedUsers, err: err, duration: time.Since(start)}
+}
+
+// runRulesConcurrently runs rules from workers goroutines, returning their outcomes in rule order
+func runRulesConcurrently(ctx context.Context, rules []engineRule, grouped GroupedTransactions, workers int) []ruleOutcome {
+	outcomes := make([]ruleOutcome, len(rules))
+	next := make(chan int)
+
+	var wg sync.WaitGroup
+	for range min(workers, len(rules)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				outcomes[i] = runTimedRule(ctx, rules[i].processor, grouped)
+			}
+		}()
+	}
+	for i := range rules {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return outcomes
+}
+
+// runRule hands batch and grouped processors the shared columns or grouping, and the others the
+// shared batch itself. Every rule reads the same input: RuleProcessor never modifies the slice it
+// is given, processors ordering or filtering transactions doing so on copies.
+func runRule(ctx context.Context, processor RuleProcessor, grouped GroupedTransactions) (map[uuid.UUID]struct{}, error) {
+	switch p := processor.(type) {
+	case groupedContextRuleProcessor:
+		return p.ProcessGroupedContext(ctx, grouped)
+	case BatchRuleProcessor:
+		return p.ProcessBatch(ctx, grouped.Batch()), nil
+	case GroupedRuleProcessor:
+		return p.ProcessGrouped(ctx, grouped), nil
+	}
+
+	transactions := grouped.All()
+	if p, ok := processor.(contextRuleProcessor); ok {
+		return p.ProcessContext(ctx, transactions)
+	}
+
+	return processor.Process(ctx, transactions), nil
+}
+
+// detailTime formats timestamps in alert details
+func detailTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
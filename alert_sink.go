@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// alertSinkBuffer is how many alerts may wait for a sink before the engine blocks on it
+const alertSinkBuffer = 64
+
+// AlertSink receives alerts as the engine produces them. Publish is never called concurrently on
+// one sink, and Flush is called once every alert of a run has been published.
+type AlertSink interface {
+	Publish(ctx context.Context, alert Alert) error
+	Flush(ctx context.Context) error
+}
+
+// AddAlertSink registers a sink receiving every alert of the following runs
+func (r *RuleEngine) AddAlertSink(sink AlertSink) {
+	r.sinks = append(r.sinks, sink)
+}
+
+// sinkPublisher feeds each sink from its own bounded queue, so a slow sink makes the engine wait
+// rather than lose alerts, and a failing one does not stop the others
+type sinkPublisher struct {
+	ctx    context.Context
+	queues []chan Alert
+	errs   []error
+	wg     sync.WaitGroup
+}
+
+func newSinkPublisher(ctx context.Context, sinks []AlertSink) *sinkPublisher {
+	p := &sinkPublisher{
+		ctx:    ctx,
+		queues: make([]chan Alert, len(sinks)),
+		errs:   make([]error, len(sinks)),
+	}
+
+	for i, sink := range sinks {
+		p.queues[i] = make(chan Alert, alertSinkBuffer)
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			var errs []error
+			for alert := range p.queues[i] {
+				if err := sink.Publish(ctx, alert); err != nil {
+					errs = append(errs, fmt.Errorf("publish alert %s: %w", alert.ID, err))
+				}
+			}
+			if err := sink.Flush(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("flush: %w", err))
+			}
+			p.errs[i] = errors.Join(errs...)
+		}()
+	}
+
+	return p
+}
+
+// publish queues alert for every sink, blocking while a queue is full unless the context is done
+func (p *sinkPublisher) publish(alert Alert) {
+	for _, queue := range p.queues {
+		select {
+		case queue <- alert:
+		case <-p.ctx.Done():
+		}
+	}
+}
+
+// close waits for every sink to drain and flush, returning their errors
+func (p *sinkPublisher) close() error {
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.wg.Wait()
+
+	return errors.Join(p.errs...)
+}
+
+// MemorySink keeps every published alert in memory, for tests and small runs
+type MemorySink struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (m *MemorySink) Publish(_ context.Context, alert Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.alerts = append(m.alerts, alert)
+	return nil
+}
+
+func (m *MemorySink) Flush(context.Context) error {
+	return nil
+}
+
+// Alerts returns a copy of the alerts published so far
+func (m *MemorySink) Alerts() []Alert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return slices.Clone(m.alerts)
+}
+
+// NDJSONFileSink writes alerts one per line to a temporary file next to Path, which Flush renames
+// to Path. Readers of Path therefore see either the previous run's alerts or this run's, never
+// a partial file.
+type NDJSONFileSink struct {
+	Path string
+
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func NewNDJSONFileSink(path string) *NDJSONFileSink {
+	return &NDJSONFileSink{Path: path}
+}
+
+func (s *NDJSONFileSink) Publish(_ context.Context, alert Alert) error {
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	return json.NewEncoder(s.writer).Encode(alert)
+}
+
+// Flush syncs the alerts published since the last Flush and atomically replaces Path with them
+func (s *NDJSONFileSink) Flush(context.Context) error {
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	file, writer := s.file, s.writer
+	s.file, s.writer = nil, nil
+
+	err := s.commit(file, writer)
+	if err != nil {
+		_ = os.Remove(file.Name())
+	}
+
+	return err
+}
+
+// Close discards alerts published since the last Flush
+func (s *NDJSONFileSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+
+	file := s.file
+	s.file, s.writer = nil, nil
+
+	return errors.Join(file.Close(), os.Remove(file.Name()))
+}
+
+// open creates the temporary file of the current run if needed
+func (s *NDJSONFileSink) open() error {
+	if s.file != nil {
+		return nil
+	}
+
+	file, err := os.CreateTemp(filepath.Dir(s.Path), filepath.Base(s.Path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	s.file, s.writer = file, bufio.NewWriter(file)
+	return nil
+}
+
+// commit makes the temporary file durable then moves it to Path
+func (s *NDJSONFileSink) commit(file *os.File, writer *bufio.Writer) error {
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), s.Path)
+}
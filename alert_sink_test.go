@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errSinkUnavailable = errors.New("sink unavailable")
+
+// flakySink fails every third Publish
+type flakySink struct {
+	MemorySink
+	calls   int
+	flushed bool
+}
+
+func (f *flakySink) Publish(ctx context.Context, alert Alert) error {
+	f.calls++
+	if f.calls%3 == 0 {
+		return errSinkUnavailable
+	}
+
+	return f.MemorySink.Publish(ctx, alert)
+}
+
+func (f *flakySink) Flush(context.Context) error {
+	f.flushed = true
+	return nil
+}
+
+// sinkFixture returns n users each flagged by the blacklist, and the last one also by the amount rule
+func sinkFixture(n int) (*RuleEngine, []Transaction) {
+	var transactions []Transaction
+	for range n {
+		transactions = append(transactions, Transaction{UserID: uuid.New(), Country: "IR", Amount: decimal.NewFromInt(20000), CreatedAt: time.Now()})
+	}
+
+	engine := NewRuleEngine([]RuleProcessor{
+		NewCountryBlackListProcessor("IR"),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000)},
+	})
+
+	return engine, transactions
+}
+
+func TestRuleEngine_EvaluateAlerts_Sinks(t *testing.T) {
+	engine, transactions := sinkFixture(100)
+	memory := NewMemorySink()
+	flaky := &flakySink{}
+	engine.AddAlertSink(memory)
+	engine.AddAlertSink(flaky)
+
+	alerts, err := engine.EvaluateAlerts(context.Background(), transactions)
+
+	require.ErrorIs(t, err, errSinkUnavailable)
+	require.Len(t, alerts, 200, "a failing sink does not stop the rules")
+	assert.Equal(t, alerts, memory.Alerts(), "more alerts than the buffer holds are all delivered, in order")
+	assert.Len(t, flaky.Alerts(), 200-200/3)
+	assert.True(t, flaky.flushed)
+}
+
+func TestNDJSONFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.ndjson")
+	require.NoError(t, os.WriteFile(path, []byte("previous run\n"), 0o644))
+
+	sink := NewNDJSONFileSink(path)
+	engine, transactions := sinkFixture(3)
+	engine.AddAlertSink(sink)
+
+	alerts, err := engine.EvaluateAlerts(context.Background(), transactions)
+	require.NoError(t, err)
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var written []Alert
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var alert Alert
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &alert))
+		written = append(written, alert)
+	}
+	require.NoError(t, scanner.Err())
+
+	require.Len(t, written, len(alerts))
+	for i := range alerts {
+		assert.Equal(t, alerts[i].ID, written[i].ID)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary file is left behind")
+
+	_, err = engine.EvaluateAlerts(context.Background(), nil)
+	require.NoError(t, err)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Empty(t, content, "each run replaces the previous one")
+}
+
+func TestNDJSONFileSink_Close(t *testing.T) {
+	dir := t.TempDir()
+	sink := NewNDJSONFileSink(filepath.Join(dir, "alerts.ndjson"))
+
+	require.NoError(t, sink.Publish(context.Background(), Alert{ID: uuid.New()}))
+	require.NoError(t, sink.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "unflushed alerts are discarded")
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleEngine_EvaluateAlerts(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	fast, rich := uuid.New(), uuid.New()
+
+	transactions := []Transaction{
+		{UserID: fast, Country: "FR", Amount: decimal.NewFromInt(10), CreatedAt: baseTime.Add(-month)},
+		{UserID: fast, Country: "FR", Amount: decimal.NewFromInt(10), CreatedAt: baseTime},
+		{UserID: fast, Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: fast, Country: "FR", Amount: decimal.NewFromInt(10), CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: rich, Country: "FR", Amount: decimal.NewFromInt(20000), CreatedAt: baseTime},
+		{UserID: rich, Country: "FR", Amount: decimal.NewFromInt(50), CreatedAt: baseTime.Add(time.Hour)},
+	}
+
+	engine := NewRuleEngine([]RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewNamedVelocityPeriod("weekly", week, 2)}),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000)},
+	})
+	engine.AddRuleProcessor(NewCountryBlackListProcessor("IR"))
+
+	alerts, err := engine.EvaluateAlerts(context.Background(), transactions)
+	require.NoError(t, err)
+	require.Len(t, alerts, 3)
+
+	velocity, amount, blacklist := alerts[0], alerts[1], alerts[2]
+
+	assert.Equal(t, fast, velocity.UserID)
+	assert.Equal(t, "VelocityProcessor", velocity.RuleName)
+	assert.Equal(t, SeverityMedium, velocity.Severity)
+	assert.Len(t, velocity.Evidence, 3, "the month-old transaction is outside the violating window")
+	assert.Equal(t, map[string]string{
+		"periods":      "weekly",
+		"period":       "weekly: >2 tx / 168h",
+		"count":        "3",
+		"peak_count":   "3",
+		"threshold":    "2",
+		"window_start": "2024-03-01T09:00:00Z",
+		"window_end":   "2024-03-01T11:00:00Z",
+	}, velocity.Details)
+
+	assert.Equal(t, rich, amount.UserID)
+	assert.Equal(t, "TransactionAmountProcessor", amount.RuleName)
+	assert.Equal(t, transactions[4:5], amount.Evidence)
+	assert.Equal(t, "10000", amount.Details["threshold"])
+
+	assert.Equal(t, fast, blacklist.UserID)
+	assert.Equal(t, SeverityCritical, blacklist.Severity)
+	assert.Equal(t, transactions[2:3], blacklist.Evidence)
+	assert.Equal(t, "IR", blacklist.Details["countries"])
+
+	for _, alert := range alerts {
+		assert.NotEqual(t, uuid.Nil, alert.ID)
+		assert.False(t, alert.CreatedAt.IsZero())
+	}
+}
+
+func TestRuleEngine_Evaluate_RuleWorkers(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	var transactions []Transaction
+	for i := range 200 {
+		country := "FR"
+		if i%7 == 0 {
+			country = "IR"
+		}
+		transactions = append(transactions, Transaction{
+			UserID:    uuid.NewSHA1(uuid.Nil, []byte{byte(i % 20)}),
+			Country:   country,
+			Amount:    decimal.NewFromInt(int64(i * 100)),
+			CreatedAt: baseTime.Add(time.Duration(200-i) * time.Hour),
+		})
+	}
+	rules := []RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(24*time.Hour, 2)}),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(15000)},
+		NewCountryBlackListProcessor("IR"),
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 5)}),
+	}
+
+	sequential, err := NewRuleEngine(rules).Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+	concurrent, err := NewRuleEngine(rules, WithRuleWorkers(3)).Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+
+	require.Len(t, concurrent.Alerts, len(sequential.Alerts))
+	for i := range sequential.Alerts {
+		assert.Equal(t, sequential.Alerts[i].UserID, concurrent.Alerts[i].UserID)
+		assert.Equal(t, sequential.Alerts[i].RuleName, concurrent.Alerts[i].RuleName)
+		assert.Equal(t, sequential.Alerts[i].Evidence, concurrent.Alerts[i].Evidence)
+	}
+	assert.Equal(t, sequential.Rules, concurrent.Rules)
+}
+
+func TestRuleEngine_EvaluateAlerts_ProcessorError(t *testing.T) {
+	baseTime := time.Now()
+	userID := uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, Country: "IR", CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID, Country: "IR", CreatedAt: baseTime},
+	}
+
+	engine := NewRuleEngine([]RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 1)}, WithStrictSortedInput()),
+		NewCountryBlackListProcessor("IR"),
+	})
+
+	alerts, err := engine.EvaluateAlerts(context.Background(), transactions)
+
+	assert.ErrorIs(t, err, ErrUnsortedTransactions)
+	assert.True(t, strings.HasPrefix(err.Error(), "VelocityProcessor: "))
+	require.Len(t, alerts, 1, "the failing processor does not stop the others")
+	assert.Equal(t, "CountryBlackListProcessor", alerts[0].RuleName)
+}
+
+func TestRuleEngine_EvaluateAlerts_GroupingKeyEvidence(t *testing.T) {
+	baseTime := time.Now()
+	account := uuid.New()
+	transactions := []Transaction{
+		{UserID: uuid.New(), AccountID: account, CreatedAt: baseTime},
+		{UserID: uuid.New(), AccountID: account, CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: uuid.New(), AccountID: uuid.New(), CreatedAt: baseTime.Add(2 * time.Hour)},
+	}
+
+	engine := NewRuleEngine([]RuleProcessor{NewVelocityValidatorWithKey([]VelocityPeriod{NewVelocityPeriod(week, 1)}, ByAccount)})
+	alerts, err := engine.EvaluateAlerts(context.Background(), transactions)
+
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	assert.Equal(t, account, alerts[0].UserID)
+	assert.Equal(t, transactions[:2], alerts[0].Evidence, "evidence is gathered by account, across users")
+}
+
+func TestAlert_JSONRoundTrip(t *testing.T) {
+	alert := Alert{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		RuleName:  "TransactionAmountProcessor",
+		Severity:  SeverityHigh,
+		CreatedAt: time.Date(2024, 3, 1, 9, 30, 0, 123456789, time.UTC),
+		Evidence: []Transaction{{
+			UserID:    uuid.New(),
+			AccountID: uuid.New(),
+			Amount:    decimal.RequireFromString("10000.10"),
+			Currency:  "EUR",
+			Country:   "FR",
+			Status:    StatusCompleted,
+			Direction: DirectionDebit,
+			CreatedAt: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		}},
+		Details: map[string]string{"threshold": "10000"},
+	}
+
+	data, err := json.Marshal(alert)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"amount":"10000.1"`)
+	assert.Contains(t, string(data), `"severity":"high"`)
+
+	var decoded Alert
+	require.NoError(t, json.Unmarshal(data, &decoded))
+
+	assert.True(t, alert.Evidence[0].Amount.Equal(decoded.Evidence[0].Amount))
+	decoded.Evidence[0].Amount = alert.Evidence[0].Amount
+	assert.Equal(t, alert, decoded)
+}
+
+func TestRuleEngine_EvaluateAlerts_Severity(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	nearMiss, burst, blacklisted := uuid.New(), uuid.New(), uuid.New()
+
+	var transactions []Transaction
+	for i := range 3 {
+		transactions = append(transactions, Transaction{UserID: nearMiss, CreatedAt: baseTime.Add(time.Duration(i) * time.Hour)})
+	}
+	for i := range 5 {
+		transactions = append(transactions, Transaction{UserID: burst, CreatedAt: baseTime.Add(time.Duration(i) * time.Hour)})
+	}
+	transactions = append(transactions, Transaction{UserID: blacklisted, Country: "IR", CreatedAt: baseTime})
+
+	engine := NewRuleEngine(nil)
+	engine.AddRuleProcessorWithSeverity(NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(24*time.Hour, 2)}), SeverityLow)
+	engine.AddRuleProcessor(NewCountryBlackListProcessor("IR"))
+	engine.AddRuleProcessorWithSeverity(NewCountryBlackListProcessor("IR"), SeverityHigh)
+
+	result, err := engine.Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+
+	severities := make(map[uuid.UUID][]Severity)
+	for _, alert := range result.Alerts {
+		severities[alert.UserID] = append(severities[alert.UserID], alert.Severity)
+	}
+	assert.Equal(t, []Severity{SeverityLow}, severities[nearMiss], "3 transactions against a threshold of 2 stay at the registered severity")
+	assert.Equal(t, []Severity{SeverityMedium}, severities[burst], "5 transactions against a threshold of 2 escalate once")
+	assert.Equal(t, []Severity{SeverityCritical, SeverityHigh}, severities[blacklisted], "the default severity of a blacklist is critical")
+
+	assert.Equal(t, map[Severity]int{SeverityLow: 1, SeverityMedium: 1, SeverityHigh: 1, SeverityCritical: 1}, result.Summary.SeverityFlags)
+}
+
+func TestSeverity_Escalate(t *testing.T) {
+	assert.Equal(t, SeverityMedium, SeverityLow.Escalate())
+	assert.Equal(t, SeverityHigh, SeverityMedium.Escalate())
+	assert.Equal(t, SeverityCritical, SeverityHigh.Escalate())
+	assert.Equal(t, SeverityCritical, SeverityCritical.Escalate())
+	assert.Equal(t, Severity("custom"), Severity("custom").Escalate())
+}
@@ -2,31 +2,184 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+// RuleProcessor flags the users of a batch breaking a rule. Process never modifies the transactions
+// it is given, neither their order nor their content: processors ordering or filtering them work on
+// a copy or on positions into the batch, so one batch can be handed to several processors at once.
+// The flagged set has no order; FlaggedUsersOf or ProcessFlaggedUsers give it in canonical order.
 type RuleProcessor interface {
 	Process(context.Context, []Transaction) map[uuid.UUID]struct{}
 }
 
+// NamedRuleProcessor is a RuleProcessor reporting its own name, e.g. a wrapper naming what it wraps
+type NamedRuleProcessor interface {
+	RuleProcessor
+	Name() string
+}
+
+// ruleName returns the name a processor is reported under: its own for a NamedRuleProcessor,
+// its type name otherwise, e.g. "VelocityProcessor"
+func ruleName(processor RuleProcessor) string {
+	if named, ok := processor.(NamedRuleProcessor); ok {
+		return named.Name()
+	}
+
+	name := fmt.Sprintf("%T", processor)
+	name = strings.TrimPrefix(name, "*")
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+
+	return name
+}
+
 type Transaction struct {
-	UserID    uuid.UUID
-	Amount    decimal.Decimal
-	Country   string
-	CreatedAt time.Time
+	// TransactionID identifies the transaction across rules and alerts, zero when the source has no identifier
+	TransactionID uuid.UUID `json:"transaction_id,omitzero"`
+	UserID        uuid.UUID `json:"user_id"`
+	AccountID     uuid.UUID `json:"account_id"`
+	// Amount is negative for a reversal or credit-back of earlier spend. Rules summing amounts count
+	// negative ones as zero rather than netting them against spend; threshold rules never flag them.
+	Amount decimal.Decimal `json:"amount"`
+	// Currency is an ISO 4217 code such as "EUR", empty when amounts share a single currency
+	Currency string `json:"currency,omitempty"`
+	Country  string `json:"country,omitempty"`
+	// DestinationCountry is where funds are sent, Country being the origin. Empty when unknown.
+	DestinationCountry string            `json:"destination_country,omitempty"`
+	Status             TransactionStatus `json:"status,omitempty"`
+	Direction          Direction         `json:"direction,omitempty"`
+	Channel            Channel           `json:"channel,omitempty"`
+	// Category is the merchant category, e.g. an MCC such as "7995" or a label such as "gambling"
+	Category string `json:"category,omitempty"`
+	// Description is the free-text payment reference, e.g. "invoice 2024-113"
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	UserName         string `json:"user_name,omitempty"`
+	CounterpartyName string `json:"counterparty_name,omitempty"`
+	// CounterpartyID identifies the other side of the transaction, empty when unknown
+	CounterpartyID string `json:"counterparty_id,omitempty"`
+	// CounterpartyCountry is where the counterparty is located, empty when unknown
+	CounterpartyCountry string `json:"counterparty_country,omitempty"`
 }
 
+// spendAmount is the amount a transaction adds to sums, zero for reversals recorded as negative amounts
+func spendAmount(tx Transaction) decimal.Decimal {
+	if tx.Amount.IsNegative() {
+		return decimal.Zero
+	}
+
+	return tx.Amount
+}
+
+// Direction tells whether funds enter (credit) or leave (debit) the user's account. The zero value means unknown.
+type Direction string
+
+const (
+	DirectionCredit Direction = "credit"
+	DirectionDebit  Direction = "debit"
+)
+
+// Channel is the payment rail a transaction went through. The zero value means unknown.
+type Channel string
+
+const (
+	ChannelCard   Channel = "card"
+	ChannelWire   Channel = "wire"
+	ChannelCrypto Channel = "crypto"
+	ChannelCash   Channel = "cash"
+)
+
+// TransactionStatus describes the lifecycle state of a transaction. The zero value means unknown.
+type TransactionStatus string
+
+const (
+	StatusCompleted TransactionStatus = "completed"
+	StatusRefunded  TransactionStatus = "refunded"
+	StatusReversed  TransactionStatus = "reversed"
+)
+
 type RuleEngine struct {
-	processors []RuleProcessor
+	// mu guards rules and configVersion, which ReloadConfig replaces while runs may be in flight
+	mu            sync.RWMutex
+	rules         []engineRule
+	configVersion string
+
+	sinks      []AlertSink
+	callbacks  callbackOptions
+	validation *ValidationPolicy
+	http       httpOptions
+	stats      atomic.Pointer[engineStats]
+	// ruleWorkers is how many rules Evaluate runs at once, one at a time when below two
+	ruleWorkers int
+	// shardWorkers is how many shards ShardedEvaluate evaluates at once, one at a time when below two
+	shardWorkers int
+	// deduplicate drops transactions redelivered within a batch, set with WithDeduplication
+	deduplicate bool
+	// spill is nil unless set with WithSpill
+	spill *spillOptions
+	// progress has no callback unless set with WithProgress
+	progress progressOptions
+	// metrics is nil unless set with WithMetrics
+	metrics MetricsSink
+	// tracer is nil unless set with WithTracer
+	tracer Tracer
+	// logger is nil unless set with WithLogger
+	logger *slog.Logger
+	// report is nil unless set with WithStreamingReport
+	report *StreamingReportWriter
+}
+
+// engineRule is a registered processor with the severity its alerts carry
+type engineRule struct {
+	processor RuleProcessor
+	severity  Severity
 }
 
-func NewRuleEngine(validators []RuleProcessor) *RuleEngine {
-	return &RuleEngine{processors: make([]RuleProcessor, 0)}
+func NewRuleEngine(validators []RuleProcessor, opts ...RuleEngineOption) *RuleEngine {
+	engine := &RuleEngine{rules: make([]engineRule, 0, len(validators))}
+	for _, validator := range validators {
+		engine.AddRuleProcessor(validator)
+	}
+	for _, opt := range opts {
+		opt(engine)
+	}
+
+	return engine
 }
 
+// AddRuleProcessor registers a processor with the default severity of its rule type
 func (r *RuleEngine) AddRuleProcessor(processor RuleProcessor) {
-	r.processors = append(r.processors, processor)
+	r.AddRuleProcessorWithSeverity(processor, defaultSeverity(processor))
+}
+
+// AddRuleProcessorWithSeverity registers a processor whose alerts carry severity, unless it escalates them
+// through SeverityAware. The engine no longer reports a config version, its rules differing from the
+// loaded config.
+func (r *RuleEngine) AddRuleProcessorWithSeverity(processor RuleProcessor, severity Severity) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.rules = append(slices.Clip(r.rules), engineRule{processor: processor, severity: severity})
+	r.configVersion = ""
+}
+
+// currentRules returns the rules a run starts with and the version of the config they come from.
+// The slice is never modified afterwards, so the run keeps it whatever is registered or reloaded.
+func (r *RuleEngine) currentRules() ([]engineRule, string) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.rules, r.configVersion
 }
@@ -0,0 +1,569 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: aml.proto
+
+package amlpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Transaction struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	TransactionId       string                 `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	UserId              string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	AccountId           string                 `protobuf:"bytes,3,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Amount              string                 `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency            string                 `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	Country             string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	DestinationCountry  string                 `protobuf:"bytes,7,opt,name=destination_country,json=destinationCountry,proto3" json:"destination_country,omitempty"`
+	Status              string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	Direction           string                 `protobuf:"bytes,9,opt,name=direction,proto3" json:"direction,omitempty"`
+	Channel             string                 `protobuf:"bytes,10,opt,name=channel,proto3" json:"channel,omitempty"`
+	Category            string                 `protobuf:"bytes,11,opt,name=category,proto3" json:"category,omitempty"`
+	Description         string                 `protobuf:"bytes,12,opt,name=description,proto3" json:"description,omitempty"`
+	CreatedAt           *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UserName            string                 `protobuf:"bytes,14,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
+	CounterpartyName    string                 `protobuf:"bytes,15,opt,name=counterparty_name,json=counterpartyName,proto3" json:"counterparty_name,omitempty"`
+	CounterpartyId      string                 `protobuf:"bytes,16,opt,name=counterparty_id,json=counterpartyId,proto3" json:"counterparty_id,omitempty"`
+	CounterpartyCountry string                 `protobuf:"bytes,17,opt,name=counterparty_country,json=counterpartyCountry,proto3" json:"counterparty_country,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *Transaction) Reset() {
+	*x = Transaction{}
+	mi := &file_aml_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Transaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Transaction) ProtoMessage() {}
+
+func (x *Transaction) ProtoReflect() protoreflect.Message {
+	mi := &file_aml_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Transaction.ProtoReflect.Descriptor instead.
+func (*Transaction) Descriptor() ([]byte, []int) {
+	return file_aml_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Transaction) GetTransactionId() string {
+	if x != nil {
+		return x.TransactionId
+	}
+	return ""
+}
+
+func (x *Transaction) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Transaction) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *Transaction) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *Transaction) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Transaction) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *Transaction) GetDestinationCountry() string {
+	if x != nil {
+		return x.DestinationCountry
+	}
+	return ""
+}
+
+func (x *Transaction) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Transaction) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *Transaction) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *Transaction) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *Transaction) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Transaction) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Transaction) GetUserName() string {
+	if x != nil {
+		return x.UserName
+	}
+	return ""
+}
+
+func (x *Transaction) GetCounterpartyName() string {
+	if x != nil {
+		return x.CounterpartyName
+	}
+	return ""
+}
+
+func (x *Transaction) GetCounterpartyId() string {
+	if x != nil {
+		return x.CounterpartyId
+	}
+	return ""
+}
+
+func (x *Transaction) GetCounterpartyCountry() string {
+	if x != nil {
+		return x.CounterpartyCountry
+	}
+	return ""
+}
+
+type Alert struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId        string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	RuleName      string                 `protobuf:"bytes,3,opt,name=rule_name,json=ruleName,proto3" json:"rule_name,omitempty"`
+	Severity      string                 `protobuf:"bytes,4,opt,name=severity,proto3" json:"severity,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Evidence      []*Transaction         `protobuf:"bytes,6,rep,name=evidence,proto3" json:"evidence,omitempty"`
+	Details       map[string]string      `protobuf:"bytes,7,rep,name=details,proto3" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Alert) Reset() {
+	*x = Alert{}
+	mi := &file_aml_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Alert) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Alert) ProtoMessage() {}
+
+func (x *Alert) ProtoReflect() protoreflect.Message {
+	mi := &file_aml_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Alert.ProtoReflect.Descriptor instead.
+func (*Alert) Descriptor() ([]byte, []int) {
+	return file_aml_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Alert) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Alert) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Alert) GetRuleName() string {
+	if x != nil {
+		return x.RuleName
+	}
+	return ""
+}
+
+func (x *Alert) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Alert) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Alert) GetEvidence() []*Transaction {
+	if x != nil {
+		return x.Evidence
+	}
+	return nil
+}
+
+func (x *Alert) GetDetails() map[string]string {
+	if x != nil {
+		return x.Details
+	}
+	return nil
+}
+
+type RuleSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Severity      string                 `protobuf:"bytes,2,opt,name=severity,proto3" json:"severity,omitempty"`
+	FlaggedUsers  int32                  `protobuf:"varint,3,opt,name=flagged_users,json=flaggedUsers,proto3" json:"flagged_users,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RuleSummary) Reset() {
+	*x = RuleSummary{}
+	mi := &file_aml_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RuleSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RuleSummary) ProtoMessage() {}
+
+func (x *RuleSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_aml_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RuleSummary.ProtoReflect.Descriptor instead.
+func (*RuleSummary) Descriptor() ([]byte, []int) {
+	return file_aml_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *RuleSummary) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *RuleSummary) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *RuleSummary) GetFlaggedUsers() int32 {
+	if x != nil {
+		return x.FlaggedUsers
+	}
+	return 0
+}
+
+type EvaluateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*Transaction         `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateRequest) Reset() {
+	*x = EvaluateRequest{}
+	mi := &file_aml_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateRequest) ProtoMessage() {}
+
+func (x *EvaluateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_aml_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateRequest.ProtoReflect.Descriptor instead.
+func (*EvaluateRequest) Descriptor() ([]byte, []int) {
+	return file_aml_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *EvaluateRequest) GetTransactions() []*Transaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+type EvaluateResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RunId         string                 `protobuf:"bytes,1,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Alerts        []*Alert               `protobuf:"bytes,2,rep,name=alerts,proto3" json:"alerts,omitempty"`
+	Rules         []*RuleSummary         `protobuf:"bytes,3,rep,name=rules,proto3" json:"rules,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EvaluateResponse) Reset() {
+	*x = EvaluateResponse{}
+	mi := &file_aml_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EvaluateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EvaluateResponse) ProtoMessage() {}
+
+func (x *EvaluateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_aml_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EvaluateResponse.ProtoReflect.Descriptor instead.
+func (*EvaluateResponse) Descriptor() ([]byte, []int) {
+	return file_aml_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EvaluateResponse) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *EvaluateResponse) GetAlerts() []*Alert {
+	if x != nil {
+		return x.Alerts
+	}
+	return nil
+}
+
+func (x *EvaluateResponse) GetRules() []*RuleSummary {
+	if x != nil {
+		return x.Rules
+	}
+	return nil
+}
+
+var File_aml_proto protoreflect.FileDescriptor
+
+const file_aml_proto_rawDesc = "" +
+	"\n" +
+	"\taml.proto\x12\x06aml.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xda\x04\n" +
+	"\vTransaction\x12%\n" +
+	"\x0etransaction_id\x18\x01 \x01(\tR\rtransactionId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x03 \x01(\tR\taccountId\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\tR\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x12/\n" +
+	"\x13destination_country\x18\a \x01(\tR\x12destinationCountry\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12\x1c\n" +
+	"\tdirection\x18\t \x01(\tR\tdirection\x12\x18\n" +
+	"\achannel\x18\n" +
+	" \x01(\tR\achannel\x12\x1a\n" +
+	"\bcategory\x18\v \x01(\tR\bcategory\x12 \n" +
+	"\vdescription\x18\f \x01(\tR\vdescription\x129\n" +
+	"\n" +
+	"created_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x1b\n" +
+	"\tuser_name\x18\x0e \x01(\tR\buserName\x12+\n" +
+	"\x11counterparty_name\x18\x0f \x01(\tR\x10counterpartyName\x12'\n" +
+	"\x0fcounterparty_id\x18\x10 \x01(\tR\x0ecounterpartyId\x121\n" +
+	"\x14counterparty_country\x18\x11 \x01(\tR\x13counterpartyCountry\"\xc7\x02\n" +
+	"\x05Alert\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x1b\n" +
+	"\trule_name\x18\x03 \x01(\tR\bruleName\x12\x1a\n" +
+	"\bseverity\x18\x04 \x01(\tR\bseverity\x129\n" +
+	"\n" +
+	"created_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12/\n" +
+	"\bevidence\x18\x06 \x03(\v2\x13.aml.v1.TransactionR\bevidence\x124\n" +
+	"\adetails\x18\a \x03(\v2\x1a.aml.v1.Alert.DetailsEntryR\adetails\x1a:\n" +
+	"\fDetailsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"b\n" +
+	"\vRuleSummary\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1a\n" +
+	"\bseverity\x18\x02 \x01(\tR\bseverity\x12#\n" +
+	"\rflagged_users\x18\x03 \x01(\x05R\fflaggedUsers\"J\n" +
+	"\x0fEvaluateRequest\x127\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x13.aml.v1.TransactionR\ftransactions\"{\n" +
+	"\x10EvaluateResponse\x12\x15\n" +
+	"\x06run_id\x18\x01 \x01(\tR\x05runId\x12%\n" +
+	"\x06alerts\x18\x02 \x03(\v2\r.aml.v1.AlertR\x06alerts\x12)\n" +
+	"\x05rules\x18\x03 \x03(\v2\x13.aml.v1.RuleSummaryR\x05rules2\x85\x01\n" +
+	"\n" +
+	"RuleEngine\x12=\n" +
+	"\bEvaluate\x12\x17.aml.v1.EvaluateRequest\x1a\x18.aml.v1.EvaluateResponse\x128\n" +
+	"\x0eEvaluateStream\x12\x13.aml.v1.Transaction\x1a\r.aml.v1.Alert(\x010\x01B\x17Z\x15aml_rule_engine/amlpbb\x06proto3"
+
+var (
+	file_aml_proto_rawDescOnce sync.Once
+	file_aml_proto_rawDescData []byte
+)
+
+func file_aml_proto_rawDescGZIP() []byte {
+	file_aml_proto_rawDescOnce.Do(func() {
+		file_aml_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_aml_proto_rawDesc), len(file_aml_proto_rawDesc)))
+	})
+	return file_aml_proto_rawDescData
+}
+
+var file_aml_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_aml_proto_goTypes = []any{
+	(*Transaction)(nil),           // 0: aml.v1.Transaction
+	(*Alert)(nil),                 // 1: aml.v1.Alert
+	(*RuleSummary)(nil),           // 2: aml.v1.RuleSummary
+	(*EvaluateRequest)(nil),       // 3: aml.v1.EvaluateRequest
+	(*EvaluateResponse)(nil),      // 4: aml.v1.EvaluateResponse
+	nil,                           // 5: aml.v1.Alert.DetailsEntry
+	(*timestamppb.Timestamp)(nil), // 6: google.protobuf.Timestamp
+}
+var file_aml_proto_depIdxs = []int32{
+	6, // 0: aml.v1.Transaction.created_at:type_name -> google.protobuf.Timestamp
+	6, // 1: aml.v1.Alert.created_at:type_name -> google.protobuf.Timestamp
+	0, // 2: aml.v1.Alert.evidence:type_name -> aml.v1.Transaction
+	5, // 3: aml.v1.Alert.details:type_name -> aml.v1.Alert.DetailsEntry
+	0, // 4: aml.v1.EvaluateRequest.transactions:type_name -> aml.v1.Transaction
+	1, // 5: aml.v1.EvaluateResponse.alerts:type_name -> aml.v1.Alert
+	2, // 6: aml.v1.EvaluateResponse.rules:type_name -> aml.v1.RuleSummary
+	3, // 7: aml.v1.RuleEngine.Evaluate:input_type -> aml.v1.EvaluateRequest
+	0, // 8: aml.v1.RuleEngine.EvaluateStream:input_type -> aml.v1.Transaction
+	4, // 9: aml.v1.RuleEngine.Evaluate:output_type -> aml.v1.EvaluateResponse
+	1, // 10: aml.v1.RuleEngine.EvaluateStream:output_type -> aml.v1.Alert
+	9, // [9:11] is the sub-list for method output_type
+	7, // [7:9] is the sub-list for method input_type
+	7, // [7:7] is the sub-list for extension type_name
+	7, // [7:7] is the sub-list for extension extendee
+	0, // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_aml_proto_init() }
+func file_aml_proto_init() {
+	if File_aml_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_aml_proto_rawDesc), len(file_aml_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_aml_proto_goTypes,
+		DependencyIndexes: file_aml_proto_depIdxs,
+		MessageInfos:      file_aml_proto_msgTypes,
+	}.Build()
+	File_aml_proto = out.File
+	file_aml_proto_goTypes = nil
+	file_aml_proto_depIdxs = nil
+}
@@ -0,0 +1,154 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.0
+// - protoc             (unknown)
+// source: aml.proto
+
+package amlpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RuleEngine_Evaluate_FullMethodName       = "/aml.v1.RuleEngine/Evaluate"
+	RuleEngine_EvaluateStream_FullMethodName = "/aml.v1.RuleEngine/EvaluateStream"
+)
+
+// RuleEngineClient is the client API for RuleEngine service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RuleEngineClient interface {
+	Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error)
+	EvaluateStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Transaction, Alert], error)
+}
+
+type ruleEngineClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRuleEngineClient(cc grpc.ClientConnInterface) RuleEngineClient {
+	return &ruleEngineClient{cc}
+}
+
+func (c *ruleEngineClient) Evaluate(ctx context.Context, in *EvaluateRequest, opts ...grpc.CallOption) (*EvaluateResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EvaluateResponse)
+	err := c.cc.Invoke(ctx, RuleEngine_Evaluate_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ruleEngineClient) EvaluateStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[Transaction, Alert], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RuleEngine_ServiceDesc.Streams[0], RuleEngine_EvaluateStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Transaction, Alert]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RuleEngine_EvaluateStreamClient = grpc.BidiStreamingClient[Transaction, Alert]
+
+// RuleEngineServer is the server API for RuleEngine service.
+// All implementations must embed UnimplementedRuleEngineServer
+// for forward compatibility.
+type RuleEngineServer interface {
+	Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error)
+	EvaluateStream(grpc.BidiStreamingServer[Transaction, Alert]) error
+	mustEmbedUnimplementedRuleEngineServer()
+}
+
+// UnimplementedRuleEngineServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRuleEngineServer struct{}
+
+func (UnimplementedRuleEngineServer) Evaluate(context.Context, *EvaluateRequest) (*EvaluateResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Evaluate not implemented")
+}
+func (UnimplementedRuleEngineServer) EvaluateStream(grpc.BidiStreamingServer[Transaction, Alert]) error {
+	return status.Error(codes.Unimplemented, "method EvaluateStream not implemented")
+}
+func (UnimplementedRuleEngineServer) mustEmbedUnimplementedRuleEngineServer() {}
+func (UnimplementedRuleEngineServer) testEmbeddedByValue()                    {}
+
+// UnsafeRuleEngineServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RuleEngineServer will
+// result in compilation errors.
+type UnsafeRuleEngineServer interface {
+	mustEmbedUnimplementedRuleEngineServer()
+}
+
+func RegisterRuleEngineServer(s grpc.ServiceRegistrar, srv RuleEngineServer) {
+	// If the following call panics, it indicates UnimplementedRuleEngineServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RuleEngine_ServiceDesc, srv)
+}
+
+func _RuleEngine_Evaluate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EvaluateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RuleEngineServer).Evaluate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RuleEngine_Evaluate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RuleEngineServer).Evaluate(ctx, req.(*EvaluateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RuleEngine_EvaluateStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RuleEngineServer).EvaluateStream(&grpc.GenericServerStream[Transaction, Alert]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RuleEngine_EvaluateStreamServer = grpc.BidiStreamingServer[Transaction, Alert]
+
+// RuleEngine_ServiceDesc is the grpc.ServiceDesc for RuleEngine service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RuleEngine_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "aml.v1.RuleEngine",
+	HandlerType: (*RuleEngineServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Evaluate",
+			Handler:    _RuleEngine_Evaluate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "EvaluateStream",
+			Handler:       _RuleEngine_EvaluateStream_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "aml.proto",
+}
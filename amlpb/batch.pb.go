@@ -0,0 +1,320 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        (unknown)
+// source: batch.proto
+
+package amlpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StoredTransaction struct {
+	state               protoimpl.MessageState `protogen:"open.v1"`
+	TransactionId       []byte                 `protobuf:"bytes,1,opt,name=transaction_id,json=transactionId,proto3" json:"transaction_id,omitempty"`
+	UserId              []byte                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	AccountId           []byte                 `protobuf:"bytes,3,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Amount              string                 `protobuf:"bytes,4,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency            string                 `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	Country             string                 `protobuf:"bytes,6,opt,name=country,proto3" json:"country,omitempty"`
+	DestinationCountry  string                 `protobuf:"bytes,7,opt,name=destination_country,json=destinationCountry,proto3" json:"destination_country,omitempty"`
+	Status              string                 `protobuf:"bytes,8,opt,name=status,proto3" json:"status,omitempty"`
+	Direction           string                 `protobuf:"bytes,9,opt,name=direction,proto3" json:"direction,omitempty"`
+	Channel             string                 `protobuf:"bytes,10,opt,name=channel,proto3" json:"channel,omitempty"`
+	Category            string                 `protobuf:"bytes,11,opt,name=category,proto3" json:"category,omitempty"`
+	Description         string                 `protobuf:"bytes,12,opt,name=description,proto3" json:"description,omitempty"`
+	CreatedAt           *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UserName            string                 `protobuf:"bytes,14,opt,name=user_name,json=userName,proto3" json:"user_name,omitempty"`
+	CounterpartyName    string                 `protobuf:"bytes,15,opt,name=counterparty_name,json=counterpartyName,proto3" json:"counterparty_name,omitempty"`
+	CounterpartyId      string                 `protobuf:"bytes,16,opt,name=counterparty_id,json=counterpartyId,proto3" json:"counterparty_id,omitempty"`
+	CounterpartyCountry string                 `protobuf:"bytes,17,opt,name=counterparty_country,json=counterpartyCountry,proto3" json:"counterparty_country,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *StoredTransaction) Reset() {
+	*x = StoredTransaction{}
+	mi := &file_batch_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StoredTransaction) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StoredTransaction) ProtoMessage() {}
+
+func (x *StoredTransaction) ProtoReflect() protoreflect.Message {
+	mi := &file_batch_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StoredTransaction.ProtoReflect.Descriptor instead.
+func (*StoredTransaction) Descriptor() ([]byte, []int) {
+	return file_batch_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StoredTransaction) GetTransactionId() []byte {
+	if x != nil {
+		return x.TransactionId
+	}
+	return nil
+}
+
+func (x *StoredTransaction) GetUserId() []byte {
+	if x != nil {
+		return x.UserId
+	}
+	return nil
+}
+
+func (x *StoredTransaction) GetAccountId() []byte {
+	if x != nil {
+		return x.AccountId
+	}
+	return nil
+}
+
+func (x *StoredTransaction) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetCountry() string {
+	if x != nil {
+		return x.Country
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetDestinationCountry() string {
+	if x != nil {
+		return x.DestinationCountry
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetCategory() string {
+	if x != nil {
+		return x.Category
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *StoredTransaction) GetUserName() string {
+	if x != nil {
+		return x.UserName
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetCounterpartyName() string {
+	if x != nil {
+		return x.CounterpartyName
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetCounterpartyId() string {
+	if x != nil {
+		return x.CounterpartyId
+	}
+	return ""
+}
+
+func (x *StoredTransaction) GetCounterpartyCountry() string {
+	if x != nil {
+		return x.CounterpartyCountry
+	}
+	return ""
+}
+
+type TransactionBatch struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Transactions  []*StoredTransaction   `protobuf:"bytes,1,rep,name=transactions,proto3" json:"transactions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TransactionBatch) Reset() {
+	*x = TransactionBatch{}
+	mi := &file_batch_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TransactionBatch) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TransactionBatch) ProtoMessage() {}
+
+func (x *TransactionBatch) ProtoReflect() protoreflect.Message {
+	mi := &file_batch_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TransactionBatch.ProtoReflect.Descriptor instead.
+func (*TransactionBatch) Descriptor() ([]byte, []int) {
+	return file_batch_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TransactionBatch) GetTransactions() []*StoredTransaction {
+	if x != nil {
+		return x.Transactions
+	}
+	return nil
+}
+
+var File_batch_proto protoreflect.FileDescriptor
+
+const file_batch_proto_rawDesc = "" +
+	"\n" +
+	"\vbatch.proto\x12\x06aml.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xe0\x04\n" +
+	"\x11StoredTransaction\x12%\n" +
+	"\x0etransaction_id\x18\x01 \x01(\fR\rtransactionId\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\fR\x06userId\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x03 \x01(\fR\taccountId\x12\x16\n" +
+	"\x06amount\x18\x04 \x01(\tR\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\x12\x18\n" +
+	"\acountry\x18\x06 \x01(\tR\acountry\x12/\n" +
+	"\x13destination_country\x18\a \x01(\tR\x12destinationCountry\x12\x16\n" +
+	"\x06status\x18\b \x01(\tR\x06status\x12\x1c\n" +
+	"\tdirection\x18\t \x01(\tR\tdirection\x12\x18\n" +
+	"\achannel\x18\n" +
+	" \x01(\tR\achannel\x12\x1a\n" +
+	"\bcategory\x18\v \x01(\tR\bcategory\x12 \n" +
+	"\vdescription\x18\f \x01(\tR\vdescription\x129\n" +
+	"\n" +
+	"created_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x1b\n" +
+	"\tuser_name\x18\x0e \x01(\tR\buserName\x12+\n" +
+	"\x11counterparty_name\x18\x0f \x01(\tR\x10counterpartyName\x12'\n" +
+	"\x0fcounterparty_id\x18\x10 \x01(\tR\x0ecounterpartyId\x121\n" +
+	"\x14counterparty_country\x18\x11 \x01(\tR\x13counterpartyCountry\"Q\n" +
+	"\x10TransactionBatch\x12=\n" +
+	"\ftransactions\x18\x01 \x03(\v2\x19.aml.v1.StoredTransactionR\ftransactionsB\x17Z\x15aml_rule_engine/amlpbb\x06proto3"
+
+var (
+	file_batch_proto_rawDescOnce sync.Once
+	file_batch_proto_rawDescData []byte
+)
+
+func file_batch_proto_rawDescGZIP() []byte {
+	file_batch_proto_rawDescOnce.Do(func() {
+		file_batch_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_batch_proto_rawDesc), len(file_batch_proto_rawDesc)))
+	})
+	return file_batch_proto_rawDescData
+}
+
+var file_batch_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_batch_proto_goTypes = []any{
+	(*StoredTransaction)(nil),     // 0: aml.v1.StoredTransaction
+	(*TransactionBatch)(nil),      // 1: aml.v1.TransactionBatch
+	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+}
+var file_batch_proto_depIdxs = []int32{
+	2, // 0: aml.v1.StoredTransaction.created_at:type_name -> google.protobuf.Timestamp
+	0, // 1: aml.v1.TransactionBatch.transactions:type_name -> aml.v1.StoredTransaction
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_batch_proto_init() }
+func file_batch_proto_init() {
+	if File_batch_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_batch_proto_rawDesc), len(file_batch_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_batch_proto_goTypes,
+		DependencyIndexes: file_batch_proto_depIdxs,
+		MessageInfos:      file_batch_proto_msgTypes,
+	}.Build()
+	File_batch_proto = out.File
+	file_batch_proto_goTypes = nil
+	file_batch_proto_depIdxs = nil
+}
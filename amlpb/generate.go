@@ -0,0 +1,7 @@
+// Package amlpb holds the gRPC service definition of the rule engine and the storage format of
+// transaction batches, with their generated code. On the wire, UUIDs travel as canonical strings; in
+// storage, as 16 raw bytes. Amounts are decimal strings and timestamps google.protobuf.Timestamp.
+package amlpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative aml.proto
+//go:generate protoc --go_out=. --go_opt=paths=source_relative batch.proto
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// amlrules screens a transactions file with the rules of a config file and writes the report:
+//
+//	amlrules -input transactions.csv -rules rules.yaml [-format json|csv|summary] [-concurrency n] [-output path]
+//
+// The input format follows the extension: .csv, .ndjson or .jsonl, or .json for a JSON array.
+// It exits with exitCritical when any critical alert was raised, so pipelines can gate on it.
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// amlrules exit codes
+const (
+	exitOK       = 0
+	exitCritical = 1
+	exitError    = 2
+)
+
+// progressMinBytes is the input size from which reading and evaluation progress is printed to stderr
+var progressMinBytes int64 = 64 << 20
+
+// cliFormats are the report formats of the -format flag
+var cliFormats = []string{"json", "csv", "summary"}
+
+// cliOptions are the flags of amlrules
+type cliOptions struct {
+	input       string
+	rules       string
+	format      string
+	concurrency int
+	output      string
+}
+
+// run is amlrules with its arguments and output streams, returning the exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	var options cliOptions
+	flags := flag.NewFlagSet("amlrules", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&options.input, "input", "", "transactions `file`: .csv, .ndjson, .jsonl or .json")
+	flags.StringVar(&options.rules, "rules", "", "rules config `file`, YAML or JSON")
+	flags.StringVar(&options.format, "format", "json", "report format: "+strings.Join(cliFormats, ", "))
+	flags.IntVar(&options.concurrency, "concurrency", 1, "rules evaluated at once")
+	flags.StringVar(&options.output, "output", "", "report `file`, stdout when empty")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitError
+	}
+	if options.input == "" || options.rules == "" {
+		fmt.Fprintln(stderr, "amlrules: -input and -rules are required")
+		flags.Usage()
+		return exitError
+	}
+	if !slices.Contains(cliFormats, options.format) {
+		fmt.Fprintf(stderr, "amlrules: unknown format %q\n", options.format)
+		return exitError
+	}
+
+	critical, err := screen(options, stdout, stderr)
+	switch {
+	case err != nil:
+		fmt.Fprintf(stderr, "amlrules: %v\n", err)
+		return exitError
+	case critical:
+		return exitCritical
+	default:
+		return exitOK
+	}
+}
+
+// screen evaluates the input file with the rules file and writes the report, reporting whether any
+// alert is critical. The report is still written when rules fail, their error being returned after it.
+func screen(options cliOptions, stdout, stderr io.Writer) (bool, error) {
+	rulesFile, err := os.Open(options.rules)
+	if err != nil {
+		return false, err
+	}
+	engine, err := LoadRulesConfig(rulesFile, WithRuleWorkers(options.concurrency))
+	rulesFile.Close()
+	if err != nil {
+		return false, err
+	}
+
+	transactions, progress, err := loadTransactionsFile(options.input, stderr)
+	if err != nil {
+		return false, err
+	}
+
+	start := time.Now()
+	result, evalErr := engine.Evaluate(context.Background(), transactions)
+	if progress {
+		fmt.Fprintf(stderr, "amlrules: evaluated %d transactions in %s, %d alerts\n",
+			len(transactions), time.Since(start).Round(time.Millisecond), len(result.Alerts))
+	}
+
+	if err := writeCLIOutput(options, stdout, result); err != nil {
+		return false, fmt.Errorf("write report: %w", err)
+	}
+
+	critical := slices.ContainsFunc(result.Alerts, func(alert Alert) bool { return alert.Severity == SeverityCritical })
+	return critical, evalErr
+}
+
+// loadTransactionsFile reads path in the format of its extension, printing progress to stderr when it
+// is at least progressMinBytes long, which is reported
+func loadTransactionsFile(path string, stderr io.Writer) ([]Transaction, bool, error) {
+	var load func(io.Reader) ([]Transaction, error)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		load = func(r io.Reader) ([]Transaction, error) { return LoadTransactionsCSV(r) }
+	case ".ndjson", ".jsonl":
+		load = func(r io.Reader) ([]Transaction, error) { return LoadTransactionsNDJSON(r) }
+	case ".json":
+		load = func(r io.Reader) ([]Transaction, error) { return LoadTransactionsJSON(r) }
+	default:
+		return nil, false, fmt.Errorf("%s: unknown input format, expected .csv, .ndjson, .jsonl or .json", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var r io.Reader = file
+	progress := info.Size() >= progressMinBytes
+	if progress {
+		r = &progressReader{r: file, w: stderr, name: filepath.Base(path), total: info.Size(), next: 10}
+	}
+
+	transactions, err := load(r)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", path, err)
+	}
+
+	return transactions, progress, nil
+}
+
+// progressReader prints to w each time another tenth of total bytes has been read
+type progressReader struct {
+	r     io.Reader
+	w     io.Writer
+	name  string
+	total int64
+	read  int64
+	// next is the next percentage to report
+	next int64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	for p.next <= 100 && p.read*100 >= p.total*p.next {
+		fmt.Fprintf(p.w, "amlrules: read %d%% of %s\n", p.next, p.name)
+		p.next += 10
+	}
+
+	return n, err
+}
+
+// writeCLIOutput writes the report to the output file, or to stdout without one
+func writeCLIOutput(options cliOptions, stdout io.Writer, result EvaluationResult) error {
+	if options.output == "" {
+		return writeCLIReport(stdout, options.format, result)
+	}
+
+	file, err := os.Create(options.output)
+	if err != nil {
+		return err
+	}
+	if err := writeCLIReport(file, options.format, result); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+func writeCLIReport(w io.Writer, format string, result EvaluationResult) error {
+	switch format {
+	case "csv":
+		return WriteCSVAlerts(w, result.Alerts)
+	case "summary":
+		_, err := io.WriteString(w, result.Summary.String())
+		return err
+	default:
+		return WriteJSONReport(w, result)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCLI runs amlrules with args, returning its exit code, stdout and stderr
+func runCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+// normalizeCLIReport blanks what differs between runs: identifiers, wall-clock times and durations
+func normalizeCLIReport(t *testing.T, format, report string) []byte {
+	t.Helper()
+
+	switch format {
+	case "json":
+		var doc map[string]any
+		require.NoError(t, json.Unmarshal([]byte(report), &doc))
+		doc["run_id"], doc["started_at"], doc["finished_at"] = "", "", ""
+		doc["summary"].(map[string]any)["duration_ns"] = 0
+		for _, alert := range doc["alerts"].([]any) {
+			alert.(map[string]any)["id"], alert.(map[string]any)["created_at"] = "", ""
+		}
+		normalized, err := json.MarshalIndent(doc, "", "  ")
+		require.NoError(t, err)
+		return append(normalized, '\n')
+	case "summary":
+		return regexp.MustCompile(`duration: \S+`).ReplaceAll([]byte(report), []byte("duration: 0s"))
+	default:
+		return []byte(report)
+	}
+}
+
+func TestRun_Formats(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		format string
+		golden string
+	}{
+		{name: "CSV input, JSON report", input: "transactions.csv", format: "json", golden: "report.golden.json"},
+		{name: "NDJSON input, JSON report", input: "transactions.ndjson", format: "json", golden: "report.golden.json"},
+		{name: "JSON array input, JSON report", input: "transactions.json", format: "json", golden: "report.golden.json"},
+		{name: "CSV report", input: "transactions.csv", format: "csv", golden: "alerts.golden.csv"},
+		{name: "summary report", input: "transactions.ndjson", format: "summary", golden: "summary.golden.txt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t,
+				"-input", filepath.Join("testdata", "cli", tt.input),
+				"-rules", filepath.Join("testdata", "cli", "rules.json"),
+				"-format", tt.format,
+				"-concurrency", "2")
+
+			assert.Equal(t, exitCritical, code, "a blacklisted country raises a critical alert")
+			assert.Empty(t, stderr)
+			assertGolden(t, filepath.Join("cli", tt.golden), normalizeCLIReport(t, tt.format, stdout))
+		})
+	}
+}
+
+func TestRun_ExitCodes(t *testing.T) {
+	rules := filepath.Join("testdata", "cli", "rules.json")
+	brokenRules := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(brokenRules, []byte(`{"rules":[{"type":"velocity","periods":[{"duration":"a week","threshold":3}]}]}`), 0o644))
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStderr string
+	}{
+		{name: "no critical alert", args: []string{"-input", filepath.Join("testdata", "cli", "clean.ndjson"), "-rules", rules}, wantCode: exitOK},
+		{name: "missing flags", args: []string{"-rules", rules}, wantCode: exitError, wantStderr: "-input and -rules are required"},
+		{name: "unknown format", args: []string{"-input", "x.csv", "-rules", rules, "-format", "xml"}, wantCode: exitError, wantStderr: `unknown format "xml"`},
+		{name: "unknown input extension", args: []string{"-input", "transactions.txt", "-rules", rules}, wantCode: exitError, wantStderr: "unknown input format"},
+		{name: "missing input", args: []string{"-input", filepath.Join("testdata", "cli", "missing.csv"), "-rules", rules}, wantCode: exitError, wantStderr: "missing.csv"},
+		{name: "malformed input", args: []string{"-input", filepath.Join("testdata", "transactions_broken.csv"), "-rules", rules}, wantCode: exitError, wantStderr: "line"},
+		{name: "invalid rules", args: []string{"-input", filepath.Join("testdata", "cli", "clean.ndjson"), "-rules", brokenRules}, wantCode: exitError, wantStderr: "rule 0 (velocity)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _, stderr := runCLI(t, tt.args...)
+
+			assert.Equal(t, tt.wantCode, code)
+			assert.Contains(t, stderr, tt.wantStderr)
+		})
+	}
+}
+
+func TestRun_OutputAndProgress(t *testing.T) {
+	minBytes := progressMinBytes
+	progressMinBytes = 0
+	t.Cleanup(func() { progressMinBytes = minBytes })
+
+	output := filepath.Join(t.TempDir(), "alerts.csv")
+	code, stdout, stderr := runCLI(t,
+		"-input", filepath.Join("testdata", "cli", "transactions.csv"),
+		"-rules", filepath.Join("testdata", "cli", "rules.json"),
+		"-format", "csv",
+		"-output", output)
+
+	assert.Equal(t, exitCritical, code)
+	assert.Empty(t, stdout, "the report goes to the output file")
+	written, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assertGolden(t, filepath.Join("cli", "alerts.golden.csv"), written)
+
+	assert.Contains(t, stderr, "read 10% of transactions.csv")
+	assert.Contains(t, stderr, "read 100% of transactions.csv")
+	assert.Contains(t, stderr, "evaluated 7 transactions")
+}
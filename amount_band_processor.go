@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrInvalidAmountBand      = errors.New("amount band min is greater than max")
+	ErrOverlappingAmountBands = errors.New("amount bands overlap")
+)
+
+// AmountBand is an amount range including both Min and Max
+type AmountBand struct {
+	Min decimal.Decimal
+	Max decimal.Decimal
+}
+
+func (b AmountBand) contains(amount decimal.Decimal) bool {
+	return amount.GreaterThanOrEqual(b.Min) && amount.LessThanOrEqual(b.Max)
+}
+
+func (b AmountBand) String() string {
+	return fmt.Sprintf("[%s, %s]", b.Min, b.Max)
+}
+
+// AmountBandProcessor flags users with at least MinCount transactions inside any band within Window
+type AmountBandProcessor struct {
+	Bands    []AmountBand
+	MinCount int
+	// Window bounds how far apart counted transactions may be, zero counts the whole batch
+	Window time.Duration
+}
+
+// NewAmountBandProcessor validates the bands, rejecting overlapping ones unless allowOverlap is set.
+// A minCount below 1 flags any single transaction in a band.
+func NewAmountBandProcessor(bands []AmountBand, minCount int, window time.Duration, allowOverlap bool) (AmountBandProcessor, error) {
+	for _, band := range bands {
+		if band.Min.GreaterThan(band.Max) {
+			return AmountBandProcessor{}, fmt.Errorf("band %s: %w", band, ErrInvalidAmountBand)
+		}
+	}
+
+	if !allowOverlap {
+		sorted := slices.Clone(bands)
+		slices.SortFunc(sorted, func(a, b AmountBand) int { return a.Min.Cmp(b.Min) })
+		for i := 1; i < len(sorted); i++ {
+			if sorted[i].Min.LessThanOrEqual(sorted[i-1].Max) {
+				return AmountBandProcessor{}, fmt.Errorf("bands %s and %s: %w", sorted[i-1], sorted[i], ErrOverlappingAmountBands)
+			}
+		}
+	}
+
+	return AmountBandProcessor{
+		Bands:    bands,
+		MinCount: max(minCount, 1),
+		Window:   window,
+	}, nil
+}
+
+func (a AmountBandProcessor) inAnyBand(amount decimal.Decimal) bool {
+	for _, band := range a.Bands {
+		if band.contains(amount) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (a AmountBandProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	window := a.Window
+	if window <= 0 {
+		window = math.MaxInt64
+	}
+
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return a.inAnyBand(tx.Amount)
+	}}
+	// "at least MinCount" is a velocity period flagging more than MinCount-1
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(window, max(a.MinCount, 1)-1)}, options)
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			if violated, _ := checker.CheckUser(txs); violated {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
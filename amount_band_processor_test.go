@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAmountBandProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	bands := []AmountBand{
+		{Min: decimal.NewFromInt(9000), Max: decimal.RequireFromString("9999.99")},
+		{Min: decimal.NewFromInt(4500), Max: decimal.NewFromInt(4999)},
+	}
+
+	tx := func(days int, amount string) Transaction {
+		return Transaction{UserID: userID, Amount: decimal.RequireFromString(amount), CreatedAt: baseTime.Add(time.Duration(days) * 24 * time.Hour)}
+	}
+
+	tests := []struct {
+		name         string
+		minCount     int
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{name: "exactly at min is counted", minCount: 1, transactions: []Transaction{tx(0, "9000")}, wantFlagged: true},
+		{name: "exactly at max is counted", minCount: 1, transactions: []Transaction{tx(0, "9999.99")}, wantFlagged: true},
+		{name: "just below min is not counted", minCount: 1, transactions: []Transaction{tx(0, "8999.99")}, wantFlagged: false},
+		{name: "just above max is not counted", minCount: 1, transactions: []Transaction{tx(0, "10000")}, wantFlagged: false},
+		{
+			name:         "hits across bands accumulate",
+			minCount:     3,
+			transactions: []Transaction{tx(0, "9500"), tx(1, "4600"), tx(2, "9000"), tx(3, "100")},
+			wantFlagged:  true,
+		},
+		{
+			name:         "too few hits",
+			minCount:     3,
+			transactions: []Transaction{tx(0, "9500"), tx(1, "4600"), tx(2, "8000")},
+			wantFlagged:  false,
+		},
+		{
+			name:         "hits spread beyond the window",
+			minCount:     3,
+			transactions: []Transaction{tx(0, "9500"), tx(10, "9600"), tx(20, "9700")},
+			wantFlagged:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor, err := NewAmountBandProcessor(bands, tt.minCount, week, false)
+			require.NoError(t, err)
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+
+	wholeBatch, err := NewAmountBandProcessor(bands, 3, 0, false)
+	require.NoError(t, err)
+	assert.Contains(t, wholeBatch.Process(context.Background(), []Transaction{tx(0, "9500"), tx(100, "9600"), tx(200, "9700")}), userID)
+}
+
+func TestNewAmountBandProcessor_Validation(t *testing.T) {
+	_, err := NewAmountBandProcessor([]AmountBand{{Min: decimal.NewFromInt(10), Max: decimal.NewFromInt(5)}}, 1, week, false)
+	assert.ErrorIs(t, err, ErrInvalidAmountBand)
+
+	overlapping := []AmountBand{
+		{Min: decimal.NewFromInt(9000), Max: decimal.NewFromInt(9999)},
+		{Min: decimal.NewFromInt(5000), Max: decimal.NewFromInt(9000)},
+	}
+	_, err = NewAmountBandProcessor(overlapping, 1, week, false)
+	assert.ErrorIs(t, err, ErrOverlappingAmountBands)
+
+	_, err = NewAmountBandProcessor(overlapping, 1, week, true)
+	assert.NoError(t, err)
+
+	_, err = NewAmountBandProcessor([]AmountBand{{Min: decimal.NewFromInt(5), Max: decimal.NewFromInt(5)}}, 1, week, false)
+	assert.NoError(t, err)
+}
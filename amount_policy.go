@@ -0,0 +1,102 @@
+package main
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+// RoundingMode is how AmountPolicy rounds amounts to a currency's scale. The zero value is RoundHalfEven.
+type RoundingMode int
+
+const (
+	// RoundHalfEven rounds halves to the even digit, as banks do: 0.125 EUR is 0.12, 0.135 EUR is 0.14
+	RoundHalfEven RoundingMode = iota
+	// RoundHalfUp rounds halves away from zero: 0.125 EUR is 0.13
+	RoundHalfUp
+	// RoundDown truncates towards zero: 0.129 EUR is 0.12
+	RoundDown
+)
+
+// currencyScales are the ISO 4217 minor-unit digits of the currencies AmountPolicy knows by default
+var currencyScales = map[string]int32{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+
+	"AED": 2, "ARS": 2, "AUD": 2, "BDT": 2, "BGN": 2, "BRL": 2, "CAD": 2, "CHF": 2, "CNY": 2,
+	"COP": 2, "CZK": 2, "DKK": 2, "EGP": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2, "IDR": 2,
+	"ILS": 2, "INR": 2, "IRR": 2, "KES": 2, "LKR": 2, "MAD": 2, "MXN": 2, "MYR": 2, "NGN": 2,
+	"NOK": 2, "NZD": 2, "PEN": 2, "PHP": 2, "PKR": 2, "PLN": 2, "QAR": 2, "RON": 2, "RUB": 2,
+	"SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TRY": 2, "TWD": 2, "UAH": 2, "USD": 2, "ZAR": 2,
+
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+
+	"CLF": 4, "UYW": 4,
+}
+
+// AmountPolicy rounds amounts to the minor units of their currency before they are summed or
+// compared, so 100 and 100.00 weigh the same and 4999.995 EUR can't pass as below 5000.00 EUR.
+// The zero value uses the ISO 4217 scales and RoundHalfEven.
+type AmountPolicy struct {
+	// Scales overrides or extends the ISO 4217 scales, keyed by upper-case currency code.
+	// NewAmountPolicy takes codes in any case.
+	Scales   map[string]int32
+	Rounding RoundingMode
+}
+
+// NewAmountPolicy creates an AmountPolicy, keying scales by normalized currency code so that
+// lookups need no normalizing of their own
+func NewAmountPolicy(scales map[string]int32, rounding RoundingMode) AmountPolicy {
+	normalized := make(map[string]int32, len(scales))
+	for code, scale := range scales {
+		normalized[normalizeCurrency(code)] = scale
+	}
+
+	return AmountPolicy{Scales: normalized, Rounding: rounding}
+}
+
+// Scale returns the number of decimal places of currency, false for a currency the policy doesn't
+// know, including the empty one
+func (p AmountPolicy) Scale(currency string) (int32, bool) {
+	currency = normalizeCurrency(currency)
+	if scale, ok := p.Scales[currency]; ok {
+		return scale, true
+	}
+
+	scale, ok := currencyScales[currency]
+	return scale, ok
+}
+
+// Normalize rounds amount to the scale of currency. Amounts in a currency without a scale are returned as is.
+func (p AmountPolicy) Normalize(amount decimal.Decimal, currency string) decimal.Decimal {
+	scale, ok := p.Scale(currency)
+	if !ok {
+		return amount
+	}
+
+	switch p.Rounding {
+	case RoundHalfUp:
+		return amount.Round(scale)
+	case RoundDown:
+		return amount.Truncate(scale)
+	default:
+		return amount.RoundBank(scale)
+	}
+}
+
+// orDefault returns the policy p points to, the zero AmountPolicy when p is nil
+func (p *AmountPolicy) orDefault() AmountPolicy {
+	if p == nil {
+		return AmountPolicy{}
+	}
+
+	return *p
+}
+
+// amount is the amount of tx rounded to the scale of its currency
+func (p AmountPolicy) amount(tx Transaction) decimal.Decimal {
+	return p.Normalize(tx.Amount, tx.Currency)
+}
+
+// spend is spendAmount of tx rounded to the scale of its currency, for sums
+func (p AmountPolicy) spend(tx Transaction) decimal.Decimal {
+	return p.Normalize(spendAmount(tx), tx.Currency)
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAmountPolicy_Scale(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    AmountPolicy
+		currency  string
+		wantScale int32
+		wantOK    bool
+	}{
+		{name: "JPY has no minor unit", currency: "JPY", wantScale: 0, wantOK: true},
+		{name: "EUR has cents", currency: "EUR", wantScale: 2, wantOK: true},
+		{name: "BHD has fils", currency: "BHD", wantScale: 3, wantOK: true},
+		{name: "codes are case and space insensitive", currency: " bhd ", wantScale: 3, wantOK: true},
+		{name: "unknown currency", currency: "XTS", wantOK: false},
+		{name: "empty currency", currency: "", wantOK: false},
+		{
+			name:      "override",
+			policy:    AmountPolicy{Scales: map[string]int32{"JPY": 2}},
+			currency:  "JPY",
+			wantScale: 2,
+			wantOK:    true,
+		},
+		{
+			name:      "override normalized by the constructor",
+			policy:    NewAmountPolicy(map[string]int32{" jpy": 2}, RoundHalfEven),
+			currency:  "jpy",
+			wantScale: 2,
+			wantOK:    true,
+		},
+		{
+			name:      "extension",
+			policy:    AmountPolicy{Scales: map[string]int32{"XTS": 1}},
+			currency:  "XTS",
+			wantScale: 1,
+			wantOK:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scale, ok := tt.policy.Scale(tt.currency)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.wantScale, scale)
+		})
+	}
+}
+
+func TestAmountPolicy_Normalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		rounding RoundingMode
+		amount   string
+		currency string
+		want     string
+	}{
+		{name: "EUR without decimals", amount: "100", currency: "EUR", want: "100.00"},
+		{name: "EUR half-even down", amount: "0.125", currency: "EUR", want: "0.12"},
+		{name: "EUR half-even up", amount: "0.135", currency: "EUR", want: "0.14"},
+		{name: "EUR half-up", rounding: RoundHalfUp, amount: "0.125", currency: "EUR", want: "0.13"},
+		{name: "EUR half-up negative", rounding: RoundHalfUp, amount: "-0.125", currency: "EUR", want: "-0.13"},
+		{name: "EUR down", rounding: RoundDown, amount: "0.129", currency: "EUR", want: "0.12"},
+		{name: "JPY half-even", amount: "100.5", currency: "JPY", want: "100"},
+		{name: "JPY half-up", rounding: RoundHalfUp, amount: "100.5", currency: "JPY", want: "101"},
+		{name: "BHD keeps fils", amount: "1.2345", currency: "BHD", want: "1.234"},
+		{name: "BHD pads fils", amount: "1.2", currency: "BHD", want: "1.200"},
+		{name: "unknown currency as is", amount: "1.23456", currency: "XTS", want: "1.23456"},
+		{name: "empty currency as is", amount: "1.23456", currency: "", want: "1.23456"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := AmountPolicy{Rounding: tt.rounding}
+			got := policy.Normalize(decimal.RequireFromString(tt.amount), tt.currency)
+			assert.True(t, got.Equal(decimal.RequireFromString(tt.want)), "got %s", got)
+			assert.Equal(t, tt.want, got.StringFixed(got.Exponent()*-1))
+		})
+	}
+}
+
+func TestTransactionAmountProcessor_Process_AmountPolicy(t *testing.T) {
+	tx := func(amount, currency string) Transaction {
+		return Transaction{UserID: uuid.New(), Amount: decimal.RequireFromString(amount), Currency: currency}
+	}
+
+	tests := []struct {
+		name        string
+		processor   TransactionAmountProcessor
+		tx          Transaction
+		wantFlagged bool
+	}{
+		{
+			name:      "EUR at the threshold with more decimals",
+			processor: TransactionAmountProcessor{Threshold: decimal.RequireFromString("5000.00")},
+			tx:        tx("5000", "EUR"),
+		},
+		{
+			name:      "EUR a fraction of a cent above the threshold",
+			processor: TransactionAmountProcessor{Threshold: decimal.NewFromInt(5000)},
+			tx:        tx("5000.004", "EUR"),
+		},
+		{
+			name:      "EUR a half cent above the threshold rounded half-even",
+			processor: TransactionAmountProcessor{Threshold: decimal.NewFromInt(5000)},
+			tx:        tx("5000.005", "EUR"),
+		},
+		{
+			name: "EUR a half cent above the threshold rounded half-up",
+			processor: TransactionAmountProcessor{
+				Threshold: decimal.NewFromInt(5000),
+				Amounts:   &AmountPolicy{Rounding: RoundHalfUp},
+			},
+			tx:          tx("5000.005", "EUR"),
+			wantFlagged: true,
+		},
+		{
+			name:        "EUR a cent above the threshold",
+			processor:   TransactionAmountProcessor{Threshold: decimal.NewFromInt(5000)},
+			tx:          tx("5000.01", "EUR"),
+			wantFlagged: true,
+		},
+		{
+			name:      "JPY a fraction of a yen above the threshold",
+			processor: TransactionAmountProcessor{Threshold: decimal.NewFromInt(1_000_000)},
+			tx:        tx("1000000.4", "JPY"),
+		},
+		{
+			name:        "JPY a yen above the threshold",
+			processor:   TransactionAmountProcessor{Threshold: decimal.NewFromInt(1_000_000)},
+			tx:          tx("1000001", "JPY"),
+			wantFlagged: true,
+		},
+		{
+			name:      "BHD a fraction of a fils above the threshold",
+			processor: TransactionAmountProcessor{Threshold: decimal.RequireFromString("1000.000")},
+			tx:        tx("1000.0004", "BHD"),
+		},
+		{
+			name:        "BHD a fils above the threshold",
+			processor:   TransactionAmountProcessor{Threshold: decimal.NewFromInt(1000)},
+			tx:          tx("1000.001", "BHD"),
+			wantFlagged: true,
+		},
+		{
+			name: "converted amount rounded to the base currency",
+			processor: TransactionAmountProcessor{
+				Threshold:  decimal.NewFromInt(5000),
+				Conversion: &CurrencyConversion{Base: "EUR", Rates: testRates()},
+			},
+			// 5555.56 USD is 5000.004 EUR
+			tx: tx("5555.56", "USD"),
+		},
+		{
+			name:        "unknown currency compared as is",
+			processor:   TransactionAmountProcessor{Threshold: decimal.NewFromInt(5000)},
+			tx:          tx("5000.004", "XTS"),
+			wantFlagged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions := []Transaction{tt.tx}
+
+			_, flagged := tt.processor.Process(context.Background(), transactions)[tt.tx.UserID]
+			assert.Equal(t, tt.wantFlagged, flagged, "Process")
+
+			_, flagged = tt.processor.ProcessBatch(context.Background(), NewTransactionBatch(transactions))[tt.tx.UserID]
+			assert.Equal(t, tt.wantFlagged, flagged, "ProcessBatch")
+
+			evidence, _ := tt.processor.AlertDetails(context.Background(), tt.tx.UserID, transactions)
+			assert.Equal(t, tt.wantFlagged, len(evidence) == 1, "AlertDetails")
+		})
+	}
+}
+
+func TestDailyAggregateProcessor_Process_AmountPolicy(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		threshold   string
+		currency    string
+		amounts     []string
+		wantFlagged bool
+	}{
+		{
+			name:      "EUR mixed scales summing to the threshold",
+			threshold: "10000",
+			currency:  "EUR",
+			amounts:   []string{"2500", "2500.0", "2500.00", "2500.000"},
+		},
+		{
+			name:      "EUR fractions of a cent adding up above the threshold",
+			threshold: "10000.00",
+			currency:  "EUR",
+			amounts:   []string{"3333.334", "3333.334", "3333.334"},
+		},
+		{
+			name:        "EUR a cent above the threshold",
+			threshold:   "10000",
+			currency:    "EUR",
+			amounts:     []string{"3333.33", "3333.34", "3333.34"},
+			wantFlagged: true,
+		},
+		{
+			name:      "JPY fractions of a yen adding up above the threshold",
+			threshold: "100000",
+			currency:  "JPY",
+			amounts:   []string{"50000.4", "50000.4"},
+		},
+		{
+			name:        "JPY a yen above the threshold",
+			threshold:   "100000",
+			currency:    "JPY",
+			amounts:     []string{"50000", "50001"},
+			wantFlagged: true,
+		},
+		{
+			name:      "BHD fractions of a fils adding up above the threshold",
+			threshold: "1000.000",
+			currency:  "BHD",
+			amounts:   []string{"500.0004", "500.0004"},
+		},
+		{
+			name:        "BHD a fils above the threshold",
+			threshold:   "1000",
+			currency:    "BHD",
+			amounts:     []string{"500", "500.001"},
+			wantFlagged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		for _, rolling := range []bool{false, true} {
+			t.Run(tt.name, func(t *testing.T) {
+				userID := uuid.New()
+				var transactions []Transaction
+				for i, amount := range tt.amounts {
+					transactions = append(transactions, Transaction{
+						UserID:    userID,
+						Amount:    decimal.RequireFromString(amount),
+						Currency:  tt.currency,
+						CreatedAt: baseTime.Add(time.Duration(i) * time.Hour),
+					})
+				}
+
+				processor := DailyAggregateProcessor{Threshold: decimal.RequireFromString(tt.threshold), Rolling: rolling}
+				_, flagged := processor.Process(context.Background(), transactions)[userID]
+				assert.Equal(t, tt.wantFlagged, flagged, "rolling: %t", rolling)
+			})
+		}
+	}
+}
+
+func TestStructuringProcessor_Process_AmountPolicy(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		currency    string
+		amount      string
+		wantFlagged bool
+	}{
+		{name: "EUR just below the threshold", currency: "EUR", amount: "9999.99", wantFlagged: true},
+		{name: "EUR rounding to the threshold", currency: "EUR", amount: "9999.995"},
+		{name: "EUR at the lower bound with extra decimals", currency: "EUR", amount: "8999.996", wantFlagged: true},
+		{name: "EUR rounding below the lower bound", currency: "EUR", amount: "8999.994"},
+		{name: "JPY rounding to the threshold", currency: "JPY", amount: "9999.5"},
+		{name: "BHD a fils below the threshold", currency: "BHD", amount: "9999.999", wantFlagged: true},
+		{name: "BHD rounding to the threshold", currency: "BHD", amount: "9999.9995"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			var transactions []Transaction
+			for i := range 3 {
+				transactions = append(transactions, Transaction{
+					UserID:    userID,
+					Amount:    decimal.RequireFromString(tt.amount),
+					Currency:  tt.currency,
+					CreatedAt: baseTime.Add(time.Duration(i) * time.Hour),
+				})
+			}
+
+			processor := NewStructuringProcessor(decimal.NewFromInt(10000), decimal.RequireFromString("0.1"), 3, 24*time.Hour)
+			_, flagged := processor.Process(context.Background(), transactions)[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+
+			_, flagged = processor.ProcessGrouped(context.Background(), GroupByUser(transactions))[userID]
+			assert.Equal(t, tt.wantFlagged, flagged, "ProcessGrouped")
+		})
+	}
+}
@@ -2,23 +2,207 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 )
 
+// ErrNoAmountThreshold is returned when an amount processor is configured without any threshold
+var ErrNoAmountThreshold = errors.New("no default or per-currency threshold configured")
+
 type TransactionAmountProcessor struct {
-	Threshold decimal.Decimal
+	// Threshold applies to transactions without a country or currency threshold, including empty ones
+	Threshold         decimal.Decimal
+	CountryThresholds map[string]decimal.Decimal
+	// CurrencyThresholds are absolute thresholds per Currency, used without any rate lookup.
+	// A country threshold takes precedence over a currency one. With a Conversion, amounts are
+	// in its Base, so only the Base entry applies.
+	CurrencyThresholds map[string]decimal.Decimal
+	// Conversion compares amounts in its base currency, thresholds being expressed in it. Nil compares raw amounts.
+	Conversion *CurrencyConversion
+	// Amounts rounds amounts to their currency's scale before comparison, the zero AmountPolicy when nil
+	Amounts *AmountPolicy
+
+	// noDefault leaves transactions without a country or currency threshold unevaluated instead of using Threshold
+	noDefault bool
+}
+
+// NewCountryAmountProcessor creates a TransactionAmountProcessor with per-country thresholds
+func NewCountryAmountProcessor(defaultThreshold decimal.Decimal, countryThresholds map[string]decimal.Decimal) TransactionAmountProcessor {
+	return TransactionAmountProcessor{
+		Threshold:         defaultThreshold,
+		CountryThresholds: countryThresholds,
+	}
+}
+
+// NewCurrencyAmountProcessor creates a TransactionAmountProcessor with per-currency thresholds. Without
+// a default threshold, transactions in other currencies are reported as unevaluated by Evaluate.
+func NewCurrencyAmountProcessor(defaultThreshold *decimal.Decimal, currencyThresholds map[string]decimal.Decimal) (TransactionAmountProcessor, error) {
+	if defaultThreshold == nil && len(currencyThresholds) == 0 {
+		return TransactionAmountProcessor{}, ErrNoAmountThreshold
+	}
+
+	processor := TransactionAmountProcessor{
+		CurrencyThresholds: currencyThresholds,
+		noDefault:          defaultThreshold == nil,
+	}
+	if defaultThreshold != nil {
+		processor.Threshold = *defaultThreshold
+	}
+
+	return processor, nil
+}
+
+// AmountEvaluation is the detailed result of TransactionAmountProcessor
+type AmountEvaluation struct {
+	Flagged map[uuid.UUID]struct{}
+	// Unevaluated holds the transactions no threshold applied to
+	Unevaluated []Transaction
+}
+
+func (c TransactionAmountProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	return c.Evaluate(ctx, transactions).Flagged
 }
 
-func (c TransactionAmountProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+// ProcessGrouped reads the batch the engine grouped once for every rule: thresholds apply to one
+// transaction at a time, so no grouping is needed
+func (c TransactionAmountProcessor) ProcessGrouped(ctx context.Context, grouped GroupedTransactions) map[uuid.UUID]struct{} {
+	return c.Evaluate(ctx, grouped.All()).Flagged
+}
+
+// ProcessBatch scans the amount, country and currency columns only. With a Conversion, which needs
+// whole transactions, it evaluates the rows instead.
+func (c TransactionAmountProcessor) ProcessBatch(ctx context.Context, batch *TransactionBatch) map[uuid.UUID]struct{} {
+	if c.Conversion != nil {
+		return c.Evaluate(ctx, batch.Transactions()).Flagged
+	}
+
+	thresholds := c.thresholds()
+	amounts := c.Amounts.orDefault()
 	flaggedUsers := make(map[uuid.UUID]struct{})
+	for i, amount := range batch.Amounts {
+		threshold, ok := thresholds.lookupCodes(batch.Countries[i], batch.Currencies[i])
+		if ok && amounts.Normalize(amount, batch.Currencies[i]).GreaterThan(threshold) {
+			flaggedUsers[batch.UserIDs[i]] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// Evaluate compares every transaction with its country threshold, else its currency threshold,
+// else the default one
+func (c TransactionAmountProcessor) Evaluate(_ context.Context, transactions []Transaction) AmountEvaluation {
+	thresholds := c.thresholds()
+	amounts := c.Amounts.orDefault()
+	transactions, flaggedUsers := c.Conversion.convert(transactions)
+	evaluation := AmountEvaluation{Flagged: flaggedUsers}
 
 	for _, tx := range transactions {
-		if tx.Amount.GreaterThan(c.Threshold) {
-			flaggedUsers[tx.UserID] = struct{}{}
+		threshold, ok := thresholds.lookup(tx)
+		if !ok {
+			evaluation.Unevaluated = append(evaluation.Unevaluated, tx)
+			continue
+		}
+
+		if amounts.amount(tx).GreaterThan(threshold) {
+			evaluation.Flagged[tx.UserID] = struct{}{}
 		}
 	}
 
-	return flaggedUsers
+	return evaluation
+}
+
+// AlertDetails reports the thresholds the evidence actually exceeded, in order of first use
+func (c TransactionAmountProcessor) AlertDetails(_ context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	thresholds := c.thresholds()
+	amounts := c.Amounts.orDefault()
+
+	var evidence []Transaction
+	var applied []string
+	for _, tx := range transactions {
+		converted, review := c.Conversion.convert([]Transaction{tx})
+		if _, flagged := review[userID]; flagged {
+			evidence = append(evidence, tx)
+			continue
+		}
+		if len(converted) == 0 || converted[0].UserID != userID {
+			continue
+		}
+
+		threshold, ok := thresholds.lookup(converted[0])
+		if ok && amounts.amount(converted[0]).GreaterThan(threshold) {
+			evidence = append(evidence, tx)
+			if !slices.Contains(applied, threshold.String()) {
+				applied = append(applied, threshold.String())
+			}
+		}
+	}
+	if len(applied) == 0 {
+		applied = []string{c.Threshold.String()}
+	}
+
+	return evidence, map[string]string{
+		"threshold":    strings.Join(applied, ", "),
+		"transactions": strconv.Itoa(len(evidence)),
+	}
+}
+
+// amountThresholds are the thresholds of a TransactionAmountProcessor keyed by normalized code
+type amountThresholds struct {
+	processor TransactionAmountProcessor
+	country   map[string]decimal.Decimal
+	currency  map[string]decimal.Decimal
+}
+
+func (c TransactionAmountProcessor) thresholds() amountThresholds {
+	thresholds := amountThresholds{
+		processor: c,
+		country:   make(map[string]decimal.Decimal, len(c.CountryThresholds)),
+		currency:  make(map[string]decimal.Decimal, len(c.CurrencyThresholds)),
+	}
+	for country, threshold := range c.CountryThresholds {
+		thresholds.country[normalizeCountry(country)] = threshold
+	}
+	for currency, threshold := range c.CurrencyThresholds {
+		thresholds.currency[normalizeCurrency(currency)] = threshold
+	}
+
+	return thresholds
+}
+
+// lookup returns the threshold applying to tx once converted, false when none does
+func (t amountThresholds) lookup(tx Transaction) (decimal.Decimal, bool) {
+	return t.lookupCodes(tx.Country, tx.Currency)
+}
+
+// lookupCodes returns the threshold applying to a converted transaction in country and currency
+func (t amountThresholds) lookupCodes(country, currency string) (decimal.Decimal, bool) {
+	if threshold, ok := t.country[normalizeCountry(country)]; ok {
+		return threshold, true
+	}
+
+	if t.processor.Conversion != nil {
+		currency = t.processor.Conversion.Base
+	}
+	if threshold, ok := t.currency[normalizeCurrency(currency)]; ok {
+		return threshold, true
+	}
+
+	return t.processor.Threshold, !t.processor.noDefault
+}
+
+func (c TransactionAmountProcessor) ExplainAlert(evidence []Transaction, _ map[string]string) string {
+	largest := decimal.Zero
+	for _, tx := range evidence {
+		largest = decimal.Max(largest, tx.Amount)
+	}
+
+	return fmt.Sprintf("flagged by amount threshold: %s above the limit, the largest being %s",
+		pluralize(len(evidence), "transaction"), largest)
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionAmountProcessor_Process(t *testing.T) {
+	userID := uuid.New()
+	countryThresholds := map[string]decimal.Decimal{
+		"IR": decimal.NewFromInt(1000),
+		"ch": decimal.NewFromInt(50000),
+	}
+
+	tests := []struct {
+		name        string
+		tx          Transaction
+		wantFlagged bool
+	}{
+		{
+			name:        "default threshold",
+			tx:          Transaction{UserID: userID, Country: "FR", Amount: decimal.NewFromInt(10001)},
+			wantFlagged: true,
+		},
+		{
+			name:        "amount at the default threshold",
+			tx:          Transaction{UserID: userID, Country: "FR", Amount: decimal.NewFromInt(10000)},
+			wantFlagged: false,
+		},
+		{
+			name:        "lower country threshold catches what the default would not",
+			tx:          Transaction{UserID: userID, Country: "ir", Amount: decimal.NewFromInt(3000)},
+			wantFlagged: true,
+		},
+		{
+			name:        "higher country threshold lets the same amount pass",
+			tx:          Transaction{UserID: userID, Country: "CH", Amount: decimal.NewFromInt(30000)},
+			wantFlagged: false,
+		},
+		{
+			name:        "empty country uses the default",
+			tx:          Transaction{UserID: userID, Amount: decimal.NewFromInt(30000)},
+			wantFlagged: true,
+		},
+	}
+
+	processor := NewCountryAmountProcessor(decimal.NewFromInt(10000), countryThresholds)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), []Transaction{tt.tx})
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+
+	legacy := TransactionAmountProcessor{Threshold: decimal.NewFromInt(100)}
+	assert.Contains(t, legacy.Process(context.Background(), []Transaction{{UserID: userID, Country: "IR", Amount: decimal.NewFromInt(101)}}), userID)
+}
+
+func TestTransactionAmountProcessor_Evaluate_CurrencyThresholds(t *testing.T) {
+	eur, usd, gbp, chf := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	currencyThresholds := map[string]decimal.Decimal{
+		"EUR": decimal.NewFromInt(10000),
+		"usd": decimal.NewFromInt(11000),
+		"GBP": decimal.NewFromInt(8500),
+	}
+
+	transactions := []Transaction{
+		{UserID: eur, Currency: "EUR", Amount: decimal.NewFromInt(10001)},
+		{UserID: usd, Currency: "USD", Amount: decimal.NewFromInt(10500)},
+		{UserID: gbp, Currency: "gbp", Amount: decimal.NewFromInt(9000)},
+		{UserID: chf, Currency: "CHF", Amount: decimal.NewFromInt(9000)},
+	}
+
+	processor, err := NewCurrencyAmountProcessor(nil, currencyThresholds)
+	require.NoError(t, err)
+
+	evaluation := processor.Evaluate(context.Background(), transactions)
+	assert.Equal(t, map[uuid.UUID]struct{}{eur: {}, gbp: {}}, evaluation.Flagged)
+	assert.Equal(t, []Transaction{transactions[3]}, evaluation.Unevaluated)
+
+	defaultThreshold := decimal.NewFromInt(5000)
+	processor, err = NewCurrencyAmountProcessor(&defaultThreshold, currencyThresholds)
+	require.NoError(t, err)
+
+	evaluation = processor.Evaluate(context.Background(), transactions)
+	assert.Equal(t, map[uuid.UUID]struct{}{eur: {}, gbp: {}, chf: {}}, evaluation.Flagged)
+	assert.Empty(t, evaluation.Unevaluated)
+
+	_, err = NewCurrencyAmountProcessor(nil, nil)
+	assert.ErrorIs(t, err, ErrNoAmountThreshold)
+}
+
+func TestTransactionAmountProcessor_AlertDetails_AppliedThreshold(t *testing.T) {
+	userID := uuid.New()
+	processor := TransactionAmountProcessor{
+		Threshold:          decimal.NewFromInt(10000),
+		CountryThresholds:  map[string]decimal.Decimal{"IR": decimal.NewFromInt(100)},
+		CurrencyThresholds: map[string]decimal.Decimal{"GBP": decimal.NewFromInt(8000)},
+	}
+
+	tests := []struct {
+		name          string
+		transactions  []Transaction
+		wantEvidence  int
+		wantThreshold string
+	}{
+		{
+			name:          "country threshold",
+			transactions:  []Transaction{{UserID: userID, Country: "ir", Amount: decimal.NewFromInt(500)}},
+			wantEvidence:  1,
+			wantThreshold: "100",
+		},
+		{
+			name:          "currency threshold",
+			transactions:  []Transaction{{UserID: userID, Currency: "GBP", Amount: decimal.NewFromInt(9000)}},
+			wantEvidence:  1,
+			wantThreshold: "8000",
+		},
+		{
+			name: "several thresholds",
+			transactions: []Transaction{
+				{UserID: userID, Currency: "GBP", Amount: decimal.NewFromInt(9000)},
+				{UserID: userID, Amount: decimal.NewFromInt(20000)},
+				{UserID: userID, Country: "IR", Amount: decimal.NewFromInt(500)},
+				{UserID: userID, Currency: "GBP", Amount: decimal.NewFromInt(8500)},
+			},
+			wantEvidence:  4,
+			wantThreshold: "8000, 10000, 100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evidence, details := processor.AlertDetails(context.Background(), userID, tt.transactions)
+
+			assert.Len(t, evidence, tt.wantEvidence)
+			assert.Equal(t, tt.wantThreshold, details["threshold"])
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// BaselineSpikeProcessor flags users with a transaction far above their own trailing average amount,
+// catching small accounts being drained that absolute thresholds miss
+type BaselineSpikeProcessor struct {
+	// Baseline is how far back before each transaction its trailing average is taken
+	Baseline   time.Duration
+	Multiplier decimal.Decimal
+	// Floor keeps tiny spikes from being flagged, transactions at or below it are never flagged
+	Floor decimal.Decimal
+	// MinHistory is the number of baseline transactions needed for a trailing average
+	MinHistory int
+
+	coldStart *decimal.Decimal
+}
+
+// BaselineSpikeOption configures how BaselineSpikeProcessor treats users without enough history
+type BaselineSpikeOption func(*BaselineSpikeProcessor)
+
+// WithColdStartBaseline evaluates transactions lacking MinHistory against average instead of skipping them
+func WithColdStartBaseline(average decimal.Decimal) BaselineSpikeOption {
+	return func(b *BaselineSpikeProcessor) {
+		b.coldStart = &average
+	}
+}
+
+// NewBaselineSpikeProcessor skips transactions with fewer than minHistory baseline transactions
+// unless WithColdStartBaseline is given
+func NewBaselineSpikeProcessor(baseline time.Duration, multiplier, floor decimal.Decimal, minHistory int, opts ...BaselineSpikeOption) BaselineSpikeProcessor {
+	b := BaselineSpikeProcessor{
+		Baseline:   baseline,
+		Multiplier: multiplier,
+		Floor:      floor,
+		MinHistory: max(minHistory, 1),
+	}
+	for _, opt := range opts {
+		opt(&b)
+	}
+
+	return b
+}
+
+func (b BaselineSpikeProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, velocityOptions{}, nil) {
+		for userID, txs := range userTransactions {
+			sortByCreatedAt(txs)
+
+			if b.hasSpike(txs) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// hasSpike compares each transaction with the average of the transactions before it within Baseline
+// Time complexity: O(n), the baseline is a sliding window over sorted txs
+func (b BaselineSpikeProcessor) hasSpike(txs []Transaction) bool {
+	left := 0
+	sum := decimal.Zero
+
+	for right, tx := range txs {
+		for left < right && tx.CreatedAt.Sub(txs[left].CreatedAt) > b.Baseline {
+			sum = sum.Sub(spendAmount(txs[left]))
+			left++
+		}
+
+		var average decimal.Decimal
+		switch count := right - left; {
+		case count >= b.MinHistory:
+			average = sum.Div(decimal.NewFromInt(int64(count)))
+		case b.coldStart != nil:
+			average = *b.coldStart
+		default:
+			sum = sum.Add(spendAmount(tx))
+			continue
+		}
+
+		if tx.Amount.GreaterThan(b.Floor) && tx.Amount.GreaterThan(average.Mul(b.Multiplier)) {
+			return true
+		}
+		sum = sum.Add(spendAmount(tx))
+	}
+
+	return false
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBaselineSpikeProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	daily := func(amounts ...int64) []Transaction {
+		transactions := make([]Transaction, len(amounts))
+		for i, amount := range amounts {
+			transactions[i] = Transaction{UserID: userID, Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(time.Duration(i) * 24 * time.Hour)}
+		}
+		return transactions
+	}
+
+	tests := []struct {
+		name         string
+		opts         []BaselineSpikeOption
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "steady 50 spender hit with 2000",
+			transactions: daily(50, 50, 50, 50, 50, 2000),
+			wantFlagged:  true,
+		},
+		{
+			name:         "whole history is large amounts",
+			transactions: daily(1800, 2500, 2000, 2200, 1900, 2000),
+			wantFlagged:  false,
+		},
+		{
+			name:         "brand-new user is skipped",
+			transactions: daily(2000),
+			wantFlagged:  false,
+		},
+		{
+			name:         "brand-new user against the cold-start default",
+			opts:         []BaselineSpikeOption{WithColdStartBaseline(decimal.NewFromInt(50))},
+			transactions: daily(2000),
+			wantFlagged:  true,
+		},
+		{
+			name:         "spike below the absolute floor",
+			transactions: daily(5, 5, 5, 5, 5, 400),
+			wantFlagged:  false,
+		},
+		{
+			name:         "small history has aged out of the baseline",
+			transactions: append(daily(50, 50, 50), Transaction{UserID: userID, Amount: decimal.NewFromInt(2000), CreatedAt: baseTime.Add(3 * month)}),
+			wantFlagged:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewBaselineSpikeProcessor(month, decimal.NewFromInt(10), decimal.NewFromInt(500), 3, tt.opts...)
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
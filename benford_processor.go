@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// BenfordProcessor flags users whose first-digit distribution of amounts deviates from Benford's law,
+// a signal of fabricated amounts. The deviation is the chi-squared statistic over digits 1-9.
+type BenfordProcessor struct {
+	// MinTransactions is the number of eligible amounts a user needs to be evaluated
+	MinTransactions int
+	// Threshold on the chi-squared statistic, e.g. 15.51 for p = 0.05 with 8 degrees of freedom
+	Threshold float64
+}
+
+func NewBenfordProcessor(minTransactions int, threshold float64) BenfordProcessor {
+	return BenfordProcessor{
+		MinTransactions: minTransactions,
+		Threshold:       threshold,
+	}
+}
+
+func (b BenfordProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID, deviation := range b.Deviations(ctx, transactions) {
+		if deviation > b.Threshold {
+			flaggedUsers[userID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// Deviations returns the chi-squared statistic of every user with at least MinTransactions amounts
+// of 1 or more in the major currency unit; smaller and zero amounts have no meaningful first digit
+func (b BenfordProcessor) Deviations(_ context.Context, transactions []Transaction) map[uuid.UUID]float64 {
+	histograms := make(map[uuid.UUID]*[10]int)
+	for _, tx := range transactions {
+		digit, ok := firstSignificantDigit(tx.Amount)
+		if !ok {
+			continue
+		}
+
+		histogram, exists := histograms[tx.UserID]
+		if !exists {
+			histogram = new([10]int)
+			histograms[tx.UserID] = histogram
+		}
+		histogram[digit]++
+		histogram[0]++ // total
+	}
+
+	deviations := make(map[uuid.UUID]float64)
+	for userID, histogram := range histograms {
+		total := histogram[0]
+		if total < b.MinTransactions {
+			continue
+		}
+
+		chiSquared := 0.0
+		for digit := 1; digit <= 9; digit++ {
+			expected := float64(total) * math.Log10(1+1/float64(digit))
+			diff := float64(histogram[digit]) - expected
+			chiSquared += diff * diff / expected
+		}
+		deviations[userID] = chiSquared
+	}
+
+	return deviations
+}
+
+// firstSignificantDigit returns the leading digit of |amount|, which must be at least 1
+func firstSignificantDigit(amount decimal.Decimal) (int, bool) {
+	integer := amount.Abs().Truncate(0)
+	if integer.IsZero() {
+		return 0, false
+	}
+
+	return int(integer.String()[0] - '0'), true
+}
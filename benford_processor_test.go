@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBenfordProcessor_Process(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	benfordUser := uuid.New()
+	uniformUser := uuid.New()
+	smallSampleUser := uuid.New()
+
+	var transactions []Transaction
+	for i := 0; i < 1000; i++ {
+		// 10^u with u uniform over whole decades follows Benford's law
+		benford := math.Pow(10, 4*rng.Float64())
+		uniform := float64(1+rng.Intn(9))*1000 + float64(rng.Intn(1000))
+
+		transactions = append(transactions,
+			Transaction{UserID: benfordUser, Amount: decimal.NewFromFloat(benford).Round(2)},
+			Transaction{UserID: uniformUser, Amount: decimal.NewFromFloat(uniform)},
+		)
+	}
+	for i := 0; i < 50; i++ {
+		transactions = append(transactions, Transaction{UserID: smallSampleUser, Amount: decimal.NewFromInt(9000)})
+	}
+	// Excluded amounts do not count toward the minimum nor the histogram
+	for i := 0; i < 100; i++ {
+		transactions = append(transactions,
+			Transaction{UserID: smallSampleUser, Amount: decimal.RequireFromString("0.75")},
+			Transaction{UserID: smallSampleUser, Amount: decimal.Zero},
+		)
+	}
+
+	processor := NewBenfordProcessor(100, 15.51)
+
+	deviations := processor.Deviations(context.Background(), transactions)
+	assert.Less(t, deviations[benfordUser], 15.51)
+	assert.Greater(t, deviations[uniformUser], 15.51)
+	assert.NotContains(t, deviations, smallSampleUser)
+
+	flaggedUsers := processor.Process(context.Background(), transactions)
+	assert.Equal(t, map[uuid.UUID]struct{}{uniformUser: {}}, flaggedUsers)
+}
+
+func TestFirstSignificantDigit(t *testing.T) {
+	tests := []struct {
+		amount    string
+		wantDigit int
+		wantOK    bool
+	}{
+		{"1", 1, true},
+		{"9.99", 9, true},
+		{"42000", 4, true},
+		{"-305.10", 3, true},
+		{"0.99", 0, false},
+		{"0", 0, false},
+	}
+
+	for _, tt := range tests {
+		digit, ok := firstSignificantDigit(decimal.RequireFromString(tt.amount))
+		assert.Equal(t, tt.wantOK, ok, tt.amount)
+		assert.Equal(t, tt.wantDigit, digit, tt.amount)
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+// BurstVelocityProcessor is a VelocityProcessor preset for card-testing style bursts,
+// where many transactions land within minutes or seconds of each other
+type BurstVelocityProcessor struct {
+	VelocityProcessor
+}
+
+// DefaultBurstPeriods flags more than 10 transactions in a minute or more than 25 in five minutes
+var DefaultBurstPeriods = []VelocityPeriod{
+	NewVelocityPeriod(minute, 10),
+	NewVelocityPeriod(5*minute, 25),
+}
+
+// NewBurstVelocityProcessor creates a BurstVelocityProcessor, using DefaultBurstPeriods when periods is empty
+func NewBurstVelocityProcessor(periods []VelocityPeriod, opts ...VelocityOption) BurstVelocityProcessor {
+	if len(periods) == 0 {
+		periods = DefaultBurstPeriods
+	}
+
+	return BurstVelocityProcessor{
+		VelocityProcessor: NewVelocityValidator(periods, opts...),
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBurstVelocityProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	sameTimestamp := func(n int) []Transaction {
+		txs := make([]Transaction, n)
+		for i := range txs {
+			txs[i] = Transaction{UserID: userID, CreatedAt: baseTime}
+		}
+		return txs
+	}
+
+	oneSecondApart := func(n int) []Transaction {
+		txs := make([]Transaction, n)
+		for i := range txs {
+			txs[i] = Transaction{UserID: userID, CreatedAt: baseTime.Add(time.Duration(i) * time.Second)}
+		}
+		return txs
+	}
+
+	oneMinuteApart := func(n int) []Transaction {
+		txs := make([]Transaction, n)
+		for i := range txs {
+			txs[i] = Transaction{UserID: userID, CreatedAt: baseTime.Add(time.Duration(i) * time.Minute)}
+		}
+		return txs
+	}
+
+	tests := []struct {
+		name         string
+		periods      []VelocityPeriod
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "50 identical timestamps above threshold",
+			periods:      []VelocityPeriod{NewVelocityPeriod(time.Second, 49)},
+			transactions: sameTimestamp(50),
+			wantFlagged:  true,
+		},
+		{
+			name:         "50 identical timestamps at threshold",
+			periods:      []VelocityPeriod{NewVelocityPeriod(time.Second, 50)},
+			transactions: sameTimestamp(50),
+			wantFlagged:  false,
+		},
+		{
+			name:         "identical timestamps with zero duration window",
+			periods:      []VelocityPeriod{NewVelocityPeriod(0, 49)},
+			transactions: sameTimestamp(50),
+			wantFlagged:  true,
+		},
+		{
+			// 0s..5s spans exactly the window: six transactions, boundary inclusive
+			name:         "one second apart reaching the window boundary",
+			periods:      []VelocityPeriod{NewVelocityPeriod(5*time.Second, 5)},
+			transactions: oneSecondApart(6),
+			wantFlagged:  true,
+		},
+		{
+			// every 5s window over 0s..9s holds at most five transactions
+			name:         "one second apart straddling the window boundary",
+			periods:      []VelocityPeriod{NewVelocityPeriod(4*time.Second+999*time.Millisecond, 5)},
+			transactions: oneSecondApart(10),
+			wantFlagged:  false,
+		},
+		{
+			name:         "default burst periods",
+			transactions: oneSecondApart(11),
+			wantFlagged:  true,
+		},
+		{
+			name:         "default burst periods spread over an hour",
+			transactions: oneMinuteApart(60),
+			wantFlagged:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewBurstVelocityProcessor(tt.periods)
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestBurstVelocityProcessor_Process_AllImplementations(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	var transactions []Transaction
+	for i := 0; i < 30; i++ {
+		transactions = append(transactions, Transaction{UserID: userID, CreatedAt: baseTime.Add(time.Duration(i*10) * time.Second)})
+	}
+
+	for name, processor := range velocityImplementations(DefaultBurstPeriods) {
+		t.Run(name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), append([]Transaction(nil), transactions...))
+
+			assert.Contains(t, flaggedUsers, userID)
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// AccountResolver maps an account reference of a bank statement, an IBAN or another account
+// identifier, to the user holding the account
+type AccountResolver func(reference string) (uuid.UUID, bool)
+
+// CAMTWarning reports a statement entry LoadTransactionsCAMT053 skipped
+type CAMTWarning struct {
+	Statement string
+	// Entry is the 1-based position of the entry in its statement
+	Entry int
+	// References lists the account references tried for the user, in order
+	References []string
+	Reason     string
+}
+
+func (w CAMTWarning) String() string {
+	return fmt.Sprintf("statement %s: entry %d: %s (tried %q)", w.Statement, w.Entry, w.Reason, w.References)
+}
+
+// camtDocument is the part of an ISO 20022 camt.053 bank-to-customer statement the loader reads.
+// Element names are matched whatever their namespace, so every camt.053.001 version is accepted.
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	ID      string      `xml:"Id"`
+	Account camtAccount `xml:"Acct"`
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtAccount struct {
+	IBAN  string `xml:"Id>IBAN"`
+	Other string `xml:"Id>Othr>Id"`
+}
+
+// reference is the account's IBAN, or its other identifier without one
+func (a camtAccount) reference() string {
+	return strings.TrimSpace(cmp.Or(a.IBAN, a.Other))
+}
+
+type camtEntry struct {
+	Amount         camtAmount        `xml:"Amt"`
+	CreditDebit    string            `xml:"CdtDbtInd"`
+	Reversal       bool              `xml:"RvslInd"`
+	Status         camtStatus        `xml:"Sts"`
+	BookingDate    camtDate          `xml:"BookgDt"`
+	ValueDate      camtDate          `xml:"ValDt"`
+	Details        []camtTransaction `xml:"NtryDtls>TxDtls"`
+	AdditionalInfo string            `xml:"AddtlNtryInf"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+// camtStatus is "BOOK" as <Sts>BOOK</Sts> up to version 06 and <Sts><Cd>BOOK</Cd></Sts> since
+type camtStatus struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+type camtDate struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type camtTransaction struct {
+	Amount       camtAmount  `xml:"Amt"`
+	Debtor       camtParty   `xml:"RltdPties>Dbtr"`
+	DebtorAcct   camtAccount `xml:"RltdPties>DbtrAcct"`
+	Creditor     camtParty   `xml:"RltdPties>Cdtr"`
+	CreditorAcct camtAccount `xml:"RltdPties>CdtrAcct"`
+	Remittance   []string    `xml:"RmtInf>Ustrd"`
+}
+
+// camtParty is a debtor or creditor, whose name and address sit under <Pty> since version 08
+type camtParty struct {
+	Name      string `xml:"Nm"`
+	Country   string `xml:"PstlAdr>Ctry"`
+	PartyName string `xml:"Pty>Nm"`
+	PartyCtry string `xml:"Pty>PstlAdr>Ctry"`
+}
+
+func (p camtParty) name() string    { return strings.TrimSpace(cmp.Or(p.Name, p.PartyName)) }
+func (p camtParty) country() string { return strings.TrimSpace(cmp.Or(p.Country, p.PartyCtry)) }
+
+// LoadTransactionsCAMT053 reads the entries of the statements of a camt.053 XML document, one
+// transaction per transaction detail of an entry, or per entry without details. The user is the
+// account holder's side of each entry: the debtor of a debit (DBIT) and the creditor of a credit
+// (CRDT), whose account reference, else the statement account's, is passed to resolve. Entries no
+// reference resolves are skipped and reported as warnings.
+//
+// Amounts are positive whatever the direction, keeping their currency and precision. A reversal
+// (RvslInd) is marked StatusReversed and a booked entry StatusCompleted. CreatedAt is the booking
+// date, else the value date, a date without a time being midnight UTC. Country is the debtor's country and DestinationCountry the creditor's.
+// Malformed amounts, dates or indicators fail the load with the statement and entry they are in.
+func LoadTransactionsCAMT053(r io.Reader, resolve AccountResolver) ([]Transaction, []CAMTWarning, error) {
+	var document camtDocument
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		return nil, nil, fmt.Errorf("camt.053: %w", err)
+	}
+
+	var transactions []Transaction
+	var warnings []CAMTWarning
+	for _, statement := range document.Statements {
+		for i, entry := range statement.Entries {
+			details := entry.Details
+			if len(details) == 0 {
+				details = []camtTransaction{{}}
+			}
+
+			for _, detail := range details {
+				tx, references, err := camtTransactionFor(statement, entry, detail)
+				if err != nil {
+					return nil, nil, fmt.Errorf("camt.053: statement %s: entry %d: %w", statement.ID, i+1, err)
+				}
+
+				resolved := false
+				for _, reference := range references {
+					if tx.UserID, resolved = resolve(reference); resolved {
+						break
+					}
+				}
+				if !resolved {
+					warnings = append(warnings, CAMTWarning{
+						Statement:  statement.ID,
+						Entry:      i + 1,
+						References: references,
+						Reason:     "no user for the account holder",
+					})
+					continue
+				}
+
+				transactions = append(transactions, tx)
+			}
+		}
+	}
+
+	return transactions, warnings, nil
+}
+
+// camtTransactionFor converts one transaction of an entry, returning the account references of its user
+func camtTransactionFor(statement camtStatement, entry camtEntry, detail camtTransaction) (Transaction, []string, error) {
+	amount := detail.Amount
+	if strings.TrimSpace(amount.Value) == "" {
+		amount = entry.Amount
+	}
+	value, err := decimal.NewFromString(strings.TrimSpace(amount.Value))
+	if err != nil {
+		return Transaction{}, nil, fmt.Errorf("amount: %w", err)
+	}
+
+	createdAt, err := entry.BookingDate.parse()
+	if err == nil && createdAt.IsZero() {
+		createdAt, err = entry.ValueDate.parse()
+	}
+	if err != nil {
+		return Transaction{}, nil, fmt.Errorf("date: %w", err)
+	}
+	if createdAt.IsZero() {
+		return Transaction{}, nil, fmt.Errorf("date: no booking or value date")
+	}
+
+	tx := Transaction{
+		Amount:             value,
+		Currency:           strings.TrimSpace(amount.Currency),
+		Country:            detail.Debtor.country(),
+		DestinationCountry: detail.Creditor.country(),
+		CreatedAt:          createdAt,
+		Description:        strings.TrimSpace(strings.Join(detail.Remittance, " ")),
+	}
+	if tx.Description == "" {
+		tx.Description = strings.TrimSpace(entry.AdditionalInfo)
+	}
+	switch {
+	case entry.Reversal:
+		tx.Status = StatusReversed
+	case strings.TrimSpace(cmp.Or(entry.Status.Code, entry.Status.Text)) == "BOOK":
+		tx.Status = StatusCompleted
+	}
+
+	var user, counterparty camtParty
+	var userAccount, counterpartyAccount camtAccount
+	switch strings.TrimSpace(entry.CreditDebit) {
+	case "DBIT":
+		tx.Direction = DirectionDebit
+		user, userAccount = detail.Debtor, detail.DebtorAcct
+		counterparty, counterpartyAccount = detail.Creditor, detail.CreditorAcct
+	case "CRDT":
+		tx.Direction = DirectionCredit
+		user, userAccount = detail.Creditor, detail.CreditorAcct
+		counterparty, counterpartyAccount = detail.Debtor, detail.DebtorAcct
+	default:
+		return Transaction{}, nil, fmt.Errorf("credit/debit indicator %q", entry.CreditDebit)
+	}
+	tx.UserName = user.name()
+	tx.CounterpartyName = counterparty.name()
+	tx.CounterpartyID = counterpartyAccount.reference()
+	tx.CounterpartyCountry = counterparty.country()
+
+	var references []string
+	for _, reference := range []string{userAccount.reference(), statement.Account.reference()} {
+		if reference != "" && (len(references) == 0 || references[0] != reference) {
+			references = append(references, reference)
+		}
+	}
+
+	return tx, references, nil
+}
+
+// parse reads the date-time, else the date as midnight UTC, the zero time meaning neither is set
+func (d camtDate) parse() (time.Time, error) {
+	if dateTime := strings.TrimSpace(d.DateTime); dateTime != "" {
+		// ISODateTime may omit the zone, in which case UTC is assumed
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+			if t, err := time.Parse(layout, dateTime); err == nil {
+				return t.UTC(), nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("invalid date-time %q", dateTime)
+	}
+	if date := strings.TrimSpace(d.Date); date != "" {
+		return time.Parse(time.DateOnly, date)
+	}
+
+	return time.Time{}, nil
+}
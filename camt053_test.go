@@ -0,0 +1,164 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapResolver resolves the account references of accounts
+func mapResolver(accounts map[string]uuid.UUID) AccountResolver {
+	return func(reference string) (uuid.UUID, bool) {
+		userID, ok := accounts[reference]
+		return userID, ok
+	}
+}
+
+func TestLoadTransactionsCAMT053(t *testing.T) {
+	file, err := os.Open("testdata/camt053/statement.xml")
+	require.NoError(t, err)
+	defer file.Close()
+
+	jane, pierre := uuid.New(), uuid.New()
+	transactions, warnings, err := LoadTransactionsCAMT053(file, mapResolver(map[string]uuid.UUID{
+		"DE89370400440532013000":      jane,
+		"FR1420041010050500013M02606": pierre,
+	}))
+	require.NoError(t, err)
+
+	march1 := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		want   Transaction
+		amount string
+	}{
+		{
+			amount: "9800.55",
+			want: Transaction{
+				UserID: jane, Currency: "EUR", Country: "DE", DestinationCountry: "IR", Status: StatusCompleted,
+				Direction: DirectionDebit, Description: "invoice 2024-113 machine parts", CreatedAt: march1,
+				UserName: "Jane Doe", CounterpartyName: "Tehran Trading Co", CounterpartyID: "IR-0099-7731", CounterpartyCountry: "IR",
+			},
+		},
+		{
+			amount: "1250.00",
+			want: Transaction{
+				UserID: jane, Currency: "EUR", Country: "AT", Status: StatusCompleted, Direction: DirectionCredit,
+				Description: "salary february", CreatedAt: time.Date(2024, 3, 1, 13, 32, 10, 123456789, time.UTC),
+				UserName: "Jane Doe", CounterpartyName: "ACME GmbH", CounterpartyID: "AT611904300234573201", CounterpartyCountry: "AT",
+			},
+		},
+		{
+			amount: "300.10",
+			want: Transaction{
+				UserID: jane, Currency: "EUR", Status: StatusReversed, Direction: DirectionCredit,
+				Description: "RETURN OF CARD PAYMENT", CreatedAt: march1,
+			},
+		},
+		{
+			amount: "2500.00",
+			want: Transaction{
+				UserID: pierre, Currency: "EUR", DestinationCountry: "FR", Status: StatusCompleted, Direction: DirectionDebit,
+				CreatedAt: march1, CounterpartyName: "Supplier One", CounterpartyID: "FR7630006000011234567890189", CounterpartyCountry: "FR",
+			},
+		},
+		{
+			amount: "1500.00",
+			want: Transaction{
+				UserID: pierre, Currency: "EUR", DestinationCountry: "BE", Status: StatusCompleted, Direction: DirectionDebit,
+				CreatedAt: march1, CounterpartyName: "Supplier Two", CounterpartyID: "BE68539007547034", CounterpartyCountry: "BE",
+			},
+		},
+	}
+
+	require.Len(t, transactions, len(tests))
+	for i, tt := range tests {
+		got := transactions[i]
+		assert.Equal(t, tt.amount, got.Amount.StringFixed(got.Amount.Exponent()*-1), "transaction %d amount", i)
+		assert.True(t, tt.want.CreatedAt.Equal(got.CreatedAt), "transaction %d date: %s", i, got.CreatedAt)
+		got.Amount, got.CreatedAt = tt.want.Amount, tt.want.CreatedAt
+		assert.Equal(t, tt.want, got, "transaction %d", i)
+	}
+
+	require.Len(t, warnings, 1)
+	assert.Equal(t, CAMTWarning{
+		Statement:  "STMT-CH-0003",
+		Entry:      1,
+		References: []string{"GB29NWBK60161331926819", "CH-LEGACY-4471"},
+		Reason:     "no user for the account holder",
+	}, warnings[0])
+	assert.Contains(t, warnings[0].String(), "STMT-CH-0003")
+}
+
+func TestLoadTransactionsCAMT053_PrefixedNamespace(t *testing.T) {
+	file, err := os.Open("testdata/camt053/statement_v08.xml")
+	require.NoError(t, err)
+	defer file.Close()
+
+	jan := uuid.New()
+	transactions, warnings, err := LoadTransactionsCAMT053(file, mapResolver(map[string]uuid.UUID{"NL91ABNA0417164300": jan}))
+	require.NoError(t, err)
+
+	assert.Empty(t, warnings)
+	require.Len(t, transactions, 1)
+	tx := transactions[0]
+	assert.Equal(t, jan, tx.UserID)
+	assert.Equal(t, "0.000001", tx.Amount.String())
+	assert.Equal(t, time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC), tx.CreatedAt, "a date-time without a zone is UTC")
+	assert.Equal(t, StatusCompleted, tx.Status)
+	assert.Equal(t, "Jan Jansen", tx.UserName)
+	assert.Equal(t, "NL", tx.Country)
+	assert.Equal(t, "KP", tx.DestinationCountry)
+	assert.Equal(t, "KP", tx.CounterpartyCountry)
+}
+
+func TestLoadTransactionsCAMT053_Errors(t *testing.T) {
+	entry := func(fields string) string {
+		return `<Document><BkToCstmrStmt><Stmt><Id>S1</Id><Ntry>` + fields + `</Ntry></Stmt></BkToCstmrStmt></Document>`
+	}
+	resolveAll := func(string) (uuid.UUID, bool) { return uuid.New(), true }
+
+	tests := []struct {
+		name        string
+		document    string
+		wantMessage string
+	}{
+		{
+			name:        "malformed XML",
+			document:    `<Document><BkToCstmrStmt>`,
+			wantMessage: "camt.053: XML syntax error",
+		},
+		{
+			name:        "malformed amount",
+			document:    entry(`<Amt Ccy="EUR">12,50</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>2024-03-01</Dt></BookgDt>`),
+			wantMessage: "statement S1: entry 1: amount:",
+		},
+		{
+			name:        "malformed date",
+			document:    entry(`<Amt Ccy="EUR">12.50</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>01/03/2024</Dt></BookgDt>`),
+			wantMessage: "statement S1: entry 1: date:",
+		},
+		{
+			name:        "missing date",
+			document:    entry(`<Amt Ccy="EUR">12.50</Amt><CdtDbtInd>DBIT</CdtDbtInd>`),
+			wantMessage: "no booking or value date",
+		},
+		{
+			name:        "unknown indicator",
+			document:    entry(`<Amt Ccy="EUR">12.50</Amt><CdtDbtInd>BOTH</CdtDbtInd><BookgDt><Dt>2024-03-01</Dt></BookgDt>`),
+			wantMessage: `credit/debit indicator "BOTH"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := LoadTransactionsCAMT053(strings.NewReader(tt.document), resolveAll)
+
+			assert.ErrorContains(t, err, tt.wantMessage)
+		})
+	}
+}
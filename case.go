@@ -0,0 +1,105 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Case gathers every alert raised on one user in a run, for investigation as a whole
+type Case struct {
+	UserID uuid.UUID `json:"user_id"`
+	// Evidence is the union of the alerts' evidence, each transaction once, ordered by CreatedAt
+	Evidence []Transaction `json:"evidence"`
+	Rules    []CaseRule    `json:"rules"`
+	// Severity is the highest severity among Rules
+	Severity Severity `json:"severity"`
+	// TotalAmount sums the amounts of Evidence, reversals included
+	TotalAmount decimal.Decimal `json:"total_amount"`
+}
+
+// CaseRule is one rule contributing to a case
+type CaseRule struct {
+	RuleName string   `json:"rule_name"`
+	Severity Severity `json:"severity"`
+}
+
+// GroupAlerts merges the alerts of each user into one case. Cases are ordered by user ID and their rules
+// by name, so identical alerts produce identical cases whatever their order.
+func GroupAlerts(alerts []Alert) []Case {
+	byUser := make(map[uuid.UUID][]Alert)
+	for _, alert := range alerts {
+		byUser[alert.UserID] = append(byUser[alert.UserID], alert)
+	}
+
+	cases := make([]Case, 0, len(byUser))
+	for userID, userAlerts := range byUser {
+		c := Case{UserID: userID, Severity: userAlerts[0].Severity, TotalAmount: decimal.Zero}
+
+		seen := make(map[string]struct{})
+		for _, alert := range userAlerts {
+			c.Rules = append(c.Rules, CaseRule{RuleName: alert.RuleName, Severity: alert.Severity})
+			c.Severity = maxSeverity(c.Severity, alert.Severity)
+
+			for _, tx := range alert.Evidence {
+				key := transactionKey(tx)
+				if _, duplicate := seen[key]; duplicate {
+					continue
+				}
+				seen[key] = struct{}{}
+
+				c.Evidence = append(c.Evidence, tx)
+				c.TotalAmount = c.TotalAmount.Add(tx.Amount)
+			}
+		}
+
+		slices.SortFunc(c.Rules, func(a, b CaseRule) int {
+			return cmp.Or(
+				strings.Compare(a.RuleName, b.RuleName),
+				cmp.Compare(severityIndex(a.Severity), severityIndex(b.Severity)),
+			)
+		})
+		slices.SortFunc(c.Evidence, func(a, b Transaction) int {
+			return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), strings.Compare(transactionKey(a), transactionKey(b)))
+		})
+
+		cases = append(cases, c)
+	}
+
+	slices.SortFunc(cases, func(a, b Case) int {
+		return compareUserIDs(a.UserID, b.UserID)
+	})
+
+	return cases
+}
+
+// transactionKey identifies a transaction by its TransactionID, or by every field when it has none.
+// Free-text fields are quoted so a separator inside one cannot make two transactions collide.
+func transactionKey(tx Transaction) string {
+	if tx.TransactionID != uuid.Nil {
+		return tx.TransactionID.String()
+	}
+
+	return fmt.Sprintf("%s|%s|%s|%q|%q|%q|%q|%q|%q|%q|%q|%d|%q|%q|%q|%q",
+		tx.UserID, tx.AccountID, tx.Amount, tx.Currency, tx.Country, tx.DestinationCountry,
+		tx.Status, tx.Direction, tx.Channel, tx.Category, tx.Description, tx.CreatedAt.UnixNano(),
+		tx.UserName, tx.CounterpartyName, tx.CounterpartyID, tx.CounterpartyCountry)
+}
+
+// severityIndex ranks a severity, unknown ones ranking below low
+func severityIndex(s Severity) int {
+	return slices.Index(severityRank, s)
+}
+
+// maxSeverity returns the more urgent of a and b
+func maxSeverity(a, b Severity) Severity {
+	if severityIndex(b) > severityIndex(a) {
+		return b
+	}
+
+	return a
+}
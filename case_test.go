@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupAlerts(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	userID, other := uuid.New(), uuid.New()
+
+	first := Transaction{TransactionID: uuid.New(), UserID: userID, Amount: decimal.NewFromInt(100), CreatedAt: baseTime}
+	second := Transaction{TransactionID: uuid.New(), UserID: userID, Amount: decimal.NewFromInt(250), CreatedAt: baseTime.Add(time.Hour)}
+	third := Transaction{TransactionID: uuid.New(), UserID: userID, Country: "IR", Amount: decimal.NewFromInt(50), CreatedAt: baseTime.Add(2 * time.Hour)}
+	anonymous := Transaction{UserID: other, Amount: decimal.NewFromInt(20000), CreatedAt: baseTime}
+
+	alerts := []Alert{
+		{UserID: userID, RuleName: "VelocityProcessor", Severity: SeverityMedium, Evidence: []Transaction{third, second, first}},
+		{UserID: other, RuleName: "TransactionAmountProcessor", Severity: SeverityMedium, Evidence: []Transaction{anonymous}},
+		{UserID: userID, RuleName: "CountryBlackListProcessor", Severity: SeverityCritical, Evidence: []Transaction{third}},
+		{UserID: other, RuleName: "StructuringProcessor", Severity: SeverityLow, Evidence: []Transaction{anonymous}},
+		{UserID: userID, RuleName: "DuplicateTransactionProcessor", Severity: SeverityLow, Evidence: []Transaction{first, second}},
+	}
+
+	cases := GroupAlerts(alerts)
+	require.Len(t, cases, 2)
+
+	byUser := map[uuid.UUID]Case{cases[0].UserID: cases[0], cases[1].UserID: cases[1]}
+	assert.Less(t, cases[0].UserID.String(), cases[1].UserID.String())
+
+	c := byUser[userID]
+	assert.Equal(t, []Transaction{first, second, third}, c.Evidence, "overlapping evidence is kept once, in time order")
+	assert.Equal(t, []CaseRule{
+		{RuleName: "CountryBlackListProcessor", Severity: SeverityCritical},
+		{RuleName: "DuplicateTransactionProcessor", Severity: SeverityLow},
+		{RuleName: "VelocityProcessor", Severity: SeverityMedium},
+	}, c.Rules)
+	assert.Equal(t, SeverityCritical, c.Severity)
+	assert.Equal(t, "400", c.TotalAmount.String())
+
+	c = byUser[other]
+	assert.Equal(t, []Transaction{anonymous}, c.Evidence, "transactions without an ID are matched by content")
+	assert.Equal(t, SeverityMedium, c.Severity)
+	assert.Equal(t, "20000", c.TotalAmount.String())
+
+	reversed := []Alert{alerts[4], alerts[3], alerts[2], alerts[1], alerts[0]}
+	assert.Equal(t, cases, GroupAlerts(reversed), "grouping does not depend on alert order")
+
+	assert.Empty(t, GroupAlerts(nil))
+}
+
+func TestTransactionKey(t *testing.T) {
+	base := Transaction{UserID: uuid.New(), Amount: decimal.NewFromInt(100), CreatedAt: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name   string
+		modify func(*Transaction)
+	}{
+		{name: "channel", modify: func(tx *Transaction) { tx.Channel = ChannelWire }},
+		{name: "status", modify: func(tx *Transaction) { tx.Status = StatusReversed }},
+		{name: "category", modify: func(tx *Transaction) { tx.Category = "7995" }},
+		{name: "counterparty country", modify: func(tx *Transaction) { tx.CounterpartyCountry = "IR" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			other := base
+			tt.modify(&other)
+
+			assert.NotEqual(t, transactionKey(base), transactionKey(other))
+		})
+	}
+
+	shifted := base
+	shifted.Description, shifted.CounterpartyID = "a", "b"
+	split := base
+	split.Description, split.CounterpartyID = "a|b", ""
+	assert.NotEqual(t, transactionKey(shifted), transactionKey(split))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// CategorySpendProcessor flags users spending too much in target merchant categories within Window,
+// e.g. more than 2000 on gambling in 7 days
+type CategorySpendProcessor struct {
+	Categories map[string]struct{}
+	Window     time.Duration
+	// CountThreshold flags more than this many target transactions within Window, 0 disables it
+	CountThreshold int
+	// AmountThreshold flags a target sum above it within Window, zero disables it
+	AmountThreshold decimal.Decimal
+	// FlagUnknown counts transactions without a Category as target spend, they are ignored otherwise
+	FlagUnknown bool
+}
+
+func NewCategorySpendProcessor(categories []string, window time.Duration, countThreshold int, amountThreshold decimal.Decimal) CategorySpendProcessor {
+	set := make(map[string]struct{}, len(categories))
+	for _, category := range categories {
+		set[category] = struct{}{}
+	}
+
+	return CategorySpendProcessor{
+		Categories:      set,
+		Window:          window,
+		CountThreshold:  countThreshold,
+		AmountThreshold: amountThreshold,
+	}
+}
+
+func (c CategorySpendProcessor) isTarget(tx Transaction) bool {
+	if tx.Category == "" {
+		return c.FlagUnknown
+	}
+
+	_, ok := c.Categories[tx.Category]
+	return ok
+}
+
+func (c CategorySpendProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID := range c.Breakdowns(ctx, transactions) {
+		flaggedUsers[userID] = struct{}{}
+	}
+
+	return flaggedUsers
+}
+
+// Breakdowns returns, per flagged user, the target spend per category within the first window
+// breaching a threshold. Unknown categories are reported under "".
+func (c CategorySpendProcessor) Breakdowns(_ context.Context, transactions []Transaction) map[uuid.UUID]map[string]decimal.Decimal {
+	options := velocityOptions{filter: c.isTarget}
+	breakdowns := make(map[uuid.UUID]map[string]decimal.Decimal)
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			sortByCreatedAt(txs)
+
+			if window, ok := c.firstBreach(txs); ok {
+				breakdown := make(map[string]decimal.Decimal)
+				for _, tx := range window {
+					breakdown[tx.Category] = breakdown[tx.Category].Add(spendAmount(tx))
+				}
+				breakdowns[userID] = breakdown
+			}
+		}
+	}
+
+	return breakdowns
+}
+
+// firstBreach slides Window over sorted txs and returns the first window breaching a threshold
+// Time complexity: O(n) where n is the number of target transactions for a user
+func (c CategorySpendProcessor) firstBreach(txs []Transaction) ([]Transaction, bool) {
+	left := 0
+	sum := decimal.Zero
+
+	for right := range txs {
+		sum = sum.Add(spendAmount(txs[right]))
+
+		for txs[right].CreatedAt.Sub(txs[left].CreatedAt) > c.Window {
+			sum = sum.Sub(spendAmount(txs[left]))
+			left++
+		}
+
+		if c.CountThreshold > 0 && right-left+1 > c.CountThreshold {
+			return txs[left : right+1], true
+		}
+		if c.AmountThreshold.IsPositive() && sum.GreaterThan(c.AmountThreshold) {
+			return txs[left : right+1], true
+		}
+	}
+
+	return nil, false
+}
+
+func (c CategorySpendProcessor) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	var evidence []Transaction
+	for _, tx := range transactions {
+		if c.isTarget(tx) {
+			evidence = append(evidence, tx)
+		}
+	}
+
+	details := map[string]string{"window": formatDuration(c.Window)}
+	for category, spend := range c.Breakdowns(ctx, transactions)[userID] {
+		details["category:"+category] = spend.String()
+	}
+
+	return evidence, details
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCategorySpendProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	tx := func(days int, category string, amount int64) Transaction {
+		return Transaction{UserID: userID, Category: category, Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(time.Duration(days) * 24 * time.Hour)}
+	}
+
+	tests := []struct {
+		name         string
+		flagUnknown  bool
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "target spend above the threshold",
+			transactions: []Transaction{tx(0, "gambling", 1200), tx(3, "7995", 900)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "only the target portion of mixed spend counts",
+			transactions: []Transaction{tx(0, "gambling", 1200), tx(1, "groceries", 5000), tx(2, "travel", 3000), tx(3, "gambling", 700)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "target spend spread beyond the window",
+			transactions: []Transaction{tx(0, "gambling", 1200), tx(10, "gambling", 1200)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "unknown categories ignored",
+			transactions: []Transaction{tx(0, "gambling", 1200), tx(1, "", 1200)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "unknown categories counted with FlagUnknown",
+			flagUnknown:  true,
+			transactions: []Transaction{tx(0, "gambling", 1200), tx(1, "", 1200)},
+			wantFlagged:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewCategorySpendProcessor([]string{"gambling", "7995"}, week, 0, decimal.NewFromInt(2000))
+			processor.FlagUnknown = tt.flagUnknown
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestCategorySpendProcessor_Breakdowns(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, Category: "gambling", Amount: decimal.NewFromInt(800), CreatedAt: baseTime},
+		{UserID: userID, Category: "groceries", Amount: decimal.NewFromInt(5000), CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID, Category: "7995", Amount: decimal.NewFromInt(300), CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: userID, Category: "gambling", Amount: decimal.NewFromInt(1000), CreatedAt: baseTime.Add(3 * time.Hour)},
+	}
+
+	processor := NewCategorySpendProcessor([]string{"gambling", "7995"}, week, 0, decimal.NewFromInt(2000))
+
+	breakdowns := processor.Breakdowns(context.Background(), transactions)
+
+	assert.Len(t, breakdowns, 1)
+	assert.True(t, breakdowns[userID]["gambling"].Equal(decimal.NewFromInt(1800)))
+	assert.True(t, breakdowns[userID]["7995"].Equal(decimal.NewFromInt(300)))
+	assert.NotContains(t, breakdowns[userID], "groceries")
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ChannelLimit caps a user's activity on one channel within a window
+type ChannelLimit struct {
+	// Count flags more than this many transactions, 0 disables it
+	Count int
+	// Amount flags a sum above it, zero disables it
+	Amount decimal.Decimal
+}
+
+// ChannelRiskProcessor flags users exceeding the limit of any configured high-risk channel within
+// Window. Channels without a limit are not evaluated.
+type ChannelRiskProcessor struct {
+	Limits map[Channel]ChannelLimit
+	Window time.Duration
+}
+
+func NewChannelRiskProcessor(limits map[Channel]ChannelLimit, window time.Duration) ChannelRiskProcessor {
+	return ChannelRiskProcessor{
+		Limits: limits,
+		Window: window,
+	}
+}
+
+func (c ChannelRiskProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		_, ok := c.Limits[tx.Channel]
+		return ok
+	}}
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			// sorting by channel then time leaves each channel's run ordered for the windows
+			slices.SortFunc(txs, func(a, b Transaction) int {
+				return cmp.Or(cmp.Compare(a.Channel, b.Channel), a.CreatedAt.Compare(b.CreatedAt))
+			})
+
+			for start := 0; start < len(txs); {
+				end := start + 1
+				for end < len(txs) && txs[end].Channel == txs[start].Channel {
+					end++
+				}
+
+				if c.exceedsLimit(txs[start:end], c.Limits[txs[start].Channel]) {
+					flaggedUsers[userID] = struct{}{}
+					break
+				}
+				start = end
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// exceedsLimit checks one channel's sorted transactions against its limit
+func (c ChannelRiskProcessor) exceedsLimit(txs []Transaction, limit ChannelLimit) bool {
+	if limit.Count > 0 {
+		checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(c.Window, limit.Count)}, velocityOptions{sortedInput: true})
+		if violated, _ := checker.CheckUser(txs); violated {
+			return true
+		}
+	}
+
+	return limit.Amount.IsPositive() && exceedsWindowAmount(txs, c.Window, limit.Amount, AmountPolicy{})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChannelRiskProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	tx := func(days int, channel Channel, amount int64) Transaction {
+		return Transaction{UserID: userID, Channel: channel, Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(time.Duration(days) * 24 * time.Hour)}
+	}
+
+	limits := map[Channel]ChannelLimit{
+		ChannelCash:   {Count: 3},
+		ChannelCrypto: {Amount: decimal.NewFromInt(10000)},
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name: "only the cash limit is breached",
+			transactions: []Transaction{
+				tx(0, ChannelCash, 500), tx(1, ChannelCard, 50), tx(2, ChannelCash, 500), tx(3, ChannelCrypto, 4000),
+				tx(3, ChannelCash, 500), tx(4, ChannelWire, 9000), tx(5, ChannelCash, 500), tx(5, ChannelCrypto, 4000),
+			},
+			wantFlagged: true,
+		},
+		{
+			name: "many transactions spread over channels",
+			transactions: []Transaction{
+				tx(0, ChannelCash, 500), tx(1, ChannelCard, 50), tx(2, ChannelCash, 500), tx(3, ChannelCrypto, 4000),
+				tx(3, ChannelCard, 50), tx(4, ChannelWire, 9000), tx(5, ChannelCash, 500), tx(5, ChannelCrypto, 4000),
+			},
+			wantFlagged: false,
+		},
+		{
+			name:         "crypto amount limit",
+			transactions: []Transaction{tx(0, ChannelCrypto, 6000), tx(2, ChannelCrypto, 6000)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "unlimited channels are not evaluated",
+			transactions: []Transaction{tx(0, ChannelCard, 50), tx(0, ChannelCard, 50), tx(0, ChannelCard, 50), tx(0, ChannelCard, 50), tx(0, ChannelWire, 50000)},
+			wantFlagged:  false,
+		},
+	}
+
+	processor := NewChannelRiskProcessor(limits, week)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestVelocityProcessor_Process_OnChannels(t *testing.T) {
+	baseTime := time.Now()
+	userID := uuid.New()
+	cashDeposits := []VelocityPeriod{NewVelocityPeriod(week, 3)}
+
+	transactions := []Transaction{
+		{UserID: userID, Channel: ChannelCash, Status: StatusCompleted, CreatedAt: baseTime},
+		{UserID: userID, Channel: ChannelCard, CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID, Channel: ChannelCash, CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: userID, Channel: ChannelCard, CreatedAt: baseTime.Add(3 * time.Hour)},
+		{UserID: userID, Channel: ChannelCash, CreatedAt: baseTime.Add(4 * time.Hour)},
+		{UserID: userID, Channel: ChannelCash, Status: StatusReversed, CreatedAt: baseTime.Add(5 * time.Hour)},
+	}
+
+	overall := NewVelocityValidator(cashDeposits)
+	cash := NewVelocityValidator(cashDeposits, WithTransactionFilter(OnChannels(ChannelCash)))
+	settledCash := NewVelocityValidator(cashDeposits, WithTransactionFilter(OnChannels(ChannelCash)), WithTransactionFilter(ExcludeReversals))
+
+	assert.Contains(t, overall.Process(context.Background(), append([]Transaction(nil), transactions...)), userID)
+	assert.Contains(t, cash.Process(context.Background(), append([]Transaction(nil), transactions...)), userID)
+	assert.Empty(t, settledCash.Process(context.Background(), append([]Transaction(nil), transactions...)), "filters combine")
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// CircularFlowProcessor flags users taking part in a payment cycle such as A pays B, B pays C and
+// C pays A within Window. A transaction is an edge from its UserID to its CounterpartyID, which
+// matches another user when it holds that user's UserID in any form uuid.Parse accepts.
+type CircularFlowProcessor struct {
+	Window time.Duration
+	// MaxLength is the longest cycle searched for, in payments
+	MaxLength int
+	// MinEdgeAmount ignores payments below it
+	MinEdgeAmount decimal.Decimal
+}
+
+func NewCircularFlowProcessor(window time.Duration, maxLength int, minEdgeAmount decimal.Decimal) CircularFlowProcessor {
+	return CircularFlowProcessor{
+		Window:        window,
+		MaxLength:     maxLength,
+		MinEdgeAmount: minEdgeAmount,
+	}
+}
+
+func (c CircularFlowProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	// parallel payments between the same two users collapse into one edge holding their times
+	edges := make(map[uuid.UUID]map[uuid.UUID][]time.Time)
+	for _, tx := range transactions {
+		if tx.CounterpartyID == "" || tx.Amount.LessThan(c.MinEdgeAmount) {
+			continue
+		}
+		to, err := uuid.Parse(tx.CounterpartyID)
+		if err != nil {
+			continue
+		}
+		if edges[tx.UserID] == nil {
+			edges[tx.UserID] = make(map[uuid.UUID][]time.Time)
+		}
+		edges[tx.UserID][to] = append(edges[tx.UserID][to], tx.CreatedAt)
+	}
+	for _, outgoing := range edges {
+		for _, times := range outgoing {
+			slices.SortFunc(times, time.Time.Compare)
+		}
+	}
+
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	for origin := range edges {
+		if _, ok := flaggedUsers[origin]; ok {
+			continue
+		}
+
+		search := cycleSearch{processor: c, edges: edges, visited: map[uuid.UUID]struct{}{origin: {}}}
+		if search.extend(origin, origin) {
+			for _, hop := range search.path {
+				flaggedUsers[hop.from] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// CrossUser is true, a user being flagged on the payments of the other users of a cycle
+func (c CircularFlowProcessor) CrossUser() bool {
+	return true
+}
+
+// cycleHop is one edge of a searched path with the sorted times of its payments
+type cycleHop struct {
+	from  uuid.UUID
+	times []time.Time
+}
+
+// cycleSearch looks for a cycle through one user that can be paid within Window, picking one payment
+// per edge. The search branches on distinct counterparties rather than on payments, and the depth
+// limit of MaxLength bounds it on dense graphs.
+type cycleSearch struct {
+	processor CircularFlowProcessor
+	edges     map[uuid.UUID]map[uuid.UUID][]time.Time
+	path      []cycleHop
+	visited   map[uuid.UUID]struct{}
+}
+
+// extend continues the path from node, depth-first, until it returns to origin
+func (s *cycleSearch) extend(origin, node uuid.UUID) bool {
+	if len(s.path) >= s.processor.MaxLength {
+		return false
+	}
+
+	for to, times := range s.edges[node] {
+		if _, ok := s.visited[to]; ok && to != origin {
+			continue
+		}
+
+		s.path = append(s.path, cycleHop{from: node, times: times})
+		if s.withinWindow() {
+			if to == origin {
+				return true
+			}
+			s.visited[to] = struct{}{}
+			found := s.extend(origin, to)
+			delete(s.visited, to)
+			if found {
+				return true
+			}
+		}
+		s.path = s.path[:len(s.path)-1]
+	}
+
+	return false
+}
+
+// withinWindow reports whether one payment of every hop on the path falls within Window of the
+// earliest of them. Each payment is tried as that earliest one, so this is O(p*h*log p) for p
+// payments over h hops.
+func (s *cycleSearch) withinWindow() bool {
+	for _, anchor := range s.path {
+		for _, start := range anchor.times {
+			end := start.Add(s.processor.Window)
+			if s.coveredFrom(start, end) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// coveredFrom reports whether every hop has a payment in [start, end]
+func (s *cycleSearch) coveredFrom(start, end time.Time) bool {
+	for _, hop := range s.path {
+		i, _ := slices.BinarySearchFunc(hop.times, start, time.Time.Compare)
+		if i == len(hop.times) || hop.times[i].After(end) {
+			return false
+		}
+	}
+
+	return true
+}
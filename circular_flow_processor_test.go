@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircularFlowProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	users := make([]uuid.UUID, 5)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+
+	pay := func(from, to int, days int, amount int64) Transaction {
+		return Transaction{UserID: users[from], CounterpartyID: users[to].String(), Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(time.Duration(days) * 24 * time.Hour)}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantUsers    []uuid.UUID
+	}{
+		{
+			name:         "planted 3-cycle",
+			transactions: []Transaction{pay(0, 1, 0, 5000), pay(1, 2, 1, 4900), pay(2, 0, 2, 4800), pay(3, 4, 1, 5000)},
+			wantUsers:    users[:3],
+		},
+		{
+			name:         "chain without a cycle",
+			transactions: []Transaction{pay(0, 1, 0, 5000), pay(1, 2, 1, 4900), pay(2, 3, 2, 4800), pay(3, 4, 3, 4700)},
+			wantUsers:    nil,
+		},
+		{
+			name:         "5-cycle longer than the maximum length",
+			transactions: []Transaction{pay(0, 1, 0, 5000), pay(1, 2, 1, 4900), pay(2, 3, 2, 4800), pay(3, 4, 3, 4700), pay(4, 0, 4, 4600)},
+			wantUsers:    nil,
+		},
+		{
+			name:         "cycle spread beyond the window",
+			transactions: []Transaction{pay(0, 1, 0, 5000), pay(1, 2, 5, 4900), pay(2, 0, 10, 4800)},
+			wantUsers:    nil,
+		},
+		{
+			name:         "cycle closed by a payment below the minimum edge amount",
+			transactions: []Transaction{pay(0, 1, 0, 5000), pay(1, 2, 1, 4900), pay(2, 0, 2, 50)},
+			wantUsers:    nil,
+		},
+		{
+			name:         "cycle closed by an upper-case counterparty ID",
+			transactions: []Transaction{pay(0, 1, 0, 5000), pay(1, 2, 1, 4900), {UserID: users[2], CounterpartyID: strings.ToUpper(users[0].String()), Amount: decimal.NewFromInt(4800), CreatedAt: baseTime}},
+			wantUsers:    users[:3],
+		},
+		{
+			name:         "cycle closed by a later parallel payment",
+			transactions: []Transaction{pay(0, 1, 0, 5000), pay(1, 2, 20, 4900), pay(0, 1, 19, 5000), pay(2, 0, 21, 4800)},
+			wantUsers:    users[:3],
+		},
+		{
+			name:         "cycle through an external counterparty is not closed",
+			transactions: []Transaction{pay(0, 1, 0, 5000), {UserID: users[1], CounterpartyID: "external", Amount: decimal.NewFromInt(4900), CreatedAt: baseTime}},
+			wantUsers:    nil,
+		},
+	}
+
+	processor := NewCircularFlowProcessor(week, 4, decimal.NewFromInt(1000))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			assert.Len(t, flaggedUsers, len(tt.wantUsers))
+			for _, wantUser := range tt.wantUsers {
+				assert.Contains(t, flaggedUsers, wantUser)
+			}
+		})
+	}
+}
+
+func TestCircularFlowProcessor_Process_DenseGraph(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	users := make([]uuid.UUID, 30)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+
+	// a complete graph paying forward only: many paths, no cycle
+	var transactions []Transaction
+	for from := range users {
+		for to := from + 1; to < len(users); to++ {
+			transactions = append(transactions, Transaction{UserID: users[from], CounterpartyID: users[to].String(), Amount: decimal.NewFromInt(5000), CreatedAt: baseTime})
+		}
+	}
+
+	assert.Empty(t, NewCircularFlowProcessor(week, 4, decimal.NewFromInt(1000)).Process(context.Background(), transactions))
+}
+
+func TestCircularFlowProcessor_Process_ParallelPayments(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	users := make([]uuid.UUID, 12)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+
+	// users pay forward only, 50 times over each edge: the search must branch on counterparties, not payments
+	var transactions []Transaction
+	for from := range users {
+		for to := from + 1; to < len(users); to++ {
+			for i := range 50 {
+				transactions = append(transactions, Transaction{UserID: users[from], CounterpartyID: users[to].String(), Amount: decimal.NewFromInt(5000), CreatedAt: baseTime.Add(time.Duration(i) * time.Minute)})
+			}
+		}
+	}
+	processor := NewCircularFlowProcessor(week, 5, decimal.NewFromInt(1000))
+
+	assert.Empty(t, processor.Process(context.Background(), transactions))
+
+	closing := Transaction{UserID: users[3], CounterpartyID: users[0].String(), Amount: decimal.NewFromInt(5000), CreatedAt: baseTime}
+	flaggedUsers := processor.Process(context.Background(), append(transactions, closing))
+
+	assert.Len(t, flaggedUsers, 4)
+	for _, user := range users[:4] {
+		assert.Contains(t, flaggedUsers, user)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// AndProcessor flags users flagged by every child. Without children it flags nobody.
+type AndProcessor struct {
+	Children []RuleProcessor
+	// Concurrent runs the children in parallel over the same transactions
+	Concurrent bool
+}
+
+func NewAndProcessor(children ...RuleProcessor) AndProcessor {
+	return AndProcessor{Children: children}
+}
+
+func (a AndProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	results := runChildren(ctx, a.Children, transactions, a.Concurrent)
+	if len(results) == 0 {
+		return make(map[uuid.UUID]struct{})
+	}
+
+	flaggedUsers := FlaggedUsersOf(results[0])
+	for _, result := range results[1:] {
+		flaggedUsers = flaggedUsers.Intersect(FlaggedUsersOf(result))
+	}
+
+	return flaggedUsers.Set()
+}
+
+// CrossUser is true when any child needs other users' transactions
+func (a AndProcessor) CrossUser() bool {
+	return slices.ContainsFunc(a.Children, crossUser)
+}
+
+// OrProcessor flags users flagged by any child
+type OrProcessor struct {
+	Children []RuleProcessor
+	// Concurrent runs the children in parallel over the same transactions
+	Concurrent bool
+}
+
+func NewOrProcessor(children ...RuleProcessor) OrProcessor {
+	return OrProcessor{Children: children}
+}
+
+func (o OrProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	var flaggedUsers FlaggedUsers
+	for _, result := range runChildren(ctx, o.Children, transactions, o.Concurrent) {
+		flaggedUsers = flaggedUsers.Union(FlaggedUsersOf(result))
+	}
+
+	return flaggedUsers.Set()
+}
+
+// CrossUser is true when any child needs other users' transactions
+func (o OrProcessor) CrossUser() bool {
+	return slices.ContainsFunc(o.Children, crossUser)
+}
+
+// NotProcessor flags the users of the batch that Child does not flag
+type NotProcessor struct {
+	Child RuleProcessor
+}
+
+func NewNotProcessor(child RuleProcessor) NotProcessor {
+	return NotProcessor{Child: child}
+}
+
+func (n NotProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	excluded := ProcessFlaggedUsers(ctx, n.Child, transactions)
+
+	userIDs := make([]uuid.UUID, len(transactions))
+	for i, tx := range transactions {
+		userIDs[i] = tx.UserID
+	}
+
+	return NewFlaggedUsers(userIDs...).Difference(excluded).Set()
+}
+
+func (n NotProcessor) CrossUser() bool {
+	return crossUser(n.Child)
+}
+
+// ExceptProcessor flags users flagged by Include but not by Exclude
+type ExceptProcessor struct {
+	Include RuleProcessor
+	Exclude RuleProcessor
+	// Concurrent runs both children in parallel over the same transactions
+	Concurrent bool
+}
+
+func NewExceptProcessor(include, exclude RuleProcessor) ExceptProcessor {
+	return ExceptProcessor{Include: include, Exclude: exclude}
+}
+
+func (e ExceptProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	results := runChildren(ctx, []RuleProcessor{e.Include, e.Exclude}, transactions, e.Concurrent)
+
+	return FlaggedUsersOf(results[0]).Difference(FlaggedUsersOf(results[1])).Set()
+}
+
+// CrossUser is true when either child needs other users' transactions
+func (e ExceptProcessor) CrossUser() bool {
+	return crossUser(e.Include) || crossUser(e.Exclude)
+}
+
+// runChildren returns each child's flagged set in children order
+func runChildren(ctx context.Context, children []RuleProcessor, transactions []Transaction, concurrent bool) []map[uuid.UUID]struct{} {
+	results := make([]map[uuid.UUID]struct{}, len(children))
+
+	if !concurrent {
+		for i, child := range children {
+			results[i] = child.Process(ctx, transactions)
+		}
+		return results
+	}
+
+	var wg sync.WaitGroup
+	for i, child := range children {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = child.Process(ctx, transactions)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombinatorProcessors_Process(t *testing.T) {
+	// big: amount above 1000, iran: blacklisted country, korea: other blacklisted country
+	bigIran, bigOnly, iranOnly, korea, neither := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	transactions := []Transaction{
+		{UserID: bigIran, Country: "IR", Amount: decimal.NewFromInt(5000)},
+		{UserID: bigOnly, Country: "FR", Amount: decimal.NewFromInt(5000)},
+		{UserID: iranOnly, Country: "IR", Amount: decimal.NewFromInt(50)},
+		{UserID: korea, Country: "KP", Amount: decimal.NewFromInt(50)},
+		{UserID: neither, Country: "FR", Amount: decimal.NewFromInt(50)},
+	}
+
+	big := TransactionAmountProcessor{Threshold: decimal.NewFromInt(1000)}
+	iran := NewCountryBlackListProcessor("IR")
+	korean := NewCountryBlackListProcessor("KP")
+	users := func(userIDs ...uuid.UUID) map[uuid.UUID]struct{} {
+		set := make(map[uuid.UUID]struct{})
+		for _, userID := range userIDs {
+			set[userID] = struct{}{}
+		}
+		return set
+	}
+
+	tests := []struct {
+		name      string
+		processor RuleProcessor
+		want      map[uuid.UUID]struct{}
+	}{
+		{"and", NewAndProcessor(iran, big), users(bigIran)},
+		{"or", NewOrProcessor(iran, big), users(bigIran, bigOnly, iranOnly)},
+		{"not", NewNotProcessor(big), users(iranOnly, korea, neither)},
+		{"except", NewExceptProcessor(iran, big), users(iranOnly)},
+		{"nested and of or and not", NewAndProcessor(NewOrProcessor(iran, korean), NewNotProcessor(big)), users(iranOnly, korea)},
+		{"concurrent nested", AndProcessor{Children: []RuleProcessor{OrProcessor{Children: []RuleProcessor{iran, korean, big}, Concurrent: true}, NewNotProcessor(iran)}, Concurrent: true}, users(bigOnly, korea)},
+		{"and without children", NewAndProcessor(), users()},
+		{"or without children", NewOrProcessor(), users()},
+		{"and with one child", NewAndProcessor(big), users(bigIran, bigOnly)},
+		{"and with a child flagging nobody", NewAndProcessor(big, NewCountryBlackListProcessor()), users()},
+		{"except nothing", NewExceptProcessor(big, NewCountryBlackListProcessor()), users(bigIran, bigOnly)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.processor.Process(context.Background(), transactions))
+		})
+	}
+
+	t.Run("empty batch", func(t *testing.T) {
+		for i, processor := range []RuleProcessor{NewAndProcessor(big, iran), NewOrProcessor(big), NewNotProcessor(big), NewExceptProcessor(big, iran)} {
+			assert.Empty(t, processor.Process(context.Background(), nil), fmt.Sprint(i))
+		}
+	})
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Corridor is an ordered pair of countries funds move between, from the transaction's Country
+// to its DestinationCountry
+type Corridor struct {
+	Origin      string
+	Destination string
+}
+
+func (c Corridor) String() string {
+	return c.Origin + "->" + c.Destination
+}
+
+// CorridorProcessor flags users moving too much through high-risk corridors within Window. Corridors
+// match directionally, FR->IR not matching IR->FR, unless Bidirectional is set.
+type CorridorProcessor struct {
+	Corridors []Corridor
+	Window    time.Duration
+	// CountThreshold flags more than this many transactions in one corridor within Window, 0 disables it
+	CountThreshold int
+	// AmountThreshold flags a sum above it in one corridor within Window, zero disables it
+	AmountThreshold decimal.Decimal
+	Bidirectional   bool
+}
+
+func NewCorridorProcessor(corridors []Corridor, window time.Duration, countThreshold int, amountThreshold decimal.Decimal) CorridorProcessor {
+	return CorridorProcessor{
+		Corridors:       corridors,
+		Window:          window,
+		CountThreshold:  countThreshold,
+		AmountThreshold: amountThreshold,
+	}
+}
+
+func (c CorridorProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID := range c.Violations(ctx, transactions) {
+		flaggedUsers[userID] = struct{}{}
+	}
+
+	return flaggedUsers
+}
+
+// Violations returns, per flagged user, the configured corridors whose limits they breached,
+// ordered by origin then destination
+func (c CorridorProcessor) Violations(_ context.Context, transactions []Transaction) map[uuid.UUID][]Corridor {
+	// maps each matching country pair to the configured corridor it is reported under
+	corridors := make(map[Corridor]Corridor, len(c.Corridors))
+	for _, corridor := range c.Corridors {
+		normalized := Corridor{Origin: normalizeCountry(corridor.Origin), Destination: normalizeCountry(corridor.Destination)}
+		corridors[normalized] = normalized
+		if c.Bidirectional {
+			corridors[Corridor{Origin: normalized.Destination, Destination: normalized.Origin}] = normalized
+		}
+	}
+
+	corridorOf := func(tx Transaction) (Corridor, bool) {
+		corridor, ok := corridors[Corridor{Origin: normalizeCountry(tx.Country), Destination: normalizeCountry(tx.DestinationCountry)}]
+		return corridor, ok
+	}
+	compareCorridors := func(a, b Corridor) int {
+		return cmp.Or(cmp.Compare(a.Origin, b.Origin), cmp.Compare(a.Destination, b.Destination))
+	}
+
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		_, ok := corridorOf(tx)
+		return ok
+	}}
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(c.Window, c.CountThreshold)}, velocityOptions{sortedInput: true})
+	violations := make(map[uuid.UUID][]Corridor)
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			// sorting by corridor then time leaves each corridor's run ordered for the windows
+			slices.SortFunc(txs, func(a, b Transaction) int {
+				corridorA, _ := corridorOf(a)
+				corridorB, _ := corridorOf(b)
+				return cmp.Or(compareCorridors(corridorA, corridorB), a.CreatedAt.Compare(b.CreatedAt))
+			})
+
+			for start := 0; start < len(txs); {
+				corridor, _ := corridorOf(txs[start])
+				end := start + 1
+				for end < len(txs) {
+					if next, _ := corridorOf(txs[end]); next != corridor {
+						break
+					}
+					end++
+				}
+
+				if c.exceedsLimits(checker, txs[start:end]) {
+					violations[userID] = append(violations[userID], corridor)
+				}
+				start = end
+			}
+		}
+	}
+
+	return violations
+}
+
+func (c CorridorProcessor) exceedsLimits(checker velocityChecker, txs []Transaction) bool {
+	if c.CountThreshold > 0 {
+		if violated, _ := checker.CheckUser(txs); violated {
+			return true
+		}
+	}
+
+	return c.AmountThreshold.IsPositive() && exceedsWindowAmount(txs, c.Window, c.AmountThreshold, AmountPolicy{})
+}
+
+func (c CorridorProcessor) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	violations := c.Violations(ctx, transactions)[userID]
+
+	var evidence []Transaction
+	corridors := make([]string, len(violations))
+	for i, corridor := range violations {
+		corridors[i] = corridor.String()
+		for _, tx := range transactions {
+			origin, destination := normalizeCountry(tx.Country), normalizeCountry(tx.DestinationCountry)
+			if (origin == corridor.Origin && destination == corridor.Destination) ||
+				(c.Bidirectional && origin == corridor.Destination && destination == corridor.Origin) {
+				evidence = append(evidence, tx)
+			}
+		}
+	}
+
+	return evidence, map[string]string{"corridors": strings.Join(corridors, ", ")}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCorridorProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	tx := func(days int, origin, destination string) Transaction {
+		return Transaction{UserID: userID, Country: origin, DestinationCountry: destination, Amount: decimal.NewFromInt(100), CreatedAt: baseTime.Add(time.Duration(days) * 24 * time.Hour)}
+	}
+
+	tests := []struct {
+		name          string
+		bidirectional bool
+		transactions  []Transaction
+		wantFlagged   bool
+	}{
+		{
+			name:         "corridor limit exceeded",
+			transactions: []Transaction{tx(0, "FR", "IR"), tx(1, "fr", "ir"), tx(2, "FR", "IR"), tx(3, "FR", "DE")},
+			wantFlagged:  true,
+		},
+		{
+			name:         "at the corridor limit",
+			transactions: []Transaction{tx(0, "FR", "IR"), tx(1, "FR", "IR"), tx(2, "FR", "DE"), tx(3, "DE", "IR")},
+			wantFlagged:  false,
+		},
+		{
+			name:         "reverse direction not matched when directional",
+			transactions: []Transaction{tx(0, "IR", "FR"), tx(1, "IR", "FR"), tx(2, "IR", "FR")},
+			wantFlagged:  false,
+		},
+		{
+			name:          "reverse direction matched when bidirectional",
+			bidirectional: true,
+			transactions:  []Transaction{tx(0, "IR", "FR"), tx(1, "FR", "IR"), tx(2, "IR", "FR")},
+			wantFlagged:   true,
+		},
+		{
+			name:         "corridor activity spread beyond the window",
+			transactions: []Transaction{tx(0, "FR", "IR"), tx(5, "FR", "IR"), tx(10, "FR", "IR")},
+			wantFlagged:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewCorridorProcessor([]Corridor{{Origin: "FR", Destination: "IR"}}, week, 2, decimal.Zero)
+			processor.Bidirectional = tt.bidirectional
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestCorridorProcessor_Violations(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, Country: "FR", DestinationCountry: "IR", Amount: decimal.NewFromInt(6000), CreatedAt: baseTime},
+		{UserID: userID, Country: "GB", DestinationCountry: "KP", Amount: decimal.NewFromInt(6000), CreatedAt: baseTime},
+		{UserID: userID, Country: "GB", DestinationCountry: "KP", Amount: decimal.NewFromInt(6000), CreatedAt: baseTime.Add(time.Hour)},
+	}
+
+	processor := NewCorridorProcessor([]Corridor{{Origin: "FR", Destination: "IR"}, {Origin: "gb", Destination: "kp"}}, week, 0, decimal.NewFromInt(10000))
+
+	violations := processor.Violations(context.Background(), transactions)
+
+	assert.Equal(t, map[uuid.UUID][]Corridor{userID: {{Origin: "GB", Destination: "KP"}}}, violations)
+	assert.Equal(t, "GB->KP", violations[userID][0].String())
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// CounterpartyBlacklistProcessor flags users transacting with a known-bad counterparty such as a
+// mule account or scam merchant. The blacklist can be replaced with Reload while processing; the zero
+// value has an empty one.
+type CounterpartyBlacklistProcessor struct {
+	blacklist atomic.Pointer[map[string]struct{}]
+}
+
+// NewCounterpartyBlacklistProcessor creates a CounterpartyBlacklistProcessor from counterparty IDs
+func NewCounterpartyBlacklistProcessor(counterpartyIDs ...string) *CounterpartyBlacklistProcessor {
+	c := &CounterpartyBlacklistProcessor{}
+	c.store(counterpartyIDs)
+
+	return c
+}
+
+// NewCounterpartyBlacklistProcessorFromReader reads the blacklist as described in Reload
+func NewCounterpartyBlacklistProcessorFromReader(r io.Reader) (*CounterpartyBlacklistProcessor, error) {
+	c := NewCounterpartyBlacklistProcessor()
+	if err := c.Reload(r); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Reload replaces the blacklist with one counterparty ID per line read from r, ignoring blank lines
+// and lines starting with #. Process calls in flight finish with the previous list; on a read error
+// the previous list is kept.
+func (c *CounterpartyBlacklistProcessor) Reload(r io.Reader) error {
+	var counterpartyIDs []string
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		counterpartyIDs = append(counterpartyIDs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	c.store(counterpartyIDs)
+	return nil
+}
+
+func (c *CounterpartyBlacklistProcessor) store(counterpartyIDs []string) {
+	blacklist := make(map[string]struct{}, len(counterpartyIDs))
+	for _, counterpartyID := range counterpartyIDs {
+		if counterpartyID = strings.TrimSpace(counterpartyID); counterpartyID != "" {
+			blacklist[counterpartyID] = struct{}{}
+		}
+	}
+
+	c.blacklist.Store(&blacklist)
+}
+
+func (c *CounterpartyBlacklistProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID := range c.Matches(ctx, transactions) {
+		flaggedUsers[userID] = struct{}{}
+	}
+
+	return flaggedUsers
+}
+
+// Matches returns, per flagged user, the blacklisted counterparties they transacted with in sorted
+// order. Transactions without a CounterpartyID are ignored.
+func (c *CounterpartyBlacklistProcessor) Matches(_ context.Context, transactions []Transaction) map[uuid.UUID][]string {
+	matches := make(map[uuid.UUID][]string)
+	loaded := c.blacklist.Load()
+	if loaded == nil {
+		return matches
+	}
+	blacklist := *loaded
+
+	for _, tx := range transactions {
+		if tx.CounterpartyID == "" {
+			continue
+		}
+		if _, exists := blacklist[tx.CounterpartyID]; exists && !slices.Contains(matches[tx.UserID], tx.CounterpartyID) {
+			matches[tx.UserID] = append(matches[tx.UserID], tx.CounterpartyID)
+		}
+	}
+
+	for _, counterpartyIDs := range matches {
+		slices.Sort(counterpartyIDs)
+	}
+
+	return matches
+}
+
+func (c *CounterpartyBlacklistProcessor) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	counterpartyIDs := c.Matches(ctx, transactions)[userID]
+
+	var evidence []Transaction
+	for _, tx := range transactions {
+		if slices.Contains(counterpartyIDs, tx.CounterpartyID) {
+			evidence = append(evidence, tx)
+		}
+	}
+
+	return evidence, map[string]string{"counterparties": strings.Join(counterpartyIDs, ", ")}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounterpartyBlacklistProcessor_Matches(t *testing.T) {
+	userID1 := uuid.New()
+	userID2 := uuid.New()
+
+	processor, err := NewCounterpartyBlacklistProcessorFromReader(strings.NewReader("# mule accounts\nmule-1\n\n  mule-2  \nscam-merchant\n"))
+	require.NoError(t, err)
+
+	transactions := []Transaction{
+		{UserID: userID1, CounterpartyID: "mule-2"},
+		{UserID: userID1, CounterpartyID: "grocer"},
+		{UserID: userID1, CounterpartyID: "mule-1"},
+		{UserID: userID1, CounterpartyID: "mule-2"},
+		{UserID: userID2, CounterpartyID: ""},
+		{UserID: userID2, CounterpartyID: "# mule accounts"},
+	}
+
+	matches := processor.Matches(context.Background(), transactions)
+
+	assert.Equal(t, map[uuid.UUID][]string{userID1: {"mule-1", "mule-2"}}, matches)
+	assert.Equal(t, map[uuid.UUID]struct{}{userID1: {}}, processor.Process(context.Background(), transactions))
+}
+
+func TestCounterpartyBlacklistProcessor_Reload(t *testing.T) {
+	userID := uuid.New()
+	transactions := []Transaction{{UserID: userID, CounterpartyID: "mule-1"}}
+
+	processor := NewCounterpartyBlacklistProcessor()
+	assert.Empty(t, processor.Process(context.Background(), transactions))
+
+	require.NoError(t, processor.Reload(strings.NewReader("mule-1\n")))
+	assert.Contains(t, processor.Process(context.Background(), transactions), userID)
+
+	require.NoError(t, processor.Reload(strings.NewReader("")))
+	assert.Empty(t, processor.Process(context.Background(), transactions))
+}
+
+func TestCounterpartyBlacklistProcessor_ZeroValue(t *testing.T) {
+	userID := uuid.New()
+	transactions := []Transaction{{UserID: userID, CounterpartyID: "mule-1"}}
+
+	var processor CounterpartyBlacklistProcessor
+	assert.Empty(t, processor.Process(context.Background(), transactions))
+	evidence, details := processor.AlertDetails(context.Background(), userID, transactions)
+	assert.Empty(t, evidence)
+	assert.Equal(t, "", details["counterparties"])
+
+	require.NoError(t, processor.Reload(strings.NewReader("mule-1\n")))
+	assert.Contains(t, processor.Process(context.Background(), transactions), userID)
+}
+
+func TestCounterpartyBlacklistProcessor_ConcurrentReload(t *testing.T) {
+	userID := uuid.New()
+	transactions := []Transaction{{UserID: userID, CounterpartyID: "mule-1"}}
+	processor := NewCounterpartyBlacklistProcessor("mule-1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				// every list contains mule-1, so each Process sees a complete list holding it
+				assert.NoError(t, processor.Reload(strings.NewReader(fmt.Sprintf("mule-1\nmule-%d\n", j+2))))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.Contains(t, processor.Process(context.Background(), transactions), userID)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// CounterpartyCountryProcessor flags users whose counterparties are located in blacklisted countries,
+// even when the transactions are booked domestically. CountryBlackListProcessor covers the transaction's
+// own Country; transactions without a CounterpartyCountry are skipped.
+type CounterpartyCountryProcessor struct {
+	Blacklist map[string]struct{}
+	// MinCount is the number of matching transactions needed to flag, 0 or 1 flags on the first
+	MinCount int
+	// MinAmount is the matching total needed to flag, zero flags on any amount
+	MinAmount decimal.Decimal
+}
+
+// NewCounterpartyCountryProcessor creates a CounterpartyCountryProcessor from country codes
+func NewCounterpartyCountryProcessor(countries ...string) CounterpartyCountryProcessor {
+	return CounterpartyCountryProcessor{Blacklist: newCountrySet(countries)}
+}
+
+type counterpartyCountryHits struct {
+	count int
+	total decimal.Decimal
+}
+
+func (c CounterpartyCountryProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	blacklist := normalizeCountrySet(c.Blacklist)
+	hits := make(map[uuid.UUID]*counterpartyCountryHits)
+
+	for _, tx := range transactions {
+		country := normalizeCountry(tx.CounterpartyCountry)
+		if country == "" {
+			continue
+		}
+		if _, exists := blacklist[country]; !exists {
+			continue
+		}
+
+		h, ok := hits[tx.UserID]
+		if !ok {
+			h = &counterpartyCountryHits{}
+			hits[tx.UserID] = h
+		}
+		h.count++
+		h.total = h.total.Add(spendAmount(tx))
+	}
+
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	for userID, h := range hits {
+		if h.count >= c.MinCount && h.total.GreaterThanOrEqual(c.MinAmount) {
+			flaggedUsers[userID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCounterpartyCountryProcessor_Process(t *testing.T) {
+	userID := uuid.New()
+
+	tx := func(country, counterpartyCountry string, amount int64) Transaction {
+		return Transaction{UserID: userID, Country: country, CounterpartyCountry: counterpartyCountry, Amount: decimal.NewFromInt(amount)}
+	}
+
+	tests := []struct {
+		name         string
+		minCount     int
+		minAmount    decimal.Decimal
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "domestic transaction with a blacklisted counterparty country",
+			transactions: []Transaction{tx("FR", " ir ", 100)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "blacklisted transaction country with a domestic counterparty",
+			transactions: []Transaction{tx("IR", "FR", 100)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "empty counterparty country",
+			transactions: []Transaction{tx("IR", "", 100)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "below the minimum count",
+			minCount:     3,
+			transactions: []Transaction{tx("FR", "IR", 100), tx("FR", "KP", 100), tx("FR", "DE", 100)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "at the minimum count",
+			minCount:     3,
+			transactions: []Transaction{tx("FR", "IR", 100), tx("FR", "KP", 100), tx("FR", "IR", 100)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "below the minimum amount",
+			minAmount:    decimal.NewFromInt(1000),
+			transactions: []Transaction{tx("FR", "IR", 400), tx("FR", "IR", 500)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "at the minimum amount",
+			minAmount:    decimal.NewFromInt(1000),
+			transactions: []Transaction{tx("FR", "IR", 500), tx("FR", "IR", 500)},
+			wantFlagged:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewCounterpartyCountryProcessor("IR", "kp")
+			processor.MinCount = tt.minCount
+			processor.MinAmount = tt.minAmount
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+
+	inverse := []Transaction{tx("IR", "FR", 100)}
+	assert.Contains(t, NewCountryBlackListProcessor("IR").Process(context.Background(), inverse), userID, "covered by the transaction country blacklist")
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// normalizeCountry makes country codes comparable regardless of surrounding spaces, case and whether
+// they are ISO 3166-1 alpha-2 or alpha-3 codes, the latter being mapped to alpha-2, e.g. " fra" to "FR"
+func normalizeCountry(country string) string {
+	normalized := strings.ToUpper(strings.TrimSpace(country))
+	if alpha2, ok := countryAlpha3[normalized]; ok {
+		return alpha2
+	}
+
+	return normalized
+}
+
+// countryCode normalizes country as normalizeCountry does without allocating, returning the two
+// upper-case letters of the alpha-2 code. ok is false unless country normalizes to two ASCII letters.
+func countryCode(country string) (code [2]byte, ok bool) {
+	start, end := 0, len(country)
+	for start < end && isASCIISpace(country[start]) {
+		start++
+	}
+	for end > start && isASCIISpace(country[end-1]) {
+		end--
+	}
+
+	var buf [3]byte
+	n := end - start
+	if n != 2 && n != 3 {
+		return code, false
+	}
+	for i := range n {
+		c := country[start+i]
+		if 'a' <= c && c <= 'z' {
+			c -= 'a' - 'A'
+		}
+		if c < 'A' || c > 'Z' {
+			return code, false
+		}
+		buf[i] = c
+	}
+	if n == 2 {
+		return [2]byte{buf[0], buf[1]}, true
+	}
+
+	alpha2, known := countryAlpha3[string(buf[:])]
+	if !known {
+		return code, false
+	}
+	return [2]byte{alpha2[0], alpha2[1]}, true
+}
+
+func isASCIISpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+// countryMatcher tells whether a country normalizes to one of a set of codes, looking alpha-2 codes up
+// in a table indexed by their letters so matching does not allocate
+type countryMatcher struct {
+	alpha2 [26][26]bool
+	// other holds the normalized codes that are not alpha-2, matched through normalizeCountry
+	other map[string]struct{}
+}
+
+func newCountryMatcher(countries map[string]struct{}) countryMatcher {
+	var m countryMatcher
+	for country := range countries {
+		if code, ok := countryCode(country); ok {
+			m.alpha2[code[0]-'A'][code[1]-'A'] = true
+			continue
+		}
+		if m.other == nil {
+			m.other = make(map[string]struct{})
+		}
+		m.other[normalizeCountry(country)] = struct{}{}
+	}
+
+	return m
+}
+
+func (m *countryMatcher) matches(country string) bool {
+	if code, ok := countryCode(country); ok {
+		return m.alpha2[code[0]-'A'][code[1]-'A']
+	}
+	if len(m.other) == 0 {
+		return false
+	}
+
+	_, ok := m.other[normalizeCountry(country)]
+	return ok
+}
+
+// normalizeCountrySet returns countries with every key normalized
+func normalizeCountrySet(countries map[string]struct{}) map[string]struct{} {
+	normalized := make(map[string]struct{}, len(countries))
+	for country := range countries {
+		normalized[normalizeCountry(country)] = struct{}{}
+	}
+
+	return normalized
+}
+
+// newCountrySet builds a normalized country set from a list of codes
+func newCountrySet(countries []string) map[string]struct{} {
+	set := make(map[string]struct{}, len(countries))
+	for _, country := range countries {
+		set[normalizeCountry(country)] = struct{}{}
+	}
+
+	return set
+}
+
+// countryAlertDetails returns the transactions whose country, once normalized with normalize, matches,
+// and the sorted matched countries
+func countryAlertDetails(transactions []Transaction, normalize func(string) string, matches func(country string) bool) ([]Transaction, map[string]string) {
+	var evidence []Transaction
+	var countries []string
+	for _, tx := range transactions {
+		country := normalize(tx.Country)
+		if matches(country) {
+			evidence = append(evidence, tx)
+			countries = append(countries, country)
+		}
+	}
+	slices.Sort(countries)
+
+	return evidence, map[string]string{"countries": strings.Join(slices.Compact(countries), ", ")}
+}
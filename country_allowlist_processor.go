@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ErrEmptyAllowlist is returned when a CountryAllowListProcessor would have no permitted country
+var ErrEmptyAllowlist = errors.New("country allow-list is empty")
+
+// CountryAllowListProcessor flags users with any transaction outside the permitted countries,
+// the inverse of CountryBlackListProcessor. Countries are normalized the same way.
+type CountryAllowListProcessor struct {
+	Allowlist map[string]struct{}
+	// FlagEmptyCountry flags transactions without a country, which are ignored otherwise
+	FlagEmptyCountry bool
+}
+
+// NewCountryAllowListProcessor creates a CountryAllowListProcessor, rejecting an empty allow-list
+// since it would flag every transaction
+func NewCountryAllowListProcessor(countries []string, flagEmptyCountry bool) (CountryAllowListProcessor, error) {
+	allowlist := newCountrySet(countries)
+	delete(allowlist, "")
+	if len(allowlist) == 0 {
+		return CountryAllowListProcessor{}, ErrEmptyAllowlist
+	}
+
+	return CountryAllowListProcessor{
+		Allowlist:        allowlist,
+		FlagEmptyCountry: flagEmptyCountry,
+	}, nil
+}
+
+func (c CountryAllowListProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	allowlist := normalizeCountrySet(c.Allowlist)
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, tx := range transactions {
+		country := normalizeCountry(tx.Country)
+		if country == "" {
+			if c.FlagEmptyCountry {
+				flaggedUsers[tx.UserID] = struct{}{}
+			}
+			continue
+		}
+
+		if _, allowed := allowlist[country]; !allowed {
+			flaggedUsers[tx.UserID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+func (c CountryAllowListProcessor) AlertDetails(_ context.Context, _ uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	allowlist := normalizeCountrySet(c.Allowlist)
+	return countryAlertDetails(transactions, normalizeCountry, func(country string) bool {
+		if country == "" {
+			return c.FlagEmptyCountry
+		}
+		_, allowed := allowlist[country]
+		return !allowed
+	})
+}
+
+func (c CountryAllowListProcessor) ExplainAlert(evidence []Transaction, details map[string]string) string {
+	return fmt.Sprintf("flagged by country allow-list: %s outside it, in %s", pluralize(len(evidence), "transaction"), details["countries"])
+}
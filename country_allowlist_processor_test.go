@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountryAllowListProcessor_Process(t *testing.T) {
+	mixedUser := uuid.New()
+	allowedUser := uuid.New()
+	emptyCountryUser := uuid.New()
+
+	transactions := []Transaction{
+		{UserID: mixedUser, Country: "FR"},
+		{UserID: mixedUser, Country: "IR"},
+		{UserID: allowedUser, Country: " de "},
+		{UserID: allowedUser, Country: "fr"},
+		{UserID: emptyCountryUser, Country: "FR"},
+		{UserID: emptyCountryUser, Country: "  "},
+	}
+
+	tests := []struct {
+		name             string
+		flagEmptyCountry bool
+		wantUsers        map[uuid.UUID]struct{}
+	}{
+		{
+			name:      "empty country ignored",
+			wantUsers: map[uuid.UUID]struct{}{mixedUser: {}},
+		},
+		{
+			name:             "empty country flagged",
+			flagEmptyCountry: true,
+			wantUsers:        map[uuid.UUID]struct{}{mixedUser: {}, emptyCountryUser: {}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor, err := NewCountryAllowListProcessor([]string{"FR", "De"}, tt.flagEmptyCountry)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantUsers, processor.Process(context.Background(), transactions))
+		})
+	}
+}
+
+func TestNewCountryAllowListProcessor_Empty(t *testing.T) {
+	_, err := NewCountryAllowListProcessor(nil, false)
+	assert.ErrorIs(t, err, ErrEmptyAllowlist)
+
+	_, err = NewCountryAllowListProcessor([]string{" "}, false)
+	assert.ErrorIs(t, err, ErrEmptyAllowlist)
+}
+
+func TestCountryProcessors_SharedNormalization(t *testing.T) {
+	userID := uuid.New()
+	transactions := []Transaction{{UserID: userID, Country: " ir"}}
+
+	blacklist := CountryBlackListProcessor{Blacklist: map[string]struct{}{"Ir ": {}}}
+	allowlist := CountryAllowListProcessor{Allowlist: map[string]struct{}{"Ir ": {}}}
+
+	assert.Contains(t, blacklist.Process(context.Background(), transactions), userID)
+	assert.Empty(t, allowlist.Process(context.Background(), transactions))
+	assert.Contains(t, NewCountryBlackListProcessor("IR").Process(context.Background(), transactions), userID)
+}
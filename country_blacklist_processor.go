@@ -2,22 +2,85 @@ package main
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/google/uuid"
 )
 
+// CountryBlackListProcessor flags users with a transaction in a blacklisted country. Countries are
+// compared regardless of surrounding spaces, case and alpha-2 or alpha-3 form, without allocating.
 type CountryBlackListProcessor struct {
 	Blacklist map[string]struct{}
+	// ExactCountries compares Country with the Blacklist keys as they are, for data already holding
+	// normalized codes
+	ExactCountries bool
+}
+
+// NewCountryBlackListProcessor creates a CountryBlackListProcessor from country codes
+func NewCountryBlackListProcessor(countries ...string) CountryBlackListProcessor {
+	return CountryBlackListProcessor{Blacklist: newCountrySet(countries)}
 }
 
 func (c CountryBlackListProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
 	flaggedUsers := make(map[uuid.UUID]struct{})
 
+	if c.ExactCountries {
+		for _, tx := range transactions {
+			if _, exists := c.Blacklist[tx.Country]; exists {
+				flaggedUsers[tx.UserID] = struct{}{}
+			}
+		}
+		return flaggedUsers
+	}
+
+	blacklist := newCountryMatcher(c.Blacklist)
 	for _, tx := range transactions {
-		if _, exists := c.Blacklist[tx.Country]; exists {
+		if blacklist.matches(tx.Country) {
 			flaggedUsers[tx.UserID] = struct{}{}
 		}
 	}
 
 	return flaggedUsers
 }
+
+// ProcessBatch scans the country column only
+func (c CountryBlackListProcessor) ProcessBatch(_ context.Context, batch *TransactionBatch) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	if c.ExactCountries {
+		for i, country := range batch.Countries {
+			if _, exists := c.Blacklist[country]; exists {
+				flaggedUsers[batch.UserIDs[i]] = struct{}{}
+			}
+		}
+		return flaggedUsers
+	}
+
+	blacklist := newCountryMatcher(c.Blacklist)
+	for i, country := range batch.Countries {
+		if blacklist.matches(country) {
+			flaggedUsers[batch.UserIDs[i]] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+func (c CountryBlackListProcessor) AlertDetails(_ context.Context, _ uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	if c.ExactCountries {
+		return countryAlertDetails(transactions, func(country string) string { return country }, func(country string) bool {
+			_, exists := c.Blacklist[country]
+			return exists
+		})
+	}
+
+	blacklist := normalizeCountrySet(c.Blacklist)
+	return countryAlertDetails(transactions, normalizeCountry, func(country string) bool {
+		_, exists := blacklist[country]
+		return exists
+	})
+}
+
+func (c CountryBlackListProcessor) ExplainAlert(evidence []Transaction, details map[string]string) string {
+	return fmt.Sprintf("flagged by country blacklist: %s in %s", pluralize(len(evidence), "transaction"), details["countries"])
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountryCode(t *testing.T) {
+	tests := []struct {
+		country string
+		want    string
+	}{
+		{country: "FR", want: "FR"},
+		{country: "fr", want: "FR"},
+		{country: " Fr\t", want: "FR"},
+		{country: "FRA", want: "FR"},
+		{country: " fra ", want: "FR"},
+		{country: "\tdeu\n", want: "DE"},
+		{country: "IRN", want: "IR"},
+		{country: "XX", want: "XX"},
+		{country: "XYZ"},
+		{country: ""},
+		{country: "  "},
+		{country: "F"},
+		{country: "FRAN"},
+		{country: "F1"},
+		{country: "F R"},
+		{country: " FR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.country, func(t *testing.T) {
+			code, ok := countryCode(tt.country)
+			assert.Equal(t, tt.want != "", ok)
+			if ok {
+				assert.Equal(t, tt.want, string(code[:]))
+				assert.Equal(t, tt.want, normalizeCountry(tt.country), "countryCode agrees with normalizeCountry")
+			}
+		})
+	}
+}
+
+func TestNormalizeCountry_Alpha3(t *testing.T) {
+	assert.Equal(t, "FR", normalizeCountry("fra"))
+	assert.Equal(t, "GB", normalizeCountry(" GBR "))
+	assert.Equal(t, "KP", normalizeCountry("PRK"))
+	assert.Equal(t, "XYZ", normalizeCountry("xyz"), "unknown codes are only trimmed and upper-cased")
+	assert.Equal(t, "FRANCE", normalizeCountry("France"))
+
+	for alpha3, alpha2 := range countryAlpha3 {
+		require.Len(t, alpha3, 3)
+		require.Len(t, alpha2, 2)
+		code, ok := countryCode(strings.ToLower(alpha3))
+		require.True(t, ok, alpha3)
+		require.Equal(t, alpha2, string(code[:]))
+	}
+}
+
+func TestCountryBlackListProcessor_Process(t *testing.T) {
+	alpha2User, alpha3User, spacedUser, cleanUser, otherUser := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	transactions := []Transaction{
+		{UserID: alpha2User, Country: "ir"},
+		{UserID: alpha3User, Country: "PRK"},
+		{UserID: spacedUser, Country: " Irn\t"},
+		{UserID: cleanUser, Country: "FR"},
+		{UserID: cleanUser, Country: "DEU"},
+		{UserID: otherUser, Country: "Atlantis"},
+	}
+
+	tests := []struct {
+		name      string
+		processor CountryBlackListProcessor
+		wantUsers []uuid.UUID
+	}{
+		{
+			name:      "normalized",
+			processor: NewCountryBlackListProcessor("IR", "kp"),
+			wantUsers: []uuid.UUID{alpha2User, alpha3User, spacedUser},
+		},
+		{
+			name:      "alpha-3 blacklist",
+			processor: NewCountryBlackListProcessor("IRN", " prk "),
+			wantUsers: []uuid.UUID{alpha2User, alpha3User, spacedUser},
+		},
+		{
+			name:      "literal blacklist with other codes",
+			processor: CountryBlackListProcessor{Blacklist: map[string]struct{}{" atlantis": {}, "Kp ": {}}},
+			wantUsers: []uuid.UUID{alpha3User, otherUser},
+		},
+		{
+			name:      "exact countries",
+			processor: CountryBlackListProcessor{Blacklist: map[string]struct{}{"ir": {}, "DEU": {}}, ExactCountries: true},
+			wantUsers: []uuid.UUID{alpha2User, cleanUser},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := make(map[uuid.UUID]struct{})
+			for _, userID := range tt.wantUsers {
+				want[userID] = struct{}{}
+			}
+			assert.Equal(t, want, tt.processor.Process(context.Background(), transactions))
+		})
+	}
+}
+
+func TestCountryBlackListProcessor_AlertDetails(t *testing.T) {
+	transactions := []Transaction{{Country: "fra"}, {Country: "DE"}, {Country: " FR"}, {Country: "IRN"}}
+
+	evidence, details := NewCountryBlackListProcessor("FR", "IRN").AlertDetails(context.Background(), uuid.New(), transactions)
+	assert.Equal(t, []Transaction{transactions[0], transactions[2], transactions[3]}, evidence)
+	assert.Equal(t, map[string]string{"countries": "FR, IR"}, details)
+
+	exact := CountryBlackListProcessor{Blacklist: map[string]struct{}{"fra": {}}, ExactCountries: true}
+	evidence, details = exact.AlertDetails(context.Background(), uuid.New(), transactions)
+	assert.Equal(t, []Transaction{transactions[0]}, evidence)
+	assert.Equal(t, map[string]string{"countries": "fra"}, details)
+}
+
+func TestCountryMatcher_ZeroAllocs(t *testing.T) {
+	// a blacklist of country codes only, a name such as "Atlantis" being matched through normalizeCountry
+	matcher := newCountryMatcher(newCountrySet([]string{"IR", "KP", "PRK"}))
+	countries := []string{"ir", " IRN ", "fr", "FRA", "PRK\t", "xyz", ""}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, country := range countries {
+			matcher.matches(country)
+		}
+	})
+	assert.Zero(t, allocs)
+}
+
+func TestRulesConfig_CountryBlacklistExact(t *testing.T) {
+	engine, err := LoadRulesConfig(strings.NewReader("rules:\n  - type: country_blacklist\n    countries: [ir, FRA]\n    exact_countries: true\n"))
+	require.NoError(t, err)
+
+	rules, _ := engine.currentRules()
+	require.Len(t, rules, 1)
+	assert.Equal(t, CountryBlackListProcessor{Blacklist: map[string]struct{}{"ir": {}, "FRA": {}}, ExactCountries: true}, rules[0].processor)
+
+	var dumped strings.Builder
+	require.NoError(t, DumpRulesConfig(&dumped, engine))
+	assert.Contains(t, dumped.String(), "exact_countries: true")
+	assert.Contains(t, dumped.String(), "- FRA")
+}
+
+// messyCountries is a country pool as unnormalized feeds deliver it
+var messyCountries = []string{"FR", "fr", " FR", "FRA", "de", "DEU ", "IR", "irn", "GB", " gbr", "US", "usa"}
+
+func BenchmarkCountryBlackListProcessor_Process(b *testing.B) {
+	transactions := NewTransactionGenerator(WithUserCount(10000), WithCountryPool(messyCountries...)).Generate().Transactions
+
+	b.Run("Normalized", func(b *testing.B) {
+		processor := NewCountryBlackListProcessor("IR", "KP")
+		b.ReportAllocs()
+		for b.Loop() {
+			processor.Process(context.Background(), transactions)
+		}
+	})
+
+	b.Run("Exact", func(b *testing.B) {
+		processor := CountryBlackListProcessor{Blacklist: map[string]struct{}{"IR": {}, "KP": {}}, ExactCountries: true}
+		b.ReportAllocs()
+		for b.Loop() {
+			processor.Process(context.Background(), transactions)
+		}
+	})
+}
+
+// BenchmarkCountryMatcher_Matches is the per-transaction hot loop of the blacklist, which allocates nothing
+func BenchmarkCountryMatcher_Matches(b *testing.B) {
+	transactions := NewTransactionGenerator(WithUserCount(1000), WithCountryPool(messyCountries...)).Generate().Transactions
+	matcher := newCountryMatcher(newCountrySet([]string{"IR", "KP"}))
+
+	b.ReportAllocs()
+	for b.Loop() {
+		for _, tx := range transactions {
+			matcher.matches(tx.Country)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CountryRiskProcessor scores users by the risk points of the countries they transact in, so
+// grey-listed countries add up to a flag without flagging on a single transaction
+type CountryRiskProcessor struct {
+	// Risk maps a country to the points each of its transactions adds; unknown countries add none
+	Risk      map[string]int
+	Threshold int
+	// Window bounds how far apart scored transactions may be, zero scores the whole batch
+	Window time.Duration
+}
+
+func NewCountryRiskProcessor(risk map[string]int, threshold int, window time.Duration) CountryRiskProcessor {
+	return CountryRiskProcessor{
+		Risk:      risk,
+		Threshold: threshold,
+		Window:    window,
+	}
+}
+
+func (c CountryRiskProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID, score := range c.Scores(ctx, transactions) {
+		if score > c.Threshold {
+			flaggedUsers[userID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// Scores returns each user's highest risk score over any Window, counting every transaction
+func (c CountryRiskProcessor) Scores(_ context.Context, transactions []Transaction) map[uuid.UUID]int {
+	risk := c.normalizedRisk()
+	scores := make(map[uuid.UUID]int)
+	for _, userTransactions := range groupTransactions(transactions, velocityOptions{}, nil) {
+		for userID, txs := range userTransactions {
+			sortByCreatedAt(txs)
+			scores[userID] = c.maxWindowScore(txs, risk)
+		}
+	}
+
+	return scores
+}
+
+// normalizedRisk keys Risk by normalized country code
+func (c CountryRiskProcessor) normalizedRisk() map[string]int {
+	risk := make(map[string]int, len(c.Risk))
+	for country, points := range c.Risk {
+		risk[normalizeCountry(country)] = points
+	}
+
+	return risk
+}
+
+// maxWindowScore slides a Window over sorted txs and returns the highest sum of risk points
+// Time complexity: O(n) where n is the number of transactions for a user
+func (c CountryRiskProcessor) maxWindowScore(txs []Transaction, risk map[string]int) int {
+	best, score, left := 0, 0, 0
+
+	for right := range txs {
+		score += risk[normalizeCountry(txs[right].Country)]
+
+		for c.Window > 0 && txs[right].CreatedAt.Sub(txs[left].CreatedAt) > c.Window {
+			score -= risk[normalizeCountry(txs[left].Country)]
+			left++
+		}
+
+		best = max(best, score)
+	}
+
+	return best
+}
+
+func (c CountryRiskProcessor) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	risk := c.normalizedRisk()
+	var evidence []Transaction
+	for _, tx := range transactions {
+		if risk[normalizeCountry(tx.Country)] > 0 {
+			evidence = append(evidence, tx)
+		}
+	}
+
+	return evidence, map[string]string{
+		"score":     strconv.Itoa(c.Scores(ctx, transactions)[userID]),
+		"threshold": strconv.Itoa(c.Threshold),
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountryRiskProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	risk := map[string]int{"KP": 8, "IR": 8, "PA": 3, "AE": 2}
+
+	tx := func(days int, country string) Transaction {
+		return Transaction{UserID: userID, Country: country, CreatedAt: baseTime.Add(time.Duration(days) * 24 * time.Hour)}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantScore    int
+		wantFlagged  bool
+	}{
+		{
+			name:         "one high-risk transaction under threshold",
+			transactions: []Transaction{tx(0, "KP"), tx(1, "FR")},
+			wantScore:    8,
+			wantFlagged:  false,
+		},
+		{
+			name:         "grey-list transactions accumulating past the threshold",
+			transactions: []Transaction{tx(0, "PA"), tx(1, "pa"), tx(2, "AE"), tx(3, "PA")},
+			wantScore:    11,
+			wantFlagged:  true,
+		},
+		{
+			name:         "grey-list transactions spread beyond the window",
+			transactions: []Transaction{tx(0, "PA"), tx(10, "PA"), tx(20, "PA"), tx(30, "PA")},
+			wantScore:    3,
+			wantFlagged:  false,
+		},
+		{
+			name:         "unknown countries contribute zero",
+			transactions: []Transaction{tx(0, "FR"), tx(1, "DE"), tx(2, ""), tx(3, "XX")},
+			wantScore:    0,
+			wantFlagged:  false,
+		},
+	}
+
+	processor := NewCountryRiskProcessor(risk, 10, week)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := processor.Scores(context.Background(), tt.transactions)
+			assert.Equal(t, tt.wantScore, scores[userID])
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestCountryRiskProcessor_AlertDetails_UnnormalizedRisk(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	processor := NewCountryRiskProcessor(map[string]int{"gb ": 5}, 4, week)
+	transactions := []Transaction{
+		{UserID: userID, Country: "GB", CreatedAt: baseTime},
+		{UserID: userID, Country: "FR", CreatedAt: baseTime.Add(time.Hour)},
+	}
+
+	evidence, details := processor.AlertDetails(context.Background(), userID, transactions)
+
+	assert.Equal(t, transactions[:1], evidence, "evidence uses the same normalized risk as the score")
+	assert.Equal(t, "5", details["score"])
+}
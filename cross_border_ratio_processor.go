@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// CrossBorderRatioProcessor flags users whose recent activity is mostly outside their home country.
+// The home country comes from the user's profile when Profiles is set, falling back to HomeCountry.
+type CrossBorderRatioProcessor struct {
+	HomeCountry string
+	Profiles    UserProfileProvider
+	// Ratio flags a foreign share above it, e.g. 0.8
+	Ratio float64
+	// ByAmount measures the foreign share of the amount instead of the transaction count
+	ByAmount bool
+	// MinTransactions is the activity a user needs within Window to be evaluated
+	MinTransactions int
+	// Window is the period ending at the user's latest transaction that is evaluated, zero evaluates the whole batch
+	Window time.Duration
+}
+
+func NewCrossBorderRatioProcessor(homeCountry string, ratio float64, minTransactions int, window time.Duration) CrossBorderRatioProcessor {
+	return CrossBorderRatioProcessor{
+		HomeCountry:     homeCountry,
+		Ratio:           ratio,
+		MinTransactions: minTransactions,
+		Window:          window,
+	}
+}
+
+// CrossBorderResult is a user's foreign share within the evaluated window
+type CrossBorderResult struct {
+	Ratio        float64
+	Transactions int
+	// NoHomeCountry marks users skipped for lack of a home country
+	NoHomeCountry bool
+}
+
+func (c CrossBorderRatioProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	results, _ := c.Results(ctx, transactions)
+
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	for userID, result := range results {
+		if !result.NoHomeCountry && result.Transactions >= c.MinTransactions && result.Ratio > c.Ratio {
+			flaggedUsers[userID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// Results returns every user's foreign share. Profile lookups failing for a reason other than
+// ErrProfileNotFound are reported in UserErrors, those users falling back to HomeCountry.
+func (c CrossBorderRatioProcessor) Results(ctx context.Context, transactions []Transaction) (map[uuid.UUID]CrossBorderResult, error) {
+	grouped := groupTransactions(transactions, velocityOptions{}, nil)
+
+	profiles := make(map[uuid.UUID]UserProfile)
+	userErrors := make(UserErrors)
+	if c.Profiles != nil {
+		var userIDs []uuid.UUID
+		for _, userTransactions := range grouped {
+			for userID := range userTransactions {
+				userIDs = append(userIDs, userID)
+			}
+		}
+
+		var lookupErrors UserErrors
+		profiles, lookupErrors = loadProfiles(ctx, c.Profiles, userIDs)
+		for userID, err := range lookupErrors {
+			if !errors.Is(err, ErrProfileNotFound) {
+				userErrors[userID] = err
+			}
+		}
+	}
+
+	results := make(map[uuid.UUID]CrossBorderResult)
+	for _, userTransactions := range grouped {
+		for userID, txs := range userTransactions {
+			home := normalizeCountry(profiles[userID].HomeCountry)
+			if home == "" {
+				home = normalizeCountry(c.HomeCountry)
+			}
+			if home == "" {
+				results[userID] = CrossBorderResult{NoHomeCountry: true}
+				continue
+			}
+
+			results[userID] = c.foreignShare(txs, home)
+		}
+	}
+
+	if len(userErrors) > 0 {
+		return results, userErrors
+	}
+
+	return results, nil
+}
+
+// foreignShare measures the share of txs outside home within Window of the latest transaction
+func (c CrossBorderRatioProcessor) foreignShare(txs []Transaction, home string) CrossBorderResult {
+	latest := txs[0].CreatedAt
+	for _, tx := range txs {
+		if tx.CreatedAt.After(latest) {
+			latest = tx.CreatedAt
+		}
+	}
+
+	var count, foreignCount int
+	total, foreign := decimal.Zero, decimal.Zero
+	for _, tx := range txs {
+		if c.Window > 0 && latest.Sub(tx.CreatedAt) > c.Window {
+			continue
+		}
+
+		count++
+		total = total.Add(spendAmount(tx))
+		if normalizeCountry(tx.Country) != home {
+			foreignCount++
+			foreign = foreign.Add(spendAmount(tx))
+		}
+	}
+
+	result := CrossBorderResult{Transactions: count}
+	switch {
+	case c.ByAmount && total.IsPositive():
+		result.Ratio = foreign.Div(total).InexactFloat64()
+	case !c.ByAmount:
+		result.Ratio = float64(foreignCount) / float64(count)
+	}
+
+	return result
+}
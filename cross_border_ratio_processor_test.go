@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCrossBorderRatioProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	mix := func(foreign, domestic int) []Transaction {
+		var transactions []Transaction
+		for i := 0; i < foreign+domestic; i++ {
+			country := "FR"
+			if i < foreign {
+				country = "DE"
+			}
+			transactions = append(transactions, Transaction{UserID: userID, Country: country, Amount: decimal.NewFromInt(100), CreatedAt: baseTime.Add(time.Duration(i) * time.Hour)})
+		}
+		return transactions
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{name: "9 foreign, 1 domestic", transactions: mix(9, 1), wantFlagged: true},
+		{name: "5 foreign, 5 domestic", transactions: mix(5, 5), wantFlagged: false},
+		{name: "below the activity floor", transactions: mix(4, 0), wantFlagged: false},
+		{
+			name: "foreign activity outside the window",
+			transactions: append(mix(9, 0), Transaction{
+				UserID: userID, Country: "fr", Amount: decimal.NewFromInt(100), CreatedAt: baseTime.Add(month),
+			}),
+			wantFlagged: false,
+		},
+	}
+
+	processor := NewCrossBorderRatioProcessor("FR", 0.8, 5, week)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestCrossBorderRatioProcessor_Results(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	german, unknownHome, noProfile := uuid.New(), uuid.New(), uuid.New()
+	profiles := InMemoryProfileProvider{
+		german:      {HomeCountry: "de"},
+		unknownHome: {},
+	}
+
+	var transactions []Transaction
+	for _, userID := range []uuid.UUID{german, unknownHome, noProfile} {
+		transactions = append(transactions,
+			Transaction{UserID: userID, Country: "DE", Amount: decimal.NewFromInt(900), CreatedAt: baseTime},
+			Transaction{UserID: userID, Country: "FR", Amount: decimal.NewFromInt(100), CreatedAt: baseTime.Add(time.Hour)},
+		)
+	}
+
+	processor := CrossBorderRatioProcessor{Profiles: profiles, Ratio: 0.8, MinTransactions: 1, Window: week}
+
+	results, err := processor.Results(context.Background(), transactions)
+	require.NoError(t, err)
+	assert.Equal(t, CrossBorderResult{Ratio: 0.5, Transactions: 2}, results[german])
+	assert.True(t, results[unknownHome].NoHomeCountry)
+	assert.True(t, results[noProfile].NoHomeCountry)
+
+	processor.ByAmount = true
+	results, err = processor.Results(context.Background(), transactions)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.1, results[german].Ratio, 1e-9)
+
+	processor.HomeCountry = "FR"
+	results, err = processor.Results(context.Background(), transactions)
+	require.NoError(t, err)
+	assert.InDelta(t, 0.1, results[german].Ratio, 1e-9, "the profile's home country wins")
+	assert.InDelta(t, 0.9, results[noProfile].Ratio, 1e-9, "users without a profile fall back to HomeCountry")
+	assert.Contains(t, processor.Process(context.Background(), transactions), noProfile)
+}
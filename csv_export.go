@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// csvAlertHeader is the column order of WriteCSVAlerts. Columns are only ever appended, never
+// reordered, so downstream tooling can rely on their position.
+var csvAlertHeader = []string{
+	"user_id",
+	"rule",
+	"severity",
+	"first_evidence_at",
+	"last_evidence_at",
+	"evidence_count",
+	"total_amount",
+}
+
+// WriteCSVAlerts writes a header then one row per alert, sorted by user then rule name:
+//
+//	user_id, rule, severity, first_evidence_at, last_evidence_at, evidence_count, total_amount
+//
+// Evidence timestamps are RFC3339Nano in UTC, empty without evidence, and total_amount is the
+// decimal sum of the evidence amounts, reversals included.
+func WriteCSVAlerts(w io.Writer, alerts []Alert) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvAlertHeader); err != nil {
+		return err
+	}
+
+	for _, alert := range reportAlerts(alerts) {
+		var first, last string
+		total := decimal.Zero
+		for _, tx := range alert.Evidence {
+			total = total.Add(tx.Amount)
+		}
+		if len(alert.Evidence) > 0 {
+			byTime := func(a, b Transaction) int { return a.CreatedAt.Compare(b.CreatedAt) }
+			first = detailTime(slices.MinFunc(alert.Evidence, byTime).CreatedAt)
+			last = detailTime(slices.MaxFunc(alert.Evidence, byTime).CreatedAt)
+		}
+
+		err := writer.Write([]string{
+			alert.UserID.String(),
+			alert.RuleName,
+			string(alert.Severity),
+			first,
+			last,
+			strconv.Itoa(len(alert.Evidence)),
+			total.String(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteCSVFlaggedUsers writes a user_id, rules header then one row per user sorted by user ID,
+// the rules that flagged them being sorted and joined with ", "
+func WriteCSVFlaggedUsers(w io.Writer, flaggedUsers map[uuid.UUID][]string) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"user_id", "rules"}); err != nil {
+		return err
+	}
+
+	for _, userID := range NewFlaggedUsers(slices.Collect(maps.Keys(flaggedUsers))...) {
+		rules := slices.Clone(flaggedUsers[userID])
+		slices.Sort(rules)
+		if err := writer.Write([]string{userID.String(), strings.Join(rules, ", ")}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteCSVAlerts(t *testing.T) {
+	result := reportFixture()
+	first := result.Alerts[2].UserID
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	alerts := append(result.Alerts, Alert{
+		UserID:   first,
+		RuleName: "VelocityProcessor [cash, deposits\nabove 1k]",
+		Severity: SeverityHigh,
+		Evidence: []Transaction{
+			{UserID: first, Amount: decimal.RequireFromString("1000.25"), CreatedAt: baseTime.Add(time.Hour)},
+			{UserID: first, Amount: decimal.RequireFromString("-200.25"), CreatedAt: baseTime},
+			{UserID: first, Amount: decimal.RequireFromString("3000"), CreatedAt: baseTime.Add(30 * time.Minute)},
+		},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSVAlerts(&buf, alerts))
+	assertGolden(t, "alerts.golden.csv", buf.Bytes())
+
+	records, err := csv.NewReader(bytes.NewReader(buf.Bytes())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 5)
+	assert.Equal(t, csvAlertHeader, records[0])
+
+	var firstUserRules []string
+	for _, record := range records[1:] {
+		if record[0] == first.String() {
+			firstUserRules = append(firstUserRules, record[1])
+		}
+	}
+	assert.Equal(t, []string{
+		"CountryBlackListProcessor",
+		"TransactionAmountProcessor",
+		"VelocityProcessor [cash, deposits\nabove 1k]",
+	}, firstUserRules, "a user flagged by several rules gets one row per rule")
+
+	assert.Equal(t, []string{
+		first.String(),
+		"VelocityProcessor [cash, deposits\nabove 1k]",
+		"high",
+		"2024-03-01T09:00:00Z",
+		"2024-03-01T10:00:00Z",
+		"3",
+		"3800",
+	}, records[3])
+}
+
+func TestWriteCSVFlaggedUsers(t *testing.T) {
+	first := uuid.MustParse("1b4e28ba-2fa1-11d2-883f-0016d3cca427")
+	second := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSVFlaggedUsers(&buf, map[uuid.UUID][]string{
+		second: {"VelocityProcessor", "CountryBlackListProcessor"},
+		first:  {"FilteredProcessor [card, \"online\"]"},
+	}))
+
+	assert.Equal(t, "user_id,rules\n"+
+		"1b4e28ba-2fa1-11d2-883f-0016d3cca427,\"FilteredProcessor [card, \"\"online\"\"]\"\n"+
+		"6ba7b810-9dad-11d1-80b4-00c04fd430c8,\"CountryBlackListProcessor, VelocityProcessor\"\n", buf.String())
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrRateNotFound is returned by a RateProvider without a rate for the requested currencies
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+// RateProvider returns how many units of currency to one unit of from is worth
+type RateProvider interface {
+	Rate(from, to string) (decimal.Decimal, error)
+}
+
+// CurrencyPair keys StaticRateProvider rates, e.g. {From: "USD", To: "EUR"}
+type CurrencyPair struct {
+	From string
+	To   string
+}
+
+// StaticRateProvider is an in-memory RateProvider keyed by upper-case ISO 4217 codes. A pair missing
+// in one direction is derived from the inverse rate when present; a direct rate always takes precedence.
+type StaticRateProvider map[CurrencyPair]decimal.Decimal
+
+func (s StaticRateProvider) Rate(from, to string) (decimal.Decimal, error) {
+	from, to = normalizeCurrency(from), normalizeCurrency(to)
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+
+	if rate, ok := s[CurrencyPair{From: from, To: to}]; ok {
+		return rate, nil
+	}
+	if rate, ok := s[CurrencyPair{From: to, To: from}]; ok && !rate.IsZero() {
+		return decimal.NewFromInt(1).Div(rate), nil
+	}
+
+	return decimal.Decimal{}, fmt.Errorf("%s to %s: %w", from, to, ErrRateNotFound)
+}
+
+func normalizeCurrency(currency string) string {
+	return strings.ToUpper(strings.TrimSpace(currency))
+}
+
+// CurrencyConversion converts amounts into Base before threshold comparison.
+// Transactions without a Currency are taken to already be in Base.
+type CurrencyConversion struct {
+	Base  string
+	Rates RateProvider
+	// Strict flags the users of transactions without a rate for review, they are skipped otherwise
+	Strict bool
+}
+
+// convert returns copies of transactions with amounts and currency in Base, dropping those without a rate,
+// along with the users flagged for review in strict mode. A nil conversion returns transactions as is.
+func (c *CurrencyConversion) convert(transactions []Transaction) ([]Transaction, map[uuid.UUID]struct{}) {
+	review := make(map[uuid.UUID]struct{})
+	if c == nil {
+		return transactions, review
+	}
+
+	converted := make([]Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if tx.Currency != "" {
+			rate, err := c.Rates.Rate(tx.Currency, c.Base)
+			if err != nil {
+				if c.Strict {
+					review[tx.UserID] = struct{}{}
+				}
+				continue
+			}
+			tx.Amount = tx.Amount.Mul(rate)
+		}
+		tx.Currency = c.Base
+
+		converted = append(converted, tx)
+	}
+
+	return converted, review
+}
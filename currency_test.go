@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRates() StaticRateProvider {
+	return StaticRateProvider{
+		{From: "USD", To: "EUR"}: decimal.RequireFromString("0.9"),
+		{From: "EUR", To: "GBP"}: decimal.RequireFromString("0.8"),
+	}
+}
+
+func TestStaticRateProvider_Rate(t *testing.T) {
+	rates := testRates()
+
+	rate, err := rates.Rate("usd", "EUR")
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.RequireFromString("0.9")))
+
+	rate, err = rates.Rate("GBP", "EUR")
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.RequireFromString("1.25")), "inverse of EUR/GBP")
+
+	rate, err = rates.Rate("EUR", "EUR")
+	require.NoError(t, err)
+	assert.True(t, rate.Equal(decimal.NewFromInt(1)))
+
+	_, err = rates.Rate("USD", "GBP")
+	assert.ErrorIs(t, err, ErrRateNotFound)
+
+	// both directions configured with rates that are not exact inverses
+	rates[CurrencyPair{From: "EUR", To: "USD"}] = decimal.RequireFromString("1.2")
+	for range 20 {
+		rate, err = rates.Rate("EUR", "USD")
+		require.NoError(t, err)
+		assert.True(t, rate.Equal(decimal.RequireFromString("1.2")), "the direct rate wins over the inverse")
+	}
+}
+
+func TestTransactionAmountProcessor_Process_MixedCurrencies(t *testing.T) {
+	usd, gbp, eur, chf := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	transactions := []Transaction{
+		// 10500 USD is 9450 EUR, under the threshold unconverted it would be flagged
+		{UserID: usd, Amount: decimal.NewFromInt(10500), Currency: "USD"},
+		// 8500 GBP is 10625 EUR, over the threshold only once converted
+		{UserID: gbp, Amount: decimal.NewFromInt(8500), Currency: "GBP"},
+		{UserID: eur, Amount: decimal.NewFromInt(9000), Currency: "EUR"},
+		{UserID: chf, Amount: decimal.NewFromInt(100), Currency: "CHF"},
+	}
+
+	tests := []struct {
+		name      string
+		strict    bool
+		wantUsers []uuid.UUID
+	}{
+		{name: "missing rate skipped", strict: false, wantUsers: []uuid.UUID{gbp}},
+		{name: "missing rate flagged for review", strict: true, wantUsers: []uuid.UUID{gbp, chf}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := TransactionAmountProcessor{
+				Threshold:  decimal.NewFromInt(10000),
+				Conversion: &CurrencyConversion{Base: "EUR", Rates: testRates(), Strict: tt.strict},
+			}
+
+			flaggedUsers := processor.Process(context.Background(), transactions)
+
+			assert.Len(t, flaggedUsers, len(tt.wantUsers))
+			for _, wantUser := range tt.wantUsers {
+				assert.Contains(t, flaggedUsers, wantUser)
+			}
+		})
+	}
+
+	assert.True(t, transactions[1].Amount.Equal(decimal.NewFromInt(8500)), "caller's amounts are left unconverted")
+}
+
+func TestTransactionAmountProcessor_Process_ConvertedCurrencyThresholds(t *testing.T) {
+	usd, gbp := uuid.New(), uuid.New()
+	transactions := []Transaction{
+		// 9500 USD is 8550 EUR: under the EUR threshold though over the USD one
+		{UserID: usd, Amount: decimal.NewFromInt(9500), Currency: "USD"},
+		// 7600 GBP is 9500 EUR: over the EUR threshold though under the GBP one
+		{UserID: gbp, Amount: decimal.NewFromInt(7600), Currency: "GBP"},
+	}
+	processor := TransactionAmountProcessor{
+		Threshold: decimal.NewFromInt(50000),
+		CurrencyThresholds: map[string]decimal.Decimal{
+			"EUR": decimal.NewFromInt(9000),
+			"USD": decimal.NewFromInt(9200),
+			"GBP": decimal.NewFromInt(8000),
+		},
+		Conversion: &CurrencyConversion{Base: "EUR", Rates: testRates()},
+	}
+
+	assert.Equal(t, map[uuid.UUID]struct{}{gbp: {}}, processor.Process(context.Background(), transactions))
+
+	evidence, details := processor.AlertDetails(context.Background(), gbp, transactions)
+	assert.Equal(t, []Transaction{transactions[1]}, evidence)
+	assert.Equal(t, "9000", details["threshold"], "the base currency threshold applied, not the default")
+}
+
+func TestDailyAggregateProcessor_Process_MixedCurrencies(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	// 4000 EUR + 5000 GBP (6250 EUR) exceed 10000 EUR only once converted, the raw sum being 9000
+	transactions := []Transaction{
+		{UserID: userID, Amount: decimal.NewFromInt(4000), Currency: "EUR", CreatedAt: baseTime},
+		{UserID: userID, Amount: decimal.NewFromInt(5000), Currency: "GBP", CreatedAt: baseTime.Add(time.Hour)},
+	}
+
+	for _, rolling := range []bool{false, true} {
+		processor := DailyAggregateProcessor{
+			Threshold:  decimal.NewFromInt(10000),
+			Rolling:    rolling,
+			Conversion: &CurrencyConversion{Base: "EUR", Rates: testRates()},
+		}
+		assert.Contains(t, processor.Process(context.Background(), transactions), userID, "rolling %v", rolling)
+
+		processor.Conversion = nil
+		assert.NotContains(t, processor.Process(context.Background(), transactions), userID, "rolling %v", rolling)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// DailyAggregateProcessor flags users whose total amount in a day exceeds Threshold, catching
+// amounts split across many transactions that TransactionAmountProcessor would miss
+type DailyAggregateProcessor struct {
+	Threshold decimal.Decimal
+	// Location defines calendar days, UTC when nil. Days follow its clock, DST days lasting 23 or 25 hours.
+	Location *time.Location
+	// Rolling sums over any 24h window instead of calendar days
+	Rolling bool
+	// Conversion sums amounts in its base currency, Threshold being expressed in it. Nil sums raw amounts.
+	Conversion *CurrencyConversion
+	// Amounts rounds amounts to their currency's scale before they are summed, the zero AmountPolicy when nil
+	Amounts *AmountPolicy
+}
+
+func NewDailyAggregateProcessor(threshold decimal.Decimal, location *time.Location) DailyAggregateProcessor {
+	return DailyAggregateProcessor{
+		Threshold: threshold,
+		Location:  location,
+	}
+}
+
+type userDay struct {
+	userID uuid.UUID
+	date   civilDate
+}
+
+func (d DailyAggregateProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	transactions, flaggedUsers := d.Conversion.convert(transactions)
+	if d.Rolling {
+		return d.processRolling(transactions, flaggedUsers)
+	}
+
+	location := calendarLocation(d.Location)
+	amounts := d.Amounts.orDefault()
+	totals := make(map[userDay]decimal.Decimal)
+	for _, tx := range transactions {
+		key := userDay{userID: tx.UserID, date: localDate(tx.CreatedAt, location)}
+		totals[key] = totals[key].Add(amounts.spend(tx))
+	}
+
+	for key, total := range totals {
+		if total.GreaterThan(d.Threshold) {
+			flaggedUsers[key.userID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// processRolling reuses the per-user sort + sliding window of the velocity processors over amounts
+func (d DailyAggregateProcessor) processRolling(transactions []Transaction, flaggedUsers map[uuid.UUID]struct{}) map[uuid.UUID]struct{} {
+	for _, userTransactions := range groupTransactions(transactions, velocityOptions{}, nil) {
+		for userID, txs := range userTransactions {
+			sortByCreatedAt(txs)
+
+			if exceedsWindowAmount(txs, 24*time.Hour, d.Threshold, d.Amounts.orDefault()) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
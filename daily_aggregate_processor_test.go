@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDailyAggregateProcessor_Process(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	userID := uuid.New()
+	localMidnight := time.Date(2024, 3, 2, 0, 0, 0, 0, paris)
+
+	tx := func(at time.Time, amount int64) Transaction {
+		return Transaction{UserID: userID, Amount: decimal.NewFromInt(amount), CreatedAt: at}
+	}
+
+	twentySmall := make([]Transaction, 20)
+	for i := range twentySmall {
+		twentySmall[i] = tx(localMidnight.Add(time.Duration(i)*30*time.Minute), 600)
+	}
+
+	tests := []struct {
+		name         string
+		rolling      bool
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "twenty small transactions in one day",
+			transactions: twentySmall,
+			wantFlagged:  true,
+		},
+		{
+			name:         "either side of local midnight in calendar mode",
+			transactions: []Transaction{tx(localMidnight.Add(-time.Minute), 6000), tx(localMidnight.Add(time.Minute), 6000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "either side of local midnight in rolling mode",
+			rolling:      true,
+			transactions: []Transaction{tx(localMidnight.Add(-time.Minute), 6000), tx(localMidnight.Add(time.Minute), 6000)},
+			wantFlagged:  true,
+		},
+		{
+			// 23:30 UTC on the 1st is 00:30 on the 2nd in Paris, the same local day as 10:00
+			name:         "calendar days follow the configured location",
+			transactions: []Transaction{tx(time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC), 6000), tx(localMidnight.Add(10*time.Hour), 6000)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "rolling window more than a day apart",
+			rolling:      true,
+			transactions: []Transaction{tx(localMidnight, 6000), tx(localMidnight.Add(24*time.Hour+time.Second), 6000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "daily total at the threshold",
+			transactions: []Transaction{tx(localMidnight.Add(time.Hour), 5000), tx(localMidnight.Add(2*time.Hour), 5000)},
+			wantFlagged:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewDailyAggregateProcessor(decimal.NewFromInt(10000), paris)
+			processor.Rolling = tt.rolling
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+// On 2024-03-31 Paris clocks jump from 02:00 CET to 03:00 CEST, a 23 hour day, and on 2024-10-27
+// they go back from 03:00 CEST to 02:00 CET, a 25 hour day
+func TestLocalDate_DST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	spring := civilDate{year: 2024, month: time.March, day: 31}
+	autumn := civilDate{year: 2024, month: time.October, day: 27}
+	tests := []struct {
+		name string
+		t    time.Time
+		want civilDate
+	}{
+		{"spring 01:30 CET", time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC), spring},
+		{"spring 02:30 does not exist and reads 03:30 CEST", time.Date(2024, 3, 31, 2, 30, 0, 0, paris), spring},
+		{"spring 03:30 CEST", time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), spring},
+		{"spring 00:00 CET opens the day", time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC), spring},
+		{"spring 23:59 CEST, 22h59 later, closes it", time.Date(2024, 3, 31, 21, 59, 0, 0, time.UTC), spring},
+		{"spring 00:00 CEST is the next day", time.Date(2024, 3, 31, 22, 0, 0, 0, time.UTC), civilDate{year: 2024, month: time.April, day: 1}},
+		{"autumn 01:30 CEST", time.Date(2024, 10, 26, 23, 30, 0, 0, time.UTC), autumn},
+		{"autumn first 02:30, CEST", time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), autumn},
+		{"autumn second 02:30, CET", time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC), autumn},
+		{"autumn 03:30 CET", time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC), autumn},
+		{"autumn 00:00 CEST opens the day", time.Date(2024, 10, 26, 22, 0, 0, 0, time.UTC), autumn},
+		{"autumn 23:59 CET, 24h59 later, closes it", time.Date(2024, 10, 27, 22, 59, 0, 0, time.UTC), autumn},
+		{"autumn 00:00 CET is the next day", time.Date(2024, 10, 27, 23, 0, 0, 0, time.UTC), civilDate{year: 2024, month: time.October, day: 28}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, localDate(tt.t, paris))
+		})
+	}
+
+	assert.Equal(t, autumn, localDate(time.Date(2024, 10, 27, 23, 0, 0, 0, paris), nil), "nil location is UTC")
+}
+
+func TestDailyAggregateProcessor_Process_DST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	processor := NewDailyAggregateProcessor(decimal.NewFromInt(10000), paris)
+
+	tests := []struct {
+		name        string
+		first, last time.Time
+		wantFlagged bool
+	}{
+		{"both ends of the 25 hour day", time.Date(2024, 10, 27, 0, 0, 0, 0, paris), time.Date(2024, 10, 27, 23, 59, 0, 0, paris), true},
+		{"second 02:30 and 00:30 the next day", time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC), time.Date(2024, 10, 28, 0, 30, 0, 0, paris), false},
+		{"both ends of the 23 hour day", time.Date(2024, 3, 31, 0, 0, 0, 0, paris), time.Date(2024, 3, 31, 23, 59, 0, 0, paris), true},
+		{"03:30 CEST and 00:30 the next day, 22 hours apart", time.Date(2024, 3, 31, 3, 30, 0, 0, paris), time.Date(2024, 4, 1, 0, 30, 0, 0, paris), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			flagged := processor.Process(context.Background(), []Transaction{
+				{UserID: userID, Amount: decimal.NewFromInt(6000), CreatedAt: tt.first},
+				{UserID: userID, Amount: decimal.NewFromInt(6000), CreatedAt: tt.last},
+			})
+			assert.Equal(t, tt.wantFlagged, len(flagged) == 1)
+		})
+	}
+}
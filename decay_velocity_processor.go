@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DecayVelocityProcessor scores users by exponentially decayed transaction counts, so activity
+// spread just below a hard count threshold still accumulates when it is close together
+type DecayVelocityProcessor struct {
+	HalfLife  time.Duration
+	Threshold float64
+}
+
+func NewDecayVelocityProcessor(halfLife time.Duration, threshold float64) DecayVelocityProcessor {
+	return DecayVelocityProcessor{
+		HalfLife:  halfLife,
+		Threshold: threshold,
+	}
+}
+
+func (d DecayVelocityProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID, score := range d.Scores(ctx, transactions) {
+		if score > d.Threshold {
+			flaggedUsers[userID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// Scores returns each user's decayed score, where a transaction contributes exp(-Δt/τ) with Δt its
+// age relative to the user's most recent transaction and τ = HalfLife / ln 2
+func (d DecayVelocityProcessor) Scores(_ context.Context, transactions []Transaction) map[uuid.UUID]float64 {
+	latest := make(map[uuid.UUID]time.Time)
+	for _, tx := range transactions {
+		if last, ok := latest[tx.UserID]; !ok || tx.CreatedAt.After(last) {
+			latest[tx.UserID] = tx.CreatedAt
+		}
+	}
+
+	tau := float64(d.HalfLife) / math.Ln2
+	scores := make(map[uuid.UUID]float64, len(latest))
+
+	// O(N), no per-user sorting needed since every weight is relative to the latest transaction
+	for _, tx := range transactions {
+		age := latest[tx.UserID].Sub(tx.CreatedAt)
+
+		weight := 0.0
+		if tau > 0 {
+			weight = math.Exp(-float64(age) / tau)
+		} else if age == 0 {
+			// A zero half-life only counts transactions sharing the latest timestamp
+			weight = 1
+		}
+
+		scores[tx.UserID] += weight
+	}
+
+	return scores
+}
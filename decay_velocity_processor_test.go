@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecayVelocityProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	spreadUser := uuid.New()
+	packedUser := uuid.New()
+
+	var transactions []Transaction
+	for i := 0; i < 8; i++ {
+		transactions = append(transactions,
+			Transaction{UserID: spreadUser, CreatedAt: baseTime.Add(time.Duration(i) * 4 * 24 * time.Hour)},
+			Transaction{UserID: packedUser, CreatedAt: baseTime.Add(time.Duration(i) * 3 * time.Hour)},
+		)
+	}
+
+	processor := NewDecayVelocityProcessor(24*time.Hour, 4)
+
+	scores := processor.Scores(context.Background(), transactions)
+	assert.Less(t, scores[spreadUser], 4.0)
+	assert.Greater(t, scores[packedUser], 4.0)
+	assert.Greater(t, scores[packedUser], scores[spreadUser])
+
+	flaggedUsers := processor.Process(context.Background(), transactions)
+	assert.Equal(t, map[uuid.UUID]struct{}{packedUser: {}}, flaggedUsers)
+}
+
+func TestDecayVelocityProcessor_Scores(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	transactions := []Transaction{
+		{UserID: userID, CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: userID, CreatedAt: baseTime},
+		{UserID: userID, CreatedAt: baseTime.Add(time.Hour)},
+	}
+
+	// weights relative to the latest transaction: 1, 1/2, 1/4
+	scores := NewDecayVelocityProcessor(time.Hour, 0).Scores(context.Background(), transactions)
+	assert.InDelta(t, 1.75, scores[userID], 1e-9)
+
+	scores = NewDecayVelocityProcessor(0, 0).Scores(context.Background(), transactions)
+	assert.InDelta(t, 1.0, scores[userID], 1e-9)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/google/uuid"
+)
+
+// DeduplicateTransactions drops the transactions whose TransactionID appeared earlier in the batch,
+// as redelivered by an at-least-once source, and returns the rest in order along with how many it
+// dropped. Transactions with a zero TransactionID are always kept. The input is not modified.
+func DeduplicateTransactions(transactions []Transaction) ([]Transaction, int) {
+	seen := make(map[uuid.UUID]struct{}, len(transactions))
+	var deduplicated []Transaction
+	for i, tx := range transactions {
+		if tx.TransactionID == uuid.Nil {
+			if deduplicated != nil {
+				deduplicated = append(deduplicated, tx)
+			}
+			continue
+		}
+
+		if _, duplicate := seen[tx.TransactionID]; !duplicate {
+			seen[tx.TransactionID] = struct{}{}
+			if deduplicated != nil {
+				deduplicated = append(deduplicated, tx)
+			}
+			continue
+		}
+
+		// the batch is only copied from its first duplicate on
+		if deduplicated == nil {
+			deduplicated = make([]Transaction, i, len(transactions)-1)
+			copy(deduplicated, transactions[:i])
+		}
+	}
+
+	if deduplicated == nil {
+		return transactions, 0
+	}
+
+	return deduplicated, len(transactions) - len(deduplicated)
+}
+
+// WithDeduplication drops duplicated transactions from every batch with DeduplicateTransactions,
+// after validation and before the processors run, the drop count being reported in
+// Summary.DuplicatesDropped. Redelivered transactions then no longer count twice towards velocity
+// and sums, nor are they caught by DuplicateTransactionProcessor. ShardedEvaluate deduplicates
+// each shard, which holds every transaction of its users.
+func WithDeduplication() RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.deduplicate = true
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeduplicateTransactions(t *testing.T) {
+	userID := uuid.New()
+	tx := func(id uuid.UUID, amount int64) Transaction {
+		return Transaction{TransactionID: id, UserID: userID, Amount: decimal.NewFromInt(amount)}
+	}
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		want         []Transaction
+		wantDropped  int
+	}{
+		{
+			name:         "empty batch",
+			transactions: nil,
+			want:         nil,
+		},
+		{
+			name:         "no duplicates",
+			transactions: []Transaction{tx(a, 1), tx(b, 2), tx(c, 3)},
+			want:         []Transaction{tx(a, 1), tx(b, 2), tx(c, 3)},
+		},
+		{
+			name:         "first occurrence kept",
+			transactions: []Transaction{tx(a, 1), tx(b, 2), tx(a, 3), tx(c, 4), tx(b, 5), tx(a, 6)},
+			want:         []Transaction{tx(a, 1), tx(b, 2), tx(c, 4)},
+			wantDropped:  3,
+		},
+		{
+			name:         "zero IDs never deduplicated",
+			transactions: []Transaction{tx(uuid.Nil, 1), tx(a, 2), tx(uuid.Nil, 1), tx(a, 2), tx(uuid.Nil, 3)},
+			want:         []Transaction{tx(uuid.Nil, 1), tx(a, 2), tx(uuid.Nil, 1), tx(uuid.Nil, 3)},
+			wantDropped:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := slices.Clone(tt.transactions)
+
+			got, dropped := DeduplicateTransactions(tt.transactions)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantDropped, dropped)
+			assert.Equal(t, input, tt.transactions, "the input is left unchanged")
+		})
+	}
+}
+
+func TestRuleEngine_Evaluate_WithDeduplication(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	velocityUser, amountUser, otherUser := uuid.New(), uuid.New(), uuid.New()
+
+	tx := func(userID uuid.UUID, minutes int, amount int64) Transaction {
+		return Transaction{
+			TransactionID: uuid.New(),
+			UserID:        userID,
+			Amount:        decimal.NewFromInt(amount),
+			CreatedAt:     baseTime.Add(time.Duration(minutes) * time.Minute),
+		}
+	}
+
+	// three transactions in an hour, at the velocity limit, redelivering the last tips it over
+	velocity := []Transaction{tx(velocityUser, 0, 10), tx(velocityUser, 20, 10), tx(velocityUser, 40, 10)}
+	// 9000 in a day, at the aggregate limit, redelivering a payment tips it over
+	amount := []Transaction{tx(amountUser, 0, 5000), tx(amountUser, 60, 4000)}
+	transactions := slices.Concat(velocity, amount, []Transaction{tx(otherUser, 0, 10)})
+	transactions = append(transactions, velocity[2], amount[0])
+
+	newEngine := func(opts ...RuleEngineOption) *RuleEngine {
+		return NewRuleEngine([]RuleProcessor{
+			NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(time.Hour, 3)}),
+			NewDailyAggregateProcessor(decimal.NewFromInt(9000), time.UTC),
+		}, opts...)
+	}
+
+	tests := []struct {
+		name           string
+		engine         *RuleEngine
+		wantFlagged    []int
+		wantDropped    int
+		wantTotal      int
+		wantSummaryOut bool
+	}{
+		{
+			name:        "duplicates counted twice",
+			engine:      newEngine(),
+			wantFlagged: []int{1, 1},
+			wantTotal:   len(transactions),
+		},
+		{
+			name:           "duplicates dropped",
+			engine:         newEngine(WithDeduplication()),
+			wantFlagged:    []int{0, 0},
+			wantDropped:    2,
+			wantTotal:      len(transactions) - 2,
+			wantSummaryOut: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.engine.Evaluate(context.Background(), transactions)
+			require.NoError(t, err)
+
+			require.Len(t, result.Rules, 2)
+			for i, rule := range result.Rules {
+				assert.Equal(t, tt.wantFlagged[i], rule.FlaggedUsers, rule.Name)
+			}
+			assert.Equal(t, tt.wantDropped, result.Summary.DuplicatesDropped)
+			assert.Equal(t, tt.wantTotal, result.Summary.TotalTransactions)
+			assert.Equal(t, tt.wantSummaryOut, strings.Contains(result.Summary.String(), "duplicates dropped: 2"))
+
+			sharded, err := tt.engine.ShardedEvaluate(context.Background(), NewSliceSource(transactions), 3)
+			require.NoError(t, err)
+			assert.Equal(t, result.Rules, sharded.Rules)
+			assert.Equal(t, tt.wantDropped, sharded.Summary.DuplicatesDropped)
+			assert.Equal(t, tt.wantTotal, sharded.Summary.TotalTransactions)
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// DormancyProcessor flags users whose account reactivates with a burst of activity after a long quiet gap
+type DormancyProcessor struct {
+	Dormancy time.Duration
+	Window   time.Duration
+	// CountThreshold flags more than this many transactions within Window after the gap, 0 disables it
+	CountThreshold int
+	// AmountThreshold flags a post-gap sum above it within Window, zero disables it
+	AmountThreshold decimal.Decimal
+	// StartIsDormant treats a user's first transaction as following a dormant period
+	StartIsDormant bool
+}
+
+func NewDormancyProcessor(dormancy, window time.Duration, countThreshold int, amountThreshold decimal.Decimal) DormancyProcessor {
+	return DormancyProcessor{
+		Dormancy:        dormancy,
+		Window:          window,
+		CountThreshold:  countThreshold,
+		AmountThreshold: amountThreshold,
+	}
+}
+
+func (d DormancyProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, velocityOptions{}, nil) {
+		for userID, txs := range userTransactions {
+			sortByCreatedAt(txs)
+
+			if d.hasReactivationBurst(txs) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// ProcessGrouped is Process over the batch the engine grouped once for every rule, reading each
+// user's transactions from the shared ordering by CreatedAt
+func (d DormancyProcessor) ProcessGrouped(_ context.Context, grouped GroupedTransactions) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	for _, userID := range grouped.Users() {
+		if d.hasReactivationBurst(grouped.SortedForUser(userID)) {
+			flaggedUsers[userID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// hasReactivationBurst checks the activity following every gap longer than Dormancy
+// Time complexity: O(n + g * w), g being the gaps longer than Dormancy and w the most transactions within
+// Window after one. Each scan runs to the end of its Window, past any later gap, so O(n^2) at worst.
+func (d DormancyProcessor) hasReactivationBurst(txs []Transaction) bool {
+	for i := range txs {
+		dormant := i == 0 && d.StartIsDormant
+		if i > 0 {
+			dormant = txs[i].CreatedAt.Sub(txs[i-1].CreatedAt) > d.Dormancy
+		}
+		if !dormant {
+			continue
+		}
+
+		count := 0
+		sum := decimal.Zero
+		for j := i; j < len(txs) && txs[j].CreatedAt.Sub(txs[i].CreatedAt) <= d.Window; j++ {
+			count++
+			sum = sum.Add(spendAmount(txs[j]))
+		}
+
+		if d.CountThreshold > 0 && count > d.CountThreshold {
+			return true
+		}
+		if d.AmountThreshold.IsPositive() && sum.GreaterThan(d.AmountThreshold) {
+			return true
+		}
+	}
+
+	return false
+}
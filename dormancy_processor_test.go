@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDormancyProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	day := 24 * time.Hour
+
+	tx := func(days int, amount int64) Transaction {
+		return Transaction{
+			UserID:    userID,
+			Amount:    decimal.NewFromInt(amount),
+			CreatedAt: baseTime.Add(time.Duration(days) * day),
+		}
+	}
+
+	tests := []struct {
+		name           string
+		startIsDormant bool
+		transactions   []Transaction
+		wantFlagged    bool
+	}{
+		{
+			name:         "long gap followed by a burst of large amounts",
+			transactions: []Transaction{tx(0, 50), tx(120, 8000), tx(121, 9000)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "long gap followed by many transactions",
+			transactions: []Transaction{tx(0, 50), tx(100, 10), tx(101, 10), tx(102, 10), tx(103, 10)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "long gap followed by one small transaction",
+			transactions: []Transaction{tx(0, 50), tx(120, 20)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "burst outside the post-dormancy window",
+			transactions: []Transaction{tx(0, 50), tx(120, 20), tx(130, 9000), tx(131, 9000)},
+			wantFlagged:  false,
+		},
+		{
+			name: "continuous activity",
+			transactions: []Transaction{
+				tx(0, 5000), tx(30, 5000), tx(60, 5000), tx(90, 5000), tx(91, 5000), tx(92, 5000),
+			},
+			wantFlagged: false,
+		},
+		{
+			name:         "first transactions are not post-dormancy by default",
+			transactions: []Transaction{tx(0, 9000), tx(1, 9000)},
+			wantFlagged:  false,
+		},
+		{
+			name:           "first transactions with account start treated as dormant",
+			startIsDormant: true,
+			transactions:   []Transaction{tx(1, 9000), tx(0, 9000)},
+			wantFlagged:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewDormancyProcessor(90*day, week, 3, decimal.NewFromInt(10000))
+			processor.StartIsDormant = tt.startIsDormant
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
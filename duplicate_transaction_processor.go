@@ -0,0 +1,91 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DuplicateTransactionProcessor flags users with near-identical transactions, as produced by upstream
+// retries or deliberate replay: equal Amount and Country within Tolerance of each other
+type DuplicateTransactionProcessor struct {
+	Tolerance time.Duration
+	// MinGroupSize is how many repeats make a group, at least two: a lone transaction is no duplicate
+	MinGroupSize int
+}
+
+func NewDuplicateTransactionProcessor(tolerance time.Duration, minGroupSize int) DuplicateTransactionProcessor {
+	return DuplicateTransactionProcessor{
+		Tolerance:    tolerance,
+		MinGroupSize: minGroupSize,
+	}
+}
+
+func (d DuplicateTransactionProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID := range d.Groups(ctx, transactions) {
+		flaggedUsers[userID] = struct{}{}
+	}
+
+	return flaggedUsers
+}
+
+// Groups returns, per user, every group of at least MinGroupSize duplicates, two when below. A group starts at a
+// transaction and holds the following ones with the same Amount and Country within Tolerance of it.
+// Time complexity: O(n log n) per user, sorting by country, amount then time
+func (d DuplicateTransactionProcessor) Groups(_ context.Context, transactions []Transaction) map[uuid.UUID][][]Transaction {
+	groups := make(map[uuid.UUID][][]Transaction)
+	minGroupSize := max(d.MinGroupSize, 2)
+
+	for _, userTransactions := range groupTransactions(transactions, velocityOptions{}, nil) {
+		for userID, txs := range userTransactions {
+			slices.SortFunc(txs, func(a, b Transaction) int {
+				return cmp.Or(
+					cmp.Compare(normalizeCountry(a.Country), normalizeCountry(b.Country)),
+					a.Amount.Cmp(b.Amount),
+					a.CreatedAt.Compare(b.CreatedAt),
+				)
+			})
+
+			for start := 0; start < len(txs); {
+				end := start + 1
+				for end < len(txs) && d.isDuplicate(txs[start], txs[end]) {
+					end++
+				}
+
+				if end-start >= minGroupSize {
+					groups[userID] = append(groups[userID], txs[start:end:end])
+				}
+				start = end
+			}
+		}
+	}
+
+	return groups
+}
+
+// isDuplicate reports whether later repeats first, given both are sorted with first not after later
+func (d DuplicateTransactionProcessor) isDuplicate(first, later Transaction) bool {
+	return normalizeCountry(first.Country) == normalizeCountry(later.Country) &&
+		first.Amount.Equal(later.Amount) &&
+		later.CreatedAt.Sub(first.CreatedAt) <= d.Tolerance
+}
+
+func (d DuplicateTransactionProcessor) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	groups := d.Groups(ctx, transactions)[userID]
+
+	var evidence []Transaction
+	for _, group := range groups {
+		evidence = append(evidence, group...)
+	}
+
+	return evidence, map[string]string{
+		"groups":    strconv.Itoa(len(groups)),
+		"tolerance": formatDuration(d.Tolerance),
+	}
+}
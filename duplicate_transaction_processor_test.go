@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDuplicateTransactionProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	tx := func(offset time.Duration, amount, country string) Transaction {
+		return Transaction{
+			UserID:    userID,
+			Amount:    decimal.RequireFromString(amount),
+			Country:   country,
+			CreatedAt: baseTime.Add(offset),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		minGroupSize int
+		transactions []Transaction
+		wantGroups   int
+	}{
+		{
+			name:         "exact duplicates",
+			minGroupSize: 2,
+			transactions: []Transaction{tx(0, "250.00", "FR"), tx(0, "250", "FR")},
+			wantGroups:   1,
+		},
+		{
+			name:         "same amount ten minutes apart",
+			minGroupSize: 2,
+			transactions: []Transaction{tx(0, "250", "FR"), tx(10*time.Minute, "250", "FR")},
+			wantGroups:   0,
+		},
+		{
+			name:         "same amount in different countries",
+			minGroupSize: 2,
+			transactions: []Transaction{tx(0, "250", "FR"), tx(time.Second, "250", "DE")},
+			wantGroups:   0,
+		},
+		{
+			name:         "different amounts",
+			minGroupSize: 2,
+			transactions: []Transaction{tx(0, "250", "FR"), tx(time.Second, "250.01", "FR")},
+			wantGroups:   0,
+		},
+		{
+			name:         "three-way duplicates counted as one group",
+			minGroupSize: 2,
+			transactions: []Transaction{
+				tx(30*time.Second, "99", "FR"), tx(0, "99", "fr"), tx(59*time.Second, "99", "FR"),
+				tx(time.Hour, "5", "FR"),
+			},
+			wantGroups: 1,
+		},
+		{
+			name:         "pair below the minimum group size",
+			minGroupSize: 3,
+			transactions: []Transaction{tx(0, "99", "FR"), tx(time.Second, "99", "FR")},
+			wantGroups:   0,
+		},
+		{
+			name:         "two separate duplicate groups",
+			minGroupSize: 2,
+			transactions: []Transaction{
+				tx(0, "99", "FR"), tx(time.Second, "99", "FR"),
+				tx(time.Hour, "99", "FR"), tx(time.Hour+time.Second, "99", "FR"),
+			},
+			wantGroups: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewDuplicateTransactionProcessor(60*time.Second, tt.minGroupSize)
+
+			groups := processor.Groups(context.Background(), tt.transactions)
+			assert.Len(t, groups[userID], tt.wantGroups)
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantGroups > 0, flagged)
+		})
+	}
+}
+
+func TestDuplicateTransactionProcessor_Process_MinGroupSizeBelowTwo(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	single, repeated := uuid.New(), uuid.New()
+	transactions := []Transaction{
+		{UserID: single, Amount: decimal.NewFromInt(250), Country: "FR", CreatedAt: baseTime},
+		{UserID: repeated, Amount: decimal.NewFromInt(99), Country: "FR", CreatedAt: baseTime},
+		{UserID: repeated, Amount: decimal.NewFromInt(99), Country: "FR", CreatedAt: baseTime.Add(time.Second)},
+	}
+
+	for _, processor := range []DuplicateTransactionProcessor{
+		{Tolerance: time.Minute},
+		NewDuplicateTransactionProcessor(time.Minute, 0),
+		NewDuplicateTransactionProcessor(time.Minute, 1),
+	} {
+		assert.Equal(t, map[uuid.UUID]struct{}{repeated: {}}, processor.Process(context.Background(), transactions),
+			"a lone transaction is no duplicate with MinGroupSize %d", processor.MinGroupSize)
+	}
+}
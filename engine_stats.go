@@ -0,0 +1,239 @@
+package main
+
+import (
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EngineStats is a snapshot of the counters a RuleEngine accumulates across runs since it was
+// created or last reset
+type EngineStats struct {
+	Evaluations int64 `json:"evaluations"`
+	// RuleAlerts and CountryAlerts count alerts per rule name and per normalized evidence country
+	RuleAlerts    map[string]int64 `json:"rule_alerts"`
+	CountryAlerts map[string]int64 `json:"country_alerts"`
+	// FlaggedUsersEstimate approximates the distinct flagged users, within a few percent
+	FlaggedUsersEstimate uint64 `json:"flagged_users_estimate"`
+	// RuleDurations holds the processing time quantiles per rule name
+	RuleDurations map[string]DurationQuantiles `json:"rule_durations"`
+}
+
+// DurationQuantiles are upper bounds of the observed durations, exact to a factor of two
+type DurationQuantiles struct {
+	Count int64         `json:"count"`
+	P50   time.Duration `json:"p50"`
+	P95   time.Duration `json:"p95"`
+}
+
+// Stats returns a copy of the engine's counters. It is safe to call concurrently with Evaluate.
+func (r *RuleEngine) Stats() EngineStats {
+	return r.currentStats().snapshot()
+}
+
+// ResetStats starts the counters over. Runs in progress keep recording into the previous counters.
+func (r *RuleEngine) ResetStats() {
+	r.stats.Store(newEngineStats())
+}
+
+// currentStats returns the live counters, creating them on first use
+func (r *RuleEngine) currentStats() *engineStats {
+	if stats := r.stats.Load(); stats != nil {
+		return stats
+	}
+
+	r.stats.CompareAndSwap(nil, newEngineStats())
+	return r.stats.Load()
+}
+
+type engineStats struct {
+	evaluations  atomic.Int64
+	flaggedUsers hyperLogLog
+
+	mu        sync.Mutex
+	rules     map[string]*ruleStats
+	countries map[string]*atomic.Int64
+}
+
+type ruleStats struct {
+	alerts    atomic.Int64
+	durations durationHistogram
+}
+
+func newEngineStats() *engineStats {
+	return &engineStats{
+		rules:     make(map[string]*ruleStats),
+		countries: make(map[string]*atomic.Int64),
+	}
+}
+
+func (s *engineStats) rule(name string) *ruleStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.rules[name]
+	if !ok {
+		stats = &ruleStats{}
+		s.rules[name] = stats
+	}
+
+	return stats
+}
+
+func (s *engineStats) country(country string) *atomic.Int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, ok := s.countries[country]
+	if !ok {
+		counter = &atomic.Int64{}
+		s.countries[country] = counter
+	}
+
+	return counter
+}
+
+// recordAlert counts alert toward its rule, its evidence countries and the flagged users
+func (s *engineStats) recordAlert(alert Alert) {
+	s.rule(alert.RuleName).alerts.Add(1)
+	s.flaggedUsers.add(alert.UserID)
+
+	countries := make(map[string]struct{})
+	for _, tx := range alert.Evidence {
+		if country := normalizeCountry(tx.Country); country != "" {
+			countries[country] = struct{}{}
+		}
+	}
+	for country := range countries {
+		s.country(country).Add(1)
+	}
+}
+
+func (s *engineStats) snapshot() EngineStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := EngineStats{
+		Evaluations:          s.evaluations.Load(),
+		RuleAlerts:           make(map[string]int64, len(s.rules)),
+		CountryAlerts:        make(map[string]int64, len(s.countries)),
+		FlaggedUsersEstimate: s.flaggedUsers.estimate(),
+		RuleDurations:        make(map[string]DurationQuantiles, len(s.rules)),
+	}
+	for name, rule := range s.rules {
+		snapshot.RuleAlerts[name] = rule.alerts.Load()
+		snapshot.RuleDurations[name] = rule.durations.quantiles()
+	}
+	for country, counter := range s.countries {
+		snapshot.CountryAlerts[country] = counter.Load()
+	}
+
+	return snapshot
+}
+
+// durationHistogram counts durations in power-of-two buckets from 1µs, the last one open-ended,
+// trading precision for constant memory and lock-free recording
+type durationHistogram struct {
+	buckets [40]atomic.Int64
+}
+
+func (h *durationHistogram) record(d time.Duration) {
+	bucket := 0
+	if d > time.Microsecond {
+		bucket = bits.Len64(uint64(d-1) / uint64(time.Microsecond))
+	}
+	h.buckets[min(bucket, len(h.buckets)-1)].Add(1)
+}
+
+func (h *durationHistogram) quantiles() DurationQuantiles {
+	var counts [len(durationHistogram{}.buckets)]int64
+	var quantiles DurationQuantiles
+	for i := range h.buckets {
+		counts[i] = h.buckets[i].Load()
+		quantiles.Count += counts[i]
+	}
+
+	quantiles.P50 = histogramQuantile(counts[:], quantiles.Count, 0.50)
+	quantiles.P95 = histogramQuantile(counts[:], quantiles.Count, 0.95)
+
+	return quantiles
+}
+
+// histogramQuantile returns the upper bound of the bucket holding the q-quantile, zero without observations
+func histogramQuantile(counts []int64, total int64, q float64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(q * float64(total)))
+	var seen int64
+	for i, count := range counts {
+		seen += count
+		if seen >= rank {
+			return time.Microsecond << i
+		}
+	}
+
+	return time.Microsecond << (len(counts) - 1)
+}
+
+// hyperLogLog estimates the number of distinct users added with 2^hllPrecision registers,
+// for a standard error of about 1.04/sqrt(2^hllPrecision), 3% here
+type hyperLogLog struct {
+	registers [1 << hllPrecision]atomic.Uint32
+}
+
+const hllPrecision = 10
+
+func (h *hyperLogLog) add(userID uuid.UUID) {
+	hash := fnv.New64a()
+	hash.Write(userID[:])
+	sum := mix64(hash.Sum64())
+
+	register := &h.registers[sum>>(64-hllPrecision)]
+	rank := uint32(bits.LeadingZeros64(sum<<hllPrecision|1<<(hllPrecision-1)) + 1)
+	for {
+		current := register.Load()
+		if rank <= current || register.CompareAndSwap(current, rank) {
+			return
+		}
+	}
+}
+
+func (h *hyperLogLog) estimate() uint64 {
+	m := float64(len(h.registers))
+
+	var sum float64
+	zeros := 0
+	for i := range h.registers {
+		rank := h.registers[i].Load()
+		sum += math.Ldexp(1, -int(rank))
+		if rank == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// linear counting is more accurate for small cardinalities
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(math.Round(estimate))
+}
+
+// mix64 is the splitmix64 finalizer, spreading FNV's weak high bits over the whole word
+func mix64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+
+	return x
+}
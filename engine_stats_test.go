@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleEngine_Stats(t *testing.T) {
+	const runs, usersPerRun = 8, 50
+
+	engine := NewRuleEngine([]RuleProcessor{
+		NewCountryBlackListProcessor("IR"),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000)},
+	})
+
+	var wg sync.WaitGroup
+	for run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			var transactions []Transaction
+			for i := range usersPerRun {
+				country := "IR"
+				if i%2 == 1 {
+					country = "fr"
+				}
+				transactions = append(transactions, Transaction{
+					UserID:    testUserID(run*usersPerRun + i),
+					Country:   country,
+					Amount:    decimal.NewFromInt(20000),
+					CreatedAt: time.Now(),
+				})
+			}
+
+			_, err := engine.EvaluateAlerts(context.Background(), transactions)
+			assert.NoError(t, err)
+			_ = engine.Stats()
+		}()
+	}
+	wg.Wait()
+
+	stats := engine.Stats()
+	assert.Equal(t, int64(runs), stats.Evaluations)
+	assert.Equal(t, map[string]int64{
+		"CountryBlackListProcessor":  runs * usersPerRun / 2,
+		"TransactionAmountProcessor": runs * usersPerRun,
+	}, stats.RuleAlerts)
+	assert.Equal(t, map[string]int64{"IR": runs * usersPerRun, "FR": runs * usersPerRun / 2}, stats.CountryAlerts)
+	assert.InEpsilon(t, runs*usersPerRun, stats.FlaggedUsersEstimate, 0.1)
+
+	require.Contains(t, stats.RuleDurations, "CountryBlackListProcessor")
+	durations := stats.RuleDurations["CountryBlackListProcessor"]
+	assert.Equal(t, int64(runs), durations.Count)
+	assert.Positive(t, durations.P50)
+	assert.GreaterOrEqual(t, durations.P95, durations.P50)
+
+	stats.RuleAlerts["CountryBlackListProcessor"] = 0
+	assert.Equal(t, int64(runs*usersPerRun/2), engine.Stats().RuleAlerts["CountryBlackListProcessor"], "the snapshot is a copy")
+
+	engine.ResetStats()
+	stats = engine.Stats()
+	assert.Zero(t, stats.Evaluations)
+	assert.Empty(t, stats.RuleAlerts)
+	assert.Zero(t, stats.FlaggedUsersEstimate)
+}
+
+func TestDurationHistogram_Quantiles(t *testing.T) {
+	var h durationHistogram
+	assert.Equal(t, DurationQuantiles{}, h.quantiles())
+
+	for range 90 {
+		h.record(3 * time.Millisecond)
+	}
+	for range 10 {
+		h.record(time.Second)
+	}
+	h.record(0)
+
+	quantiles := h.quantiles()
+	assert.Equal(t, int64(101), quantiles.Count)
+	assert.Equal(t, 4096*time.Microsecond, quantiles.P50, "3ms falls in the (2.048ms, 4.096ms] bucket")
+	assert.Equal(t, 1048576*time.Microsecond, quantiles.P95)
+}
+
+func TestHyperLogLog_Estimate(t *testing.T) {
+	var h hyperLogLog
+	assert.Zero(t, h.estimate())
+
+	for _, n := range []int{10, 1000, 20000} {
+		h = hyperLogLog{}
+		for i := range n {
+			userID := testUserID(i)
+			h.add(userID)
+			h.add(userID)
+		}
+		assert.InEpsilon(t, n, h.estimate(), 0.1, "%d users", n)
+	}
+}
+
+// testUserID returns a deterministic user ID, keeping cardinality estimates stable between runs
+func testUserID(i int) uuid.UUID {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(strconv.Itoa(i)))
+}
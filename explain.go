@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Explainer is implemented by processors that can tell in one sentence why a user was flagged,
+// given the evidence and details returned by their AlertDetails
+type Explainer interface {
+	ExplainAlert(evidence []Transaction, details map[string]string) string
+}
+
+// Reason is one rule flagging the explained user
+type Reason struct {
+	RuleName string            `json:"rule_name"`
+	Severity Severity          `json:"severity"`
+	Evidence []Transaction     `json:"evidence"`
+	Details  map[string]string `json:"details"`
+	Text     string            `json:"text"`
+}
+
+// Explanation answers why a user was flagged, or states that they were not
+type Explanation struct {
+	UserID  uuid.UUID `json:"user_id"`
+	Flagged bool      `json:"flagged"`
+	// Reasons lists the rules that flagged the user, in registration order
+	Reasons   []Reason `json:"reasons"`
+	Narrative string   `json:"narrative"`
+}
+
+// Explain re-runs every registered processor on the transactions of userID alone and describes the
+// rules flagging them. Processors implementing Explainer contribute their own sentence, the others
+// a generic one built from their alert details. A processor failing is reported in the joined error,
+// the explanation still covering the others.
+func (r *RuleEngine) Explain(ctx context.Context, userID uuid.UUID, transactions []Transaction) (Explanation, error) {
+	var userTransactions []Transaction
+	for _, tx := range transactions {
+		if tx.UserID == userID {
+			userTransactions = append(userTransactions, tx)
+		}
+	}
+
+	explanation := Explanation{UserID: userID}
+	grouped := GroupByUser(userTransactions)
+	rules, _ := r.currentRules()
+	var errs []error
+	for _, rule := range rules {
+		name := ruleName(rule.processor)
+
+		flaggedUsers, err := runRule(ctx, rule.processor, grouped)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+		if _, flagged := flaggedUsers[userID]; !flagged {
+			continue
+		}
+
+		reason := Reason{
+			RuleName: name,
+			Severity: rule.severity,
+			Evidence: userTransactions,
+			Details:  map[string]string{},
+		}
+		if detailer, ok := rule.processor.(AlertDetailer); ok {
+			reason.Evidence, reason.Details = detailer.AlertDetails(ctx, userID, slices.Clone(userTransactions))
+		}
+		if aware, ok := rule.processor.(SeverityAware); ok {
+			reason.Severity = aware.AlertSeverity(rule.severity, reason.Evidence, reason.Details)
+		}
+
+		if explainer, ok := rule.processor.(Explainer); ok {
+			reason.Text = explainer.ExplainAlert(reason.Evidence, reason.Details)
+		} else {
+			reason.Text = genericExplanation(name, reason.Evidence, reason.Details)
+		}
+
+		explanation.Reasons = append(explanation.Reasons, reason)
+	}
+
+	explanation.Flagged = len(explanation.Reasons) > 0
+	explanation.Narrative = explanation.narrative(len(rules))
+
+	return explanation, errors.Join(errs...)
+}
+
+// narrative renders the reasons as a header line followed by one line per rule
+func (e Explanation) narrative(rules int) string {
+	if !e.Flagged {
+		return fmt.Sprintf("user %s was not flagged by any of %d rules", e.UserID, rules)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "user %s was flagged by %d of %d rules:", e.UserID, len(e.Reasons), rules)
+	for _, reason := range e.Reasons {
+		fmt.Fprintf(&b, "\n- %s", reason.Text)
+	}
+
+	return b.String()
+}
+
+// genericExplanation names the rule, counts the evidence and lists the details sorted by key
+func genericExplanation(name string, evidence []Transaction, details map[string]string) string {
+	text := fmt.Sprintf("flagged by %s: %s", name, pluralize(len(evidence), "transaction"))
+	if len(details) == 0 {
+		return text
+	}
+
+	pairs := make([]string, 0, len(details))
+	for _, key := range slices.Sorted(maps.Keys(details)) {
+		pairs = append(pairs, key+"="+details[key])
+	}
+
+	return text + " (" + strings.Join(pairs, ", ") + ")"
+}
+
+// velocityExplanation describes the first violated period from velocityAlertDetails
+func velocityExplanation(evidence []Transaction, details map[string]string) string {
+	period, _, _ := strings.Cut(details["periods"], ", ")
+
+	return fmt.Sprintf("flagged by %s velocity: %s between %s and %s against a limit of %s",
+		period, pluralize(len(evidence), "transaction"),
+		explanationDate(details["window_start"]), explanationDate(details["window_end"]), details["threshold"])
+}
+
+// explanationDate shortens a detail timestamp to its date, returning it unchanged if it does not parse
+func explanationDate(detail string) string {
+	t, err := time.Parse(time.RFC3339Nano, detail)
+	if err != nil {
+		return detail
+	}
+
+	return t.Format(time.DateOnly)
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleEngine_Explain(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	userID, other := uuid.New(), uuid.New()
+
+	var transactions []Transaction
+	for day := range 7 {
+		transactions = append(transactions, Transaction{
+			UserID:    userID,
+			Country:   "FR",
+			Amount:    decimal.NewFromInt(10),
+			CreatedAt: baseTime.Add(time.Duration(day) * 16 * time.Hour),
+		})
+	}
+	transactions = append(transactions,
+		Transaction{UserID: userID, Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: baseTime.Add(time.Hour)},
+		Transaction{UserID: other, Country: "IR", Amount: decimal.NewFromInt(20000), CreatedAt: baseTime},
+	)
+
+	engine := NewRuleEngine([]RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewNamedVelocityPeriod("weekly", week, 7)}),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000)},
+		NewCountryBlackListProcessor("IR"),
+		NewDuplicateTransactionProcessor(time.Minute, 2),
+		NewNotProcessor(NewCountryBlackListProcessor("RU")),
+	})
+
+	explanation, err := engine.Explain(context.Background(), userID, transactions)
+	require.NoError(t, err)
+
+	assert.True(t, explanation.Flagged)
+	require.Len(t, explanation.Reasons, 3)
+	assert.Equal(t, "VelocityProcessor", explanation.Reasons[0].RuleName)
+	assert.Equal(t, SeverityCritical, explanation.Reasons[1].Severity)
+	assert.Equal(t, "user "+userID.String()+" was flagged by 3 of 5 rules:\n"+
+		"- flagged by weekly velocity: 8 transactions between 2024-03-01 and 2024-03-05 against a limit of 7\n"+
+		"- flagged by country blacklist: 1 transaction in IR\n"+
+		"- flagged by NotProcessor: 8 transactions", explanation.Narrative)
+
+	explanation, err = engine.Explain(context.Background(), uuid.Nil, transactions)
+	require.NoError(t, err)
+	assert.False(t, explanation.Flagged)
+	assert.Empty(t, explanation.Reasons)
+	assert.Equal(t, "user 00000000-0000-0000-0000-000000000000 was not flagged by any of 5 rules", explanation.Narrative)
+}
+
+func TestGenericExplanation(t *testing.T) {
+	assert.Equal(t, "flagged by DuplicateTransactionProcessor: 2 transactions (groups=1, tolerance=1m)",
+		genericExplanation("DuplicateTransactionProcessor", make([]Transaction, 2), map[string]string{"tolerance": "1m", "groups": "1"}))
+	assert.Equal(t, "flagged by X: 1 transaction", genericExplanation("X", make([]Transaction, 1), nil))
+}
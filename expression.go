@@ -0,0 +1,449 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/shopspring/decimal"
+)
+
+// ExpressionError reports where an expression failed to compile
+type ExpressionError struct {
+	Expression string
+	// Position is the 1-based byte offset of the offending token, one past the end for a truncated expression
+	Position int
+	Message  string
+}
+
+func (e ExpressionError) Error() string {
+	return fmt.Sprintf("expression %q: position %d: %s", e.Expression, e.Position, e.Message)
+}
+
+// expressionKind is the type of an expression operand
+type expressionKind int
+
+const (
+	kindBool expressionKind = iota
+	kindNumber
+	kindString
+)
+
+func (k expressionKind) String() string {
+	return [...]string{"boolean", "number", "string"}[k]
+}
+
+// expressionField is a transaction field an expression can read. Country and currency fields are
+// normalized, and so are the literals they are compared with, as the processors comparing them do.
+type expressionField struct {
+	kind      expressionKind
+	number    func(Transaction) decimal.Decimal
+	text      func(Transaction) string
+	normalize func(string) string
+}
+
+func textExpressionField(get func(Transaction) string, normalize func(string) string) expressionField {
+	if normalize == nil {
+		return expressionField{kind: kindString, text: get}
+	}
+
+	return expressionField{
+		kind:      kindString,
+		text:      func(tx Transaction) string { return normalize(get(tx)) },
+		normalize: normalize,
+	}
+}
+
+var expressionFields = map[string]expressionField{
+	"amount":               {kind: kindNumber, number: func(tx Transaction) decimal.Decimal { return tx.Amount }},
+	"currency":             textExpressionField(func(tx Transaction) string { return tx.Currency }, normalizeCurrency),
+	"country":              textExpressionField(func(tx Transaction) string { return tx.Country }, normalizeCountry),
+	"destination_country":  textExpressionField(func(tx Transaction) string { return tx.DestinationCountry }, normalizeCountry),
+	"counterparty_country": textExpressionField(func(tx Transaction) string { return tx.CounterpartyCountry }, normalizeCountry),
+	"status":               textExpressionField(func(tx Transaction) string { return string(tx.Status) }, nil),
+	"direction":            textExpressionField(func(tx Transaction) string { return string(tx.Direction) }, nil),
+	"channel":              textExpressionField(func(tx Transaction) string { return string(tx.Channel) }, nil),
+	"category":             textExpressionField(func(tx Transaction) string { return tx.Category }, nil),
+	"description":          textExpressionField(func(tx Transaction) string { return tx.Description }, nil),
+	"user_name":            textExpressionField(func(tx Transaction) string { return tx.UserName }, nil),
+	"counterparty_name":    textExpressionField(func(tx Transaction) string { return tx.CounterpartyName }, nil),
+	"counterparty_id":      textExpressionField(func(tx Transaction) string { return tx.CounterpartyID }, nil),
+}
+
+// compileExpression compiles a boolean expression over transaction fields into a predicate:
+//
+//	amount > 10000 && country == "IR"
+//	(amount >= 9000 && amount < 10000) || !(channel in ["card", "transfer"])
+//
+// Numbers are compared as decimals, strings with == and != only. && binds tighter than ||, and !
+// tighter than both.
+func compileExpression(expression string) (func(Transaction) bool, error) {
+	tokens, err := lexExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	p := expressionParser{expression: expression, tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEnd {
+		return nil, p.errorAt(next, "unexpected %s", next)
+	}
+	if node.kind != kindBool {
+		return nil, p.errorAt(tokens[0], "expression is a %s, not a condition", node.kind)
+	}
+
+	return node.boolean, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEnd tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type expressionToken struct {
+	kind tokenKind
+	text string
+	// pos is the 1-based byte offset of the token
+	pos int
+}
+
+func (t expressionToken) String() string {
+	if t.kind == tokenEnd {
+		return "end of expression"
+	}
+
+	return strconv.Quote(t.text)
+}
+
+// expressionOperators lists two-character operators first, so they are matched before their prefixes
+var expressionOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")", "[", "]", ",", "-"}
+
+func lexExpression(expression string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	for i := 0; i < len(expression); {
+		c := rune(expression[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(expression) && (expression[i] == '_' || unicode.IsLetter(rune(expression[i])) || unicode.IsDigit(rune(expression[i]))) {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenIdent, text: expression[start:i], pos: start + 1})
+
+		case unicode.IsDigit(c) || c == '.':
+			start := i
+			for i < len(expression) && (unicode.IsDigit(rune(expression[i])) || expression[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenNumber, text: expression[start:i], pos: start + 1})
+
+		case c == '"':
+			start := i
+			for i++; i < len(expression) && expression[i] != '"'; i++ {
+				if expression[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(expression) {
+				return nil, ExpressionError{Expression: expression, Position: start + 1, Message: "unterminated string"}
+			}
+			i++
+			text, err := strconv.Unquote(expression[start:i])
+			if err != nil {
+				return nil, ExpressionError{Expression: expression, Position: start + 1, Message: "invalid string " + expression[start:i]}
+			}
+			tokens = append(tokens, expressionToken{kind: tokenString, text: text, pos: start + 1})
+
+		default:
+			operator := ""
+			for _, candidate := range expressionOperators {
+				if strings.HasPrefix(expression[i:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				return nil, ExpressionError{Expression: expression, Position: i + 1, Message: fmt.Sprintf("unexpected character %q", c)}
+			}
+			tokens = append(tokens, expressionToken{kind: tokenOperator, text: operator, pos: i + 1})
+			i += len(operator)
+		}
+	}
+
+	return append(tokens, expressionToken{kind: tokenEnd, pos: len(expression) + 1}), nil
+}
+
+// expressionNode is a compiled operand, only the function of its kind being set
+type expressionNode struct {
+	kind    expressionKind
+	boolean func(Transaction) bool
+	number  func(Transaction) decimal.Decimal
+	text    func(Transaction) string
+	// field is set for a field operand and literal for a literal one, so string literals can be
+	// normalized as the field they are compared with
+	field   *expressionField
+	literal *string
+}
+
+// expressionParser is a recursive descent parser, one method per precedence level
+type expressionParser struct {
+	expression string
+	tokens     []expressionToken
+	next       int
+}
+
+func (p *expressionParser) peek() expressionToken {
+	return p.tokens[p.next]
+}
+
+func (p *expressionParser) advance() expressionToken {
+	token := p.tokens[p.next]
+	if token.kind != tokenEnd {
+		p.next++
+	}
+
+	return token
+}
+
+// accept consumes the next token if it is the operator or keyword text
+func (p *expressionParser) accept(text string) bool {
+	if next := p.peek(); (next.kind == tokenOperator || next.kind == tokenIdent) && next.text == text {
+		p.next++
+		return true
+	}
+
+	return false
+}
+
+func (p *expressionParser) expect(text string) error {
+	if next := p.peek(); !p.accept(text) {
+		return p.errorAt(next, "expected %q, found %s", text, next)
+	}
+
+	return nil
+}
+
+func (p *expressionParser) errorAt(token expressionToken, format string, args ...any) error {
+	return ExpressionError{Expression: p.expression, Position: token.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *expressionParser) parseOr() (expressionNode, error) {
+	return p.parseBinary("||", p.parseAnd, func(left, right func(Transaction) bool) func(Transaction) bool {
+		return func(tx Transaction) bool { return left(tx) || right(tx) }
+	})
+}
+
+func (p *expressionParser) parseAnd() (expressionNode, error) {
+	return p.parseBinary("&&", p.parseNot, func(left, right func(Transaction) bool) func(Transaction) bool {
+		return func(tx Transaction) bool { return left(tx) && right(tx) }
+	})
+}
+
+// parseBinary parses a left-associative chain of boolean operands joined by operator
+func (p *expressionParser) parseBinary(operator string, operand func() (expressionNode, error), join func(left, right func(Transaction) bool) func(Transaction) bool) (expressionNode, error) {
+	start := p.peek()
+	left, err := operand()
+	if err != nil {
+		return expressionNode{}, err
+	}
+
+	for {
+		token := p.peek()
+		if !p.accept(operator) {
+			return left, nil
+		}
+		if left.kind != kindBool {
+			return expressionNode{}, p.errorAt(start, "%s operand is a %s, not a condition", operator, left.kind)
+		}
+
+		start = p.peek()
+		right, err := operand()
+		if err != nil {
+			return expressionNode{}, err
+		}
+		if right.kind != kindBool {
+			return expressionNode{}, p.errorAt(start, "%s operand is a %s, not a condition", token.text, right.kind)
+		}
+		left = expressionNode{kind: kindBool, boolean: join(left.boolean, right.boolean)}
+	}
+}
+
+func (p *expressionParser) parseNot() (expressionNode, error) {
+	token := p.peek()
+	if !p.accept("!") {
+		return p.parseComparison()
+	}
+
+	operand, err := p.parseNot()
+	if err != nil {
+		return expressionNode{}, err
+	}
+	if operand.kind != kindBool {
+		return expressionNode{}, p.errorAt(token, "! operand is a %s, not a condition", operand.kind)
+	}
+
+	return expressionNode{kind: kindBool, boolean: func(tx Transaction) bool { return !operand.boolean(tx) }}, nil
+}
+
+// parseComparison parses an operand, optionally compared with another or tested against a list
+func (p *expressionParser) parseComparison() (expressionNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return expressionNode{}, err
+	}
+
+	token := p.peek()
+	if p.accept("in") {
+		return p.parseIn(token, left)
+	}
+	if token.kind != tokenOperator || !slices.Contains([]string{"==", "!=", "<", "<=", ">", ">="}, token.text) {
+		return left, nil
+	}
+	p.advance()
+
+	right, err := p.parseOperand()
+	if err != nil {
+		return expressionNode{}, err
+	}
+	if left.kind != right.kind || left.kind == kindBool {
+		return expressionNode{}, p.errorAt(token, "cannot compare a %s with a %s using %s", left.kind, right.kind, token.text)
+	}
+
+	if left.kind == kindString {
+		left, right = normalizeLiteral(left, right), normalizeLiteral(right, left)
+		equal := func(tx Transaction) bool { return left.text(tx) == right.text(tx) }
+		switch token.text {
+		case "==":
+			return expressionNode{kind: kindBool, boolean: equal}, nil
+		case "!=":
+			return expressionNode{kind: kindBool, boolean: func(tx Transaction) bool { return !equal(tx) }}, nil
+		}
+		return expressionNode{}, p.errorAt(token, "strings can only be compared with == and !=, not %s", token.text)
+	}
+
+	accept := map[string]func(int) bool{
+		"==": func(c int) bool { return c == 0 },
+		"!=": func(c int) bool { return c != 0 },
+		"<":  func(c int) bool { return c < 0 },
+		"<=": func(c int) bool { return c <= 0 },
+		">":  func(c int) bool { return c > 0 },
+		">=": func(c int) bool { return c >= 0 },
+	}[token.text]
+
+	return expressionNode{kind: kindBool, boolean: func(tx Transaction) bool {
+		return accept(left.number(tx).Cmp(right.number(tx)))
+	}}, nil
+}
+
+// parseIn parses the list of "operand in [a, b]", whose elements are literals of the operand's kind
+func (p *expressionParser) parseIn(in expressionToken, operand expressionNode) (expressionNode, error) {
+	if operand.kind == kindBool {
+		return expressionNode{}, p.errorAt(in, "in needs a number or string operand, not a %s", operand.kind)
+	}
+	if err := p.expect("["); err != nil {
+		return expressionNode{}, err
+	}
+
+	var numbers []decimal.Decimal
+	texts := make(map[string]struct{})
+	for first := true; !p.accept("]"); first = false {
+		if !first {
+			if err := p.expect(","); err != nil {
+				return expressionNode{}, err
+			}
+		}
+
+		token := p.peek()
+		element, err := p.parseOperand()
+		if err != nil {
+			return expressionNode{}, err
+		}
+		if element.kind != operand.kind || element.literal == nil {
+			return expressionNode{}, p.errorAt(token, "list elements must be %s literals", operand.kind)
+		}
+		if operand.kind == kindNumber {
+			numbers = append(numbers, element.number(Transaction{}))
+		} else {
+			texts[normalizeLiteral(element, operand).text(Transaction{})] = struct{}{}
+		}
+	}
+
+	if operand.kind == kindNumber {
+		return expressionNode{kind: kindBool, boolean: func(tx Transaction) bool {
+			amount := operand.number(tx)
+			return slices.ContainsFunc(numbers, amount.Equal)
+		}}, nil
+	}
+
+	return expressionNode{kind: kindBool, boolean: func(tx Transaction) bool {
+		_, ok := texts[operand.text(tx)]
+		return ok
+	}}, nil
+}
+
+// parseOperand parses a field, a literal, or a parenthesized expression
+func (p *expressionParser) parseOperand() (expressionNode, error) {
+	token := p.advance()
+	switch {
+	case token.kind == tokenIdent && (token.text == "true" || token.text == "false"):
+		value := token.text == "true"
+		return expressionNode{kind: kindBool, boolean: func(Transaction) bool { return value }}, nil
+
+	case token.kind == tokenIdent:
+		field, ok := expressionFields[token.text]
+		if !ok {
+			return expressionNode{}, p.errorAt(token, "unknown field %q", token.text)
+		}
+		return expressionNode{kind: field.kind, number: field.number, text: field.text, field: &field}, nil
+
+	case token.kind == tokenNumber, token.kind == tokenOperator && token.text == "-" && p.peek().kind == tokenNumber:
+		text := token.text
+		if token.kind == tokenOperator {
+			text += p.advance().text
+		}
+		value, err := decimal.NewFromString(text)
+		if err != nil {
+			return expressionNode{}, p.errorAt(token, "invalid number %q", text)
+		}
+		return expressionNode{kind: kindNumber, number: func(Transaction) decimal.Decimal { return value }, literal: &text}, nil
+
+	case token.kind == tokenString:
+		value := token.text
+		return expressionNode{kind: kindString, text: func(Transaction) string { return value }, literal: &value}, nil
+
+	case token.kind == tokenOperator && token.text == "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return expressionNode{}, err
+		}
+		if err := p.expect(")"); err != nil {
+			return expressionNode{}, err
+		}
+		return node, nil
+	}
+
+	return expressionNode{}, p.errorAt(token, "unexpected %s", token)
+}
+
+// normalizeLiteral normalizes a string literal as the field it is compared with
+func normalizeLiteral(literal, other expressionNode) expressionNode {
+	if literal.literal == nil || other.field == nil || other.field.normalize == nil {
+		return literal
+	}
+
+	value := other.field.normalize(*literal.literal)
+	return expressionNode{kind: kindString, text: func(Transaction) string { return value }, literal: &value}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ExpressionProcessor flags users with at least MinCount transactions matching an expression within
+// Window, e.g. amount > 10000 && country == "IR", so simple per-transaction rules need no Go type.
+// A zero Window counts the matches of the whole batch.
+type ExpressionProcessor struct {
+	MinCount int
+	Window   time.Duration
+
+	expression string
+	match      func(Transaction) bool
+}
+
+// NewExpressionProcessor compiles expression, see compileExpression for its syntax. A malformed
+// expression fails with an ExpressionError giving the position of the offending token. A minCount
+// below one counts as one.
+func NewExpressionProcessor(expression string, minCount int, window time.Duration) (ExpressionProcessor, error) {
+	match, err := compileExpression(expression)
+	if err != nil {
+		return ExpressionProcessor{}, err
+	}
+
+	return ExpressionProcessor{
+		MinCount:   max(minCount, 1),
+		Window:     window,
+		expression: expression,
+		match:      match,
+	}, nil
+}
+
+// Expression returns the source of the compiled expression
+func (e ExpressionProcessor) Expression() string {
+	return e.expression
+}
+
+// Name qualifies the rule with its expression, e.g. "ExpressionProcessor [amount > 10000]"
+func (e ExpressionProcessor) Name() string {
+	return fmt.Sprintf("ExpressionProcessor [%s]", e.expression)
+}
+
+func (e ExpressionProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	if e.match == nil {
+		return flaggedUsers
+	}
+
+	options := velocityOptions{filter: e.match}
+	// "at least MinCount" is a velocity period flagging more than MinCount-1
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(e.Window, e.MinCount-1)}, options)
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			violated := len(txs) >= e.MinCount
+			if e.Window > 0 && violated {
+				violated, _ = checker.CheckUser(txs)
+			}
+			if violated {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// AlertDetails reports the user's matching transactions as evidence
+func (e ExpressionProcessor) AlertDetails(_ context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	var evidence []Transaction
+	for _, tx := range transactions {
+		if tx.UserID == userID && e.match != nil && e.match(tx) {
+			evidence = append(evidence, tx)
+		}
+	}
+
+	return evidence, map[string]string{
+		"expression":   e.expression,
+		"transactions": strconv.Itoa(len(evidence)),
+	}
+}
+
+func (e ExpressionProcessor) ExplainAlert(evidence []Transaction, details map[string]string) string {
+	return fmt.Sprintf("flagged by expression %s: %s matched", details["expression"], pluralize(len(evidence), "transaction"))
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpressionProcessor_EquivalentProcessors(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	countries := []string{"IR", "ir ", "KP", "DE", "US", ""}
+	users := make([]uuid.UUID, 40)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+
+	transactions := make([]Transaction, 400)
+	for i := range transactions {
+		transactions[i] = Transaction{
+			TransactionID: uuid.New(),
+			UserID:        users[rng.IntN(len(users))],
+			Amount:        decimal.New(rng.Int64N(2000000), -2),
+			Country:       countries[rng.IntN(len(countries))],
+			CreatedAt:     time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(i) * time.Minute),
+		}
+	}
+
+	tests := []struct {
+		name       string
+		expression string
+		processor  RuleProcessor
+	}{
+		{
+			name:       "amount threshold",
+			expression: "amount > 10000",
+			processor:  TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000)},
+		},
+		{
+			name:       "fractional amount threshold",
+			expression: "amount > 19000.55",
+			processor:  TransactionAmountProcessor{Threshold: decimal.RequireFromString("19000.55")},
+		},
+		{
+			name:       "country blacklist",
+			expression: `country in ["ir", "KP"]`,
+			processor:  NewCountryBlackListProcessor("ir", "KP"),
+		},
+		{
+			name:       "country equality",
+			expression: `country == "IR" || country == "kp"`,
+			processor:  NewCountryBlackListProcessor("IR", "KP"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression, err := NewExpressionProcessor(tt.expression, 0, 0)
+			require.NoError(t, err)
+
+			want := tt.processor.Process(context.Background(), transactions)
+			require.NotEmpty(t, want)
+			assert.Equal(t, want, expression.Process(context.Background(), transactions))
+		})
+	}
+}
+
+func TestExpressionProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	amounts := func(hours []int, amounts ...int64) []Transaction {
+		transactions := make([]Transaction, len(amounts))
+		for i, amount := range amounts {
+			transactions[i] = Transaction{UserID: userID, Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(time.Duration(hours[i]) * time.Hour)}
+		}
+		return transactions
+	}
+
+	tests := []struct {
+		name         string
+		minCount     int
+		window       time.Duration
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "one match by default",
+			transactions: amounts([]int{0, 1}, 9500, 100),
+			wantFlagged:  true,
+		},
+		{
+			name:         "no match",
+			transactions: amounts([]int{0, 1}, 10000, 8999),
+			wantFlagged:  false,
+		},
+		{
+			name:         "matches across the batch without a window",
+			minCount:     3,
+			transactions: amounts([]int{0, 100, 200, 300}, 9100, 9200, 100, 9300),
+			wantFlagged:  true,
+		},
+		{
+			name:         "below the count",
+			minCount:     3,
+			transactions: amounts([]int{0, 1, 2}, 9100, 9200, 100),
+			wantFlagged:  false,
+		},
+		{
+			name:         "matches within the window",
+			minCount:     2,
+			window:       24 * time.Hour,
+			transactions: amounts([]int{0, 30, 40}, 9100, 9200, 9300),
+			wantFlagged:  true,
+		},
+		{
+			name:         "matches spread beyond the window",
+			minCount:     2,
+			window:       24 * time.Hour,
+			transactions: amounts([]int{0, 30, 60}, 9100, 9200, 9300),
+			wantFlagged:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor, err := NewExpressionProcessor("amount >= 9000 && amount < 10000", tt.minCount, tt.window)
+			require.NoError(t, err)
+
+			_, flagged := processor.Process(context.Background(), tt.transactions)[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestExpressionProcessor_Errors(t *testing.T) {
+	_, err := NewExpressionProcessor("amount > 10000 &&", 1, 0)
+
+	var expressionErr ExpressionError
+	require.ErrorAs(t, err, &expressionErr)
+	assert.Equal(t, 18, expressionErr.Position)
+	assert.EqualError(t, err, `expression "amount > 10000 &&": position 18: unexpected end of expression`)
+
+	assert.Empty(t, ExpressionProcessor{}.Process(context.Background(), blacklistedTransactions(3)),
+		"a processor not built by NewExpressionProcessor flags no one")
+}
+
+func TestExpressionProcessor_Alerts(t *testing.T) {
+	processor, err := NewExpressionProcessor(`amount > 10000 && country == "IR"`, 1, 0)
+	require.NoError(t, err)
+	engine := NewRuleEngine(nil)
+	engine.AddRuleProcessor(processor)
+
+	userID := uuid.New()
+	transactions := []Transaction{
+		{TransactionID: uuid.New(), UserID: userID, Amount: decimal.NewFromInt(12000), Country: "IR"},
+		{TransactionID: uuid.New(), UserID: userID, Amount: decimal.NewFromInt(12000), Country: "DE"},
+		{TransactionID: uuid.New(), UserID: userID, Amount: decimal.NewFromInt(500), Country: "IR"},
+	}
+
+	alerts, err := engine.EvaluateAlerts(context.Background(), transactions)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+
+	alert := alerts[0]
+	assert.Equal(t, `ExpressionProcessor [amount > 10000 && country == "IR"]`, alert.RuleName)
+	assert.Equal(t, []Transaction{transactions[0]}, alert.Evidence)
+	assert.Equal(t, map[string]string{"expression": processor.Expression(), "transactions": "1"}, alert.Details)
+	assert.Equal(t, fmt.Sprintf("flagged by expression %s: 1 transaction matched", processor.Expression()),
+		processor.ExplainAlert(alert.Evidence, alert.Details))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileExpression(t *testing.T) {
+	tx := Transaction{
+		Amount:   decimal.RequireFromString("9500.50"),
+		Country:  " ir",
+		Currency: "eur",
+		Channel:  ChannelCard,
+	}
+
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{`amount > 10000 && country == "IR"`, false},
+		{`amount >= 9000 && amount < 10000`, true},
+		{`amount == 9500.5`, true},
+		{`amount != 9500.500`, false},
+		{`amount > 9500.49 && amount <= 9500.51`, true},
+		{`amount > -1`, true},
+		{`country == "Ir"`, true},
+		{`country != "KP"`, true},
+		{`"EUR" == currency`, true},
+		{`country in ["KP", "ir"]`, true},
+		{`channel in ["transfer", "cash"]`, false},
+		{`amount in [100, 9500.50]`, true},
+		{`true || false && false`, true},
+		{`(true || false) && false`, false},
+		{`!false && false`, false},
+		{`!(false && false)`, true},
+		{`!!true`, true},
+		{`amount < 100 || country == "IR" && currency == "USD"`, false},
+		{`(amount < 100 || country == "IR") && currency == "EUR"`, true},
+		{`description == ""`, true},
+		{`description == "say \"hi\""`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			match, err := compileExpression(tt.expression)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, match(tx))
+		})
+	}
+}
+
+func TestCompileExpression_Errors(t *testing.T) {
+	tests := []struct {
+		expression   string
+		wantPosition int
+		wantMessage  string
+	}{
+		{`amount >`, 9, "unexpected end of expression"},
+		{`amount > 10000 &&`, 18, "unexpected end of expression"},
+		{`amount > 10000 country == "IR"`, 16, `unexpected "country"`},
+		{`amont > 10000`, 1, `unknown field "amont"`},
+		{`amount > "10000"`, 8, "cannot compare a number with a string using >"},
+		{`country < "IR"`, 9, "strings can only be compared with == and !=, not <"},
+		{`amount`, 1, "expression is a number, not a condition"},
+		{`amount && true`, 1, "&& operand is a number, not a condition"},
+		{`true || country`, 9, "|| operand is a string, not a condition"},
+		{`!amount`, 1, "! operand is a number, not a condition"},
+		{`(amount > 1`, 12, `expected ")", found end of expression`},
+		{`country == "IR`, 12, "unterminated string"},
+		{`amount > 1.2.3`, 10, `invalid number "1.2.3"`},
+		{`amount % 2`, 8, `unexpected character '%'`},
+		{`country in "IR"`, 12, `expected "[", found "IR"`},
+		{`country in ["IR" "KP"]`, 18, `expected ",", found "KP"`},
+		{`country in [currency]`, 13, "list elements must be string literals"},
+		{`amount in ["1"]`, 12, "list elements must be number literals"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := compileExpression(tt.expression)
+
+			var expressionErr ExpressionError
+			require.ErrorAs(t, err, &expressionErr)
+			assert.Equal(t, tt.wantPosition, expressionErr.Position)
+			assert.Equal(t, tt.wantMessage, expressionErr.Message)
+			assert.Equal(t, tt.expression, expressionErr.Expression)
+		})
+	}
+}
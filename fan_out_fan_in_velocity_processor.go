@@ -9,12 +9,13 @@ package main
 
 import (
 	"context"
-	"sort"
+	"fmt"
 	"sync"
 
 	"github.com/google/uuid"
 )
 
+// UserJob holds the transactions of one grouping key, the user unless WithGroupingKey is set
 type UserJob struct {
 	UserID       uuid.UUID
 	Transactions []Transaction
@@ -23,42 +24,109 @@ type UserJob struct {
 type UserResult struct {
 	UserID       uuid.UUID
 	HasViolation bool
+	Err          error
 }
 
 type ConcurrentVelocityProcessor struct {
 	Periods     []VelocityPeriod
 	WorkerCount int
+
+	options velocityOptions
 }
 
-func NewConcurrentVelocityProcessor(periods []VelocityPeriod, workerCount int) ConcurrentVelocityProcessor {
+func NewConcurrentVelocityProcessor(periods []VelocityPeriod, workerCount int, opts ...VelocityOption) ConcurrentVelocityProcessor {
 	return ConcurrentVelocityProcessor{
 		Periods:     periods,
 		WorkerCount: workerCount,
+		options:     newVelocityOptions(opts),
 	}
 }
 
+// GroupingKey returns the entity tx is grouped under, as set by WithGroupingKey
+func (v ConcurrentVelocityProcessor) GroupingKey(tx Transaction) uuid.UUID {
+	return v.options.key(tx)
+}
+
+func (v ConcurrentVelocityProcessor) groupsByUser() bool {
+	return v.options.groupingKey == nil
+}
+
 func (v ConcurrentVelocityProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers, _ := v.ProcessContext(ctx, transactions)
+	return flaggedUsers
+}
+
+// ProcessContext is Process but reports ordering errors in strict sorted-input mode, and ctx's error
+// when it is done before every user is checked. The returned set is empty in both cases.
+func (v ConcurrentVelocityProcessor) ProcessContext(ctx context.Context, transactions []Transaction) (map[uuid.UUID]struct{}, error) {
 	userJobs := v.fanOut(ctx, transactions)
 
 	results := v.process(ctx, userJobs)
 
-	return v.fanIn(results)
+	flaggedUsers, err := v.fanIn(results)
+	if err != nil {
+		return flaggedUsers, err
+	}
+	if err := ctx.Err(); err != nil {
+		// fan-out and workers stopped early, the flagged set covers only some users
+		return make(map[uuid.UUID]struct{}), err
+	}
+
+	return flaggedUsers, nil
 }
 
 func (v ConcurrentVelocityProcessor) fanOut(ctx context.Context, transactions []Transaction) <-chan UserJob {
+	if v.options.presortedByUser {
+		return v.fanOutPresorted(ctx, transactions)
+	}
+
 	userJobs := make(chan UserJob, 1000)
 
 	go func() {
 		defer close(userJobs)
-		userTransactions := make(map[uuid.UUID][]Transaction)
 
-		for _, tx := range transactions {
-			userTransactions[tx.UserID] = append(userTransactions[tx.UserID], tx)
+		for _, userTransactions := range groupTransactions(transactions, v.options, nil) {
+			for userID, txs := range userTransactions {
+				select {
+				case userJobs <- UserJob{UserID: userID, Transactions: txs}:
+				case <-ctx.Done():
+					return
+				}
+			}
 		}
+	}()
+
+	return userJobs
+}
+
+// fanOutPresorted emits one job per contiguous user run, holding at most one run per in-flight job
+func (v ConcurrentVelocityProcessor) fanOutPresorted(ctx context.Context, transactions []Transaction) <-chan UserJob {
+	userJobs := make(chan UserJob, v.WorkerCount)
+
+	go func() {
+		defer close(userJobs)
+
+		for start := 0; start < len(transactions); {
+			key := v.options.key(transactions[start])
+			end := start + 1
+			for end < len(transactions) && v.options.key(transactions[end]) == key {
+				end++
+			}
+
+			txs := make([]Transaction, 0, end-start)
+			for _, tx := range transactions[start:end] {
+				if v.options.keep(tx) {
+					txs = append(txs, tx)
+				}
+			}
+			start = end
+
+			if len(txs) == 0 {
+				continue
+			}
 
-		for userID, txs := range userTransactions {
 			select {
-			case userJobs <- UserJob{UserID: userID, Transactions: txs}:
+			case userJobs <- UserJob{UserID: key, Transactions: txs}:
 			case <-ctx.Done():
 				return
 			}
@@ -78,12 +146,22 @@ func (v ConcurrentVelocityProcessor) process(ctx context.Context, jobs <-chan Us
 		go func() {
 			defer wg.Done()
 
+			ctx, end := startSpan(ctx, "aml.velocity_worker")
+			users, err := 0, error(nil)
+			defer func() { end(map[string]any{attrUsers: users}, err) }()
+			progress := ruleProgressFrom(ctx)
+
 			for job := range jobs {
 				select {
 				case <-ctx.Done():
 					return
 				default:
 					result := v.processUser(job.UserID, job.Transactions)
+					users++
+					progress.add(1, len(job.Transactions))
+					if err == nil {
+						err = result.Err
+					}
 					results <- result
 				}
 			}
@@ -100,54 +178,51 @@ func (v ConcurrentVelocityProcessor) process(ctx context.Context, jobs <-chan Us
 	return results
 }
 
-func (v ConcurrentVelocityProcessor) fanIn(results <-chan UserResult) map[uuid.UUID]struct{} {
-	flaggedUsers := make(map[uuid.UUID]struct{})
-	for result := range results {
-		if result.HasViolation {
-			flaggedUsers[result.UserID] = struct{}{}
-		}
-	}
-
-	return flaggedUsers
+func (v ConcurrentVelocityProcessor) fanIn(results <-chan UserResult) (map[uuid.UUID]struct{}, error) {
+	return collectUserResults(results)
 }
 
 func (v ConcurrentVelocityProcessor) processUser(userID uuid.UUID, txs []Transaction) UserResult {
-	// Sort transactions (O(T log T))
-	sort.Slice(txs, func(i, j int) bool {
-		return txs[i].CreatedAt.Before(txs[j].CreatedAt)
-	})
-
-	// Check velocity violations (O(P × T))
-	hasViolation := v.hasViolatedVelocityPeriods(txs)
+	hasViolation, err := newVelocityChecker(v.Periods, v.options).CheckUser(txs)
+	if err != nil {
+		err = fmt.Errorf("%s: %w", userID, err)
+	}
 
 	return UserResult{
 		UserID:       userID,
 		HasViolation: hasViolation,
+		Err:          err,
 	}
 }
 
-func (v ConcurrentVelocityProcessor) hasViolatedVelocityPeriods(txs []Transaction) bool {
-	for _, period := range v.Periods {
-		if v.hasViolatedVelocity(txs, period) {
-			return true
+// collectUserResults drains results into the flagged set, keeping the first error reported by a worker
+func collectUserResults(results <-chan UserResult) (map[uuid.UUID]struct{}, error) {
+	var firstErr error
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	for result := range results {
+		if result.Err != nil && firstErr == nil {
+			firstErr = result.Err
+		}
+		if result.HasViolation {
+			flaggedUsers[result.UserID] = struct{}{}
 		}
 	}
-	return false
-}
 
-func (v ConcurrentVelocityProcessor) hasViolatedVelocity(txs []Transaction, period VelocityPeriod) bool {
-	left := 0
-	for right := 0; right < len(txs); right++ {
-		for left <= right && txs[right].CreatedAt.Sub(txs[left].CreatedAt) > period.Duration {
-			left++
-		}
+	if firstErr != nil {
+		return make(map[uuid.UUID]struct{}), firstErr
+	}
 
-		windowSize := right - left + 1
+	return flaggedUsers, nil
+}
 
-		if windowSize > period.Threshold {
-			return true
-		}
-	}
+func (v ConcurrentVelocityProcessor) AlertDetails(_ context.Context, _ uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	return velocityAlertDetails(v.Periods, v.options, transactions)
+}
+
+func (v ConcurrentVelocityProcessor) ExplainAlert(evidence []Transaction, details map[string]string) string {
+	return velocityExplanation(evidence, details)
+}
 
-	return false
+func (v ConcurrentVelocityProcessor) AlertSeverity(base Severity, _ []Transaction, details map[string]string) Severity {
+	return velocitySeverity(base, details)
 }
@@ -6,7 +6,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
 )
 
 func BenchmarkConcurrentVelocityProcessor_Process(b *testing.B) {
@@ -17,24 +17,67 @@ func BenchmarkConcurrentVelocityProcessor_Process(b *testing.B) {
 	}, 5)
 
 	// Create test data
-	userCount := 10000
-	transactionsPerUser := 50
-	transactions := make([]Transaction, 0, userCount*transactionsPerUser)
-
-	baseTime := time.Now()
-	for i := 0; i < userCount; i++ {
-		userID := uuid.New()
-		for j := 0; j < transactionsPerUser; j++ {
-			transactions = append(transactions, Transaction{
-				UserID:    userID,
-				Amount:    decimal.NewFromFloat(float64(j * 100)),
-				CreatedAt: baseTime.Add(time.Duration(j) * time.Hour),
-			})
-		}
-	}
+	transactions := NewTransactionGenerator(WithUserCount(10000), WithTransactionsPerUser(FixedCount(50))).Generate().Transactions
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		processor.Process(context.Background(), transactions)
 	}
 }
+
+func TestConcurrentVelocityProcessor_Process_PresortedByUser(t *testing.T) {
+	periods := []VelocityPeriod{NewVelocityPeriod(week, 2)}
+	baseTime := time.Now()
+	userID1 := uuid.New()
+	userID2 := uuid.New()
+	userID3 := uuid.New()
+
+	transactions := []Transaction{
+		{UserID: userID1, CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: userID1, CreatedAt: baseTime},
+		{UserID: userID1, CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID2, CreatedAt: baseTime},
+		{UserID: userID2, CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID3, CreatedAt: baseTime},
+		{UserID: userID3, CreatedAt: baseTime.Add(time.Minute)},
+		{UserID: userID3, CreatedAt: baseTime.Add(2 * time.Minute)},
+	}
+
+	grouped := NewConcurrentVelocityProcessor(periods, 2).Process(context.Background(), append([]Transaction(nil), transactions...))
+	presorted := NewConcurrentVelocityProcessor(periods, 2, WithPresortedByUser()).Process(context.Background(), transactions)
+
+	assert.Equal(t, grouped, presorted)
+	assert.Len(t, presorted, 2)
+	assert.Contains(t, presorted, userID1)
+	assert.Contains(t, presorted, userID3)
+}
+
+func BenchmarkConcurrentVelocityProcessor_Process_1M(b *testing.B) {
+	periods := []VelocityPeriod{
+		NewVelocityPeriod(week, 5),
+		NewVelocityPeriod(month, 20),
+		NewVelocityPeriod(year, 100),
+	}
+
+	// Create test data, already grouped by user
+	transactions := NewTransactionGenerator(WithUserCount(20000), WithTransactionsPerUser(FixedCount(50))).Generate().Transactions
+
+	b.Run("Grouped", func(b *testing.B) {
+		processor := NewConcurrentVelocityProcessor(periods, 4)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			processor.Process(context.Background(), transactions)
+		}
+	})
+
+	b.Run("PresortedByUser", func(b *testing.B) {
+		processor := NewConcurrentVelocityProcessor(periods, 4, WithPresortedByUser())
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			processor.Process(context.Background(), transactions)
+		}
+	})
+}
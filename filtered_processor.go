@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// FilteredProcessor applies Inner only to the transactions Keep accepts, e.g. a velocity limit on
+// cash transactions or on transactions above 500
+type FilteredProcessor struct {
+	Keep  func(Transaction) bool
+	Inner RuleProcessor
+	// Description names the filter in the processor's Name, e.g. "amount > 500"
+	Description string
+}
+
+func NewFilteredProcessor(description string, keep func(Transaction) bool, inner RuleProcessor) FilteredProcessor {
+	return FilteredProcessor{
+		Keep:        keep,
+		Inner:       inner,
+		Description: description,
+	}
+}
+
+// Name is the inner rule's name qualified by the filter, e.g. "VelocityProcessor [amount > 500]"
+func (f FilteredProcessor) Name() string {
+	return fmt.Sprintf("%s [%s]", ruleName(f.Inner), f.Description)
+}
+
+// Process delegates a filtered copy of transactions, leaving the caller's slice untouched
+func (f FilteredProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	filtered := make([]Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if f.Keep(tx) {
+			filtered = append(filtered, tx)
+		}
+	}
+
+	return f.Inner.Process(ctx, filtered)
+}
+
+func (f FilteredProcessor) CrossUser() bool {
+	return crossUser(f.Inner)
+}
+
+// AlertDetails delegates the kept transactions to Inner when it is an AlertDetailer, adding the filter
+func (f FilteredProcessor) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	var kept []Transaction
+	for _, tx := range transactions {
+		if f.Keep(tx) {
+			kept = append(kept, tx)
+		}
+	}
+
+	evidence, details := kept, map[string]string{}
+	if detailer, ok := f.Inner.(AlertDetailer); ok {
+		evidence, details = detailer.AlertDetails(ctx, userID, kept)
+	}
+	details["filter"] = f.Description
+
+	return evidence, details
+}
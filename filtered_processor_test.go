@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilteredProcessor_Process(t *testing.T) {
+	baseTime := time.Now()
+	largeBurst, smallBurst := uuid.New(), uuid.New()
+
+	var transactions []Transaction
+	for i := 0; i < 4; i++ {
+		transactions = append(transactions,
+			Transaction{UserID: largeBurst, Amount: decimal.NewFromInt(2000), CreatedAt: baseTime.Add(time.Duration(i) * time.Minute)},
+			Transaction{UserID: smallBurst, Amount: decimal.NewFromInt(20), CreatedAt: baseTime.Add(time.Duration(i) * time.Minute)},
+		)
+	}
+	original := append([]Transaction(nil), transactions...)
+
+	velocity := NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(hour, 3)})
+	largeOnly := NewFilteredProcessor("amount > 500", func(tx Transaction) bool {
+		return tx.Amount.GreaterThan(decimal.NewFromInt(500))
+	}, velocity)
+
+	assert.Equal(t, map[uuid.UUID]struct{}{largeBurst: {}, smallBurst: {}}, velocity.Process(context.Background(), append([]Transaction(nil), transactions...)))
+	assert.Equal(t, map[uuid.UUID]struct{}{largeBurst: {}}, largeOnly.Process(context.Background(), transactions))
+	assert.Equal(t, original, transactions, "the caller's slice is not mutated")
+
+	assert.Equal(t, "VelocityProcessor [amount > 500]", largeOnly.Name())
+	assert.Equal(t, "VelocityProcessor [amount > 500] [cash]", NewFilteredProcessor("cash", OnChannels(ChannelCash), largeOnly).Name())
+
+	combined := NewExceptProcessor(largeOnly, NewCountryBlackListProcessor())
+	assert.Equal(t, map[uuid.UUID]struct{}{largeBurst: {}}, combined.Process(context.Background(), transactions))
+}
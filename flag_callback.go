@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrCallbackPanic is reported when an OnFlagged callback panics; the run carries on regardless
+var ErrCallbackPanic = errors.New("flag callback panicked")
+
+// RuleEngineOption configures optional behaviour of a RuleEngine
+type RuleEngineOption func(*RuleEngine)
+
+// FlagCallback is notified of each alert as the engine produces it. The alert's Evidence and Details
+// are copies, so the callback may keep or modify them without affecting the run's results.
+type FlagCallback func(ctx context.Context, alert Alert)
+
+// WithOnFlagged registers callback, invoked for every alert of a run before EvaluateAlerts returns
+func WithOnFlagged(callback FlagCallback) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.callbacks.callback = callback
+	}
+}
+
+// WithCallbackWorkers invokes the OnFlagged callback from n workers instead of synchronously,
+// the engine waiting once all n are busy
+func WithCallbackWorkers(n int) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.callbacks.workers = n
+	}
+}
+
+// WithCallbackPerUser invokes the OnFlagged callback for the first alert of each user in a run only,
+// however many rules flag them
+func WithCallbackPerUser() RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.callbacks.perUser = true
+	}
+}
+
+type callbackOptions struct {
+	callback FlagCallback
+	workers  int
+	perUser  bool
+}
+
+// callbackDispatcher runs the OnFlagged callback of one run, recovering its panics
+type callbackDispatcher struct {
+	ctx      context.Context
+	options  callbackOptions
+	notified map[uuid.UUID]struct{}
+	queue    chan Alert
+	wg       sync.WaitGroup
+
+	mu   sync.Mutex
+	errs []error
+}
+
+func newCallbackDispatcher(ctx context.Context, options callbackOptions) *callbackDispatcher {
+	d := &callbackDispatcher{
+		ctx:      ctx,
+		options:  options,
+		notified: make(map[uuid.UUID]struct{}),
+	}
+	if options.callback == nil || options.workers <= 0 {
+		return d
+	}
+
+	d.queue = make(chan Alert, options.workers)
+	for range options.workers {
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for alert := range d.queue {
+				d.invoke(alert)
+			}
+		}()
+	}
+
+	return d
+}
+
+// notify invokes the callback for alert, or queues it for the workers
+func (d *callbackDispatcher) notify(alert Alert) {
+	if d.options.callback == nil {
+		return
+	}
+	if d.options.perUser {
+		if _, notified := d.notified[alert.UserID]; notified {
+			return
+		}
+		d.notified[alert.UserID] = struct{}{}
+	}
+
+	alert.Evidence = slices.Clone(alert.Evidence)
+	alert.Details = maps.Clone(alert.Details)
+	if d.queue == nil {
+		d.invoke(alert)
+		return
+	}
+
+	select {
+	case d.queue <- alert:
+	case <-d.ctx.Done():
+	}
+}
+
+func (d *callbackDispatcher) invoke(alert Alert) {
+	defer func() {
+		if r := recover(); r != nil {
+			d.mu.Lock()
+			d.errs = append(d.errs, fmt.Errorf("%w: alert %s for user %s: %v", ErrCallbackPanic, alert.ID, alert.UserID, r))
+			d.mu.Unlock()
+		}
+	}()
+
+	d.options.callback(d.ctx, alert)
+}
+
+// close waits for queued callbacks and returns the panics they raised
+func (d *callbackDispatcher) close() error {
+	if d.queue != nil {
+		close(d.queue)
+		d.wg.Wait()
+	}
+
+	return errors.Join(d.errs...)
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingCallback records the alerts it is called with, from any goroutine
+type recordingCallback struct {
+	mu     sync.Mutex
+	alerts []Alert
+}
+
+func (r *recordingCallback) record(_ context.Context, alert Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.alerts = append(r.alerts, alert)
+}
+
+func (r *recordingCallback) users() map[uuid.UUID]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	counts := make(map[uuid.UUID]int)
+	for _, alert := range r.alerts {
+		counts[alert.UserID]++
+	}
+
+	return counts
+}
+
+func TestRuleEngine_OnFlagged(t *testing.T) {
+	both, blacklisted := uuid.New(), uuid.New()
+	transactions := []Transaction{
+		{UserID: both, Country: "IR", Amount: decimal.NewFromInt(20000), CreatedAt: time.Now()},
+		{UserID: blacklisted, Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: time.Now()},
+		{UserID: uuid.New(), Country: "FR", Amount: decimal.NewFromInt(10), CreatedAt: time.Now()},
+	}
+	rules := []RuleProcessor{
+		NewCountryBlackListProcessor("IR"),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000)},
+	}
+
+	tests := []struct {
+		name string
+		opts []RuleEngineOption
+		want map[uuid.UUID]int
+	}{
+		{
+			name: "synchronous, once per alert",
+			want: map[uuid.UUID]int{both: 2, blacklisted: 1},
+		},
+		{
+			name: "worker pool, once per alert",
+			opts: []RuleEngineOption{WithCallbackWorkers(2)},
+			want: map[uuid.UUID]int{both: 2, blacklisted: 1},
+		},
+		{
+			name: "deduplicated per user",
+			opts: []RuleEngineOption{WithCallbackPerUser()},
+			want: map[uuid.UUID]int{both: 1, blacklisted: 1},
+		},
+		{
+			name: "worker pool deduplicated per user",
+			opts: []RuleEngineOption{WithCallbackWorkers(4), WithCallbackPerUser()},
+			want: map[uuid.UUID]int{both: 1, blacklisted: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &recordingCallback{}
+			engine := NewRuleEngine(rules, append(tt.opts, WithOnFlagged(recorder.record))...)
+
+			alerts, err := engine.EvaluateAlerts(context.Background(), transactions)
+			require.NoError(t, err)
+			require.Len(t, alerts, 3)
+
+			assert.Equal(t, tt.want, recorder.users())
+		})
+	}
+}
+
+func TestRuleEngine_OnFlagged_Copies(t *testing.T) {
+	userID := uuid.New()
+	transactions := []Transaction{{UserID: userID, Country: "IR", Amount: decimal.NewFromInt(20000), CreatedAt: time.Now()}}
+	engine := NewRuleEngine(
+		[]RuleProcessor{TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000)}},
+		WithOnFlagged(func(_ context.Context, alert Alert) {
+			alert.Evidence[0].Amount = decimal.Zero
+			alert.Details["threshold"] = "tampered"
+		}),
+	)
+
+	alerts, err := engine.EvaluateAlerts(context.Background(), transactions)
+	require.NoError(t, err)
+
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "20000", alerts[0].Evidence[0].Amount.String())
+	assert.Equal(t, "10000", alerts[0].Details["threshold"])
+}
+
+func TestRuleEngine_OnFlagged_Panic(t *testing.T) {
+	var transactions []Transaction
+	for range 5 {
+		transactions = append(transactions, Transaction{UserID: uuid.New(), Country: "IR", CreatedAt: time.Now()})
+	}
+
+	for _, workers := range []int{0, 3} {
+		recorder := &recordingCallback{}
+		calls := 0
+		var mu sync.Mutex
+		callback := func(ctx context.Context, alert Alert) {
+			mu.Lock()
+			calls++
+			n := calls
+			mu.Unlock()
+			if n%2 == 0 {
+				panic("case management unavailable")
+			}
+			recorder.record(ctx, alert)
+		}
+
+		engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")},
+			WithOnFlagged(callback), WithCallbackWorkers(workers))
+
+		alerts, err := engine.EvaluateAlerts(context.Background(), transactions)
+
+		require.ErrorIs(t, err, ErrCallbackPanic)
+		assert.Contains(t, err.Error(), "case management unavailable")
+		assert.Len(t, alerts, 5, "a panicking callback does not stop the run")
+		assert.Len(t, recorder.users(), 3)
+		assert.Equal(t, 5, calls)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// FlaggedUsers is a set of users in canonical order, ascending by the bytes of their IDs, which is
+// also the order of their string forms. Unlike the map processors return, iterating or serializing
+// it gives the same result on every run.
+type FlaggedUsers []uuid.UUID
+
+// NewFlaggedUsers returns userIDs in canonical order without repeats. userIDs is not modified.
+func NewFlaggedUsers(userIDs ...uuid.UUID) FlaggedUsers {
+	flagged := FlaggedUsers(slices.Clone(userIDs))
+	slices.SortFunc(flagged, compareUserIDs)
+
+	return slices.Compact(flagged)
+}
+
+// FlaggedUsersOf returns the users of a flagged set, as returned by RuleProcessor.Process, in canonical order
+func FlaggedUsersOf(flaggedUsers map[uuid.UUID]struct{}) FlaggedUsers {
+	return slices.SortedFunc(maps.Keys(flaggedUsers), compareUserIDs)
+}
+
+// ProcessFlaggedUsers runs processor over transactions and returns the users it flagged in canonical order
+func ProcessFlaggedUsers(ctx context.Context, processor RuleProcessor, transactions []Transaction) FlaggedUsers {
+	return FlaggedUsersOf(processor.Process(ctx, transactions))
+}
+
+// compareUserIDs orders user IDs canonically, by their bytes
+func compareUserIDs(a, b uuid.UUID) int {
+	return bytes.Compare(a[:], b[:])
+}
+
+// Contains reports whether userID is in f
+func (f FlaggedUsers) Contains(userID uuid.UUID) bool {
+	_, found := slices.BinarySearchFunc(f, userID, compareUserIDs)
+	return found
+}
+
+// Union returns the users in f or other
+func (f FlaggedUsers) Union(other FlaggedUsers) FlaggedUsers {
+	union := make(FlaggedUsers, 0, len(f)+len(other))
+	i, j := 0, 0
+	for i < len(f) && j < len(other) {
+		switch c := compareUserIDs(f[i], other[j]); {
+		case c < 0:
+			union = append(union, f[i])
+			i++
+		case c > 0:
+			union = append(union, other[j])
+			j++
+		default:
+			union = append(union, f[i])
+			i++
+			j++
+		}
+	}
+	union = append(union, f[i:]...)
+
+	return append(union, other[j:]...)
+}
+
+// Intersect returns the users in both f and other
+func (f FlaggedUsers) Intersect(other FlaggedUsers) FlaggedUsers {
+	intersection := make(FlaggedUsers, 0, min(len(f), len(other)))
+	i, j := 0, 0
+	for i < len(f) && j < len(other) {
+		switch c := compareUserIDs(f[i], other[j]); {
+		case c < 0:
+			i++
+		case c > 0:
+			j++
+		default:
+			intersection = append(intersection, f[i])
+			i++
+			j++
+		}
+	}
+
+	return intersection
+}
+
+// Difference returns the users in f but not in other
+func (f FlaggedUsers) Difference(other FlaggedUsers) FlaggedUsers {
+	difference := make(FlaggedUsers, 0, len(f))
+	j := 0
+	for _, userID := range f {
+		for j < len(other) && compareUserIDs(other[j], userID) < 0 {
+			j++
+		}
+		if j < len(other) && other[j] == userID {
+			continue
+		}
+		difference = append(difference, userID)
+	}
+
+	return difference
+}
+
+// Set returns f as the flagged set RuleProcessor.Process returns
+func (f FlaggedUsers) Set() map[uuid.UUID]struct{} {
+	set := make(map[uuid.UUID]struct{}, len(f))
+	for _, userID := range f {
+		set[userID] = struct{}{}
+	}
+
+	return set
+}
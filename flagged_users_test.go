@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFlaggedUsers(t *testing.T) {
+	a := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	b := uuid.MustParse("0000000a-0000-0000-0000-000000000000")
+	c := uuid.MustParse("f0000000-0000-0000-0000-000000000000")
+	input := []uuid.UUID{c, a, b, a, c}
+
+	flagged := NewFlaggedUsers(input...)
+	assert.Equal(t, FlaggedUsers{a, b, c}, flagged)
+	assert.Equal(t, []uuid.UUID{c, a, b, a, c}, input, "the input is left unchanged")
+	assert.True(t, slices.IsSortedFunc(flagged, func(x, y uuid.UUID) int {
+		return bytes.Compare([]byte(x.String()), []byte(y.String()))
+	}), "byte order is string order")
+
+	assert.Equal(t, flagged, FlaggedUsersOf(map[uuid.UUID]struct{}{b: {}, c: {}, a: {}}))
+	assert.Equal(t, map[uuid.UUID]struct{}{a: {}, b: {}, c: {}}, flagged.Set())
+	assert.Empty(t, FlaggedUsersOf(nil))
+}
+
+func TestFlaggedUsers_SetOperations(t *testing.T) {
+	ids := make([]uuid.UUID, 6)
+	for i := range ids {
+		ids[i] = uuid.UUID{byte(i)}
+	}
+	users := func(indexes ...int) FlaggedUsers {
+		flagged := FlaggedUsers{}
+		for _, i := range indexes {
+			flagged = append(flagged, ids[i])
+		}
+		return flagged
+	}
+
+	tests := []struct {
+		name           string
+		f, other       FlaggedUsers
+		wantUnion      FlaggedUsers
+		wantIntersect  FlaggedUsers
+		wantDifference FlaggedUsers
+	}{
+		{
+			name:           "both empty",
+			f:              users(),
+			other:          users(),
+			wantUnion:      users(),
+			wantIntersect:  users(),
+			wantDifference: users(),
+		},
+		{
+			name:           "one empty",
+			f:              users(1, 3),
+			other:          nil,
+			wantUnion:      users(1, 3),
+			wantIntersect:  users(),
+			wantDifference: users(1, 3),
+		},
+		{
+			name:           "overlapping",
+			f:              users(0, 2, 3, 5),
+			other:          users(1, 2, 4, 5),
+			wantUnion:      users(0, 1, 2, 3, 4, 5),
+			wantIntersect:  users(2, 5),
+			wantDifference: users(0, 3),
+		},
+		{
+			name:           "disjoint",
+			f:              users(0, 1),
+			other:          users(4, 5),
+			wantUnion:      users(0, 1, 4, 5),
+			wantIntersect:  users(),
+			wantDifference: users(0, 1),
+		},
+		{
+			name:           "equal",
+			f:              users(1, 2),
+			other:          users(1, 2),
+			wantUnion:      users(1, 2),
+			wantIntersect:  users(1, 2),
+			wantDifference: users(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantUnion, tt.f.Union(tt.other))
+			assert.Equal(t, tt.wantUnion, tt.other.Union(tt.f), "union is symmetric")
+			assert.Equal(t, tt.wantIntersect, tt.f.Intersect(tt.other))
+			assert.Equal(t, tt.wantIntersect, tt.other.Intersect(tt.f), "intersection is symmetric")
+			assert.Equal(t, tt.wantDifference, tt.f.Difference(tt.other))
+
+			for i, userID := range ids {
+				assert.Equal(t, slices.Contains(tt.f, userID), tt.f.Contains(userID), "user %d", i)
+			}
+		})
+	}
+}
+
+func TestRuleEngine_Evaluate_ReproducibleOutput(t *testing.T) {
+	transactions := NewTransactionGenerator(
+		WithSeed(7),
+		WithUserCount(100),
+		WithTransactionsPerUser(PoissonCount(8)),
+		WithSpacing(PoissonSpacing(3*time.Hour)),
+		WithCountryPool("DE", "FR", "IR"),
+		WithVelocityViolators(5, NewVelocityPeriod(24*time.Hour, 6)),
+		WithStructuringUsers(5, decimal.NewFromInt(10000)),
+	).Generate().Transactions
+
+	period := NewVelocityPeriod(24*time.Hour, 6)
+	rules := slices.Concat(perUserRules(), timeWindowRules(), []RuleProcessor{
+		NewConcurrentVelocityProcessor([]VelocityPeriod{period}, 4),
+		NewOrProcessor(NewCountryBlackListProcessor("IR"), TransactionAmountProcessor{Threshold: decimal.NewFromInt(950)}),
+		NewExceptProcessor(NewVelocityValidator([]VelocityPeriod{period}), NewCountryBlackListProcessor("IR")),
+		NewDailyAggregateProcessor(decimal.NewFromInt(5000), time.UTC),
+	})
+
+	serialize := func() []byte {
+		engine := NewRuleEngine(rules, WithRuleWorkers(4))
+		result, err := engine.Evaluate(context.Background(), slices.Clone(transactions))
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Alerts)
+
+		result = comparableResult(result)
+
+		var b bytes.Buffer
+		require.NoError(t, WriteJSONReport(&b, result))
+		require.NoError(t, WriteCSVAlerts(&b, result.Alerts))
+		require.NoError(t, json.NewEncoder(&b).Encode(result.Flagged()))
+		require.NoError(t, json.NewEncoder(&b).Encode(ProcessFlaggedUsers(context.Background(), rules[0], transactions)))
+
+		return b.Bytes()
+	}
+
+	want := serialize()
+	for i := range 50 {
+		require.Equal(t, string(want), string(serialize()), "run %d", i)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FrequencyDeviationProcessor flags users transacting much more often than their own norm. The
+// Recent window ends at the user's latest transaction and the Baseline period precedes it.
+type FrequencyDeviationProcessor struct {
+	Baseline time.Duration
+	Recent   time.Duration
+	// Multiplier flags a recent rate more than Multiplier times the baseline rate
+	Multiplier float64
+	// MinRecentCount keeps low-volume jumps such as 1 to 3 transactions from being flagged
+	MinRecentCount int
+	// MinBaselineCount is the number of baseline transactions a user needs to be evaluated, at least 2
+	MinBaselineCount int
+}
+
+func NewFrequencyDeviationProcessor(baseline, recent time.Duration, multiplier float64, minRecentCount, minBaselineCount int) FrequencyDeviationProcessor {
+	return FrequencyDeviationProcessor{
+		Baseline:         baseline,
+		Recent:           recent,
+		Multiplier:       multiplier,
+		MinRecentCount:   minRecentCount,
+		MinBaselineCount: max(minBaselineCount, 2),
+	}
+}
+
+func (f FrequencyDeviationProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, velocityOptions{}, nil) {
+		for userID, txs := range userTransactions {
+			sortByCreatedAt(txs)
+
+			if f.isAccelerating(txs) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// isAccelerating compares the recent rate, count over Recent, with the baseline rate, one over
+// the average gap between baseline transactions
+func (f FrequencyDeviationProcessor) isAccelerating(txs []Transaction) bool {
+	latest := txs[len(txs)-1].CreatedAt
+	recentStart := latest.Add(-f.Recent)
+	baselineStart := recentStart.Add(-f.Baseline)
+
+	var baseline []Transaction
+	recentCount := 0
+	for i, tx := range txs {
+		if tx.CreatedAt.After(recentStart) {
+			recentCount = len(txs) - i
+			break
+		}
+		if !tx.CreatedAt.Before(baselineStart) {
+			baseline = append(baseline, tx)
+		}
+	}
+
+	if len(baseline) < max(f.MinBaselineCount, 2) || recentCount < f.MinRecentCount {
+		return false
+	}
+
+	averageGap := baseline[len(baseline)-1].CreatedAt.Sub(baseline[0].CreatedAt) / time.Duration(len(baseline)-1)
+	if averageGap == 0 {
+		return false // a baseline burst at a single instant has no meaningful rate
+	}
+
+	// recentCount / Recent > Multiplier / averageGap, rearranged to avoid dividing durations
+	return float64(recentCount)*averageGap.Seconds() > f.Multiplier*f.Recent.Seconds()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrequencyDeviationProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	userID := uuid.New()
+
+	every := func(start time.Time, spacing time.Duration, count int) []Transaction {
+		transactions := make([]Transaction, count)
+		for i := range transactions {
+			transactions[i] = Transaction{UserID: userID, Amount: decimal.NewFromInt(50), CreatedAt: start.Add(time.Duration(i) * spacing)}
+		}
+		return transactions
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "weekly user suddenly transacting daily",
+			transactions: append(every(baseTime, week, 12), every(baseTime.Add(12*week), day, 7)...),
+			wantFlagged:  true,
+		},
+		{
+			name:         "daily user staying daily",
+			transactions: every(baseTime, day, 90),
+			wantFlagged:  false,
+		},
+		{
+			name:         "weekly user staying weekly",
+			transactions: every(baseTime, week, 14),
+			wantFlagged:  false,
+		},
+		{
+			name:         "weekly user with a 1 to 3 jump",
+			transactions: append(every(baseTime, week, 12), every(baseTime.Add(12*week), 2*day, 3)...),
+			wantFlagged:  false,
+		},
+		{
+			name:         "brand-new user",
+			transactions: every(baseTime, time.Hour, 10),
+			wantFlagged:  false,
+		},
+	}
+
+	processor := NewFrequencyDeviationProcessor(3*month, week, 4, 5, 4)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FunnelProcessor flags users among many distinct senders paying one counterparty within a short
+// time, as seen with mule networks funnelling into a collection account
+type FunnelProcessor struct {
+	Window time.Duration
+	// MaxSenders flags more than this many distinct users paying a counterparty within Window
+	MaxSenders int
+}
+
+func NewFunnelProcessor(window time.Duration, maxSenders int) FunnelProcessor {
+	return FunnelProcessor{
+		Window:     window,
+		MaxSenders: maxSenders,
+	}
+}
+
+// Funnel is a counterparty receiving from too many distinct senders. WindowStart and WindowEnd
+// span every violating window; Senders holds every user in one of them.
+type Funnel struct {
+	CounterpartyID string
+	Senders        []uuid.UUID
+	WindowStart    time.Time
+	WindowEnd      time.Time
+}
+
+func (f FunnelProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, funnel := range f.Funnels(ctx, transactions) {
+		for _, sender := range funnel.Senders {
+			flaggedUsers[sender] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// CrossUser is true, a user being flagged on many senders paying one counterparty
+func (f FunnelProcessor) CrossUser() bool {
+	return true
+}
+
+// Funnels groups transactions by counterparty rather than by user and returns the violating
+// counterparties ordered by CounterpartyID. Transactions without a CounterpartyID are ignored.
+func (f FunnelProcessor) Funnels(_ context.Context, transactions []Transaction) []Funnel {
+	byCounterparty := make(map[string][]Transaction)
+	for _, tx := range transactions {
+		if tx.CounterpartyID != "" {
+			byCounterparty[tx.CounterpartyID] = append(byCounterparty[tx.CounterpartyID], tx)
+		}
+	}
+
+	var funnels []Funnel
+	for counterpartyID, txs := range byCounterparty {
+		sortByCreatedAt(txs)
+
+		if funnel, ok := f.scanCounterparty(txs); ok {
+			funnel.CounterpartyID = counterpartyID
+			funnels = append(funnels, funnel)
+		}
+	}
+
+	slices.SortFunc(funnels, func(a, b Funnel) int {
+		return strings.Compare(a.CounterpartyID, b.CounterpartyID)
+	})
+
+	return funnels
+}
+
+// scanCounterparty slides a Window over one counterparty's sorted txs, counting transactions per sender
+// Time complexity: O(n * s) where s is the number of distinct senders within a violating window
+func (f FunnelProcessor) scanCounterparty(txs []Transaction) (Funnel, bool) {
+	var funnel Funnel
+	window := make(map[uuid.UUID]int)
+	senders := make(map[uuid.UUID]struct{})
+	left := 0
+
+	for right, tx := range txs {
+		window[tx.UserID]++
+
+		for tx.CreatedAt.Sub(txs[left].CreatedAt) > f.Window {
+			sender := txs[left].UserID
+			if window[sender]--; window[sender] == 0 {
+				delete(window, sender)
+			}
+			left++
+		}
+
+		if len(window) <= f.MaxSenders {
+			continue
+		}
+
+		if len(senders) == 0 {
+			funnel.WindowStart = txs[left].CreatedAt
+		}
+		funnel.WindowEnd = txs[right].CreatedAt
+		for sender := range window {
+			senders[sender] = struct{}{}
+		}
+	}
+
+	if len(senders) == 0 {
+		return Funnel{}, false
+	}
+
+	for sender := range senders {
+		funnel.Senders = append(funnel.Senders, sender)
+	}
+	slices.SortFunc(funnel.Senders, func(a, b uuid.UUID) int {
+		return strings.Compare(a.String(), b.String())
+	})
+
+	return funnel, true
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFunnelProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	users := make([]uuid.UUID, 10)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+
+	senders := func(spacing time.Duration, counterparty func(i int) string) []Transaction {
+		transactions := make([]Transaction, len(users))
+		for i, userID := range users {
+			transactions[i] = Transaction{UserID: userID, CounterpartyID: counterparty(i), CreatedAt: baseTime.Add(time.Duration(i) * spacing)}
+		}
+		return transactions
+	}
+	same := func(int) string { return "mule-account" }
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  int
+	}{
+		{
+			name:         "10 users to one counterparty within a day",
+			transactions: senders(2*time.Hour, same),
+			wantFlagged:  10,
+		},
+		{
+			name:         "10 users to one counterparty over 3 months",
+			transactions: senders(10*24*time.Hour, same),
+			wantFlagged:  0,
+		},
+		{
+			name:         "10 users to 10 different counterparties",
+			transactions: senders(2*time.Hour, func(i int) string { return fmt.Sprintf("account-%d", i) }),
+			wantFlagged:  0,
+		},
+		{
+			name:         "no counterparty",
+			transactions: senders(2*time.Hour, func(int) string { return "" }),
+			wantFlagged:  0,
+		},
+	}
+
+	processor := NewFunnelProcessor(24*time.Hour, 5)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			assert.Len(t, flaggedUsers, tt.wantFlagged)
+		})
+	}
+}
+
+func TestFunnelProcessor_Funnels(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	early, late := uuid.New(), uuid.New()
+	transactions := []Transaction{
+		{UserID: early, CounterpartyID: "mule-account", CreatedAt: baseTime},
+		{UserID: early, CounterpartyID: "mule-account", CreatedAt: baseTime.Add(time.Hour)},
+	}
+	for i := 0; i < 3; i++ {
+		transactions = append(transactions, Transaction{UserID: uuid.New(), CounterpartyID: "mule-account", CreatedAt: baseTime.Add(week + time.Duration(i)*time.Hour)})
+	}
+	transactions = append(transactions, Transaction{UserID: late, CounterpartyID: "mule-account", CreatedAt: baseTime.Add(week + 3*time.Hour)})
+
+	funnels := NewFunnelProcessor(24*time.Hour, 3).Funnels(context.Background(), transactions)
+
+	require.Len(t, funnels, 1)
+	assert.Equal(t, "mule-account", funnels[0].CounterpartyID)
+	assert.Len(t, funnels[0].Senders, 4)
+	assert.Contains(t, funnels[0].Senders, late)
+	assert.NotContains(t, funnels[0].Senders, early, "repeat payments by one sender a week earlier are outside the window")
+	assert.Equal(t, baseTime.Add(week), funnels[0].WindowStart)
+	assert.Equal(t, baseTime.Add(week+3*time.Hour), funnels[0].WindowEnd)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// GroupedRuleProcessor is a RuleProcessor reading the batch already grouped by user, which the engine
+// groups once per evaluation and shares between every such rule instead of each rule grouping its own
+// copy. The engine prefers ProcessGrouped over Process. The grouped transactions are shared, so they
+// must not be modified.
+type GroupedRuleProcessor interface {
+	RuleProcessor
+	ProcessGrouped(ctx context.Context, grouped GroupedTransactions) map[uuid.UUID]struct{}
+}
+
+// groupedContextRuleProcessor is the grouped counterpart of contextRuleProcessor
+type groupedContextRuleProcessor interface {
+	ProcessGroupedContext(context.Context, GroupedTransactions) (map[uuid.UUID]struct{}, error)
+}
+
+// GroupedTransactions is a batch indexed by user. Copies share the same index, built by GroupByUser,
+// and the per-user orderings by CreatedAt and the columnar batch, built on first use.
+type GroupedTransactions struct {
+	transactions []Transaction
+	users        []uuid.UUID
+	// positions is where each user is in users, groups holding their transactions at the same position
+	positions map[uuid.UUID]int
+	groups    [][]Transaction
+	sorted    *sortedGroups
+	columns   *columnarBatch
+}
+
+// sortedGroups holds the users' transactions ordered by CreatedAt, each user sorted on first use
+type sortedGroups struct {
+	once   sync.Once
+	groups []sortedGroup
+}
+
+// sortedGroup is one user's transactions ordered by CreatedAt, computed once
+type sortedGroup struct {
+	once sync.Once
+	txs  []Transaction
+}
+
+// sortedGroupHook, when set, is called with every user SortedForUser sorts
+var sortedGroupHook func(userID uuid.UUID)
+
+// columnarBatch holds the batch laid out in columns, computed once
+type columnarBatch struct {
+	once  sync.Once
+	batch *TransactionBatch
+}
+
+// GroupByUser indexes transactions by UserID, each user's transactions keeping their input order.
+// The groups are carved out of a single copy of the batch, so transactions itself is never modified.
+func GroupByUser(transactions []Transaction) GroupedTransactions {
+	positions := make(map[uuid.UUID]int)
+	var users []uuid.UUID
+	var counts []int
+	for _, tx := range transactions {
+		position, seen := positions[tx.UserID]
+		if !seen {
+			position = len(users)
+			positions[tx.UserID] = position
+			users = append(users, tx.UserID)
+			counts = append(counts, 0)
+		}
+		counts[position]++
+	}
+
+	backing := make([]Transaction, len(transactions))
+	groups := make([][]Transaction, len(users))
+	offset := 0
+	for position, count := range counts {
+		groups[position] = backing[offset : offset : offset+count]
+		offset += count
+	}
+	for _, tx := range transactions {
+		position := positions[tx.UserID]
+		groups[position] = append(groups[position], tx)
+	}
+
+	return GroupedTransactions{
+		transactions: transactions,
+		users:        users,
+		positions:    positions,
+		groups:       groups,
+		sorted:       &sortedGroups{},
+		columns:      &columnarBatch{},
+	}
+}
+
+// Len returns the number of transactions in the batch
+func (g GroupedTransactions) Len() int {
+	return len(g.transactions)
+}
+
+// All returns the batch as it was grouped, to be read only
+func (g GroupedTransactions) All() []Transaction {
+	return g.transactions
+}
+
+// Users returns the users of the batch in order of first appearance, to be read only
+func (g GroupedTransactions) Users() []uuid.UUID {
+	return g.users
+}
+
+// ForUser returns the transactions of userID in input order, to be read only
+func (g GroupedTransactions) ForUser(userID uuid.UUID) []Transaction {
+	position, ok := g.positions[userID]
+	if !ok {
+		return nil
+	}
+
+	return g.groups[position]
+}
+
+// SortedForUser returns the transactions of userID ordered by CreatedAt, ties keeping their input
+// order, to be read only. A user is sorted on the first call asking for them and the result shared
+// by every later one, so rules reading the same grouping sort each user once between them. It is
+// safe for concurrent use.
+func (g GroupedTransactions) SortedForUser(userID uuid.UUID) []Transaction {
+	position, ok := g.positions[userID]
+	if !ok || g.sorted == nil {
+		return nil
+	}
+
+	g.sorted.once.Do(func() {
+		// every user's sorted transactions are carved out of one buffer, at the offset of their group
+		backing := make([]Transaction, len(g.transactions))
+		g.sorted.groups = make([]sortedGroup, len(g.groups))
+		offset := 0
+		for i, group := range g.groups {
+			g.sorted.groups[i].txs = backing[offset : offset+len(group) : offset+len(group)]
+			offset += len(group)
+		}
+	})
+
+	sorted := &g.sorted.groups[position]
+	sorted.once.Do(func() {
+		if sortedGroupHook != nil {
+			sortedGroupHook(userID)
+		}
+		copy(sorted.txs, g.groups[position])
+		if len(sorted.txs) >= parallelSortThreshold {
+			sortLargeByCreatedAt(sorted.txs, runtime.GOMAXPROCS(0))
+			return
+		}
+		slices.SortStableFunc(sorted.txs, func(a, b Transaction) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+	})
+
+	return sorted.txs
+}
+
+// Batch returns the batch in columnar form, to be read only. It is built on the first call, which
+// is safe for concurrent use.
+func (g GroupedTransactions) Batch() *TransactionBatch {
+	if g.columns == nil {
+		return NewTransactionBatch(g.transactions)
+	}
+
+	g.columns.once.Do(func() {
+		g.columns.batch = NewTransactionBatch(g.transactions)
+	})
+
+	return g.columns.batch
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupByUser(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	first, second := uuid.New(), uuid.New()
+	transactions := []Transaction{
+		{TransactionID: uuid.New(), UserID: second, CreatedAt: baseTime.Add(2 * time.Hour)},
+		{TransactionID: uuid.New(), UserID: first, CreatedAt: baseTime.Add(time.Hour)},
+		{TransactionID: uuid.New(), UserID: second, CreatedAt: baseTime},
+		{TransactionID: uuid.New(), UserID: second, CreatedAt: baseTime.Add(2 * time.Hour)},
+	}
+	input := append([]Transaction(nil), transactions...)
+
+	grouped := GroupByUser(transactions)
+
+	assert.Equal(t, 4, grouped.Len())
+	assert.Equal(t, transactions, grouped.All())
+	assert.Equal(t, []uuid.UUID{second, first}, grouped.Users(), "users in order of first appearance")
+	assert.Equal(t, []Transaction{transactions[0], transactions[2], transactions[3]}, grouped.ForUser(second))
+	assert.Equal(t, []Transaction{transactions[1]}, grouped.ForUser(first))
+	assert.Empty(t, grouped.ForUser(uuid.New()))
+
+	assert.Equal(t, []Transaction{transactions[2], transactions[0], transactions[3]}, grouped.SortedForUser(second),
+		"ordered by CreatedAt, ties keeping their input order")
+	assert.Equal(t, []Transaction{transactions[0], transactions[2], transactions[3]}, grouped.ForUser(second),
+		"sorting leaves the input-order groups alone")
+	assert.Equal(t, input, transactions, "the batch itself is never modified")
+
+	assert.Len(t, append(grouped.ForUser(second), Transaction{}), 4)
+	assert.Equal(t, []Transaction{transactions[1]}, grouped.ForUser(first), "appending to a group cannot overwrite the next one")
+	grouped.ForUser(first)[0].Country = "IR"
+	assert.Empty(t, transactions[1].Country, "groups are a copy of the batch")
+
+	assert.Nil(t, GroupedTransactions{}.SortedForUser(first))
+	assert.Zero(t, GroupByUser(nil).Len())
+}
+
+func TestGroupByUser_ConcurrentSortedAccess(t *testing.T) {
+	generated := NewTransactionGenerator(WithUserCount(50), WithSpacing(PoissonSpacing(time.Hour)), WithTimeOrder()).Generate()
+	grouped := GroupByUser(generated.Transactions)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, userID := range grouped.Users() {
+				assert.Len(t, grouped.SortedForUser(userID), 10)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestVelocityProcessor_ProcessGrouped(t *testing.T) {
+	period := NewVelocityPeriod(24*time.Hour, 4)
+	generated := NewTransactionGenerator(
+		WithUserCount(100),
+		WithTransactionsPerUser(PoissonCount(6)),
+		WithSpacing(PoissonSpacing(6*time.Hour)),
+		WithCountryPool("DE", "IR"),
+		WithVelocityViolators(5, period),
+		WithTimeOrder(),
+	).Generate()
+	rng := NewTransactionGenerator(WithUserCount(10)).Generate().Users
+	for i := range generated.Transactions {
+		generated.Transactions[i].AccountID = rng[i%len(rng)]
+	}
+
+	tests := []struct {
+		name string
+		opts []VelocityOption
+	}{
+		{name: "default"},
+		{name: "sorted input", opts: []VelocityOption{WithSortedInput()}},
+		{name: "filter", opts: []VelocityOption{WithTransactionFilter(func(tx Transaction) bool { return tx.Country == "DE" })}},
+		{name: "grouping key", opts: []VelocityOption{WithGroupingKey(ByAccount)}},
+		{name: "strict sorted input", opts: []VelocityOption{WithStrictSortedInput()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewVelocityValidator([]VelocityPeriod{period}, tt.opts...)
+			want, err := processor.ProcessContext(context.Background(), append([]Transaction(nil), generated.Transactions...))
+			require.NoError(t, err)
+			require.NotEmpty(t, want)
+
+			got, err := processor.ProcessGroupedContext(context.Background(), GroupByUser(generated.Transactions))
+			require.NoError(t, err)
+			assert.Equal(t, want, got)
+			assert.Equal(t, want, processor.ProcessGrouped(context.Background(), GroupByUser(generated.Transactions)))
+		})
+	}
+
+	t.Run("strict sorted input rejects unsorted batches", func(t *testing.T) {
+		unsorted := NewTransactionGenerator(WithUserCount(3)).Generate().Transactions
+		unsorted[0], unsorted[1] = unsorted[1], unsorted[0]
+
+		processor := NewVelocityValidator([]VelocityPeriod{period}, WithStrictSortedInput())
+		_, err := processor.ProcessGroupedContext(context.Background(), GroupByUser(unsorted))
+		assert.ErrorIs(t, err, ErrUnsortedTransactions)
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		flagged, err := NewVelocityValidator([]VelocityPeriod{period}).ProcessGroupedContext(ctx, GroupByUser(generated.Transactions))
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, flagged)
+	})
+}
+
+func TestTransactionAmountProcessor_ProcessGrouped(t *testing.T) {
+	generated := NewTransactionGenerator(WithAmounts(LogNormalAmount(7, 1.5)), WithCountryPool("DE", "IR")).Generate()
+	processor := TransactionAmountProcessor{Threshold: decimal.NewFromInt(5000), CountryThresholds: map[string]decimal.Decimal{"IR": decimal.NewFromInt(1000)}}
+
+	want := processor.Process(context.Background(), generated.Transactions)
+	require.NotEmpty(t, want)
+	assert.Equal(t, want, processor.ProcessGrouped(context.Background(), GroupByUser(generated.Transactions)))
+}
+
+// groupingRecorder records the grouping the engine hands it
+type groupingRecorder struct {
+	mu      sync.Mutex
+	seen    []*sortedGroups
+	flatten int
+}
+
+func (g *groupingRecorder) Process(context.Context, []Transaction) map[uuid.UUID]struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.flatten++
+	return nil
+}
+
+func (g *groupingRecorder) ProcessGrouped(_ context.Context, grouped GroupedTransactions) map[uuid.UUID]struct{} {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.seen = append(g.seen, grouped.sorted)
+	return map[uuid.UUID]struct{}{grouped.Users()[0]: {}}
+}
+
+// failingGroupedProcessor reports an error through the grouped context interface
+type failingGroupedProcessor struct{ groupingRecorder }
+
+func (f *failingGroupedProcessor) ProcessGroupedContext(context.Context, GroupedTransactions) (map[uuid.UUID]struct{}, error) {
+	return nil, errors.New("grouped failure")
+}
+
+func TestRuleEngine_Evaluate_GroupsOnce(t *testing.T) {
+	for _, workers := range []int{1, 3} {
+		recorder := &groupingRecorder{}
+		engine := NewRuleEngine([]RuleProcessor{recorder, recorder, recorder}, WithRuleWorkers(workers))
+
+		transactions := blacklistedTransactions(4)
+		result, err := engine.Evaluate(context.Background(), transactions)
+		require.NoError(t, err)
+
+		require.Len(t, recorder.seen, 3)
+		assert.Zero(t, recorder.flatten, "ProcessGrouped is preferred over Process")
+		assert.Same(t, recorder.seen[0], recorder.seen[1], "every rule shares the run's grouping")
+		assert.Same(t, recorder.seen[0], recorder.seen[2])
+		require.Len(t, result.Alerts, 3)
+		assert.Equal(t, []Transaction{transactions[0]}, result.Alerts[0].Evidence)
+
+		again := &groupingRecorder{}
+		engine = NewRuleEngine([]RuleProcessor{again}, WithRuleWorkers(workers))
+		_, err = engine.Evaluate(context.Background(), transactions)
+		require.NoError(t, err)
+		assert.NotSame(t, recorder.seen[0], again.seen[0], "each run groups its own batch")
+	}
+
+	engine := NewRuleEngine([]RuleProcessor{&failingGroupedProcessor{}})
+	result, err := engine.Evaluate(context.Background(), blacklistedTransactions(2))
+	assert.ErrorContains(t, err, "grouped failure")
+	assert.Equal(t, "grouped failure", result.Rules[0].Error)
+}
+
+// ungroupedRule hides every interface of its processor but RuleProcessor, as before grouped rules
+type ungroupedRule struct{ RuleProcessor }
+
+// ungroupedKeyedRule is an ungroupedRule whose evidence is gathered by its own key, as before grouped rules
+type ungroupedKeyedRule struct {
+	ungroupedRule
+	GroupingKeyer
+}
+
+func BenchmarkRuleEngine_Evaluate_Grouped(b *testing.B) {
+	transactions := NewTransactionGenerator(
+		WithUserCount(2000),
+		WithTransactionsPerUser(FixedCount(50)),
+		WithAmounts(UniformAmount(decimal.Zero, decimal.NewFromInt(5000))),
+		WithSpacing(PoissonSpacing(4*time.Hour)),
+		WithTimeOrder(),
+	).Generate().Transactions
+	rules := []RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(24*time.Hour, 10)}),
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 40)}),
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(hour, 3)}, WithTransactionFilter(ExcludeReversals)),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(4900)},
+		NewCountryBlackListProcessor("IR"),
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(month, 100)}),
+	}
+
+	b.Run("Grouped", func(b *testing.B) {
+		engine := NewRuleEngine(rules)
+		b.ReportAllocs()
+		for b.Loop() {
+			engine.Evaluate(context.Background(), transactions)
+		}
+	})
+
+	b.Run("PerRule", func(b *testing.B) {
+		ungrouped := make([]RuleProcessor, len(rules))
+		for i, rule := range rules {
+			ungrouped[i] = ungroupedRule{rule}
+			if keyer, ok := rule.(GroupingKeyer); ok {
+				ungrouped[i] = ungroupedKeyedRule{ungroupedRule{rule}, keyer}
+			}
+		}
+		engine := NewRuleEngine(ungrouped)
+		b.ReportAllocs()
+		for b.Loop() {
+			engine.Evaluate(context.Background(), transactions)
+		}
+	})
+}
+
+// timeWindowRules are four rules reading each user's transactions in CreatedAt order
+func timeWindowRules() []RuleProcessor {
+	return []RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(24*time.Hour, 6)}),
+		NewBurstVelocityProcessor([]VelocityPeriod{NewVelocityPeriod(4*hour, 2)}),
+		NewStructuringProcessor(decimal.NewFromInt(10000), decimal.NewFromFloat(0.1), 3, 48*time.Hour),
+		NewDormancyProcessor(4*24*time.Hour, 24*time.Hour, 2, decimal.Zero),
+	}
+}
+
+func timeWindowTransactions(users int) []Transaction {
+	return NewTransactionGenerator(
+		WithUserCount(users),
+		WithTransactionsPerUser(PoissonCount(12)),
+		WithAmounts(UniformAmount(decimal.NewFromInt(100), decimal.NewFromInt(9999))),
+		WithSpacing(PoissonSpacing(18*time.Hour)),
+		WithVelocityViolators(users/20, NewVelocityPeriod(24*time.Hour, 6)),
+		WithStructuringUsers(users/20, decimal.NewFromInt(10000)),
+	).Generate().Transactions
+}
+
+func TestTimeWindowProcessors_ProcessGrouped(t *testing.T) {
+	transactions := timeWindowTransactions(400)
+	for _, rule := range timeWindowRules() {
+		grouped, ok := rule.(GroupedRuleProcessor)
+		require.True(t, ok, "%T", rule)
+
+		want := rule.Process(context.Background(), append([]Transaction(nil), transactions...))
+		require.NotEmpty(t, want, "%T", rule)
+		assert.Equal(t, want, grouped.ProcessGrouped(context.Background(), GroupByUser(transactions)), "%T", rule)
+	}
+}
+
+func TestRuleEngine_Evaluate_SortsEachUserOnce(t *testing.T) {
+	transactions := timeWindowTransactions(200)
+	users := len(GroupByUser(transactions).Users())
+
+	for _, workers := range []int{1, 4} {
+		var mu sync.Mutex
+		sorts := make(map[uuid.UUID]int)
+		sortedGroupHook = func(userID uuid.UUID) {
+			mu.Lock()
+			defer mu.Unlock()
+			sorts[userID]++
+		}
+		t.Cleanup(func() { sortedGroupHook = nil })
+
+		result, err := NewRuleEngine(timeWindowRules(), WithRuleWorkers(workers)).Evaluate(context.Background(), transactions)
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Alerts)
+
+		assert.Len(t, sorts, users, "%d workers", workers)
+		for userID, count := range sorts {
+			assert.Equal(t, 1, count, "%d workers: user %s sorted %d times", workers, userID, count)
+		}
+	}
+}
+
+func BenchmarkRuleEngine_Evaluate_TimeWindowRules(b *testing.B) {
+	transactions := timeWindowTransactions(5000)
+	rules := timeWindowRules()
+
+	b.Run("SharedSort", func(b *testing.B) {
+		engine := NewRuleEngine(rules)
+		b.ReportAllocs()
+		for b.Loop() {
+			engine.Evaluate(context.Background(), transactions)
+		}
+	})
+
+	b.Run("PerRule", func(b *testing.B) {
+		ungrouped := make([]RuleProcessor, len(rules))
+		for i, rule := range rules {
+			ungrouped[i] = ungroupedRule{rule}
+		}
+		engine := NewRuleEngine(ungrouped)
+		b.ReportAllocs()
+		for b.Loop() {
+			engine.Evaluate(context.Background(), transactions)
+		}
+	})
+}
+
+// orderSensitiveRules are processors ordering each user's transactions before reading them
+func orderSensitiveRules(t *testing.T) map[string]RuleProcessor {
+	t.Helper()
+	periods := []VelocityPeriod{NewVelocityPeriod(24*time.Hour, 6)}
+	amountBand, err := NewAmountBandProcessor([]AmountBand{{Min: decimal.NewFromInt(9000), Max: decimal.NewFromInt(9999)}}, 2, week, false)
+	require.NoError(t, err)
+	keyword, err := NewKeywordProcessor([]string{"gift"}, nil, true, 2, week)
+	require.NoError(t, err)
+	expression, err := NewExpressionProcessor("amount >= 9000", 3, week)
+	require.NoError(t, err)
+	rules := map[string]RuleProcessor{
+		"stateful":             NewStatefulVelocityProcessor(periods),
+		"acceleration":         NewAccelerationProcessor(24*time.Hour, decimal.NewFromInt(3), decimal.NewFromInt(100)),
+		"baseline_spike":       NewBaselineSpikeProcessor(week, decimal.NewFromInt(3), decimal.NewFromInt(100), 3),
+		"category_spend":       NewCategorySpendProcessor([]string{"gambling"}, 24*time.Hour, 2, decimal.Zero),
+		"circular_flow":        NewCircularFlowProcessor(week, 3, decimal.NewFromInt(100)),
+		"country_risk":         NewCountryRiskProcessor(map[string]int{"IR": 5}, 10, week),
+		"daily_aggregate":      NewDailyAggregateProcessor(decimal.NewFromInt(20000), time.UTC),
+		"duplicate":            NewDuplicateTransactionProcessor(time.Minute, 2),
+		"frequency_deviation":  NewFrequencyDeviationProcessor(month, 24*time.Hour, 3, 3, 3),
+		"funnel":               NewFunnelProcessor(24*time.Hour, 3),
+		"identical_amount":     NewIdenticalAmountProcessor(3, decimal.NewFromInt(100), week),
+		"new_country":          NewNewCountryProcessor(3, week, decimal.NewFromInt(100)),
+		"pass_through":         NewPassThroughProcessor(24*time.Hour, decimal.NewFromFloat(0.9)),
+		"repeat_counterparty":  NewRepeatCounterpartyProcessor(24*time.Hour, 2),
+		"reversal_abuse":       NewReversalAbuseProcessor(week, 2, decimal.NewFromFloat(0.3)),
+		"split_payment":        NewSplitPaymentProcessor(time.Hour, decimal.NewFromInt(10000), 3),
+		"off_hours":            NewOffHoursProcessor(22*time.Hour, 6*time.Hour, time.UTC, 3, week),
+		"zscore":               NewZScoreProcessor(5, 3, decimal.NewFromInt(100)),
+		"decay_velocity":       NewDecayVelocityProcessor(24*time.Hour, 5),
+		"cross_border_ratio":   NewCrossBorderRatioProcessor("DE", 0.5, 3, week),
+		"counterparty_country": NewCounterpartyCountryProcessor("IR"),
+		"channel_risk":         NewChannelRiskProcessor(map[Channel]ChannelLimit{ChannelCard: {Count: 3}, ChannelWire: {Count: 2}}, 24*time.Hour),
+		"corridor":             NewCorridorProcessor([]Corridor{{Origin: "US", Destination: "DE"}}, week, 2, decimal.Zero),
+		"amount_band":          amountBand,
+		"keyword":              keyword,
+		"expression":           expression,
+	}
+	for name, processor := range velocityImplementations(periods) {
+		rules[name] = processor
+	}
+	for _, processor := range timeWindowRules() {
+		rules[fmt.Sprintf("%T", processor)] = processor
+	}
+
+	return rules
+}
+
+func TestRuleProcessors_LeaveInputUnchanged(t *testing.T) {
+	transactions := timeWindowTransactions(300)
+	input := append([]Transaction(nil), transactions...)
+	grouped := GroupByUser(transactions)
+	groups := make(map[uuid.UUID][]Transaction)
+	for _, userID := range grouped.Users() {
+		groups[userID] = append([]Transaction(nil), grouped.ForUser(userID)...)
+	}
+
+	for name, processor := range orderSensitiveRules(t) {
+		processor.Process(context.Background(), transactions)
+		require.Equal(t, input, transactions, "%s reordered or modified the caller's batch", name)
+
+		if groupedProcessor, ok := processor.(GroupedRuleProcessor); ok {
+			groupedProcessor.ProcessGrouped(context.Background(), grouped)
+			for userID, txs := range groups {
+				require.Equal(t, txs, grouped.ForUser(userID), "%s modified the shared grouping", name)
+			}
+		}
+	}
+}
+
+// TestVelocityProcessors_ConcurrentSharedInput runs velocity processors concurrently over the same
+// batch and the same grouping, which the race detector reports if any of them writes to either
+func TestVelocityProcessors_ConcurrentSharedInput(t *testing.T) {
+	transactions := timeWindowTransactions(300)
+	input := append([]Transaction(nil), transactions...)
+	grouped := GroupByUser(transactions)
+	periods := []VelocityPeriod{NewVelocityPeriod(24*time.Hour, 6)}
+	want := NewVelocityValidator(periods).Process(context.Background(), input)
+
+	var wg sync.WaitGroup
+	for range 2 {
+		for name, processor := range velocityImplementations(periods) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Equal(t, want, processor.Process(context.Background(), transactions), name)
+			}()
+		}
+		for _, processor := range []GroupedRuleProcessor{NewVelocityValidator(periods), NewBurstVelocityProcessor(periods)} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Equal(t, want, processor.ProcessGrouped(context.Background(), grouped))
+			}()
+		}
+	}
+	wg.Wait()
+
+	assert.Equal(t, input, transactions, "the caller's batch keeps its order")
+}
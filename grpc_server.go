@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"aml_rule_engine/amlpb"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GRPCServer serves a RuleEngine as the aml.v1.RuleEngine gRPC service defined in amlpb/aml.proto
+type GRPCServer struct {
+	amlpb.UnimplementedRuleEngineServer
+
+	engine        *RuleEngine
+	batchSize     int
+	flushInterval time.Duration
+}
+
+// ErrNoEngine is returned when a server is created without a RuleEngine to serve
+var ErrNoEngine = errors.New("no rule engine")
+
+// NewGRPCServer serves engine, evaluating EvaluateStream transactions in batches of batchSize,
+// a partial batch being evaluated once its first transaction has waited flushInterval as
+// EvaluateSource does. A nil engine or a batchSize below one is rejected.
+func NewGRPCServer(engine *RuleEngine, batchSize int, flushInterval time.Duration) (*GRPCServer, error) {
+	if engine == nil {
+		return nil, ErrNoEngine
+	}
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidBatchSize, batchSize)
+	}
+
+	return &GRPCServer{engine: engine, batchSize: batchSize, flushInterval: flushInterval}, nil
+}
+
+// Evaluate runs every rule over the request's transactions. Malformed transactions and batches
+// rejected by validation fail with InvalidArgument, failing rules with Internal.
+func (s *GRPCServer) Evaluate(ctx context.Context, req *amlpb.EvaluateRequest) (*amlpb.EvaluateResponse, error) {
+	transactions := make([]Transaction, len(req.GetTransactions()))
+	for i, msg := range req.GetTransactions() {
+		tx, err := transactionFromProto(msg)
+		if err != nil {
+			return nil, grpcError(ctx, ElementError{Index: i, Err: err})
+		}
+		transactions[i] = tx
+	}
+
+	result, err := s.engine.Evaluate(ctx, transactions)
+	if err != nil {
+		return nil, grpcError(ctx, err)
+	}
+
+	resp := &amlpb.EvaluateResponse{
+		RunId:  result.RunID.String(),
+		Alerts: make([]*amlpb.Alert, len(result.Alerts)),
+		Rules:  make([]*amlpb.RuleSummary, len(result.Rules)),
+	}
+	for i, alert := range result.Alerts {
+		resp.Alerts[i] = alertToProto(alert)
+	}
+	for i, rule := range result.Rules {
+		resp.Rules[i] = &amlpb.RuleSummary{Name: rule.Name, Severity: string(rule.Severity), FlaggedUsers: int32(rule.FlaggedUsers)}
+	}
+
+	return resp, nil
+}
+
+// EvaluateStream evaluates the streamed transactions as EvaluateSource does, sending the alerts of each
+// batch once it is evaluated
+func (s *GRPCServer) EvaluateStream(stream amlpb.RuleEngine_EvaluateStreamServer) error {
+	ctx := stream.Context()
+	err := s.engine.evaluateSource(ctx, &grpcStreamSource{stream: stream}, s.batchSize, s.flushInterval,
+		func(result EvaluationResult) error {
+			for _, alert := range result.Alerts {
+				if err := stream.Send(alertToProto(alert)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return grpcError(ctx, err)
+	}
+
+	return nil
+}
+
+// grpcStreamSource reads the transactions of an EvaluateStream call, returning io.EOF once the client
+// closes its side
+type grpcStreamSource struct {
+	stream   amlpb.RuleEngine_EvaluateStreamServer
+	received int
+	// pending delivers the Recv in flight, kept for the next call when ctx is done first since a
+	// stream takes one Recv at a time
+	pending chan grpcReceived
+}
+
+type grpcReceived struct {
+	msg *amlpb.Transaction
+	err error
+}
+
+// Next returns on ctx being done even while Recv blocks on a client sending nothing
+func (s *grpcStreamSource) Next(ctx context.Context) (Transaction, error) {
+	if s.pending == nil {
+		pending := make(chan grpcReceived, 1)
+		go func() {
+			msg, err := s.stream.Recv()
+			pending <- grpcReceived{msg: msg, err: err}
+		}()
+		s.pending = pending
+	}
+
+	var received grpcReceived
+	select {
+	case received = <-s.pending:
+		s.pending = nil
+	case <-ctx.Done():
+		return Transaction{}, ctx.Err()
+	}
+	if received.err != nil {
+		return Transaction{}, received.err
+	}
+	s.received++
+
+	tx, err := transactionFromProto(received.msg)
+	if err != nil {
+		return Transaction{}, ElementError{Index: s.received - 1, Err: err}
+	}
+
+	return tx, nil
+}
+
+// grpcError maps an evaluation error to a status, the call's context taking precedence
+func grpcError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var elementErr ElementError
+	if errors.As(err, &elementErr) || errors.Is(err, ErrInvalidBatch) {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+// transactionFromProto converts a wire transaction, empty UUIDs and amounts being read as zero
+// and a missing creation time as the zero time
+func transactionFromProto(msg *amlpb.Transaction) (Transaction, error) {
+	tx := Transaction{
+		Currency:            msg.GetCurrency(),
+		Country:             msg.GetCountry(),
+		DestinationCountry:  msg.GetDestinationCountry(),
+		Status:              TransactionStatus(msg.GetStatus()),
+		Direction:           Direction(msg.GetDirection()),
+		Channel:             Channel(msg.GetChannel()),
+		Category:            msg.GetCategory(),
+		Description:         msg.GetDescription(),
+		UserName:            msg.GetUserName(),
+		CounterpartyName:    msg.GetCounterpartyName(),
+		CounterpartyID:      msg.GetCounterpartyId(),
+		CounterpartyCountry: msg.GetCounterpartyCountry(),
+	}
+
+	var err error
+	if tx.TransactionID, err = uuidFromProto(msg.GetTransactionId()); err != nil {
+		return Transaction{}, fmt.Errorf("transaction_id: %w", err)
+	}
+	if tx.UserID, err = uuidFromProto(msg.GetUserId()); err != nil {
+		return Transaction{}, fmt.Errorf("user_id: %w", err)
+	}
+	if tx.AccountID, err = uuidFromProto(msg.GetAccountId()); err != nil {
+		return Transaction{}, fmt.Errorf("account_id: %w", err)
+	}
+	if msg.GetAmount() != "" {
+		if tx.Amount, err = decimal.NewFromString(msg.GetAmount()); err != nil {
+			return Transaction{}, fmt.Errorf("amount: %w", err)
+		}
+	}
+	if msg.GetCreatedAt() != nil {
+		if err := msg.GetCreatedAt().CheckValid(); err != nil {
+			return Transaction{}, fmt.Errorf("created_at: %w", err)
+		}
+		tx.CreatedAt = msg.GetCreatedAt().AsTime()
+	}
+
+	return tx, nil
+}
+
+func uuidFromProto(s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, nil
+	}
+
+	return uuid.Parse(s)
+}
+
+// uuidToProto writes the zero UUID as an empty string
+func uuidToProto(id uuid.UUID) string {
+	if id == uuid.Nil {
+		return ""
+	}
+
+	return id.String()
+}
+
+func transactionToProto(tx Transaction) *amlpb.Transaction {
+	msg := &amlpb.Transaction{
+		TransactionId:       uuidToProto(tx.TransactionID),
+		UserId:              uuidToProto(tx.UserID),
+		AccountId:           uuidToProto(tx.AccountID),
+		Amount:              tx.Amount.String(),
+		Currency:            tx.Currency,
+		Country:             tx.Country,
+		DestinationCountry:  tx.DestinationCountry,
+		Status:              string(tx.Status),
+		Direction:           string(tx.Direction),
+		Channel:             string(tx.Channel),
+		Category:            tx.Category,
+		Description:         tx.Description,
+		UserName:            tx.UserName,
+		CounterpartyName:    tx.CounterpartyName,
+		CounterpartyId:      tx.CounterpartyID,
+		CounterpartyCountry: tx.CounterpartyCountry,
+	}
+	if !tx.CreatedAt.IsZero() {
+		msg.CreatedAt = timestamppb.New(tx.CreatedAt)
+	}
+
+	return msg
+}
+
+func alertToProto(alert Alert) *amlpb.Alert {
+	msg := &amlpb.Alert{
+		Id:        uuidToProto(alert.ID),
+		UserId:    uuidToProto(alert.UserID),
+		RuleName:  alert.RuleName,
+		Severity:  string(alert.Severity),
+		CreatedAt: timestamppb.New(alert.CreatedAt),
+		Evidence:  make([]*amlpb.Transaction, len(alert.Evidence)),
+		Details:   alert.Details,
+	}
+	for i, tx := range alert.Evidence {
+		msg.Evidence[i] = transactionToProto(tx)
+	}
+
+	return msg
+}
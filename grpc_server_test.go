@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"aml_rule_engine/amlpb"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newGRPCClient serves engine over an in-memory connection for the duration of the test
+func newGRPCClient(t *testing.T, engine *RuleEngine, batchSize int, flushInterval time.Duration) amlpb.RuleEngineClient {
+	t.Helper()
+	server, err := NewGRPCServer(engine, batchSize, flushInterval)
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	amlpb.RegisterRuleEngineServer(grpcServer, server)
+	go grpcServer.Serve(listener)
+	// GracefulStop waits for the handlers, which may outlive a call the client cancelled
+	t.Cleanup(grpcServer.GracefulStop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return amlpb.NewRuleEngineClient(conn)
+}
+
+func TestGRPCServer_Evaluate(t *testing.T) {
+	flagged := Transaction{
+		TransactionID: uuid.New(),
+		UserID:        uuid.New(),
+		Amount:        decimal.RequireFromString("1234.5678901234"),
+		Country:       "IR",
+		Channel:       ChannelWire,
+		CreatedAt:     time.Date(2024, 3, 1, 9, 0, 0, 123456789, time.UTC),
+	}
+	clean := Transaction{UserID: uuid.New(), Amount: decimal.NewFromInt(10), Country: "FR", CreatedAt: flagged.CreatedAt}
+
+	engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")})
+	client := newGRPCClient(t, engine, 10, time.Second)
+
+	resp, err := client.Evaluate(context.Background(), &amlpb.EvaluateRequest{
+		Transactions: []*amlpb.Transaction{transactionToProto(flagged), transactionToProto(clean)},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, resp.GetAlerts(), 1)
+	alert := resp.GetAlerts()[0]
+	assert.Equal(t, flagged.UserID.String(), alert.GetUserId())
+	assert.Equal(t, "CountryBlackListProcessor", alert.GetRuleName())
+	assert.Equal(t, string(SeverityCritical), alert.GetSeverity())
+	require.Len(t, alert.GetEvidence(), 1)
+
+	evidence, err := transactionFromProto(alert.GetEvidence()[0])
+	require.NoError(t, err)
+	assert.Equal(t, "1234.5678901234", evidence.Amount.String(), "amounts keep their precision")
+	assert.True(t, flagged.CreatedAt.Equal(evidence.CreatedAt))
+	assert.Equal(t, flagged.TransactionID, evidence.TransactionID)
+	assert.Equal(t, uuid.Nil, evidence.AccountID)
+	assert.Empty(t, alert.GetEvidence()[0].GetAccountId(), "the zero UUID is sent as an empty string")
+
+	require.Len(t, resp.GetRules(), 1)
+	assert.Equal(t, int32(1), resp.GetRules()[0].GetFlaggedUsers())
+}
+
+func TestNewGRPCServer_Rejects(t *testing.T) {
+	_, err := NewGRPCServer(nil, 10, time.Second)
+	assert.ErrorIs(t, err, ErrNoEngine)
+
+	for _, batchSize := range []int{0, -1} {
+		_, err = NewGRPCServer(NewRuleEngine(nil), batchSize, time.Second)
+		assert.ErrorIs(t, err, ErrInvalidBatchSize)
+	}
+}
+
+func TestGRPCServer_Evaluate_Errors(t *testing.T) {
+	valid := &amlpb.Transaction{UserId: uuid.NewString(), Amount: "10"}
+	userID := uuid.New()
+
+	tests := []struct {
+		name         string
+		processors   []RuleProcessor
+		transactions []*amlpb.Transaction
+		wantCode     codes.Code
+		wantMessage  string
+	}{
+		{
+			name:         "malformed amount",
+			transactions: []*amlpb.Transaction{valid, {UserId: uuid.NewString(), Amount: "1x"}},
+			wantCode:     codes.InvalidArgument,
+			wantMessage:  "element 1: amount:",
+		},
+		{
+			name:         "malformed UUID",
+			transactions: []*amlpb.Transaction{{UserId: "not-a-uuid"}},
+			wantCode:     codes.InvalidArgument,
+			wantMessage:  "element 0: user_id:",
+		},
+		{
+			name:       "rule error",
+			processors: []RuleProcessor{NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 1)}, WithStrictSortedInput())},
+			transactions: []*amlpb.Transaction{
+				transactionToProto(Transaction{UserID: userID, CreatedAt: time.Now()}),
+				transactionToProto(Transaction{UserID: userID, CreatedAt: time.Now().Add(-time.Hour)}),
+			},
+			wantCode:    codes.Internal,
+			wantMessage: "VelocityProcessor:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newGRPCClient(t, NewRuleEngine(tt.processors), 10, time.Second)
+
+			_, err := client.Evaluate(context.Background(), &amlpb.EvaluateRequest{Transactions: tt.transactions})
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Contains(t, status.Convert(err).Message(), tt.wantMessage)
+		})
+	}
+}
+
+func TestGRPCServer_EvaluateStream(t *testing.T) {
+	engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")})
+	client := newGRPCClient(t, engine, 2, time.Hour)
+
+	stream, err := client.EvaluateStream(context.Background())
+	require.NoError(t, err)
+
+	transactions := blacklistedTransactions(3)
+	for _, tx := range transactions {
+		require.NoError(t, stream.Send(transactionToProto(tx)))
+	}
+
+	// the first full batch is answered while the stream is still open
+	var firstBatch []string
+	for range 2 {
+		alert, err := stream.Recv()
+		require.NoError(t, err)
+		firstBatch = append(firstBatch, alert.GetUserId())
+	}
+	assert.ElementsMatch(t, []string{transactions[0].UserID.String(), transactions[1].UserID.String()}, firstBatch)
+
+	require.NoError(t, stream.CloseSend())
+	alert, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, transactions[2].UserID.String(), alert.GetUserId(), "the partial batch is evaluated on close")
+
+	_, err = stream.Recv()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Equal(t, int64(2), engine.Stats().Evaluations)
+}
+
+func TestGRPCServer_EvaluateStream_Errors(t *testing.T) {
+	t.Run("malformed transaction", func(t *testing.T) {
+		client := newGRPCClient(t, NewRuleEngine(nil), 10, time.Hour)
+
+		stream, err := client.EvaluateStream(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(&amlpb.Transaction{UserId: uuid.NewString(), Amount: "10"}))
+		require.NoError(t, stream.Send(&amlpb.Transaction{UserId: uuid.NewString(), Amount: "ten"}))
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, status.Convert(err).Message(), "element 1: amount:")
+	})
+
+	t.Run("cancelled stream", func(t *testing.T) {
+		engine, sink := newSourceEngine()
+		client := newGRPCClient(t, engine, 10, time.Hour)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stream, err := client.EvaluateStream(ctx)
+		require.NoError(t, err)
+		require.NoError(t, stream.Send(transactionToProto(blacklistedTransactions(1)[0])))
+		cancel()
+
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Canceled, status.Code(err))
+		assert.Never(t, func() bool { return engine.Stats().Evaluations > 0 }, 50*time.Millisecond, 5*time.Millisecond,
+			"the pending batch is discarded")
+		assert.Empty(t, sink.Alerts())
+	})
+}
+
+// blockingStream is an EvaluateStream server stream whose Recv waits for a message on msgs
+type blockingStream struct {
+	amlpb.RuleEngine_EvaluateStreamServer
+	msgs  chan *amlpb.Transaction
+	recvs atomic.Int32
+}
+
+func (b *blockingStream) Recv() (*amlpb.Transaction, error) {
+	b.recvs.Add(1)
+	msg, ok := <-b.msgs
+	if !ok {
+		return nil, io.EOF
+	}
+	return msg, nil
+}
+
+func TestGRPCStreamSource_Next_Cancelled(t *testing.T) {
+	stream := &blockingStream{msgs: make(chan *amlpb.Transaction)}
+	source := &grpcStreamSource{stream: stream}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := source.Next(ctx)
+	assert.ErrorIs(t, err, context.Canceled, "Next returns while Recv blocks")
+
+	// the Recv left in flight is picked up by the next call, no message being lost or read twice
+	tx := blacklistedTransactions(1)[0]
+	stream.msgs <- transactionToProto(tx)
+	got, err := source.Next(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, tx.UserID, got.UserID)
+	assert.Equal(t, int32(1), stream.recvs.Load())
+
+	close(stream.msgs)
+	_, err = source.Next(context.Background())
+	assert.ErrorIs(t, err, io.EOF)
+}
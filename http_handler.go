@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"time"
+)
+
+// defaults of the limits applied by the HTTP handler
+const (
+	defaultMaxRequestBytes   = 10 << 20
+	defaultEvaluationTimeout = 30 * time.Second
+)
+
+type httpOptions struct {
+	maxRequestBytes int64
+	timeout         time.Duration
+}
+
+// WithMaxRequestBytes caps the body of evaluation requests served by NewHTTPHandler, larger ones
+// failing with 413. It defaults to 10 MiB.
+func WithMaxRequestBytes(n int64) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.http.maxRequestBytes = n
+	}
+}
+
+// WithEvaluationTimeout bounds each evaluation served by NewHTTPHandler, defaulting to 30 seconds.
+// The timeout is passed to the processors through their context and cannot interrupt those ignoring it:
+// a request fails with 504 only when a processor gives up with the context's error, a slow processor
+// that ignores the context running to completion and answering with its report.
+func WithEvaluationTimeout(timeout time.Duration) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.http.timeout = timeout
+	}
+}
+
+// NewHTTPHandler serves engine over HTTP:
+//
+//	POST /v1/evaluate  evaluates a JSON array or NDJSON body of transactions, answering the JSON report
+//	GET  /v1/rules     lists the registered rules with their severity and configuration
+//	GET  /healthz      answers 200 while the process is up
+//
+// Malformed payloads fail with 400 and the offending row, a batch rejected by validation with 400 and
+// its failed checks, and failing rules with 500 naming them.
+func NewHTTPHandler(engine *RuleEngine) http.Handler {
+	h := &httpHandler{engine: engine, maxRequestBytes: engine.http.maxRequestBytes, timeout: engine.http.timeout}
+	if h.maxRequestBytes <= 0 {
+		h.maxRequestBytes = defaultMaxRequestBytes
+	}
+	if h.timeout <= 0 {
+		h.timeout = defaultEvaluationTimeout
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/evaluate", h.evaluate)
+	mux.HandleFunc("GET /v1/rules", h.rules)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, "ok\n")
+	})
+
+	return mux
+}
+
+type httpHandler struct {
+	engine          *RuleEngine
+	maxRequestBytes int64
+	timeout         time.Duration
+}
+
+// httpError is the body of every failed response
+type httpError struct {
+	Error string `json:"error"`
+	// Rows locates the malformed part of the payload
+	Rows             []httpRowError    `json:"rows,omitempty"`
+	ValidationErrors []ValidationError `json:"validation_errors,omitempty"`
+	// Rules lists the rules that failed
+	Rules []RuleSummary `json:"rules,omitempty"`
+}
+
+// httpRowError is a malformed NDJSON line, or JSON array element when Index is set
+type httpRowError struct {
+	Line    int    `json:"line,omitempty"`
+	Index   *int   `json:"index,omitempty"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// httpRule describes a registered rule, Config holding the processor's exported fields or null when
+// they cannot be written as JSON
+type httpRule struct {
+	Name     string          `json:"name"`
+	Severity Severity        `json:"severity"`
+	Config   json.RawMessage `json:"config"`
+}
+
+func (h *httpHandler) evaluate(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, h.maxRequestBytes)
+	transactions, err := decodeTransactions(body, r.Header.Get("Content-Type"))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeJSON(w, http.StatusRequestEntityTooLarge, httpError{Error: tooLarge.Error()})
+			return
+		}
+		writeJSON(w, http.StatusBadRequest, httpError{Error: err.Error(), Rows: httpRowErrors(err)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), h.timeout)
+	defer cancel()
+
+	result, err := h.engine.Evaluate(ctx, transactions)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		writeJSON(w, http.StatusGatewayTimeout, httpError{Error: "evaluation timed out after " + h.timeout.String()})
+	case errors.Is(err, context.Canceled):
+		writeJSON(w, http.StatusServiceUnavailable, httpError{Error: "evaluation canceled: " + err.Error()})
+	case errors.Is(err, ErrInvalidBatch):
+		writeJSON(w, http.StatusBadRequest, httpError{Error: err.Error(), ValidationErrors: result.ValidationErrors})
+	case err != nil:
+		var failed []RuleSummary
+		for _, rule := range result.Rules {
+			if rule.Error != "" {
+				failed = append(failed, rule)
+			}
+		}
+		writeJSON(w, http.StatusInternalServerError, httpError{Error: err.Error(), Rules: failed})
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		WriteJSONReport(w, result)
+	}
+}
+
+func (h *httpHandler) rules(w http.ResponseWriter, _ *http.Request) {
+	engineRules, _ := h.engine.currentRules()
+	rules := make([]httpRule, len(engineRules))
+	for i, rule := range engineRules {
+		rules[i] = httpRule{Name: ruleName(rule.processor), Severity: rule.severity}
+		if config, err := json.Marshal(rule.processor); err == nil {
+			rules[i].Config = config
+		}
+	}
+
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// decodeTransactions reads an NDJSON body, or a JSON array when its first non-blank byte is '['
+// unless the content type says NDJSON
+func decodeTransactions(body io.Reader, contentType string) ([]Transaction, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/x-ndjson" {
+		return LoadTransactionsNDJSON(body)
+	}
+
+	reader := bufio.NewReader(body)
+	var blank []byte
+	for {
+		b, err := reader.ReadByte()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			blank = append(blank, b)
+			continue
+		}
+
+		reader.UnreadByte()
+		if b == '[' {
+			return LoadTransactionsJSON(reader)
+		}
+		// the skipped blank lines are replayed so NDJSON line numbers stay right
+		return LoadTransactionsNDJSON(io.MultiReader(bytes.NewReader(blank), reader))
+	}
+}
+
+// httpRowErrors locates a payload error, nil when it is not tied to a row
+func httpRowErrors(err error) []httpRowError {
+	var rowErr RowError
+	if errors.As(err, &rowErr) {
+		return []httpRowError{{Line: rowErr.Line, Column: rowErr.Column, Message: rowErr.Err.Error()}}
+	}
+	var elementErr ElementError
+	if errors.As(err, &elementErr) {
+		return []httpRowError{{Index: &elementErr.Index, Message: elementErr.Err.Error()}}
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
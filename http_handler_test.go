@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingProcessor gives up with the context's error once it is done, signalling started when it begins
+type blockingProcessor struct {
+	started chan struct{}
+}
+
+func (b blockingProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers, _ := b.ProcessContext(ctx, transactions)
+	return flaggedUsers
+}
+
+func (b blockingProcessor) ProcessContext(ctx context.Context, _ []Transaction) (map[uuid.UUID]struct{}, error) {
+	close(b.started)
+	<-ctx.Done()
+	return map[uuid.UUID]struct{}{}, ctx.Err()
+}
+
+// slowProcessor ignores its context, flagging every user once delay has elapsed
+type slowProcessor struct {
+	delay time.Duration
+}
+
+func (s slowProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	time.Sleep(s.delay)
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	for _, tx := range transactions {
+		flaggedUsers[tx.UserID] = struct{}{}
+	}
+	return flaggedUsers
+}
+
+const httpTestUser = "1b4e28ba-2fa1-11d2-883f-0016d3cca427"
+
+func serveHTTP(t *testing.T, handler http.Handler, req *http.Request) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var body map[string]any
+	if rec.Header().Get("Content-Type") == "application/json" {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	}
+
+	return rec, body
+}
+
+func TestHTTPHandler_Evaluate(t *testing.T) {
+	ndjson := `
+{"user_id":"` + httpTestUser + `","country":"IR","amount":"10","created_at":"2024-03-01T09:00:00Z"}
+{"user_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","country":"FR","amount":"10","created_at":"2024-03-01T09:00:00Z"}
+`
+	array := `[
+  {"user_id":"` + httpTestUser + `","country":"IR","amount":"10","created_at":"2024-03-01T09:00:00Z"},
+  {"user_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","country":"FR","amount":"1x","created_at":"2024-03-01T09:00:00Z"}
+]`
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantAlerts  int
+		wantRow     map[string]any
+	}{
+		{name: "NDJSON", contentType: "application/x-ndjson", body: ndjson, wantStatus: http.StatusOK, wantAlerts: 1},
+		{name: "NDJSON sniffed", body: ndjson, wantStatus: http.StatusOK, wantAlerts: 1},
+		{name: "JSON array", contentType: "application/json", body: strings.Replace(array, "1x", "10", 1), wantStatus: http.StatusOK, wantAlerts: 1},
+		{name: "empty body", wantStatus: http.StatusOK},
+		{
+			name:       "malformed decimal in NDJSON",
+			body:       strings.Replace(ndjson, `"IR","amount":"10"`, `"IR","amount":"1x"`, 1),
+			wantStatus: http.StatusBadRequest,
+			wantRow:    map[string]any{"line": float64(2)},
+		},
+		{
+			name:       "malformed decimal in JSON array",
+			body:       array,
+			wantStatus: http.StatusBadRequest,
+			wantRow:    map[string]any{"index": float64(1)},
+		},
+	}
+
+	handler := NewHTTPHandler(NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			rec, body := serveHTTP(t, handler, req)
+
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantRow != nil {
+				rows := body["rows"].([]any)
+				require.Len(t, rows, 1)
+				row := rows[0].(map[string]any)
+				for key, want := range tt.wantRow {
+					assert.Equal(t, want, row[key])
+				}
+				assert.Contains(t, row["message"], "decimal")
+				return
+			}
+
+			alerts := body["alerts"].([]any)
+			require.Len(t, alerts, tt.wantAlerts)
+			if tt.wantAlerts > 0 {
+				assert.Equal(t, httpTestUser, alerts[0].(map[string]any)["user_id"])
+			}
+		})
+	}
+}
+
+func TestHTTPHandler_Evaluate_Limits(t *testing.T) {
+	body := `{"user_id":"` + httpTestUser + `","country":"IR","amount":"10","created_at":"2024-03-01T09:00:00Z"}` + "\n"
+
+	t.Run("oversized body", func(t *testing.T) {
+		handler := NewHTTPHandler(NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")}, WithMaxRequestBytes(int64(len(body)))))
+
+		rec, _ := serveHTTP(t, handler, httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, rec.Code, "a body of exactly the limit is accepted")
+
+		rec, errBody := serveHTTP(t, handler, httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(body+body)))
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+		assert.Contains(t, errBody["error"], "too large")
+	})
+
+	t.Run("rule error", func(t *testing.T) {
+		unsorted := `{"user_id":"` + httpTestUser + `","created_at":"2024-03-01T10:00:00Z"}
+{"user_id":"` + httpTestUser + `","created_at":"2024-03-01T09:00:00Z"}`
+		handler := NewHTTPHandler(NewRuleEngine([]RuleProcessor{
+			NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 1)}, WithStrictSortedInput()),
+			NewCountryBlackListProcessor("IR"),
+		}))
+
+		rec, errBody := serveHTTP(t, handler, httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(unsorted)))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		rules := errBody["rules"].([]any)
+		require.Len(t, rules, 1)
+		assert.Equal(t, "VelocityProcessor", rules[0].(map[string]any)["name"])
+		assert.Contains(t, rules[0].(map[string]any)["error"], "sorted")
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		policy := DefaultValidationPolicy()
+		policy.Action = ValidationFail
+		handler := NewHTTPHandler(NewRuleEngine(nil, WithValidation(policy)))
+
+		rec, errBody := serveHTTP(t, handler, httptest.NewRequest(http.MethodPost, "/v1/evaluate",
+			strings.NewReader(`{"country":"IR","amount":"10","created_at":"2024-03-01T09:00:00Z"}`)))
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Len(t, errBody["validation_errors"], 1)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		processor := blockingProcessor{started: make(chan struct{})}
+		handler := NewHTTPHandler(NewRuleEngine([]RuleProcessor{processor}, WithEvaluationTimeout(10*time.Millisecond)))
+
+		rec, _ := serveHTTP(t, handler, httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(body)))
+		assert.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	})
+
+	t.Run("processor ignoring the timeout", func(t *testing.T) {
+		handler := NewHTTPHandler(NewRuleEngine([]RuleProcessor{slowProcessor{delay: 30 * time.Millisecond}}, WithEvaluationTimeout(time.Millisecond)))
+
+		rec, body := serveHTTP(t, handler, httptest.NewRequest(http.MethodPost, "/v1/evaluate", strings.NewReader(body)))
+		assert.Equal(t, http.StatusOK, rec.Code, "the completed run is reported, not a timeout")
+		assert.Len(t, body["alerts"], 1)
+	})
+
+	t.Run("client cancellation mid-request", func(t *testing.T) {
+		processor := blockingProcessor{started: make(chan struct{})}
+		handler := NewHTTPHandler(NewRuleEngine([]RuleProcessor{processor}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/v1/evaluate", strings.NewReader(body))
+		go func() {
+			<-processor.started
+			cancel()
+		}()
+
+		rec, errBody := serveHTTP(t, handler, req)
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		assert.Contains(t, errBody["error"], "canceled")
+	})
+}
+
+func TestHTTPHandler_Rules(t *testing.T) {
+	handler := NewHTTPHandler(NewRuleEngine([]RuleProcessor{
+		NewCountryBlackListProcessor("IR"),
+		NewFilteredProcessor("amount > 500", func(Transaction) bool { return true }, NewCountryBlackListProcessor("RU")),
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/rules", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var rules []httpRule
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rules))
+	require.Len(t, rules, 2)
+	assert.Equal(t, "CountryBlackListProcessor", rules[0].Name)
+	assert.Equal(t, SeverityCritical, rules[0].Severity)
+	assert.JSONEq(t, `{"Blacklist":{"IR":{}},"ExactCountries":false}`, string(rules[0].Config))
+	assert.Equal(t, "null", string(rules[1].Config), "a filter function cannot be written as JSON")
+}
+
+func TestHTTPHandler_Routes(t *testing.T) {
+	handler := NewHTTPHandler(NewRuleEngine(nil))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "ok\n", rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/evaluate", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
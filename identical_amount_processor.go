@@ -0,0 +1,63 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// IdenticalAmountProcessor flags users repeating the exact same amount at least MinCount times within
+// Window, e.g. five transactions of 487.23 in a week
+type IdenticalAmountProcessor struct {
+	MinCount int
+	// Floor ignores amounts below it, zero keeps every amount. Subscriptions and small fees
+	// repeat identical amounts legitimately and are the main false-positive source without it.
+	Floor  decimal.Decimal
+	Window time.Duration
+}
+
+func NewIdenticalAmountProcessor(minCount int, floor decimal.Decimal, window time.Duration) IdenticalAmountProcessor {
+	return IdenticalAmountProcessor{
+		MinCount: minCount,
+		Floor:    floor,
+		Window:   window,
+	}
+}
+
+func (i IdenticalAmountProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return tx.Amount.GreaterThanOrEqual(i.Floor)
+	}}
+	// "at least MinCount" is a velocity period flagging more than MinCount-1
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(i.Window, i.MinCount-1)}, options)
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			// Amount.String() drops trailing zeros, so 487.23 and 487.230 share a run; sorting by
+			// amount then time leaves each run ordered for the checker
+			slices.SortFunc(txs, func(a, b Transaction) int {
+				return cmp.Or(cmp.Compare(a.Amount.String(), b.Amount.String()), a.CreatedAt.Compare(b.CreatedAt))
+			})
+
+			for start := 0; start < len(txs); {
+				end := start + 1
+				for end < len(txs) && txs[end].Amount.String() == txs[start].Amount.String() {
+					end++
+				}
+
+				if violated, _ := checker.CheckUser(txs[start:end]); violated {
+					flaggedUsers[userID] = struct{}{}
+					break
+				}
+				start = end
+			}
+		}
+	}
+
+	return flaggedUsers
+}
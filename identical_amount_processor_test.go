@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdenticalAmountProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	repeated := func(spacing time.Duration, amounts ...string) []Transaction {
+		transactions := make([]Transaction, len(amounts))
+		for i, amount := range amounts {
+			transactions[i] = Transaction{UserID: userID, Amount: decimal.RequireFromString(amount), CreatedAt: baseTime.Add(time.Duration(i) * spacing)}
+		}
+		return transactions
+	}
+
+	tests := []struct {
+		name         string
+		floor        decimal.Decimal
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "five identical amounts inside the window",
+			transactions: repeated(24*time.Hour, "487.23", "487.23", "487.23", "487.23", "487.23"),
+			wantFlagged:  true,
+		},
+		{
+			name:         "five identical amounts spread beyond the window",
+			transactions: repeated(5*24*time.Hour, "487.23", "487.23", "487.23", "487.23", "487.23"),
+			wantFlagged:  false,
+		},
+		{
+			name:         "four identical amounts",
+			transactions: repeated(24*time.Hour, "487.23", "487.23", "487.23", "487.23", "120.00"),
+			wantFlagged:  false,
+		},
+		{
+			name:         "trailing zeros compare equal",
+			transactions: repeated(24*time.Hour, "487.23", "487.230", "487.2300", "487.23", "487.230"),
+			wantFlagged:  true,
+		},
+		{
+			name:         "near-identical amounts are distinct",
+			transactions: repeated(24*time.Hour, "487.23", "487.24", "487.23", "487.22", "487.23"),
+			wantFlagged:  false,
+		},
+		{
+			// a daily 9.99 subscription is flagged without a floor, the known false positive it exists for
+			name:         "small subscription without a floor",
+			transactions: repeated(24*time.Hour, "9.99", "9.99", "9.99", "9.99", "9.99"),
+			wantFlagged:  true,
+		},
+		{
+			name:         "small subscription below the floor",
+			floor:        decimal.NewFromInt(50),
+			transactions: repeated(24*time.Hour, "9.99", "9.99", "9.99", "9.99", "9.99"),
+			wantFlagged:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewIdenticalAmountProcessor(5, tt.floor, week)
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
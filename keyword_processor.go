@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// KeywordProcessor flags users with at least MinCount transactions within Window whose Description
+// matches a keyword or pattern, such as "loan repayment" or "gift"
+type KeywordProcessor struct {
+	MinCount int
+	Window   time.Duration
+
+	matchers []keywordMatcher
+}
+
+// keywordMatcher pairs the configured term with its compiled form
+type keywordMatcher struct {
+	term string
+	re   *regexp.Regexp
+}
+
+// NewKeywordProcessor matches keywords literally and case-insensitively, only as whole words when
+// wordBoundary is set, and patterns as regular expressions. Invalid patterns are reported here.
+func NewKeywordProcessor(keywords, patterns []string, wordBoundary bool, minCount int, window time.Duration) (KeywordProcessor, error) {
+	k := KeywordProcessor{MinCount: minCount, Window: window}
+
+	for _, keyword := range keywords {
+		expr := "(?i)" + regexp.QuoteMeta(keyword)
+		if wordBoundary {
+			expr = `(?i)\b` + regexp.QuoteMeta(keyword) + `\b`
+		}
+		k.matchers = append(k.matchers, keywordMatcher{term: keyword, re: regexp.MustCompile(expr)})
+	}
+
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return KeywordProcessor{}, fmt.Errorf("pattern %q: %w", pattern, err)
+		}
+		k.matchers = append(k.matchers, keywordMatcher{term: pattern, re: re})
+	}
+
+	return k, nil
+}
+
+// matchedTerms returns the terms matching description in configuration order
+func (k KeywordProcessor) matchedTerms(description string) []string {
+	var terms []string
+	for _, matcher := range k.matchers {
+		if matcher.re.MatchString(description) {
+			terms = append(terms, matcher.term)
+		}
+	}
+
+	return terms
+}
+
+func (k KeywordProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID := range k.Matches(ctx, transactions) {
+		flaggedUsers[userID] = struct{}{}
+	}
+
+	return flaggedUsers
+}
+
+// Matches returns, per flagged user, the sorted terms matched by any of their transactions.
+// Transactions with an empty Description are skipped.
+func (k KeywordProcessor) Matches(_ context.Context, transactions []Transaction) map[uuid.UUID][]string {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return strings.TrimSpace(tx.Description) != "" && len(k.matchedTerms(tx.Description)) > 0
+	}}
+	// "at least MinCount" is a velocity period flagging more than MinCount-1
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(k.Window, k.MinCount-1)}, options)
+	matches := make(map[uuid.UUID][]string)
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			if violated, _ := checker.CheckUser(txs); !violated {
+				continue
+			}
+
+			var terms []string
+			for _, tx := range txs {
+				terms = append(terms, k.matchedTerms(tx.Description)...)
+			}
+			slices.Sort(terms)
+			matches[userID] = slices.Compact(terms)
+		}
+	}
+
+	return matches
+}
+
+func (k KeywordProcessor) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	var evidence []Transaction
+	for _, tx := range transactions {
+		if len(k.matchedTerms(tx.Description)) > 0 {
+			evidence = append(evidence, tx)
+		}
+	}
+
+	return evidence, map[string]string{"terms": strings.Join(k.Matches(ctx, transactions)[userID], ", ")}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeywordProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	described := func(descriptions ...string) []Transaction {
+		transactions := make([]Transaction, len(descriptions))
+		for i, description := range descriptions {
+			transactions[i] = Transaction{UserID: userID, Description: description, CreatedAt: baseTime.Add(time.Duration(i) * time.Hour)}
+		}
+		return transactions
+	}
+
+	tests := []struct {
+		name         string
+		wordBoundary bool
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "keywords accumulate toward the count",
+			wordBoundary: true,
+			transactions: described("Gift for Anna", "LOAN REPAYMENT march", "birthday gift"),
+			wantFlagged:  true,
+		},
+		{
+			name:         "below the count",
+			wordBoundary: true,
+			transactions: described("gift", "groceries", "loan repayment"),
+			wantFlagged:  false,
+		},
+		{
+			name:         "keyword inside a longer word in word-boundary mode",
+			wordBoundary: true,
+			transactions: described("giftcard", "regifted", "gifts"),
+			wantFlagged:  false,
+		},
+		{
+			name:         "keyword inside a longer word without word boundaries",
+			wordBoundary: false,
+			transactions: described("giftcard", "regifted", "gifts"),
+			wantFlagged:  true,
+		},
+		{
+			name:         "regex pattern",
+			wordBoundary: true,
+			transactions: described("INV-2024-0113", "INV-2024-0114", "inv-2024-0115"),
+			wantFlagged:  true,
+		},
+		{
+			name:         "empty descriptions skipped",
+			wordBoundary: true,
+			transactions: described("", "  ", "gift", "gift"),
+			wantFlagged:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor, err := NewKeywordProcessor([]string{"gift", "loan repayment"}, []string{`(?i)^inv-\d{4}-\d+$`}, tt.wordBoundary, 3, week)
+			require.NoError(t, err)
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestKeywordProcessor_Matches(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, Description: "crypto gift", CreatedAt: baseTime},
+		{UserID: userID, Description: "Crypto top-up", CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID, Description: "rent", CreatedAt: baseTime.Add(2 * time.Hour)},
+	}
+
+	processor, err := NewKeywordProcessor([]string{"crypto", "gift", "invoice"}, nil, true, 2, week)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[uuid.UUID][]string{userID: {"crypto", "gift"}}, processor.Matches(context.Background(), transactions))
+}
+
+func TestNewKeywordProcessor_InvalidPattern(t *testing.T) {
+	_, err := NewKeywordProcessor([]string{"gift"}, []string{`(unclosed`}, true, 1, week)
+
+	assert.ErrorContains(t, err, "(unclosed")
+}
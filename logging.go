@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/google/uuid"
+)
+
+// WithLogger logs each evaluation to logger: its start and end at Info, each rule's completion at
+// Debug, failing or timed out rules at Error and validation failures at Warn, every line carrying
+// the run's evaluation_id. Nothing is logged per transaction. Without it, nothing is logged.
+func WithLogger(logger *slog.Logger) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.logger = logger
+	}
+}
+
+// runLogger returns the engine's logger scoped to a run, discarding everything without one
+func (r *RuleEngine) runLogger(runID uuid.UUID) *slog.Logger {
+	if r.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+
+	return r.logger.With(slog.String("evaluation_id", runID.String()))
+}
+
+// logRule logs the outcome of a rule, one stopped by the context's deadline being reported as timed out
+func logRule(ctx context.Context, logger *slog.Logger, rule string, outcome ruleOutcome) {
+	attrs := []slog.Attr{
+		slog.String("rule", rule),
+		slog.Int("flagged_users", len(outcome.flaggedUsers)),
+		slog.Duration("duration", outcome.duration),
+	}
+
+	switch {
+	case errors.Is(outcome.err, context.DeadlineExceeded):
+		logger.LogAttrs(ctx, slog.LevelError, "rule timed out", append(attrs, slog.Any("error", outcome.err))...)
+	case outcome.err != nil:
+		logger.LogAttrs(ctx, slog.LevelError, "rule failed", append(attrs, slog.Any("error", outcome.err))...)
+	default:
+		logger.LogAttrs(ctx, slog.LevelDebug, "rule completed", attrs...)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logLines decodes the lines written by a JSON slog handler
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		delete(entry, "time")
+		lines = append(lines, entry)
+	}
+
+	return lines
+}
+
+func TestRuleEngine_Evaluate_Logging(t *testing.T) {
+	baseTime := time.Now().Add(-time.Hour)
+	userID := uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: baseTime.Add(time.Minute)},
+		{UserID: userID, Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: baseTime},
+		{UserID: uuid.New(), Country: "FR", CreatedAt: baseTime},
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	engine := NewRuleEngine([]RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 1)}, WithStrictSortedInput()),
+		NewCountryBlackListProcessor("IR"),
+	}, WithLogger(logger), WithValidation(DefaultValidationPolicy()))
+
+	result, err := engine.Evaluate(context.Background(), transactions)
+	require.Error(t, err)
+
+	lines := logLines(t, &buf)
+	require.Len(t, lines, 5, "one line per run event and rule, none per transaction")
+	for _, line := range lines {
+		assert.Equal(t, result.RunID.String(), line["evaluation_id"])
+		delete(line, "evaluation_id")
+	}
+
+	assert.Equal(t, map[string]any{"level": "INFO", "msg": "evaluation started", "transactions": 3.0, "rules": 2.0}, lines[0])
+	assert.Equal(t, map[string]any{"level": "WARN", "msg": "transactions failed validation", "failed_checks": 1.0, "dropped": 1.0}, lines[1])
+
+	assert.Equal(t, "ERROR", lines[2]["level"])
+	assert.Equal(t, "rule failed", lines[2]["msg"])
+	assert.Equal(t, "VelocityProcessor", lines[2]["rule"])
+	assert.Equal(t, 0.0, lines[2]["flagged_users"])
+	assert.Contains(t, lines[2]["error"], "not sorted")
+	assert.Contains(t, lines[2], "duration")
+
+	assert.Equal(t, "DEBUG", lines[3]["level"])
+	assert.Equal(t, "rule completed", lines[3]["msg"])
+	assert.Equal(t, "CountryBlackListProcessor", lines[3]["rule"])
+	assert.Equal(t, 1.0, lines[3]["flagged_users"])
+
+	assert.Equal(t, "INFO", lines[4]["level"])
+	assert.Equal(t, "evaluation finished", lines[4]["msg"])
+	assert.Equal(t, 2.0, lines[4]["transactions"])
+	assert.Equal(t, 1.0, lines[4]["alerts"])
+	assert.Contains(t, lines[4]["error"], "VelocityProcessor")
+}
+
+func TestRuleEngine_Evaluate_LoggingTimeout(t *testing.T) {
+	var buf bytes.Buffer
+	engine := NewRuleEngine([]RuleProcessor{blockingProcessor{started: make(chan struct{})}},
+		WithLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := engine.Evaluate(ctx, []Transaction{{UserID: uuid.New(), CreatedAt: time.Now()}})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	lines := logLines(t, &buf)
+	require.Len(t, lines, 3)
+	assert.Equal(t, "ERROR", lines[1]["level"])
+	assert.Equal(t, "rule timed out", lines[1]["msg"])
+	assert.Equal(t, "blockingProcessor", lines[1]["rule"])
+	assert.Equal(t, "evaluation finished", lines[2]["msg"])
+}
+
+func TestRuleEngine_Evaluate_WithoutLogger(t *testing.T) {
+	engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")})
+
+	assert.False(t, engine.runLogger(uuid.New()).Enabled(context.Background(), slog.LevelError))
+}
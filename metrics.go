@@ -0,0 +1,25 @@
+package main
+
+import "time"
+
+// MetricsSink receives the engine's metrics during evaluation, e.g. to expose them to Prometheus
+// through the prommetrics package. It is called once per batch and per rule, never per transaction,
+// from the goroutine running Evaluate, and must be safe for concurrent use across runs.
+type MetricsSink interface {
+	// ObserveRuleDuration records how long a rule's processor ran
+	ObserveRuleDuration(rule string, d time.Duration)
+	// IncFlagged counts the users a rule flagged, zero included
+	IncFlagged(rule string, n int)
+	// IncErrors counts a rule failing
+	IncErrors(rule string)
+	// ObserveBatchSize records the transactions of a run that reach the rules, after validation
+	ObserveBatchSize(n int)
+}
+
+// WithMetrics reports the engine's metrics to sink. Without it, no metrics are recorded and
+// evaluation pays nothing for them.
+func WithMetrics(sink MetricsSink) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.metrics = sink
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"aml_rule_engine/prommetrics"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ MetricsSink = (*prommetrics.Sink)(nil)
+
+// recordingMetrics records every call as a line such as "flagged VelocityProcessor 2"
+type recordingMetrics struct {
+	mu        sync.Mutex
+	calls     []string
+	durations map[string]time.Duration
+}
+
+func (m *recordingMetrics) record(format string, args ...any) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, fmt.Sprintf(format, args...))
+}
+
+func (m *recordingMetrics) ObserveRuleDuration(rule string, d time.Duration) {
+	m.record("duration %s", rule)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.durations == nil {
+		m.durations = make(map[string]time.Duration)
+	}
+	m.durations[rule] = d
+}
+
+func (m *recordingMetrics) IncFlagged(rule string, n int) { m.record("flagged %s %d", rule, n) }
+func (m *recordingMetrics) IncErrors(rule string)         { m.record("error %s", rule) }
+func (m *recordingMetrics) ObserveBatchSize(n int)        { m.record("batch %d", n) }
+
+func TestRuleEngine_Evaluate_Metrics(t *testing.T) {
+	baseTime := time.Now()
+	userID, rich := uuid.New(), uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, Country: "IR", CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID, Country: "IR", CreatedAt: baseTime},
+		{UserID: rich, Country: "FR", Amount: decimal.NewFromInt(20000), CreatedAt: baseTime},
+	}
+	metrics := &recordingMetrics{}
+	engine := NewRuleEngine([]RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 1)}, WithStrictSortedInput()),
+		NewCountryBlackListProcessor("IR"),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000)},
+	}, WithMetrics(metrics))
+
+	_, err := engine.Evaluate(context.Background(), transactions)
+	require.ErrorIs(t, err, ErrUnsortedTransactions)
+
+	assert.Equal(t, []string{
+		"batch 3",
+		"duration VelocityProcessor",
+		"flagged VelocityProcessor 0",
+		"error VelocityProcessor",
+		"duration CountryBlackListProcessor",
+		"flagged CountryBlackListProcessor 1",
+		"duration TransactionAmountProcessor",
+		"flagged TransactionAmountProcessor 1",
+	}, metrics.calls)
+	assert.Len(t, metrics.durations, 3)
+}
+
+func TestRuleEngine_Evaluate_MetricsAfterValidation(t *testing.T) {
+	policy := DefaultValidationPolicy()
+	policy.Action = ValidationDrop
+	metrics := &recordingMetrics{}
+	engine := NewRuleEngine(nil, WithValidation(policy), WithMetrics(metrics))
+
+	_, err := engine.Evaluate(context.Background(), []Transaction{
+		{UserID: uuid.New(), Amount: decimal.NewFromInt(10), CreatedAt: time.Now()},
+		{Amount: decimal.NewFromInt(10), CreatedAt: time.Now()},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"batch 1"}, metrics.calls, "dropped transactions are not counted")
+}
+
+func BenchmarkRuleEngine_Evaluate(b *testing.B) {
+	rules := []RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 20)}),
+		NewCountryBlackListProcessor("IR"),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(4000)},
+	}
+
+	transactions := NewTransactionGenerator(
+		WithUserCount(200),
+		WithTransactionsPerUser(FixedCount(50)),
+		WithAmounts(UniformAmount(decimal.Zero, decimal.NewFromInt(5000))),
+	).Generate().Transactions
+
+	benchmarks := []struct {
+		name string
+		opts []RuleEngineOption
+	}{
+		{name: "no metrics"},
+		{name: "prometheus", opts: []RuleEngineOption{WithMetrics(prommetrics.New("aml"))}},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			engine := NewRuleEngine(rules, bm.opts...)
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				engine.Evaluate(context.Background(), transactions)
+			}
+		})
+	}
+}
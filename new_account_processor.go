@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// NewAccountProcessor applies stricter limits to accounts younger than MinAge. Only transactions
+// made while the account was younger than MinAge count toward the limits.
+type NewAccountProcessor struct {
+	Profiles UserProfileProvider
+	MinAge   time.Duration
+	Window   time.Duration
+	// CountThreshold flags more than this many young-account transactions within Window, 0 disables it
+	CountThreshold int
+	// AmountThreshold flags a young-account sum above it within Window, zero disables it
+	AmountThreshold decimal.Decimal
+}
+
+func NewNewAccountProcessor(profiles UserProfileProvider, minAge, window time.Duration, countThreshold int, amountThreshold decimal.Decimal) NewAccountProcessor {
+	return NewAccountProcessor{
+		Profiles:        profiles,
+		MinAge:          minAge,
+		Window:          window,
+		CountThreshold:  countThreshold,
+		AmountThreshold: amountThreshold,
+	}
+}
+
+func (n NewAccountProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers, _ := n.ProcessContext(ctx, transactions)
+	return flaggedUsers
+}
+
+// ProcessContext is Process but reports profile lookup failures in UserErrors. Each user's profile
+// is looked up once per call; users whose lookup failed are skipped, the others are unaffected.
+func (n NewAccountProcessor) ProcessContext(ctx context.Context, transactions []Transaction) (map[uuid.UUID]struct{}, error) {
+	grouped := groupTransactions(transactions, velocityOptions{}, nil)
+
+	var userIDs []uuid.UUID
+	for _, userTransactions := range grouped {
+		for userID := range userTransactions {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	profiles, userErrors := loadProfiles(ctx, n.Profiles, userIDs)
+
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(n.Window, n.CountThreshold)}, velocityOptions{})
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range grouped {
+		for userID, txs := range userTransactions {
+			profile, ok := profiles[userID]
+			if !ok {
+				continue
+			}
+
+			young := txs[:0]
+			for _, tx := range txs {
+				if tx.CreatedAt.Sub(profile.CreatedAt) < n.MinAge {
+					young = append(young, tx)
+				}
+			}
+			sortByCreatedAt(young)
+
+			if n.exceedsLimits(checker, young) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	if len(userErrors) > 0 {
+		return flaggedUsers, userErrors
+	}
+
+	return flaggedUsers, nil
+}
+
+func (n NewAccountProcessor) exceedsLimits(checker velocityChecker, txs []Transaction) bool {
+	if n.CountThreshold > 0 {
+		if violated, _ := checker.CheckUser(txs); violated {
+			return true
+		}
+	}
+
+	return n.AmountThreshold.IsPositive() && exceedsWindowAmount(txs, n.Window, n.AmountThreshold, AmountPolicy{})
+}
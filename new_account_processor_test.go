@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingProfileProvider counts lookups per user and fails for the users in failing
+type countingProfileProvider struct {
+	profiles InMemoryProfileProvider
+	failing  map[uuid.UUID]struct{}
+	calls    map[uuid.UUID]int
+}
+
+func (p *countingProfileProvider) Profile(ctx context.Context, userID uuid.UUID) (UserProfile, error) {
+	p.calls[userID]++
+	if _, ok := p.failing[userID]; ok {
+		return UserProfile{}, errors.New("profile service unavailable")
+	}
+
+	return p.profiles.Profile(ctx, userID)
+}
+
+func TestNewAccountProcessor_ProcessContext(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	young, established, aging, failing, unknown := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	provider := &countingProfileProvider{
+		profiles: InMemoryProfileProvider{
+			young:       {CreatedAt: baseTime.Add(-5 * 24 * time.Hour), RiskTier: RiskTierMedium},
+			established: {CreatedAt: baseTime.Add(-year), RiskTier: RiskTierLow},
+			// 28 days old at the first transaction, 31 by the last
+			aging:   {CreatedAt: baseTime.Add(-28 * 24 * time.Hour)},
+			failing: {CreatedAt: baseTime.Add(-5 * 24 * time.Hour)},
+		},
+		failing: map[uuid.UUID]struct{}{failing: {}},
+		calls:   make(map[uuid.UUID]int),
+	}
+
+	var transactions []Transaction
+	for _, userID := range []uuid.UUID{young, established, aging, failing, unknown} {
+		for day := 0; day < 4; day++ {
+			transactions = append(transactions, Transaction{UserID: userID, Amount: decimal.NewFromInt(500), CreatedAt: baseTime.Add(time.Duration(day) * 24 * time.Hour)})
+		}
+	}
+
+	processor := NewNewAccountProcessor(provider, 30*24*time.Hour, week, 3, decimal.Zero)
+
+	flaggedUsers, err := processor.ProcessContext(context.Background(), transactions)
+
+	assert.Equal(t, map[uuid.UUID]struct{}{young: {}}, flaggedUsers)
+
+	var userErrors UserErrors
+	require.ErrorAs(t, err, &userErrors)
+	assert.Len(t, userErrors, 2)
+	assert.ErrorIs(t, userErrors[unknown], ErrProfileNotFound)
+	assert.Contains(t, userErrors, failing)
+
+	for userID, calls := range provider.calls {
+		assert.Equal(t, 1, calls, "user %s looked up once per batch", userID)
+	}
+}
+
+func TestNewAccountProcessor_Process_AmountThreshold(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	provider := InMemoryProfileProvider{userID: {CreatedAt: baseTime}}
+
+	transactions := []Transaction{
+		{UserID: userID, Amount: decimal.NewFromInt(3000), CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID, Amount: decimal.NewFromInt(3000), CreatedAt: baseTime.Add(2 * time.Hour)},
+	}
+
+	strict := NewNewAccountProcessor(provider, 30*24*time.Hour, week, 0, decimal.NewFromInt(5000))
+	lenient := NewNewAccountProcessor(provider, 30*24*time.Hour, week, 0, decimal.NewFromInt(10000))
+
+	assert.Contains(t, strict.Process(context.Background(), append([]Transaction(nil), transactions...)), userID)
+	assert.Empty(t, lenient.Process(context.Background(), append([]Transaction(nil), transactions...)))
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// NewCountryProcessor flags users who, after an established baseline history, transact in a country
+// they never used during that baseline
+type NewCountryProcessor struct {
+	// BaselineTransactions includes a user's first N transactions in the baseline, 0 disables it
+	BaselineTransactions int
+	// BaselinePeriod includes transactions within this duration of the user's first one, 0 disables it.
+	// When both are set the baseline extends to whichever covers more transactions. When neither is,
+	// there is no baseline to compare against and the rule flags nobody.
+	BaselinePeriod time.Duration
+	// MinAmount only flags new-country transactions of at least this amount, zero flags any
+	MinAmount decimal.Decimal
+}
+
+func NewNewCountryProcessor(baselineTransactions int, baselinePeriod time.Duration, minAmount decimal.Decimal) NewCountryProcessor {
+	return NewCountryProcessor{
+		BaselineTransactions: baselineTransactions,
+		BaselinePeriod:       baselinePeriod,
+		MinAmount:            minAmount,
+	}
+}
+
+func (n NewCountryProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	if n.BaselineTransactions <= 0 && n.BaselinePeriod <= 0 {
+		return flaggedUsers
+	}
+
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return normalizeCountry(tx.Country) != ""
+	}}
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			sortByCreatedAt(txs)
+
+			if n.introducesNewCountry(txs) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+func (n NewCountryProcessor) inBaseline(txs []Transaction, i int) bool {
+	return (n.BaselineTransactions > 0 && i < n.BaselineTransactions) ||
+		(n.BaselinePeriod > 0 && txs[i].CreatedAt.Sub(txs[0].CreatedAt) <= n.BaselinePeriod)
+}
+
+func (n NewCountryProcessor) introducesNewCountry(txs []Transaction) bool {
+	baseline := make(map[string]struct{})
+
+	i := 0
+	for ; i < len(txs) && n.inBaseline(txs, i); i++ {
+		baseline[normalizeCountry(txs[i].Country)] = struct{}{}
+	}
+
+	for ; i < len(txs); i++ {
+		if _, known := baseline[normalizeCountry(txs[i].Country)]; !known && txs[i].Amount.GreaterThanOrEqual(n.MinAmount) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCountryProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	tx := func(days int, country string, amount int64) Transaction {
+		return Transaction{
+			UserID:    userID,
+			Country:   country,
+			Amount:    decimal.NewFromInt(amount),
+			CreatedAt: baseTime.Add(time.Duration(days) * 24 * time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		processor    NewCountryProcessor
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "new country on day 2 inside the baseline",
+			processor:    NewNewCountryProcessor(0, 30*24*time.Hour, decimal.Zero),
+			transactions: []Transaction{tx(0, "FR", 100), tx(2, "DE", 100), tx(40, "DE", 100), tx(45, "fr", 100)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "new country on day 60",
+			processor:    NewNewCountryProcessor(0, 30*24*time.Hour, decimal.Zero),
+			transactions: []Transaction{tx(60, "AE", 100), tx(0, "FR", 100), tx(2, "DE", 100)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "history entirely inside the baseline",
+			processor:    NewNewCountryProcessor(0, 30*24*time.Hour, decimal.Zero),
+			transactions: []Transaction{tx(0, "FR", 100), tx(10, "DE", 100), tx(29, "AE", 100)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "baseline by transaction count",
+			processor:    NewNewCountryProcessor(2, 0, decimal.Zero),
+			transactions: []Transaction{tx(0, "FR", 100), tx(1, "DE", 100), tx(2, "AE", 100)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "new country below the minimum amount",
+			processor:    NewNewCountryProcessor(0, 30*24*time.Hour, decimal.NewFromInt(1000)),
+			transactions: []Transaction{tx(0, "FR", 100), tx(60, "AE", 999)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "new country at the minimum amount",
+			processor:    NewNewCountryProcessor(0, 30*24*time.Hour, decimal.NewFromInt(1000)),
+			transactions: []Transaction{tx(0, "FR", 100), tx(60, "AE", 1000)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "transactions without a country are ignored",
+			processor:    NewNewCountryProcessor(0, 30*24*time.Hour, decimal.Zero),
+			transactions: []Transaction{tx(0, "FR", 100), tx(60, "", 100)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "no baseline configured disables the rule",
+			processor:    NewNewCountryProcessor(0, 0, decimal.Zero),
+			transactions: []Transaction{tx(0, "FR", 100), tx(60, "AE", 100)},
+			wantFlagged:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := tt.processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Calendar tells business days from weekends and holidays
+type Calendar interface {
+	IsBusinessDay(time.Time) bool
+}
+
+// HolidayCalendar treats Saturdays, Sundays and the configured holidays as non-business days,
+// judging each time by its calendar date in Location
+type HolidayCalendar struct {
+	Location *time.Location
+
+	holidays map[civilDate]struct{}
+}
+
+// civilDate is a calendar date, as read on a clock in some location
+type civilDate struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+// calendarLocation is location, UTC when nil
+func calendarLocation(location *time.Location) *time.Location {
+	if location == nil {
+		return time.UTC
+	}
+
+	return location
+}
+
+// localDate is the calendar date of t in location, UTC when nil. Dates come from the local clock,
+// not from fixed 24h steps, so the 23 and 25 hour days of DST shifts hold what their clock shows.
+func localDate(t time.Time, location *time.Location) civilDate {
+	year, month, day := t.In(calendarLocation(location)).Date()
+	return civilDate{year: year, month: month, day: day}
+}
+
+// NewHolidayCalendar takes holidays by their calendar date as given, whatever their location.
+// A nil location means UTC.
+func NewHolidayCalendar(location *time.Location, holidays ...time.Time) HolidayCalendar {
+	location = calendarLocation(location)
+
+	c := HolidayCalendar{Location: location, holidays: make(map[civilDate]struct{}, len(holidays))}
+	for _, holiday := range holidays {
+		year, month, day := holiday.Date()
+		c.holidays[civilDate{year: year, month: month, day: day}] = struct{}{}
+	}
+
+	return c
+}
+
+func (c HolidayCalendar) IsBusinessDay(t time.Time) bool {
+	if weekday := t.In(calendarLocation(c.Location)).Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+		return false
+	}
+
+	_, holiday := c.holidays[localDate(t, c.Location)]
+
+	return !holiday
+}
+
+// NonBusinessDayProcessor flags users transacting heavily on weekends and holidays
+type NonBusinessDayProcessor struct {
+	Calendar Calendar
+	Window   time.Duration
+	// CountThreshold flags more than this many non-business-day transactions within Window, 0 disables it
+	CountThreshold int
+	// AmountThreshold flags a non-business-day sum above it within Window, zero disables it
+	AmountThreshold decimal.Decimal
+}
+
+func NewNonBusinessDayProcessor(calendar Calendar, window time.Duration, countThreshold int, amountThreshold decimal.Decimal) NonBusinessDayProcessor {
+	return NonBusinessDayProcessor{
+		Calendar:        calendar,
+		Window:          window,
+		CountThreshold:  countThreshold,
+		AmountThreshold: amountThreshold,
+	}
+}
+
+func (n NonBusinessDayProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return !n.Calendar.IsBusinessDay(tx.CreatedAt)
+	}}
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(n.Window, n.CountThreshold)}, options)
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			// CheckUser sorts txs in place, which the amount check relies on as well
+			if n.CountThreshold > 0 {
+				if violated, _ := checker.CheckUser(txs); violated {
+					flaggedUsers[userID] = struct{}{}
+					continue
+				}
+			} else {
+				sortByCreatedAt(txs)
+			}
+
+			if n.AmountThreshold.IsPositive() && exceedsWindowAmount(txs, n.Window, n.AmountThreshold, AmountPolicy{}) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHolidayCalendar_IsBusinessDay(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	calendar := NewHolidayCalendar(paris, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"friday afternoon", time.Date(2024, 3, 1, 15, 0, 0, 0, time.UTC), true},
+		{"friday 23:30 UTC is saturday in Paris", time.Date(2024, 3, 1, 23, 30, 0, 0, time.UTC), false},
+		{"sunday 23:30 UTC is monday in Paris", time.Date(2024, 3, 3, 23, 30, 0, 0, time.UTC), true},
+		{"new year's eve 22:59 UTC is still december 31st in Paris", time.Date(2024, 12, 31, 22, 59, 0, 0, time.UTC), true},
+		{"new year's eve 23:00 UTC is january 1st in Paris", time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC), false},
+		{"january 1st 23:30 UTC is january 2nd in Paris", time.Date(2025, 1, 1, 23, 30, 0, 0, time.UTC), true},
+		{"holidays are yearly dates, not recurring", time.Date(2026, 1, 1, 12, 0, 0, 0, paris), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, calendar.IsBusinessDay(tt.t))
+		})
+	}
+}
+
+func TestHolidayCalendar_IsBusinessDay_DST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	// 2024-10-27 is a Sunday whose clocks go back at 03:00 CEST, and 2024-11-01 a holiday
+	calendar := NewHolidayCalendar(paris, time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC))
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"sunday 01:30 CEST", time.Date(2024, 10, 26, 23, 30, 0, 0, time.UTC), false},
+		{"sunday second 02:30, CET", time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC), false},
+		{"sunday 23:30 CET, past a CEST midnight", time.Date(2024, 10, 27, 22, 30, 0, 0, time.UTC), false},
+		{"monday 00:30 CET", time.Date(2024, 10, 27, 23, 30, 0, 0, time.UTC), true},
+		{"the holiday's 23:30 CET", time.Date(2024, 11, 1, 22, 30, 0, 0, time.UTC), false},
+		{"saturday 00:30 CET after the holiday", time.Date(2024, 11, 1, 23, 30, 0, 0, time.UTC), false},
+		{"monday 03:30 CEST after the spring jump", time.Date(2024, 4, 1, 1, 30, 0, 0, time.UTC), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, calendar.IsBusinessDay(tt.t))
+		})
+	}
+}
+
+func TestNonBusinessDayProcessor_Process(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	calendar := NewHolidayCalendar(paris, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC))
+	userID := uuid.New()
+
+	// New Year's Eve 2024 is a Tuesday, local midnight in Paris is 23:00 UTC
+	newYear := time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC)
+	tx := func(offset time.Duration, amount int64) Transaction {
+		return Transaction{UserID: userID, Amount: decimal.NewFromInt(amount), CreatedAt: newYear.Add(offset)}
+	}
+
+	tests := []struct {
+		name            string
+		countThreshold  int
+		amountThreshold decimal.Decimal
+		transactions    []Transaction
+		wantFlagged     bool
+	}{
+		{
+			name:           "batch straddling local midnight, only the holiday side counts",
+			countThreshold: 2,
+			transactions:   []Transaction{tx(-30*time.Minute, 10), tx(-10*time.Minute, 10), tx(10*time.Minute, 10), tx(20*time.Minute, 10)},
+			wantFlagged:    false,
+		},
+		{
+			name:           "three transactions after local midnight",
+			countThreshold: 2,
+			transactions:   []Transaction{tx(-30*time.Minute, 10), tx(10*time.Minute, 10), tx(20*time.Minute, 10), tx(30*time.Minute, 10)},
+			wantFlagged:    true,
+		},
+		{
+			name:            "holiday amount above the threshold",
+			amountThreshold: decimal.NewFromInt(15000),
+			transactions:    []Transaction{tx(-time.Minute, 50000), tx(time.Hour, 8000), tx(2*time.Hour, 8000)},
+			wantFlagged:     true,
+		},
+		{
+			name:            "business-day amount does not count",
+			amountThreshold: decimal.NewFromInt(15000),
+			transactions:    []Transaction{tx(-time.Minute, 50000), tx(time.Hour, 8000)},
+			wantFlagged:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewNonBusinessDayProcessor(calendar, week, tt.countThreshold, tt.amountThreshold)
+
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
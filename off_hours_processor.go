@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OffHoursProcessor flags users with at least MinCount transactions inside a daily quiet-hours range
+// within Window. Start and End are local clock times as offsets from midnight; Start is inclusive, End
+// exclusive, and the range wraps midnight when Start is after End (e.g. 23:00-05:00). Equal bounds
+// wrap all the way round and cover the whole day.
+type OffHoursProcessor struct {
+	Start time.Duration
+	End   time.Duration
+	// Location is the clock Start and End are read on, UTC when nil
+	Location *time.Location
+	MinCount int
+	Window   time.Duration
+}
+
+func NewOffHoursProcessor(start, end time.Duration, location *time.Location, minCount int, window time.Duration) OffHoursProcessor {
+	return OffHoursProcessor{
+		Start:    start,
+		End:      end,
+		Location: location,
+		MinCount: minCount,
+		Window:   window,
+	}
+}
+
+// inRange reports whether the local wall-clock time of t falls inside the quiet hours. Wall-clock
+// time is used so DST shifts move the range along with local midnight.
+func (o OffHoursProcessor) inRange(t time.Time) bool {
+	local := t.In(calendarLocation(o.Location))
+	clock := time.Duration(local.Hour())*time.Hour +
+		time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second +
+		time.Duration(local.Nanosecond())
+
+	if o.Start == o.End {
+		return true
+	}
+	if o.Start < o.End {
+		return clock >= o.Start && clock < o.End
+	}
+
+	return clock >= o.Start || clock < o.End
+}
+
+func (o OffHoursProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return o.inRange(tx.CreatedAt)
+	}}
+	// "at least MinCount" is a velocity period flagging more than MinCount-1
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(o.Window, o.MinCount-1)}, options)
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			if violated, _ := checker.CheckUser(txs); violated {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
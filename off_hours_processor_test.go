@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOffHoursProcessor_InRange(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	wrapping := NewOffHoursProcessor(23*time.Hour, 5*time.Hour, paris, 1, week)
+	daytime := NewOffHoursProcessor(1*time.Hour, 5*time.Hour, paris, 1, week)
+	allDay := NewOffHoursProcessor(6*time.Hour, 6*time.Hour, paris, 1, week)
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 3, day, hour, minute, 0, 0, paris)
+	}
+
+	tests := []struct {
+		name      string
+		processor OffHoursProcessor
+		t         time.Time
+		want      bool
+	}{
+		{"23:30 in a wrapping range", wrapping, at(1, 23, 30), true},
+		{"02:00 in a wrapping range", wrapping, at(1, 2, 0), true},
+		{"12:00 outside a wrapping range", wrapping, at(1, 12, 0), false},
+		{"exactly at start", wrapping, at(1, 23, 0), true},
+		{"exactly at end", wrapping, at(1, 5, 0), false},
+		{"just before end", wrapping, at(1, 4, 59), true},
+		{"just before start", wrapping, at(1, 22, 59), false},
+		{"exactly at start of a daytime range", daytime, at(1, 1, 0), true},
+		{"exactly at end of a daytime range", daytime, at(1, 5, 0), false},
+		{"equal bounds cover the start", allDay, at(1, 6, 0), true},
+		{"equal bounds cover the whole day", allDay, at(1, 5, 59), true},
+		{"local time, not UTC", daytime, time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC), true},
+		// On 2024-03-31 Paris clocks jump from 02:00 to 03:00: 00:30 UTC is 01:30 CET, 01:30 UTC is 03:30 CEST
+		{"before the spring DST jump", daytime, time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC), true},
+		{"after the spring DST jump", daytime, time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), true},
+		{"05:00 CEST on the DST day", daytime, time.Date(2024, 3, 31, 3, 0, 0, 0, time.UTC), false},
+		// On 2024-10-27 clocks go back from 03:00 to 02:00: 04:30 UTC is 05:30 CET
+		{"after the autumn DST fallback", daytime, time.Date(2024, 10, 27, 3, 30, 0, 0, time.UTC), true},
+		{"end of range after the fallback", daytime, time.Date(2024, 10, 27, 4, 30, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.processor.inRange(tt.t))
+		})
+	}
+}
+
+func TestOffHoursProcessor_InRange_DST(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+	// quiet hours from 02:00 to 03:00 local, a range the spring jump skips and the autumn fallback repeats
+	processor := NewOffHoursProcessor(2*time.Hour, 3*time.Hour, paris, 1, week)
+
+	tests := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"spring 01:30 CET", time.Date(2024, 3, 31, 0, 30, 0, 0, time.UTC), false},
+		{"spring 02:30 does not exist and reads 03:30 CEST", time.Date(2024, 3, 31, 2, 30, 0, 0, paris), false},
+		{"spring 03:30 CEST", time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), false},
+		{"autumn 01:30 CEST", time.Date(2024, 10, 26, 23, 30, 0, 0, time.UTC), false},
+		{"autumn first 02:30, CEST", time.Date(2024, 10, 27, 0, 30, 0, 0, time.UTC), true},
+		{"autumn second 02:30, CET", time.Date(2024, 10, 27, 1, 30, 0, 0, time.UTC), true},
+		{"autumn 03:30 CET", time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, processor.inRange(tt.t))
+		})
+	}
+
+	assert.True(t, OffHoursProcessor{Start: 2 * time.Hour, End: 3 * time.Hour}.inRange(time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC)), "nil location is UTC")
+}
+
+func TestOffHoursProcessor_Process(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	nightUser := uuid.New()
+	dayUser := uuid.New()
+	spreadUser := uuid.New()
+
+	at := func(userID uuid.UUID, day, hour int) Transaction {
+		return Transaction{UserID: userID, CreatedAt: time.Date(2024, 3, day, hour, 30, 0, 0, paris)}
+	}
+
+	transactions := []Transaction{
+		at(nightUser, 1, 23), at(nightUser, 2, 2), at(nightUser, 3, 4),
+		at(dayUser, 1, 12), at(dayUser, 2, 14), at(dayUser, 3, 2),
+		at(spreadUser, 1, 2), at(spreadUser, 10, 2), at(spreadUser, 20, 2),
+	}
+
+	processor := NewOffHoursProcessor(23*time.Hour, 5*time.Hour, paris, 3, week)
+
+	assert.Equal(t, map[uuid.UUID]struct{}{nightUser: {}}, processor.Process(context.Background(), transactions))
+}
@@ -0,0 +1,62 @@
+// Package oteltrace traces a rule engine with OpenTelemetry. A Tracer is passed to the engine with
+// WithTracer; its method only takes standard types, so the engine does not depend on OpenTelemetry.
+package oteltrace
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracer starts the engine's spans with an OpenTelemetry tracer, a failed span being recorded
+// with the error and an Error status
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New creates a Tracer starting its spans with tracer, e.g. otel.Tracer("aml_rule_engine")
+func New(tracer trace.Tracer) Tracer {
+	return Tracer{tracer: tracer}
+}
+
+// Start begins a span named name as a child of the span in ctx
+func (t Tracer) Start(ctx context.Context, name string) (context.Context, func(attributes map[string]any, err error)) {
+	ctx, span := t.tracer.Start(ctx, name)
+
+	return ctx, func(attributes map[string]any, err error) {
+		span.SetAttributes(toAttributes(attributes)...)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// toAttributes converts attributes in key order, values of other types being formatted as strings
+func toAttributes(attributes map[string]any) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attributes))
+	for _, key := range slices.Sorted(maps.Keys(attributes)) {
+		switch value := attributes[key].(type) {
+		case string:
+			kvs = append(kvs, attribute.String(key, value))
+		case int:
+			kvs = append(kvs, attribute.Int(key, value))
+		case int64:
+			kvs = append(kvs, attribute.Int64(key, value))
+		case bool:
+			kvs = append(kvs, attribute.Bool(key, value))
+		case float64:
+			kvs = append(kvs, attribute.Float64(key, value))
+		default:
+			kvs = append(kvs, attribute.String(key, fmt.Sprint(value)))
+		}
+	}
+
+	return kvs
+}
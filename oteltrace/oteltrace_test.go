@@ -0,0 +1,45 @@
+package oteltrace
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer_Start(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := New(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test"))
+
+	ctx, endParent := tracer.Start(context.Background(), "parent")
+	_, endChild := tracer.Start(ctx, "child")
+	endChild(map[string]any{"rule": "VelocityProcessor", "flagged": 2, "strict": true, "ratio": 0.5, "level": struct{}{}},
+		errors.New("out of order"))
+	endParent(nil, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	child, parent := spans[0], spans[1]
+
+	assert.Equal(t, "child", child.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), child.Parent().SpanID())
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.Int("flagged", 2),
+		attribute.String("level", "{}"),
+		attribute.Float64("ratio", 0.5),
+		attribute.String("rule", "VelocityProcessor"),
+		attribute.Bool("strict", true),
+	}, child.Attributes())
+	assert.Equal(t, codes.Error, child.Status().Code)
+	assert.Equal(t, "out of order", child.Status().Description)
+	require.Len(t, child.Events(), 1, "the error is recorded as an event")
+
+	assert.Equal(t, codes.Unset, parent.Status().Code)
+	assert.Empty(t, parent.Attributes())
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+)
+
+// parallelSortThreshold is the number of transactions from which sortByCreatedAt sorts keys in parallel
+const parallelSortThreshold = 1 << 15
+
+// createdAtKey is the sort key of the transaction at index
+type createdAtKey struct {
+	sec   int64
+	nsec  int32
+	index int
+}
+
+func compareCreatedAtKeys(a, b createdAtKey) int {
+	if c := cmp.Compare(a.sec, b.sec); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(a.nsec, b.nsec); c != 0 {
+		return c
+	}
+
+	return cmp.Compare(a.index, b.index)
+}
+
+// sortLargeByCreatedAt sorts txs by CreatedAt, keeping transactions sharing a timestamp in their
+// order. It sorts small keys rather than the transactions themselves with sortCreatedAtKeys, and
+// the transactions are then moved once into place.
+func sortLargeByCreatedAt(txs []Transaction, workers int) {
+	keys := make([]createdAtKey, len(txs))
+	for i, tx := range txs {
+		keys[i] = createdAtKey{sec: tx.CreatedAt.Unix(), nsec: int32(tx.CreatedAt.Nanosecond()), index: i}
+	}
+
+	permuteTransactions(txs, sortCreatedAtKeys(keys, workers))
+}
+
+// sortLargeIndexByCreatedAt is sortIndexByCreatedAt sorting keys with sortCreatedAtKeys
+func sortLargeIndexByCreatedAt(batch []Transaction, index []int32, workers int) {
+	keys := make([]createdAtKey, len(index))
+	for i, at := range index {
+		createdAt := batch[at].CreatedAt
+		keys[i] = createdAtKey{sec: createdAt.Unix(), nsec: int32(createdAt.Nanosecond()), index: int(at)}
+	}
+
+	for i, key := range sortCreatedAtKeys(keys, workers) {
+		index[i] = int32(key.index)
+	}
+}
+
+// sortCreatedAtKeys splits keys in up to workers runs sorted concurrently and merges the runs
+// pairwise, each level of merges running concurrently. It returns the sorted keys, which are keys
+// or a buffer as long.
+func sortCreatedAtKeys(keys []createdAtKey, workers int) []createdAtKey {
+	run := max((len(keys)+max(workers, 1)-1)/max(workers, 1), 1)
+	var wg sync.WaitGroup
+	for lo := 0; lo < len(keys); lo += run {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slices.SortFunc(keys[lo:min(lo+run, len(keys))], compareCreatedAtKeys)
+		}()
+	}
+	wg.Wait()
+
+	src, dst := keys, make([]createdAtKey, len(keys))
+	for width := run; width < len(src); width *= 2 {
+		for lo := 0; lo < len(src); lo += 2 * width {
+			mid, hi := min(lo+width, len(src)), min(lo+2*width, len(src))
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				mergeCreatedAtKeys(dst[lo:hi], src[lo:mid], src[mid:hi])
+			}()
+		}
+		wg.Wait()
+		src, dst = dst, src
+	}
+
+	return src
+}
+
+// mergeCreatedAtKeys merges the sorted a and b into dst, which is as long as both
+func mergeCreatedAtKeys(dst, a, b []createdAtKey) {
+	i, j := 0, 0
+	for k := range dst {
+		if j == len(b) || (i < len(a) && compareCreatedAtKeys(a[i], b[j]) <= 0) {
+			dst[k] = a[i]
+			i++
+		} else {
+			dst[k] = b[j]
+			j++
+		}
+	}
+}
+
+// permuteTransactions moves txs[keys[i].index] to i for every i, following each cycle of the
+// permutation so every transaction is copied once. It consumes keys.
+func permuteTransactions(txs []Transaction, keys []createdAtKey) {
+	for start := range keys {
+		if keys[start].index == start {
+			continue
+		}
+
+		moved := txs[start]
+		at := start
+		for {
+			from := keys[at].index
+			keys[at].index = at
+			if from == start {
+				txs[at] = moved
+				break
+			}
+			txs[at] = txs[from]
+			at = from
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// heavyUserTransactions is the transaction count of the single user of BenchmarkSortByCreatedAt_HeavyUser
+var heavyUserTransactions = flag.Int("heavy-user-transactions", 5_000_000, "transactions of the heavy user in the sort benchmark")
+
+// shuffledUserTransactions returns n transactions of one user in random order, many sharing a timestamp
+func shuffledUserTransactions(seed uint64, n int) []Transaction {
+	rng := rand.New(rand.NewPCG(seed, seed))
+	userID := uuid.New()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	txs := make([]Transaction, n)
+	for i := range txs {
+		txs[i] = Transaction{
+			TransactionID: uuid.New(),
+			UserID:        userID,
+			CreatedAt:     base.Add(time.Duration(rng.IntN(max(n/4, 1))) * time.Second).Add(time.Duration(rng.IntN(3))),
+		}
+	}
+
+	return txs
+}
+
+func TestSortLargeByCreatedAt(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 7, 100, 1001} {
+		for _, workers := range []int{0, 1, 2, 3, 8, 2000} {
+			txs := shuffledUserTransactions(uint64(n), n)
+			want := slices.Clone(txs)
+			slices.SortStableFunc(want, func(a, b Transaction) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+			sortLargeByCreatedAt(txs, workers)
+			assert.Equal(t, want, txs, "%d transactions, %d workers", n, workers)
+		}
+	}
+}
+
+func TestSortByCreatedAt_AboveThreshold(t *testing.T) {
+	txs := shuffledUserTransactions(3, parallelSortThreshold+17)
+	want := slices.Clone(txs)
+	slices.SortStableFunc(want, func(a, b Transaction) int { return a.CreatedAt.Compare(b.CreatedAt) })
+
+	sortByCreatedAt(txs)
+	assert.Equal(t, want, txs, "large slices keep ties in their order")
+}
+
+func TestSortLargeIndexByCreatedAt(t *testing.T) {
+	batch := shuffledUserTransactions(9, 1001)
+	index := make([]int32, 0, len(batch))
+	for i := len(batch) - 1; i >= 0; i -= 2 {
+		index = append(index, int32(i))
+	}
+	slices.Reverse(index)
+
+	want := slices.Clone(index)
+	slices.SortStableFunc(want, func(a, b int32) int { return batch[a].CreatedAt.Compare(batch[b].CreatedAt) })
+
+	for _, workers := range []int{1, 3, 8} {
+		got := slices.Clone(index)
+		sortLargeIndexByCreatedAt(batch, got, workers)
+		assert.Equal(t, want, got, "%d workers", workers)
+	}
+}
+
+func TestVelocityProcessors_HeavyUser(t *testing.T) {
+	heavy := shuffledUserTransactions(5, parallelSortThreshold*2)
+	transactions := append(slices.Clone(heavy), NewTransactionGenerator(WithUserCount(50)).Generate().Transactions...)
+	input := slices.Clone(transactions)
+
+	periods := []VelocityPeriod{NewVelocityPeriod(time.Second, 9), NewVelocityPeriod(24*time.Hour, 1_000_000)}
+	sorted := slices.Clone(heavy)
+	slices.SortFunc(sorted, func(a, b Transaction) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	violated, _ := velocityChecker{periods: periods}.scan(sorted, true)
+	require.True(t, violated)
+
+	for name, processor := range velocityImplementations(periods) {
+		flagged := processor.Process(context.Background(), transactions)
+		assert.Contains(t, flagged, heavy[0].UserID, name)
+		assert.Equal(t, input, transactions, "%s leaves the caller's slice as it was", name)
+	}
+}
+
+// BenchmarkSortByCreatedAt_HeavyUser sorts the transactions of a single user of -heavy-user-transactions
+func BenchmarkSortByCreatedAt_HeavyUser(b *testing.B) {
+	shuffled := shuffledUserTransactions(7, *heavyUserTransactions)
+	txs := make([]Transaction, len(shuffled))
+
+	for _, bm := range []struct {
+		name string
+		sort func([]Transaction)
+	}{
+		{name: "SortFunc", sort: func(txs []Transaction) {
+			slices.SortFunc(txs, func(a, b Transaction) int { return a.CreatedAt.Compare(b.CreatedAt) })
+		}},
+		{name: "Keys", sort: sortByCreatedAt},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for b.Loop() {
+				b.StopTimer()
+				copy(txs, shuffled)
+				b.StartTimer()
+				bm.sort(txs)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PassThroughProcessor flags users whose incoming funds leave again shortly after arriving,
+// a layering indicator. Inflows are credits, outflows debits; other directions are ignored.
+type PassThroughProcessor struct {
+	Window time.Duration
+	// Ratio flags users whose outflows matched to prior inflows within Window reach this share of inflows
+	Ratio decimal.Decimal
+}
+
+func NewPassThroughProcessor(window time.Duration, ratio decimal.Decimal) PassThroughProcessor {
+	return PassThroughProcessor{
+		Window: window,
+		Ratio:  ratio,
+	}
+}
+
+func (p PassThroughProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return tx.Direction == DirectionCredit || tx.Direction == DirectionDebit
+	}}
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			sortByCreatedAt(txs)
+
+			inflow, matched := p.matchOutflows(txs)
+			if inflow.IsPositive() && matched.Div(inflow).GreaterThanOrEqual(p.Ratio) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// pendingCredit is the part of an inflow not yet matched by an outflow
+type pendingCredit struct {
+	createdAt time.Time
+	remaining decimal.Decimal
+}
+
+// matchOutflows pairs each debit with the oldest unmatched credits received within Window before it
+// and returns the total inflow and the amount of it that was passed through
+func (p PassThroughProcessor) matchOutflows(txs []Transaction) (inflow, matched decimal.Decimal) {
+	var pending []pendingCredit
+
+	for _, tx := range txs {
+		if tx.Direction == DirectionCredit {
+			inflow = inflow.Add(spendAmount(tx))
+			pending = append(pending, pendingCredit{createdAt: tx.CreatedAt, remaining: spendAmount(tx)})
+			continue
+		}
+
+		// Credits older than the window can no longer be matched
+		for len(pending) > 0 && tx.CreatedAt.Sub(pending[0].createdAt) > p.Window {
+			pending = pending[1:]
+		}
+
+		outflow := spendAmount(tx)
+		for len(pending) > 0 && outflow.IsPositive() {
+			used := decimal.Min(outflow, pending[0].remaining)
+			matched = matched.Add(used)
+			outflow = outflow.Sub(used)
+			pending[0].remaining = pending[0].remaining.Sub(used)
+
+			if !pending[0].remaining.IsPositive() {
+				pending = pending[1:]
+			}
+		}
+	}
+
+	return inflow, matched
+}
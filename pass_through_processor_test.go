@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPassThroughProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	day := 24 * time.Hour
+
+	credit := func(days int, amount int64) Transaction {
+		return Transaction{UserID: userID, Direction: DirectionCredit, Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(time.Duration(days) * day)}
+	}
+	debit := func(days int, amount int64) Transaction {
+		return Transaction{UserID: userID, Direction: DirectionDebit, Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(time.Duration(days) * day)}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "deposit followed by an equal withdrawal the next day",
+			transactions: []Transaction{credit(0, 5000), debit(1, 5000)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "deposit followed by a withdrawal three weeks later",
+			transactions: []Transaction{credit(0, 5000), debit(21, 5000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "outflow below the ratio",
+			transactions: []Transaction{credit(0, 5000), debit(1, 3000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "several deposits drained by one withdrawal",
+			transactions: []Transaction{credit(0, 2000), credit(1, 2000), debit(2, 3600), credit(10, 500)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "withdrawal before the deposit is not matched",
+			transactions: []Transaction{debit(0, 5000), credit(1, 5000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "all credits",
+			transactions: []Transaction{credit(0, 5000), credit(1, 5000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "all debits",
+			transactions: []Transaction{debit(0, 5000), debit(1, 5000)},
+			wantFlagged:  false,
+		},
+		{
+			name: "unknown direction is ignored",
+			transactions: []Transaction{
+				credit(0, 5000),
+				{UserID: userID, Amount: decimal.NewFromInt(5000), CreatedAt: baseTime.Add(day)},
+			},
+			wantFlagged: false,
+		},
+	}
+
+	processor := NewPassThroughProcessor(72*time.Hour, decimal.RequireFromString("0.8"))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
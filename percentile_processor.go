@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"math"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// PercentileProcessor flags users with a transaction above a batch-wide amount percentile, e.g. the
+// 99.5th, catching outliers relative to the whole population rather than to the user's own history
+type PercentileProcessor struct {
+	// Percentile is a fraction in (0, 1], e.g. 0.995
+	Percentile float64
+	// MinBatchSize skips batches too small for the percentile to be meaningful
+	MinBatchSize int
+}
+
+func NewPercentileProcessor(percentile float64, minBatchSize int) PercentileProcessor {
+	return PercentileProcessor{
+		Percentile:   percentile,
+		MinBatchSize: minBatchSize,
+	}
+}
+
+func (p PercentileProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	threshold, ok := p.Threshold(transactions)
+	if !ok {
+		return flaggedUsers
+	}
+
+	for _, tx := range transactions {
+		if tx.Amount.GreaterThan(threshold) {
+			flaggedUsers[tx.UserID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// CrossUser is true, a user being flagged on the batch-wide amount percentile
+func (p PercentileProcessor) CrossUser() bool {
+	return true
+}
+
+// Threshold returns the Percentile of the batch amounts using the nearest-rank method, so it is
+// always one of the amounts. It reports false for batches smaller than MinBatchSize.
+// Time complexity: O(n log n), sorting a copy of the amounts
+func (p PercentileProcessor) Threshold(transactions []Transaction) (decimal.Decimal, bool) {
+	if len(transactions) == 0 || len(transactions) < p.MinBatchSize {
+		return decimal.Decimal{}, false
+	}
+
+	amounts := make([]decimal.Decimal, len(transactions))
+	for i, tx := range transactions {
+		amounts[i] = tx.Amount
+	}
+	slices.SortFunc(amounts, decimal.Decimal.Cmp)
+
+	rank := int(math.Ceil(p.Percentile * float64(len(amounts))))
+	rank = min(max(rank, 1), len(amounts))
+
+	return amounts[rank-1], true
+}
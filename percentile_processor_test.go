@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentileProcessor_Process(t *testing.T) {
+	users := make([]uuid.UUID, 10)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+	whale := uuid.New()
+
+	// amounts 1..999 spread over ten users, plus one planted whale
+	var transactions []Transaction
+	for amount := 1; amount < 1000; amount++ {
+		transactions = append(transactions, Transaction{UserID: users[amount%len(users)], Amount: decimal.NewFromInt(int64(amount))})
+	}
+	transactions = append(transactions, Transaction{UserID: whale, Amount: decimal.NewFromInt(1_000_000)})
+
+	processor := NewPercentileProcessor(0.995, 100)
+
+	threshold, ok := processor.Threshold(transactions)
+	require.True(t, ok)
+	assert.True(t, threshold.Equal(decimal.NewFromInt(995)), "rank ceil(0.995 * 1000) of 1000 amounts")
+
+	flaggedUsers := processor.Process(context.Background(), transactions)
+	assert.Contains(t, flaggedUsers, whale)
+	// only 996..999 among the regular amounts are above the threshold
+	assert.Len(t, flaggedUsers, 5)
+
+	_, ok = processor.Threshold(transactions[:10])
+	assert.False(t, ok)
+	assert.Empty(t, processor.Process(context.Background(), append(transactions[:9:9], transactions[len(transactions)-1])))
+}
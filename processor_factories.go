@@ -0,0 +1,1129 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// The params of the built-in rules other than those of rules_config.go. Durations are Go duration
+// strings and amounts decimal strings; an optional field left out disables what it bounds, as the
+// matching processor field documents.
+
+type structuringParams struct {
+	ReportingThreshold string `yaml:"reporting_threshold"`
+	Band               string `yaml:"band"`
+	Count              int    `yaml:"count"`
+	Window             string `yaml:"window"`
+}
+
+type dormancyParams struct {
+	Dormancy        string `yaml:"dormancy"`
+	Window          string `yaml:"window"`
+	CountThreshold  int    `yaml:"count_threshold,omitempty"`
+	AmountThreshold string `yaml:"amount_threshold,omitempty"`
+	StartIsDormant  bool   `yaml:"start_is_dormant,omitempty"`
+}
+
+type duplicateParams struct {
+	Tolerance    string `yaml:"tolerance,omitempty"`
+	MinGroupSize int    `yaml:"min_group_size,omitempty"`
+}
+
+type dailyAggregateParams struct {
+	Threshold string `yaml:"threshold"`
+	Location  string `yaml:"location,omitempty"`
+	Rolling   bool   `yaml:"rolling,omitempty"`
+}
+
+type offHoursParams struct {
+	Start    string `yaml:"start"`
+	End      string `yaml:"end"`
+	Location string `yaml:"location,omitempty"`
+	MinCount int    `yaml:"min_count"`
+	Window   string `yaml:"window,omitempty"`
+}
+
+type countryAllowlistParams struct {
+	Countries        []string `yaml:"countries"`
+	FlagEmptyCountry bool     `yaml:"flag_empty_country,omitempty"`
+}
+
+type countryRiskParams struct {
+	Risk      map[string]int `yaml:"risk"`
+	Threshold int            `yaml:"threshold"`
+	Window    string         `yaml:"window,omitempty"`
+}
+
+type newCountryParams struct {
+	BaselineTransactions int    `yaml:"baseline_transactions,omitempty"`
+	BaselinePeriod       string `yaml:"baseline_period,omitempty"`
+	MinAmount            string `yaml:"min_amount,omitempty"`
+}
+
+type funnelParams struct {
+	Window     string `yaml:"window"`
+	MaxSenders int    `yaml:"max_senders"`
+}
+
+type circularFlowParams struct {
+	Window        string `yaml:"window"`
+	MaxLength     int    `yaml:"max_length"`
+	MinEdgeAmount string `yaml:"min_edge_amount,omitempty"`
+}
+
+type percentileParams struct {
+	Percentile   float64 `yaml:"percentile"`
+	MinBatchSize int     `yaml:"min_batch_size,omitempty"`
+}
+
+type accelerationParams struct {
+	Window     string `yaml:"window"`
+	Multiplier string `yaml:"multiplier"`
+	Floor      string `yaml:"floor,omitempty"`
+}
+
+type amountBandParams struct {
+	Bands        []amountBandConfig `yaml:"bands"`
+	MinCount     int                `yaml:"min_count"`
+	Window       string             `yaml:"window,omitempty"`
+	AllowOverlap bool               `yaml:"allow_overlap,omitempty"`
+}
+
+type amountBandConfig struct {
+	Min string `yaml:"min"`
+	Max string `yaml:"max"`
+}
+
+type baselineSpikeParams struct {
+	Baseline   string `yaml:"baseline"`
+	Multiplier string `yaml:"multiplier"`
+	Floor      string `yaml:"floor,omitempty"`
+	MinHistory int    `yaml:"min_history,omitempty"`
+}
+
+type benfordParams struct {
+	MinTransactions int     `yaml:"min_transactions"`
+	Threshold       float64 `yaml:"threshold"`
+}
+
+// burstVelocityParams are the params of a "burst_velocity" rule, DefaultBurstPeriods applying without periods
+type burstVelocityParams struct {
+	Periods []periodConfig `yaml:"periods,omitempty"`
+}
+
+type categorySpendParams struct {
+	Categories      []string `yaml:"categories"`
+	Window          string   `yaml:"window"`
+	CountThreshold  int      `yaml:"count_threshold,omitempty"`
+	AmountThreshold string   `yaml:"amount_threshold,omitempty"`
+	FlagUnknown     bool     `yaml:"flag_unknown,omitempty"`
+}
+
+type channelRiskParams struct {
+	Limits map[Channel]channelLimitConfig `yaml:"limits"`
+	Window string                         `yaml:"window"`
+}
+
+type channelLimitConfig struct {
+	Count  int    `yaml:"count,omitempty"`
+	Amount string `yaml:"amount,omitempty"`
+}
+
+type corridorParams struct {
+	Corridors       []corridorConfig `yaml:"corridors"`
+	Window          string           `yaml:"window"`
+	CountThreshold  int              `yaml:"count_threshold,omitempty"`
+	AmountThreshold string           `yaml:"amount_threshold,omitempty"`
+	Bidirectional   bool             `yaml:"bidirectional,omitempty"`
+}
+
+type corridorConfig struct {
+	Origin      string `yaml:"origin"`
+	Destination string `yaml:"destination"`
+}
+
+type counterpartyBlacklistParams struct {
+	CounterpartyIDs []string `yaml:"counterparty_ids"`
+}
+
+type counterpartyCountryParams struct {
+	Countries []string `yaml:"countries"`
+	MinCount  int      `yaml:"min_count,omitempty"`
+	MinAmount string   `yaml:"min_amount,omitempty"`
+}
+
+// crossBorderRatioParams are the params of a "cross_border_ratio" rule. A config cannot name a
+// UserProfileProvider, so every user is compared to HomeCountry.
+type crossBorderRatioParams struct {
+	HomeCountry     string  `yaml:"home_country"`
+	Ratio           float64 `yaml:"ratio"`
+	ByAmount        bool    `yaml:"by_amount,omitempty"`
+	MinTransactions int     `yaml:"min_transactions,omitempty"`
+	Window          string  `yaml:"window,omitempty"`
+}
+
+type decayVelocityParams struct {
+	HalfLife  string  `yaml:"half_life"`
+	Threshold float64 `yaml:"threshold"`
+}
+
+type frequencyDeviationParams struct {
+	Baseline         string  `yaml:"baseline"`
+	Recent           string  `yaml:"recent"`
+	Multiplier       float64 `yaml:"multiplier"`
+	MinRecentCount   int     `yaml:"min_recent_count,omitempty"`
+	MinBaselineCount int     `yaml:"min_baseline_count,omitempty"`
+}
+
+type identicalAmountParams struct {
+	MinCount int    `yaml:"min_count"`
+	Floor    string `yaml:"floor,omitempty"`
+	Window   string `yaml:"window,omitempty"`
+}
+
+type keywordParams struct {
+	Keywords     []string `yaml:"keywords,omitempty"`
+	Patterns     []string `yaml:"patterns,omitempty"`
+	WordBoundary bool     `yaml:"word_boundary,omitempty"`
+	MinCount     int      `yaml:"min_count"`
+	Window       string   `yaml:"window,omitempty"`
+}
+
+type passThroughParams struct {
+	Window string `yaml:"window"`
+	Ratio  string `yaml:"ratio"`
+}
+
+type repeatCounterpartyParams struct {
+	Window   string `yaml:"window"`
+	MaxCount int    `yaml:"max_count"`
+}
+
+type reversalAbuseParams struct {
+	Window       string `yaml:"window"`
+	MinReversals int    `yaml:"min_reversals"`
+	MinRatio     string `yaml:"min_ratio,omitempty"`
+}
+
+type splitPaymentParams struct {
+	MaxGap         string `yaml:"max_gap"`
+	Threshold      string `yaml:"threshold"`
+	MinClusterSize int    `yaml:"min_cluster_size,omitempty"`
+}
+
+// watchlistParams are the params of a "watchlist" rule, mode being exact, normalized or fuzzy
+type watchlistParams struct {
+	Entries     []string `yaml:"entries"`
+	Mode        string   `yaml:"mode,omitempty"`
+	MaxDistance int      `yaml:"max_distance,omitempty"`
+}
+
+type zScoreParams struct {
+	MinHistory int     `yaml:"min_history"`
+	K          float64 `yaml:"k"`
+	MinAmount  string  `yaml:"min_amount,omitempty"`
+}
+
+var matchModes = map[string]MatchMode{
+	"":           MatchExact,
+	"exact":      MatchExact,
+	"normalized": MatchNormalized,
+	"fuzzy":      MatchFuzzy,
+}
+
+func init() {
+	mustRegisterProcessorFactory("structuring", newStructuringRule)
+	mustRegisterProcessorFactory("dormancy", newDormancyRule)
+	mustRegisterProcessorFactory("duplicate", newDuplicateRule)
+	mustRegisterProcessorFactory("daily_aggregate", newDailyAggregateRule)
+	mustRegisterProcessorFactory("off_hours", newOffHoursRule)
+	mustRegisterProcessorFactory("country_allowlist", newCountryAllowlistRule)
+	mustRegisterProcessorFactory("country_risk", newCountryRiskRule)
+	mustRegisterProcessorFactory("new_country", newNewCountryRule)
+	mustRegisterProcessorFactory("funnel", newFunnelRule)
+	mustRegisterProcessorFactory("circular_flow", newCircularFlowRule)
+	mustRegisterProcessorFactory("percentile", newPercentileRule)
+	mustRegisterProcessorFactory("acceleration", newAccelerationRule)
+	mustRegisterProcessorFactory("amount_band", newAmountBandRule)
+	mustRegisterProcessorFactory("baseline_spike", newBaselineSpikeRule)
+	mustRegisterProcessorFactory("benford", newBenfordRule)
+	mustRegisterProcessorFactory("burst_velocity", newBurstVelocityRule)
+	mustRegisterProcessorFactory("category_spend", newCategorySpendRule)
+	mustRegisterProcessorFactory("channel_risk", newChannelRiskRule)
+	mustRegisterProcessorFactory("corridor", newCorridorRule)
+	mustRegisterProcessorFactory("counterparty_blacklist", newCounterpartyBlacklistRule)
+	mustRegisterProcessorFactory("counterparty_country", newCounterpartyCountryRule)
+	mustRegisterProcessorFactory("cross_border_ratio", newCrossBorderRatioRule)
+	mustRegisterProcessorFactory("decay_velocity", newDecayVelocityRule)
+	mustRegisterProcessorFactory("frequency_deviation", newFrequencyDeviationRule)
+	mustRegisterProcessorFactory("identical_amount", newIdenticalAmountRule)
+	mustRegisterProcessorFactory("keyword", newKeywordRule)
+	mustRegisterProcessorFactory("pass_through", newPassThroughRule)
+	mustRegisterProcessorFactory("repeat_counterparty", newRepeatCounterpartyRule)
+	mustRegisterProcessorFactory("reversal_abuse", newReversalAbuseRule)
+	mustRegisterProcessorFactory("split_payment", newSplitPaymentRule)
+	mustRegisterProcessorFactory("watchlist", newWatchlistRule)
+	mustRegisterProcessorFactory("zscore", newZScoreRule)
+}
+
+func newStructuringRule(params map[string]any) (RuleProcessor, error) {
+	var p structuringParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	reportingThreshold, err := parsePositiveDecimal("reporting_threshold", p.ReportingThreshold)
+	if err != nil {
+		return nil, err
+	}
+	band, err := parsePositiveDecimal("band", p.Band)
+	if err != nil {
+		return nil, err
+	}
+	if band.GreaterThanOrEqual(decimal.NewFromInt(1)) {
+		return nil, fmt.Errorf("band: %s is not below 1", band)
+	}
+	if err := checkPositive("count", p.Count); err != nil {
+		return nil, err
+	}
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewStructuringProcessor(reportingThreshold, band, p.Count, window), nil
+}
+
+func newDormancyRule(params map[string]any) (RuleProcessor, error) {
+	var p dormancyParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	dormancy, err := parsePositiveDuration("dormancy", p.Dormancy)
+	if err != nil {
+		return nil, err
+	}
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNonNegative("count_threshold", p.CountThreshold); err != nil {
+		return nil, err
+	}
+	amountThreshold, err := parseOptionalDecimal("amount_threshold", p.AmountThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if p.CountThreshold == 0 && amountThreshold.IsZero() {
+		return nil, errors.New("count_threshold, amount_threshold: none configured")
+	}
+
+	processor := NewDormancyProcessor(dormancy, window, p.CountThreshold, amountThreshold)
+	processor.StartIsDormant = p.StartIsDormant
+
+	return processor, nil
+}
+
+func newDuplicateRule(params map[string]any) (RuleProcessor, error) {
+	var p duplicateParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	tolerance, err := parseOptionalDuration("tolerance", p.Tolerance)
+	if err != nil {
+		return nil, err
+	}
+	// left out it defaults to a pair, below that every user would be flagged
+	minGroupSize := p.MinGroupSize
+	if minGroupSize == 0 {
+		minGroupSize = 2
+	} else if minGroupSize < 2 {
+		return nil, fmt.Errorf("min_group_size: %d is below 2", minGroupSize)
+	}
+
+	return NewDuplicateTransactionProcessor(tolerance, minGroupSize), nil
+}
+
+func newDailyAggregateRule(params map[string]any) (RuleProcessor, error) {
+	var p dailyAggregateParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	threshold, err := parsePositiveDecimal("threshold", p.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(p.Location)
+	if err != nil {
+		return nil, fmt.Errorf("location: %w", err)
+	}
+
+	processor := NewDailyAggregateProcessor(threshold, location)
+	processor.Rolling = p.Rolling
+
+	return processor, nil
+}
+
+func newOffHoursRule(params map[string]any) (RuleProcessor, error) {
+	var p offHoursParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	start, err := parseClock("start", p.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock("end", p.End)
+	if err != nil {
+		return nil, err
+	}
+	location, err := time.LoadLocation(p.Location)
+	if err != nil {
+		return nil, fmt.Errorf("location: %w", err)
+	}
+	if err := checkPositive("min_count", p.MinCount); err != nil {
+		return nil, err
+	}
+	window, err := parseOptionalDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewOffHoursProcessor(start, end, location, p.MinCount, window), nil
+}
+
+func newCountryAllowlistRule(params map[string]any) (RuleProcessor, error) {
+	var p countryAllowlistParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	processor, err := NewCountryAllowListProcessor(p.Countries, p.FlagEmptyCountry)
+	if err != nil {
+		return nil, fmt.Errorf("countries: %w", err)
+	}
+
+	return processor, nil
+}
+
+func newCountryRiskRule(params map[string]any) (RuleProcessor, error) {
+	var p countryRiskParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Risk) == 0 {
+		return nil, errors.New("risk: none configured")
+	}
+	for _, country := range slices.Sorted(maps.Keys(p.Risk)) {
+		if err := checkNonNegative("risk: "+country, p.Risk[country]); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkNonNegative("threshold", p.Threshold); err != nil {
+		return nil, err
+	}
+	window, err := parseOptionalDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCountryRiskProcessor(p.Risk, p.Threshold, window), nil
+}
+
+func newNewCountryRule(params map[string]any) (RuleProcessor, error) {
+	var p newCountryParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if err := checkNonNegative("baseline_transactions", p.BaselineTransactions); err != nil {
+		return nil, err
+	}
+	baselinePeriod, err := parseOptionalDuration("baseline_period", p.BaselinePeriod)
+	if err != nil {
+		return nil, err
+	}
+	// without a baseline the processor flags nobody, which is never what a config means
+	if p.BaselineTransactions == 0 && baselinePeriod == 0 {
+		return nil, errors.New("baseline_transactions, baseline_period: none configured")
+	}
+	minAmount, err := parseOptionalDecimal("min_amount", p.MinAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewNewCountryProcessor(p.BaselineTransactions, baselinePeriod, minAmount), nil
+}
+
+func newFunnelRule(params map[string]any) (RuleProcessor, error) {
+	var p funnelParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPositive("max_senders", p.MaxSenders); err != nil {
+		return nil, err
+	}
+
+	return NewFunnelProcessor(window, p.MaxSenders), nil
+}
+
+func newCircularFlowRule(params map[string]any) (RuleProcessor, error) {
+	var p circularFlowParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+	if p.MaxLength < 2 {
+		return nil, fmt.Errorf("max_length: %d is below 2", p.MaxLength)
+	}
+	minEdgeAmount, err := parseOptionalDecimal("min_edge_amount", p.MinEdgeAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCircularFlowProcessor(window, p.MaxLength, minEdgeAmount), nil
+}
+
+func newPercentileRule(params map[string]any) (RuleProcessor, error) {
+	var p percentileParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if err := checkFraction("percentile", p.Percentile); err != nil {
+		return nil, err
+	}
+	if err := checkNonNegative("min_batch_size", p.MinBatchSize); err != nil {
+		return nil, err
+	}
+
+	return NewPercentileProcessor(p.Percentile, p.MinBatchSize), nil
+}
+
+func newAccelerationRule(params map[string]any) (RuleProcessor, error) {
+	var p accelerationParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+	multiplier, err := parsePositiveDecimal("multiplier", p.Multiplier)
+	if err != nil {
+		return nil, err
+	}
+	floor, err := parseOptionalDecimal("floor", p.Floor)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAccelerationProcessor(window, multiplier, floor), nil
+}
+
+func newAmountBandRule(params map[string]any) (RuleProcessor, error) {
+	var p amountBandParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Bands) == 0 {
+		return nil, errors.New("bands: none configured")
+	}
+	bands := make([]AmountBand, len(p.Bands))
+	for i, band := range p.Bands {
+		var err error
+		if bands[i].Min, err = decimal.NewFromString(band.Min); err != nil {
+			return nil, fmt.Errorf("bands[%d].min: %w", i, err)
+		}
+		if bands[i].Max, err = decimal.NewFromString(band.Max); err != nil {
+			return nil, fmt.Errorf("bands[%d].max: %w", i, err)
+		}
+	}
+	if err := checkPositive("min_count", p.MinCount); err != nil {
+		return nil, err
+	}
+	window, err := parseOptionalDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	processor, err := NewAmountBandProcessor(bands, p.MinCount, window, p.AllowOverlap)
+	if err != nil {
+		return nil, fmt.Errorf("bands: %w", err)
+	}
+
+	return processor, nil
+}
+
+func newBaselineSpikeRule(params map[string]any) (RuleProcessor, error) {
+	var p baselineSpikeParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	baseline, err := parsePositiveDuration("baseline", p.Baseline)
+	if err != nil {
+		return nil, err
+	}
+	multiplier, err := parsePositiveDecimal("multiplier", p.Multiplier)
+	if err != nil {
+		return nil, err
+	}
+	floor, err := parseOptionalDecimal("floor", p.Floor)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNonNegative("min_history", p.MinHistory); err != nil {
+		return nil, err
+	}
+
+	return NewBaselineSpikeProcessor(baseline, multiplier, floor, p.MinHistory), nil
+}
+
+func newBenfordRule(params map[string]any) (RuleProcessor, error) {
+	var p benfordParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if err := checkPositive("min_transactions", p.MinTransactions); err != nil {
+		return nil, err
+	}
+	if p.Threshold <= 0 {
+		return nil, fmt.Errorf("threshold: %g is not positive", p.Threshold)
+	}
+
+	return NewBenfordProcessor(p.MinTransactions, p.Threshold), nil
+}
+
+func newBurstVelocityRule(params map[string]any) (RuleProcessor, error) {
+	var p burstVelocityParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	periods, err := parsePeriods(p.Periods)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBurstVelocityProcessor(periods), nil
+}
+
+func newCategorySpendRule(params map[string]any) (RuleProcessor, error) {
+	var p categorySpendParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Categories) == 0 {
+		return nil, errors.New("categories: none configured")
+	}
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNonNegative("count_threshold", p.CountThreshold); err != nil {
+		return nil, err
+	}
+	amountThreshold, err := parseOptionalDecimal("amount_threshold", p.AmountThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if p.CountThreshold == 0 && amountThreshold.IsZero() {
+		return nil, errors.New("count_threshold, amount_threshold: none configured")
+	}
+
+	processor := NewCategorySpendProcessor(p.Categories, window, p.CountThreshold, amountThreshold)
+	processor.FlagUnknown = p.FlagUnknown
+
+	return processor, nil
+}
+
+func newChannelRiskRule(params map[string]any) (RuleProcessor, error) {
+	var p channelRiskParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Limits) == 0 {
+		return nil, errors.New("limits: none configured")
+	}
+	limits := make(map[Channel]ChannelLimit, len(p.Limits))
+	for _, channel := range slices.Sorted(maps.Keys(p.Limits)) {
+		switch channel {
+		case ChannelCard, ChannelWire, ChannelCrypto, ChannelCash:
+		default:
+			return nil, fmt.Errorf("limits: unknown channel %q", channel)
+		}
+		limit := p.Limits[channel]
+		if err := checkNonNegative(fmt.Sprintf("limits.%s.count", channel), limit.Count); err != nil {
+			return nil, err
+		}
+		amount, err := parseOptionalDecimal(fmt.Sprintf("limits.%s.amount", channel), limit.Amount)
+		if err != nil {
+			return nil, err
+		}
+		limits[channel] = ChannelLimit{Count: limit.Count, Amount: amount}
+	}
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewChannelRiskProcessor(limits, window), nil
+}
+
+func newCorridorRule(params map[string]any) (RuleProcessor, error) {
+	var p corridorParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Corridors) == 0 {
+		return nil, errors.New("corridors: none configured")
+	}
+	corridors := make([]Corridor, len(p.Corridors))
+	for i, corridor := range p.Corridors {
+		if corridor.Origin == "" || corridor.Destination == "" {
+			return nil, fmt.Errorf("corridors[%d]: needs an origin and a destination", i)
+		}
+		corridors[i] = Corridor{Origin: corridor.Origin, Destination: corridor.Destination}
+	}
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNonNegative("count_threshold", p.CountThreshold); err != nil {
+		return nil, err
+	}
+	amountThreshold, err := parseOptionalDecimal("amount_threshold", p.AmountThreshold)
+	if err != nil {
+		return nil, err
+	}
+	if p.CountThreshold == 0 && amountThreshold.IsZero() {
+		return nil, errors.New("count_threshold, amount_threshold: none configured")
+	}
+
+	processor := NewCorridorProcessor(corridors, window, p.CountThreshold, amountThreshold)
+	processor.Bidirectional = p.Bidirectional
+
+	return processor, nil
+}
+
+func newCounterpartyBlacklistRule(params map[string]any) (RuleProcessor, error) {
+	var p counterpartyBlacklistParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.CounterpartyIDs) == 0 {
+		return nil, errors.New("counterparty_ids: none configured")
+	}
+
+	return NewCounterpartyBlacklistProcessor(p.CounterpartyIDs...), nil
+}
+
+func newCounterpartyCountryRule(params map[string]any) (RuleProcessor, error) {
+	var p counterpartyCountryParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Countries) == 0 {
+		return nil, errors.New("countries: none configured")
+	}
+	if err := checkNonNegative("min_count", p.MinCount); err != nil {
+		return nil, err
+	}
+	minAmount, err := parseOptionalDecimal("min_amount", p.MinAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	processor := NewCounterpartyCountryProcessor(p.Countries...)
+	processor.MinCount, processor.MinAmount = p.MinCount, minAmount
+
+	return processor, nil
+}
+
+func newCrossBorderRatioRule(params map[string]any) (RuleProcessor, error) {
+	var p crossBorderRatioParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if p.HomeCountry == "" {
+		return nil, errors.New("home_country: not configured")
+	}
+	if err := checkFraction("ratio", p.Ratio); err != nil {
+		return nil, err
+	}
+	if err := checkNonNegative("min_transactions", p.MinTransactions); err != nil {
+		return nil, err
+	}
+	window, err := parseOptionalDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	processor := NewCrossBorderRatioProcessor(p.HomeCountry, p.Ratio, p.MinTransactions, window)
+	processor.ByAmount = p.ByAmount
+
+	return processor, nil
+}
+
+func newDecayVelocityRule(params map[string]any) (RuleProcessor, error) {
+	var p decayVelocityParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	halfLife, err := parsePositiveDuration("half_life", p.HalfLife)
+	if err != nil {
+		return nil, err
+	}
+	if p.Threshold <= 0 {
+		return nil, fmt.Errorf("threshold: %g is not positive", p.Threshold)
+	}
+
+	return NewDecayVelocityProcessor(halfLife, p.Threshold), nil
+}
+
+func newFrequencyDeviationRule(params map[string]any) (RuleProcessor, error) {
+	var p frequencyDeviationParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	baseline, err := parsePositiveDuration("baseline", p.Baseline)
+	if err != nil {
+		return nil, err
+	}
+	recent, err := parsePositiveDuration("recent", p.Recent)
+	if err != nil {
+		return nil, err
+	}
+	if p.Multiplier <= 0 {
+		return nil, fmt.Errorf("multiplier: %g is not positive", p.Multiplier)
+	}
+	if err := checkNonNegative("min_recent_count", p.MinRecentCount); err != nil {
+		return nil, err
+	}
+	if err := checkNonNegative("min_baseline_count", p.MinBaselineCount); err != nil {
+		return nil, err
+	}
+
+	return NewFrequencyDeviationProcessor(baseline, recent, p.Multiplier, p.MinRecentCount, p.MinBaselineCount), nil
+}
+
+func newIdenticalAmountRule(params map[string]any) (RuleProcessor, error) {
+	var p identicalAmountParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if p.MinCount < 2 {
+		return nil, fmt.Errorf("min_count: %d is below 2", p.MinCount)
+	}
+	floor, err := parseOptionalDecimal("floor", p.Floor)
+	if err != nil {
+		return nil, err
+	}
+	window, err := parseOptionalDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewIdenticalAmountProcessor(p.MinCount, floor, window), nil
+}
+
+func newKeywordRule(params map[string]any) (RuleProcessor, error) {
+	var p keywordParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Keywords) == 0 && len(p.Patterns) == 0 {
+		return nil, errors.New("keywords, patterns: none configured")
+	}
+	if err := checkPositive("min_count", p.MinCount); err != nil {
+		return nil, err
+	}
+	window, err := parseOptionalDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	processor, err := NewKeywordProcessor(p.Keywords, p.Patterns, p.WordBoundary, p.MinCount, window)
+	if err != nil {
+		return nil, fmt.Errorf("patterns: %w", err)
+	}
+
+	return processor, nil
+}
+
+func newPassThroughRule(params map[string]any) (RuleProcessor, error) {
+	var p passThroughParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+	ratio, err := parsePositiveDecimal("ratio", p.Ratio)
+	if err != nil {
+		return nil, err
+	}
+	if ratio.GreaterThan(decimal.NewFromInt(1)) {
+		return nil, fmt.Errorf("ratio: %s is above 1", ratio)
+	}
+
+	return NewPassThroughProcessor(window, ratio), nil
+}
+
+func newRepeatCounterpartyRule(params map[string]any) (RuleProcessor, error) {
+	var p repeatCounterpartyParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPositive("max_count", p.MaxCount); err != nil {
+		return nil, err
+	}
+
+	return NewRepeatCounterpartyProcessor(window, p.MaxCount), nil
+}
+
+func newReversalAbuseRule(params map[string]any) (RuleProcessor, error) {
+	var p reversalAbuseParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	window, err := parsePositiveDuration("window", p.Window)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPositive("min_reversals", p.MinReversals); err != nil {
+		return nil, err
+	}
+	minRatio, err := parseOptionalDecimal("min_ratio", p.MinRatio)
+	if err != nil {
+		return nil, err
+	}
+	if minRatio.GreaterThan(decimal.NewFromInt(1)) {
+		return nil, fmt.Errorf("min_ratio: %s is above 1", minRatio)
+	}
+
+	return NewReversalAbuseProcessor(window, p.MinReversals, minRatio), nil
+}
+
+func newSplitPaymentRule(params map[string]any) (RuleProcessor, error) {
+	var p splitPaymentParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	maxGap, err := parsePositiveDuration("max_gap", p.MaxGap)
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := parsePositiveDecimal("threshold", p.Threshold)
+	if err != nil {
+		return nil, err
+	}
+	if p.MinClusterSize != 0 && p.MinClusterSize < 2 {
+		return nil, fmt.Errorf("min_cluster_size: %d is below 2", p.MinClusterSize)
+	}
+
+	return NewSplitPaymentProcessor(maxGap, threshold, p.MinClusterSize), nil
+}
+
+func newWatchlistRule(params map[string]any) (RuleProcessor, error) {
+	var p watchlistParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if len(p.Entries) == 0 {
+		return nil, errors.New("entries: none configured")
+	}
+	mode, ok := matchModes[p.Mode]
+	if !ok {
+		return nil, fmt.Errorf("mode: unknown mode %q", p.Mode)
+	}
+	if err := checkNonNegative("max_distance", p.MaxDistance); err != nil {
+		return nil, err
+	}
+
+	return NewWatchlistProcessor(p.Entries, mode, p.MaxDistance), nil
+}
+
+func newZScoreRule(params map[string]any) (RuleProcessor, error) {
+	var p zScoreParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	if err := checkPositive("min_history", p.MinHistory); err != nil {
+		return nil, err
+	}
+	if p.K <= 0 {
+		return nil, fmt.Errorf("k: %g is not positive", p.K)
+	}
+	minAmount, err := parseOptionalDecimal("min_amount", p.MinAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewZScoreProcessor(p.MinHistory, p.K, minAmount), nil
+}
+
+// parsePeriods parses the periods of a velocity rule, none giving nil
+func parsePeriods(configs []periodConfig) ([]VelocityPeriod, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	periods := make([]VelocityPeriod, len(configs))
+	for i, period := range configs {
+		duration, err := time.ParseDuration(period.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("periods[%d].duration: %w", i, err)
+		}
+		if period.Threshold < 0 {
+			return nil, fmt.Errorf("periods[%d].threshold: %d is negative", i, period.Threshold)
+		}
+		periods[i] = NewNamedVelocityPeriod(period.Name, duration, period.Threshold)
+	}
+
+	return periods, nil
+}
+
+// parseOptionalDuration parses a non-negative duration, zero when left out
+func parseOptionalDuration(field, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", field, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s: %s is negative", field, s)
+	}
+
+	return d, nil
+}
+
+// parsePositiveDuration parses a duration that must be configured and positive
+func parsePositiveDuration(field, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("%s: not configured", field)
+	}
+
+	d, err := parseOptionalDuration(field, s)
+	if err == nil && d == 0 {
+		err = fmt.Errorf("%s: %s is not positive", field, s)
+	}
+
+	return d, err
+}
+
+// parseClock parses a time of day given as a duration since midnight, e.g. 22h or 6h30m
+func parseClock(field, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("%s: not configured", field)
+	}
+
+	d, err := parseOptionalDuration(field, s)
+	if err == nil && d >= 24*time.Hour {
+		err = fmt.Errorf("%s: %s is not within a day", field, s)
+	}
+
+	return d, err
+}
+
+// parseOptionalDecimal parses a non-negative decimal, the zero Decimal when left out
+func parseOptionalDecimal(field, s string) (decimal.Decimal, error) {
+	if s == "" {
+		return decimal.Decimal{}, nil
+	}
+
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("%s: %w", field, err)
+	}
+	if d.IsNegative() {
+		return decimal.Zero, fmt.Errorf("%s: %s is negative", field, s)
+	}
+
+	return d, nil
+}
+
+// parsePositiveDecimal parses a decimal that must be configured and positive
+func parsePositiveDecimal(field, s string) (decimal.Decimal, error) {
+	if s == "" {
+		return decimal.Zero, fmt.Errorf("%s: not configured", field)
+	}
+
+	d, err := parseOptionalDecimal(field, s)
+	if err == nil && d.IsZero() {
+		err = fmt.Errorf("%s: %s is not positive", field, s)
+	}
+
+	return d, err
+}
+
+func checkPositive(field string, n int) error {
+	if n <= 0 {
+		return fmt.Errorf("%s: %d is not positive", field, n)
+	}
+
+	return nil
+}
+
+func checkNonNegative(field string, n int) error {
+	if n < 0 {
+		return fmt.Errorf("%s: %d is negative", field, n)
+	}
+
+	return nil
+}
+
+// checkFraction checks a share is in (0, 1]
+func checkFraction(field string, f float64) error {
+	if f <= 0 || f > 1 {
+		return fmt.Errorf("%s: %g is not in (0, 1]", field, f)
+	}
+
+	return nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadRulesConfig_BuiltInTypes(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	d := decimal.RequireFromString
+
+	dormancy := NewDormancyProcessor(90*24*time.Hour, 24*time.Hour, 0, d("5000"))
+	dormancy.StartIsDormant = true
+	rolling := NewDailyAggregateProcessor(d("10000"), time.UTC)
+	rolling.Rolling = true
+	counterpartyCountry := NewCounterpartyCountryProcessor("IR")
+	counterpartyCountry.MinCount = 2
+	amountBand, err := NewAmountBandProcessor([]AmountBand{{Min: d("900"), Max: d("999.99")}}, 3, week, false)
+	require.NoError(t, err)
+	countryAllowlist, err := NewCountryAllowListProcessor([]string{"DE", "FR"}, true)
+	require.NoError(t, err)
+	keyword, err := NewKeywordProcessor([]string{"gift card"}, []string{`inv-\d+`}, true, 2, 0)
+	require.NoError(t, err)
+	corridor := NewCorridorProcessor([]Corridor{{Origin: "US", Destination: "MX"}}, week, 10, decimal.Decimal{})
+	corridor.Bidirectional = true
+
+	tests := []struct {
+		config string
+		want   RuleProcessor
+	}{
+		{
+			config: "type: structuring\nreporting_threshold: 10000\nband: \"0.1\"\ncount: 3\nwindow: 72h",
+			want:   NewStructuringProcessor(d("10000"), d("0.1"), 3, 72*time.Hour),
+		},
+		{
+			config: "type: dormancy\ndormancy: 2160h\nwindow: 24h\namount_threshold: 5000\nstart_is_dormant: true",
+			want:   dormancy,
+		},
+		{
+			config: "type: duplicate\ntolerance: 5m",
+			want:   NewDuplicateTransactionProcessor(5*time.Minute, 2),
+		},
+		{
+			config: "type: daily_aggregate\nthreshold: 10000\nlocation: Europe/Berlin",
+			want:   NewDailyAggregateProcessor(d("10000"), berlin),
+		},
+		{
+			config: "type: daily_aggregate\nthreshold: 10000\nrolling: true",
+			want:   rolling,
+		},
+		{
+			config: "type: off_hours\nstart: 22h\nend: 6h30m\nlocation: Europe/Berlin\nmin_count: 3\nwindow: 168h",
+			want:   NewOffHoursProcessor(22*time.Hour, 6*time.Hour+30*time.Minute, berlin, 3, week),
+		},
+		{
+			config: "type: country_allowlist\ncountries: [DE, FR]\nflag_empty_country: true",
+			want:   countryAllowlist,
+		},
+		{
+			config: "type: country_risk\nrisk: {IR: 10, RU: 5}\nthreshold: 20\nwindow: 720h",
+			want:   NewCountryRiskProcessor(map[string]int{"IR": 10, "RU": 5}, 20, 720*time.Hour),
+		},
+		{
+			config: "type: new_country\nbaseline_transactions: 10\nmin_amount: 500",
+			want:   NewNewCountryProcessor(10, 0, d("500")),
+		},
+		{
+			config: "type: funnel\nwindow: 24h\nmax_senders: 10",
+			want:   NewFunnelProcessor(24*time.Hour, 10),
+		},
+		{
+			config: "type: circular_flow\nwindow: 168h\nmax_length: 4\nmin_edge_amount: 1000",
+			want:   NewCircularFlowProcessor(week, 4, d("1000")),
+		},
+		{
+			config: "type: percentile\npercentile: 0.995\nmin_batch_size: 100",
+			want:   NewPercentileProcessor(0.995, 100),
+		},
+		{
+			config: "type: acceleration\nwindow: 168h\nmultiplier: 3\nfloor: 1000",
+			want:   NewAccelerationProcessor(week, d("3"), d("1000")),
+		},
+		{
+			config: "type: amount_band\nbands: [{min: 900, max: \"999.99\"}]\nmin_count: 3\nwindow: 168h",
+			want:   amountBand,
+		},
+		{
+			config: "type: baseline_spike\nbaseline: 720h\nmultiplier: 5\nmin_history: 3",
+			want:   NewBaselineSpikeProcessor(720*time.Hour, d("5"), decimal.Decimal{}, 3),
+		},
+		{
+			config: "type: benford\nmin_transactions: 50\nthreshold: 15.51",
+			want:   NewBenfordProcessor(50, 15.51),
+		},
+		{
+			config: "type: burst_velocity",
+			want:   NewBurstVelocityProcessor(nil),
+		},
+		{
+			config: "type: burst_velocity\nperiods: [{duration: 1m, threshold: 5}]",
+			want:   NewBurstVelocityProcessor([]VelocityPeriod{NewVelocityPeriod(time.Minute, 5)}),
+		},
+		{
+			config: "type: category_spend\ncategories: [gambling]\nwindow: 24h\ncount_threshold: 5",
+			want:   NewCategorySpendProcessor([]string{"gambling"}, 24*time.Hour, 5, decimal.Decimal{}),
+		},
+		{
+			config: "type: channel_risk\nlimits: {crypto: {count: 3, amount: 5000}}\nwindow: 24h",
+			want:   NewChannelRiskProcessor(map[Channel]ChannelLimit{ChannelCrypto: {Count: 3, Amount: d("5000")}}, 24*time.Hour),
+		},
+		{
+			config: "type: corridor\ncorridors: [{origin: US, destination: MX}]\nwindow: 168h\ncount_threshold: 10\nbidirectional: true",
+			want:   corridor,
+		},
+		{
+			config: "type: counterparty_blacklist\ncounterparty_ids: [acct-1, acct-2]",
+			want:   NewCounterpartyBlacklistProcessor("acct-1", "acct-2"),
+		},
+		{
+			config: "type: counterparty_country\ncountries: [IR]\nmin_count: 2",
+			want:   counterpartyCountry,
+		},
+		{
+			config: "type: cross_border_ratio\nhome_country: DE\nratio: 0.8\nmin_transactions: 10",
+			want:   NewCrossBorderRatioProcessor("DE", 0.8, 10, 0),
+		},
+		{
+			config: "type: decay_velocity\nhalf_life: 24h\nthreshold: 5",
+			want:   NewDecayVelocityProcessor(24*time.Hour, 5),
+		},
+		{
+			config: "type: frequency_deviation\nbaseline: 720h\nrecent: 24h\nmultiplier: 4\nmin_recent_count: 5",
+			want:   NewFrequencyDeviationProcessor(720*time.Hour, 24*time.Hour, 4, 5, 0),
+		},
+		{
+			config: "type: identical_amount\nmin_count: 3\nfloor: 100",
+			want:   NewIdenticalAmountProcessor(3, d("100"), 0),
+		},
+		{
+			config: "type: keyword\nkeywords: [gift card]\npatterns: ['inv-\\d+']\nword_boundary: true\nmin_count: 2",
+			want:   keyword,
+		},
+		{
+			config: "type: pass_through\nwindow: 48h\nratio: \"0.9\"",
+			want:   NewPassThroughProcessor(48*time.Hour, d("0.9")),
+		},
+		{
+			config: "type: repeat_counterparty\nwindow: 24h\nmax_count: 5",
+			want:   NewRepeatCounterpartyProcessor(24*time.Hour, 5),
+		},
+		{
+			config: "type: reversal_abuse\nwindow: 720h\nmin_reversals: 3\nmin_ratio: \"0.5\"",
+			want:   NewReversalAbuseProcessor(720*time.Hour, 3, d("0.5")),
+		},
+		{
+			config: "type: split_payment\nmax_gap: 10m\nthreshold: 1000",
+			want:   NewSplitPaymentProcessor(10*time.Minute, d("1000"), 0),
+		},
+		{
+			config: "type: watchlist\nentries: [John Doe]\nmode: fuzzy\nmax_distance: 1",
+			want:   NewWatchlistProcessor([]string{"John Doe"}, MatchFuzzy, 1),
+		},
+		{
+			config: "type: zscore\nmin_history: 10\nk: 3\nmin_amount: 1000",
+			want:   NewZScoreProcessor(10, 3, d("1000")),
+		},
+	}
+
+	for _, tt := range tests {
+		name, _, _ := strings.Cut(strings.TrimPrefix(tt.config, "type: "), "\n")
+		t.Run(name, func(t *testing.T) {
+			config := "rules:\n  - " + strings.ReplaceAll(tt.config, "\n", "\n    ") + "\n"
+			engine, err := LoadRulesConfig(strings.NewReader(config))
+			require.NoError(t, err)
+
+			rules, version := engine.currentRules()
+			require.Len(t, rules, 1)
+			assert.NotEmpty(t, version)
+			if want, ok := tt.want.(*CounterpartyBlacklistProcessor); ok {
+				got := rules[0].processor.(*CounterpartyBlacklistProcessor)
+				assert.Equal(t, *want.blacklist.Load(), *got.blacklist.Load())
+				return
+			}
+			assert.Equal(t, tt.want, rules[0].processor)
+		})
+	}
+}
+
+func TestLoadRulesConfig_BuiltInTypeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:    "structuring without a window",
+			config:  "type: structuring\nreporting_threshold: 10000\nband: \"0.1\"\ncount: 3",
+			wantErr: "rule 0 (structuring): window: not configured",
+		},
+		{
+			name:    "structuring band of one",
+			config:  "type: structuring\nreporting_threshold: 10000\nband: 1\ncount: 3\nwindow: 72h",
+			wantErr: "rule 0 (structuring): band: 1 is not below 1",
+		},
+		{
+			name:    "dormancy without a threshold",
+			config:  "type: dormancy\ndormancy: 2160h\nwindow: 24h",
+			wantErr: "rule 0 (dormancy): count_threshold, amount_threshold: none configured",
+		},
+		{
+			name:    "duplicate group of one",
+			config:  "type: duplicate\nmin_group_size: 1",
+			wantErr: "rule 0 (duplicate): min_group_size: 1 is below 2",
+		},
+		{
+			name:    "daily aggregate unknown location",
+			config:  "type: daily_aggregate\nthreshold: 10000\nlocation: Mars/Olympus",
+			wantErr: "rule 0 (daily_aggregate): location: unknown time zone Mars/Olympus",
+		},
+		{
+			name:    "off hours past midnight",
+			config:  "type: off_hours\nstart: 25h\nend: 6h\nmin_count: 3",
+			wantErr: "rule 0 (off_hours): start: 25h is not within a day",
+		},
+		{
+			name:    "empty country allowlist",
+			config:  "type: country_allowlist\ncountries: []",
+			wantErr: "rule 0 (country_allowlist): countries: " + ErrEmptyAllowlist.Error(),
+		},
+		{
+			name:    "negative country risk",
+			config:  "type: country_risk\nrisk: {IR: -1}\nthreshold: 5",
+			wantErr: "rule 0 (country_risk): risk: IR: -1 is negative",
+		},
+		{
+			name:    "new country without a baseline",
+			config:  "type: new_country\nmin_amount: 500",
+			wantErr: "rule 0 (new_country): baseline_transactions, baseline_period: none configured",
+		},
+		{
+			name:    "funnel without senders",
+			config:  "type: funnel\nwindow: 24h",
+			wantErr: "rule 0 (funnel): max_senders: 0 is not positive",
+		},
+		{
+			name:    "circular flow of one payment",
+			config:  "type: circular_flow\nwindow: 24h\nmax_length: 1",
+			wantErr: "rule 0 (circular_flow): max_length: 1 is below 2",
+		},
+		{
+			name:    "percentile above one",
+			config:  "type: percentile\npercentile: 99.5",
+			wantErr: "rule 0 (percentile): percentile: 99.5 is not in (0, 1]",
+		},
+		{
+			name:    "overlapping amount bands",
+			config:  "type: amount_band\nbands: [{min: 100, max: 200}, {min: 150, max: 300}]\nmin_count: 2",
+			wantErr: "rule 0 (amount_band): bands: ",
+		},
+		{
+			name:    "unknown channel",
+			config:  "type: channel_risk\nlimits: {pigeon: {count: 1}}\nwindow: 24h",
+			wantErr: `rule 0 (channel_risk): limits: unknown channel "pigeon"`,
+		},
+		{
+			name:    "invalid keyword pattern",
+			config:  "type: keyword\npatterns: ['(']\nmin_count: 1",
+			wantErr: "rule 0 (keyword): patterns: ",
+		},
+		{
+			name:    "unknown watchlist mode",
+			config:  "type: watchlist\nentries: [John Doe]\nmode: phonetic",
+			wantErr: `rule 0 (watchlist): mode: unknown mode "phonetic"`,
+		},
+		{
+			name:    "negative decimal",
+			config:  "type: zscore\nmin_history: 10\nk: 3\nmin_amount: -5",
+			wantErr: "rule 0 (zscore): min_amount: -5 is negative",
+		},
+		{
+			name:    "unparsable window",
+			config:  "type: repeat_counterparty\nwindow: daily\nmax_count: 5",
+			wantErr: "rule 0 (repeat_counterparty): window: time: invalid duration",
+		},
+		{
+			name:    "unknown field",
+			config:  "type: funnel\nwindow: 24h\nmax_sender: 10",
+			wantErr: "field max_sender not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := "rules:\n  - " + strings.ReplaceAll(tt.config, "\n", "\n    ") + "\n"
+			_, err := LoadRulesConfig(strings.NewReader(config))
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
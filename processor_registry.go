@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProcessorFactory builds a processor from the parameters of a rules config entry, every key of the
+// entry but type and severity. It validates params, naming the offending one in its error, e.g.
+// "periods[1].duration: time: missing unit in duration".
+type ProcessorFactory func(params map[string]any) (RuleProcessor, error)
+
+// ErrDuplicateProcessor is returned by RegisterProcessorFactory for a name already registered
+var ErrDuplicateProcessor = errors.New("processor factory already registered")
+
+var processorFactories = struct {
+	mu        sync.RWMutex
+	factories map[string]ProcessorFactory
+}{factories: make(map[string]ProcessorFactory)}
+
+// RegisterProcessorFactory makes rules config entries of type name build their processor with f, so
+// rules defined outside this package can be loaded by LoadRulesConfig and the amlrules command. The
+// built-in rules are already registered, RegisteredProcessors listing their names.
+func RegisterProcessorFactory(name string, f ProcessorFactory) error {
+	if name == "" || f == nil {
+		return errors.New("processor factory needs a name and a function")
+	}
+
+	processorFactories.mu.Lock()
+	defer processorFactories.mu.Unlock()
+	if _, exists := processorFactories.factories[name]; exists {
+		return fmt.Errorf("%w: %q", ErrDuplicateProcessor, name)
+	}
+	processorFactories.factories[name] = f
+
+	return nil
+}
+
+// RegisteredProcessors returns the names of the registered processor factories in sorted order
+func RegisteredProcessors() []string {
+	processorFactories.mu.RLock()
+	defer processorFactories.mu.RUnlock()
+
+	return slices.Sorted(maps.Keys(processorFactories.factories))
+}
+
+// NewProcessor builds a processor with the factory registered under name
+func NewProcessor(name string, params map[string]any) (RuleProcessor, error) {
+	processorFactories.mu.RLock()
+	f, ok := processorFactories.factories[name]
+	processorFactories.mu.RUnlock()
+	if !ok {
+		return nil, errors.New("unknown rule type")
+	}
+
+	return f(params)
+}
+
+// mustRegisterProcessorFactory registers a built-in factory, a duplicate being a programming error
+func mustRegisterProcessorFactory(name string, f ProcessorFactory) {
+	if err := RegisterProcessorFactory(name, f); err != nil {
+		panic(err)
+	}
+}
+
+// DecodeParams decodes the params of a ProcessorFactory into the struct pointed to by into, whose
+// fields are matched by their yaml tags, rejecting params into has no field for
+func DecodeParams(params map[string]any, into any) error {
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(into); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// encodeParams is the reverse of DecodeParams, describing a processor's parameters as config params
+func encodeParams(from any) (map[string]any, error) {
+	data, err := yaml.Marshal(from)
+	if err != nil {
+		return nil, err
+	}
+
+	var params map[string]any
+	if err := yaml.Unmarshal(data, &params); err != nil {
+		return nil, err
+	}
+
+	return params, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// largeCashProcessor is a rule defined outside the built-in set, flagging cash transactions above Limit
+type largeCashProcessor struct {
+	Limit decimal.Decimal
+}
+
+func (p largeCashProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	for _, tx := range transactions {
+		if tx.Channel == ChannelCash && tx.Amount.GreaterThan(p.Limit) {
+			flaggedUsers[tx.UserID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// registerTestFactory registers f under name for the duration of the test
+func registerTestFactory(t *testing.T, name string, f ProcessorFactory) {
+	t.Helper()
+	require.NoError(t, RegisterProcessorFactory(name, f))
+	t.Cleanup(func() {
+		processorFactories.mu.Lock()
+		defer processorFactories.mu.Unlock()
+		delete(processorFactories.factories, name)
+	})
+}
+
+func newLargeCashRule(params map[string]any) (RuleProcessor, error) {
+	var p struct {
+		Limit string `yaml:"limit"`
+	}
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	limit, err := decimal.NewFromString(p.Limit)
+	if err != nil {
+		return nil, errors.New("limit: not a decimal")
+	}
+
+	return largeCashProcessor{Limit: limit}, nil
+}
+
+func TestRegisterProcessorFactory(t *testing.T) {
+	registerTestFactory(t, "large_cash", newLargeCashRule)
+	assert.Contains(t, RegisteredProcessors(), "large_cash")
+
+	config := `
+rules:
+  - type: large_cash
+    limit: "5000"
+    severity: high
+  - type: country_blacklist
+    countries: [IR]
+`
+	engine, err := LoadRulesConfig(strings.NewReader(config))
+	require.NoError(t, err)
+	assert.NotEmpty(t, engine.ConfigVersion(), "configs with custom rules are versioned too")
+
+	cash, card := uuid.New(), uuid.New()
+	result, err := engine.Evaluate(context.Background(), []Transaction{
+		{TransactionID: uuid.New(), UserID: cash, Amount: decimal.NewFromInt(6000), Channel: ChannelCash, CreatedAt: time.Now()},
+		{TransactionID: uuid.New(), UserID: card, Amount: decimal.NewFromInt(6000), Channel: ChannelCard, CreatedAt: time.Now()},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Alerts, 1)
+	assert.Equal(t, cash, result.Alerts[0].UserID)
+	assert.Equal(t, "largeCashProcessor", result.Alerts[0].RuleName)
+	assert.Equal(t, SeverityHigh, result.Alerts[0].Severity)
+
+	changed, err := LoadRulesConfig(strings.NewReader(strings.Replace(config, `"5000"`, `"7000"`, 1)))
+	require.NoError(t, err)
+	assert.NotEqual(t, engine.ConfigVersion(), changed.ConfigVersion())
+
+	_, err = LoadRulesConfig(strings.NewReader("rules:\n  - type: large_cash\n    limit: lots\n"))
+	assert.EqualError(t, err, "rules config: rule 0 (large_cash): limit: not a decimal")
+	_, err = LoadRulesConfig(strings.NewReader("rules:\n  - type: large_cash\n    limit: 1\n    currency: EUR\n"))
+	assert.ErrorContains(t, err, "rule 0 (large_cash): ")
+	assert.ErrorContains(t, err, "field currency not found")
+
+	assert.ErrorIs(t, DumpRulesConfig(&bytes.Buffer{}, engine), ErrUnsupportedRule)
+}
+
+func TestRegisterProcessorFactory_Errors(t *testing.T) {
+	for _, name := range []string{"velocity", "amount_threshold", "country_blacklist", "expression"} {
+		err := RegisterProcessorFactory(name, newLargeCashRule)
+		assert.ErrorIs(t, err, ErrDuplicateProcessor, name)
+	}
+
+	registerTestFactory(t, "large_cash", newLargeCashRule)
+	assert.ErrorIs(t, RegisterProcessorFactory("large_cash", newLargeCashRule), ErrDuplicateProcessor)
+	assert.Error(t, RegisterProcessorFactory("", newLargeCashRule))
+	assert.Error(t, RegisterProcessorFactory("no_factory", nil))
+	assert.NotContains(t, RegisteredProcessors(), "no_factory")
+}
+
+func TestNewProcessor(t *testing.T) {
+	processor, err := NewProcessor("velocity", map[string]any{
+		"periods": []any{map[string]any{"duration": "24h", "threshold": 2}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(24*time.Hour, 2)}), processor)
+
+	_, err = NewProcessor("velocity", nil)
+	assert.EqualError(t, err, "periods: none configured")
+	_, err = NewProcessor("teleport", nil)
+	assert.EqualError(t, err, "unknown rule type")
+}
+
+func TestDumpRulesConfig_Expression(t *testing.T) {
+	config := "rules:\n  - type: expression\n    expression: amount >= 9000 && amount < 10000\n    min_count: 3\n    window: 168h\n"
+	engine, err := LoadRulesConfig(strings.NewReader(config))
+	require.NoError(t, err)
+
+	var dumped bytes.Buffer
+	require.NoError(t, DumpRulesConfig(&dumped, engine))
+	reloaded, err := LoadRulesConfig(&dumped)
+	require.NoError(t, err)
+
+	rules, _ := reloaded.currentRules()
+	require.Len(t, rules, 1)
+	processor := rules[0].processor.(ExpressionProcessor)
+	assert.Equal(t, "amount >= 9000 && amount < 10000", processor.Expression())
+	assert.Equal(t, 3, processor.MinCount)
+	assert.Equal(t, week, processor.Window)
+	assert.Equal(t, engine.ConfigVersion(), reloaded.ConfigVersion())
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultProgressInterval is how often WithProgress reports unless set with WithProgressInterval
+const DefaultProgressInterval = time.Second
+
+// ProgressUpdate is a snapshot of how far a run has gone. Rules go through the batch one after the
+// other, so Transactions and Users count a transaction or user once per rule done with it, reaching
+// TotalTransactions and TotalUsers, the batch's counts times its rules, when the run completes.
+// Runs over several batches, such as EvaluateSource, EvaluateRange and ShardedEvaluate, add each
+// batch to the totals as it is grouped, so totals grow as the run goes.
+type ProgressUpdate struct {
+	Transactions      int
+	TotalTransactions int
+	Users             int
+	TotalUsers        int
+	// RulesCompleted counts rule runs over a batch, TotalRules being the rules times the batches
+	RulesCompleted int
+	TotalRules     int
+	Elapsed        time.Duration
+	// ETA is the time left extrapolated from Elapsed and the transactions done, zero until some are
+	ETA time.Duration
+}
+
+// WithProgress reports the progress of every Evaluate, EvaluateAlerts, EvaluateSource, EvaluateRange
+// and ShardedEvaluate call to callback, every DefaultProgressInterval or WithProgressInterval, and
+// once more when the call completes with the final counts. callback is never called from more than
+// one goroutine at a time and no longer once the call has returned.
+func WithProgress(callback func(ProgressUpdate)) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.progress.callback = callback
+	}
+}
+
+// WithProgressInterval sets how often WithProgress reports
+func WithProgressInterval(interval time.Duration) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.progress.interval = interval
+	}
+}
+
+// ReportUserProgress tells the run processing ctx that a processor is done with one user holding
+// transactions transactions. Processors iterating per user call it between users, so runs report
+// progress while the rule is running; the others are accounted for when they return. It does nothing
+// outside of a run reporting its progress.
+func ReportUserProgress(ctx context.Context, transactions int) {
+	ruleProgressFrom(ctx).add(1, transactions)
+}
+
+// progressOptions are set by WithProgress and WithProgressInterval
+type progressOptions struct {
+	callback func(ProgressUpdate)
+	interval time.Duration
+}
+
+type progressKey struct{}
+
+// progressTracker counts the progress of a run, from every goroutine of the run
+type progressTracker struct {
+	started           time.Time
+	transactions      atomic.Int64
+	totalTransactions atomic.Int64
+	users             atomic.Int64
+	totalUsers        atomic.Int64
+	rules             atomic.Int64
+	totalRules        atomic.Int64
+}
+
+// withProgress starts reporting the progress of a run to the engine's callback, returning the
+// context to run under and a stop function reporting the final update. Without a callback, or
+// within a run already reporting, ctx is returned as is and stop does nothing.
+func (r *RuleEngine) withProgress(ctx context.Context) (context.Context, func()) {
+	if r.progress.callback == nil || progressTrackerFrom(ctx) != nil {
+		return ctx, func() {}
+	}
+
+	tracker := &progressTracker{started: time.Now()}
+	interval := r.progress.interval
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				r.progress.callback(tracker.snapshot())
+			}
+		}
+	}()
+
+	return context.WithValue(ctx, progressKey{}, tracker), func() {
+		close(done)
+		wg.Wait()
+		r.progress.callback(tracker.snapshot())
+	}
+}
+
+func progressTrackerFrom(ctx context.Context) *progressTracker {
+	tracker, _ := ctx.Value(progressKey{}).(*progressTracker)
+	return tracker
+}
+
+// expect adds a batch of transactions and users that rules go through to the totals
+func (t *progressTracker) expect(transactions, users, rules int) {
+	if t == nil {
+		return
+	}
+
+	t.totalTransactions.Add(int64(transactions * rules))
+	t.totalUsers.Add(int64(users * rules))
+	t.totalRules.Add(int64(rules))
+}
+
+func (t *progressTracker) snapshot() ProgressUpdate {
+	update := ProgressUpdate{
+		Transactions:      int(t.transactions.Load()),
+		TotalTransactions: int(t.totalTransactions.Load()),
+		Users:             int(t.users.Load()),
+		TotalUsers:        int(t.totalUsers.Load()),
+		RulesCompleted:    int(t.rules.Load()),
+		TotalRules:        int(t.totalRules.Load()),
+		Elapsed:           time.Since(t.started),
+	}
+	if update.Transactions > 0 && update.Transactions < update.TotalTransactions {
+		remaining := float64(update.TotalTransactions-update.Transactions) / float64(update.Transactions)
+		update.ETA = time.Duration(float64(update.Elapsed) * remaining)
+	}
+
+	return update
+}
+
+type ruleProgressKey struct{}
+
+// ruleProgress counts one rule's progress through a batch, never past the batch's counts
+type ruleProgress struct {
+	tracker           *progressTracker
+	totalTransactions int64
+	totalUsers        int64
+	transactions      atomic.Int64
+	users             atomic.Int64
+}
+
+// startRule returns the context a rule runs under to report its progress through grouped, nil
+// when the run does not report progress
+func startRule(ctx context.Context, grouped GroupedTransactions) (context.Context, *ruleProgress) {
+	tracker := progressTrackerFrom(ctx)
+	if tracker == nil {
+		return ctx, nil
+	}
+
+	progress := &ruleProgress{tracker: tracker, totalTransactions: int64(grouped.Len()), totalUsers: int64(len(grouped.Users()))}
+	return context.WithValue(ctx, ruleProgressKey{}, progress), progress
+}
+
+func ruleProgressFrom(ctx context.Context) *ruleProgress {
+	progress, _ := ctx.Value(ruleProgressKey{}).(*ruleProgress)
+	return progress
+}
+
+// add counts users and transactions done, as much of them as the batch has left
+func (p *ruleProgress) add(users, transactions int) {
+	if p == nil {
+		return
+	}
+
+	p.tracker.users.Add(clampedAdd(&p.users, int64(users), p.totalUsers))
+	p.tracker.transactions.Add(clampedAdd(&p.transactions, int64(transactions), p.totalTransactions))
+}
+
+// complete counts whatever of the batch the rule did not report and the rule itself
+func (p *ruleProgress) complete() {
+	if p == nil {
+		return
+	}
+
+	p.add(int(p.totalUsers), int(p.totalTransactions))
+	p.tracker.rules.Add(1)
+}
+
+// clampedAdd adds n to counter and returns how much of n fits below limit
+func clampedAdd(counter *atomic.Int64, n, limit int64) int64 {
+	after := counter.Add(n)
+	return max(min(n, limit-(after-n)), 0)
+}
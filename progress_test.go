@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// progressRecorder records every update, failing the test if two calls ever overlap
+type progressRecorder struct {
+	t       *testing.T
+	calling atomic.Bool
+	mu      sync.Mutex
+	updates []ProgressUpdate
+}
+
+func (p *progressRecorder) record(update ProgressUpdate) {
+	assert.True(p.t, p.calling.CompareAndSwap(false, true), "progress callbacks overlap")
+	defer p.calling.Store(false)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.updates = append(p.updates, update)
+}
+
+func (p *progressRecorder) recorded() []ProgressUpdate {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]ProgressUpdate(nil), p.updates...)
+}
+
+// assertProgress checks updates only grow and the last one is complete
+func assertProgress(t *testing.T, updates []ProgressUpdate, transactions, users, rules int) {
+	t.Helper()
+	require.NotEmpty(t, updates)
+	for i := 1; i < len(updates); i++ {
+		prev, next := updates[i-1], updates[i]
+		assert.LessOrEqual(t, prev.Transactions, next.Transactions)
+		assert.LessOrEqual(t, prev.Users, next.Users)
+		assert.LessOrEqual(t, prev.RulesCompleted, next.RulesCompleted)
+		assert.LessOrEqual(t, prev.Elapsed, next.Elapsed)
+	}
+
+	last := updates[len(updates)-1]
+	assert.Equal(t, transactions*rules, last.TotalTransactions)
+	assert.Equal(t, last.TotalTransactions, last.Transactions)
+	assert.Equal(t, users*rules, last.TotalUsers)
+	assert.Equal(t, last.TotalUsers, last.Users)
+	assert.Equal(t, last.TotalRules, last.RulesCompleted)
+	assert.Zero(t, last.ETA)
+}
+
+// slowUserProcessor visits users one by one, reporting each and pausing now and then
+type slowUserProcessor struct{}
+
+func (slowUserProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	grouped := GroupByUser(transactions)
+	for i, userID := range grouped.Users() {
+		if i%100 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		ReportUserProgress(ctx, len(grouped.ForUser(userID)))
+	}
+	return map[uuid.UUID]struct{}{}
+}
+
+func TestRuleEngine_WithProgress(t *testing.T) {
+	generated := NewTransactionGenerator(WithUserCount(3000), WithTransactionsPerUser(PoissonCount(6))).Generate()
+	users := len(GroupByUser(generated.Transactions).Users())
+	rules := []RuleProcessor{
+		slowUserProcessor{},
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(24*time.Hour, 5)}),
+		NewCountryBlackListProcessor("IR"),
+		NewWorkerVelocityProcessor([]VelocityPeriod{NewVelocityPeriod(week, 20)}, 3),
+	}
+
+	for _, workers := range []int{1, 3} {
+		recorder := &progressRecorder{t: t}
+		engine := NewRuleEngine(rules, WithProgress(recorder.record), WithProgressInterval(time.Millisecond), WithRuleWorkers(workers))
+
+		_, err := engine.Evaluate(context.Background(), generated.Transactions)
+		require.NoError(t, err)
+
+		updates := recorder.recorded()
+		assertProgress(t, updates, len(generated.Transactions), users, len(rules))
+		assert.Equal(t, len(rules), updates[len(updates)-1].TotalRules)
+		assert.True(t, slices.ContainsFunc(updates, func(update ProgressUpdate) bool {
+			return update.Users > 0 && update.Users < update.TotalUsers && update.ETA > 0
+		}), "updates are reported while rules run, %d workers", workers)
+
+		time.Sleep(5 * time.Millisecond)
+		assert.Len(t, recorder.recorded(), len(updates), "no update once the call returned")
+	}
+}
+
+func TestRuleEngine_WithProgress_Batches(t *testing.T) {
+	transactions := blacklistedTransactions(250)
+	rules := []RuleProcessor{NewCountryBlackListProcessor("IR"), NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(hour, 3)})}
+
+	t.Run("source", func(t *testing.T) {
+		recorder := &progressRecorder{t: t}
+		engine := NewRuleEngine(rules, WithProgress(recorder.record))
+		require.NoError(t, engine.EvaluateSource(context.Background(), NewSliceSource(transactions), 100, time.Hour))
+
+		updates := recorder.recorded()
+		assertProgress(t, updates, len(transactions), len(transactions), len(rules))
+		assert.Equal(t, 3*len(rules), updates[len(updates)-1].TotalRules, "one run of each rule per batch")
+	})
+
+	t.Run("sharded", func(t *testing.T) {
+		recorder := &progressRecorder{t: t}
+		engine := NewRuleEngine(rules, WithProgress(recorder.record), WithShardWorkers(4))
+		_, err := engine.ShardedEvaluate(context.Background(), NewSliceSource(transactions), 8)
+		require.NoError(t, err)
+
+		assertProgress(t, recorder.recorded(), len(transactions), len(transactions), len(rules))
+	})
+
+	t.Run("without callback", func(t *testing.T) {
+		ctx, stop := NewRuleEngine(rules).withProgress(context.Background())
+		defer stop()
+		assert.Nil(t, progressTrackerFrom(ctx))
+		ReportUserProgress(ctx, 3)
+	})
+}
+
+func TestRuleProgress_Clamped(t *testing.T) {
+	tracker := &progressTracker{started: time.Now()}
+	tracker.expect(10, 4, 1)
+	progress := &ruleProgress{tracker: tracker, totalTransactions: 10, totalUsers: 4}
+
+	progress.add(3, 8)
+	progress.add(3, 8)
+	update := tracker.snapshot()
+	assert.Equal(t, 4, update.Users, "a rule never reports more than its batch")
+	assert.Equal(t, 10, update.Transactions)
+
+	progress.complete()
+	update = tracker.snapshot()
+	assert.Equal(t, 4, update.Users)
+	assert.Equal(t, 1, update.RulesCompleted)
+}
+
+func TestStatefulVelocityProcessor_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	processor := NewStatefulVelocityProcessor([]VelocityPeriod{NewVelocityPeriod(hour, 1)})
+	_, err := processor.ProcessContext(ctx, blacklistedTransactions(5))
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,30 @@
+package prommetrics_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"aml_rule_engine/prommetrics"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// The sink is registered with a registry served on /metrics, then passed to the engine:
+//
+//	engine := NewRuleEngine(processors, WithMetrics(sink))
+func ExampleNew() {
+	sink := prommetrics.New("aml")
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(sink)
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	sink.ObserveBatchSize(500)
+	families, _ := registry.Gather()
+	for _, family := range families {
+		fmt.Println(family.GetName())
+	}
+	// Output:
+	// aml_batch_size
+}
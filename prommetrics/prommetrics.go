@@ -0,0 +1,83 @@
+// Package prommetrics exposes the metrics of a rule engine to Prometheus. A *Sink is passed to the
+// engine with WithMetrics and registered with a prometheus.Registerer as a Collector; its methods
+// only take standard types, so the engine does not depend on the Prometheus client.
+package prommetrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Sink records the engine's metrics as Prometheus histograms and counters:
+//
+//	<namespace>_rule_duration_seconds{rule}  histogram of processor run times
+//	<namespace>_flagged_users_total{rule}    users flagged per rule
+//	<namespace>_rule_errors_total{rule}      runs in which the rule failed
+//	<namespace>_batch_size                   histogram of evaluated batch sizes
+type Sink struct {
+	ruleDuration *prometheus.HistogramVec
+	flagged      *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	batchSize    prometheus.Histogram
+}
+
+// New creates a Sink whose metric names are prefixed with namespace, e.g. "aml"
+func New(namespace string) *Sink {
+	return &Sink{
+		ruleDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "rule_duration_seconds",
+			Help:      "Time a rule's processor took over one batch.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"rule"}),
+		flagged: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "flagged_users_total",
+			Help:      "Users flagged by a rule.",
+		}, []string{"rule"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rule_errors_total",
+			Help:      "Batches in which a rule failed.",
+		}, []string{"rule"}),
+		batchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "batch_size",
+			Help:      "Transactions per evaluated batch.",
+			Buckets:   prometheus.ExponentialBuckets(1, 10, 8),
+		}),
+	}
+}
+
+func (s *Sink) ObserveRuleDuration(rule string, d time.Duration) {
+	s.ruleDuration.WithLabelValues(rule).Observe(d.Seconds())
+}
+
+func (s *Sink) IncFlagged(rule string, n int) {
+	s.flagged.WithLabelValues(rule).Add(float64(n))
+}
+
+func (s *Sink) IncErrors(rule string) {
+	s.errors.WithLabelValues(rule).Inc()
+}
+
+func (s *Sink) ObserveBatchSize(n int) {
+	s.batchSize.Observe(float64(n))
+}
+
+// Describe implements prometheus.Collector
+func (s *Sink) Describe(ch chan<- *prometheus.Desc) {
+	s.ruleDuration.Describe(ch)
+	s.flagged.Describe(ch)
+	s.errors.Describe(ch)
+	s.batchSize.Describe(ch)
+}
+
+// Collect implements prometheus.Collector
+func (s *Sink) Collect(ch chan<- prometheus.Metric) {
+	s.ruleDuration.Collect(ch)
+	s.flagged.Collect(ch)
+	s.errors.Collect(ch)
+	s.batchSize.Collect(ch)
+}
@@ -0,0 +1,40 @@
+package prommetrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSink(t *testing.T) {
+	sink := New("aml")
+	sink.ObserveBatchSize(120)
+	sink.ObserveRuleDuration("VelocityProcessor", 3*time.Millisecond)
+	sink.ObserveRuleDuration("CountryBlackListProcessor", time.Millisecond)
+	sink.IncFlagged("VelocityProcessor", 4)
+	sink.IncFlagged("VelocityProcessor", 1)
+	sink.IncFlagged("CountryBlackListProcessor", 0)
+	sink.IncErrors("VelocityProcessor")
+
+	registry := prometheus.NewPedanticRegistry()
+	require.NoError(t, registry.Register(sink))
+
+	err := testutil.GatherAndCompare(registry, strings.NewReader(`
+# HELP aml_flagged_users_total Users flagged by a rule.
+# TYPE aml_flagged_users_total counter
+aml_flagged_users_total{rule="CountryBlackListProcessor"} 0
+aml_flagged_users_total{rule="VelocityProcessor"} 5
+# HELP aml_rule_errors_total Batches in which a rule failed.
+# TYPE aml_rule_errors_total counter
+aml_rule_errors_total{rule="VelocityProcessor"} 1
+`), "aml_flagged_users_total", "aml_rule_errors_total")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, testutil.CollectAndCount(sink, "aml_rule_duration_seconds"))
+	assert.Equal(t, 1, testutil.CollectAndCount(sink, "aml_batch_size"))
+}
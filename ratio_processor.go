@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RatioProcessor flags users for whom more than Ratio of their recent transactions are individually
+// risky, rather than flagging on a single risky transaction
+type RatioProcessor struct {
+	// Risky reports whether a single transaction looks risky, e.g. a large amount or an off-hours time
+	Risky func(Transaction) bool
+	// Window is the period ending at the user's latest transaction that is evaluated, zero evaluates the whole batch
+	Window time.Duration
+	// MinTransactions is the number of transactions within Window a user needs to be evaluated
+	MinTransactions int
+	Ratio           float64
+}
+
+func NewRatioProcessor(risky func(Transaction) bool, window time.Duration, minTransactions int, ratio float64) RatioProcessor {
+	return RatioProcessor{
+		Risky:           risky,
+		Window:          window,
+		MinTransactions: minTransactions,
+		Ratio:           ratio,
+	}
+}
+
+func (r RatioProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, velocityOptions{}, nil) {
+		for userID, txs := range userTransactions {
+			hits, total := r.countRecent(txs)
+			if total >= r.MinTransactions && total > 0 && float64(hits)/float64(total) > r.Ratio {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// countRecent counts the risky and total transactions within Window of the latest one
+func (r RatioProcessor) countRecent(txs []Transaction) (hits, total int) {
+	latest := txs[0].CreatedAt
+	for _, tx := range txs {
+		if tx.CreatedAt.After(latest) {
+			latest = tx.CreatedAt
+		}
+	}
+
+	for _, tx := range txs {
+		if r.Window > 0 && latest.Sub(tx.CreatedAt) > r.Window {
+			continue
+		}
+
+		total++
+		if r.Risky(tx) {
+			hits++
+		}
+	}
+
+	return hits, total
+}
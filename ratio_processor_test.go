@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRatioProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	mix := func(risky, total int) []Transaction {
+		transactions := make([]Transaction, total)
+		for i := range transactions {
+			amount := int64(100)
+			if i < risky {
+				amount = 10001
+			}
+			transactions[i] = Transaction{UserID: userID, Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(time.Duration(i) * time.Hour)}
+		}
+		return transactions
+	}
+
+	// later returns n non-risky transactions a month after the mix ones
+	later := func(n int) []Transaction {
+		transactions := mix(0, n)
+		for i := range transactions {
+			transactions[i].CreatedAt = transactions[i].CreatedAt.Add(month)
+		}
+		return transactions
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{name: "4 of 10 risky", transactions: mix(4, 10), wantFlagged: true},
+		{name: "3 of 10 risky, at the ratio", transactions: mix(3, 10), wantFlagged: false},
+		{name: "2 of 10 risky", transactions: mix(2, 10), wantFlagged: false},
+		{name: "1 of 2 risky, below the minimum count", transactions: mix(1, 2), wantFlagged: false},
+		{
+			name:         "risky transactions outside the window",
+			transactions: append(mix(4, 4), later(6)...),
+			wantFlagged:  false,
+		},
+	}
+
+	processor := NewRatioProcessor(func(tx Transaction) bool {
+		return tx.Amount.GreaterThan(decimal.NewFromInt(10000))
+	}, week, 5, 0.3)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// The regression scenarios live in testdata/regression, each a pair of files named after it: the
+// <name>.rules.yaml config the engine is loaded from and the <name>.transactions.csv batch it
+// evaluates. The decisions are compared with <name>.golden.json, which -update writes, so a new
+// scenario is added by dropping its two files there and running the tests once with -update.
+const regressionDir = "testdata/regression"
+
+// regressionOutcome is the part of a run a refactor must not change, serialized deterministically
+type regressionOutcome struct {
+	Transactions int              `json:"transactions"`
+	Rules        []regressionRule `json:"rules"`
+}
+
+type regressionRule struct {
+	Name     string            `json:"name"`
+	Severity Severity          `json:"severity"`
+	Error    string            `json:"error,omitempty"`
+	Flagged  []regressionAlert `json:"flagged"`
+}
+
+type regressionAlert struct {
+	UserID   uuid.UUID         `json:"user_id"`
+	Evidence []uuid.UUID       `json:"evidence"`
+	Details  map[string]string `json:"details,omitempty"`
+}
+
+func TestRegressionScenarios(t *testing.T) {
+	configs, err := filepath.Glob(filepath.Join(regressionDir, "*.rules.yaml"))
+	require.NoError(t, err)
+	require.NotEmpty(t, configs)
+
+	for _, config := range configs {
+		name := strings.TrimSuffix(filepath.Base(config), ".rules.yaml")
+		t.Run(name, func(t *testing.T) {
+			got := runRegressionScenario(t, name)
+
+			data, err := json.MarshalIndent(got, "", "  ")
+			require.NoError(t, err)
+			path := filepath.Join(regressionDir, name+".golden.json")
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(path, append(data, '\n'), 0o644))
+			}
+
+			golden, err := os.ReadFile(path)
+			require.NoError(t, err, "run with -update to create the golden file")
+			var want regressionOutcome
+			require.NoError(t, json.Unmarshal(golden, &want))
+
+			if diff := diffRegressionOutcomes(want, got); diff != "" {
+				t.Errorf("decisions differ from %s (-golden +got):\n%s", path, diff)
+			}
+		})
+	}
+}
+
+// runRegressionScenario evaluates the scenario's transactions with its rules
+func runRegressionScenario(t *testing.T, name string) regressionOutcome {
+	t.Helper()
+
+	rules, err := os.Open(filepath.Join(regressionDir, name+".rules.yaml"))
+	require.NoError(t, err)
+	defer rules.Close()
+	engine, err := LoadRulesConfig(rules)
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(regressionDir, name+".transactions.csv"))
+	require.NoError(t, err)
+	transactions, err := LoadTransactionsCSV(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	// a rule failing is a decision too, recorded in the outcome rather than failing the test
+	result, _ := engine.Evaluate(context.Background(), transactions)
+
+	outcome := regressionOutcome{Transactions: result.TransactionCount}
+	index := make(map[string]int, len(result.Rules))
+	for _, rule := range result.Rules {
+		require.NotContains(t, index, rule.Name, "the rules of a scenario must have distinct names")
+		index[rule.Name] = len(outcome.Rules)
+		outcome.Rules = append(outcome.Rules, regressionRule{Name: rule.Name, Severity: rule.Severity, Error: rule.Error, Flagged: []regressionAlert{}})
+	}
+	for _, alert := range result.Alerts {
+		flagged := regressionAlert{UserID: alert.UserID, Evidence: make([]uuid.UUID, len(alert.Evidence)), Details: alert.Details}
+		for i, tx := range alert.Evidence {
+			flagged.Evidence[i] = tx.TransactionID
+		}
+		rule := &outcome.Rules[index[alert.RuleName]]
+		rule.Flagged = append(rule.Flagged, flagged)
+	}
+	for _, rule := range outcome.Rules {
+		slices.SortFunc(rule.Flagged, func(a, b regressionAlert) int { return strings.Compare(a.UserID.String(), b.UserID.String()) })
+	}
+
+	return outcome
+}
+
+// diffRegressionOutcomes lists the decisions that changed, one line each, empty when none did
+func diffRegressionOutcomes(want, got regressionOutcome) string {
+	var diff []string
+	report := func(format string, args ...any) {
+		diff = append(diff, fmt.Sprintf(format, args...))
+	}
+
+	if want.Transactions != got.Transactions {
+		report("~ transactions: %d -> %d", want.Transactions, got.Transactions)
+	}
+
+	wantRules, gotRules := indexRegressionRules(want.Rules), indexRegressionRules(got.Rules)
+	for _, name := range slices.Sorted(maps.Keys(wantRules)) {
+		if _, ok := gotRules[name]; !ok {
+			report("- rule %s, which flagged %d users", name, len(wantRules[name].Flagged))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(gotRules)) {
+		wantRule, ok := wantRules[name]
+		gotRule := gotRules[name]
+		if !ok {
+			report("+ rule %s, flagging %d users", name, len(gotRule.Flagged))
+			continue
+		}
+
+		if wantRule.Severity != gotRule.Severity {
+			report("~ %s: severity %s -> %s", name, wantRule.Severity, gotRule.Severity)
+		}
+		if wantRule.Error != gotRule.Error {
+			report("~ %s: error %q -> %q", name, wantRule.Error, gotRule.Error)
+		}
+
+		wantUsers, gotUsers := indexRegressionAlerts(wantRule.Flagged), indexRegressionAlerts(gotRule.Flagged)
+		for _, user := range slices.Sorted(maps.Keys(wantUsers)) {
+			if _, ok := gotUsers[user]; !ok {
+				report("- %s: user %s no longer flagged", name, user)
+			}
+		}
+		for _, user := range slices.Sorted(maps.Keys(gotUsers)) {
+			wantAlert, ok := wantUsers[user]
+			gotAlert := gotUsers[user]
+			if !ok {
+				report("+ %s: user %s newly flagged", name, user)
+				continue
+			}
+
+			if !slices.Equal(wantAlert.Evidence, gotAlert.Evidence) {
+				report("~ %s: user %s: evidence %v -> %v", name, user, wantAlert.Evidence, gotAlert.Evidence)
+			}
+			for _, key := range slices.Sorted(maps.Keys(joinKeys(wantAlert.Details, gotAlert.Details))) {
+				wantValue, wantOK := wantAlert.Details[key]
+				gotValue, gotOK := gotAlert.Details[key]
+				switch {
+				case !gotOK:
+					report("~ %s: user %s: details[%s] %q removed", name, user, key, wantValue)
+				case !wantOK:
+					report("~ %s: user %s: details[%s] %q added", name, user, key, gotValue)
+				case wantValue != gotValue:
+					report("~ %s: user %s: details[%s] %q -> %q", name, user, key, wantValue, gotValue)
+				}
+			}
+		}
+	}
+
+	return strings.Join(diff, "\n")
+}
+
+func indexRegressionRules(rules []regressionRule) map[string]regressionRule {
+	index := make(map[string]regressionRule, len(rules))
+	for _, rule := range rules {
+		index[rule.Name] = rule
+	}
+
+	return index
+}
+
+func indexRegressionAlerts(alerts []regressionAlert) map[string]regressionAlert {
+	index := make(map[string]regressionAlert, len(alerts))
+	for _, alert := range alerts {
+		index[alert.UserID.String()] = alert
+	}
+
+	return index
+}
+
+func joinKeys(a, b map[string]string) map[string]struct{} {
+	keys := make(map[string]struct{}, len(a)+len(b))
+	for key := range a {
+		keys[key] = struct{}{}
+	}
+	for key := range b {
+		keys[key] = struct{}{}
+	}
+
+	return keys
+}
+
+func TestDiffRegressionOutcomes(t *testing.T) {
+	first, second := uuid.MustParse("00000001-0000-4000-8000-000000000001"), uuid.MustParse("00000001-0000-4000-8000-000000000002")
+	tx1, tx2 := uuid.MustParse("00000000-0000-4000-8000-000000000001"), uuid.MustParse("00000000-0000-4000-8000-000000000002")
+
+	want := regressionOutcome{Transactions: 10, Rules: []regressionRule{
+		{Name: "VelocityProcessor", Severity: SeverityMedium, Flagged: []regressionAlert{
+			{UserID: first, Evidence: []uuid.UUID{tx1}, Details: map[string]string{"count": "3", "period": "daily"}},
+			{UserID: second, Evidence: []uuid.UUID{tx2}},
+		}},
+		{Name: "CountryBlackListProcessor", Severity: SeverityCritical, Flagged: []regressionAlert{{UserID: first}}},
+	}}
+	require.Empty(t, diffRegressionOutcomes(want, want))
+
+	got := regressionOutcome{Transactions: 10, Rules: []regressionRule{
+		{Name: "VelocityProcessor", Severity: SeverityHigh, Flagged: []regressionAlert{
+			{UserID: first, Evidence: []uuid.UUID{tx1, tx2}, Details: map[string]string{"count": "4", "window": "24h"}},
+		}},
+		{Name: "TransactionAmountProcessor", Severity: SeverityMedium, Flagged: []regressionAlert{{UserID: second}}},
+	}}
+
+	require.Equal(t, strings.Join([]string{
+		"- rule CountryBlackListProcessor, which flagged 1 users",
+		"+ rule TransactionAmountProcessor, flagging 1 users",
+		"~ VelocityProcessor: severity medium -> high",
+		"- VelocityProcessor: user 00000001-0000-4000-8000-000000000002 no longer flagged",
+		"~ VelocityProcessor: user 00000001-0000-4000-8000-000000000001: evidence [00000000-0000-4000-8000-000000000001] -> [00000000-0000-4000-8000-000000000001 00000000-0000-4000-8000-000000000002]",
+		`~ VelocityProcessor: user 00000001-0000-4000-8000-000000000001: details[count] "3" -> "4"`,
+		`~ VelocityProcessor: user 00000001-0000-4000-8000-000000000001: details[period] "daily" removed`,
+		`~ VelocityProcessor: user 00000001-0000-4000-8000-000000000001: details[window] "24h" added`,
+	}, "\n"), diffRegressionOutcomes(want, got))
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RepeatCounterpartyProcessor flags users paying the same counterparty more than MaxCount times
+// within Window, as in invoice fraud or layering loops
+type RepeatCounterpartyProcessor struct {
+	Window   time.Duration
+	MaxCount int
+}
+
+func NewRepeatCounterpartyProcessor(window time.Duration, maxCount int) RepeatCounterpartyProcessor {
+	return RepeatCounterpartyProcessor{
+		Window:   window,
+		MaxCount: maxCount,
+	}
+}
+
+// CounterpartyViolation is a velocity violation over a user's transactions with one counterparty
+type CounterpartyViolation struct {
+	CounterpartyID string
+	VelocityViolation
+}
+
+func (r RepeatCounterpartyProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID := range r.Violations(ctx, transactions) {
+		flaggedUsers[userID] = struct{}{}
+	}
+
+	return flaggedUsers
+}
+
+// Violations returns, per flagged user, one violation per counterparty paid too often, ordered by
+// CounterpartyID. Transactions without a CounterpartyID are ignored.
+func (r RepeatCounterpartyProcessor) Violations(_ context.Context, transactions []Transaction) map[uuid.UUID][]CounterpartyViolation {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return tx.CounterpartyID != ""
+	}}
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(r.Window, r.MaxCount)}, options)
+	violations := make(map[uuid.UUID][]CounterpartyViolation)
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			// sorting by counterparty then time leaves each counterparty's run ordered for the checker
+			slices.SortFunc(txs, func(a, b Transaction) int {
+				return cmp.Or(cmp.Compare(a.CounterpartyID, b.CounterpartyID), a.CreatedAt.Compare(b.CreatedAt))
+			})
+
+			for start := 0; start < len(txs); {
+				end := start + 1
+				for end < len(txs) && txs[end].CounterpartyID == txs[start].CounterpartyID {
+					end++
+				}
+
+				found, _ := checker.CheckUserDetailed(txs[start:end])
+				for _, violation := range found {
+					violations[userID] = append(violations[userID], CounterpartyViolation{
+						CounterpartyID:    txs[start].CounterpartyID,
+						VelocityViolation: violation,
+					})
+				}
+				start = end
+			}
+		}
+	}
+
+	return violations
+}
+
+func (r RepeatCounterpartyProcessor) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	violations := r.Violations(ctx, transactions)[userID]
+
+	var evidence []Transaction
+	counterparties := make([]string, len(violations))
+	for i, violation := range violations {
+		counterparties[i] = violation.CounterpartyID
+		for _, tx := range transactions {
+			if tx.CounterpartyID == violation.CounterpartyID &&
+				!tx.CreatedAt.Before(violation.WindowStart) && !tx.CreatedAt.After(violation.WindowEnd) {
+				evidence = append(evidence, tx)
+			}
+		}
+	}
+
+	return evidence, map[string]string{
+		"counterparties": strings.Join(counterparties, ", "),
+		"max_count":      strconv.Itoa(r.MaxCount),
+		"window":         formatDuration(r.Window),
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepeatCounterpartyProcessor_Process(t *testing.T) {
+	baseTime := time.Now()
+	userID1 := uuid.New()
+	userID2 := uuid.New()
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantUsers    []uuid.UUID
+	}{
+		{
+			name: "exactly at the threshold",
+			transactions: []Transaction{
+				{UserID: userID1, CounterpartyID: "supplier", CreatedAt: baseTime},
+				{UserID: userID1, CounterpartyID: "supplier", CreatedAt: baseTime.Add(time.Hour)},
+			},
+			wantUsers: []uuid.UUID{},
+		},
+		{
+			name: "over the threshold with one counterparty",
+			transactions: []Transaction{
+				{UserID: userID1, CounterpartyID: "supplier", CreatedAt: baseTime},
+				{UserID: userID1, CounterpartyID: "supplier", CreatedAt: baseTime.Add(time.Hour)},
+				{UserID: userID1, CounterpartyID: "supplier", CreatedAt: baseTime.Add(2 * time.Hour)},
+				{UserID: userID2, CounterpartyID: "supplier", CreatedAt: baseTime},
+			},
+			wantUsers: []uuid.UUID{userID1},
+		},
+		{
+			name: "spread across counterparties",
+			transactions: []Transaction{
+				{UserID: userID1, CounterpartyID: "supplier-a", CreatedAt: baseTime},
+				{UserID: userID1, CounterpartyID: "supplier-b", CreatedAt: baseTime.Add(time.Hour)},
+				{UserID: userID1, CounterpartyID: "supplier-a", CreatedAt: baseTime.Add(2 * time.Hour)},
+				{UserID: userID1, CounterpartyID: "supplier-b", CreatedAt: baseTime.Add(3 * time.Hour)},
+			},
+			wantUsers: []uuid.UUID{},
+		},
+		{
+			name: "window boundary is inclusive",
+			transactions: []Transaction{
+				{UserID: userID1, CounterpartyID: "supplier", CreatedAt: baseTime},
+				{UserID: userID1, CounterpartyID: "supplier", CreatedAt: baseTime.Add(3 * 24 * time.Hour)},
+				{UserID: userID1, CounterpartyID: "supplier", CreatedAt: baseTime.Add(week)},
+				{UserID: userID2, CounterpartyID: "supplier", CreatedAt: baseTime},
+				{UserID: userID2, CounterpartyID: "supplier", CreatedAt: baseTime.Add(3 * 24 * time.Hour)},
+				{UserID: userID2, CounterpartyID: "supplier", CreatedAt: baseTime.Add(week + time.Nanosecond)},
+			},
+			wantUsers: []uuid.UUID{userID1},
+		},
+		{
+			name: "empty counterparty is ignored",
+			transactions: []Transaction{
+				{UserID: userID1, CreatedAt: baseTime},
+				{UserID: userID1, CreatedAt: baseTime.Add(time.Hour)},
+				{UserID: userID1, CreatedAt: baseTime.Add(2 * time.Hour)},
+			},
+			wantUsers: []uuid.UUID{},
+		},
+	}
+
+	processor := NewRepeatCounterpartyProcessor(week, 2)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			assert.Equal(t, len(tt.wantUsers), len(flaggedUsers))
+			for _, wantUser := range tt.wantUsers {
+				assert.Contains(t, flaggedUsers, wantUser)
+			}
+		})
+	}
+}
+
+func TestRepeatCounterpartyProcessor_Violations(t *testing.T) {
+	baseTime := time.Now()
+	userID := uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, CounterpartyID: "supplier-b", CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: userID, CounterpartyID: "supplier-a", CreatedAt: baseTime},
+		{UserID: userID, CounterpartyID: "supplier-b", CreatedAt: baseTime},
+		{UserID: userID, CounterpartyID: "supplier-b", CreatedAt: baseTime.Add(time.Hour)},
+	}
+
+	violations := NewRepeatCounterpartyProcessor(week, 2).Violations(context.Background(), transactions)
+
+	require.Len(t, violations[userID], 1)
+	assert.Equal(t, "supplier-b", violations[userID][0].CounterpartyID)
+	assert.Equal(t, 3, violations[userID][0].Count)
+	assert.True(t, violations[userID][0].WindowStart.Equal(baseTime))
+	assert.True(t, violations[userID][0].WindowEnd.Equal(baseTime.Add(2*time.Hour)))
+}
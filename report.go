@@ -0,0 +1,105 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EvaluationResult is the outcome of one RuleEngine.Evaluate run
+type EvaluationResult struct {
+	RunID            uuid.UUID     `json:"run_id"`
+	StartedAt        time.Time     `json:"started_at"`
+	FinishedAt       time.Time     `json:"finished_at"`
+	TransactionCount int           `json:"transaction_count"`
+	Rules            []RuleSummary `json:"rules"`
+	Alerts           []Alert       `json:"alerts"`
+	Summary          Summary       `json:"summary"`
+	// ValidationErrors lists the checks failed by the batch when the engine validates it
+	ValidationErrors []ValidationError `json:"validation_errors,omitempty"`
+	// ConfigVersion identifies the rules config the run evaluated, as stamped on its alerts
+	ConfigVersion string `json:"config_version,omitempty"`
+}
+
+// Flagged returns the users flagged by any rule of the run, in canonical order
+func (r EvaluationResult) Flagged() FlaggedUsers {
+	userIDs := make([]uuid.UUID, len(r.Alerts))
+	for i, alert := range r.Alerts {
+		userIDs[i] = alert.UserID
+	}
+
+	return NewFlaggedUsers(userIDs...)
+}
+
+// RuleSummary describes how one registered rule fared during a run
+type RuleSummary struct {
+	Name         string   `json:"name"`
+	Severity     Severity `json:"severity"`
+	FlaggedUsers int      `json:"flagged_users"`
+	// Error holds the message of the error the rule failed with, if any
+	Error string `json:"error,omitempty"`
+}
+
+// WriteJSONReport writes result as a single indented JSON document. Rules are sorted by name and
+// alerts by user then rule name so identical runs produce identical reports. Amounts are written
+// as strings and timestamps as RFC3339Nano in UTC.
+func WriteJSONReport(w io.Writer, result EvaluationResult) error {
+	result.StartedAt = result.StartedAt.UTC()
+	result.FinishedAt = result.FinishedAt.UTC()
+	result.Rules = append([]RuleSummary{}, result.Rules...)
+	slices.SortStableFunc(result.Rules, func(a, b RuleSummary) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	result.Alerts = reportAlerts(result.Alerts)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(result)
+}
+
+// WriteNDJSONAlerts writes one alert per line, in the same order as WriteJSONReport
+func WriteNDJSONAlerts(w io.Writer, alerts []Alert) error {
+	encoder := json.NewEncoder(w)
+	for _, alert := range reportAlerts(alerts) {
+		if err := encoder.Encode(alert); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reportAlerts returns a copy of alerts sorted by user then rule name, with timestamps in UTC
+// and empty collections written as such rather than null
+func reportAlerts(alerts []Alert) []Alert {
+	sorted := make([]Alert, len(alerts))
+	for i, alert := range alerts {
+		alert.CreatedAt = alert.CreatedAt.UTC()
+		alert.Evidence = slices.Clone(alert.Evidence)
+		if alert.Evidence == nil {
+			alert.Evidence = []Transaction{}
+		}
+		for j := range alert.Evidence {
+			alert.Evidence[j].CreatedAt = alert.Evidence[j].CreatedAt.UTC()
+		}
+		if alert.Details == nil {
+			alert.Details = map[string]string{}
+		}
+		sorted[i] = alert
+	}
+
+	slices.SortStableFunc(sorted, func(a, b Alert) int {
+		return cmp.Or(
+			compareUserIDs(a.UserID, b.UserID),
+			strings.Compare(a.RuleName, b.RuleName),
+		)
+	})
+
+	return sorted
+}
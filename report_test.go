@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// assertGolden compares got with testdata/name, rewriting the file instead when run with -update
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		require.NoError(t, os.WriteFile(path, got, 0o644))
+	}
+
+	want, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(want), string(got))
+}
+
+// reportFixture returns a run with two users, one of them flagged by two rules, listed out of order
+func reportFixture() EvaluationResult {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		paris = time.FixedZone("CET", 3600)
+	}
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 123456789, time.UTC)
+	first := uuid.MustParse("1B4E28BA-2FA1-11D2-883F-0016D3CCA427")
+	second := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	largeTransfer := Transaction{
+		UserID:    second,
+		Amount:    decimal.RequireFromString("15000.50"),
+		Currency:  "EUR",
+		Country:   "FR",
+		CreatedAt: baseTime.In(paris),
+	}
+	blacklisted := Transaction{
+		UserID:      first,
+		Amount:      decimal.RequireFromString("0.10"),
+		Country:     "IR",
+		Description: "invoice, \"urgent\"",
+		CreatedAt:   baseTime.Add(time.Hour),
+	}
+
+	result := EvaluationResult{
+		RunID:            uuid.MustParse("00000000-0000-4000-8000-000000000001"),
+		StartedAt:        baseTime.Add(2 * time.Hour).In(paris),
+		FinishedAt:       baseTime.Add(2*time.Hour + time.Second),
+		TransactionCount: 3,
+		Rules: []RuleSummary{
+			{Name: "TransactionAmountProcessor", Severity: SeverityMedium, FlaggedUsers: 2},
+			{Name: "CountryBlackListProcessor", Severity: SeverityCritical, FlaggedUsers: 1},
+			{Name: "VelocityProcessor", Severity: SeverityMedium, Error: "unsorted transactions"},
+		},
+		Alerts: []Alert{
+			{
+				ID:        uuid.MustParse("00000000-0000-4000-8000-000000000002"),
+				UserID:    second,
+				RuleName:  "TransactionAmountProcessor",
+				Severity:  SeverityMedium,
+				CreatedAt: baseTime.Add(2 * time.Hour),
+				Evidence:  []Transaction{largeTransfer},
+				Details:   map[string]string{"transactions": "1", "threshold": "10000"},
+			},
+			{
+				ID:        uuid.MustParse("00000000-0000-4000-8000-000000000003"),
+				UserID:    first,
+				RuleName:  "TransactionAmountProcessor",
+				Severity:  SeverityMedium,
+				CreatedAt: baseTime.Add(2 * time.Hour),
+			},
+			{
+				ID:        uuid.MustParse("00000000-0000-4000-8000-000000000004"),
+				UserID:    first,
+				RuleName:  "CountryBlackListProcessor",
+				Severity:  SeverityCritical,
+				CreatedAt: baseTime.Add(2 * time.Hour),
+				Evidence:  []Transaction{blacklisted},
+				Details:   map[string]string{"countries": "IR"},
+			},
+		},
+	}
+	result.Summary = Summarize(result.Alerts, []Transaction{largeTransfer, blacklisted, {UserID: second}})
+	result.Summary.Duration = time.Second
+
+	return result
+}
+
+func TestWriteJSONReport(t *testing.T) {
+	result := reportFixture()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSONReport(&buf, result))
+	assertGolden(t, "report.golden.json", buf.Bytes())
+
+	assert.Equal(t, "TransactionAmountProcessor", result.Rules[0].Name, "the caller's result is left unsorted")
+}
+
+func TestWriteNDJSONAlerts(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteNDJSONAlerts(&buf, reportFixture().Alerts))
+	assertGolden(t, "alerts.golden.ndjson", buf.Bytes())
+
+	buf.Reset()
+	require.NoError(t, WriteNDJSONAlerts(&buf, nil))
+	assert.Empty(t, buf.String())
+}
+
+func TestRuleEngine_Evaluate(t *testing.T) {
+	userID := uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: time.Now()},
+		{UserID: userID, Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: time.Now().Add(-time.Hour)},
+	}
+
+	engine := NewRuleEngine([]RuleProcessor{
+		NewCountryBlackListProcessor("IR"),
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 1)}, WithStrictSortedInput()),
+	})
+
+	result, err := engine.Evaluate(context.Background(), transactions)
+	require.Error(t, err)
+
+	assert.Equal(t, 2, result.TransactionCount)
+	assert.False(t, result.FinishedAt.Before(result.StartedAt))
+	require.Len(t, result.Rules, 2)
+	assert.Equal(t, RuleSummary{Name: "CountryBlackListProcessor", Severity: SeverityCritical, FlaggedUsers: 1}, result.Rules[0])
+	assert.Equal(t, "VelocityProcessor", result.Rules[1].Name)
+	assert.NotEmpty(t, result.Rules[1].Error)
+	require.Len(t, result.Alerts, 1)
+	assert.Equal(t, userID, result.Alerts[0].UserID)
+	assert.Equal(t, map[string]int{"IR": 1}, result.Summary.CountryFlags)
+	assert.Equal(t, result.FinishedAt.Sub(result.StartedAt), result.Summary.Duration)
+}
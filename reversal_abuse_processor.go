@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ReversalAbuseProcessor flags users repeatedly getting spend reversed or charged back shortly after
+// making it, as when gaming limits or testing stolen cards. A reversal is a negative Amount, or a
+// credit with a refunded or reversed status; spend is any other transaction that is not a credit.
+type ReversalAbuseProcessor struct {
+	Window time.Duration
+	// MinReversals is the number of reversals within Window, each paired with prior spend, needed to flag
+	MinReversals int
+	// MinRatio is the share of the spend within Window that must have been reversed, e.g. 0.5
+	MinRatio decimal.Decimal
+}
+
+func NewReversalAbuseProcessor(window time.Duration, minReversals int, minRatio decimal.Decimal) ReversalAbuseProcessor {
+	return ReversalAbuseProcessor{
+		Window:       window,
+		MinReversals: minReversals,
+		MinRatio:     minRatio,
+	}
+}
+
+func isReversal(tx Transaction) bool {
+	if tx.Amount.IsNegative() {
+		return true
+	}
+
+	return tx.Direction == DirectionCredit && (tx.Status == StatusRefunded || tx.Status == StatusReversed)
+}
+
+func isSpend(tx Transaction) bool {
+	return !isReversal(tx) && tx.Direction != DirectionCredit
+}
+
+func (r ReversalAbuseProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return isReversal(tx) || isSpend(tx)
+	}}
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			sortByCreatedAt(txs)
+
+			if r.isAbusive(txs) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// isAbusive pairs, within the Window ending at each reversal, every reversal with an earlier unpaired
+// spend. Windows end at reversals and extend fully back so a window cannot leave out earlier spend.
+// Time complexity: O(n * w) where w is the number of transactions within a window
+func (r ReversalAbuseProcessor) isAbusive(txs []Transaction) bool {
+	left := 0
+
+	for right, end := range txs {
+		for end.CreatedAt.Sub(txs[left].CreatedAt) > r.Window {
+			left++
+		}
+		if !isReversal(end) {
+			continue
+		}
+
+		spends, unpaired, paired := 0, 0, 0
+		for _, tx := range txs[left : right+1] {
+			switch {
+			case isSpend(tx):
+				spends++
+				unpaired++
+			case unpaired > 0:
+				paired++
+				unpaired--
+			}
+		}
+
+		if paired == 0 {
+			continue
+		}
+
+		ratio := decimal.NewFromInt(int64(paired)).Div(decimal.NewFromInt(int64(spends)))
+		if paired >= r.MinReversals && ratio.GreaterThanOrEqual(r.MinRatio) {
+			return true
+		}
+	}
+
+	return false
+}
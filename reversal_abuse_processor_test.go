@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReversalAbuseProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	purchase := func(hours int) Transaction {
+		return Transaction{UserID: userID, Amount: decimal.NewFromInt(40), CreatedAt: baseTime.Add(time.Duration(hours) * time.Hour)}
+	}
+	reversal := func(hours int) Transaction {
+		return Transaction{UserID: userID, Amount: decimal.NewFromInt(-40), CreatedAt: baseTime.Add(time.Duration(hours) * time.Hour)}
+	}
+	chargeback := func(hours int) Transaction {
+		return Transaction{UserID: userID, Amount: decimal.NewFromInt(40), Direction: DirectionCredit, Status: StatusReversed, CreatedAt: baseTime.Add(time.Duration(hours) * time.Hour)}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name: "5 reversals against 6 purchases in a week",
+			transactions: []Transaction{
+				purchase(0), reversal(2), purchase(24), reversal(26), purchase(48), chargeback(50),
+				purchase(72), reversal(74), purchase(96), chargeback(98), purchase(120),
+			},
+			wantFlagged: true,
+		},
+		{
+			name:         "a single reversal",
+			transactions: []Transaction{purchase(0), reversal(2), purchase(24), purchase(48)},
+			wantFlagged:  false,
+		},
+		{
+			name: "reversals without prior spend are not paired",
+			transactions: []Transaction{
+				reversal(0), reversal(1), reversal(2), reversal(3), purchase(4), purchase(5),
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "reversals of spend made outside the window",
+			transactions: []Transaction{
+				purchase(0), purchase(1), purchase(2), purchase(3), reversal(10 * 24), reversal(10*24 + 1), reversal(10*24 + 2),
+			},
+			wantFlagged: false,
+		},
+		{
+			name: "reversed share below the ratio",
+			transactions: []Transaction{
+				purchase(0), purchase(1), purchase(2), purchase(3), purchase(4), purchase(5), purchase(6), purchase(7),
+				reversal(8), reversal(9), reversal(10),
+			},
+			wantFlagged: false,
+		},
+	}
+
+	processor := NewReversalAbuseProcessor(week, 3, decimal.RequireFromString("0.5"))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestNegativeAmounts_IgnoredByAmountRules(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	// a large reversal must neither be flagged on its own nor offset the spend around it
+	transactions := []Transaction{
+		{UserID: userID, Amount: decimal.NewFromInt(-50000), CreatedAt: baseTime},
+		{UserID: userID, Amount: decimal.NewFromInt(6000), CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID, Amount: decimal.NewFromInt(6000), CreatedAt: baseTime.Add(2 * time.Hour)},
+	}
+
+	assert.Empty(t, TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000)}.Process(context.Background(), transactions[:1]))
+
+	for _, rolling := range []bool{false, true} {
+		processor := DailyAggregateProcessor{Threshold: decimal.NewFromInt(10000), Rolling: rolling}
+		assert.Contains(t, processor.Process(context.Background(), append([]Transaction(nil), transactions...)), userID, "rolling %v", rolling)
+	}
+
+	assert.False(t, ExcludeReversals(transactions[0]))
+	assert.True(t, ExcludeReversals(transactions[1]))
+}
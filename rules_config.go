@@ -0,0 +1,388 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnsupportedRule is returned by DumpRulesConfig for a registered processor the config cannot describe
+var ErrUnsupportedRule = errors.New("rule cannot be described by a rules config")
+
+// rulesConfig is the document read by LoadRulesConfig, listing rules by type, in YAML or JSON:
+//
+//	rules:
+//	  - type: velocity
+//	    periods:
+//	      - {name: weekly, duration: 168h, threshold: 5}
+//	  - type: amount_threshold
+//	    threshold: "10000"
+//	    country_thresholds: {IR: "100"}
+//	  - type: country_blacklist
+//	    countries: [IR, KP]
+//	    severity: high
+//	  - type: expression
+//	    expression: amount >= 9000 && amount < 10000
+//	    min_count: 3
+//	    window: 168h
+//
+// Types resolve through the processor registry, which is passed the other keys of the entry as
+// params. Besides those above, every built-in processor built from plain values has a type, named
+// after it in snake case, e.g. structuring, dormancy or circular_flow; see processor_factories.go
+// for their params. Processors built from functions or providers, such as RatioProcessor,
+// NewAccountProcessor, NonBusinessDayProcessor and the combinators, are only available in code.
+// A rule without a severity gets the default of its type.
+type rulesConfig struct {
+	Rules []ruleConfig `yaml:"rules"`
+}
+
+type ruleConfig struct {
+	Type     string         `yaml:"type"`
+	Severity Severity       `yaml:"severity,omitempty"`
+	Params   map[string]any `yaml:",inline"`
+}
+
+// velocityParams are the params of a "velocity" rule
+type velocityParams struct {
+	Periods []periodConfig `yaml:"periods"`
+}
+
+type periodConfig struct {
+	Name      string `yaml:"name,omitempty"`
+	Duration  string `yaml:"duration"`
+	Threshold int    `yaml:"threshold"`
+}
+
+// amountThresholdParams are the params of an "amount_threshold" rule, amounts being decimal strings.
+// Threshold may be left out when CurrencyThresholds is set, transactions in other currencies then
+// being reported as unevaluated as with NewCurrencyAmountProcessor.
+type amountThresholdParams struct {
+	Threshold          string            `yaml:"threshold,omitempty"`
+	CountryThresholds  map[string]string `yaml:"country_thresholds,omitempty"`
+	CurrencyThresholds map[string]string `yaml:"currency_thresholds,omitempty"`
+}
+
+// countryBlacklistParams are the params of a "country_blacklist" rule
+type countryBlacklistParams struct {
+	Countries      []string `yaml:"countries"`
+	ExactCountries bool     `yaml:"exact_countries,omitempty"`
+}
+
+// expressionParams are the params of an "expression" rule, see NewExpressionProcessor
+type expressionParams struct {
+	Expression string `yaml:"expression"`
+	MinCount   int    `yaml:"min_count,omitempty"`
+	Window     string `yaml:"window,omitempty"`
+}
+
+func init() {
+	mustRegisterProcessorFactory("velocity", newVelocityRule)
+	mustRegisterProcessorFactory("amount_threshold", newAmountThresholdRule)
+	mustRegisterProcessorFactory("country_blacklist", newCountryBlacklistRule)
+	mustRegisterProcessorFactory("expression", newExpressionRule)
+}
+
+// LoadRulesConfig reads a YAML or JSON rules config and registers its rules, in order, on a new
+// engine configured with opts. Malformed entries fail the load with an error naming the entry by
+// position and type, e.g. "rule 2 (velocity): periods[0].duration: time: missing unit in duration".
+func LoadRulesConfig(r io.Reader, opts ...RuleEngineOption) (*RuleEngine, error) {
+	rules, version, err := loadRulesConfig(r)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := NewRuleEngine(nil, opts...)
+	engine.rules, engine.configVersion = rules, version
+
+	return engine, nil
+}
+
+// ReloadConfig replaces the engine's rules with those of a rules config, as LoadRulesConfig reads it.
+// The config is parsed and checked in full first, so a malformed one fails with the rules left as
+// they were. Runs already in flight finish with the rules they started with; runs starting after the
+// swap use the new ones and stamp the new config version on their alerts.
+func (r *RuleEngine) ReloadConfig(config io.Reader) error {
+	rules, version, err := loadRulesConfig(config)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules, r.configVersion = rules, version
+
+	return nil
+}
+
+// ConfigVersion identifies the rules config the engine runs, the first 16 hex digits of the SHA-256
+// of the config as DumpRulesConfig writes it, so reformatting a config file does not change its
+// version. It is empty when the rules were not loaded from a config.
+func (r *RuleEngine) ConfigVersion() string {
+	_, version := r.currentRules()
+	return version
+}
+
+// loadRulesConfig parses a rules config into rules and computes its version
+func loadRulesConfig(r io.Reader) ([]engineRule, string, error) {
+	decoder := yaml.NewDecoder(r)
+	decoder.KnownFields(true)
+
+	var config rulesConfig
+	if err := decoder.Decode(&config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, "", fmt.Errorf("rules config: %w", err)
+	}
+
+	rules := make([]engineRule, 0, len(config.Rules))
+	for i, rule := range config.Rules {
+		processor, err := NewProcessor(rule.Type, rule.Params)
+		if err != nil {
+			return nil, "", fmt.Errorf("rules config: rule %d (%s): %w", i, rule.Type, err)
+		}
+
+		severity := rule.Severity
+		if severity == "" {
+			severity = defaultSeverity(processor)
+		} else if !slices.Contains(severityRank, severity) {
+			return nil, "", fmt.Errorf("rules config: rule %d (%s): unknown severity %q", i, rule.Type, severity)
+		}
+		rules = append(rules, engineRule{processor: processor, severity: severity})
+	}
+
+	version, err := configVersion(config.Rules, rules)
+	if err != nil {
+		return nil, "", fmt.Errorf("rules config: %w", err)
+	}
+
+	return rules, version, nil
+}
+
+// configVersion hashes the rules as DumpRulesConfig describes them, or as they were written for
+// rules it cannot describe, such as those of factories registered outside this package
+func configVersion(entries []ruleConfig, rules []engineRule) (string, error) {
+	canonical := rulesConfig{Rules: make([]ruleConfig, len(rules))}
+	for i, rule := range rules {
+		entry, err := newRuleConfig(rule.processor)
+		if errors.Is(err, ErrUnsupportedRule) {
+			entry, err = entries[i], nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("rule %d (%s): %w", i, entries[i].Type, err)
+		}
+		entry.Severity = rule.severity
+		canonical.Rules[i] = entry
+	}
+
+	hash := sha256.New()
+	if err := yaml.NewEncoder(hash).Encode(canonical); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)[:8]), nil
+}
+
+// DumpRulesConfig writes the rules of engine as a YAML rules config that LoadRulesConfig reads back
+// into equivalent processors. Velocity options are not part of the config and are left out. Only
+// velocity, amount_threshold, country_blacklist and expression rules are described; any other
+// processor fails with ErrUnsupportedRule.
+func DumpRulesConfig(w io.Writer, engine *RuleEngine) error {
+	rules, _ := engine.currentRules()
+	return dumpRules(w, rules)
+}
+
+func dumpRules(w io.Writer, rules []engineRule) error {
+	config := rulesConfig{Rules: make([]ruleConfig, 0, len(rules))}
+	for i, rule := range rules {
+		entry, err := newRuleConfig(rule.processor)
+		if err != nil {
+			return fmt.Errorf("rule %d (%s): %w", i, ruleName(rule.processor), err)
+		}
+		entry.Severity = rule.severity
+		config.Rules = append(config.Rules, entry)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func newVelocityRule(params map[string]any) (RuleProcessor, error) {
+	var p velocityParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Periods) == 0 {
+		return nil, errors.New("periods: none configured")
+	}
+
+	periods, err := parsePeriods(p.Periods)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewVelocityValidator(periods), nil
+}
+
+func newAmountThresholdRule(params map[string]any) (RuleProcessor, error) {
+	var p amountThresholdParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	var threshold *decimal.Decimal
+	if p.Threshold != "" {
+		parsed, err := decimal.NewFromString(p.Threshold)
+		if err != nil {
+			return nil, fmt.Errorf("threshold: %w", err)
+		}
+		threshold = &parsed
+	}
+	countryThresholds, err := parseThresholds("country_thresholds", p.CountryThresholds)
+	if err != nil {
+		return nil, err
+	}
+	currencyThresholds, err := parseThresholds("currency_thresholds", p.CurrencyThresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	processor, err := NewCurrencyAmountProcessor(threshold, currencyThresholds)
+	if err != nil {
+		return nil, fmt.Errorf("threshold: %w", err)
+	}
+	processor.CountryThresholds = countryThresholds
+
+	return processor, nil
+}
+
+func newCountryBlacklistRule(params map[string]any) (RuleProcessor, error) {
+	var p countryBlacklistParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Countries) == 0 {
+		return nil, errors.New("countries: none configured")
+	}
+
+	processor := NewCountryBlackListProcessor(p.Countries...)
+	if p.ExactCountries {
+		processor.Blacklist = make(map[string]struct{}, len(p.Countries))
+		for _, country := range p.Countries {
+			processor.Blacklist[country] = struct{}{}
+		}
+		processor.ExactCountries = true
+	}
+
+	return processor, nil
+}
+
+func newExpressionRule(params map[string]any) (RuleProcessor, error) {
+	var p expressionParams
+	if err := DecodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	var window time.Duration
+	if p.Window != "" {
+		var err error
+		if window, err = time.ParseDuration(p.Window); err != nil {
+			return nil, fmt.Errorf("window: %w", err)
+		}
+	}
+	processor, err := NewExpressionProcessor(p.Expression, p.MinCount, window)
+	if err != nil {
+		return nil, fmt.Errorf("expression: %w", err)
+	}
+
+	return processor, nil
+}
+
+// parseThresholds parses the decimal values of a threshold map, nil staying nil
+func parseThresholds(field string, thresholds map[string]string) (map[string]decimal.Decimal, error) {
+	if thresholds == nil {
+		return nil, nil
+	}
+
+	parsed := make(map[string]decimal.Decimal, len(thresholds))
+	for _, key := range slices.Sorted(maps.Keys(thresholds)) {
+		threshold, err := decimal.NewFromString(thresholds[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s: %w", field, key, err)
+		}
+		parsed[key] = threshold
+	}
+
+	return parsed, nil
+}
+
+// newRuleConfig describes processor as a config entry, leaving the severity to the caller
+func newRuleConfig(processor RuleProcessor) (ruleConfig, error) {
+	var entry ruleConfig
+	var params any
+	switch p := processor.(type) {
+	case VelocityProcessor:
+		periods := make([]periodConfig, len(p.Periods))
+		for i, period := range p.Periods {
+			periods[i] = periodConfig{Name: period.Name, Duration: formatDuration(period.Duration), Threshold: period.Threshold}
+		}
+		entry.Type, params = "velocity", velocityParams{Periods: periods}
+
+	case TransactionAmountProcessor:
+		if p.Conversion != nil {
+			return ruleConfig{}, fmt.Errorf("%w: amount threshold with a currency conversion", ErrUnsupportedRule)
+		}
+		amountThreshold := amountThresholdParams{
+			CountryThresholds:  formatThresholds(p.CountryThresholds),
+			CurrencyThresholds: formatThresholds(p.CurrencyThresholds),
+		}
+		if !p.noDefault {
+			amountThreshold.Threshold = p.Threshold.String()
+		}
+		entry.Type, params = "amount_threshold", amountThreshold
+
+	case CountryBlackListProcessor:
+		blacklist := countryBlacklistParams{Countries: slices.Sorted(maps.Keys(normalizeCountrySet(p.Blacklist)))}
+		if p.ExactCountries {
+			blacklist = countryBlacklistParams{Countries: slices.Sorted(maps.Keys(p.Blacklist)), ExactCountries: true}
+		}
+		entry.Type, params = "country_blacklist", blacklist
+
+	case ExpressionProcessor:
+		expression := expressionParams{Expression: p.Expression(), MinCount: p.MinCount}
+		if p.Window > 0 {
+			expression.Window = formatDuration(p.Window)
+		}
+		entry.Type, params = "expression", expression
+
+	default:
+		return ruleConfig{}, ErrUnsupportedRule
+	}
+
+	var err error
+	entry.Params, err = encodeParams(params)
+	return entry, err
+}
+
+func formatThresholds(thresholds map[string]decimal.Decimal) map[string]string {
+	if thresholds == nil {
+		return nil
+	}
+
+	formatted := make(map[string]string, len(thresholds))
+	for key, threshold := range thresholds {
+		formatted[key] = threshold.String()
+	}
+
+	return formatted
+}
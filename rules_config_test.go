@@ -0,0 +1,325 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const yamlRulesConfig = `
+rules:
+  - type: velocity
+    periods:
+      - {name: daily, duration: 24h, threshold: 2}
+      - {duration: 168h, threshold: 4}
+  - type: amount_threshold
+    threshold: 10000
+    country_thresholds: {IR: "100.50"}
+  - type: country_blacklist
+    countries: [IR, kp]
+    severity: high
+`
+
+const jsonRulesConfig = `{"rules": [
+  {"type": "velocity", "periods": [{"name": "daily", "duration": "24h", "threshold": 2}, {"duration": "168h", "threshold": 4}]},
+  {"type": "amount_threshold", "threshold": "10000", "country_thresholds": {"IR": "100.50"}},
+  {"type": "country_blacklist", "countries": ["IR", "kp"], "severity": "high"}
+]}`
+
+// rulesConfigTransactions gives each rule of the test configs something to flag
+func rulesConfigTransactions() []Transaction {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	fast, rich, risky, quiet := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	return []Transaction{
+		{UserID: fast, Amount: decimal.NewFromInt(10), Country: "FR", CreatedAt: baseTime},
+		{UserID: fast, Amount: decimal.NewFromInt(10), Country: "FR", CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: fast, Amount: decimal.NewFromInt(10), Country: "FR", CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: rich, Amount: decimal.NewFromInt(10001), Country: "FR", CreatedAt: baseTime},
+		{UserID: risky, Amount: decimal.RequireFromString("100.51"), Country: "ir", CreatedAt: baseTime},
+		{UserID: quiet, Amount: decimal.NewFromInt(5000), Country: "DE", CreatedAt: baseTime},
+	}
+}
+
+func TestLoadRulesConfig(t *testing.T) {
+	transactions := rulesConfigTransactions()
+
+	handBuilt := NewRuleEngine([]RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{NewNamedVelocityPeriod("daily", 24*time.Hour, 2), NewVelocityPeriod(week, 4)}),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(10000), CountryThresholds: map[string]decimal.Decimal{"IR": decimal.RequireFromString("100.50")}},
+	})
+	handBuilt.AddRuleProcessorWithSeverity(NewCountryBlackListProcessor("IR", "KP"), SeverityHigh)
+	want, err := handBuilt.EvaluateAlerts(context.Background(), transactions)
+	require.NoError(t, err)
+	require.Len(t, want, 4)
+
+	for name, config := range map[string]string{"YAML": yamlRulesConfig, "JSON": jsonRulesConfig} {
+		t.Run(name, func(t *testing.T) {
+			engine, err := LoadRulesConfig(strings.NewReader(config))
+			require.NoError(t, err)
+
+			got, err := engine.EvaluateAlerts(context.Background(), transactions)
+			require.NoError(t, err)
+
+			require.Len(t, got, len(want))
+			for i := range want {
+				assert.Equal(t, want[i].UserID, got[i].UserID)
+				assert.Equal(t, want[i].RuleName, got[i].RuleName)
+				assert.Equal(t, want[i].Severity, got[i].Severity)
+				assert.Equal(t, want[i].Details, got[i].Details)
+			}
+		})
+	}
+}
+
+func TestLoadRulesConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{
+			name:    "unknown rule type",
+			config:  "rules:\n  - type: country_blacklist\n    countries: [IR]\n  - type: teleport\n",
+			wantErr: "rule 1 (teleport): unknown rule type",
+		},
+		{
+			name:    "unparsable duration",
+			config:  "rules:\n  - type: velocity\n    periods: [{duration: a week, threshold: 3}]\n",
+			wantErr: "rule 0 (velocity): periods[0].duration: time: invalid duration",
+		},
+		{
+			name:    "duration without a unit",
+			config:  `{"rules": [{"type": "velocity", "periods": [{"duration": "10", "threshold": 3}]}]}`,
+			wantErr: "rule 0 (velocity): periods[0].duration: time: missing unit",
+		},
+		{
+			name:    "invalid threshold decimal",
+			config:  "rules:\n  - type: amount_threshold\n    threshold: 10k\n",
+			wantErr: "rule 0 (amount_threshold): threshold:",
+		},
+		{
+			name:    "invalid country threshold decimal",
+			config:  "rules:\n  - type: amount_threshold\n    threshold: 10\n    country_thresholds: {IR: ten}\n",
+			wantErr: "rule 0 (amount_threshold): country_thresholds: IR:",
+		},
+		{
+			name:    "no threshold",
+			config:  "rules:\n  - type: amount_threshold\n    country_thresholds: {IR: \"100\"}\n",
+			wantErr: "rule 0 (amount_threshold): threshold: no default or per-currency threshold configured",
+		},
+		{
+			name:    "unknown severity",
+			config:  "rules:\n  - type: country_blacklist\n    countries: [IR]\n    severity: urgent\n",
+			wantErr: `rule 0 (country_blacklist): unknown severity "urgent"`,
+		},
+		{
+			name:    "negative period threshold",
+			config:  "rules:\n  - type: velocity\n    periods: [{duration: 24h, threshold: 2}, {duration: 168h, threshold: -1}]\n",
+			wantErr: "rule 0 (velocity): periods[1].threshold: -1 is negative",
+		},
+		{
+			name:    "malformed expression",
+			config:  "rules:\n  - type: expression\n    expression: amount >\n",
+			wantErr: `rule 0 (expression): expression: expression "amount >": position 9: unexpected end of expression`,
+		},
+		{
+			name:    "unknown field",
+			config:  "rules:\n  - type: country_blacklist\n    countrys: [IR]\n",
+			wantErr: "field countrys not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRulesConfig(strings.NewReader(tt.config))
+
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestDumpRulesConfig_RoundTrip(t *testing.T) {
+	engine, err := LoadRulesConfig(strings.NewReader(yamlRulesConfig))
+	require.NoError(t, err)
+
+	var dumped bytes.Buffer
+	require.NoError(t, DumpRulesConfig(&dumped, engine))
+
+	reloaded, err := LoadRulesConfig(bytes.NewReader(dumped.Bytes()))
+	require.NoError(t, err)
+	want, err := engine.EvaluateAlerts(context.Background(), rulesConfigTransactions())
+	require.NoError(t, err)
+	got, err := reloaded.EvaluateAlerts(context.Background(), rulesConfigTransactions())
+	require.NoError(t, err)
+	assert.Equal(t, len(want), len(got))
+	for i := range want {
+		assert.Equal(t, want[i].RuleName, got[i].RuleName)
+		assert.Equal(t, want[i].Severity, got[i].Severity)
+	}
+
+	var again bytes.Buffer
+	require.NoError(t, DumpRulesConfig(&again, reloaded))
+	assert.Equal(t, dumped.String(), again.String())
+	assert.Contains(t, dumped.String(), "duration: 168h")
+
+	unsupported := NewRuleEngine([]RuleProcessor{NewBenfordProcessor(10, 0.5)})
+	err = DumpRulesConfig(&bytes.Buffer{}, unsupported)
+	assert.ErrorIs(t, err, ErrUnsupportedRule)
+	assert.Contains(t, err.Error(), "BenfordProcessor")
+}
+
+func TestLoadRulesConfig_CurrencyThresholdsWithoutDefault(t *testing.T) {
+	config := "rules:\n  - type: amount_threshold\n    currency_thresholds: {EUR: \"1000\"}\n"
+	engine, err := LoadRulesConfig(strings.NewReader(config))
+	require.NoError(t, err)
+
+	threshold := decimal.NewFromInt(1000)
+	want, err := NewCurrencyAmountProcessor(nil, map[string]decimal.Decimal{"EUR": threshold})
+	require.NoError(t, err)
+	rules, _ := engine.currentRules()
+	require.Len(t, rules, 1)
+	require.Equal(t, want, rules[0].processor)
+
+	euro, dollar := uuid.New(), uuid.New()
+	transactions := []Transaction{
+		{UserID: euro, Amount: decimal.NewFromInt(1001), Currency: "EUR"},
+		{UserID: dollar, Amount: decimal.NewFromInt(1000000), Currency: "USD"},
+	}
+	evaluation := rules[0].processor.(TransactionAmountProcessor).Evaluate(context.Background(), transactions)
+	assert.Equal(t, map[uuid.UUID]struct{}{euro: {}}, evaluation.Flagged)
+	assert.Equal(t, transactions[1:], evaluation.Unevaluated, "without a default, other currencies are not evaluated")
+
+	var dumped bytes.Buffer
+	require.NoError(t, DumpRulesConfig(&dumped, engine))
+	assert.NotContains(t, dumped.String(), "threshold:")
+	reloaded, err := LoadRulesConfig(bytes.NewReader(dumped.Bytes()))
+	require.NoError(t, err)
+	reloadedRules, _ := reloaded.currentRules()
+	assert.Equal(t, want, reloadedRules[0].processor)
+}
+
+func TestRuleEngine_ReloadConfig(t *testing.T) {
+	transactions := rulesConfigTransactions()
+	engine, err := LoadRulesConfig(strings.NewReader(yamlRulesConfig))
+	require.NoError(t, err)
+	jsonEngine, err := LoadRulesConfig(strings.NewReader(jsonRulesConfig))
+	require.NoError(t, err)
+
+	before := engine.ConfigVersion()
+	assert.Len(t, before, 16)
+	assert.Equal(t, before, jsonEngine.ConfigVersion(), "the version ignores how the config is written")
+
+	first, err := engine.Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+
+	require.NoError(t, engine.ReloadConfig(strings.NewReader(`
+rules:
+  - type: amount_threshold
+    threshold: 4000
+`)))
+	after := engine.ConfigVersion()
+	assert.NotEqual(t, before, after)
+
+	second, err := engine.Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+
+	assert.Equal(t, before, first.ConfigVersion)
+	assert.Len(t, first.Alerts, 4)
+	for _, alert := range first.Alerts {
+		assert.Equal(t, before, alert.ConfigVersion)
+	}
+	assert.Equal(t, after, second.ConfigVersion)
+	assert.Len(t, second.Alerts, 2, "the reloaded threshold flags rich and quiet")
+	for _, alert := range second.Alerts {
+		assert.Equal(t, "TransactionAmountProcessor", alert.RuleName)
+		assert.Equal(t, after, alert.ConfigVersion)
+	}
+
+	err = engine.ReloadConfig(strings.NewReader("rules:\n  - type: amount_threshold\n  - type: bogus\n"))
+	assert.ErrorContains(t, err, "rule 0 (amount_threshold)")
+	assert.Equal(t, after, engine.ConfigVersion(), "a rejected config leaves the rules as they were")
+
+	engine.AddRuleProcessor(NewCountryBlackListProcessor("IR"))
+	assert.Empty(t, engine.ConfigVersion(), "hand-registered rules are not part of any config")
+}
+
+func TestRuleEngine_ReloadConfig_InFlight(t *testing.T) {
+	engine, err := LoadRulesConfig(strings.NewReader(yamlRulesConfig))
+	require.NoError(t, err)
+	before := engine.ConfigVersion()
+	// hold the run inside its last rule while the config is swapped
+	gate := gatedProcessor{started: make(chan struct{}), release: make(chan struct{})}
+	engine.rules = append(engine.rules, engineRule{processor: gate, severity: SeverityLow})
+
+	done := make(chan EvaluationResult)
+	go func() {
+		result, err := engine.Evaluate(context.Background(), rulesConfigTransactions())
+		assert.NoError(t, err)
+		done <- result
+	}()
+	<-gate.started
+
+	require.NoError(t, engine.ReloadConfig(strings.NewReader("rules:\n  - type: country_blacklist\n    countries: [DE]\n")))
+	next, err := engine.Evaluate(context.Background(), rulesConfigTransactions())
+	require.NoError(t, err)
+	close(gate.release)
+	inFlight := <-done
+
+	assert.Equal(t, before, inFlight.ConfigVersion)
+	assert.Len(t, inFlight.Rules, 4, "the run in flight keeps the rules it started with")
+	assert.Len(t, inFlight.Alerts, 4)
+	for _, alert := range inFlight.Alerts {
+		assert.Equal(t, before, alert.ConfigVersion)
+	}
+
+	require.Len(t, next.Alerts, 1)
+	assert.Equal(t, engine.ConfigVersion(), next.Alerts[0].ConfigVersion)
+	assert.NotEqual(t, before, next.ConfigVersion)
+}
+
+func TestRuleEngine_ReloadConfig_Concurrent(t *testing.T) {
+	engine, err := LoadRulesConfig(strings.NewReader(yamlRulesConfig))
+	require.NoError(t, err)
+	versions := map[string]bool{engine.ConfigVersion(): true}
+
+	configs := []string{yamlRulesConfig, "rules:\n  - type: amount_threshold\n    threshold: 4000\n"}
+	for _, config := range configs {
+		loaded, err := LoadRulesConfig(strings.NewReader(config))
+		require.NoError(t, err)
+		versions[loaded.ConfigVersion()] = true
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := range 50 {
+			assert.NoError(t, engine.ReloadConfig(strings.NewReader(configs[i%2])))
+		}
+	}()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				result, err := engine.Evaluate(context.Background(), rulesConfigTransactions())
+				assert.NoError(t, err)
+				for _, alert := range result.Alerts {
+					assert.Equal(t, result.ConfigVersion, alert.ConfigVersion, "a run never mixes configs")
+				}
+				assert.True(t, versions[result.ConfigVersion])
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidShardCount is returned when transactions are to be evaluated in fewer than one shard
+var ErrInvalidShardCount = errors.New("shard count must be positive")
+
+// ErrShardIncompatible is returned by ShardedEvaluate when a registered rule decides on users from
+// other users' transactions, which a shard does not hold
+var ErrShardIncompatible = errors.New("rule cannot be evaluated in user shards")
+
+// CrossUserProcessor is implemented by processors whose decision on a user may depend on the
+// transactions of other users, e.g. a funnel of many senders paying one counterparty. Processors
+// reporting true are rejected by ShardedEvaluate. Processors grouping by another key than the user,
+// as set by WithGroupingKey, are treated as cross-user without implementing it.
+type CrossUserProcessor interface {
+	CrossUser() bool
+}
+
+// crossUser reports whether processor needs transactions of other users than the one it decides on
+func crossUser(processor RuleProcessor) bool {
+	if cross, ok := processor.(CrossUserProcessor); ok {
+		return cross.CrossUser()
+	}
+	if keyer, ok := processor.(GroupingKeyer); ok {
+		return !groupsByUser(keyer)
+	}
+
+	return false
+}
+
+// WithShardWorkers evaluates up to n shards of a ShardedEvaluate run at once, one at a time when below two
+func WithShardWorkers(n int) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.shardWorkers = n
+	}
+}
+
+// ShardedEvaluate pulls every transaction from src and evaluates them in shards, routing each user's
+// transactions to one of shards buckets by a hash of the UserID, so only one shard's working set is
+// built at a time, or WithShardWorkers of them. Every rule deciding on a user from that user's
+// transactions alone decides as Evaluate would on the whole batch, and the merged result carries the
+// same rule summaries, alerts in the same order and the same summary. Cross-user rules, as told by
+// CrossUserProcessor, make the call fail with ErrShardIncompatible before anything is pulled.
+//
+// The whole batch is validated before it is sharded, as Evaluate would. Each shard is then evaluated
+// as a run of its own, publishing its alerts to the sinks, callbacks and streaming report as it
+// completes. A source error other than io.EOF ends the call before any shard is evaluated; errors
+// wrapping ErrTransientSource are retried as by EvaluateSource. With WithSpill, shards are written
+// to disk as they are pulled rather than held in memory.
+func (r *RuleEngine) ShardedEvaluate(ctx context.Context, src TransactionSource, shards int) (EvaluationResult, error) {
+	if shards <= 0 {
+		return EvaluationResult{}, fmt.Errorf("%w: %d", ErrInvalidShardCount, shards)
+	}
+
+	rules, configVersion := r.currentRules()
+	var incompatible []string
+	for _, rule := range rules {
+		if crossUser(rule.processor) {
+			incompatible = append(incompatible, ruleName(rule.processor))
+		}
+	}
+	if len(incompatible) > 0 {
+		return EvaluationResult{}, fmt.Errorf("%w: %s", ErrShardIncompatible, strings.Join(incompatible, ", "))
+	}
+
+	ctx, stop := r.withProgress(ctx)
+	defer stop()
+
+	result := EvaluationResult{
+		RunID:         uuid.New(),
+		StartedAt:     time.Now().UTC(),
+		Rules:         make([]RuleSummary, len(rules)),
+		ConfigVersion: configVersion,
+	}
+	for i, rule := range rules {
+		result.Rules[i] = RuleSummary{Name: ruleName(rule.processor), Severity: rule.severity}
+	}
+
+	var runs []EvaluationResult
+	var errs []error
+	if r.spill != nil {
+		runs, errs, err := r.evaluateSpilled(ctx, src, shards, rules, configVersion, &result)
+		if err != nil {
+			result.Rules = []RuleSummary{}
+			result.FinishedAt = time.Now().UTC()
+			return result, err
+		}
+		return r.mergeShards(result, runs, errs)
+	}
+
+	buckets, pulled, validationErrors, err := r.pullShards(ctx, src, shards)
+	result.TransactionCount, result.ValidationErrors = pulled, validationErrors
+	if err != nil {
+		result.Rules = []RuleSummary{}
+		result.FinishedAt = time.Now().UTC()
+		return result, err
+	}
+
+	runs = make([]EvaluationResult, shards)
+	errs = make([]error, shards)
+	workers := make(chan struct{}, max(r.shardWorkers, 1))
+	var wg sync.WaitGroup
+	for i := range buckets {
+		if len(buckets[i]) == 0 {
+			continue
+		}
+
+		workers <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-workers
+				wg.Done()
+			}()
+
+			runs[i], errs[i] = r.evaluateShard(ctx, buckets[i], rules, configVersion)
+			// the shard's transactions live on only as its alerts' evidence
+			buckets[i] = nil
+		}()
+	}
+	wg.Wait()
+
+	return r.mergeShards(result, runs, errs)
+}
+
+// evaluateShard evaluates one shard's transactions as a run of its own
+func (r *RuleEngine) evaluateShard(ctx context.Context, transactions []Transaction, rules []engineRule, configVersion string) (EvaluationResult, error) {
+	ctx, end := startSpan(r.withTracer(ctx), "aml.evaluate")
+	run, err := r.evaluateRules(ctx, transactions, rules, configVersion, nil)
+	end(map[string]any{
+		attrTransactions: len(transactions),
+		attrRules:        len(run.Rules),
+		attrAlerts:       len(run.Alerts),
+	}, err)
+
+	return run, err
+}
+
+// mergeShards completes result with the shards' runs and their errors, indexed by shard
+func (r *RuleEngine) mergeShards(result EvaluationResult, runs []EvaluationResult, errs []error) (EvaluationResult, error) {
+	var shardErrs []error
+	for i, err := range errs {
+		if err != nil {
+			shardErrs = append(shardErrs, fmt.Errorf("shard %d: %w", i, err))
+		}
+	}
+	result.Alerts = mergeShardAlerts(result.Rules, runs)
+
+	result.FinishedAt = time.Now().UTC()
+	result.Summary = Summarize(result.Alerts, nil)
+	for _, run := range runs {
+		result.Summary.TotalTransactions += run.Summary.TotalTransactions
+		result.Summary.DistinctUsers += run.Summary.DistinctUsers
+		result.Summary.DuplicatesDropped += run.Summary.DuplicatesDropped
+	}
+	if result.Summary.DistinctUsers > 0 {
+		result.Summary.FlaggedRate = float64(result.Summary.FlaggedUsers) / float64(result.Summary.DistinctUsers)
+	}
+	result.Summary.Duration = result.FinishedAt.Sub(result.StartedAt)
+
+	return result, errors.Join(shardErrs...)
+}
+
+// pullShards pulls src to its end into shards buckets by user, also returning how many transactions
+// it pulled, and validates the batch first when the engine validates. A batch rejected by validation
+// is returned as an error with its validation errors.
+func (r *RuleEngine) pullShards(ctx context.Context, src TransactionSource, shards int) ([][]Transaction, int, []ValidationError, error) {
+	buckets := make([][]Transaction, shards)
+	// a validated batch is validated whole, since its checks span users
+	var batch []Transaction
+	count, err := pullEach(ctx, src, func(tx Transaction) error {
+		if r.validation != nil {
+			batch = append(batch, tx)
+		} else {
+			shard := userShard(tx.UserID, shards)
+			buckets[shard] = append(buckets[shard], tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, count, nil, err
+	}
+	if r.validation == nil {
+		return buckets, count, nil, nil
+	}
+
+	valid, validationErrors := ValidateTransactions(batch, *r.validation)
+	if r.validation.Action == ValidationFail && len(validationErrors) > 0 {
+		return nil, count, validationErrors, fmt.Errorf("%w: %d failed checks", ErrInvalidBatch, len(validationErrors))
+	}
+	for _, tx := range valid {
+		shard := userShard(tx.UserID, shards)
+		buckets[shard] = append(buckets[shard], tx)
+	}
+
+	return buckets, count, validationErrors, nil
+}
+
+// pullEach pulls src to its end, handing each transaction to add, and returns how many it pulled.
+// It stops at the first error of src, other than io.EOF, or of add.
+func pullEach(ctx context.Context, src TransactionSource, add func(Transaction) error) (int, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	pulled := make(chan sourceItem)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		pullSource(ctx, src, pulled)
+	}()
+	// the puller must not outlive the call, since src is the caller's again once it returns
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	count := 0
+	for {
+		var item sourceItem
+		select {
+		case item = <-pulled:
+		case <-ctx.Done():
+			return count, ctx.Err()
+		}
+		if errors.Is(item.err, io.EOF) {
+			return count, nil
+		}
+		if item.err != nil {
+			return count, fmt.Errorf("transaction source: %w", item.err)
+		}
+		count++
+
+		if err := add(item.tx); err != nil {
+			return count, err
+		}
+	}
+}
+
+// userShard is the shard of shards holding userID's transactions
+func userShard(userID uuid.UUID, shards int) int {
+	h := fnv.New64a()
+	h.Write(userID[:])
+	return int(h.Sum64() % uint64(shards))
+}
+
+// mergeShardAlerts sums the shards' rule summaries into rules and returns their alerts ordered as
+// a single run orders them, by registration then user ID. Each shard's alerts come in that order
+// already, one per flagged user of each rule.
+func mergeShardAlerts(rules []RuleSummary, runs []EvaluationResult) []Alert {
+	perRule := make([][]Alert, len(rules))
+	for _, run := range runs {
+		alerts := run.Alerts
+		for i, summary := range run.Rules {
+			rules[i].FlaggedUsers += summary.FlaggedUsers
+			if rules[i].Error == "" {
+				rules[i].Error = summary.Error
+			}
+			perRule[i] = append(perRule[i], alerts[:summary.FlaggedUsers]...)
+			alerts = alerts[summary.FlaggedUsers:]
+		}
+	}
+
+	var merged []Alert
+	for _, alerts := range perRule {
+		slices.SortStableFunc(alerts, func(a, b Alert) int {
+			return compareUserIDs(a.UserID, b.UserID)
+		})
+		merged = append(merged, alerts...)
+	}
+
+	return merged
+}
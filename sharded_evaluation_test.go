@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// comparableResult strips what differs between two runs over the same batch: IDs and timestamps
+func comparableResult(result EvaluationResult) EvaluationResult {
+	result.RunID = [16]byte{}
+	result.StartedAt, result.FinishedAt = time.Time{}, time.Time{}
+	result.Summary.Duration = 0
+	alerts := make([]Alert, len(result.Alerts))
+	for i, alert := range result.Alerts {
+		alert.ID = [16]byte{}
+		alert.CreatedAt = time.Time{}
+		alerts[i] = alert
+	}
+	result.Alerts = alerts
+
+	return result
+}
+
+func perUserRules() []RuleProcessor {
+	period := NewVelocityPeriod(24*time.Hour, 6)
+	return []RuleProcessor{
+		NewVelocityValidator([]VelocityPeriod{period}),
+		NewWorkerVelocityProcessor([]VelocityPeriod{NewVelocityPeriod(week, 30)}, 3),
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(900)},
+		NewCountryBlackListProcessor("IR"),
+		NewStructuringProcessor(decimal.NewFromInt(10000), decimal.NewFromFloat(0.05), 3, 4*time.Hour),
+		NewAndProcessor(NewCountryBlackListProcessor("IR"), NewVelocityValidator([]VelocityPeriod{period})),
+		NewNotProcessor(TransactionAmountProcessor{Threshold: decimal.NewFromInt(500)}),
+	}
+}
+
+func TestRuleEngine_ShardedEvaluate(t *testing.T) {
+	generated := NewTransactionGenerator(
+		WithUserCount(300),
+		WithTransactionsPerUser(PoissonCount(8)),
+		WithSpacing(PoissonSpacing(3*time.Hour)),
+		WithCountryPool("DE", "FR", "IR"),
+		WithVelocityViolators(10, NewVelocityPeriod(24*time.Hour, 6)),
+		WithStructuringUsers(5, decimal.NewFromInt(10000)),
+		WithTimeOrder(),
+	).Generate()
+
+	want, err := NewRuleEngine(perUserRules()).Evaluate(context.Background(), generated.Transactions)
+	require.NoError(t, err)
+	require.NotEmpty(t, want.Alerts)
+
+	for _, tt := range []struct {
+		name    string
+		shards  int
+		workers int
+	}{
+		{name: "one shard", shards: 1},
+		{name: "sequential shards", shards: 7},
+		{name: "parallel shards", shards: 16, workers: 4},
+		{name: "more shards than users", shards: 1000, workers: 8},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sink := NewMemorySink()
+			engine := NewRuleEngine(perUserRules(), WithShardWorkers(tt.workers))
+			engine.AddAlertSink(sink)
+
+			got, err := engine.ShardedEvaluate(context.Background(), NewSliceSource(generated.Transactions), tt.shards)
+			require.NoError(t, err)
+			assert.Equal(t, comparableResult(want), comparableResult(got))
+			assert.Len(t, sink.Alerts(), len(want.Alerts), "every shard publishes its alerts")
+		})
+	}
+}
+
+func TestRuleEngine_ShardedEvaluate_Validation(t *testing.T) {
+	transactions := blacklistedTransactions(6)
+	transactions[2].Amount = decimal.NewFromInt(-5)
+
+	for _, action := range []ValidationAction{ValidationDrop, ValidationFail} {
+		policy := ValidationPolicy{Action: action, RejectNegativeAmounts: true}
+		want, wantErr := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")}, WithValidation(policy)).
+			Evaluate(context.Background(), transactions)
+
+		got, err := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")}, WithValidation(policy)).
+			ShardedEvaluate(context.Background(), NewSliceSource(transactions), 3)
+		assert.Equal(t, wantErr, err)
+		assert.Equal(t, comparableResult(want), comparableResult(got))
+	}
+}
+
+func TestRuleEngine_ShardedEvaluate_Rejected(t *testing.T) {
+	tests := []struct {
+		name      string
+		processor RuleProcessor
+		wantName  string
+	}{
+		{name: "cross-user rule", processor: NewFunnelProcessor(time.Hour, 3), wantName: "FunnelProcessor"},
+		{name: "batch-wide rule", processor: NewPercentileProcessor(0.99, 10), wantName: "PercentileProcessor"},
+		{name: "cycle rule", processor: NewCircularFlowProcessor(time.Hour, 3, decimal.Zero), wantName: "CircularFlowProcessor"},
+		{name: "wrapped cross-user rule", processor: NewOrProcessor(NewCountryBlackListProcessor("IR"), NewFunnelProcessor(time.Hour, 3)), wantName: "OrProcessor"},
+		{name: "cross-user include", processor: NewExceptProcessor(NewFunnelProcessor(time.Hour, 3), NewCountryBlackListProcessor("IR")), wantName: "ExceptProcessor"},
+		{name: "cross-user exclude", processor: NewExceptProcessor(NewCountryBlackListProcessor("IR"), NewFunnelProcessor(time.Hour, 3)), wantName: "ExceptProcessor"},
+		{name: "filtered cross-user rule", processor: NewFilteredProcessor("cash", func(Transaction) bool { return true }, NewFunnelProcessor(time.Hour, 3)), wantName: "FunnelProcessor [cash]"},
+		{name: "velocity by account", processor: NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 5)}, WithGroupingKey(ByAccount)), wantName: "VelocityProcessor"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := &flakySource{transactions: blacklistedTransactions(3), err: io.EOF}
+			engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR"), tt.processor})
+
+			result, err := engine.ShardedEvaluate(context.Background(), source, 4)
+			assert.ErrorIs(t, err, ErrShardIncompatible)
+			assert.ErrorContains(t, err, tt.wantName)
+			assert.Empty(t, result.Alerts)
+			assert.Zero(t, source.calls, "nothing is pulled once a rule is rejected")
+		})
+	}
+
+	t.Run("per-user wrappers are accepted", func(t *testing.T) {
+		engine := NewRuleEngine([]RuleProcessor{
+			NewOrProcessor(NewCountryBlackListProcessor("IR")),
+			NewExceptProcessor(NewCountryBlackListProcessor("IR"), NewCountryBlackListProcessor("DE")),
+			NewFilteredProcessor("all", func(Transaction) bool { return true }, NewCountryBlackListProcessor("IR")),
+		})
+		result, err := engine.ShardedEvaluate(context.Background(), NewSliceSource(blacklistedTransactions(3)), 2)
+		require.NoError(t, err)
+		assert.Len(t, result.Alerts, 9)
+	})
+}
+
+func TestRuleEngine_ShardedEvaluate_Errors(t *testing.T) {
+	engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")})
+
+	_, err := engine.ShardedEvaluate(context.Background(), NewSliceSource(nil), 0)
+	assert.ErrorIs(t, err, ErrInvalidShardCount)
+
+	sink := NewMemorySink()
+	engine.AddAlertSink(sink)
+	broken := errors.New("broker gone")
+	result, err := engine.ShardedEvaluate(context.Background(), &flakySource{
+		transactions: blacklistedTransactions(3),
+		transient:    map[int]bool{2: true},
+		err:          broken,
+	}, 2)
+	assert.ErrorIs(t, err, broken)
+	assert.Equal(t, 3, result.TransactionCount)
+	assert.Empty(t, sink.Alerts(), "no shard is evaluated from a failed source")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = engine.ShardedEvaluate(ctx, NewSliceSource(blacklistedTransactions(3)), 2)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	result, err = engine.ShardedEvaluate(context.Background(), NewSliceSource(nil), 2)
+	require.NoError(t, err)
+	assert.Equal(t, []RuleSummary{{Name: "CountryBlackListProcessor", Severity: SeverityCritical}}, result.Rules)
+}
+
+func TestUserShard(t *testing.T) {
+	generated := NewTransactionGenerator(WithUserCount(4000)).Generate()
+
+	counts := make([]int, 8)
+	for _, userID := range generated.Users {
+		shard := userShard(userID, len(counts))
+		assert.Equal(t, shard, userShard(userID, len(counts)), "a user always lands in the same shard")
+		counts[shard]++
+	}
+	for _, count := range counts {
+		assert.InDelta(t, 500, count, 100, "users spread evenly over the shards")
+	}
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+	"unsafe"
+
+	"github.com/google/uuid"
+)
+
+// WithSpill makes ShardedEvaluate write the pulled transactions to temporary shard files under dir,
+// os.TempDir when empty, and load them back one shard at a time, for batches larger than memory.
+// A shard holding more than memoryBudget bytes of transactions is split again before it is loaded,
+// so the budget rather than the shards argument sets how many shards are evaluated; zero never
+// splits. One user's transactions are loaded together whatever their size.
+//
+// Spilled shards are evaluated one at a time whatever WithShardWorkers, and their files are removed
+// before ShardedEvaluate returns, including when ctx is cancelled. Timestamps come back from the
+// files with their offset but neither their location name nor a monotonic clock reading.
+func WithSpill(dir string, memoryBudget int64) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.spill = &spillOptions{dir: dir, memoryBudget: memoryBudget}
+	}
+}
+
+// spillOptions is the spilling mode set by WithSpill
+type spillOptions struct {
+	dir          string
+	memoryBudget int64
+}
+
+// evaluateSpilled pulls src into spilled shards and evaluates them one by one, completing result with
+// what it pulled. Transactions are validated one by one as they are pulled, a batch rejected by
+// ValidationFail being rejected once src is exhausted. An error is returned when nothing could be
+// evaluated, or ctx was cancelled, the runs and errors of the evaluated shards otherwise.
+func (r *RuleEngine) evaluateSpilled(ctx context.Context, src TransactionSource, shards int, rules []engineRule, configVersion string, result *EvaluationResult) ([]EvaluationResult, []error, error) {
+	spill, err := newSpilledShards(r.spill.dir, shards)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer spill.remove()
+
+	var policy ValidationPolicy
+	if r.validation != nil {
+		policy = *r.validation
+		// every transaction is checked against the same reference time, as in a single batch
+		now := time.Now()
+		if policy.Now != nil {
+			now = policy.Now()
+		}
+		policy.Now = func() time.Time { return now }
+	}
+
+	index := 0
+	pulled, err := pullEach(ctx, src, func(tx Transaction) error {
+		defer func() { index++ }()
+		if r.validation != nil {
+			valid, validationErrors := ValidateTransactions([]Transaction{tx}, policy)
+			for _, validationError := range validationErrors {
+				validationError.Index = index
+				result.ValidationErrors = append(result.ValidationErrors, validationError)
+			}
+			if len(valid) == 0 {
+				return nil
+			}
+		}
+		return spill.add(tx)
+	})
+	result.TransactionCount = pulled
+	if err != nil {
+		result.ValidationErrors = nil
+		return nil, nil, err
+	}
+	if r.validation != nil && r.validation.Action == ValidationFail && len(result.ValidationErrors) > 0 {
+		return nil, nil, fmt.Errorf("%w: %d failed checks", ErrInvalidBatch, len(result.ValidationErrors))
+	}
+	if err := spill.flush(); err != nil {
+		return nil, nil, err
+	}
+
+	var runs []EvaluationResult
+	var errs []error
+	pending := spill.files
+	for len(pending) > 0 {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		file := pending[0]
+		pending = pending[1:]
+		if file.count == 0 {
+			continue
+		}
+		if r.spill.memoryBudget > 0 && file.bytes > r.spill.memoryBudget && file.mixed {
+			parts, err := spill.split(file, spillParts(file, r.spill.memoryBudget))
+			if err != nil {
+				return nil, nil, err
+			}
+			pending = append(parts, pending...)
+			continue
+		}
+
+		transactions, err := spill.load(file)
+		if err != nil {
+			return nil, nil, err
+		}
+		run, err := r.evaluateShard(ctx, transactions, rules, configVersion)
+		runs, errs = append(runs, run), append(errs, err)
+	}
+
+	return runs, errs, nil
+}
+
+// spilledShards are shard files under a temporary directory of their own
+type spilledShards struct {
+	dir   string
+	files []*spillFile
+}
+
+// spillFile is one shard written to disk
+type spillFile struct {
+	path string
+	file *os.File
+	buf  *bufio.Writer
+	enc  *gob.Encoder
+	// salt tells the hash which routed users to the file's parts when it is split
+	salt int
+	// count and bytes are how many transactions the file holds and their estimated size in memory
+	count int
+	bytes int64
+	// firstUser is the user of the first transaction, mixed telling whether others follow
+	firstUser uuid.UUID
+	mixed     bool
+}
+
+func newSpilledShards(dir string, shards int) (*spilledShards, error) {
+	tempDir, err := os.MkdirTemp(dir, "aml-spill-*")
+	if err != nil {
+		return nil, fmt.Errorf("spill: %w", err)
+	}
+
+	s := &spilledShards{dir: tempDir}
+	files, err := s.create(shards, 0)
+	if err != nil {
+		s.remove()
+		return nil, err
+	}
+	s.files = files
+
+	return s, nil
+}
+
+// create opens n shard files whose users are routed by a hash salted with salt
+func (s *spilledShards) create(n, salt int) ([]*spillFile, error) {
+	files := make([]*spillFile, n)
+	for i := range files {
+		file, err := os.CreateTemp(s.dir, "shard-*")
+		if err != nil {
+			for _, created := range files[:i] {
+				_ = created.file.Close()
+			}
+			return nil, fmt.Errorf("spill: %w", err)
+		}
+		buf := bufio.NewWriter(file)
+		files[i] = &spillFile{path: file.Name(), file: file, buf: buf, enc: gob.NewEncoder(buf), salt: salt}
+	}
+
+	return files, nil
+}
+
+// add writes tx to the shard of its user
+func (s *spilledShards) add(tx Transaction) error {
+	return s.files[spillShard(tx.UserID, 0, len(s.files))].write(tx)
+}
+
+// flush flushes every file and closes it for writing
+func (s *spilledShards) flush() error {
+	for _, file := range s.files {
+		if err := file.close(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// split routes the transactions of file into n new files with a hash salted anew, and removes it
+func (s *spilledShards) split(file *spillFile, n int) ([]*spillFile, error) {
+	parts, err := s.create(n, file.salt+1)
+	if err != nil {
+		return nil, err
+	}
+
+	err = file.read(func(tx Transaction) error {
+		return parts[spillShard(tx.UserID, file.salt+1, n)].write(tx)
+	})
+	for _, part := range parts {
+		err = errors.Join(err, part.close())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parts, os.Remove(file.path)
+}
+
+// load reads the transactions of file and removes it
+func (s *spilledShards) load(file *spillFile) ([]Transaction, error) {
+	transactions := make([]Transaction, 0, file.count)
+	err := file.read(func(tx Transaction) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, os.Remove(file.path)
+}
+
+// remove closes the files still open and removes the directory with whatever it holds
+func (s *spilledShards) remove() {
+	for _, file := range s.files {
+		if file.file != nil {
+			_ = file.file.Close()
+		}
+	}
+	_ = os.RemoveAll(s.dir)
+}
+
+func (f *spillFile) write(tx Transaction) error {
+	if err := f.enc.Encode(&tx); err != nil {
+		return fmt.Errorf("spill %s: %w", filepath.Base(f.path), err)
+	}
+
+	if f.count == 0 {
+		f.firstUser = tx.UserID
+	} else if tx.UserID != f.firstUser {
+		f.mixed = true
+	}
+	f.count++
+	f.bytes += transactionFootprint(tx)
+
+	return nil
+}
+
+// close flushes the file and closes it, it is only read afterwards
+func (f *spillFile) close() error {
+	err := errors.Join(f.buf.Flush(), f.file.Close())
+	f.file, f.buf, f.enc = nil, nil, nil
+	if err != nil {
+		return fmt.Errorf("spill %s: %w", filepath.Base(f.path), err)
+	}
+
+	return nil
+}
+
+// read decodes the transactions of the file in the order they were written
+func (f *spillFile) read(fn func(Transaction) error) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("spill: %w", err)
+	}
+	defer file.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(file))
+	for {
+		var tx Transaction
+		if err := dec.Decode(&tx); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("spill %s: %w", filepath.Base(f.path), err)
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+}
+
+// maxSpillParts bounds how many files a shard is split into at once, larger shards being split again
+const maxSpillParts = 64
+
+// spillParts is how many parts file is split into to fit in budget, twice as many as its size
+// requires so that most fit at the first split
+func spillParts(file *spillFile, budget int64) int {
+	parts := (file.bytes + budget - 1) / budget * 2
+	return int(min(parts, int64(file.count), maxSpillParts))
+}
+
+// spillShard is the shard of shards holding userID's transactions, as userShard for a zero salt.
+// The salted hash goes through the splitmix64 finalizer, since the low bits of FNV depend on the
+// low bits of every byte only and would keep users together whatever the salt.
+func spillShard(userID uuid.UUID, salt, shards int) int {
+	if salt == 0 {
+		return userShard(userID, shards)
+	}
+
+	h := fnv.New64a()
+	h.Write(userID[:])
+	x := h.Sum64() + uint64(salt)*0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return int(x % uint64(shards))
+}
+
+// transactionFootprint estimates how many bytes tx holds in memory
+func transactionFootprint(tx Transaction) int64 {
+	strings := len(tx.Currency) + len(tx.Country) + len(tx.DestinationCountry) + len(tx.Status) +
+		len(tx.Direction) + len(tx.Channel) + len(tx.Category) + len(tx.Description) +
+		len(tx.UserName) + len(tx.CounterpartyName) + len(tx.CounterpartyID) + len(tx.CounterpartyCountry)
+
+	return int64(unsafe.Sizeof(tx)) + int64(strings)
+}
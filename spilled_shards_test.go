@@ -0,0 +1,177 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shardCounter counts the shards it is evaluated on, optionally running hook on each
+type shardCounter struct {
+	calls atomic.Int64
+	hook  func(call int64)
+}
+
+func (c *shardCounter) Process(_ context.Context, _ []Transaction) map[uuid.UUID]struct{} {
+	call := c.calls.Add(1)
+	if c.hook != nil {
+		c.hook(call)
+	}
+	return map[uuid.UUID]struct{}{}
+}
+
+func assertNoSpillFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "spill files are removed")
+}
+
+func TestRuleEngine_ShardedEvaluate_Spill(t *testing.T) {
+	generated := NewTransactionGenerator(
+		WithUserCount(300),
+		WithTransactionsPerUser(PoissonCount(8)),
+		WithSpacing(PoissonSpacing(3*time.Hour)),
+		WithCountryPool("DE", "FR", "IR"),
+		WithVelocityViolators(10, NewVelocityPeriod(24*time.Hour, 6)),
+		WithStructuringUsers(5, decimal.NewFromInt(10000)),
+		WithTimeOrder(),
+	).Generate()
+
+	want, err := NewRuleEngine(perUserRules()).Evaluate(context.Background(), generated.Transactions)
+	require.NoError(t, err)
+	require.NotEmpty(t, want.Alerts)
+
+	tests := []struct {
+		name       string
+		shards     int
+		budget     int64
+		wantShards func(shards int64) bool
+	}{
+		{name: "no budget", shards: 4, wantShards: func(shards int64) bool { return shards == 4 }},
+		{name: "tiny budget", shards: 2, budget: 16 << 10, wantShards: func(shards int64) bool { return shards > 2 }},
+		{name: "one user per shard", shards: 1, budget: 1, wantShards: func(shards int64) bool { return shards == int64(want.Summary.DistinctUsers) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			counter := &shardCounter{}
+			engine := NewRuleEngine(perUserRules(), WithSpill(dir, tt.budget))
+			engine.AddRuleProcessor(counter)
+
+			got, err := engine.ShardedEvaluate(context.Background(), NewSliceSource(generated.Transactions), tt.shards)
+			require.NoError(t, err)
+			got.Rules = got.Rules[:len(got.Rules)-1]
+			assert.Equal(t, comparableResult(want), comparableResult(got))
+			assert.True(t, tt.wantShards(counter.calls.Load()), "evaluated %d shards", counter.calls.Load())
+			assertNoSpillFiles(t, dir)
+		})
+	}
+}
+
+func TestRuleEngine_ShardedEvaluate_SpillValidation(t *testing.T) {
+	transactions := blacklistedTransactions(6)
+	for i := range transactions {
+		// spill files keep no monotonic clock reading
+		transactions[i].CreatedAt = transactions[i].CreatedAt.Round(0)
+	}
+	transactions[2].Amount = decimal.NewFromInt(-5)
+	transactions[4].UserID = uuid.Nil
+
+	for _, action := range []ValidationAction{ValidationDrop, ValidationFail, ValidationKeep} {
+		policy := ValidationPolicy{Action: action, RejectNegativeAmounts: true}
+		want, wantErr := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")}, WithValidation(policy)).
+			Evaluate(context.Background(), transactions)
+
+		dir := t.TempDir()
+		got, err := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")}, WithValidation(policy), WithSpill(dir, 1)).
+			ShardedEvaluate(context.Background(), NewSliceSource(transactions), 3)
+		assert.Equal(t, wantErr, err)
+		assert.Equal(t, comparableResult(want), comparableResult(got))
+		assertNoSpillFiles(t, dir)
+	}
+}
+
+// cancellingSource cancels its context once it has handed out after transactions
+type cancellingSource struct {
+	TransactionSource
+	after  int
+	cancel context.CancelFunc
+	pulled int
+}
+
+func (c *cancellingSource) Next(ctx context.Context) (Transaction, error) {
+	c.pulled++
+	if c.pulled == c.after {
+		c.cancel()
+	}
+	return c.TransactionSource.Next(ctx)
+}
+
+func TestRuleEngine_ShardedEvaluate_SpillCancelled(t *testing.T) {
+	transactions := blacklistedTransactions(500)
+
+	t.Run("while pulling", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")}, WithSpill(dir, 1<<10))
+
+		result, err := engine.ShardedEvaluate(ctx, &cancellingSource{TransactionSource: NewSliceSource(transactions), after: 200, cancel: cancel}, 4)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, result.Alerts)
+		assertNoSpillFiles(t, dir)
+	})
+
+	t.Run("while evaluating", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		counter := &shardCounter{hook: func(call int64) {
+			if call == 3 {
+				cancel()
+			}
+		}}
+		sink := NewMemorySink()
+		engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR"), counter}, WithSpill(dir, 1<<10))
+		engine.AddAlertSink(sink)
+
+		_, err := engine.ShardedEvaluate(ctx, NewSliceSource(transactions), 4)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, int64(3), counter.calls.Load(), "no shard is loaded once cancelled")
+		assert.NotEmpty(t, sink.Alerts(), "shards evaluated before the cancellation published their alerts")
+		assertNoSpillFiles(t, dir)
+	})
+}
+
+func TestRuleEngine_ShardedEvaluate_SpillErrors(t *testing.T) {
+	dir := t.TempDir()
+	broken := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")}, WithSpill(dir+"/missing", 0))
+	_, err := broken.ShardedEvaluate(context.Background(), NewSliceSource(blacklistedTransactions(3)), 2)
+	assert.ErrorContains(t, err, "spill")
+
+	engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")}, WithSpill(dir, 0))
+	result, err := engine.ShardedEvaluate(context.Background(), &flakySource{transactions: blacklistedTransactions(3), err: assert.AnError}, 2)
+	assert.ErrorIs(t, err, assert.AnError)
+	assert.Equal(t, 3, result.TransactionCount)
+	assertNoSpillFiles(t, dir)
+}
+
+func TestSpillShard(t *testing.T) {
+	userID := uuid.New()
+	assert.Equal(t, userShard(userID, 16), spillShard(userID, 0, 16))
+
+	spread := make(map[int]bool)
+	for range 200 {
+		spread[spillShard(uuid.New(), 3, 4)] = true
+	}
+	assert.Len(t, spread, 4, "salted hashes spread users over every part")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// SplitPaymentProcessor flags users splitting one large payment to a counterparty into several
+// smaller ones minutes apart. Transactions above Threshold on their own are left to
+// TransactionAmountProcessor, and those without a CounterpartyID are ignored.
+type SplitPaymentProcessor struct {
+	// MaxGap chains consecutive payments to the same counterparty less than MaxGap apart into a cluster
+	MaxGap    time.Duration
+	Threshold decimal.Decimal
+	// MinClusterSize is the number of payments a cluster needs to be flagged, at least 2
+	MinClusterSize int
+}
+
+func NewSplitPaymentProcessor(maxGap time.Duration, threshold decimal.Decimal, minClusterSize int) SplitPaymentProcessor {
+	return SplitPaymentProcessor{
+		MaxGap:         maxGap,
+		Threshold:      threshold,
+		MinClusterSize: max(minClusterSize, 2),
+	}
+}
+
+func (s SplitPaymentProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return tx.CounterpartyID != "" && tx.Amount.LessThanOrEqual(s.Threshold)
+	}}
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			slices.SortFunc(txs, func(a, b Transaction) int {
+				return cmp.Or(cmp.Compare(a.CounterpartyID, b.CounterpartyID), a.CreatedAt.Compare(b.CreatedAt))
+			})
+
+			if s.hasSplitCluster(txs) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// hasSplitCluster walks txs sorted by counterparty then time, cutting a cluster at each new
+// counterparty or gap of MaxGap or more
+// Time complexity: O(n)
+func (s SplitPaymentProcessor) hasSplitCluster(txs []Transaction) bool {
+	minClusterSize := max(s.MinClusterSize, 2)
+
+	for start := 0; start < len(txs); {
+		sum := spendAmount(txs[start])
+		end := start + 1
+		for end < len(txs) &&
+			txs[end].CounterpartyID == txs[start].CounterpartyID &&
+			txs[end].CreatedAt.Sub(txs[end-1].CreatedAt) < s.MaxGap {
+			sum = sum.Add(spendAmount(txs[end]))
+			end++
+		}
+
+		if end-start >= minClusterSize && sum.GreaterThan(s.Threshold) {
+			return true
+		}
+		start = end
+	}
+
+	return false
+}
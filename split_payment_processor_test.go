@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPaymentProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	tx := func(offset time.Duration, counterpartyID string, amount int64) Transaction {
+		return Transaction{UserID: userID, CounterpartyID: counterpartyID, Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(offset)}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "3 x 4000 within 20 minutes",
+			transactions: []Transaction{tx(0, "landlord", 4000), tx(10*time.Minute, "landlord", 4000), tx(20*time.Minute, "landlord", 4000)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "3 x 4000 over 3 days",
+			transactions: []Transaction{tx(0, "landlord", 4000), tx(36*time.Hour, "landlord", 4000), tx(72*time.Hour, "landlord", 4000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "gap of exactly 30 minutes breaks the cluster",
+			transactions: []Transaction{tx(0, "landlord", 6000), tx(30*time.Minute, "landlord", 6000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "cluster chained by consecutive gaps",
+			transactions: []Transaction{tx(0, "landlord", 4000), tx(25*time.Minute, "landlord", 4000), tx(50*time.Minute, "landlord", 4000)},
+			wantFlagged:  true,
+		},
+		{
+			name:         "split across counterparties",
+			transactions: []Transaction{tx(0, "landlord", 4000), tx(10*time.Minute, "garage", 4000), tx(20*time.Minute, "landlord", 4000), tx(25*time.Minute, "garage", 4000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "single large transaction",
+			transactions: []Transaction{tx(0, "landlord", 25000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "large transaction does not complete a cluster",
+			transactions: []Transaction{tx(0, "landlord", 25000), tx(5*time.Minute, "landlord", 4000)},
+			wantFlagged:  false,
+		},
+		{
+			name:         "no counterparty",
+			transactions: []Transaction{tx(0, "", 4000), tx(10*time.Minute, "", 4000), tx(20*time.Minute, "", 4000)},
+			wantFlagged:  false,
+		},
+	}
+
+	processor := NewSplitPaymentProcessor(30*time.Minute, decimal.NewFromInt(10000), 2)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StatefulVelocityProcessor applies the velocity periods incrementally: each user's transactions are
+// retained in a WindowStore across Process calls for as long as the longest period needs them, so
+// windows spanning several batches are still detected
+type StatefulVelocityProcessor struct {
+	Periods []VelocityPeriod
+
+	options velocityOptions
+	store   WindowStore
+
+	mu      sync.Mutex
+	flagged map[uuid.UUID]struct{}
+}
+
+// NewStatefulVelocityProcessor creates a StatefulVelocityProcessor retaining state in an InMemoryWindowStore
+func NewStatefulVelocityProcessor(periods []VelocityPeriod, opts ...VelocityOption) *StatefulVelocityProcessor {
+	return NewStatefulVelocityProcessorWithStore(periods, NewInMemoryWindowStore(), opts...)
+}
+
+// NewStatefulVelocityProcessorWithStore creates a StatefulVelocityProcessor retaining state in store
+func NewStatefulVelocityProcessorWithStore(periods []VelocityPeriod, store WindowStore, opts ...VelocityOption) *StatefulVelocityProcessor {
+	return &StatefulVelocityProcessor{
+		Periods: periods,
+		options: newVelocityOptions(opts),
+		store:   store,
+		flagged: make(map[uuid.UUID]struct{}),
+	}
+}
+
+// UserErrors collects per-user failures that did not abort the rest of a batch
+type UserErrors map[uuid.UUID]error
+
+func (e UserErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for userID, err := range e {
+		messages = append(messages, fmt.Sprintf("user %s: %v", userID, err))
+	}
+	sort.Strings(messages)
+
+	return strings.Join(messages, "; ")
+}
+
+// GroupingKey returns the entity tx is grouped under, as set by WithGroupingKey
+func (v *StatefulVelocityProcessor) GroupingKey(tx Transaction) uuid.UUID {
+	return v.options.key(tx)
+}
+
+func (v *StatefulVelocityProcessor) groupsByUser() bool {
+	return v.options.groupingKey == nil
+}
+
+func (v *StatefulVelocityProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers, _ := v.ProcessContext(ctx, transactions)
+	return flaggedUsers
+}
+
+// ProcessContext evaluates the batch together with each user's retained history and returns the users
+// of this batch that violate a period. Store failures are reported per user in UserErrors; a user whose
+// history could not be read is neither evaluated nor stored, the other users are unaffected. Once ctx
+// is done the remaining users are left unevaluated and unstored, and ctx's error is returned with the
+// users flagged so far.
+func (v *StatefulVelocityProcessor) ProcessContext(ctx context.Context, transactions []Transaction) (map[uuid.UUID]struct{}, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	checker := newVelocityChecker(v.Periods, velocityOptions{})
+	retention := horizon(v.Periods)
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	userErrors := make(UserErrors)
+	progress := ruleProgressFrom(ctx)
+
+	for _, userTransactions := range groupTransactions(transactions, v.options, nil) {
+		for userID, txs := range userTransactions {
+			if err := ctx.Err(); err != nil {
+				return flaggedUsers, err
+			}
+
+			violated, err := v.processUser(ctx, checker, retention, userID, txs)
+			progress.add(1, len(txs))
+			if err != nil {
+				userErrors[userID] = err
+			}
+
+			if violated {
+				flaggedUsers[userID] = struct{}{}
+				v.flagged[userID] = struct{}{}
+			}
+		}
+	}
+
+	if len(userErrors) > 0 {
+		return flaggedUsers, userErrors
+	}
+
+	return flaggedUsers, nil
+}
+
+// processUser makes one Range, one Append and one Trim call to the store for the user's batch
+func (v *StatefulVelocityProcessor) processUser(ctx context.Context, checker velocityChecker, retention time.Duration, userID uuid.UUID, txs []Transaction) (bool, error) {
+	earliest, latest := txs[0].CreatedAt, txs[0].CreatedAt
+	for _, tx := range txs {
+		if tx.CreatedAt.Before(earliest) {
+			earliest = tx.CreatedAt
+		}
+		if tx.CreatedAt.After(latest) {
+			latest = tx.CreatedAt
+		}
+	}
+
+	// Only retained entries that can share a window with this batch take part
+	retained, err := v.store.Range(ctx, userID, earliest.Add(-retention), time.Time{})
+	if err != nil {
+		return false, fmt.Errorf("range window: %w", err)
+	}
+
+	history := make([]Transaction, 0, len(retained)+len(txs))
+	entries := make([]WindowEntry, 0, len(txs))
+	for _, entry := range retained {
+		history = append(history, Transaction{UserID: userID, Amount: entry.Amount, CreatedAt: entry.CreatedAt})
+		if entry.CreatedAt.After(latest) {
+			latest = entry.CreatedAt
+		}
+	}
+	for _, tx := range txs {
+		history = append(history, tx)
+		entries = append(entries, WindowEntry{CreatedAt: tx.CreatedAt, Amount: tx.Amount})
+	}
+
+	violated, _ := checker.CheckUser(history)
+
+	if err := v.store.Append(ctx, userID, entries); err != nil {
+		return violated, fmt.Errorf("append window: %w", err)
+	}
+	if err := v.store.Trim(ctx, userID, latest.Add(-retention)); err != nil {
+		return violated, fmt.Errorf("trim window: %w", err)
+	}
+
+	return violated, nil
+}
+
+// Flagged returns every user flagged since the processor was created or restored
+func (v *StatefulVelocityProcessor) Flagged() map[uuid.UUID]struct{} {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	flaggedUsers := make(map[uuid.UUID]struct{}, len(v.flagged))
+	for userID := range v.flagged {
+		flaggedUsers[userID] = struct{}{}
+	}
+
+	return flaggedUsers
+}
+
+// horizon is how far back from a user's latest transaction history is still needed
+func horizon(periods []VelocityPeriod) time.Duration {
+	var longest time.Duration
+	for _, period := range periods {
+		longest = max(longest, period.Duration)
+	}
+
+	return longest
+}
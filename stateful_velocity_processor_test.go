@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatefulVelocityProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	processor := NewStatefulVelocityProcessor([]VelocityPeriod{NewVelocityPeriod(week, 2)})
+
+	first := processor.Process(context.Background(), []Transaction{
+		{UserID: userID, CreatedAt: baseTime},
+		{UserID: userID, CreatedAt: baseTime.Add(time.Hour)},
+	})
+	assert.Empty(t, first)
+
+	second := processor.Process(context.Background(), []Transaction{
+		{UserID: userID, CreatedAt: baseTime.Add(2 * time.Hour)},
+	})
+	assert.Equal(t, map[uuid.UUID]struct{}{userID: {}}, second)
+
+	// History older than the longest period is dropped
+	third := processor.Process(context.Background(), []Transaction{
+		{UserID: userID, CreatedAt: baseTime.Add(30 * 24 * time.Hour)},
+	})
+	assert.Empty(t, third)
+	assert.Len(t, retainedEntries(t, processor, userID), 1)
+	assert.Contains(t, processor.Flagged(), userID)
+}
+
+func TestStatefulVelocityProcessor_SnapshotRestore(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID1 := uuid.New()
+	userID2 := uuid.New()
+	periods := []VelocityPeriod{NewNamedVelocityPeriod("weekly", week, 2)}
+
+	before := NewStatefulVelocityProcessor(periods)
+	before.Process(context.Background(), []Transaction{
+		{UserID: userID1, Amount: decimal.RequireFromString("10.005"), CreatedAt: baseTime.Add(123 * time.Nanosecond)},
+		{UserID: userID1, Amount: decimal.RequireFromString("20"), CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID2, Amount: decimal.RequireFromString("1"), CreatedAt: baseTime},
+		{UserID: userID2, Amount: decimal.RequireFromString("2"), CreatedAt: baseTime.Add(time.Minute)},
+		{UserID: userID2, Amount: decimal.RequireFromString("3"), CreatedAt: baseTime.Add(2 * time.Minute)},
+	})
+
+	data, err := before.Snapshot()
+	require.NoError(t, err)
+
+	// A restarted processor picks up the flags and the window straddling the restart
+	after := NewStatefulVelocityProcessor(periods)
+	require.NoError(t, after.Restore(data))
+
+	assert.Equal(t, map[uuid.UUID]struct{}{userID2: {}}, after.Flagged())
+	restored := retainedEntries(t, after, userID1)
+	assert.Len(t, restored, 2)
+	assert.True(t, restored[0].Amount.Equal(decimal.RequireFromString("10.005")))
+	assert.True(t, restored[0].CreatedAt.Equal(baseTime.Add(123*time.Nanosecond)))
+
+	flaggedUsers := after.Process(context.Background(), []Transaction{
+		{UserID: userID1, Amount: decimal.RequireFromString("30"), CreatedAt: baseTime.Add(2 * time.Hour)},
+	})
+	assert.Equal(t, map[uuid.UUID]struct{}{userID1: {}}, flaggedUsers)
+
+	roundTrip, err := after.Snapshot()
+	require.NoError(t, err)
+	again := NewStatefulVelocityProcessor(periods)
+	require.NoError(t, again.Restore(roundTrip))
+	secondTrip, err := again.Snapshot()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(roundTrip), string(secondTrip))
+}
+
+func TestStatefulVelocityProcessor_SnapshotRestore_After2038(t *testing.T) {
+	baseTime := time.Date(2040, 6, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	periods := []VelocityPeriod{NewVelocityPeriod(week, 2)}
+
+	empty, err := NewStatefulVelocityProcessor(periods).Snapshot()
+	require.NoError(t, err)
+
+	processor := NewStatefulVelocityProcessor(periods)
+	processor.Process(context.Background(), []Transaction{
+		{UserID: userID, Amount: decimal.NewFromInt(1), CreatedAt: baseTime},
+		{UserID: userID, Amount: decimal.NewFromInt(2), CreatedAt: baseTime.Add(time.Hour)},
+	})
+	data, err := processor.Snapshot()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "2040-06-01T01:00:00Z")
+
+	require.NoError(t, processor.Restore(empty))
+	assert.Empty(t, retainedEntries(t, processor, userID), "restoring clears entries dated after 2038")
+
+	flaggedUsers := processor.Process(context.Background(), []Transaction{
+		{UserID: userID, Amount: decimal.NewFromInt(3), CreatedAt: baseTime.Add(2 * time.Hour)},
+	})
+	assert.Empty(t, flaggedUsers)
+}
+
+func TestStatefulVelocityProcessor_Restore_Rejects(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	weekly := NewStatefulVelocityProcessor([]VelocityPeriod{NewVelocityPeriod(week, 2)})
+	weekly.Process(context.Background(), []Transaction{{UserID: userID, CreatedAt: baseTime}})
+	weeklySnapshot, err := weekly.Snapshot()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		periods []VelocityPeriod
+		data    []byte
+		wantErr error
+	}{
+		{
+			name:    "corrupted payload",
+			periods: []VelocityPeriod{NewVelocityPeriod(week, 2)},
+			data:    weeklySnapshot[:len(weeklySnapshot)/2],
+		},
+		{
+			name:    "unsupported version",
+			periods: []VelocityPeriod{NewVelocityPeriod(week, 2)},
+			data:    []byte(`{"version":99,"periods":[],"users":[]}`),
+		},
+		{
+			name:    "invalid amount",
+			periods: []VelocityPeriod{NewVelocityPeriod(week, 2)},
+			data:    []byte(`{"version":1,"periods":[{"duration":"168h0m0s","threshold":2}],"users":[{"user_id":"` + userID.String() + `","transactions":[{"amount":"ten","created_at":"2024-03-01T00:00:00Z"}]}]}`),
+		},
+		{
+			name:    "horizon too short",
+			periods: []VelocityPeriod{NewVelocityPeriod(month, 2)},
+			data:    weeklySnapshot,
+			wantErr: ErrSnapshotHorizonTooShort,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewStatefulVelocityProcessor(tt.periods)
+			processor.Process(context.Background(), []Transaction{{UserID: userID, CreatedAt: baseTime}})
+
+			err := processor.Restore(tt.data)
+
+			assert.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Len(t, retainedEntries(t, processor, userID), 1, "state must be untouched after a rejected restore")
+		})
+	}
+}
+
+func retainedEntries(t *testing.T, processor *StatefulVelocityProcessor, userID uuid.UUID) []WindowEntry {
+	t.Helper()
+
+	entries, err := processor.store.Range(context.Background(), userID, time.Time{}, time.Time{})
+	require.NoError(t, err)
+
+	return entries
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StreamingReportWriter writes the report of each run as NDJSON while the run goes on: one "alert"
+// record per alert, written as the engine builds the alerts of each rule, then a "summary" trailer
+// once the run is over. Every record is written with a single Write and flushed right after when w
+// has a Flush method, e.g. a *bufio.Writer, so a run stopped midway leaves valid NDJSON up to its last
+// record. It is safe for concurrent use, runs evaluated in parallel having their records interleaved,
+// each carrying its run_id.
+type StreamingReportWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	buf     bytes.Buffer
+	encoder *json.Encoder
+}
+
+func NewStreamingReportWriter(w io.Writer) *StreamingReportWriter {
+	s := &StreamingReportWriter{w: w}
+	s.encoder = json.NewEncoder(&s.buf)
+
+	return s
+}
+
+// WithStreamingReport writes the report of every run to report as the run goes on. Write errors fail
+// the run they happen in, after its alerts reached the sinks.
+func WithStreamingReport(report *StreamingReportWriter) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.report = report
+	}
+}
+
+// streamRecord is one line of a streamed report, Type telling which of the other fields are set
+type streamRecord struct {
+	Type  string    `json:"type"`
+	RunID uuid.UUID `json:"run_id"`
+	Alert *Alert    `json:"alert,omitempty"`
+
+	StartedAt        *time.Time    `json:"started_at,omitempty"`
+	FinishedAt       *time.Time    `json:"finished_at,omitempty"`
+	TransactionCount *int          `json:"transaction_count,omitempty"`
+	AlertCount       *int          `json:"alert_count,omitempty"`
+	Rules            []RuleSummary `json:"rules,omitempty"`
+	Summary          *Summary      `json:"summary,omitempty"`
+	ConfigVersion    string        `json:"config_version,omitempty"`
+}
+
+// WriteAlert writes an "alert" record for alert, raised during run runID
+func (s *StreamingReportWriter) WriteAlert(runID uuid.UUID, alert Alert) error {
+	alert = reportAlerts([]Alert{alert})[0]
+
+	return s.write(streamRecord{Type: "alert", RunID: runID, Alert: &alert})
+}
+
+// WriteSummary writes the "summary" trailer of a run, with its rules and summary but not its alerts
+func (s *StreamingReportWriter) WriteSummary(result EvaluationResult) error {
+	startedAt, finishedAt := result.StartedAt.UTC(), result.FinishedAt.UTC()
+	alertCount := len(result.Alerts)
+
+	return s.write(streamRecord{
+		Type:             "summary",
+		RunID:            result.RunID,
+		StartedAt:        &startedAt,
+		FinishedAt:       &finishedAt,
+		TransactionCount: &result.TransactionCount,
+		AlertCount:       &alertCount,
+		Rules:            result.Rules,
+		Summary:          &result.Summary,
+		ConfigVersion:    result.ConfigVersion,
+	})
+}
+
+// write encodes record, then writes and flushes it in one go
+func (s *StreamingReportWriter) write(record streamRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf.Reset()
+	if err := s.encoder.Encode(record); err != nil {
+		return err
+	}
+	if _, err := s.w.Write(s.buf.Bytes()); err != nil {
+		return err
+	}
+	if flusher, ok := s.w.(interface{ Flush() error }); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
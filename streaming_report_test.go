@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatedProcessor flags nobody, signalling started then waiting for release before returning
+type gatedProcessor struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (g gatedProcessor) Process(context.Context, []Transaction) map[uuid.UUID]struct{} {
+	close(g.started)
+	<-g.release
+	return map[uuid.UUID]struct{}{}
+}
+
+// streamedRecords decodes the complete lines of a streamed report, ignoring a trailing partial line
+func streamedRecords(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+
+	var records []map[string]any
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines[:len(lines)-1] {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record), "line %q", line)
+		records = append(records, record)
+	}
+
+	return records
+}
+
+func TestStreamingReportWriter_Evaluate(t *testing.T) {
+	var buf bytes.Buffer
+	engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")},
+		WithStreamingReport(NewStreamingReportWriter(&buf)))
+	transactions := blacklistedTransactions(2)
+
+	result, err := engine.Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+
+	records := streamedRecords(t, buf.Bytes())
+	require.Len(t, records, 3)
+	for _, record := range records[:2] {
+		assert.Equal(t, "alert", record["type"])
+		assert.Equal(t, result.RunID.String(), record["run_id"])
+		assert.Equal(t, "CountryBlackListProcessor", record["alert"].(map[string]any)["rule_name"])
+	}
+
+	trailer := records[2]
+	assert.Equal(t, "summary", trailer["type"])
+	assert.Equal(t, result.RunID.String(), trailer["run_id"])
+	assert.Equal(t, 2.0, trailer["transaction_count"])
+	assert.Equal(t, 2.0, trailer["alert_count"])
+	assert.Equal(t, 2.0, trailer["summary"].(map[string]any)["flagged_users"])
+	assert.Len(t, trailer["rules"], 1)
+	assert.NotContains(t, trailer, "alert")
+}
+
+func TestStreamingReportWriter_StoppedMidRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.ndjson")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	gate := gatedProcessor{started: make(chan struct{}), release: make(chan struct{})}
+	engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR"), gate},
+		WithStreamingReport(NewStreamingReportWriter(bufio.NewWriter(file))))
+
+	done := make(chan error)
+	go func() {
+		_, err := engine.Evaluate(context.Background(), blacklistedTransactions(3))
+		done <- err
+	}()
+	<-gate.started
+
+	// the process dying here would leave the first rule's alerts, flushed, and nothing else
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	records := streamedRecords(t, data)
+	require.Len(t, records, 3)
+	for _, record := range records {
+		assert.Equal(t, "alert", record["type"])
+	}
+
+	close(gate.release)
+	require.NoError(t, <-done)
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "summary", streamedRecords(t, data)[3]["type"])
+}
+
+// crashingWriter writes records until limit bytes, then writes what fits and fails as a dying process would
+type crashingWriter struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (c *crashingWriter) Write(p []byte) (int, error) {
+	if room := c.limit - c.buf.Len(); len(p) > room {
+		c.buf.Write(p[:room])
+		return room, errors.New("crashed")
+	}
+
+	return c.buf.Write(p)
+}
+
+func TestStreamingReportWriter_CrashMidRecord(t *testing.T) {
+	var full bytes.Buffer
+	transactions := blacklistedTransactions(4)
+	_, err := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")},
+		WithStreamingReport(NewStreamingReportWriter(&full))).Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+	secondRecordEnd := bytes.Index(full.Bytes(), []byte("\n")) + 1
+	secondRecordEnd += bytes.Index(full.Bytes()[secondRecordEnd:], []byte("\n")) + 1
+
+	// the crash cuts the third record in half
+	writer := &crashingWriter{limit: secondRecordEnd + 20}
+	engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")},
+		WithStreamingReport(NewStreamingReportWriter(writer)))
+	engine.AddAlertSink(NewMemorySink())
+
+	result, err := engine.Evaluate(context.Background(), transactions)
+
+	assert.ErrorContains(t, err, "streaming report: crashed")
+	assert.Len(t, result.Alerts, 4, "a failing report does not stop the run")
+	records := streamedRecords(t, writer.buf.Bytes())
+	assert.Len(t, records, 2, "records up to the crash stay readable")
+	assert.Equal(t, 20, writer.buf.Len()-secondRecordEnd, "nothing is written after the failure")
+}
+
+func TestStreamingReportWriter_Concurrent(t *testing.T) {
+	var buf bytes.Buffer
+	engine := NewRuleEngine([]RuleProcessor{
+		NewCountryBlackListProcessor("IR"),
+		NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 0)}),
+	}, WithStreamingReport(NewStreamingReportWriter(&buf)), WithRuleWorkers(2))
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := engine.Evaluate(context.Background(), blacklistedTransactions(5))
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	records := streamedRecords(t, buf.Bytes())
+	assert.Len(t, records, 8*(10+1))
+	trailers := make(map[any]int)
+	for _, record := range records {
+		if record["type"] == "summary" {
+			trailers[record["run_id"]]++
+		}
+	}
+	assert.Len(t, trailers, 8)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// StructuringProcessor flags users splitting a large transfer into several transactions just below
+// the reporting threshold. Amounts at or above the threshold are left to TransactionAmountProcessor.
+type StructuringProcessor struct {
+	ReportingThreshold decimal.Decimal
+	// Band is the fraction below ReportingThreshold considered near it, e.g. 0.1 for within 10%
+	Band   decimal.Decimal
+	Count  int
+	Window time.Duration
+	// Amounts rounds amounts to their currency's scale before comparison, the zero AmountPolicy when nil
+	Amounts *AmountPolicy
+}
+
+// NewStructuringProcessor flags users with at least count near-threshold transactions within window
+func NewStructuringProcessor(reportingThreshold, band decimal.Decimal, count int, window time.Duration) StructuringProcessor {
+	return StructuringProcessor{
+		ReportingThreshold: reportingThreshold,
+		Band:               band,
+		Count:              count,
+		Window:             window,
+	}
+}
+
+// isNearThreshold reports whether the rounded amount of tx lies in [threshold * (1 - band), threshold)
+func (s StructuringProcessor) isNearThreshold(tx Transaction) bool {
+	amount := s.Amounts.orDefault().amount(tx)
+	lower := s.ReportingThreshold.Mul(decimal.NewFromInt(1).Sub(s.Band))
+
+	return amount.GreaterThanOrEqual(lower) && amount.LessThan(s.ReportingThreshold)
+}
+
+func (s StructuringProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	options := velocityOptions{filter: func(tx Transaction) bool {
+		return s.isNearThreshold(tx)
+	}}
+	// "at least Count" is a velocity period flagging more than Count-1
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(s.Window, s.Count-1)}, options)
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	// Same per-user sort + sliding window as the velocity processors, over near-threshold transactions only
+	for _, userTransactions := range groupTransactions(transactions, options, nil) {
+		for userID, txs := range userTransactions {
+			if violated, _ := checker.CheckUser(txs); violated {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// ProcessGrouped is Process over the batch the engine grouped once for every rule, reading each
+// user's near-threshold transactions from the shared ordering by CreatedAt
+func (s StructuringProcessor) ProcessGrouped(_ context.Context, grouped GroupedTransactions) map[uuid.UUID]struct{} {
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(s.Window, s.Count-1)}, velocityOptions{})
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	var near []Transaction
+	for _, userID := range grouped.Users() {
+		near = near[:0]
+		for _, tx := range grouped.SortedForUser(userID) {
+			if s.isNearThreshold(tx) {
+				near = append(near, tx)
+			}
+		}
+
+		if violated, _ := checker.scan(near, true); violated {
+			flaggedUsers[userID] = struct{}{}
+		}
+	}
+
+	return flaggedUsers
+}
+
+func (s StructuringProcessor) AlertDetails(_ context.Context, _ uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	var evidence []Transaction
+	for _, tx := range transactions {
+		if s.isNearThreshold(tx) {
+			evidence = append(evidence, tx)
+		}
+	}
+
+	return evidence, map[string]string{
+		"reporting_threshold": s.ReportingThreshold.String(),
+		"band":                s.Band.String(),
+		"min_count":           strconv.Itoa(s.Count),
+		"window":              formatDuration(s.Window),
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructuringProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	tx := func(hours int, amount string) Transaction {
+		return Transaction{
+			UserID:    userID,
+			Amount:    decimal.RequireFromString(amount),
+			CreatedAt: baseTime.Add(time.Duration(hours) * time.Hour),
+		}
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "three near-threshold transactions in a day",
+			transactions: []Transaction{tx(0, "9990"), tx(2, "9500"), tx(5, "9000")},
+			wantFlagged:  true,
+		},
+		{
+			name:         "one amount outside the band",
+			transactions: []Transaction{tx(0, "9990"), tx(2, "9500"), tx(5, "8000")},
+			wantFlagged:  false,
+		},
+		{
+			name:         "amounts at or above the threshold do not count",
+			transactions: []Transaction{tx(0, "9990"), tx(2, "10000"), tx(5, "12000"), tx(6, "9500")},
+			wantFlagged:  false,
+		},
+		{
+			name:         "window spanning the boundary exactly",
+			transactions: []Transaction{tx(0, "9990"), tx(12, "9500"), tx(24, "9900")},
+			wantFlagged:  true,
+		},
+		{
+			name:         "window just past the boundary",
+			transactions: []Transaction{tx(0, "9990"), tx(12, "9500"), tx(25, "9900")},
+			wantFlagged:  false,
+		},
+		{
+			name:         "unsorted input",
+			transactions: []Transaction{tx(20, "9990"), tx(0, "9500"), tx(72, "9100"), tx(10, "9800")},
+			wantFlagged:  true,
+		},
+	}
+
+	processor := NewStructuringProcessor(decimal.NewFromInt(10000), decimal.RequireFromString("0.1"), 3, 24*time.Hour)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
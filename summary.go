@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Summary holds the headline numbers of a run, computed from its alerts and input batch
+type Summary struct {
+	TotalTransactions int `json:"total_transactions"`
+	DistinctUsers     int `json:"distinct_users"`
+	FlaggedUsers      int `json:"flagged_users"`
+	// FlaggedRate is FlaggedUsers over DistinctUsers, zero for an empty batch
+	FlaggedRate float64 `json:"flagged_rate"`
+	// RuleFlags counts the users flagged per rule name
+	RuleFlags map[string]int `json:"rule_flags"`
+	// SeverityFlags counts the alerts per severity
+	SeverityFlags map[Severity]int `json:"severity_flags"`
+	// CountryFlags counts, per normalized country, the alerts with evidence in that country
+	CountryFlags map[string]int `json:"country_flags"`
+	// RuleAmounts sums the evidence amounts per rule name, reversals included
+	RuleAmounts map[string]decimal.Decimal `json:"rule_amounts"`
+	// DuplicatesDropped counts the transactions dropped by WithDeduplication, not in TotalTransactions
+	DuplicatesDropped int           `json:"duplicates_dropped,omitempty"`
+	Duration          time.Duration `json:"duration_ns"`
+}
+
+// Summarize computes the Summary of alerts raised on transactions. Duration is left for the caller to set.
+func Summarize(alerts []Alert, transactions []Transaction) Summary {
+	summary := Summary{
+		TotalTransactions: len(transactions),
+		RuleFlags:         make(map[string]int),
+		SeverityFlags:     make(map[Severity]int),
+		CountryFlags:      make(map[string]int),
+		RuleAmounts:       make(map[string]decimal.Decimal),
+	}
+
+	users := make(map[uuid.UUID]struct{})
+	for _, tx := range transactions {
+		users[tx.UserID] = struct{}{}
+	}
+	summary.DistinctUsers = len(users)
+
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	for _, alert := range alerts {
+		flaggedUsers[alert.UserID] = struct{}{}
+		summary.RuleFlags[alert.RuleName]++
+		summary.SeverityFlags[alert.Severity]++
+
+		countries := make(map[string]struct{})
+		amount := summary.RuleAmounts[alert.RuleName]
+		for _, tx := range alert.Evidence {
+			if country := normalizeCountry(tx.Country); country != "" {
+				countries[country] = struct{}{}
+			}
+			amount = amount.Add(tx.Amount)
+		}
+		summary.RuleAmounts[alert.RuleName] = amount
+		for country := range countries {
+			summary.CountryFlags[country]++
+		}
+	}
+	summary.FlaggedUsers = len(flaggedUsers)
+
+	if summary.DistinctUsers > 0 {
+		summary.FlaggedRate = float64(summary.FlaggedUsers) / float64(summary.DistinctUsers)
+	}
+
+	return summary
+}
+
+// String renders the summary as a few lines of text, rules and countries sorted by name
+func (s Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "transactions: %d, users: %d, flagged: %d (%.1f%%), duration: %s\n",
+		s.TotalTransactions, s.DistinctUsers, s.FlaggedUsers, s.FlaggedRate*100, s.Duration)
+	if s.DuplicatesDropped > 0 {
+		fmt.Fprintf(&b, "duplicates dropped: %d\n", s.DuplicatesDropped)
+	}
+
+	for _, rule := range slices.Sorted(maps.Keys(s.RuleFlags)) {
+		fmt.Fprintf(&b, "rule %s: %d flagged, evidence amount %s\n", rule, s.RuleFlags[rule], s.RuleAmounts[rule])
+	}
+	for _, severity := range slices.Backward(severityRank) {
+		if count := s.SeverityFlags[severity]; count > 0 {
+			fmt.Fprintf(&b, "severity %s: %d alerts\n", severity, count)
+		}
+	}
+	for _, country := range slices.Sorted(maps.Keys(s.CountryFlags)) {
+		fmt.Fprintf(&b, "country %s: %d flagged\n", country, s.CountryFlags[country])
+	}
+
+	return b.String()
+}
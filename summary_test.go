@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarize(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	alice, bob, carol, dave := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	transactions := []Transaction{
+		{UserID: alice, Country: "IR", Amount: decimal.NewFromInt(100), CreatedAt: baseTime},
+		{UserID: alice, Country: "fr", Amount: decimal.NewFromInt(20000), CreatedAt: baseTime},
+		{UserID: bob, Country: "FR", Amount: decimal.NewFromInt(15000), CreatedAt: baseTime},
+		{UserID: bob, Country: "FR", Amount: decimal.NewFromInt(-500), CreatedAt: baseTime},
+		{UserID: carol, Country: "DE", Amount: decimal.NewFromInt(50), CreatedAt: baseTime},
+		{UserID: dave, Amount: decimal.NewFromInt(5), CreatedAt: baseTime},
+	}
+
+	alerts := []Alert{
+		{UserID: alice, RuleName: "CountryBlackListProcessor", Evidence: transactions[0:1]},
+		{UserID: alice, RuleName: "TransactionAmountProcessor", Evidence: transactions[1:2]},
+		{UserID: bob, RuleName: "TransactionAmountProcessor", Evidence: transactions[2:3]},
+		{UserID: bob, RuleName: "VelocityProcessor", Evidence: transactions[2:4]},
+	}
+
+	summary := Summarize(alerts, transactions)
+
+	// 6 transactions from 4 users, alice and bob being flagged
+	assert.Equal(t, 6, summary.TotalTransactions)
+	assert.Equal(t, 4, summary.DistinctUsers)
+	assert.Equal(t, 2, summary.FlaggedUsers)
+	assert.InDelta(t, 0.5, summary.FlaggedRate, 1e-9)
+	assert.Equal(t, map[string]int{
+		"CountryBlackListProcessor":  1,
+		"TransactionAmountProcessor": 2,
+		"VelocityProcessor":          1,
+	}, summary.RuleFlags)
+	// bob's velocity alert has two FR transactions but counts once
+	assert.Equal(t, map[string]int{"IR": 1, "FR": 3}, summary.CountryFlags)
+
+	// 20000 + 15000, and 15000 - 500 for the velocity evidence
+	assert.Equal(t, "100", summary.RuleAmounts["CountryBlackListProcessor"].String())
+	assert.Equal(t, "35000", summary.RuleAmounts["TransactionAmountProcessor"].String())
+	assert.Equal(t, "14500", summary.RuleAmounts["VelocityProcessor"].String())
+
+	summary.Duration = 1500 * time.Millisecond
+	assert.Equal(t, "transactions: 6, users: 4, flagged: 2 (50.0%), duration: 1.5s\n"+
+		"rule CountryBlackListProcessor: 1 flagged, evidence amount 100\n"+
+		"rule TransactionAmountProcessor: 2 flagged, evidence amount 35000\n"+
+		"rule VelocityProcessor: 1 flagged, evidence amount 14500\n"+
+		"country FR: 3 flagged\n"+
+		"country IR: 1 flagged\n", summary.String())
+
+	data, err := json.Marshal(summary)
+	require.NoError(t, err)
+
+	var decoded Summary
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, summary.RuleFlags, decoded.RuleFlags)
+	assert.Equal(t, summary.Duration, decoded.Duration)
+	assert.True(t, decoded.RuleAmounts["VelocityProcessor"].Equal(decimal.NewFromInt(14500)))
+	assert.Contains(t, string(data), `"TransactionAmountProcessor":"35000"`)
+}
+
+func TestSummarize_Empty(t *testing.T) {
+	summary := Summarize(nil, nil)
+
+	assert.Zero(t, summary.FlaggedRate)
+	assert.Empty(t, summary.RuleFlags)
+	assert.Equal(t, "transactions: 0, users: 0, flagged: 0 (0.0%), duration: 0s\n", summary.String())
+}
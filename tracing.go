@@ -0,0 +1,56 @@
+package main
+
+import "context"
+
+// Tracer starts the spans of an evaluation, e.g. OpenTelemetry spans through the oteltrace package.
+// Evaluate runs in an "aml.evaluate" span, each rule in a child "aml.rule" span, and the workers of
+// WorkerVelocityProcessor and ConcurrentVelocityProcessor in "aml.velocity_worker" spans below it.
+type Tracer interface {
+	// Start begins a span named name as a child of the span carried by ctx, if any, returning the
+	// context carrying the new span and the function ending it with its attributes and error.
+	// Attribute values are strings, ints, bools or float64s.
+	Start(ctx context.Context, name string) (context.Context, func(attributes map[string]any, err error))
+}
+
+// WithTracer traces every evaluation with tracer. Without it, no spans are started.
+func WithTracer(tracer Tracer) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.tracer = tracer
+	}
+}
+
+// span attribute keys
+const (
+	attrTransactions = "aml.transactions"
+	attrRules        = "aml.rules"
+	attrAlerts       = "aml.alerts"
+	attrRule         = "aml.rule"
+	attrFlaggedUsers = "aml.flagged_users"
+	attrUsers        = "aml.users"
+)
+
+type tracerKey struct{}
+
+// endSpan ends a span started by startSpan
+type endSpan func(attributes map[string]any, err error)
+
+// startSpan starts a span with the tracer the engine put in ctx, so processors can trace their
+// goroutines under the span of their rule. It does nothing when the evaluation is not traced.
+func startSpan(ctx context.Context, name string) (context.Context, endSpan) {
+	tracer, ok := ctx.Value(tracerKey{}).(Tracer)
+	if !ok {
+		return ctx, func(map[string]any, error) {}
+	}
+
+	ctx, end := tracer.Start(ctx, name)
+	return ctx, end
+}
+
+// withTracer returns ctx carrying the engine's tracer, if it has one
+func (r *RuleEngine) withTracer(ctx context.Context) context.Context {
+	if r.tracer == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, tracerKey{}, r.tracer)
+}
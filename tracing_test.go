@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"aml_rule_engine/oteltrace"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var _ Tracer = oteltrace.Tracer{}
+
+// recordedSpan is a span ended by a recordingTracer
+type recordedSpan struct {
+	id         int
+	parent     int
+	name       string
+	attributes map[string]any
+	err        error
+}
+
+// recordingTracer keeps the spans it ended, in ending order, a parent of 0 meaning a root span
+type recordingTracer struct {
+	mu    sync.Mutex
+	next  int
+	ended []recordedSpan
+}
+
+type recordedSpanKey struct{}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, func(map[string]any, error)) {
+	t.mu.Lock()
+	t.next++
+	span := recordedSpan{id: t.next, name: name}
+	t.mu.Unlock()
+	span.parent, _ = ctx.Value(recordedSpanKey{}).(int)
+
+	return context.WithValue(ctx, recordedSpanKey{}, span.id), func(attributes map[string]any, err error) {
+		span.attributes, span.err = attributes, err
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		t.ended = append(t.ended, span)
+	}
+}
+
+// named returns the ended spans called name
+func (t *recordingTracer) named(name string) []recordedSpan {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var spans []recordedSpan
+	for _, span := range t.ended {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+func TestRuleEngine_Evaluate_Tracing(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID, other := uuid.New(), uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, Country: "IR", CreatedAt: baseTime.Add(time.Hour)},
+		{UserID: userID, Country: "IR", CreatedAt: baseTime},
+		{UserID: other, Country: "FR", CreatedAt: baseTime},
+	}
+	periods := []VelocityPeriod{NewVelocityPeriod(week, 1)}
+
+	for _, ruleWorkers := range []int{1, 3} {
+		tracer := &recordingTracer{}
+		engine := NewRuleEngine([]RuleProcessor{
+			NewWorkerVelocityProcessor(periods, 2),
+			NewConcurrentVelocityProcessor(periods, 2, WithStrictSortedInput()),
+			NewCountryBlackListProcessor("IR"),
+		}, WithTracer(tracer), WithRuleWorkers(ruleWorkers))
+
+		_, err := engine.Evaluate(context.Background(), transactions)
+		require.Error(t, err)
+
+		roots := tracer.named("aml.evaluate")
+		require.Len(t, roots, 1)
+		root := roots[0]
+		assert.Zero(t, root.parent)
+		assert.Equal(t, map[string]any{attrTransactions: 3, attrRules: 3, attrAlerts: 2}, root.attributes)
+		assert.Error(t, root.err)
+
+		rules := tracer.named("aml.rule")
+		require.Len(t, rules, 3)
+		ruleSpans := make(map[string]recordedSpan)
+		for _, span := range rules {
+			assert.Equal(t, root.id, span.parent, "rule spans are children of the evaluation")
+			ruleSpans[span.attributes[attrRule].(string)] = span
+		}
+		assert.Equal(t, map[string]any{attrRule: "WorkerVelocityProcessor", attrTransactions: 3, attrFlaggedUsers: 1},
+			ruleSpans["WorkerVelocityProcessor"].attributes)
+		assert.NoError(t, ruleSpans["WorkerVelocityProcessor"].err)
+		assert.Equal(t, map[string]any{attrRule: "ConcurrentVelocityProcessor", attrTransactions: 3, attrFlaggedUsers: 0},
+			ruleSpans["ConcurrentVelocityProcessor"].attributes)
+		assert.ErrorIs(t, ruleSpans["ConcurrentVelocityProcessor"].err, ErrUnsortedTransactions)
+		assert.Equal(t, 1, ruleSpans["CountryBlackListProcessor"].attributes[attrFlaggedUsers])
+
+		// each velocity rule's workers nest under its span, together covering every user once
+		workers := tracer.named("aml.velocity_worker")
+		require.Len(t, workers, 4)
+		users := make(map[int]int)
+		var workerErr error
+		for _, span := range workers {
+			users[span.parent] += span.attributes[attrUsers].(int)
+			if span.parent == ruleSpans["ConcurrentVelocityProcessor"].id && span.err != nil {
+				workerErr = span.err
+			}
+		}
+		assert.Equal(t, map[int]int{
+			ruleSpans["WorkerVelocityProcessor"].id:     2,
+			ruleSpans["ConcurrentVelocityProcessor"].id: 2,
+		}, users)
+		assert.ErrorIs(t, workerErr, ErrUnsortedTransactions)
+	}
+}
+
+func TestStartSpan_WithoutTracer(t *testing.T) {
+	ctx, end := startSpan(context.Background(), "aml.rule")
+	end(nil, nil)
+
+	assert.Equal(t, context.Background(), ctx, "untraced contexts are left as they are")
+}
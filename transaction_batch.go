@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// BatchRuleProcessor is a RuleProcessor deciding on one transaction at a time from a few fields,
+// which reads the batch in columnar form so its scans only touch those fields. The engine builds the
+// columns once per evaluation and prefers ProcessBatch over Process and ProcessGrouped. The batch is
+// shared, so it must not be modified.
+type BatchRuleProcessor interface {
+	RuleProcessor
+	ProcessBatch(ctx context.Context, batch *TransactionBatch) map[uuid.UUID]struct{}
+}
+
+// TransactionBatch holds transactions column by column, the i-th transaction being made of the
+// i-th entry of every column. All columns have the same length.
+type TransactionBatch struct {
+	UserIDs    []uuid.UUID
+	Amounts    []decimal.Decimal
+	Currencies []string
+	Countries  []string
+	CreatedAt  []time.Time
+
+	// rows are the transactions the batch was built from, nil for a batch built column by column
+	rows []Transaction
+}
+
+// NewTransactionBatch lays transactions out in columns
+func NewTransactionBatch(transactions []Transaction) *TransactionBatch {
+	batch := &TransactionBatch{
+		UserIDs:    make([]uuid.UUID, len(transactions)),
+		Amounts:    make([]decimal.Decimal, len(transactions)),
+		Currencies: make([]string, len(transactions)),
+		Countries:  make([]string, len(transactions)),
+		CreatedAt:  make([]time.Time, len(transactions)),
+		rows:       transactions,
+	}
+	for i, tx := range transactions {
+		batch.UserIDs[i] = tx.UserID
+		batch.Amounts[i] = tx.Amount
+		batch.Currencies[i] = tx.Currency
+		batch.Countries[i] = tx.Country
+		batch.CreatedAt[i] = tx.CreatedAt
+	}
+
+	return batch
+}
+
+// Len returns the number of transactions in the batch
+func (b *TransactionBatch) Len() int {
+	return len(b.UserIDs)
+}
+
+// Transactions returns the batch as rows: the transactions it was built from, to be read only, or
+// for a batch built column by column, new transactions holding only the fields of the columns
+func (b *TransactionBatch) Transactions() []Transaction {
+	if b.rows != nil {
+		return b.rows
+	}
+
+	transactions := make([]Transaction, b.Len())
+	for i := range transactions {
+		transactions[i] = Transaction{
+			UserID:    b.UserIDs[i],
+			Amount:    b.Amounts[i],
+			Currency:  b.Currencies[i],
+			Country:   b.Countries[i],
+			CreatedAt: b.CreatedAt[i],
+		}
+	}
+
+	return transactions
+}
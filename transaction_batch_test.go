@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// columnarRows is the batch size of BenchmarkColumnar, e.g. -columnar-rows=1000000 on a small machine
+var columnarRows = flag.Int("columnar-rows", 10_000_000, "transactions in the columnar benchmark batch")
+
+func TestNewTransactionBatch(t *testing.T) {
+	transactions := NewTransactionGenerator(WithSeed(1), WithUserCount(20)).Generate().Transactions
+
+	batch := NewTransactionBatch(transactions)
+	require.Equal(t, len(transactions), batch.Len())
+	for i, tx := range transactions {
+		assert.Equal(t, tx.UserID, batch.UserIDs[i])
+		assert.True(t, tx.Amount.Equal(batch.Amounts[i]))
+		assert.Equal(t, tx.Currency, batch.Currencies[i])
+		assert.Equal(t, tx.Country, batch.Countries[i])
+		assert.Equal(t, tx.CreatedAt, batch.CreatedAt[i])
+	}
+	assert.Equal(t, transactions, batch.Transactions(), "a batch built from rows returns them")
+
+	userID, createdAt := uuid.New(), time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	columns := &TransactionBatch{
+		UserIDs:    []uuid.UUID{userID},
+		Amounts:    []decimal.Decimal{decimal.NewFromInt(42)},
+		Currencies: []string{"EUR"},
+		Countries:  []string{"FR"},
+		CreatedAt:  []time.Time{createdAt},
+	}
+	assert.Equal(t, []Transaction{{
+		UserID:    userID,
+		Amount:    decimal.NewFromInt(42),
+		Currency:  "EUR",
+		Country:   "FR",
+		CreatedAt: createdAt,
+	}}, columns.Transactions())
+
+	assert.Zero(t, NewTransactionBatch(nil).Len())
+}
+
+// columnarTransactions generates a batch over messy countries and a few currencies
+func columnarTransactions(seed uint64, users int) []Transaction {
+	transactions := NewTransactionGenerator(
+		WithSeed(seed),
+		WithUserCount(users),
+		WithAmounts(UniformAmount(decimal.NewFromInt(1), decimal.NewFromInt(2000))),
+		WithCountryPool(append(messyCountries, "KP", "", "Atlantis")...),
+	).Generate().Transactions
+
+	currencies := []string{"EUR", "usd", "GBP ", "JPY", ""}
+	rng := rand.New(rand.NewPCG(seed, seed))
+	for i := range transactions {
+		transactions[i].Currency = currencies[rng.IntN(len(currencies))]
+	}
+
+	return transactions
+}
+
+func TestBatchRuleProcessor_Conformance(t *testing.T) {
+	gbp := decimal.NewFromInt(700)
+	currencyProcessor, err := NewCurrencyAmountProcessor(nil, map[string]decimal.Decimal{"EUR": decimal.NewFromInt(1500), "usd": decimal.NewFromInt(1000), "GBP": gbp})
+	require.NoError(t, err)
+
+	processors := []struct {
+		name      string
+		processor BatchRuleProcessor
+	}{
+		{name: "amount", processor: TransactionAmountProcessor{Threshold: decimal.NewFromInt(1800)}},
+		{name: "amount by country", processor: NewCountryAmountProcessor(decimal.NewFromInt(1900), map[string]decimal.Decimal{"fr": decimal.NewFromInt(1200), "IRN": decimal.NewFromInt(300)})},
+		{name: "amount by currency", processor: currencyProcessor},
+		{name: "amount converted", processor: TransactionAmountProcessor{
+			Threshold: decimal.NewFromInt(1500),
+			Conversion: &CurrencyConversion{Base: "EUR", Rates: StaticRateProvider{
+				{From: "USD", To: "EUR"}: decimal.NewFromFloat(0.9),
+				{From: "GBP", To: "EUR"}: decimal.NewFromFloat(1.2),
+			}},
+		}},
+		{name: "blacklist", processor: NewCountryBlackListProcessor("IR", "kp", "Atlantis")},
+		{name: "blacklist exact", processor: CountryBlackListProcessor{Blacklist: map[string]struct{}{"IR": {}, "irn": {}}, ExactCountries: true}},
+	}
+
+	for _, seed := range []uint64{1, 7, 42, 1234} {
+		transactions := columnarTransactions(seed, 500)
+		batch := NewTransactionBatch(transactions)
+		columns := &TransactionBatch{
+			UserIDs:    batch.UserIDs,
+			Amounts:    batch.Amounts,
+			Currencies: batch.Currencies,
+			Countries:  batch.Countries,
+			CreatedAt:  batch.CreatedAt,
+		}
+
+		for _, tt := range processors {
+			want := tt.processor.Process(context.Background(), append([]Transaction(nil), transactions...))
+			require.NotEmpty(t, want, "seed %d %s", seed, tt.name)
+			assert.Equal(t, want, tt.processor.ProcessBatch(context.Background(), batch), "seed %d %s", seed, tt.name)
+			assert.Equal(t, want, tt.processor.ProcessBatch(context.Background(), columns), "seed %d %s column by column", seed, tt.name)
+		}
+	}
+}
+
+// rowRule hides ProcessBatch from the engine, keeping the name and evidence of its rule
+type rowRule struct {
+	RuleProcessor
+}
+
+func (r rowRule) Name() string {
+	return ruleName(r.RuleProcessor)
+}
+
+func (r rowRule) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	return r.RuleProcessor.(AlertDetailer).AlertDetails(ctx, userID, transactions)
+}
+
+func TestRuleEngine_Evaluate_Batch(t *testing.T) {
+	transactions := columnarTransactions(3, 300)
+	rules := []RuleProcessor{
+		TransactionAmountProcessor{Threshold: decimal.NewFromInt(1900)},
+		NewCountryBlackListProcessor("IR"),
+	}
+	rowEngine, batchEngine := NewRuleEngine(nil), NewRuleEngine(nil)
+	for _, rule := range rules {
+		rowEngine.AddRuleProcessorWithSeverity(rowRule{rule}, defaultSeverity(rule))
+		batchEngine.AddRuleProcessorWithSeverity(rule, defaultSeverity(rule))
+	}
+
+	want, err := rowEngine.Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+	require.NotEmpty(t, want.Alerts)
+
+	got, err := batchEngine.Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+	assert.Equal(t, comparableResult(want), comparableResult(got))
+}
+
+// BenchmarkColumnar compares the row and batch paths of the per-transaction rules, tiling a
+// generated batch up to -columnar-rows transactions
+func BenchmarkColumnar(b *testing.B) {
+	generated := columnarTransactions(42, 10000)
+	transactions := make([]Transaction, *columnarRows)
+	for i := range transactions {
+		transactions[i] = generated[i%len(generated)]
+	}
+	batch := NewTransactionBatch(transactions)
+
+	processors := []struct {
+		name      string
+		processor BatchRuleProcessor
+	}{
+		{name: "Amount", processor: NewCountryAmountProcessor(decimal.NewFromInt(1900), map[string]decimal.Decimal{"FR": decimal.NewFromInt(1200)})},
+		{name: "CountryBlacklist", processor: NewCountryBlackListProcessor("IR", "KP")},
+	}
+	for _, p := range processors {
+		b.Run(p.name+"/Rows", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				p.processor.Process(context.Background(), transactions)
+			}
+			b.ReportMetric(float64(len(transactions))*float64(b.N)/b.Elapsed().Seconds(), "rows/s")
+		})
+		b.Run(p.name+"/Batch", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				p.processor.ProcessBatch(context.Background(), batch)
+			}
+			b.ReportMetric(float64(batch.Len())*float64(b.N)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
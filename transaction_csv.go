@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ErrMissingColumn is returned when a CSV header lacks a required column
+var ErrMissingColumn = errors.New("missing required column")
+
+// RowError locates a malformed CSV row
+type RowError struct {
+	// Line is the 1-based line the row starts on, the header being line 1
+	Line   int
+	Column string
+	Err    error
+}
+
+func (e RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+	}
+
+	return fmt.Sprintf("line %d: column %s: %v", e.Line, e.Column, e.Err)
+}
+
+func (e RowError) Unwrap() error {
+	return e.Err
+}
+
+// RowErrors holds every malformed row of a lenient load
+type RowErrors []RowError
+
+func (e RowErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, rowErr := range e {
+		lines[i] = rowErr.Error()
+	}
+
+	return fmt.Sprintf("%d malformed rows: %s", len(e), strings.Join(lines, "; "))
+}
+
+// CSVOption configures LoadTransactionsCSV
+type CSVOption func(*csvOptions)
+
+type csvOptions struct {
+	columns  map[string]string
+	layouts  []string
+	location *time.Location
+	comma    rune
+	lenient  bool
+}
+
+// WithCSVColumn reads field, named after its JSON tag such as "amount" or "created_at", from the
+// column with the given header instead of the column named after the field
+func WithCSVColumn(field, header string) CSVOption {
+	return func(o *csvOptions) {
+		o.columns[field] = header
+	}
+}
+
+// WithCSVTimeLayouts tries layouts in order after RFC3339 to parse created_at
+func WithCSVTimeLayouts(layouts ...string) CSVOption {
+	return func(o *csvOptions) {
+		o.layouts = append(o.layouts, layouts...)
+	}
+}
+
+// WithCSVLocation interprets timestamps without a zone in loc instead of UTC
+func WithCSVLocation(loc *time.Location) CSVOption {
+	return func(o *csvOptions) {
+		o.location = loc
+	}
+}
+
+// WithCSVComma sets the field delimiter, ',' by default
+func WithCSVComma(comma rune) CSVOption {
+	return func(o *csvOptions) {
+		o.comma = comma
+	}
+}
+
+// WithCSVLenient skips malformed rows instead of aborting, reporting them all as RowErrors
+func WithCSVLenient() CSVOption {
+	return func(o *csvOptions) {
+		o.lenient = true
+	}
+}
+
+// csvField parses one CSV cell into a transaction field
+type csvField struct {
+	name     string
+	required bool
+	set      func(tx *Transaction, value string, options csvOptions) error
+}
+
+// csvFields lists the loadable fields, named after their JSON tags
+var csvFields = []csvField{
+	{name: "transaction_id", set: func(tx *Transaction, value string, _ csvOptions) error {
+		return parseOptionalUUID(value, &tx.TransactionID)
+	}},
+	{name: "user_id", required: true, set: func(tx *Transaction, value string, _ csvOptions) error {
+		return parseOptionalUUID(value, &tx.UserID)
+	}},
+	{name: "account_id", set: func(tx *Transaction, value string, _ csvOptions) error {
+		return parseOptionalUUID(value, &tx.AccountID)
+	}},
+	{name: "amount", required: true, set: func(tx *Transaction, value string, _ csvOptions) error {
+		amount, err := decimal.NewFromString(value)
+		tx.Amount = amount
+		return err
+	}},
+	{name: "created_at", required: true, set: func(tx *Transaction, value string, options csvOptions) error {
+		createdAt, err := parseCSVTime(value, options)
+		tx.CreatedAt = createdAt
+		return err
+	}},
+	{name: "currency", set: textField(func(tx *Transaction) *string { return &tx.Currency })},
+	{name: "country", set: textField(func(tx *Transaction) *string { return &tx.Country })},
+	{name: "destination_country", set: textField(func(tx *Transaction) *string { return &tx.DestinationCountry })},
+	{name: "status", set: textField(func(tx *Transaction) *TransactionStatus { return &tx.Status })},
+	{name: "direction", set: textField(func(tx *Transaction) *Direction { return &tx.Direction })},
+	{name: "channel", set: textField(func(tx *Transaction) *Channel { return &tx.Channel })},
+	{name: "category", set: textField(func(tx *Transaction) *string { return &tx.Category })},
+	{name: "description", set: textField(func(tx *Transaction) *string { return &tx.Description })},
+	{name: "user_name", set: textField(func(tx *Transaction) *string { return &tx.UserName })},
+	{name: "counterparty_name", set: textField(func(tx *Transaction) *string { return &tx.CounterpartyName })},
+	{name: "counterparty_id", set: textField(func(tx *Transaction) *string { return &tx.CounterpartyID })},
+	{name: "counterparty_country", set: textField(func(tx *Transaction) *string { return &tx.CounterpartyCountry })},
+}
+
+// LoadTransactionsCSV reads transactions from CSV with a header row. Columns are matched by header,
+// named after the Transaction JSON tags unless remapped with WithCSVColumn; unknown columns are
+// ignored. user_id, amount and created_at are required. Amounts are parsed as exact decimals and
+// created_at as RFC3339 or one of the configured layouts.
+//
+// The first malformed row aborts the load with its RowError, unless WithCSVLenient is set, in which
+// case the valid rows are returned along with RowErrors.
+func LoadTransactionsCSV(r io.Reader, opts ...CSVOption) ([]Transaction, error) {
+	options := csvOptions{columns: make(map[string]string), location: time.UTC, comma: ','}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = options.comma
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read header: %w", err)
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.TrimSpace(name)] = i
+	}
+
+	type column struct {
+		field  csvField
+		header string
+		index  int
+	}
+	var columns []column
+	for _, field := range csvFields {
+		name := field.name
+		if mapped, ok := options.columns[field.name]; ok {
+			name = mapped
+		}
+		index, ok := positions[name]
+		if !ok {
+			if field.required {
+				return nil, fmt.Errorf("%w: %s", ErrMissingColumn, name)
+			}
+			continue
+		}
+		columns = append(columns, column{field: field, header: name, index: index})
+	}
+
+	var transactions []Transaction
+	var rowErrs RowErrors
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		var rowErr *RowError
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, err
+			}
+			rowErr = &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+		} else {
+			line, _ := reader.FieldPos(0)
+
+			var tx Transaction
+			for _, col := range columns {
+				if col.index >= len(record) {
+					rowErr = &RowError{Line: line, Column: col.header, Err: errors.New("missing value")}
+					break
+				}
+				value := strings.TrimSpace(record[col.index])
+				if value == "" && col.field.required {
+					rowErr = &RowError{Line: line, Column: col.header, Err: errors.New("empty value")}
+					break
+				}
+				if err := col.field.set(&tx, value, options); err != nil {
+					rowErr = &RowError{Line: line, Column: col.header, Err: err}
+					break
+				}
+			}
+			if rowErr == nil {
+				transactions = append(transactions, tx)
+				continue
+			}
+		}
+
+		if !options.lenient {
+			return nil, *rowErr
+		}
+		rowErrs = append(rowErrs, *rowErr)
+	}
+
+	if len(rowErrs) > 0 {
+		return transactions, rowErrs
+	}
+
+	return transactions, nil
+}
+
+// textField returns a csvField setter copying the cell into the text field returned by field
+func textField[T ~string](field func(*Transaction) *T) func(*Transaction, string, csvOptions) error {
+	return func(tx *Transaction, value string, _ csvOptions) error {
+		*field(tx) = T(value)
+		return nil
+	}
+}
+
+// parseOptionalUUID leaves target zero for an empty cell
+func parseOptionalUUID(value string, target *uuid.UUID) error {
+	if value == "" {
+		return nil
+	}
+
+	id, err := uuid.Parse(value)
+	*target = id
+	return err
+}
+
+func parseCSVTime(value string, options csvOptions) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err == nil {
+		return t, nil
+	}
+
+	for _, layout := range options.layouts {
+		if t, layoutErr := time.ParseInLocation(layout, value, options.location); layoutErr == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("timestamp %q matches neither RFC3339 nor the configured layouts", value)
+}
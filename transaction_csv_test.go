@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openFixture(t *testing.T, name string) *os.File {
+	t.Helper()
+
+	file, err := os.Open("testdata/" + name)
+	require.NoError(t, err)
+	t.Cleanup(func() { file.Close() })
+
+	return file
+}
+
+func TestLoadTransactionsCSV(t *testing.T) {
+	transactions, err := LoadTransactionsCSV(openFixture(t, "transactions.csv"))
+	require.NoError(t, err)
+	require.Len(t, transactions, 3)
+
+	first := uuid.MustParse("1b4e28ba-2fa1-11d2-883f-0016d3cca427")
+	second := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+	assert.Equal(t, uuid.MustParse("9f0c5a3e-2b1d-4c8e-9a7f-1e2d3c4b5a60"), transactions[0].TransactionID, "the BOM does not hide the first column")
+	assert.Equal(t, first, transactions[0].UserID)
+	assert.Equal(t, uuid.Nil, transactions[0].AccountID)
+	assert.Equal(t, "0.10", transactions[0].Amount.StringFixed(2))
+	assert.Equal(t, "EUR", transactions[0].Currency)
+	assert.True(t, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC).Equal(transactions[0].CreatedAt))
+
+	assert.Equal(t, second, transactions[1].AccountID)
+	assert.True(t, transactions[1].Amount.Equal(decimal.RequireFromString("12345678901234567890.123456789")), "amounts keep every digit")
+	assert.True(t, time.Date(2024, 3, 1, 9, 30, 0, 123456789, time.UTC).Equal(transactions[1].CreatedAt), "offsets are honoured")
+	assert.Equal(t, "invoice 42, \"urgent\"\nsecond line", transactions[1].Description, "quoted line breaks are kept, normalized to LF")
+
+	assert.Equal(t, second, transactions[2].UserID, "UUIDs are case-insensitive")
+	assert.Equal(t, "-25.5", transactions[2].Amount.String())
+	assert.Empty(t, transactions[2].Currency)
+	assert.Equal(t, "ir", transactions[2].Country)
+	assert.True(t, time.Date(2024, 3, 2, 5, 0, 0, 0, time.UTC).Equal(transactions[2].CreatedAt))
+}
+
+func TestLoadTransactionsCSV_ColumnMapping(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(t, err)
+
+	transactions, err := LoadTransactionsCSV(openFixture(t, "transactions_mapped.csv"),
+		WithCSVComma(';'),
+		WithCSVColumn("user_id", "Customer"),
+		WithCSVColumn("amount", "Value"),
+		WithCSVColumn("created_at", "Booked At"),
+		WithCSVColumn("country", "Country Code"),
+		WithCSVTimeLayouts("02/01/2006 15:04"),
+		WithCSVLocation(paris),
+	)
+	require.NoError(t, err)
+	require.Len(t, transactions, 2)
+
+	assert.Equal(t, "99.99", transactions[0].Amount.String())
+	assert.Equal(t, "CH", transactions[0].Country)
+	assert.True(t, time.Date(2024, 3, 1, 8, 15, 0, 0, time.UTC).Equal(transactions[0].CreatedAt), "layouts without a zone use the location")
+	assert.True(t, time.Date(2024, 3, 1, 9, 15, 0, 0, time.UTC).Equal(transactions[1].CreatedAt), "RFC3339 is always accepted")
+
+	_, err = LoadTransactionsCSV(openFixture(t, "transactions_mapped.csv"), WithCSVComma(';'))
+	assert.ErrorIs(t, err, ErrMissingColumn)
+	assert.ErrorContains(t, err, "user_id")
+}
+
+func TestLoadTransactionsCSV_MalformedRows(t *testing.T) {
+	_, err := LoadTransactionsCSV(openFixture(t, "transactions_broken.csv"))
+
+	var rowErr RowError
+	require.ErrorAs(t, err, &rowErr)
+	assert.Equal(t, 3, rowErr.Line)
+	assert.Equal(t, "amount", rowErr.Column)
+	assert.ErrorContains(t, err, "line 3: column amount:")
+
+	transactions, err := LoadTransactionsCSV(openFixture(t, "transactions_broken.csv"), WithCSVLenient())
+	require.Len(t, transactions, 2, "valid rows are kept")
+	assert.Equal(t, "100", transactions[0].Amount.String())
+	assert.Equal(t, "300", transactions[1].Amount.String())
+
+	var rowErrs RowErrors
+	require.ErrorAs(t, err, &rowErrs)
+	require.Len(t, rowErrs, 3)
+	assert.Equal(t, []int{3, 4, 5}, []int{rowErrs[0].Line, rowErrs[1].Line, rowErrs[2].Line})
+	assert.Equal(t, []string{"amount", "user_id", "created_at"}, []string{rowErrs[0].Column, rowErrs[1].Column, rowErrs[2].Column})
+	assert.ErrorContains(t, rowErrs[2], `timestamp "yesterday"`)
+}
+
+func TestLoadTransactionsCSV_EdgeCases(t *testing.T) {
+	transactions, err := LoadTransactionsCSV(strings.NewReader(""))
+	require.NoError(t, err)
+	assert.Empty(t, transactions)
+
+	_, err = LoadTransactionsCSV(strings.NewReader("user_id,amount,created_at\n,10,2024-03-01T09:00:00Z\n"))
+	assert.EqualError(t, err, "line 2: column user_id: empty value")
+
+	_, err = LoadTransactionsCSV(strings.NewReader("user_id,amount,created_at\n\"1b4e28ba-2fa1-11d2-883f-0016d3cca427,10\n"))
+	var rowErr RowError
+	require.ErrorAs(t, err, &rowErr)
+	assert.Equal(t, 2, rowErr.Line)
+}
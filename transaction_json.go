@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// transactionFields has the fields of Transaction without its methods, to marshal it without recursion
+type transactionFields Transaction
+
+// MarshalJSON writes Amount as a decimal string, whatever decimal.MarshalJSONWithoutQuotes says,
+// and CreatedAt as RFC3339Nano
+func (tx Transaction) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		transactionFields
+		Amount    string `json:"amount"`
+		CreatedAt string `json:"created_at"`
+	}{
+		transactionFields: transactionFields(tx),
+		Amount:            tx.Amount.String(),
+		CreatedAt:         tx.CreatedAt.Format(time.RFC3339Nano),
+	})
+}
+
+// JSONOption configures the JSON transaction loaders
+type JSONOption func(*jsonOptions)
+
+type jsonOptions struct {
+	strict bool
+}
+
+// WithStrictJSON rejects objects with fields Transaction does not have, which are ignored by default
+func WithStrictJSON() JSONOption {
+	return func(o *jsonOptions) {
+		o.strict = true
+	}
+}
+
+// LoadTransactionsNDJSON reads one transaction object per line, skipping blank lines. Lines are
+// decoded as they are read, so the input is never held in memory as a whole. A malformed line aborts
+// the load with a RowError giving its line number.
+func LoadTransactionsNDJSON(r io.Reader, opts ...JSONOption) ([]Transaction, error) {
+	decoder := newNDJSONDecoder(r, opts)
+
+	var transactions []Transaction
+	for {
+		tx, err := decoder.next()
+		if errors.Is(err, io.EOF) {
+			return transactions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, tx)
+	}
+}
+
+// LoadTransactionsJSON reads a top-level array of transaction objects, decoding them one by one
+func LoadTransactionsJSON(r io.Reader, opts ...JSONOption) ([]Transaction, error) {
+	options := newJSONOptions(opts)
+	decoder := json.NewDecoder(r)
+	if options.strict {
+		decoder.DisallowUnknownFields()
+	}
+
+	if token, err := decoder.Token(); err != nil {
+		return nil, err
+	} else if token != json.Delim('[') {
+		return nil, fmt.Errorf("expected a JSON array, found %v", token)
+	}
+
+	var transactions []Transaction
+	for decoder.More() {
+		var tx Transaction
+		if err := decoder.Decode(&tx); err != nil {
+			return nil, ElementError{Index: len(transactions), Err: err}
+		}
+		transactions = append(transactions, tx)
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// ElementError locates a malformed element of a JSON array
+type ElementError struct {
+	// Index is the 0-based position of the element in the array
+	Index int
+	Err   error
+}
+
+func (e ElementError) Error() string {
+	return fmt.Sprintf("element %d: %v", e.Index, e.Err)
+}
+
+func (e ElementError) Unwrap() error {
+	return e.Err
+}
+
+// WriteTransactionsNDJSON writes one transaction object per line
+func WriteTransactionsNDJSON(w io.Writer, transactions []Transaction) error {
+	encoder := json.NewEncoder(w)
+	for _, tx := range transactions {
+		if err := encoder.Encode(tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteTransactionsJSON writes transactions as a top-level array, one object per line
+func WriteTransactionsJSON(w io.Writer, transactions []Transaction) error {
+	writer := bufio.NewWriter(w)
+	writer.WriteString("[")
+	for i, tx := range transactions {
+		if i > 0 {
+			writer.WriteString(",")
+		}
+		data, err := json.Marshal(tx)
+		if err != nil {
+			return err
+		}
+		writer.WriteString("\n")
+		writer.Write(data)
+	}
+	writer.WriteString("\n]\n")
+
+	return writer.Flush()
+}
+
+func newJSONOptions(opts []JSONOption) jsonOptions {
+	var options jsonOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// ndjsonDecoder decodes NDJSON transactions one line at a time
+type ndjsonDecoder struct {
+	reader  *bufio.Reader
+	options jsonOptions
+	line    int
+}
+
+func newNDJSONDecoder(r io.Reader, opts []JSONOption) *ndjsonDecoder {
+	return &ndjsonDecoder{reader: bufio.NewReader(r), options: newJSONOptions(opts)}
+}
+
+// next returns the following transaction, io.EOF once the input is exhausted, or a RowError
+func (d *ndjsonDecoder) next() (Transaction, error) {
+	for {
+		data, err := d.reader.ReadBytes('\n')
+		if err != nil && (len(data) == 0 || !errors.Is(err, io.EOF)) {
+			return Transaction{}, err
+		}
+		d.line++
+
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		if d.options.strict {
+			decoder.DisallowUnknownFields()
+		}
+
+		var tx Transaction
+		if err := decoder.Decode(&tx); err != nil {
+			return Transaction{}, RowError{Line: d.line, Err: err}
+		}
+		if decoder.More() {
+			return Transaction{}, RowError{Line: d.line, Err: errors.New("unexpected data after the object")}
+		}
+
+		return tx, nil
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func jsonFixture() []Transaction {
+	baseTime := time.Date(2024, 3, 1, 9, 0, 0, 123456789, time.UTC)
+
+	return []Transaction{
+		{
+			TransactionID: uuid.New(),
+			UserID:        uuid.New(),
+			AccountID:     uuid.New(),
+			Amount:        decimal.RequireFromString("12345678901234567890.000000001"),
+			Currency:      "EUR",
+			Country:       "FR",
+			Status:        StatusCompleted,
+			Channel:       ChannelWire,
+			Description:   "invoice \"42\"\nline two",
+			CreatedAt:     baseTime,
+		},
+		{UserID: uuid.New(), Amount: decimal.RequireFromString("-0.10"), CreatedAt: baseTime.Add(time.Hour)},
+	}
+}
+
+// assertTransactionsEqual compares amounts numerically, decimal keeping the exponent it was parsed with
+func assertTransactionsEqual(t *testing.T, want, got []Transaction) {
+	t.Helper()
+
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.True(t, want[i].Amount.Equal(got[i].Amount), "amount %d: want %s, got %s", i, want[i].Amount, got[i].Amount)
+
+		wantTx, gotTx := want[i], got[i]
+		wantTx.Amount, gotTx.Amount = decimal.Zero, decimal.Zero
+		assert.Equal(t, wantTx, gotTx)
+	}
+}
+
+func TestTransactionsNDJSON_RoundTrip(t *testing.T) {
+	transactions := jsonFixture()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTransactionsNDJSON(&buf, transactions))
+	assert.Equal(t, 2, strings.Count(buf.String(), "\n"), "one line per transaction")
+
+	loaded, err := LoadTransactionsNDJSON(&buf)
+	require.NoError(t, err)
+	assertTransactionsEqual(t, transactions, loaded)
+}
+
+func TestTransactionsJSON_RoundTrip(t *testing.T) {
+	transactions := jsonFixture()
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTransactionsJSON(&buf, transactions))
+
+	loaded, err := LoadTransactionsJSON(&buf)
+	require.NoError(t, err)
+	assertTransactionsEqual(t, transactions, loaded)
+
+	buf.Reset()
+	require.NoError(t, WriteTransactionsJSON(&buf, nil))
+	loaded, err = LoadTransactionsJSON(&buf)
+	require.NoError(t, err)
+	assert.Empty(t, loaded)
+}
+
+func TestTransaction_MarshalJSON(t *testing.T) {
+	decimal.MarshalJSONWithoutQuotes = true
+	defer func() { decimal.MarshalJSONWithoutQuotes = false }()
+
+	userID := uuid.MustParse("1b4e28ba-2fa1-11d2-883f-0016d3cca427")
+	tx := Transaction{
+		UserID:    userID,
+		Amount:    decimal.RequireFromString("0.10"),
+		Country:   "FR",
+		CreatedAt: time.Date(2024, 3, 1, 9, 0, 0, 500, time.FixedZone("", 3600)),
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteTransactionsNDJSON(&buf, []Transaction{tx}))
+	assert.JSONEq(t, `{
+		"user_id": "1b4e28ba-2fa1-11d2-883f-0016d3cca427",
+		"account_id": "00000000-0000-0000-0000-000000000000",
+		"amount": "0.1",
+		"country": "FR",
+		"created_at": "2024-03-01T09:00:00.0000005+01:00"
+	}`, buf.String())
+}
+
+func TestLoadTransactionsNDJSON_Errors(t *testing.T) {
+	input := `{"user_id":"1b4e28ba-2fa1-11d2-883f-0016d3cca427","amount":"10","created_at":"2024-03-01T09:00:00Z","source":"kafka"}
+
+{"user_id":"1b4e28ba-2fa1-11d2-883f-0016d3cca427","amount":"ten","created_at":"2024-03-01T09:00:00Z"}
+`
+
+	_, err := LoadTransactionsNDJSON(strings.NewReader(input))
+	var rowErr RowError
+	require.ErrorAs(t, err, &rowErr)
+	assert.Equal(t, 3, rowErr.Line, "blank lines count toward line numbers")
+
+	transactions, err := LoadTransactionsNDJSON(strings.NewReader(strings.SplitN(input, "\n", 2)[0]))
+	require.NoError(t, err, "unknown fields are ignored by default, and the last line needs no newline")
+	assert.Len(t, transactions, 1)
+
+	_, err = LoadTransactionsNDJSON(strings.NewReader(input), WithStrictJSON())
+	require.ErrorAs(t, err, &rowErr)
+	assert.Equal(t, 1, rowErr.Line)
+	assert.ErrorContains(t, err, `unknown field "source"`)
+
+	_, err = LoadTransactionsNDJSON(strings.NewReader(`{"amount":"1"} {"amount":"2"}`))
+	assert.ErrorContains(t, err, "line 1: unexpected data after the object")
+}
+
+func TestLoadTransactionsJSON_Errors(t *testing.T) {
+	_, err := LoadTransactionsJSON(strings.NewReader(`{"amount":"1"}`))
+	assert.ErrorContains(t, err, "expected a JSON array")
+
+	_, err = LoadTransactionsJSON(strings.NewReader(`[{"amount":"1"},{"amount":"1","extra":true}]`), WithStrictJSON())
+	assert.ErrorContains(t, err, "element 1:")
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"aml_rule_engine/amlpb"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// ErrCorruptStream is returned when a delimited stream cannot be read back, wrapped in an OffsetError
+var ErrCorruptStream = errors.New("corrupt transaction stream")
+
+// maxDelimitedSize bounds a record's length prefix, so a corrupted one cannot trigger a huge allocation
+const maxDelimitedSize = 16 << 20
+
+// OffsetError locates a malformed record of a delimited stream
+type OffsetError struct {
+	// Offset is the byte at which the record's length prefix starts
+	Offset int64
+	Err    error
+}
+
+func (e OffsetError) Error() string {
+	return fmt.Sprintf("byte %d: %v", e.Offset, e.Err)
+}
+
+func (e OffsetError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalTransactions encodes transactions as one amlpb.TransactionBatch message
+func MarshalTransactions(transactions []Transaction) ([]byte, error) {
+	batch := &amlpb.TransactionBatch{Transactions: make([]*amlpb.StoredTransaction, len(transactions))}
+	for i, tx := range transactions {
+		batch.Transactions[i] = transactionToStored(tx)
+	}
+
+	return proto.Marshal(batch)
+}
+
+// UnmarshalTransactions decodes a batch written by MarshalTransactions, a malformed transaction
+// failing with an ElementError giving its position
+func UnmarshalTransactions(data []byte) ([]Transaction, error) {
+	var batch amlpb.TransactionBatch
+	if err := proto.Unmarshal(data, &batch); err != nil {
+		return nil, err
+	}
+
+	transactions := make([]Transaction, len(batch.GetTransactions()))
+	for i, msg := range batch.GetTransactions() {
+		tx, err := transactionFromStored(msg)
+		if err != nil {
+			return nil, ElementError{Index: i, Err: err}
+		}
+		transactions[i] = tx
+	}
+
+	return transactions, nil
+}
+
+// WriteDelimited appends tx to w as a varint length followed by its amlpb.StoredTransaction
+// encoding, so a stream of any size can be written and read back one transaction at a time
+func WriteDelimited(w io.Writer, tx Transaction) error {
+	data, err := proto.Marshal(transactionToStored(tx))
+	if err != nil {
+		return err
+	}
+
+	record := protowire.AppendVarint(make([]byte, 0, binary.MaxVarintLen64+len(data)), uint64(len(data)))
+	_, err = w.Write(append(record, data...))
+	return err
+}
+
+// DelimitedReader reads back the transactions written by WriteDelimited as they are needed
+type DelimitedReader struct {
+	r      *bufio.Reader
+	offset int64
+	buf    []byte
+}
+
+func NewDelimitedReader(r io.Reader) *DelimitedReader {
+	return &DelimitedReader{r: bufio.NewReader(r)}
+}
+
+// ReadDelimited returns the next transaction of the stream, or io.EOF once it ends between two
+// records. A truncated or malformed record fails with an OffsetError wrapping ErrCorruptStream.
+func (d *DelimitedReader) ReadDelimited() (Transaction, error) {
+	start := d.offset
+	corrupt := func(format string, args ...any) error {
+		return OffsetError{Offset: start, Err: fmt.Errorf("%w: "+format, append([]any{ErrCorruptStream}, args...)...)}
+	}
+
+	size, err := binary.ReadUvarint(d)
+	switch {
+	case errors.Is(err, io.EOF):
+		return Transaction{}, io.EOF
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return Transaction{}, corrupt("truncated length prefix")
+	case err != nil:
+		return Transaction{}, corrupt("length prefix: %v", err)
+	case size > maxDelimitedSize:
+		return Transaction{}, corrupt("record of %d bytes exceeds %d", size, maxDelimitedSize)
+	}
+
+	if uint64(cap(d.buf)) < size {
+		d.buf = make([]byte, size)
+	}
+	data := d.buf[:size]
+	n, err := io.ReadFull(d.r, data)
+	d.offset += int64(n)
+	if err != nil {
+		return Transaction{}, corrupt("record of %d bytes truncated after %d", size, n)
+	}
+
+	var msg amlpb.StoredTransaction
+	if err := proto.Unmarshal(data, &msg); err != nil {
+		return Transaction{}, corrupt("%v", err)
+	}
+	tx, err := transactionFromStored(&msg)
+	if err != nil {
+		return Transaction{}, corrupt("%v", err)
+	}
+
+	return tx, nil
+}
+
+// ReadByte lets binary.ReadUvarint read the length prefix while counting its bytes
+func (d *DelimitedReader) ReadByte() (byte, error) {
+	b, err := d.r.ReadByte()
+	if err == nil {
+		d.offset++
+	}
+
+	return b, err
+}
+
+// Next makes a DelimitedReader a TransactionSource, so archived batches can be replayed with EvaluateSource
+func (d *DelimitedReader) Next(ctx context.Context) (Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return Transaction{}, err
+	}
+
+	return d.ReadDelimited()
+}
+
+// transactionFromStored converts a stored transaction, empty UUIDs and amounts being read as zero
+// and a missing creation time as the zero time
+func transactionFromStored(msg *amlpb.StoredTransaction) (Transaction, error) {
+	tx := Transaction{
+		Currency:            msg.GetCurrency(),
+		Country:             msg.GetCountry(),
+		DestinationCountry:  msg.GetDestinationCountry(),
+		Status:              TransactionStatus(msg.GetStatus()),
+		Direction:           Direction(msg.GetDirection()),
+		Channel:             Channel(msg.GetChannel()),
+		Category:            msg.GetCategory(),
+		Description:         msg.GetDescription(),
+		UserName:            msg.GetUserName(),
+		CounterpartyName:    msg.GetCounterpartyName(),
+		CounterpartyID:      msg.GetCounterpartyId(),
+		CounterpartyCountry: msg.GetCounterpartyCountry(),
+	}
+
+	var err error
+	if tx.TransactionID, err = uuidFromStored(msg.GetTransactionId()); err != nil {
+		return Transaction{}, fmt.Errorf("transaction_id: %w", err)
+	}
+	if tx.UserID, err = uuidFromStored(msg.GetUserId()); err != nil {
+		return Transaction{}, fmt.Errorf("user_id: %w", err)
+	}
+	if tx.AccountID, err = uuidFromStored(msg.GetAccountId()); err != nil {
+		return Transaction{}, fmt.Errorf("account_id: %w", err)
+	}
+	if msg.GetAmount() != "" {
+		if tx.Amount, err = decimal.NewFromString(msg.GetAmount()); err != nil {
+			return Transaction{}, fmt.Errorf("amount: %w", err)
+		}
+	}
+	if msg.GetCreatedAt() != nil {
+		if err := msg.GetCreatedAt().CheckValid(); err != nil {
+			return Transaction{}, fmt.Errorf("created_at: %w", err)
+		}
+		tx.CreatedAt = msg.GetCreatedAt().AsTime()
+	}
+
+	return tx, nil
+}
+
+func uuidFromStored(b []byte) (uuid.UUID, error) {
+	if len(b) == 0 {
+		return uuid.Nil, nil
+	}
+
+	return uuid.FromBytes(b)
+}
+
+// uuidToStored writes the zero UUID as no bytes
+func uuidToStored(id uuid.UUID) []byte {
+	if id == uuid.Nil {
+		return nil
+	}
+
+	return id[:]
+}
+
+func transactionToStored(tx Transaction) *amlpb.StoredTransaction {
+	msg := &amlpb.StoredTransaction{
+		TransactionId:       uuidToStored(tx.TransactionID),
+		UserId:              uuidToStored(tx.UserID),
+		AccountId:           uuidToStored(tx.AccountID),
+		Amount:              tx.Amount.String(),
+		Currency:            tx.Currency,
+		Country:             tx.Country,
+		DestinationCountry:  tx.DestinationCountry,
+		Status:              string(tx.Status),
+		Direction:           string(tx.Direction),
+		Channel:             string(tx.Channel),
+		Category:            tx.Category,
+		Description:         tx.Description,
+		UserName:            tx.UserName,
+		CounterpartyName:    tx.CounterpartyName,
+		CounterpartyId:      tx.CounterpartyID,
+		CounterpartyCountry: tx.CounterpartyCountry,
+	}
+	if !tx.CreatedAt.IsZero() {
+		msg.CreatedAt = timestamppb.New(tx.CreatedAt)
+	}
+
+	return msg
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func protoTransactions() []Transaction {
+	return []Transaction{
+		{
+			TransactionID:       uuid.New(),
+			UserID:              uuid.New(),
+			AccountID:           uuid.New(),
+			Amount:              decimal.RequireFromString("1234.5678901234567890123"),
+			Currency:            "EUR",
+			Country:             "FR",
+			DestinationCountry:  "IR",
+			Status:              StatusReversed,
+			Direction:           DirectionDebit,
+			Channel:             ChannelWire,
+			Category:            "travel",
+			Description:         "ticket ✈",
+			CreatedAt:           time.Date(2024, 3, 1, 9, 0, 0, 123456789, time.UTC),
+			UserName:            "Jane Doe",
+			CounterpartyName:    "ACME",
+			CounterpartyID:      "acct-42",
+			CounterpartyCountry: "DE",
+		},
+		{UserID: uuid.New(), Amount: decimal.RequireFromString("-0.000000001"), CreatedAt: time.Date(1969, 12, 31, 23, 59, 59, 1, time.UTC)},
+		{},
+	}
+}
+
+func TestMarshalTransactions_RoundTrip(t *testing.T) {
+	transactions := protoTransactions()
+
+	data, err := MarshalTransactions(transactions)
+	require.NoError(t, err)
+	got, err := UnmarshalTransactions(data)
+	require.NoError(t, err)
+
+	assertSameTransactions(t, transactions, got)
+}
+
+func TestUnmarshalTransactions_Errors(t *testing.T) {
+	valid, err := MarshalTransactions([]Transaction{{UserID: uuid.New()}})
+	require.NoError(t, err)
+
+	// a second transaction whose user_id (field 2) has 3 bytes instead of 16
+	badUser := protowire.AppendBytes(protowire.AppendTag(nil, 2, protowire.BytesType), []byte{1, 2, 3})
+	data := protowire.AppendBytes(protowire.AppendTag(valid, 1, protowire.BytesType), badUser)
+
+	_, err = UnmarshalTransactions(data)
+	var elementErr ElementError
+	require.ErrorAs(t, err, &elementErr)
+	assert.Equal(t, 1, elementErr.Index)
+	assert.Contains(t, err.Error(), "user_id")
+
+	_, err = UnmarshalTransactions(valid[:len(valid)-1])
+	assert.Error(t, err)
+}
+
+func TestDelimited_RoundTrip(t *testing.T) {
+	transactions := protoTransactions()
+
+	var buf bytes.Buffer
+	for _, tx := range transactions {
+		require.NoError(t, WriteDelimited(&buf, tx))
+	}
+
+	reader := NewDelimitedReader(&buf)
+	var got []Transaction
+	for {
+		tx, err := reader.ReadDelimited()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, tx)
+	}
+
+	assertSameTransactions(t, transactions, got)
+}
+
+func TestDelimitedReader_Corrupt(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteDelimited(&buf, Transaction{UserID: uuid.New(), Amount: decimal.NewFromInt(10)}))
+	second := int64(buf.Len())
+	require.NoError(t, WriteDelimited(&buf, Transaction{UserID: uuid.New(), Amount: decimal.NewFromInt(20)}))
+	stream := buf.Bytes()
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantMessage string
+	}{
+		{
+			name:        "truncated record",
+			data:        stream[:len(stream)-3],
+			wantMessage: "truncated after",
+		},
+		{
+			name:        "truncated length prefix",
+			data:        append(stream[:second:second], 0x80),
+			wantMessage: "truncated length prefix",
+		},
+		{
+			name:        "oversized length prefix",
+			data:        protowire.AppendVarint(stream[:second:second], 1<<40),
+			wantMessage: "exceeds",
+		},
+		{
+			name:        "malformed record",
+			data:        append(stream[:second:second], 2, 0xff, 0xff),
+			wantMessage: "corrupt transaction stream",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewDelimitedReader(bytes.NewReader(tt.data))
+
+			_, err := reader.ReadDelimited()
+			require.NoError(t, err)
+			_, err = reader.ReadDelimited()
+
+			var offsetErr OffsetError
+			require.ErrorAs(t, err, &offsetErr)
+			assert.ErrorIs(t, err, ErrCorruptStream)
+			assert.Equal(t, second, offsetErr.Offset)
+			assert.Contains(t, err.Error(), tt.wantMessage)
+		})
+	}
+}
+
+func TestDelimitedReader_EvaluateSource(t *testing.T) {
+	var buf bytes.Buffer
+	for _, tx := range blacklistedTransactions(3) {
+		require.NoError(t, WriteDelimited(&buf, tx))
+	}
+	engine, sink := newSourceEngine()
+
+	err := engine.EvaluateSource(context.Background(), NewDelimitedReader(&buf), 2, time.Hour)
+
+	require.NoError(t, err)
+	assert.Len(t, sink.Alerts(), 3)
+}
+
+// assertSameTransactions compares transactions field by field, amounts and times by value
+func assertSameTransactions(t *testing.T, want, got []Transaction) {
+	t.Helper()
+
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.True(t, want[i].Amount.Equal(got[i].Amount), "amount %d: %s != %s", i, want[i].Amount, got[i].Amount)
+		assert.True(t, want[i].CreatedAt.Equal(got[i].CreatedAt), "created_at %d", i)
+		w, g := want[i], got[i]
+		w.Amount, g.Amount = decimal.Decimal{}, decimal.Decimal{}
+		w.CreatedAt, g.CreatedAt = time.Time{}, time.Time{}
+		assert.Equal(t, w, g)
+	}
+}
@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransactionRepository loads stored transactions by creation time
+type TransactionRepository interface {
+	// LoadByTimeRange calls fn with the transactions created in [from, to), ordered by creation time
+	// then transaction ID, in pages of at most pageSize, so no more than a page is held at once.
+	// An error from fn stops the load and is returned as is.
+	LoadByTimeRange(ctx context.Context, from, to time.Time, pageSize int, fn func(page []Transaction) error) error
+}
+
+// EvaluateRange evaluates the transactions repo holds for [from, to) one page of pageSize at a time,
+// as EvaluateSource evaluates its batches: alerts reach the registered sinks and callbacks, and errors
+// of individual pages are joined into the returned error without stopping the run. Rules only see one
+// page at a time, so windows spanning pages need a stateful processor such as StatefulVelocityProcessor.
+// A pageSize below one fails with ErrInvalidBatchSize before anything is loaded.
+func (r *RuleEngine) EvaluateRange(ctx context.Context, repo TransactionRepository, from, to time.Time, pageSize int) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidBatchSize, pageSize)
+	}
+	ctx, stop := r.withProgress(ctx)
+	defer stop()
+
+	var pageErrs []error
+	err := repo.LoadByTimeRange(ctx, from, to, pageSize, func(page []Transaction) error {
+		if _, err := r.Evaluate(ctx, page); err != nil {
+			pageErrs = append(pageErrs, err)
+		}
+		return ctx.Err()
+	})
+	if err != nil {
+		pageErrs = append(pageErrs, fmt.Errorf("repository: %w", err))
+	}
+
+	return errors.Join(pageErrs...)
+}
+
+// SQLMapping names the table and columns SQLRepository reads. Transaction fields with an empty column
+// are left zero, except for TransactionID, UserID, Amount and CreatedAt, which are required. Names are
+// written into the queries as they are, so they must come from configuration, never from user input.
+type SQLMapping struct {
+	Table string
+
+	TransactionID       string
+	UserID              string
+	AccountID           string
+	Amount              string
+	Currency            string
+	Country             string
+	DestinationCountry  string
+	Status              string
+	Direction           string
+	Channel             string
+	Category            string
+	Description         string
+	CreatedAt           string
+	UserName            string
+	CounterpartyName    string
+	CounterpartyID      string
+	CounterpartyCountry string
+
+	// Placeholder returns the bind parameter of the n-th argument of a query, counting from one,
+	// "$n" as PostgreSQL and SQLite accept when nil. MySQL would need func(int) string { return "?" }.
+	Placeholder func(n int) string
+}
+
+// DefaultSQLMapping reads a "transactions" table whose columns are the snake_case field names,
+// e.g. transaction_id and created_at
+func DefaultSQLMapping() SQLMapping {
+	return SQLMapping{
+		Table:               "transactions",
+		TransactionID:       "transaction_id",
+		UserID:              "user_id",
+		AccountID:           "account_id",
+		Amount:              "amount",
+		Currency:            "currency",
+		Country:             "country",
+		DestinationCountry:  "destination_country",
+		Status:              "status",
+		Direction:           "direction",
+		Channel:             "channel",
+		Category:            "category",
+		Description:         "description",
+		CreatedAt:           "created_at",
+		UserName:            "user_name",
+		CounterpartyName:    "counterparty_name",
+		CounterpartyID:      "counterparty_id",
+		CounterpartyCountry: "counterparty_country",
+	}
+}
+
+// ErrIncompleteSQLMapping is returned by NewSQLRepository for a mapping without a table or a required column
+var ErrIncompleteSQLMapping = errors.New("sql mapping lacks a required name")
+
+// SQLRepository loads transactions from a database/sql table, paging with the creation time and
+// transaction ID of the last row read, so each page is a bounded index range scan. Amounts are read as
+// strings, so no precision is lost to floats, and UUIDs are parsed from their text form; NULL text
+// columns read as empty strings.
+type SQLRepository struct {
+	db      *sql.DB
+	mapping SQLMapping
+	columns []sqlColumn
+}
+
+// sqlColumn is a mapped column and the Transaction field it is read into
+type sqlColumn struct {
+	name string
+	set  func(tx *Transaction, value string, options csvOptions) error
+}
+
+func NewSQLRepository(db *sql.DB, mapping SQLMapping) (*SQLRepository, error) {
+	if mapping.Table == "" || mapping.TransactionID == "" || mapping.UserID == "" || mapping.Amount == "" || mapping.CreatedAt == "" {
+		return nil, ErrIncompleteSQLMapping
+	}
+	if mapping.Placeholder == nil {
+		mapping.Placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
+	}
+
+	// columns are read as the CSV loader reads the cells of the same fields
+	columns := map[string]string{
+		"user_id":              mapping.UserID,
+		"account_id":           mapping.AccountID,
+		"amount":               mapping.Amount,
+		"currency":             mapping.Currency,
+		"country":              mapping.Country,
+		"destination_country":  mapping.DestinationCountry,
+		"status":               mapping.Status,
+		"direction":            mapping.Direction,
+		"channel":              mapping.Channel,
+		"category":             mapping.Category,
+		"description":          mapping.Description,
+		"user_name":            mapping.UserName,
+		"counterparty_name":    mapping.CounterpartyName,
+		"counterparty_id":      mapping.CounterpartyID,
+		"counterparty_country": mapping.CounterpartyCountry,
+	}
+
+	repo := &SQLRepository{db: db, mapping: mapping}
+	for _, field := range csvFields {
+		if column := columns[field.name]; column != "" {
+			repo.columns = append(repo.columns, sqlColumn{name: column, set: field.set})
+		}
+	}
+
+	return repo, nil
+}
+
+func (s *SQLRepository) LoadByTimeRange(ctx context.Context, from, to time.Time, pageSize int, fn func(page []Transaction) error) error {
+	if pageSize <= 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidBatchSize, pageSize)
+	}
+
+	var after *sqlCursor
+	for {
+		page, last, err := s.loadPage(ctx, from, to, after, pageSize)
+		if err != nil {
+			return err
+		}
+		if len(page) > 0 {
+			if err := fn(page); err != nil {
+				return err
+			}
+		}
+		if len(page) < pageSize {
+			return nil
+		}
+		after = &last
+	}
+}
+
+// sqlCursor is the position of the last row of a page, the transaction ID kept as the database wrote it
+type sqlCursor struct {
+	createdAt     time.Time
+	transactionID string
+}
+
+// loadPage reads the rows of [from, to) following after, or from the start of the range without it
+func (s *SQLRepository) loadPage(ctx context.Context, from, to time.Time, after *sqlCursor, pageSize int) ([]Transaction, sqlCursor, error) {
+	query, args := s.pageQuery(from, to, after, pageSize)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, sqlCursor{}, err
+	}
+	defer rows.Close()
+
+	values := make([]sql.NullString, len(s.columns))
+	dest := make([]any, 0, len(s.columns)+2)
+	var last sqlCursor
+	dest = append(dest, &last.transactionID, &last.createdAt)
+	for i := range values {
+		dest = append(dest, &values[i])
+	}
+
+	page := make([]Transaction, 0, pageSize)
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, sqlCursor{}, fmt.Errorf("scan: %w", err)
+		}
+		tx, err := s.transaction(last, values)
+		if err != nil {
+			return nil, sqlCursor{}, fmt.Errorf("transaction %s: %w", last.transactionID, err)
+		}
+		page = append(page, tx)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, sqlCursor{}, err
+	}
+
+	return page, last, nil
+}
+
+func (s *SQLRepository) transaction(row sqlCursor, values []sql.NullString) (Transaction, error) {
+	tx := Transaction{CreatedAt: row.createdAt.UTC()}
+
+	var err error
+	if tx.TransactionID, err = uuid.Parse(row.transactionID); err != nil {
+		return Transaction{}, fmt.Errorf("column %s: %w", s.mapping.TransactionID, err)
+	}
+	for i, column := range s.columns {
+		if err := column.set(&tx, values[i].String, csvOptions{}); err != nil {
+			return Transaction{}, fmt.Errorf("column %s: %w", column.name, err)
+		}
+	}
+
+	return tx, nil
+}
+
+// pageQuery builds the query of a page, binding its arguments in the order they appear
+func (s *SQLRepository) pageQuery(from, to time.Time, after *sqlCursor, pageSize int) (string, []any) {
+	var args []any
+	bind := func(value any) string {
+		args = append(args, value)
+		return s.mapping.Placeholder(len(args))
+	}
+
+	id, createdAt := s.mapping.TransactionID, s.mapping.CreatedAt
+	selected := []string{id, createdAt}
+	for _, column := range s.columns {
+		selected = append(selected, column.name)
+	}
+
+	var query strings.Builder
+	fmt.Fprintf(&query, "SELECT %s FROM %s WHERE %s < %s", strings.Join(selected, ", "), s.mapping.Table, createdAt, bind(to))
+	if after == nil {
+		fmt.Fprintf(&query, " AND %s >= %s", createdAt, bind(from))
+	} else {
+		fmt.Fprintf(&query, " AND (%s > %s OR (%s = %s AND %s > %s))",
+			createdAt, bind(after.createdAt), createdAt, bind(after.createdAt), id, bind(after.transactionID))
+	}
+	fmt.Fprintf(&query, " ORDER BY %s, %s LIMIT %s", createdAt, id, bind(pageSize))
+
+	return query.String(), args
+}
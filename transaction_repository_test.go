@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// newTransactionsDB creates an in-memory SQLite database holding transactions in the default mapping's
+// table, amounts being stored as text as a NUMERIC column would return them
+func newTransactionsDB(t *testing.T, transactions []Transaction) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", "file:"+t.Name()+"?mode=memory&cache=shared")
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec(`CREATE TABLE transactions (
+		transaction_id TEXT PRIMARY KEY, user_id TEXT NOT NULL, account_id TEXT, amount TEXT NOT NULL,
+		currency TEXT, country TEXT, destination_country TEXT, status TEXT, direction TEXT, channel TEXT,
+		category TEXT, description TEXT, created_at DATETIME NOT NULL, user_name TEXT, counterparty_name TEXT,
+		counterparty_id TEXT, counterparty_country TEXT)`)
+	require.NoError(t, err)
+
+	for _, tx := range transactions {
+		var country any
+		if tx.Country != "" {
+			country = tx.Country
+		}
+		_, err := db.Exec(`INSERT INTO transactions (transaction_id, user_id, amount, country, currency, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`, tx.TransactionID.String(), tx.UserID.String(), tx.Amount.String(), country, tx.Currency, tx.CreatedAt)
+		require.NoError(t, err)
+	}
+
+	return db
+}
+
+// rangeTransactions are n transactions an hour apart from baseTime, every third without a country,
+// and pairs sharing a timestamp so pages break between rows created at the same time
+func rangeTransactions(baseTime time.Time, n int) []Transaction {
+	transactions := make([]Transaction, n)
+	for i := range transactions {
+		transactions[i] = Transaction{
+			TransactionID: uuid.New(),
+			UserID:        uuid.New(),
+			Amount:        decimal.RequireFromString(fmt.Sprintf("%d.000000000000000001", i)),
+			Currency:      "EUR",
+			CreatedAt:     baseTime.Add(time.Duration(i/2) * time.Hour),
+		}
+		if i%3 != 0 {
+			transactions[i].Country = "FR"
+		}
+	}
+
+	return transactions
+}
+
+func TestSQLRepository_LoadByTimeRange(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	transactions := rangeTransactions(baseTime, 10)
+	repo, err := NewSQLRepository(newTransactionsDB(t, transactions), DefaultSQLMapping())
+	require.NoError(t, err)
+
+	// created in [baseTime+1h, baseTime+4h): transactions 2 to 7
+	want := transactions[2:8]
+
+	for _, pageSize := range []int{1, 2, 3, 5, 6, 7, 100} {
+		t.Run(fmt.Sprint(pageSize), func(t *testing.T) {
+			var pages [][]Transaction
+			err := repo.LoadByTimeRange(context.Background(), baseTime.Add(time.Hour), baseTime.Add(4*time.Hour), pageSize,
+				func(page []Transaction) error {
+					pages = append(pages, page)
+					return nil
+				})
+			require.NoError(t, err)
+
+			var got []Transaction
+			for _, page := range pages {
+				assert.LessOrEqual(t, len(page), pageSize)
+				assert.NotEmpty(t, page)
+				got = append(got, page...)
+			}
+			assert.Len(t, pages, (len(want)+pageSize-1)/pageSize)
+			assert.ElementsMatch(t, transactionIDs(want), transactionIDs(got))
+			for i := 1; i < len(got); i++ {
+				assert.False(t, got[i].CreatedAt.Before(got[i-1].CreatedAt), "pages are ordered by creation time")
+			}
+
+			byID := make(map[uuid.UUID]Transaction)
+			for _, tx := range got {
+				byID[tx.TransactionID] = tx
+			}
+			for _, tx := range want {
+				loaded := byID[tx.TransactionID]
+				assert.Equal(t, tx.Amount.String(), loaded.Amount.String(), "amounts keep their precision")
+				assert.True(t, tx.CreatedAt.Equal(loaded.CreatedAt))
+				assert.Equal(t, tx.Country, loaded.Country, "NULL countries read as empty")
+				assert.Equal(t, tx.UserID, loaded.UserID)
+				assert.Equal(t, uuid.Nil, loaded.AccountID)
+			}
+		})
+	}
+}
+
+func TestSQLRepository_LoadByTimeRange_Errors(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	db := newTransactionsDB(t, rangeTransactions(baseTime, 4))
+	_, err := db.Exec(`INSERT INTO transactions (transaction_id, user_id, amount, created_at) VALUES ($1, $2, $3, $4)`,
+		uuid.NewString(), uuid.NewString(), "12,50", baseTime.Add(10*time.Hour))
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO transactions (transaction_id, user_id, amount, created_at) VALUES ($1, $2, $3, $4)`,
+		uuid.NewString(), "not-a-uuid", "1", baseTime.Add(20*time.Hour))
+	require.NoError(t, err)
+
+	repo, err := NewSQLRepository(db, DefaultSQLMapping())
+	require.NoError(t, err)
+	load := func(from, to time.Time, fn func([]Transaction) error) error {
+		return repo.LoadByTimeRange(context.Background(), from, to, 2, fn)
+	}
+	ignore := func([]Transaction) error { return nil }
+
+	err = load(baseTime, baseTime.Add(11*time.Hour), ignore)
+	assert.ErrorContains(t, err, "column amount:")
+
+	err = load(baseTime.Add(11*time.Hour), baseTime.Add(21*time.Hour), ignore)
+	assert.ErrorContains(t, err, "column user_id:")
+
+	stop := errors.New("stop")
+	pages := 0
+	err = load(baseTime, baseTime.Add(5*time.Hour), func([]Transaction) error {
+		pages++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, pages)
+
+	mapping := DefaultSQLMapping()
+	mapping.Table = "missing"
+	missing, err := NewSQLRepository(db, mapping)
+	require.NoError(t, err)
+	assert.Error(t, missing.LoadByTimeRange(context.Background(), baseTime, baseTime.Add(time.Hour), 2, ignore))
+
+	mapping = DefaultSQLMapping()
+	mapping.Amount = ""
+	_, err = NewSQLRepository(db, mapping)
+	assert.ErrorIs(t, err, ErrIncompleteSQLMapping)
+}
+
+func TestRuleEngine_EvaluateRange(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	transactions := rangeTransactions(baseTime, 10)
+	for i := range transactions {
+		if i%4 == 0 {
+			transactions[i].Country = "IR"
+		}
+	}
+	repo, err := NewSQLRepository(newTransactionsDB(t, transactions), DefaultSQLMapping())
+	require.NoError(t, err)
+	engine, sink := newSourceEngine()
+
+	err = engine.EvaluateRange(context.Background(), repo, baseTime, baseTime.Add(24*time.Hour), 3)
+
+	require.NoError(t, err)
+	assert.ElementsMatch(t,
+		[]uuid.UUID{transactions[0].UserID, transactions[4].UserID, transactions[8].UserID},
+		alertUserIDs(sink.Alerts()))
+	assert.Equal(t, int64(4), engine.Stats().Evaluations, "one evaluation per page")
+
+	assert.ErrorIs(t, engine.EvaluateRange(context.Background(), repo, baseTime, baseTime.Add(time.Hour), 0), ErrInvalidBatchSize)
+}
+
+func transactionIDs(transactions []Transaction) []uuid.UUID {
+	ids := make([]uuid.UUID, len(transactions))
+	for i, tx := range transactions {
+		ids[i] = tx.TransactionID
+	}
+
+	return ids
+}
+
+func alertUserIDs(alerts []Alert) []uuid.UUID {
+	ids := make([]uuid.UUID, len(alerts))
+	for i, alert := range alerts {
+		ids[i] = alert.UserID
+	}
+
+	return ids
+}
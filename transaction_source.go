@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// ErrTransientSource marks source errors worth retrying, such as a broker being briefly unreachable.
+// Sources wrap it, e.g. fmt.Errorf("%w: %w", ErrTransientSource, err).
+var ErrTransientSource = errors.New("transient source error")
+
+// ErrInvalidBatchSize is returned when transactions are to be evaluated in batches of fewer than one
+var ErrInvalidBatchSize = errors.New("batch size must be positive")
+
+// TransactionSource yields transactions one at a time, e.g. from a message broker consumer.
+// Next blocks until a transaction is available and returns io.EOF once the source is exhausted.
+// It must return promptly with the context's error once ctx is done.
+type TransactionSource interface {
+	Next(ctx context.Context) (Transaction, error)
+}
+
+// source retry backoff, doubling from the initial delay up to the maximum, and the consecutive
+// transient errors after which EvaluateSource gives up
+var (
+	sourceInitialBackoff = 50 * time.Millisecond
+	sourceMaxBackoff     = 5 * time.Second
+	sourceMaxRetries     = 8
+)
+
+// EvaluateSource pulls transactions from src and evaluates them in batches of batchSize, a partial
+// batch being evaluated once its first transaction has waited flushInterval, or only when full or at the
+// end of the run when flushInterval is not positive. A batchSize below one fails with ErrInvalidBatchSize
+// before anything is pulled from src. Alerts reach the
+// registered sinks and callbacks; errors of individual batches are joined into the returned error
+// without stopping the run.
+//
+// The run ends when src returns io.EOF, after evaluating the last partial batch; when it returns
+// any other error, which is returned after evaluating the pending batch; or when ctx is done,
+// the pending batch being discarded. Errors wrapping ErrTransientSource are retried with
+// exponential backoff before giving up.
+func (r *RuleEngine) EvaluateSource(ctx context.Context, src TransactionSource, batchSize int, flushInterval time.Duration) error {
+	return r.evaluateSource(ctx, src, batchSize, flushInterval, nil)
+}
+
+// evaluateSource is EvaluateSource, also passing the result of each batch to onBatch when set.
+// An error from onBatch ends the run and is returned as is.
+func (r *RuleEngine) evaluateSource(ctx context.Context, src TransactionSource, batchSize int, flushInterval time.Duration,
+	onBatch func(EvaluationResult) error,
+) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidBatchSize, batchSize)
+	}
+	ctx, stop := r.withProgress(ctx)
+	defer stop()
+
+	ctx, cancel := context.WithCancel(ctx)
+	pulled := make(chan sourceItem)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		pullSource(ctx, src, pulled)
+	}()
+	// the puller must not outlive the call, since src is the caller's again once it returns
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+
+	var batch []Transaction
+	var batchErrs []error
+	var timeout <-chan time.Time
+	var timer *time.Timer
+
+	flush := func() error {
+		if timer != nil {
+			timer.Stop()
+			timer, timeout = nil, nil
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+		result, err := r.Evaluate(ctx, batch)
+		if err != nil {
+			batchErrs = append(batchErrs, err)
+		}
+		batch = nil
+		if onBatch != nil {
+			return onBatch(result)
+		}
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return errors.Join(append(batchErrs, ctx.Err())...)
+
+		case <-timeout:
+			if err := flush(); err != nil {
+				return err
+			}
+
+		case item := <-pulled:
+			if item.err != nil {
+				if err := flush(); err != nil {
+					return err
+				}
+				if errors.Is(item.err, io.EOF) {
+					return errors.Join(batchErrs...)
+				}
+				return errors.Join(append(batchErrs, fmt.Errorf("source: %w", item.err))...)
+			}
+
+			batch = append(batch, item.tx)
+			if len(batch) == 1 && flushInterval > 0 {
+				timer = time.NewTimer(flushInterval)
+				timeout = timer.C
+			}
+			if len(batch) >= batchSize {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+type sourceItem struct {
+	tx  Transaction
+	err error
+}
+
+// pullSource forwards transactions from src until it fails, retrying transient errors
+func pullSource(ctx context.Context, src TransactionSource, pulled chan<- sourceItem) {
+	retries := 0
+	backoff := sourceInitialBackoff
+
+	for {
+		tx, err := src.Next(ctx)
+		if errors.Is(err, ErrTransientSource) && retries < sourceMaxRetries {
+			retries++
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			backoff = min(2*backoff, sourceMaxBackoff)
+			continue
+		}
+		retries, backoff = 0, sourceInitialBackoff
+
+		select {
+		case pulled <- sourceItem{tx: tx, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// SliceSource yields the transactions of a slice, then io.EOF
+type SliceSource struct {
+	transactions []Transaction
+}
+
+func NewSliceSource(transactions []Transaction) *SliceSource {
+	return &SliceSource{transactions: transactions}
+}
+
+func (s *SliceSource) Next(ctx context.Context) (Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return Transaction{}, err
+	}
+	if len(s.transactions) == 0 {
+		return Transaction{}, io.EOF
+	}
+
+	tx := s.transactions[0]
+	s.transactions = s.transactions[1:]
+	return tx, nil
+}
+
+// ChannelSource yields the transactions received on a channel, and io.EOF once it is closed
+type ChannelSource struct {
+	transactions <-chan Transaction
+}
+
+func NewChannelSource(transactions <-chan Transaction) ChannelSource {
+	return ChannelSource{transactions: transactions}
+}
+
+func (c ChannelSource) Next(ctx context.Context) (Transaction, error) {
+	select {
+	case tx, ok := <-c.transactions:
+		if !ok {
+			return Transaction{}, io.EOF
+		}
+		return tx, nil
+	case <-ctx.Done():
+		return Transaction{}, ctx.Err()
+	}
+}
+
+// ReaderSource yields the transactions of an NDJSON stream as it is read, malformed lines
+// failing with a RowError
+type ReaderSource struct {
+	decoder *ndjsonDecoder
+}
+
+func NewReaderSource(r io.Reader, opts ...JSONOption) *ReaderSource {
+	return &ReaderSource{decoder: newNDJSONDecoder(r, opts)}
+}
+
+func (s *ReaderSource) Next(ctx context.Context) (Transaction, error) {
+	if err := ctx.Err(); err != nil {
+		return Transaction{}, err
+	}
+
+	return s.decoder.next()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakySource yields its transactions, failing transiently on the calls listed in transient
+// and with err once exhausted
+type flakySource struct {
+	transactions []Transaction
+	transient    map[int]bool
+	err          error
+	calls        int
+}
+
+func (f *flakySource) Next(context.Context) (Transaction, error) {
+	f.calls++
+	if f.transient[f.calls] {
+		return Transaction{}, fmt.Errorf("%w: broker unreachable", ErrTransientSource)
+	}
+	if len(f.transactions) == 0 {
+		return Transaction{}, f.err
+	}
+
+	tx := f.transactions[0]
+	f.transactions = f.transactions[1:]
+	return tx, nil
+}
+
+func blacklistedTransactions(n int) []Transaction {
+	transactions := make([]Transaction, n)
+	for i := range transactions {
+		transactions[i] = Transaction{UserID: uuid.New(), Country: "IR", CreatedAt: time.Now()}
+	}
+
+	return transactions
+}
+
+func newSourceEngine() (*RuleEngine, *MemorySink) {
+	sink := NewMemorySink()
+	engine := NewRuleEngine([]RuleProcessor{NewCountryBlackListProcessor("IR")})
+	engine.AddAlertSink(sink)
+
+	return engine, sink
+}
+
+func shortSourceBackoff(t *testing.T) {
+	initial, maximum := sourceInitialBackoff, sourceMaxBackoff
+	sourceInitialBackoff, sourceMaxBackoff = time.Millisecond, 4*time.Millisecond
+	t.Cleanup(func() { sourceInitialBackoff, sourceMaxBackoff = initial, maximum })
+}
+
+func TestRuleEngine_EvaluateSource_BatchSize(t *testing.T) {
+	engine, sink := newSourceEngine()
+
+	err := engine.EvaluateSource(context.Background(), NewSliceSource(blacklistedTransactions(10)), 4, time.Hour)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(3), engine.Stats().Evaluations, "batches of 4, 4 and the final 2")
+	assert.Len(t, sink.Alerts(), 10)
+
+	for _, batchSize := range []int{0, -1} {
+		source := &flakySource{transactions: blacklistedTransactions(1), err: io.EOF}
+
+		err = engine.EvaluateSource(context.Background(), source, batchSize, time.Hour)
+		assert.ErrorIs(t, err, ErrInvalidBatchSize)
+		assert.Zero(t, source.calls, "nothing is pulled with an invalid batch size")
+	}
+}
+
+func TestRuleEngine_EvaluateSource_FlushInterval(t *testing.T) {
+	engine, sink := newSourceEngine()
+	transactions := make(chan Transaction)
+	done := make(chan error)
+
+	go func() {
+		done <- engine.EvaluateSource(context.Background(), NewChannelSource(transactions), 100, 10*time.Millisecond)
+	}()
+
+	for _, tx := range blacklistedTransactions(2) {
+		transactions <- tx
+	}
+	assert.Eventually(t, func() bool { return len(sink.Alerts()) == 2 }, time.Second, time.Millisecond,
+		"a partial batch is evaluated once the interval elapses")
+
+	transactions <- blacklistedTransactions(1)[0]
+	close(transactions)
+	require.NoError(t, <-done)
+	assert.Len(t, sink.Alerts(), 3)
+	assert.Equal(t, int64(2), engine.Stats().Evaluations)
+}
+
+func TestRuleEngine_EvaluateSource_Errors(t *testing.T) {
+	shortSourceBackoff(t)
+	errBroker := errors.New("consumer group rebalanced")
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		engine, sink := newSourceEngine()
+		source := &flakySource{
+			transactions: blacklistedTransactions(5),
+			transient:    map[int]bool{1: true, 3: true, 4: true, 7: true},
+			err:          io.EOF,
+		}
+
+		require.NoError(t, engine.EvaluateSource(context.Background(), source, 2, time.Hour))
+		assert.Len(t, sink.Alerts(), 5)
+		assert.Equal(t, 10, source.calls)
+	})
+
+	t.Run("persistent transient errors give up", func(t *testing.T) {
+		engine, _ := newSourceEngine()
+		transient := make(map[int]bool)
+		for call := range sourceMaxRetries + 1 {
+			transient[call+1] = true
+		}
+		source := &flakySource{transient: transient, err: io.EOF}
+
+		err := engine.EvaluateSource(context.Background(), source, 2, time.Hour)
+		assert.ErrorIs(t, err, ErrTransientSource)
+		assert.Equal(t, sourceMaxRetries+1, source.calls)
+	})
+
+	t.Run("other errors stop after the pending batch", func(t *testing.T) {
+		engine, sink := newSourceEngine()
+		source := &flakySource{transactions: blacklistedTransactions(3), err: errBroker}
+
+		err := engine.EvaluateSource(context.Background(), source, 2, time.Hour)
+		assert.ErrorIs(t, err, errBroker)
+		assert.Len(t, sink.Alerts(), 3)
+	})
+
+	t.Run("cancellation discards the pending batch", func(t *testing.T) {
+		engine, sink := newSourceEngine()
+		transactions := make(chan Transaction, 1)
+		transactions <- blacklistedTransactions(1)[0]
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+
+		err := engine.EvaluateSource(ctx, NewChannelSource(transactions), 2, time.Hour)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, sink.Alerts())
+	})
+}
+
+func TestReaderSource(t *testing.T) {
+	input := `{"user_id":"1b4e28ba-2fa1-11d2-883f-0016d3cca427","country":"IR","amount":"1","created_at":"2024-03-01T09:00:00Z"}
+{"user_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8","country":"IR","amount":"1","created_at":"2024-03-01T09:00:00Z"}
+not json
+`
+	engine, sink := newSourceEngine()
+
+	err := engine.EvaluateSource(context.Background(), NewReaderSource(strings.NewReader(input)), 10, time.Hour)
+
+	var rowErr RowError
+	require.ErrorAs(t, err, &rowErr)
+	assert.Equal(t, 3, rowErr.Line)
+	assert.Len(t, sink.Alerts(), 2, "transactions before the malformed line are evaluated")
+
+	source := NewReaderSource(strings.NewReader(input))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = source.Next(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// AmountDistribution draws transaction amounts
+type AmountDistribution func(rng *rand.Rand) decimal.Decimal
+
+// FixedAmount gives every transaction amount
+func FixedAmount(amount decimal.Decimal) AmountDistribution {
+	return func(*rand.Rand) decimal.Decimal { return amount }
+}
+
+// UniformAmount draws amounts uniformly from [min, max), rounded down to cents
+func UniformAmount(min, max decimal.Decimal) AmountDistribution {
+	span := max.Sub(min)
+	return func(rng *rand.Rand) decimal.Decimal {
+		return min.Add(span.Mul(decimal.NewFromFloat(rng.Float64()))).RoundDown(2)
+	}
+}
+
+// LogNormalAmount draws amounts whose natural logarithm is normal with mean mu and standard deviation
+// sigma, the long right tail of real spending, rounded to cents. The median amount is e^mu.
+func LogNormalAmount(mu, sigma float64) AmountDistribution {
+	return func(rng *rand.Rand) decimal.Decimal {
+		return decimal.NewFromFloat(math.Exp(mu + sigma*rng.NormFloat64())).Round(2)
+	}
+}
+
+// CountDistribution draws the number of transactions of each user
+type CountDistribution func(rng *rand.Rand) int
+
+// FixedCount gives every user n transactions
+func FixedCount(n int) CountDistribution {
+	return func(*rand.Rand) int { return n }
+}
+
+// UniformCount draws between min and max transactions per user, both included
+func UniformCount(min, max int) CountDistribution {
+	return func(rng *rand.Rand) int { return min + rng.IntN(max-min+1) }
+}
+
+// PoissonCount draws Poisson distributed counts of mean mean
+func PoissonCount(mean float64) CountDistribution {
+	return func(rng *rand.Rand) int {
+		// Knuth's method, splitting large means so e^-mean does not underflow
+		n := 0
+		for remaining := mean; remaining > 0; remaining -= 500 {
+			limit, product := math.Exp(-min(remaining, 500)), rng.Float64()
+			for ; product > limit; n++ {
+				product *= rng.Float64()
+			}
+		}
+		return n
+	}
+}
+
+// Spacing draws the time between two transactions of a user
+type Spacing func(rng *rand.Rand) time.Duration
+
+// FixedSpacing spaces transactions evenly
+func FixedSpacing(d time.Duration) Spacing {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+// PoissonSpacing spaces transactions as a Poisson process would, with exponentially distributed gaps of mean mean
+func PoissonSpacing(mean time.Duration) Spacing {
+	return func(rng *rand.Rand) time.Duration { return time.Duration(rng.ExpFloat64() * float64(mean)) }
+}
+
+// TransactionGenerator fabricates batches of transactions for tests and benchmarks, optionally planting
+// users a rule is known to flag. The same options and seed always generate the same batch.
+type TransactionGenerator struct {
+	seed               uint64
+	users              int
+	perUser            CountDistribution
+	amounts            AmountDistribution
+	spacing            Spacing
+	start              time.Time
+	countries          []string
+	counterparties     []string
+	timeOrder          bool
+	velocityViolators  int
+	violatedPeriod     VelocityPeriod
+	structuringUsers   int
+	reportingThreshold decimal.Decimal
+}
+
+type GeneratorOption func(*TransactionGenerator)
+
+// NewTransactionGenerator generates 100 users with 10 transactions each by default, of uniform
+// amounts between 1 and 1000, an hour apart from 2024-01-01 UTC on, with seed 1
+func NewTransactionGenerator(opts ...GeneratorOption) *TransactionGenerator {
+	g := &TransactionGenerator{
+		seed:    1,
+		users:   100,
+		perUser: FixedCount(10),
+		amounts: UniformAmount(decimal.NewFromInt(1), decimal.NewFromInt(1000)),
+		spacing: FixedSpacing(time.Hour),
+		start:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// WithSeed seeds the generator's randomness, user and transaction IDs included
+func WithSeed(seed uint64) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.seed = seed
+	}
+}
+
+// WithUserCount sets the number of users generated besides the planted ones
+func WithUserCount(n int) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.users = n
+	}
+}
+
+func WithTransactionsPerUser(counts CountDistribution) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.perUser = counts
+	}
+}
+
+func WithAmounts(amounts AmountDistribution) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.amounts = amounts
+	}
+}
+
+// WithSpacing sets the gaps between the transactions of a user, whose first one is at the start time
+func WithSpacing(spacing Spacing) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.spacing = spacing
+	}
+}
+
+func WithStartTime(start time.Time) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.start = start
+	}
+}
+
+// WithCountryPool draws each transaction's Country from countries, which are left empty without one
+func WithCountryPool(countries ...string) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.countries = countries
+	}
+}
+
+// WithCounterpartyPool draws each transaction's CounterpartyID from counterparties, which are left
+// empty without one
+func WithCounterpartyPool(counterparties ...string) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.counterparties = counterparties
+	}
+}
+
+// WithTimeOrder orders the batch by CreatedAt, as a feed would deliver it, instead of grouping it by user
+func WithTimeOrder() GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.timeOrder = true
+	}
+}
+
+// WithVelocityViolators plants n users with period.Threshold+1 transactions within period.Duration,
+// so a velocity processor checking period flags them
+func WithVelocityViolators(n int, period VelocityPeriod) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.velocityViolators, g.violatedPeriod = n, period
+	}
+}
+
+// WithStructuringUsers plants n users with three transactions an hour apart, each between 95% and
+// 99% of threshold, so a StructuringProcessor with a band of 5% or more, a count of up to three and a
+// window of at least two hours flags them
+func WithStructuringUsers(n int, threshold decimal.Decimal) GeneratorOption {
+	return func(g *TransactionGenerator) {
+		g.structuringUsers, g.reportingThreshold = n, threshold
+	}
+}
+
+// GeneratedTransactions is a generated batch and the users planted in it
+type GeneratedTransactions struct {
+	Transactions []Transaction
+	// Users are the users generated from the distributions, in generation order
+	Users             []uuid.UUID
+	VelocityViolators []uuid.UUID
+	StructuringUsers  []uuid.UUID
+}
+
+// structuringDeposits is the number of transactions of a structuring user
+const structuringDeposits = 3
+
+// Generate fabricates a batch, the users generated from the distributions first, then the velocity
+// violators, then the structuring users, each user's transactions following each other
+func (g *TransactionGenerator) Generate() GeneratedTransactions {
+	rng := rand.New(rand.NewPCG(g.seed, g.seed^0x9e3779b97f4a7c15))
+	var generated GeneratedTransactions
+
+	for range g.users {
+		userID := randomUUID(rng)
+		generated.Users = append(generated.Users, userID)
+
+		at := g.start
+		for i := range g.perUser(rng) {
+			if i > 0 {
+				at = at.Add(g.spacing(rng))
+			}
+			generated.Transactions = append(generated.Transactions, g.transaction(rng, userID, g.amounts(rng), at))
+		}
+	}
+
+	count := g.violatedPeriod.Threshold + 1
+	for range g.velocityViolators {
+		userID := randomUUID(rng)
+		generated.VelocityViolators = append(generated.VelocityViolators, userID)
+
+		gap := g.violatedPeriod.Duration / time.Duration(count)
+		for i := range count {
+			generated.Transactions = append(generated.Transactions, g.transaction(rng, userID, g.amounts(rng), g.start.Add(time.Duration(i)*gap)))
+		}
+	}
+
+	for range g.structuringUsers {
+		userID := randomUUID(rng)
+		generated.StructuringUsers = append(generated.StructuringUsers, userID)
+
+		for i := range structuringDeposits {
+			share := decimal.NewFromFloat(0.95 + 0.04*rng.Float64())
+			amount := g.reportingThreshold.Mul(share).RoundDown(2)
+			generated.Transactions = append(generated.Transactions, g.transaction(rng, userID, amount, g.start.Add(time.Duration(i)*time.Hour)))
+		}
+	}
+
+	if g.timeOrder {
+		slices.SortStableFunc(generated.Transactions, func(a, b Transaction) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		})
+	}
+
+	return generated
+}
+
+func (g *TransactionGenerator) transaction(rng *rand.Rand, userID uuid.UUID, amount decimal.Decimal, at time.Time) Transaction {
+	tx := Transaction{
+		TransactionID: randomUUID(rng),
+		UserID:        userID,
+		Amount:        amount,
+		CreatedAt:     at,
+	}
+	if len(g.countries) > 0 {
+		tx.Country = g.countries[rng.IntN(len(g.countries))]
+	}
+	if len(g.counterparties) > 0 {
+		tx.CounterpartyID = g.counterparties[rng.IntN(len(g.counterparties))]
+	}
+
+	return tx
+}
+
+// randomUUID draws a version 4 UUID from rng, so generated IDs are reproducible
+func randomUUID(rng *rand.Rand) uuid.UUID {
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[:8], rng.Uint64())
+	binary.BigEndian.PutUint64(id[8:], rng.Uint64())
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+
+	return id
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"math/rand/v2"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionGenerator_Reproducible(t *testing.T) {
+	opts := []GeneratorOption{
+		WithUserCount(20),
+		WithTransactionsPerUser(PoissonCount(8)),
+		WithAmounts(LogNormalAmount(5, 1)),
+		WithSpacing(PoissonSpacing(time.Hour)),
+		WithCountryPool("DE", "FR", "IR"),
+		WithCounterpartyPool("acme", "globex"),
+		WithVelocityViolators(2, NewVelocityPeriod(24*time.Hour, 4)),
+	}
+
+	first := NewTransactionGenerator(append(opts, WithSeed(7))...).Generate()
+	again := NewTransactionGenerator(append(opts, WithSeed(7))...).Generate()
+	other := NewTransactionGenerator(append(opts, WithSeed(8))...).Generate()
+
+	assert.Equal(t, first, again)
+	assert.NotEqual(t, first.Users, other.Users)
+	assert.Len(t, first.Users, 20)
+	assert.Len(t, first.VelocityViolators, 2)
+
+	for _, tx := range first.Transactions {
+		assert.Contains(t, []string{"DE", "FR", "IR"}, tx.Country)
+		assert.Contains(t, []string{"acme", "globex"}, tx.CounterpartyID)
+		assert.True(t, tx.Amount.IsPositive())
+		assert.Equal(t, uuid.Version(4), tx.TransactionID.Version())
+	}
+}
+
+func TestTransactionGenerator_Defaults(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	generated := NewTransactionGenerator(
+		WithUserCount(3),
+		WithTransactionsPerUser(FixedCount(4)),
+		WithAmounts(FixedAmount(decimal.NewFromInt(50))),
+		WithSpacing(FixedSpacing(30*time.Minute)),
+		WithStartTime(start),
+	).Generate()
+
+	require.Len(t, generated.Transactions, 12)
+	for i, tx := range generated.Transactions {
+		assert.Equal(t, generated.Users[i/4], tx.UserID, "transactions are grouped by user")
+		assert.Equal(t, start.Add(time.Duration(i%4)*30*time.Minute), tx.CreatedAt)
+		assert.Equal(t, "50", tx.Amount.String())
+		assert.Empty(t, tx.Country)
+	}
+
+	ordered := NewTransactionGenerator(WithUserCount(3), WithTransactionsPerUser(FixedCount(4)), WithTimeOrder()).Generate()
+	assert.True(t, slices.IsSortedFunc(ordered.Transactions, func(a, b Transaction) int { return a.CreatedAt.Compare(b.CreatedAt) }))
+	assert.Equal(t, ordered.Users, []uuid.UUID{ordered.Transactions[0].UserID, ordered.Transactions[1].UserID, ordered.Transactions[2].UserID})
+}
+
+func TestTransactionGenerator_Distributions(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	low, high := decimal.NewFromInt(10), decimal.NewFromInt(20)
+
+	uniform, counts, poisson := UniformAmount(low, high), UniformCount(2, 4), PoissonCount(1000)
+	sum := 0
+	for range 1000 {
+		amount := uniform(rng)
+		assert.True(t, amount.GreaterThanOrEqual(low) && amount.LessThan(high), amount.String())
+		assert.LessOrEqual(t, amount.Exponent(), int32(0))
+		assert.GreaterOrEqual(t, amount.Exponent(), int32(-2))
+
+		n := counts(rng)
+		assert.True(t, n >= 2 && n <= 4, n)
+		sum += poisson(rng)
+	}
+	assert.InDelta(t, 1000, float64(sum)/1000, 10, "a mean above e^-mean underflow is still met")
+
+	var gaps time.Duration
+	spacing := PoissonSpacing(time.Hour)
+	for range 1000 {
+		gaps += spacing(rng)
+	}
+	assert.InDelta(t, float64(time.Hour), float64(gaps/1000), float64(10*time.Minute))
+}
+
+func TestTransactionGenerator_PlantedUsers(t *testing.T) {
+	period := NewVelocityPeriod(24*time.Hour, 5)
+	threshold := decimal.NewFromInt(10000)
+	generated := NewTransactionGenerator(
+		WithSeed(42),
+		WithUserCount(200),
+		WithAmounts(UniformAmount(decimal.NewFromInt(1), decimal.NewFromInt(5000))),
+		WithSpacing(FixedSpacing(6*time.Hour)),
+		WithVelocityViolators(5, period),
+		WithStructuringUsers(4, threshold),
+		WithTimeOrder(),
+	).Generate()
+
+	velocity := NewVelocityValidator([]VelocityPeriod{period}).Process(context.Background(), generated.Transactions)
+	assert.Len(t, velocity, 5, "background users stay below the limit")
+	for _, userID := range generated.VelocityViolators {
+		assert.Contains(t, velocity, userID)
+	}
+
+	structuring := NewStructuringProcessor(threshold, decimal.RequireFromString("0.05"), 3, 24*time.Hour).
+		Process(context.Background(), generated.Transactions)
+	assert.Len(t, structuring, 4)
+	for _, userID := range generated.StructuringUsers {
+		assert.Contains(t, structuring, userID)
+	}
+
+	amounts := TransactionAmountProcessor{Threshold: threshold}.Process(context.Background(), generated.Transactions)
+	assert.Empty(t, amounts, "structuring users stay below the reporting threshold")
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrProfileNotFound is returned by a UserProfileProvider without a profile for the requested user
+var ErrProfileNotFound = errors.New("user profile not found")
+
+// RiskTier is the customer risk rating assigned at onboarding or review. The zero value means unrated.
+type RiskTier string
+
+const (
+	RiskTierLow    RiskTier = "low"
+	RiskTierMedium RiskTier = "medium"
+	RiskTierHigh   RiskTier = "high"
+)
+
+// UserProfile holds the account data rules need beyond transactions
+type UserProfile struct {
+	CreatedAt time.Time
+	RiskTier  RiskTier
+	// HomeCountry is the user's country of residence, empty when unknown
+	HomeCountry string
+}
+
+// UserProfileProvider looks up user profiles, typically from the customer database
+type UserProfileProvider interface {
+	Profile(ctx context.Context, userID uuid.UUID) (UserProfile, error)
+}
+
+// InMemoryProfileProvider is a UserProfileProvider backed by a map
+type InMemoryProfileProvider map[uuid.UUID]UserProfile
+
+func (p InMemoryProfileProvider) Profile(_ context.Context, userID uuid.UUID) (UserProfile, error) {
+	profile, ok := p[userID]
+	if !ok {
+		return UserProfile{}, ErrProfileNotFound
+	}
+
+	return profile, nil
+}
+
+// loadProfiles looks up each user once. Users whose lookup failed are missing from the profiles
+// and reported in the errors instead.
+func loadProfiles(ctx context.Context, provider UserProfileProvider, userIDs []uuid.UUID) (map[uuid.UUID]UserProfile, UserErrors) {
+	profiles := make(map[uuid.UUID]UserProfile, len(userIDs))
+	userErrors := make(UserErrors)
+
+	for _, userID := range userIDs {
+		if _, ok := profiles[userID]; ok {
+			continue
+		}
+		if _, ok := userErrors[userID]; ok {
+			continue
+		}
+
+		profile, err := provider.Profile(ctx, userID)
+		if err != nil {
+			userErrors[userID] = err
+			continue
+		}
+		profiles[userID] = profile
+	}
+
+	return profiles, userErrors
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidBatch is returned by RuleEngine.Evaluate when validation fails a batch under ValidationFail
+var ErrInvalidBatch = errors.New("transaction batch failed validation")
+
+// ValidationAction is what happens to transactions failing validation
+type ValidationAction string
+
+const (
+	// ValidationDrop removes offending transactions from the batch
+	ValidationDrop ValidationAction = "drop"
+	// ValidationKeep only reports offending transactions
+	ValidationKeep ValidationAction = "keep"
+	// ValidationFail rejects the whole batch when any transaction fails
+	ValidationFail ValidationAction = "fail"
+)
+
+// ValidationCheck names a validation rule
+type ValidationCheck string
+
+const (
+	CheckZeroUserID      ValidationCheck = "zero_user_id"
+	CheckZeroCreatedAt   ValidationCheck = "zero_created_at"
+	CheckFutureCreatedAt ValidationCheck = "future_created_at"
+	CheckNegativeAmount  ValidationCheck = "negative_amount"
+	CheckZeroAmount      ValidationCheck = "zero_amount"
+	CheckMissingCountry  ValidationCheck = "missing_country"
+)
+
+// ValidationPolicy configures ValidateTransactions. A zero UserID or CreatedAt always fails, since
+// such transactions would be grouped as one user or break windows; the other checks are optional.
+type ValidationPolicy struct {
+	// Action defaults to ValidationDrop
+	Action ValidationAction
+	// MaxFutureSkew fails transactions created further than this in the future, zero disabling the check
+	MaxFutureSkew time.Duration
+	// RejectNegativeAmounts fails negative amounts, which are otherwise treated as reversals
+	RejectNegativeAmounts bool
+	RejectZeroAmounts     bool
+	RequireCountry        bool
+	// Now returns the reference time of MaxFutureSkew, time.Now if nil
+	Now func() time.Time
+}
+
+// DefaultValidationPolicy drops transactions without a user or timestamp, with a zero amount or
+// created more than five minutes in the future
+func DefaultValidationPolicy() ValidationPolicy {
+	return ValidationPolicy{
+		Action:            ValidationDrop,
+		MaxFutureSkew:     5 * time.Minute,
+		RejectZeroAmounts: true,
+	}
+}
+
+// ValidationError is one check failed by one transaction
+type ValidationError struct {
+	// Index is the position of the transaction in the validated batch
+	Index         int             `json:"index"`
+	TransactionID uuid.UUID       `json:"transaction_id,omitzero"`
+	UserID        uuid.UUID       `json:"user_id"`
+	Check         ValidationCheck `json:"check"`
+	Message       string          `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("transaction %d: %s: %s", e.Index, e.Check, e.Message)
+}
+
+// ValidateTransactions checks every transaction against policy and returns the batch to process,
+// which depends on the policy's action, along with every failed check. A transaction failing several
+// checks is reported once per check. Under ValidationFail the returned batch is nil if any check failed.
+func ValidateTransactions(transactions []Transaction, policy ValidationPolicy) ([]Transaction, []ValidationError) {
+	now := time.Now
+	if policy.Now != nil {
+		now = policy.Now
+	}
+	latest := now().Add(policy.MaxFutureSkew)
+
+	var errs []ValidationError
+	valid := make([]Transaction, 0, len(transactions))
+	for i, tx := range transactions {
+		failed := len(errs)
+		fail := func(check ValidationCheck, format string, args ...any) {
+			errs = append(errs, ValidationError{
+				Index:         i,
+				TransactionID: tx.TransactionID,
+				UserID:        tx.UserID,
+				Check:         check,
+				Message:       fmt.Sprintf(format, args...),
+			})
+		}
+
+		if tx.UserID == uuid.Nil {
+			fail(CheckZeroUserID, "user ID is not set")
+		}
+		switch {
+		case tx.CreatedAt.IsZero():
+			fail(CheckZeroCreatedAt, "creation time is not set")
+		case policy.MaxFutureSkew > 0 && tx.CreatedAt.After(latest):
+			fail(CheckFutureCreatedAt, "created at %s, more than %s in the future", detailTime(tx.CreatedAt), policy.MaxFutureSkew)
+		}
+		switch {
+		case policy.RejectNegativeAmounts && tx.Amount.IsNegative():
+			fail(CheckNegativeAmount, "amount %s is negative", tx.Amount)
+		case policy.RejectZeroAmounts && tx.Amount.IsZero():
+			fail(CheckZeroAmount, "amount is zero")
+		}
+		if policy.RequireCountry && normalizeCountry(tx.Country) == "" {
+			fail(CheckMissingCountry, "country is not set")
+		}
+
+		if len(errs) == failed || policy.Action == ValidationKeep {
+			valid = append(valid, tx)
+		}
+	}
+
+	if policy.Action == ValidationFail && len(errs) > 0 {
+		return nil, errs
+	}
+
+	return valid, errs
+}
+
+// WithValidation validates every batch with policy before the processors run, the errors being
+// reported in EvaluationResult.ValidationErrors
+func WithValidation(policy ValidationPolicy) RuleEngineOption {
+	return func(r *RuleEngine) {
+		r.validation = &policy
+	}
+}
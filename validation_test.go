@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTransactions_Checks(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	valid := Transaction{UserID: userID, Country: "FR", Amount: decimal.NewFromInt(10), CreatedAt: now}
+
+	with := func(change func(*Transaction)) Transaction {
+		tx := valid
+		change(&tx)
+		return tx
+	}
+
+	policy := ValidationPolicy{
+		MaxFutureSkew:         time.Minute,
+		RejectNegativeAmounts: true,
+		RejectZeroAmounts:     true,
+		RequireCountry:        true,
+		Now:                   func() time.Time { return now },
+	}
+
+	tests := []struct {
+		name   string
+		tx     Transaction
+		policy ValidationPolicy
+		want   []ValidationCheck
+	}{
+		{name: "valid", tx: valid, policy: policy},
+		{name: "zero user ID", tx: with(func(tx *Transaction) { tx.UserID = uuid.Nil }), policy: policy, want: []ValidationCheck{CheckZeroUserID}},
+		{name: "zero created at", tx: with(func(tx *Transaction) { tx.CreatedAt = time.Time{} }), policy: policy, want: []ValidationCheck{CheckZeroCreatedAt}},
+		{name: "within the future skew", tx: with(func(tx *Transaction) { tx.CreatedAt = now.Add(time.Minute) }), policy: policy},
+		{name: "beyond the future skew", tx: with(func(tx *Transaction) { tx.CreatedAt = now.Add(time.Minute + time.Second) }), policy: policy, want: []ValidationCheck{CheckFutureCreatedAt}},
+		{name: "negative amount", tx: with(func(tx *Transaction) { tx.Amount = decimal.NewFromInt(-5) }), policy: policy, want: []ValidationCheck{CheckNegativeAmount}},
+		{name: "zero amount", tx: with(func(tx *Transaction) { tx.Amount = decimal.Zero }), policy: policy, want: []ValidationCheck{CheckZeroAmount}},
+		{name: "missing country", tx: with(func(tx *Transaction) { tx.Country = " " }), policy: policy, want: []ValidationCheck{CheckMissingCountry}},
+		{
+			name:   "several checks",
+			tx:     Transaction{Amount: decimal.Zero},
+			policy: policy,
+			want:   []ValidationCheck{CheckZeroUserID, CheckZeroCreatedAt, CheckZeroAmount, CheckMissingCountry},
+		},
+		{
+			name:   "optional checks disabled",
+			tx:     with(func(tx *Transaction) { tx.Amount, tx.Country, tx.CreatedAt = decimal.NewFromInt(-5), "", now.Add(week) }),
+			policy: ValidationPolicy{Now: policy.Now},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := ValidateTransactions([]Transaction{tt.tx}, tt.policy)
+
+			var checks []ValidationCheck
+			for _, err := range errs {
+				assert.Zero(t, err.Index)
+				checks = append(checks, err.Check)
+			}
+			assert.Equal(t, tt.want, checks)
+		})
+	}
+}
+
+func TestValidateTransactions_Actions(t *testing.T) {
+	now := time.Now()
+	good := Transaction{UserID: uuid.New(), Amount: decimal.NewFromInt(10), CreatedAt: now}
+	bad := Transaction{TransactionID: uuid.New(), Amount: decimal.NewFromInt(10), CreatedAt: now}
+	transactions := []Transaction{good, bad, good}
+
+	tests := []struct {
+		action ValidationAction
+		want   []Transaction
+	}{
+		{action: "", want: []Transaction{good, good}},
+		{action: ValidationDrop, want: []Transaction{good, good}},
+		{action: ValidationKeep, want: transactions},
+		{action: ValidationFail, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.action), func(t *testing.T) {
+			kept, errs := ValidateTransactions(transactions, ValidationPolicy{Action: tt.action})
+
+			assert.Equal(t, tt.want, kept)
+			require.Len(t, errs, 1)
+			assert.Equal(t, 1, errs[0].Index)
+			assert.Equal(t, bad.TransactionID, errs[0].TransactionID)
+			assert.EqualError(t, errs[0], "transaction 1: zero_user_id: user ID is not set")
+		})
+	}
+
+	kept, errs := ValidateTransactions([]Transaction{good}, ValidationPolicy{Action: ValidationFail})
+	assert.Equal(t, []Transaction{good}, kept)
+	assert.Empty(t, errs)
+}
+
+func TestRuleEngine_Evaluate_Validation(t *testing.T) {
+	flagged := uuid.New()
+	transactions := []Transaction{
+		{UserID: flagged, Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: time.Now()},
+		{Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: time.Now()},
+		{UserID: uuid.New(), Country: "IR", Amount: decimal.NewFromInt(10), CreatedAt: time.Now().Add(week)},
+	}
+	rules := []RuleProcessor{NewCountryBlackListProcessor("IR")}
+
+	engine := NewRuleEngine(rules, WithValidation(DefaultValidationPolicy()))
+	result, err := engine.Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+	require.Len(t, result.Alerts, 1, "neither the anonymous nor the future transaction is processed")
+	assert.Equal(t, flagged, result.Alerts[0].UserID)
+	assert.Len(t, result.ValidationErrors, 2)
+
+	policy := DefaultValidationPolicy()
+	policy.Action = ValidationFail
+	engine = NewRuleEngine(rules, WithValidation(policy))
+	result, err = engine.Evaluate(context.Background(), transactions)
+	require.ErrorIs(t, err, ErrInvalidBatch)
+	assert.Empty(t, result.Alerts)
+	assert.Len(t, result.ValidationErrors, 2)
+
+	result, err = NewRuleEngine(rules).Evaluate(context.Background(), transactions)
+	require.NoError(t, err)
+	assert.Len(t, result.Alerts, 3, "without validation every transaction is processed")
+	assert.Empty(t, result.ValidationErrors)
+}
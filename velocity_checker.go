@@ -0,0 +1,348 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrUnsortedTransactions is returned in strict sorted-input mode when a group's transactions are not ordered by CreatedAt
+var ErrUnsortedTransactions = errors.New("transactions are not sorted by CreatedAt")
+
+// VelocityViolation describes the first window found to exceed a period's threshold
+type VelocityViolation struct {
+	PeriodName  string
+	Period      VelocityPeriod
+	Count       int
+	WindowStart time.Time
+	WindowEnd   time.Time
+}
+
+// velocityChecker holds the sliding-window logic shared by every velocity processor
+type velocityChecker struct {
+	periods []VelocityPeriod
+	options velocityOptions
+	// scanPeriods are periods tightest first, scanPositions[i] being where periods[i] is in them, nil
+	// when they are in configuration order already
+	scanPeriods   []VelocityPeriod
+	scanPositions []int
+}
+
+func newVelocityChecker(periods []VelocityPeriod, options velocityOptions) velocityChecker {
+	checker := velocityChecker{periods: periods, options: options, scanPeriods: periods}
+	if order := tightnessOrder(periods); order != nil {
+		checker.scanPeriods = make([]VelocityPeriod, len(periods))
+		checker.scanPositions = make([]int, len(periods))
+		for position, i := range order {
+			checker.scanPeriods[position] = periods[i]
+			checker.scanPositions[i] = position
+		}
+	}
+
+	return checker
+}
+
+// tightnessOrder lists the indexes of periods by ascending threshold to duration ratio, the period
+// allowing the fewest transactions per unit of time and so the likeliest to fire first. It returns
+// nil when periods are in that order already.
+func tightnessOrder(periods []VelocityPeriod) []int {
+	ordered := func(a, b int) int {
+		return cmp.Compare(periods[a].tightness(), periods[b].tightness())
+	}
+	order := make([]int, len(periods))
+	for i := range order {
+		order[i] = i
+	}
+	if slices.IsSortedFunc(order, ordered) {
+		return nil
+	}
+	slices.SortStableFunc(order, ordered)
+
+	return order
+}
+
+// tightness is the threshold to duration ratio of the period. A period without a duration only
+// counts transactions sharing a timestamp and comes last.
+func (p VelocityPeriod) tightness() float64 {
+	if p.Duration <= 0 {
+		return math.Inf(1)
+	}
+
+	return float64(p.Threshold) / float64(p.Duration)
+}
+
+// CheckUser orders a single user's transactions and reports whether any period is violated
+func (c velocityChecker) CheckUser(txs []Transaction) (bool, error) {
+	if err := c.order(txs); err != nil { // O(T) or O(T log T)
+		return false, err
+	}
+
+	violated, _ := c.scan(txs, true) // O(P * T), single pass over txs
+	return violated, nil
+}
+
+// CheckIndexed is CheckUser over the transactions of batch at index, ordering index rather than
+// the transactions themselves, which are neither moved nor copied. It returns ctx's error when ctx
+// is done before the check completes, which the window scan of a large user checks as it goes.
+func (c velocityChecker) CheckIndexed(ctx context.Context, batch []Transaction, index []int32) (bool, error) {
+	view := transactionView{batch: batch, index: index}
+	if !c.options.sortedInput {
+		sortIndexByCreatedAt(batch, index)
+	} else if i := view.firstUnsorted(); i >= 0 {
+		if c.options.strictSortedInput {
+			return false, fmt.Errorf("transaction %d: %w", i, ErrUnsortedTransactions)
+		}
+		sortIndexByCreatedAt(batch, index)
+	}
+
+	violated, _ := c.scanView(ctx, view, true)
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	return violated, nil
+}
+
+// CheckUserDetailed orders a single user's transactions and returns one violation per violated period
+func (c velocityChecker) CheckUserDetailed(txs []Transaction) ([]VelocityViolation, error) {
+	if err := c.order(txs); err != nil {
+		return nil, err
+	}
+
+	_, violations := c.scan(txs, false)
+	return violations, nil
+}
+
+// order sorts txs by CreatedAt, or only validates the ordering when the input is declared sorted
+func (c velocityChecker) order(txs []Transaction) error {
+	if !c.options.sortedInput {
+		sortByCreatedAt(txs)
+		return nil
+	}
+
+	if i := (transactionView{batch: txs}).firstUnsorted(); i >= 0 {
+		if c.options.strictSortedInput {
+			return fmt.Errorf("transaction %d: %w", i, ErrUnsortedTransactions)
+		}
+		sortByCreatedAt(txs)
+	}
+
+	return nil
+}
+
+// transactionView is a user's transactions in batch at the positions index lists, or batch itself
+// when index is nil
+type transactionView struct {
+	batch []Transaction
+	index []int32
+}
+
+func (v transactionView) len() int {
+	if v.index == nil {
+		return len(v.batch)
+	}
+
+	return len(v.index)
+}
+
+func (v transactionView) createdAt(i int) time.Time {
+	if v.index == nil {
+		return v.batch[i].CreatedAt
+	}
+
+	return v.batch[v.index[i]].CreatedAt
+}
+
+// firstUnsorted returns the position of the first transaction created before its predecessor, or -1
+func (v transactionView) firstUnsorted() int {
+	for i := 1; i < v.len(); i++ {
+		if v.createdAt(i).Before(v.createdAt(i - 1)) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// scan is scanView over txs, never cancelled
+func (c velocityChecker) scan(txs []Transaction, stopAtFirst bool) (bool, []VelocityViolation) {
+	return c.scanView(context.Background(), transactionView{batch: txs}, stopAtFirst)
+}
+
+// scanCancelInterval is how many transactions scanView walks between two checks of its context
+const scanCancelInterval = 1 << 14
+
+// scanView walks txs once, keeping one sliding-window left pointer per period, and returns the first
+// violation of each violated period in configuration order. With stopAtFirst it only reports whether
+// any period is violated, returning as soon as one is. Periods are visited tightest first, and each
+// drops out once the transactions left can no longer fill a window above its threshold, before the
+// walk for a threshold above the user's transaction count. The scan stops when none remains, or
+// reporting no violation once ctx is done, which it checks every scanCancelInterval transactions.
+func (c velocityChecker) scanView(ctx context.Context, txs transactionView, stopAtFirst bool) (bool, []VelocityViolation) {
+	// a checker built as a struct literal scans in configuration order
+	periods := c.scanPeriods
+	if periods == nil {
+		periods = c.periods
+	}
+
+	var leftBuf, stateBuf [8]int
+	left, state := leftBuf[:0], stateBuf[:0]
+	active := 0
+	for _, period := range periods {
+		left = append(left, 0)
+		if txs.len() <= period.Threshold {
+			state = append(state, periodExhausted)
+			continue
+		}
+		state = append(state, periodActive)
+		active++
+	}
+
+	for right := 0; right < txs.len() && active > 0; right++ {
+		if right%scanCancelInterval == scanCancelInterval-1 && ctx.Err() != nil {
+			return false, nil
+		}
+		for p, period := range periods {
+			if state[p] != periodActive {
+				continue
+			}
+
+			for left[p] <= right && txs.createdAt(right).Sub(txs.createdAt(left[p])) > period.Duration {
+				left[p]++
+			}
+
+			windowSize := right - left[p] + 1
+			switch {
+			case windowSize > period.Threshold:
+				if stopAtFirst {
+					return true, nil
+				}
+				state[p] = right
+				active--
+			case txs.len()-left[p] <= period.Threshold:
+				// even every remaining transaction in one window would not exceed the threshold
+				state[p] = periodExhausted
+				active--
+			}
+		}
+	}
+
+	var violations []VelocityViolation
+	for i := range periods {
+		p := i
+		if c.scanPositions != nil {
+			p = c.scanPositions[i]
+		}
+		if state[p] >= 0 {
+			violations = append(violations, newVelocityViolation(txs, periods[p], left[p], state[p]))
+		}
+	}
+
+	return len(violations) > 0, violations
+}
+
+// scan period states: active, exhausted, or the right index of the period's first violation
+const (
+	periodActive    = -1
+	periodExhausted = -2
+)
+
+func newVelocityViolation(txs transactionView, period VelocityPeriod, left, right int) VelocityViolation {
+	return VelocityViolation{
+		PeriodName:  period.Label(),
+		Period:      period,
+		Count:       right - left + 1,
+		WindowStart: txs.createdAt(left),
+		WindowEnd:   txs.createdAt(right),
+	}
+}
+
+// checkPeriod uses sliding window to check if a specific period has velocity violations. It is the
+// per-period reference implementation scan is verified against.
+// Time complexity: O(n) where n is the number of transactions for a user
+func (c velocityChecker) checkPeriod(txs []Transaction, period VelocityPeriod) (VelocityViolation, bool) {
+	left := 0
+
+	for right := 0; right < len(txs); right++ {
+		for left <= right && txs[right].CreatedAt.Sub(txs[left].CreatedAt) > period.Duration {
+			left++
+		}
+
+		windowSize := right - left + 1
+
+		if windowSize > period.Threshold {
+			return VelocityViolation{
+				PeriodName:  period.Label(),
+				Period:      period,
+				Count:       windowSize,
+				WindowStart: txs[left].CreatedAt,
+				WindowEnd:   txs[right].CreatedAt,
+			}, true
+		}
+	}
+
+	return VelocityViolation{}, false
+}
+
+// sortByCreatedAt does not need to be stable: transactions sharing a timestamp are
+// interchangeable for window counting, which only compares CreatedAt values. Slices of at least
+// parallelSortThreshold transactions are sorted by sortLargeByCreatedAt.
+func sortByCreatedAt(txs []Transaction) {
+	if len(txs) >= parallelSortThreshold {
+		sortLargeByCreatedAt(txs, runtime.GOMAXPROCS(0))
+		return
+	}
+
+	slices.SortFunc(txs, func(a, b Transaction) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+}
+
+// sortIndexByCreatedAt sorts index, positions into batch, by the CreatedAt of their transaction.
+// Positions sharing a timestamp are ordered by value, so an index listing a user's transactions in
+// batch order keeps them in that order. Indexes of at least parallelSortThreshold positions are
+// sorted by sortLargeIndexByCreatedAt.
+func sortIndexByCreatedAt(batch []Transaction, index []int32) {
+	if len(index) >= parallelSortThreshold {
+		sortLargeIndexByCreatedAt(batch, index, runtime.GOMAXPROCS(0))
+		return
+	}
+
+	slices.SortFunc(index, func(a, b int32) int {
+		if c := batch[a].CreatedAt.Compare(batch[b].CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	})
+}
+
+// exceedsWindowAmount slides a window over sorted txs and reports whether the amounts within any
+// window, each rounded by amounts, sum above threshold. The window boundary is inclusive, as for
+// transaction counts.
+// Time complexity: O(n) where n is the number of transactions for a user
+func exceedsWindowAmount(txs []Transaction, window time.Duration, threshold decimal.Decimal, amounts AmountPolicy) bool {
+	left := 0
+	sum := decimal.Zero
+
+	for right := 0; right < len(txs); right++ {
+		sum = sum.Add(amounts.spend(txs[right]))
+
+		for left <= right && txs[right].CreatedAt.Sub(txs[left].CreatedAt) > window {
+			sum = sum.Sub(amounts.spend(txs[left]))
+			left++
+		}
+
+		if sum.GreaterThan(threshold) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// velocityImplementations returns every velocity processor built with the same periods
+func velocityImplementations(periods []VelocityPeriod) map[string]RuleProcessor {
+	return map[string]RuleProcessor{
+		"sequential":  NewVelocityValidator(periods),
+		"worker_pool": NewWorkerVelocityProcessor(periods, 4),
+		"fan_out_in":  NewConcurrentVelocityProcessor(periods, 4),
+	}
+}
+
+func TestVelocityProcessors_Conformance(t *testing.T) {
+	baseTime := time.Now()
+	userID1 := uuid.New()
+	userID2 := uuid.New()
+
+	tests := []struct {
+		name         string
+		periods      []VelocityPeriod
+		transactions []Transaction
+		wantUsers    []uuid.UUID
+	}{
+		{
+			name:    "no violations",
+			periods: []VelocityPeriod{NewVelocityPeriod(week, 5)},
+			transactions: []Transaction{
+				{UserID: userID1, CreatedAt: baseTime},
+				{UserID: userID2, CreatedAt: baseTime.Add(time.Hour)},
+			},
+			wantUsers: []uuid.UUID{},
+		},
+		{
+			name:    "weekly violation",
+			periods: []VelocityPeriod{NewVelocityPeriod(week, 2), NewVelocityPeriod(month, 10)},
+			transactions: []Transaction{
+				{UserID: userID1, CreatedAt: baseTime},
+				{UserID: userID1, CreatedAt: baseTime.Add(time.Hour)},
+				{UserID: userID1, CreatedAt: baseTime.Add(2 * time.Hour)},
+				{UserID: userID2, CreatedAt: baseTime},
+			},
+			wantUsers: []uuid.UUID{userID1},
+		},
+		{
+			name:    "window boundary is inclusive",
+			periods: []VelocityPeriod{NewVelocityPeriod(week, 1)},
+			transactions: []Transaction{
+				{UserID: userID1, CreatedAt: baseTime},
+				{UserID: userID1, CreatedAt: baseTime.Add(week)},
+				{UserID: userID2, CreatedAt: baseTime},
+				{UserID: userID2, CreatedAt: baseTime.Add(week + time.Nanosecond)},
+			},
+			wantUsers: []uuid.UUID{userID1},
+		},
+		{
+			name:    "unsorted transactions",
+			periods: []VelocityPeriod{NewVelocityPeriod(week, 2)},
+			transactions: []Transaction{
+				{UserID: userID2, CreatedAt: baseTime.Add(3 * time.Hour)},
+				{UserID: userID2, CreatedAt: baseTime.Add(30 * 24 * time.Hour)},
+				{UserID: userID2, CreatedAt: baseTime},
+				{UserID: userID2, CreatedAt: baseTime.Add(time.Hour)},
+			},
+			wantUsers: []uuid.UUID{userID2},
+		},
+		{
+			name:         "empty transactions",
+			periods:      []VelocityPeriod{NewVelocityPeriod(week, 1)},
+			transactions: []Transaction{},
+			wantUsers:    []uuid.UUID{},
+		},
+	}
+
+	for _, tt := range tests {
+		for name, processor := range velocityImplementations(tt.periods) {
+			t.Run(fmt.Sprintf("%s/%s", tt.name, name), func(t *testing.T) {
+				transactions := append([]Transaction(nil), tt.transactions...)
+				flaggedUsers := processor.Process(context.Background(), transactions)
+
+				assert.Equal(t, len(tt.wantUsers), len(flaggedUsers))
+				for _, wantUser := range tt.wantUsers {
+					assert.Contains(t, flaggedUsers, wantUser)
+				}
+			})
+		}
+	}
+}
+
+func TestVelocityProcessors_Conformance_Randomized(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for round := 0; round < 20; round++ {
+		periods := []VelocityPeriod{
+			NewVelocityPeriod(time.Duration(1+rng.Intn(48))*time.Hour, 1+rng.Intn(5)),
+			NewVelocityPeriod(week, 3+rng.Intn(10)),
+		}
+
+		users := make([]uuid.UUID, 1+rng.Intn(50))
+		for i := range users {
+			users[i] = uuid.New()
+		}
+
+		transactions := make([]Transaction, rng.Intn(2000))
+		for i := range transactions {
+			transactions[i] = Transaction{
+				UserID:    users[rng.Intn(len(users))],
+				Amount:    decimal.NewFromInt(int64(rng.Intn(10000))),
+				CreatedAt: baseTime.Add(time.Duration(rng.Int63n(int64(month)))),
+			}
+		}
+
+		want := NewVelocityValidator(periods).Process(context.Background(), append([]Transaction(nil), transactions...))
+		for name, processor := range velocityImplementations(periods) {
+			got := processor.Process(context.Background(), append([]Transaction(nil), transactions...))
+			assert.Equal(t, want, got, "round %d: %s diverged from sequential", round, name)
+		}
+	}
+}
+
+func TestVelocityChecker_CheckUserDetailed(t *testing.T) {
+	baseTime := time.Now()
+	checker := newVelocityChecker([]VelocityPeriod{
+		NewVelocityPeriod(week, 2),
+		NewVelocityPeriod(month, 10),
+	}, velocityOptions{})
+
+	txs := []Transaction{
+		{CreatedAt: baseTime.Add(2 * time.Hour)},
+		{CreatedAt: baseTime},
+		{CreatedAt: baseTime.Add(time.Hour)},
+	}
+
+	violations, err := checker.CheckUserDetailed(txs)
+
+	assert.NoError(t, err)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, NewVelocityPeriod(week, 2), violations[0].Period)
+	assert.Equal(t, "168h-2tx", violations[0].PeriodName)
+	assert.Equal(t, 3, violations[0].Count)
+	assert.True(t, violations[0].WindowStart.Equal(baseTime))
+	assert.True(t, violations[0].WindowEnd.Equal(baseTime.Add(2*time.Hour)))
+	violated, err := checker.CheckUser(txs)
+	assert.NoError(t, err)
+	assert.Equal(t, len(violations) > 0, violated)
+}
+
+func TestVelocityProcessors_SortedInput(t *testing.T) {
+	baseTime := time.Now()
+	userID1 := uuid.New()
+	userID2 := uuid.New()
+	periods := []VelocityPeriod{NewVelocityPeriod(week, 2)}
+
+	// userID1 is out of order: evaluated unsorted it would be falsely flagged, sorted it spans ten days
+	transactions := []Transaction{
+		{UserID: userID1, CreatedAt: baseTime.Add(10 * 24 * time.Hour)},
+		{UserID: userID2, CreatedAt: baseTime},
+		{UserID: userID1, CreatedAt: baseTime},
+		{UserID: userID1, CreatedAt: baseTime.Add(9 * 24 * time.Hour)},
+	}
+
+	type contextProcessor interface {
+		ProcessContext(context.Context, []Transaction) (map[uuid.UUID]struct{}, error)
+	}
+
+	newProcessors := func(opts ...VelocityOption) map[string]contextProcessor {
+		return map[string]contextProcessor{
+			"sequential":  NewVelocityValidator(periods, opts...),
+			"worker_pool": NewWorkerVelocityProcessor(periods, 4, opts...),
+			"fan_out_in":  NewConcurrentVelocityProcessor(periods, 4, opts...),
+		}
+	}
+
+	for name, processor := range newProcessors(WithSortedInput()) {
+		t.Run("fallback/"+name, func(t *testing.T) {
+			flaggedUsers, err := processor.ProcessContext(context.Background(), append([]Transaction(nil), transactions...))
+
+			assert.NoError(t, err)
+			assert.Equal(t, map[uuid.UUID]struct{}{}, flaggedUsers)
+		})
+	}
+
+	for name, processor := range newProcessors(WithStrictSortedInput()) {
+		t.Run("strict/"+name, func(t *testing.T) {
+			flaggedUsers, err := processor.ProcessContext(context.Background(), append([]Transaction(nil), transactions...))
+
+			assert.ErrorIs(t, err, ErrUnsortedTransactions)
+			assert.Empty(t, flaggedUsers)
+		})
+	}
+}
+
+func TestVelocityChecker_Scan_MatchesPerPeriod(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for round := 0; round < 500; round++ {
+		periods := make([]VelocityPeriod, 1+rng.Intn(6))
+		for i := range periods {
+			periods[i] = NewVelocityPeriod(time.Duration(1+rng.Intn(96))*time.Hour, rng.Intn(8))
+		}
+		checker := newVelocityChecker(periods, velocityOptions{})
+
+		txs := make([]Transaction, rng.Intn(60))
+		for i := range txs {
+			txs[i] = Transaction{CreatedAt: baseTime.Add(time.Duration(rng.Int63n(int64(2 * week))))}
+		}
+		sortByCreatedAt(txs)
+
+		var want []VelocityViolation
+		for _, period := range periods {
+			if violation, violated := checker.checkPeriod(txs, period); violated {
+				want = append(want, violation)
+			}
+		}
+
+		violated, violations := checker.scan(txs, false)
+		assert.Equal(t, want, violations, "round %d", round)
+		assert.Equal(t, len(want) > 0, violated, "round %d", round)
+
+		violated, _ = checker.scan(txs, true)
+		assert.Equal(t, len(want) > 0, violated, "round %d", round)
+	}
+}
+
+func TestVelocityChecker_CheckIndexed(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	periods := []VelocityPeriod{NewVelocityPeriod(time.Hour, 3), NewVelocityPeriod(24*time.Hour, 10)}
+
+	for round := 0; round < 200; round++ {
+		batch := make([]Transaction, rng.Intn(80))
+		for i := range batch {
+			batch[i] = Transaction{TransactionID: uuid.New(), CreatedAt: baseTime.Add(time.Duration(rng.Int63n(int64(4 * 24 * time.Hour))))}
+		}
+		input := append([]Transaction(nil), batch...)
+
+		var index []int32
+		var txs []Transaction
+		for i := range batch {
+			if rng.Intn(2) == 0 {
+				index = append(index, int32(i))
+				txs = append(txs, batch[i])
+			}
+		}
+
+		checker := newVelocityChecker(periods, velocityOptions{})
+		want, err := checker.CheckUser(txs)
+		require.NoError(t, err)
+		got, err := checker.CheckIndexed(context.Background(), batch, index)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "round %d", round)
+		assert.Equal(t, input, batch, "round %d: the batch is not reordered", round)
+		assert.True(t, slices.IsSortedFunc(index, func(a, b int32) int { return batch[a].CreatedAt.Compare(batch[b].CreatedAt) }), "round %d", round)
+	}
+
+	strict := newVelocityChecker(periods, velocityOptions{sortedInput: true, strictSortedInput: true})
+	batch := []Transaction{{CreatedAt: baseTime.Add(time.Hour)}, {CreatedAt: baseTime}}
+	_, err := strict.CheckIndexed(context.Background(), batch, []int32{0, 1})
+	assert.ErrorIs(t, err, ErrUnsortedTransactions)
+}
+
+func TestTightnessOrder(t *testing.T) {
+	periods := []VelocityPeriod{
+		NewVelocityPeriod(year, 1000),
+		NewVelocityPeriod(week, 100),
+		NewVelocityPeriod(0, 2),
+		NewVelocityPeriod(hour, 30),
+		NewVelocityPeriod(24*time.Hour, 24),
+	}
+	// per hour: 0.11, 0.6, none, 30, 1
+	assert.Nil(t, tightnessOrder(periods[:2]), "periods in order need no reordering")
+	assert.Nil(t, tightnessOrder(periods[:1]))
+	assert.Equal(t, []int{0, 1, 4, 3, 2}, tightnessOrder(periods))
+
+	checker := newVelocityChecker(periods, velocityOptions{})
+	assert.Equal(t, []VelocityPeriod{periods[0], periods[1], periods[4], periods[3], periods[2]}, checker.scanPeriods)
+	assert.Equal(t, []int{0, 1, 4, 3, 2}, checker.scanPositions)
+}
+
+func TestVelocityChecker_Scan_TightnessOrderAgrees(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for round := 0; round < 500; round++ {
+		periods := make([]VelocityPeriod, 2+rng.Intn(5))
+		for i := range periods {
+			periods[i] = NewVelocityPeriod(time.Duration(1+rng.Intn(96))*time.Hour, rng.Intn(12))
+		}
+		// loosest first, as the configurations tightness ordering helps most
+		slices.SortFunc(periods, func(a, b VelocityPeriod) int { return cmp.Compare(b.tightness(), a.tightness()) })
+		configurationOrder := velocityChecker{periods: periods}
+		checker := newVelocityChecker(periods, velocityOptions{})
+
+		txs := make([]Transaction, rng.Intn(60))
+		for i := range txs {
+			txs[i] = Transaction{CreatedAt: baseTime.Add(time.Duration(rng.Int63n(int64(2 * week))))}
+		}
+		sortByCreatedAt(txs)
+
+		wantViolated, want := configurationOrder.scan(txs, false)
+		violated, violations := checker.scan(txs, false)
+		assert.Equal(t, want, violations, "round %d", round)
+		assert.Equal(t, wantViolated, violated, "round %d", round)
+
+		wantViolated, _ = configurationOrder.scan(txs, true)
+		violated, _ = checker.scan(txs, true)
+		assert.Equal(t, wantViolated, violated, "round %d", round)
+	}
+}
+
+// BenchmarkVelocityChecker_MostlyClean checks users of whom few violate against loosest-first periods,
+// in configuration order and tightest first with the short-circuits
+func BenchmarkVelocityChecker_MostlyClean(b *testing.B) {
+	periods := []VelocityPeriod{
+		NewVelocityPeriod(year, 1000),
+		NewVelocityPeriod(month, 200),
+		NewVelocityPeriod(week, 100),
+		NewVelocityPeriod(24*time.Hour, 20),
+		NewVelocityPeriod(hour, 6),
+	}
+	generated := NewTransactionGenerator(
+		WithSeed(5),
+		WithUserCount(5000),
+		WithTransactionsPerUser(PoissonCount(8)),
+		WithSpacing(PoissonSpacing(6*time.Hour)),
+		WithVelocityViolators(10, periods[4]),
+	).Generate()
+	grouped := GroupByUser(generated.Transactions)
+	users := make([][]Transaction, 0, len(grouped.Users()))
+	for _, userID := range grouped.Users() {
+		users = append(users, grouped.SortedForUser(userID))
+	}
+
+	for _, bm := range []struct {
+		name    string
+		checker velocityChecker
+	}{
+		{name: "ConfigurationOrder", checker: velocityChecker{periods: periods}},
+		{name: "TightestFirst", checker: newVelocityChecker(periods, velocityOptions{})},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				for _, txs := range users {
+					bm.checker.scan(txs, true)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVelocityChecker_SixPeriods(b *testing.B) {
+	periods := []VelocityPeriod{
+		NewVelocityPeriod(minute, 10),
+		NewVelocityPeriod(hour, 30),
+		NewVelocityPeriod(24*time.Hour, 60),
+		NewVelocityPeriod(week, 100),
+		NewVelocityPeriod(month, 200),
+		NewVelocityPeriod(year, 1000),
+	}
+	checker := newVelocityChecker(periods, velocityOptions{})
+
+	txs := NewTransactionGenerator(WithUserCount(1), WithTransactionsPerUser(FixedCount(500))).Generate().Transactions
+
+	b.Run("PerPeriod", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, period := range periods {
+				if _, violated := checker.checkPeriod(txs, period); violated {
+					break
+				}
+			}
+		}
+	})
+
+	b.Run("SinglePass", func(b *testing.B) {
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			checker.scan(txs, true)
+		}
+	})
+}
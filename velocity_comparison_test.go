@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/metrics"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// velocityReport writes the comparison of the velocity implementations to the given file, as CSV
+// when it ends in .csv and as a markdown table otherwise, e.g.
+//
+//	go test -run TestVelocityComparisonReport -velocity-report=velocity.md
+var velocityReport = flag.String("velocity-report", "", "write the velocity implementation comparison to this file")
+
+// velocityComparisonSeed seeds every shape, so the compared batches are the same from run to run
+const velocityComparisonSeed = 42
+
+var velocityComparisonPeriods = []VelocityPeriod{
+	NewVelocityPeriod(24*time.Hour, 10),
+	NewVelocityPeriod(week, 40),
+	NewVelocityPeriod(month, 150),
+}
+
+// velocityShape is a batch layout the implementations are compared on
+type velocityShape struct {
+	name string
+	opts []GeneratorOption
+}
+
+// skewedCount gives one user in a hundred heavy transactions and the others light ones
+func skewedCount(light, heavy int) CountDistribution {
+	return func(rng *rand.Rand) int {
+		if rng.IntN(100) == 0 {
+			return heavy
+		}
+		return light
+	}
+}
+
+// velocityShapes is the grid of layouts, scaled down by scale, 1 being the full benchmark size
+func velocityShapes(scale int) []velocityShape {
+	violators := WithVelocityViolators(20/scale, velocityComparisonPeriods[0])
+	return []velocityShape{
+		{name: "FewUsersManyTransactions", opts: []GeneratorOption{
+			WithUserCount(20 / scale), WithTransactionsPerUser(FixedCount(5000)), WithSpacing(PoissonSpacing(2 * time.Hour)), violators,
+		}},
+		{name: "ManyUsersFewTransactions", opts: []GeneratorOption{
+			WithUserCount(50000 / scale), WithTransactionsPerUser(FixedCount(2)), WithSpacing(PoissonSpacing(24 * time.Hour)), violators,
+		}},
+		{name: "Skewed", opts: []GeneratorOption{
+			WithUserCount(5000 / scale), WithTransactionsPerUser(skewedCount(5, 2000)), WithSpacing(PoissonSpacing(3 * time.Hour)), violators,
+		}},
+		{name: "SkewedTimeOrdered", opts: []GeneratorOption{
+			WithUserCount(5000 / scale), WithTransactionsPerUser(skewedCount(5, 2000)), WithSpacing(PoissonSpacing(3 * time.Hour)), violators, WithTimeOrder(),
+		}},
+	}
+}
+
+func (s velocityShape) generate() []Transaction {
+	opts := append([]GeneratorOption{WithSeed(velocityComparisonSeed)}, s.opts...)
+	return NewTransactionGenerator(opts...).Generate().Transactions
+}
+
+// velocityImplementation is one of the compared processors
+type velocityImplementation struct {
+	name      string
+	processor RuleProcessor
+}
+
+var velocityComparisonWorkers = []int{1, 4, 16}
+
+func velocityComparisonImplementations() []velocityImplementation {
+	implementations := []velocityImplementation{
+		{name: "VelocityProcessor", processor: NewVelocityValidator(velocityComparisonPeriods)},
+	}
+	for _, workers := range velocityComparisonWorkers {
+		implementations = append(implementations,
+			velocityImplementation{name: fmt.Sprintf("WorkerVelocityProcessor/workers=%d", workers), processor: NewWorkerVelocityProcessor(velocityComparisonPeriods, workers)},
+			velocityImplementation{name: fmt.Sprintf("ConcurrentVelocityProcessor/workers=%d", workers), processor: NewConcurrentVelocityProcessor(velocityComparisonPeriods, workers)},
+		)
+	}
+
+	return implementations
+}
+
+func TestVelocityImplementations_Agree(t *testing.T) {
+	for _, shape := range velocityShapes(10) {
+		t.Run(shape.name, func(t *testing.T) {
+			transactions := shape.generate()
+			want := NewVelocityValidator(velocityComparisonPeriods).Process(context.Background(), append([]Transaction(nil), transactions...))
+			require.NotEmpty(t, want)
+
+			for _, implementation := range velocityComparisonImplementations() {
+				got := implementation.processor.Process(context.Background(), append([]Transaction(nil), transactions...))
+				assert.Equal(t, want, got, implementation.name)
+			}
+		})
+	}
+}
+
+func BenchmarkVelocityImplementations(b *testing.B) {
+	for _, shape := range velocityShapes(1) {
+		transactions := shape.generate()
+		for _, implementation := range velocityComparisonImplementations() {
+			b.Run(shape.name+"/"+implementation.name, func(b *testing.B) {
+				benchmarkVelocityImplementation(b, implementation.processor, transactions)
+			})
+		}
+	}
+}
+
+func benchmarkVelocityImplementation(b *testing.B, processor RuleProcessor, transactions []Transaction) {
+	b.ReportAllocs()
+	peak := newHeapPeakSampler()
+	for b.Loop() {
+		processor.Process(context.Background(), transactions)
+	}
+	b.ReportMetric(float64(peak.stop()), "peak-heap-B")
+}
+
+// heapPeakSampler polls the live heap until stopped, reporting how far it grew above where it started
+type heapPeakSampler struct {
+	done chan struct{}
+	wg   sync.WaitGroup
+	peak uint64
+}
+
+func newHeapPeakSampler() *heapPeakSampler {
+	runtime.GC()
+	s := &heapPeakSampler{done: make(chan struct{})}
+	base := liveHeap()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(100 * time.Microsecond)
+		defer ticker.Stop()
+		for {
+			if heap := liveHeap(); heap > base {
+				s.peak = max(s.peak, heap-base)
+			}
+			select {
+			case <-s.done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	return s
+}
+
+func (s *heapPeakSampler) stop() uint64 {
+	close(s.done)
+	s.wg.Wait()
+	return s.peak
+}
+
+func liveHeap() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
+
+// velocityComparisonRow is one implementation's measurements on one shape
+type velocityComparisonRow struct {
+	shape          string
+	implementation string
+	transactions   int
+	nsPerOp        int64
+	allocsPerOp    int64
+	bytesPerOp     int64
+	peakHeap       int64
+}
+
+func TestVelocityComparisonReport(t *testing.T) {
+	if *velocityReport == "" {
+		t.Skip("run with -velocity-report=<file> to write the comparison")
+	}
+
+	var rows []velocityComparisonRow
+	for _, shape := range velocityShapes(1) {
+		transactions := shape.generate()
+		for _, implementation := range velocityComparisonImplementations() {
+			result := testing.Benchmark(func(b *testing.B) {
+				benchmarkVelocityImplementation(b, implementation.processor, transactions)
+			})
+			rows = append(rows, velocityComparisonRow{
+				shape:          shape.name,
+				implementation: implementation.name,
+				transactions:   len(transactions),
+				nsPerOp:        result.NsPerOp(),
+				allocsPerOp:    result.AllocsPerOp(),
+				bytesPerOp:     result.AllocedBytesPerOp(),
+				peakHeap:       int64(result.Extra["peak-heap-B"]),
+			})
+		}
+	}
+
+	f, err := os.Create(*velocityReport)
+	require.NoError(t, err)
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(*velocityReport), ".csv") {
+		require.NoError(t, writeVelocityComparisonCSV(f, rows))
+	} else {
+		require.NoError(t, writeVelocityComparisonMarkdown(f, rows))
+	}
+}
+
+func writeVelocityComparisonCSV(w io.Writer, rows []velocityComparisonRow) error {
+	if _, err := fmt.Fprintln(w, "shape,implementation,transactions,ns_per_op,allocs_per_op,bytes_per_op,peak_heap_bytes"); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(w, "%s,%s,%d,%d,%d,%d,%d\n", row.shape, row.implementation, row.transactions, row.nsPerOp, row.allocsPerOp, row.bytesPerOp, row.peakHeap); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeVelocityComparisonMarkdown(w io.Writer, rows []velocityComparisonRow) error {
+	fmt.Fprintf(w, "# Velocity implementations\n\nSeed %d, GOMAXPROCS %d, %s/%s, %s.\n\n", velocityComparisonSeed, runtime.GOMAXPROCS(0), runtime.GOOS, runtime.GOARCH, runtime.Version())
+	fmt.Fprintln(w, "| Shape | Implementation | Transactions | ns/op | allocs/op | B/op | Peak heap (B) |")
+	fmt.Fprintln(w, "|---|---|---:|---:|---:|---:|---:|")
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(w, "| %s | %s | %d | %d | %d | %d | %d |\n", row.shape, row.implementation, row.transactions, row.nsPerOp, row.allocsPerOp, row.bytesPerOp, row.peakHeap); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
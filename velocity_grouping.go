@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// indexBufferPool recycles the backing slices of index lists across Process calls
+var indexBufferPool = sync.Pool{
+	New: func() any { return new([]int32) },
+}
+
+// getIndexBuffer returns a pooled buffer with room for at least n positions
+func getIndexBuffer(n int) *[]int32 {
+	buf := indexBufferPool.Get().(*[]int32)
+	if cap(*buf) < n {
+		*buf = make([]int32, n)
+	}
+	*buf = (*buf)[:n]
+
+	return buf
+}
+
+// putIndexBuffer returns buf to the pool. Callers must not touch any list built on buf afterwards.
+func putIndexBuffer(buf *[]int32) {
+	indexBufferPool.Put(buf)
+}
+
+// groupTransactions groups transactions by the configured key. Without sharding it returns a single map
+// whose groups are carved out of backing (allocated when nil, else at least len(transactions) long);
+// with WithShardedGrouping(k) it returns k maps, each owning a disjoint set of keys, built in parallel.
+func groupTransactions(transactions []Transaction, options velocityOptions, backing []Transaction) []map[uuid.UUID][]Transaction {
+	if options.groupingShards <= 1 {
+		if backing == nil {
+			backing = make([]Transaction, len(transactions))
+		}
+
+		return []map[uuid.UUID][]Transaction{groupContiguous(transactions, options, backing)}
+	}
+
+	shardCount := options.groupingShards
+	buckets := bucketByShard(transactions, options, shardCount)
+
+	// Phase 2: each goroutine builds the map of one shard, visiting chunks in input order
+	shards := make([]map[uuid.UUID][]Transaction, shardCount)
+	var wg sync.WaitGroup
+	for s := 0; s < shardCount; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+
+			userTransactions := make(map[uuid.UUID][]Transaction)
+			for c := 0; c < shardCount; c++ {
+				for _, i := range buckets[c][s] {
+					key := options.key(transactions[i])
+					userTransactions[key] = append(userTransactions[key], transactions[i])
+				}
+			}
+			shards[s] = userTransactions
+		}(s)
+	}
+	wg.Wait()
+
+	return shards
+}
+
+// groupIndices is groupTransactions listing the positions of each group's transactions in transactions
+// instead of copying them, without sharding into lists carved out of backing. Batches must hold
+// fewer than 2^31 transactions. Without sharding, grouping stops once ctx is done, which it checks
+// every scanCancelInterval transactions, returning no group.
+func groupIndices(ctx context.Context, transactions []Transaction, options velocityOptions, backing []int32) []map[uuid.UUID][]int32 {
+	if options.groupingShards <= 1 {
+		if backing == nil {
+			backing = make([]int32, len(transactions))
+		}
+
+		indices := groupContiguousIndices(ctx, transactions, options, backing)
+		if indices == nil {
+			return nil
+		}
+		return []map[uuid.UUID][]int32{indices}
+	}
+
+	shardCount := options.groupingShards
+	buckets := bucketByShard(transactions, options, shardCount)
+
+	shards := make([]map[uuid.UUID][]int32, shardCount)
+	var wg sync.WaitGroup
+	for s := 0; s < shardCount; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+
+			indices := make(map[uuid.UUID][]int32)
+			for c := 0; c < shardCount; c++ {
+				for _, i := range buckets[c][s] {
+					key := options.key(transactions[i])
+					indices[key] = append(indices[key], int32(i))
+				}
+			}
+			shards[s] = indices
+		}(s)
+	}
+	wg.Wait()
+
+	return shards
+}
+
+// bucketByShard splits transactions in shardCount chunks, each goroutine splitting its chunk into
+// per-shard buckets of the positions of the transactions kept
+func bucketByShard(transactions []Transaction, options velocityOptions, shardCount int) [][][]int {
+	chunkSize := (len(transactions) + shardCount - 1) / shardCount
+
+	buckets := make([][][]int, shardCount)
+	var wg sync.WaitGroup
+	for c := 0; c < shardCount; c++ {
+		wg.Add(1)
+		go func(c int) {
+			defer wg.Done()
+
+			buckets[c] = make([][]int, shardCount)
+			start, end := min(c*chunkSize, len(transactions)), min((c+1)*chunkSize, len(transactions))
+			for i := start; i < end; i++ {
+				if !options.keep(transactions[i]) {
+					continue
+				}
+				shard := shardOf(options.key(transactions[i]), shardCount)
+				buckets[c][shard] = append(buckets[c][shard], i)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	return buckets
+}
+
+// groupContiguousIndices is groupContiguous for positions, returning nil once ctx is done
+func groupContiguousIndices(ctx context.Context, transactions []Transaction, options velocityOptions, backing []int32) map[uuid.UUID][]int32 {
+	counts := make(map[uuid.UUID]int, len(transactions)/16)
+	for i, tx := range transactions {
+		if i%scanCancelInterval == scanCancelInterval-1 && ctx.Err() != nil {
+			return nil
+		}
+		if options.keep(tx) {
+			counts[options.key(tx)]++
+		}
+	}
+
+	indices := make(map[uuid.UUID][]int32, len(counts))
+	offset := 0
+	for key, count := range counts {
+		indices[key] = backing[offset : offset : offset+count]
+		offset += count
+	}
+
+	for i, tx := range transactions {
+		if i%scanCancelInterval == scanCancelInterval-1 && ctx.Err() != nil {
+			return nil
+		}
+		if !options.keep(tx) {
+			continue
+		}
+		key := options.key(tx)
+		indices[key] = append(indices[key], int32(i))
+	}
+
+	return indices
+}
+
+// groupContiguous counts transactions per key first, so every group is a capacity-limited
+// subslice of backing instead of a slice grown by repeated appends
+func groupContiguous(transactions []Transaction, options velocityOptions, backing []Transaction) map[uuid.UUID][]Transaction {
+	// Heuristic: batches rarely average fewer than 16 transactions per key
+	counts := make(map[uuid.UUID]int, len(transactions)/16)
+	for _, tx := range transactions {
+		if options.keep(tx) {
+			counts[options.key(tx)]++
+		}
+	}
+
+	userTransactions := make(map[uuid.UUID][]Transaction, len(counts))
+	offset := 0
+	for key, count := range counts {
+		userTransactions[key] = backing[offset:offset:offset+count]
+		offset += count
+	}
+
+	for _, tx := range transactions {
+		if !options.keep(tx) {
+			continue
+		}
+		key := options.key(tx)
+		userTransactions[key] = append(userTransactions[key], tx)
+	}
+
+	return userTransactions
+}
+
+func shardOf(key uuid.UUID, shardCount int) int {
+	return int(binary.BigEndian.Uint64(key[8:]) % uint64(shardCount))
+}
+
+func groupCount(shards []map[uuid.UUID][]Transaction) int {
+	count := 0
+	for _, shard := range shards {
+		count += len(shard)
+	}
+
+	return count
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroupTransactions_Sharded(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	users := make([]uuid.UUID, 200)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+
+	transactions := make([]Transaction, 5000)
+	for i := range transactions {
+		transactions[i] = Transaction{
+			UserID:    users[rng.Intn(len(users))],
+			CreatedAt: baseTime.Add(time.Duration(rng.Int63n(int64(month)))),
+		}
+	}
+
+	sequential := groupTransactions(transactions, velocityOptions{}, nil)[0]
+
+	for _, shardCount := range []int{2, 3, 8} {
+		shards := groupTransactions(transactions, newVelocityOptions([]VelocityOption{WithShardedGrouping(shardCount)}), nil)
+
+		assert.Len(t, shards, shardCount)
+		merged := make(map[uuid.UUID][]Transaction)
+		for _, shard := range shards {
+			for userID, txs := range shard {
+				assert.NotContains(t, merged, userID, "user %s present in several shards", userID)
+				merged[userID] = txs
+			}
+		}
+		assert.Equal(t, sequential, merged, "shards=%d", shardCount)
+	}
+
+	periods := []VelocityPeriod{NewVelocityPeriod(24*time.Hour, 3), NewVelocityPeriod(week, 8)}
+	want := NewVelocityValidator(periods).Process(context.Background(), append([]Transaction(nil), transactions...))
+	for name, processor := range map[string]RuleProcessor{
+		"worker_pool": NewWorkerVelocityProcessor(periods, 4, WithShardedGrouping(4)),
+		"fan_out_in":  NewConcurrentVelocityProcessor(periods, 4, WithShardedGrouping(4)),
+	} {
+		got := processor.Process(context.Background(), append([]Transaction(nil), transactions...))
+		assert.Equal(t, want, got, name)
+	}
+}
+
+func TestGroupIndices(t *testing.T) {
+	transactions := NewTransactionGenerator(WithUserCount(100), WithTransactionsPerUser(PoissonCount(20))).Generate().Transactions
+	input := append([]Transaction(nil), transactions...)
+
+	for _, shardCount := range []int{1, 3} {
+		options := newVelocityOptions([]VelocityOption{WithShardedGrouping(shardCount)})
+		want := groupTransactions(transactions, velocityOptions{}, nil)[0]
+
+		got := make(map[uuid.UUID][]Transaction)
+		for _, shard := range groupIndices(context.Background(), transactions, options, nil) {
+			for userID, index := range shard {
+				for _, i := range index {
+					got[userID] = append(got[userID], transactions[i])
+				}
+			}
+		}
+		assert.Equal(t, want, got, "shards=%d", shardCount)
+	}
+	assert.Equal(t, input, transactions, "grouping leaves the batch as it was")
+}
+
+func BenchmarkGroupTransactions(b *testing.B) {
+	// The 1000-user/50-tx benchmark dataset scaled up 20x
+	transactions := NewTransactionGenerator(WithUserCount(20000), WithTransactionsPerUser(FixedCount(50))).Generate().Transactions
+
+	for _, shardCount := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("Shards_%d", shardCount), func(b *testing.B) {
+			options := newVelocityOptions([]VelocityOption{WithShardedGrouping(shardCount)})
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				groupTransactions(transactions, options, nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// VelocityOption configures optional behaviour of the velocity processors
+type VelocityOption func(*velocityOptions)
+
+type velocityOptions struct {
+	presortedByUser   bool
+	sortedInput       bool
+	strictSortedInput bool
+	groupingKey       func(Transaction) uuid.UUID
+	groupingShards    int
+	filter            func(Transaction) bool
+}
+
+func newVelocityOptions(opts []VelocityOption) velocityOptions {
+	var options velocityOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
+
+// key returns the entity a transaction is grouped under, the user unless configured otherwise
+func (o velocityOptions) key(tx Transaction) uuid.UUID {
+	if o.groupingKey != nil {
+		return o.groupingKey(tx)
+	}
+
+	return tx.UserID
+}
+
+// keep reports whether a transaction passes the configured filter, keeping everything by default
+func (o velocityOptions) keep(tx Transaction) bool {
+	return o.filter == nil || o.filter(tx)
+}
+
+// WithPresortedByUser declares that all transactions of a user (or grouping key) are contiguous in the input,
+// so jobs can be emitted per user run without grouping the whole batch in memory first.
+// Callers must guarantee the ordering: a user split across several runs is evaluated per run.
+func WithPresortedByUser() VelocityOption {
+	return func(o *velocityOptions) {
+		o.presortedByUser = true
+	}
+}
+
+// WithSortedInput declares that transactions are already ordered by CreatedAt, so per-user
+// sorting is replaced by an O(n) ordering check. Users whose transactions turn out to be
+// out of order are sorted as usual.
+func WithSortedInput() VelocityOption {
+	return func(o *velocityOptions) {
+		o.sortedInput = true
+	}
+}
+
+// WithStrictSortedInput behaves like WithSortedInput but rejects out-of-order input with
+// ErrUnsortedTransactions instead of falling back to sorting.
+func WithStrictSortedInput() VelocityOption {
+	return func(o *velocityOptions) {
+		o.sortedInput = true
+		o.strictSortedInput = true
+	}
+}
+
+// WithGroupingKey applies the velocity windows per entity returned by key instead of per user,
+// e.g. ByAccount. The flagged set then contains those keys.
+func WithGroupingKey(key func(Transaction) uuid.UUID) VelocityOption {
+	return func(o *velocityOptions) {
+		o.groupingKey = key
+	}
+}
+
+// ByUser groups transactions by UserID, the default grouping
+func ByUser(tx Transaction) uuid.UUID {
+	return tx.UserID
+}
+
+// ByAccount groups transactions by AccountID
+func ByAccount(tx Transaction) uuid.UUID {
+	return tx.AccountID
+}
+
+// WithShardedGrouping hashes grouping keys into k shards that are grouped in parallel
+// and fed to the workers shard by shard, instead of building one map sequentially
+func WithShardedGrouping(k int) VelocityOption {
+	return func(o *velocityOptions) {
+		o.groupingShards = k
+	}
+}
+
+// WithTransactionFilter excludes transactions for which keep returns false before grouping,
+// so they neither count toward any window nor appear in detailed violations. Repeated filters
+// all apply, a transaction being kept only when every one keeps it.
+func WithTransactionFilter(keep func(Transaction) bool) VelocityOption {
+	return func(o *velocityOptions) {
+		if previous := o.filter; previous != nil {
+			o.filter = func(tx Transaction) bool {
+				return previous(tx) && keep(tx)
+			}
+			return
+		}
+		o.filter = keep
+	}
+}
+
+// ExcludeReversals is a transaction filter dropping refunded and reversed transactions, and reversals
+// recorded as negative amounts
+func ExcludeReversals(tx Transaction) bool {
+	return tx.Status != StatusRefunded && tx.Status != StatusReversed && !tx.Amount.IsNegative()
+}
+
+// OnChannels returns a transaction filter keeping only the given channels, e.g. to cap cash
+// deposits separately from overall velocity
+func OnChannels(channels ...Channel) func(Transaction) bool {
+	return func(tx Transaction) bool {
+		return slices.Contains(channels, tx.Channel)
+	}
+}
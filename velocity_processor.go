@@ -2,28 +2,44 @@ package main
 
 import (
 	"context"
-	"sort"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const minute = time.Minute
+const hour = time.Hour
 const year = 365 * 24 * time.Hour
 const month = 30 * 24 * time.Hour
 const week = 7 * 24 * time.Hour
 
 type VelocityProcessor struct {
 	Periods []VelocityPeriod
+
+	options velocityOptions
+}
+
+// NewVelocityValidatorWithKey creates a VelocityProcessor that groups transactions by key instead of UserID
+func NewVelocityValidatorWithKey(periods []VelocityPeriod, key func(Transaction) uuid.UUID, opts ...VelocityOption) VelocityProcessor {
+	return NewVelocityValidator(periods, append(opts, WithGroupingKey(key))...)
 }
 
 // NewVelocityValidator creates a new VelocityProcessor with common time periods
-func NewVelocityValidator(periods []VelocityPeriod) VelocityProcessor {
+func NewVelocityValidator(periods []VelocityPeriod, opts ...VelocityOption) VelocityProcessor {
 	return VelocityProcessor{
 		Periods: periods,
+		options: newVelocityOptions(opts),
 	}
 }
 
+// VelocityPeriod flags more than Threshold transactions within Duration. It is comparable,
+// so two periods with the same name, duration and threshold are equal and usable as map keys.
 type VelocityPeriod struct {
+	Name      string
 	Duration  time.Duration
 	Threshold int
 }
@@ -35,55 +51,219 @@ func NewVelocityPeriod(period time.Duration, threshold int) VelocityPeriod {
 	}
 }
 
-func (v VelocityProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
-	userTransactions := make(map[uuid.UUID][]Transaction)
-	for _, tx := range transactions {
-		userTransactions[tx.UserID] = append(userTransactions[tx.UserID], tx)
+// NewNamedVelocityPeriod creates a VelocityPeriod reported under name, e.g. "weekly-retail"
+func NewNamedVelocityPeriod(name string, period time.Duration, threshold int) VelocityPeriod {
+	return VelocityPeriod{
+		Name:      name,
+		Duration:  period,
+		Threshold: threshold,
+	}
+}
+
+// Label returns the period's Name, or one generated from duration and threshold such as "168h-3tx"
+func (p VelocityPeriod) Label() string {
+	if p.Name != "" {
+		return p.Name
+	}
+
+	return fmt.Sprintf("%s-%dtx", formatDuration(p.Duration), p.Threshold)
+}
+
+// String renders the period as e.g. "weekly-retail: >3 tx / 168h"
+func (p VelocityPeriod) String() string {
+	return fmt.Sprintf("%s: >%d tx / %s", p.Label(), p.Threshold, formatDuration(p.Duration))
+}
+
+// formatDuration drops the zero minute and second components time.Duration prints, 168h0m0s becoming 168h
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
 	}
 
+	return s
+}
+
+// GroupingKey returns the entity tx is grouped under, as set by WithGroupingKey
+func (v VelocityProcessor) GroupingKey(tx Transaction) uuid.UUID {
+	return v.options.key(tx)
+}
+
+func (v VelocityProcessor) groupsByUser() bool {
+	return v.options.groupingKey == nil
+}
+
+// Process flags users exceeding any period. It stops checking once ctx is done, between users and
+// every so many transactions of a large one, returning an empty set; ProcessContext tells such a
+// run from one flagging nobody.
+func (v VelocityProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers, _ := v.ProcessContext(ctx, transactions)
+	return flaggedUsers
+}
+
+// ProcessContext is Process but reports ordering errors in strict sorted-input mode, and ctx's error
+// when it is done before every user is checked. The returned set is empty in both cases.
+func (v VelocityProcessor) ProcessContext(ctx context.Context, transactions []Transaction) (map[uuid.UUID]struct{}, error) {
+	buf := getIndexBuffer(len(transactions))
+	defer putIndexBuffer(buf)
+
+	checker := newVelocityChecker(v.Periods, v.options)
 	flaggedUsers := make(map[uuid.UUID]struct{})
+	progress := ruleProgressFrom(ctx)
+
+	groups := groupIndices(ctx, transactions, v.options, *buf)
+	if err := ctx.Err(); err != nil {
+		return make(map[uuid.UUID]struct{}), err
+	}
 
 	// O(U * T log T)
-	for userID, txs := range userTransactions { // O(U)
-		sort.Slice(txs, func(i, j int) bool { // O(T log T)
-			return txs[i].CreatedAt.Before(txs[j].CreatedAt)
-		})
+	for _, userIndices := range groups {
+		for userID, index := range userIndices { // O(U)
+			if err := ctx.Err(); err != nil {
+				return make(map[uuid.UUID]struct{}), err
+			}
 
-		if v.hasViolatedVelocityPeriods(txs) { // O(P * T)
-			flaggedUsers[userID] = struct{}{}
+			violated, err := checker.CheckIndexed(ctx, transactions, index) // O(T log T + P * T)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return make(map[uuid.UUID]struct{}), ctxErr
+			}
+			progress.add(1, len(index))
+			if err != nil {
+				return make(map[uuid.UUID]struct{}), fmt.Errorf("%s: %w", userID, err)
+			}
+
+			if violated {
+				flaggedUsers[userID] = struct{}{}
+			}
 		}
 	}
 
+	return flaggedUsers, nil
+}
+
+// ProcessGrouped is Process over the batch the engine grouped once for every rule
+func (v VelocityProcessor) ProcessGrouped(ctx context.Context, grouped GroupedTransactions) map[uuid.UUID]struct{} {
+	flaggedUsers, _ := v.ProcessGroupedContext(ctx, grouped)
 	return flaggedUsers
 }
 
-// hasViolatedVelocityPeriods checks if any of the configured periods have velocity violations
-func (v VelocityProcessor) hasViolatedVelocityPeriods(txs []Transaction) bool {
-	for _, period := range v.Periods {
-		if v.hasViolatedVelocity(txs, period) {
-			return true
+// ProcessGroupedContext is ProcessContext over the shared grouping, reading each user's transactions
+// in CreatedAt order without copying or sorting them again. Grouping by another key than the user and
+// checking the input order in strict sorted-input mode need the batch itself, which is then processed
+// as ProcessContext does.
+func (v VelocityProcessor) ProcessGroupedContext(ctx context.Context, grouped GroupedTransactions) (map[uuid.UUID]struct{}, error) {
+	if v.options.groupingKey != nil || v.options.strictSortedInput {
+		return v.ProcessContext(ctx, slices.Clone(grouped.All()))
+	}
+
+	checker := newVelocityChecker(v.Periods, v.options)
+	flaggedUsers := make(map[uuid.UUID]struct{})
+	progress := ruleProgressFrom(ctx)
+
+	var kept []Transaction
+	for _, userID := range grouped.Users() {
+		if err := ctx.Err(); err != nil {
+			return make(map[uuid.UUID]struct{}), err
+		}
+
+		txs := grouped.SortedForUser(userID)
+		progress.add(1, len(txs))
+		if v.options.filter != nil {
+			kept = kept[:0]
+			for _, tx := range txs {
+				if v.options.keep(tx) {
+					kept = append(kept, tx)
+				}
+			}
+			txs = kept
+		}
+
+		violated, _ := checker.scanView(ctx, transactionView{batch: txs}, true) // O(P * T)
+		if err := ctx.Err(); err != nil {
+			return make(map[uuid.UUID]struct{}), err
+		}
+		if violated {
+			flaggedUsers[userID] = struct{}{}
 		}
 	}
 
-	return false
+	return flaggedUsers, nil
 }
 
-// hasViolatedVelocity uses sliding window to check if a specific period has velocity violations
-// Time complexity: O(n) where n is the number of transactions for a user
-func (v VelocityProcessor) hasViolatedVelocity(txs []Transaction, period VelocityPeriod) bool {
-	left := 0
+func (v VelocityProcessor) AlertDetails(_ context.Context, _ uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	return velocityAlertDetails(v.Periods, v.options, transactions)
+}
 
-	for right := 0; right < len(txs); right++ {
-		for left <= right && txs[right].CreatedAt.Sub(txs[left].CreatedAt) > period.Duration {
-			left++
+// velocityAlertDetails names the violated periods and returns the first violation's window as evidence
+func velocityAlertDetails(periods []VelocityPeriod, options velocityOptions, transactions []Transaction) ([]Transaction, map[string]string) {
+	txs := make([]Transaction, 0, len(transactions))
+	for _, tx := range transactions {
+		if options.keep(tx) {
+			txs = append(txs, tx)
+		}
+	}
+
+	violations, err := newVelocityChecker(periods, velocityOptions{}).CheckUserDetailed(txs)
+	if err != nil || len(violations) == 0 {
+		return txs, map[string]string{}
+	}
+
+	labels := make([]string, len(violations))
+	for i, violation := range violations {
+		labels[i] = violation.PeriodName
+	}
+
+	first := violations[0]
+	var evidence []Transaction
+	for _, tx := range txs {
+		if !tx.CreatedAt.Before(first.WindowStart) && !tx.CreatedAt.After(first.WindowEnd) {
+			evidence = append(evidence, tx)
 		}
+	}
+
+	return evidence, map[string]string{
+		"periods":      strings.Join(labels, ", "),
+		"period":       first.Period.String(),
+		"count":        strconv.Itoa(first.Count),
+		"peak_count":   strconv.Itoa(peakWindowCount(txs, first.Period.Duration)),
+		"threshold":    strconv.Itoa(first.Period.Threshold),
+		"window_start": detailTime(first.WindowStart),
+		"window_end":   detailTime(first.WindowEnd),
+	}
+}
 
-		windowSize := right - left + 1
+func (v VelocityProcessor) ExplainAlert(evidence []Transaction, details map[string]string) string {
+	return velocityExplanation(evidence, details)
+}
+
+func (v VelocityProcessor) AlertSeverity(base Severity, _ []Transaction, details map[string]string) Severity {
+	return velocitySeverity(base, details)
+}
 
-		if windowSize > period.Threshold {
-			return true
+// peakWindowCount returns the most transactions found within duration of each other, txs being sorted by CreatedAt
+func peakWindowCount(txs []Transaction, duration time.Duration) int {
+	peak, left := 0, 0
+	for right := range txs {
+		for txs[right].CreatedAt.Sub(txs[left].CreatedAt) > duration {
+			left++
 		}
+		peak = max(peak, right-left+1)
+	}
+
+	return peak
+}
+
+// velocitySeverity escalates base once when the busiest window of the first violated period holds more
+// than twice its threshold
+func velocitySeverity(base Severity, details map[string]string) Severity {
+	count, countErr := strconv.Atoi(details["peak_count"])
+	threshold, thresholdErr := strconv.Atoi(details["threshold"])
+	if countErr != nil || thresholdErr != nil || count <= 2*threshold {
+		return base
 	}
 
-	return false
+	return base.Escalate()
 }
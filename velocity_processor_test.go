@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVelocityProcessor_Process(t *testing.T) {
@@ -162,6 +163,205 @@ func TestVelocityProcessor_Process(t *testing.T) {
 	}
 }
 
+func TestVelocityProcessor_Process_GroupedByAccount(t *testing.T) {
+	baseTime := time.Now()
+	userID1 := uuid.New()
+	userID2 := uuid.New()
+	sharedAccount := uuid.New()
+	otherAccount := uuid.New()
+	periods := []VelocityPeriod{NewVelocityPeriod(week, 3)}
+
+	// Each user stays at the threshold, but the shared account sees four transactions
+	transactions := []Transaction{
+		{UserID: userID1, AccountID: sharedAccount, CreatedAt: baseTime},
+		{UserID: userID1, AccountID: sharedAccount, CreatedAt: baseTime.Add(1 * time.Hour)},
+		{UserID: userID1, AccountID: otherAccount, CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: userID2, AccountID: sharedAccount, CreatedAt: baseTime.Add(3 * time.Hour)},
+		{UserID: userID2, AccountID: sharedAccount, CreatedAt: baseTime.Add(4 * time.Hour)},
+	}
+
+	processors := map[string]RuleProcessor{
+		"sequential":  NewVelocityValidatorWithKey(periods, ByAccount),
+		"worker_pool": NewWorkerVelocityProcessor(periods, 4, WithGroupingKey(ByAccount)),
+		"fan_out_in":  NewConcurrentVelocityProcessor(periods, 4, WithGroupingKey(ByAccount)),
+	}
+
+	assert.Empty(t, NewVelocityValidator(periods).Process(context.Background(), transactions))
+
+	for name, processor := range processors {
+		t.Run(name, func(t *testing.T) {
+			flaggedAccounts := processor.Process(context.Background(), append([]Transaction(nil), transactions...))
+
+			assert.Equal(t, map[uuid.UUID]struct{}{sharedAccount: {}}, flaggedAccounts)
+		})
+	}
+}
+
+func TestVelocityProcessor_Process_ExcludeReversals(t *testing.T) {
+	baseTime := time.Now()
+	userID := uuid.New()
+	periods := []VelocityPeriod{NewVelocityPeriod(week, 3)}
+
+	transactions := []Transaction{
+		{UserID: userID, Status: StatusCompleted, CreatedAt: baseTime},
+		{UserID: userID, Status: StatusRefunded, CreatedAt: baseTime.Add(1 * time.Hour)},
+		{UserID: userID, Status: StatusCompleted, CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: userID, Status: StatusReversed, CreatedAt: baseTime.Add(3 * time.Hour)},
+		{UserID: userID, CreatedAt: baseTime.Add(4 * time.Hour)},
+	}
+
+	assert.Contains(t, NewVelocityValidator(periods).Process(context.Background(), append([]Transaction(nil), transactions...)), userID)
+
+	processors := map[string]RuleProcessor{
+		"sequential":  NewVelocityValidator(periods, WithTransactionFilter(ExcludeReversals)),
+		"worker_pool": NewWorkerVelocityProcessor(periods, 4, WithTransactionFilter(ExcludeReversals)),
+		"fan_out_in":  NewConcurrentVelocityProcessor(periods, 4, WithTransactionFilter(ExcludeReversals)),
+		"presorted":   NewConcurrentVelocityProcessor(periods, 4, WithTransactionFilter(ExcludeReversals), WithPresortedByUser()),
+		"sharded":     NewWorkerVelocityProcessor(periods, 4, WithTransactionFilter(ExcludeReversals), WithShardedGrouping(2)),
+	}
+
+	for name, processor := range processors {
+		t.Run(name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), append([]Transaction(nil), transactions...))
+
+			assert.Empty(t, flaggedUsers)
+		})
+	}
+}
+
+func TestVelocityProcessor_ProcessContext_Cancelled(t *testing.T) {
+	baseTime := time.Now()
+	userID := uuid.New()
+	transactions := []Transaction{
+		{UserID: userID, CreatedAt: baseTime},
+		{UserID: userID, CreatedAt: baseTime.Add(time.Hour)},
+	}
+	processor := NewVelocityValidator([]VelocityPeriod{NewVelocityPeriod(week, 1)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	flaggedUsers, err := processor.ProcessContext(ctx, transactions)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, flaggedUsers)
+}
+
+func TestVelocityProcessor_ProcessContext_CancelledMidRun(t *testing.T) {
+	transactions := NewTransactionGenerator(WithUserCount(4_000), WithTransactionsPerUser(FixedCount(10))).Generate().Transactions
+	periods := []VelocityPeriod{NewVelocityPeriod(24*time.Hour, 5), NewVelocityPeriod(week, 9)}
+	grouped := GroupByUser(transactions)
+
+	tests := []struct {
+		name         string
+		newProcessor func(opts ...VelocityOption) contextRuleProcessor
+	}{
+		{
+			name: "VelocityProcessor",
+			newProcessor: func(opts ...VelocityOption) contextRuleProcessor {
+				return NewVelocityValidator(periods, opts...)
+			},
+		},
+		{
+			name: "WorkerVelocityProcessor",
+			newProcessor: func(opts ...VelocityOption) contextRuleProcessor {
+				return NewWorkerVelocityProcessor(periods, 4, opts...)
+			},
+		},
+		{
+			name: "ConcurrentVelocityProcessor",
+			newProcessor: func(opts ...VelocityOption) contextRuleProcessor {
+				return NewConcurrentVelocityProcessor(periods, 4, opts...)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// run counts the users checked through the rule's progress, cancelling once the grouping
+			// key has been asked for cancelAfter transactions, never when cancelAfter is 0
+			run := func(cancelAfter int) (map[uuid.UUID]struct{}, int64, error) {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				ctx, progress := startRule(context.WithValue(ctx, progressKey{}, &progressTracker{}), grouped)
+
+				calls := 0
+				processor := tt.newProcessor(WithGroupingKey(func(tx Transaction) uuid.UUID {
+					if calls++; calls == cancelAfter {
+						cancel()
+					}
+					return tx.UserID
+				}))
+				flaggedUsers, err := processor.ProcessContext(ctx, transactions)
+
+				return flaggedUsers, progress.users.Load(), err
+			}
+
+			_, users, err := run(0)
+			require.NoError(t, err)
+			require.Equal(t, int64(len(grouped.Users())), users, "an uncancelled run checks every user")
+
+			flaggedUsers, users, err := run(len(transactions) / 2)
+			assert.ErrorIs(t, err, context.Canceled)
+			assert.Empty(t, flaggedUsers, "an aborted run flags nobody")
+			assert.Less(t, users, int64(len(grouped.Users())), "the run stopped before checking every user")
+		})
+	}
+}
+
+func TestVelocityChecker_ScanCancelledWithinUser(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// a single user whose only violation comes after the scan first checks its context
+	txs := make([]Transaction, 2*scanCancelInterval)
+	for i := range txs {
+		txs[i] = Transaction{CreatedAt: baseTime.Add(time.Duration(i) * time.Hour)}
+	}
+	txs[len(txs)-1].CreatedAt = txs[len(txs)-2].CreatedAt
+	checker := newVelocityChecker([]VelocityPeriod{NewVelocityPeriod(time.Minute, 1)}, velocityOptions{sortedInput: true})
+
+	index := make([]int32, len(txs))
+	for i := range index {
+		index[i] = int32(i)
+	}
+	violated, err := checker.CheckIndexed(context.Background(), txs, index)
+	require.NoError(t, err)
+	require.True(t, violated)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	violated, err = checker.CheckIndexed(ctx, txs, index)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, violated)
+}
+
+func TestVelocityPeriod_String(t *testing.T) {
+	tests := []struct {
+		period    VelocityPeriod
+		wantLabel string
+		wantStr   string
+	}{
+		{NewNamedVelocityPeriod("weekly-retail", week, 3), "weekly-retail", "weekly-retail: >3 tx / 168h"},
+		{NewVelocityPeriod(week, 3), "168h-3tx", "168h-3tx: >3 tx / 168h"},
+		{NewVelocityPeriod(5*minute, 30), "5m-30tx", "5m-30tx: >30 tx / 5m"},
+		{NewVelocityPeriod(90*time.Second, 2), "1m30s-2tx", "1m30s-2tx: >2 tx / 1m30s"},
+		{NewVelocityPeriod(hour+30*minute, 2), "1h30m-2tx", "1h30m-2tx: >2 tx / 1h30m"},
+		{NewVelocityPeriod(500*time.Millisecond, 1), "500ms-1tx", "500ms-1tx: >1 tx / 500ms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantStr, func(t *testing.T) {
+			assert.Equal(t, tt.wantLabel, tt.period.Label())
+			assert.Equal(t, tt.wantStr, tt.period.String())
+		})
+	}
+
+	counts := map[VelocityPeriod]int{}
+	counts[NewNamedVelocityPeriod("weekly", week, 3)]++
+	counts[NewNamedVelocityPeriod("weekly", week, 3)]++
+	counts[NewVelocityPeriod(week, 3)]++
+	assert.Equal(t, 2, counts[NewNamedVelocityPeriod("weekly", week, 3)])
+	assert.Len(t, counts, 2)
+}
+
 // Benchmark tests
 func BenchmarkVelocityProcessor_Process(b *testing.B) {
 	processor := NewVelocityValidator([]VelocityPeriod{
@@ -171,24 +371,40 @@ func BenchmarkVelocityProcessor_Process(b *testing.B) {
 	})
 
 	// Create test data
-	userCount := 1000
-	transactionsPerUser := 50
-	transactions := make([]Transaction, 0, userCount*transactionsPerUser)
-
-	baseTime := time.Now()
-	for i := 0; i < userCount; i++ {
-		userID := uuid.New()
-		for j := 0; j < transactionsPerUser; j++ {
-			transactions = append(transactions, Transaction{
-				UserID:    userID,
-				Amount:    decimal.NewFromFloat(float64(j * 100)),
-				CreatedAt: baseTime.Add(time.Duration(j) * time.Hour),
-			})
-		}
-	}
+	transactions := NewTransactionGenerator(WithUserCount(1000), WithTransactionsPerUser(FixedCount(50))).Generate().Transactions
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		processor.Process(context.Background(), transactions)
 	}
 }
+
+func BenchmarkVelocityProcessor_Process_SortedInput(b *testing.B) {
+	periods := []VelocityPeriod{
+		NewVelocityPeriod(week, 5),
+		NewVelocityPeriod(month, 20),
+		NewVelocityPeriod(year, 100),
+	}
+
+	// Create test data, already ordered by CreatedAt
+	transactions := NewTransactionGenerator(WithUserCount(1000), WithTransactionsPerUser(FixedCount(50)), WithTimeOrder()).Generate().Transactions
+
+	b.Run("Sort", func(b *testing.B) {
+		processor := NewVelocityValidator(periods)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			processor.Process(context.Background(), transactions)
+		}
+	})
+
+	b.Run("SortedInput", func(b *testing.B) {
+		processor := NewVelocityValidator(periods, WithSortedInput())
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			processor.Process(context.Background(), transactions)
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const velocitySnapshotVersion = 1
+
+// ErrSnapshotHorizonTooShort is returned by Restore when the snapshot was taken with periods
+// retaining less history than the processor's current periods need
+var ErrSnapshotHorizonTooShort = errors.New("snapshot retains less history than the configured periods need")
+
+type velocitySnapshot struct {
+	Version int                      `json:"version"`
+	Periods []velocitySnapshotPeriod `json:"periods"`
+	Users   []velocitySnapshotUser   `json:"users"`
+}
+
+type velocitySnapshotPeriod struct {
+	Name      string `json:"name,omitempty"`
+	Duration  string `json:"duration"`
+	Threshold int    `json:"threshold"`
+}
+
+type velocitySnapshotUser struct {
+	UserID       uuid.UUID                     `json:"user_id"`
+	Transactions []velocitySnapshotTransaction `json:"transactions"`
+}
+
+type velocitySnapshotTransaction struct {
+	Amount    string `json:"amount"`
+	CreatedAt string `json:"created_at"`
+}
+
+// ErrSnapshotUnsupported is returned by Snapshot when the WindowStore cannot enumerate its users
+var ErrSnapshotUnsupported = errors.New("window store does not support snapshots")
+
+// Snapshot serializes the retained per-user transactions to JSON so a restarted processor can
+// resume without missing windows that straddle the restart
+func (v *StatefulVelocityProcessor) Snapshot() ([]byte, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	lister, ok := v.store.(windowStoreLister)
+	if !ok {
+		return nil, ErrSnapshotUnsupported
+	}
+
+	ctx := context.Background()
+	users, err := lister.Users(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list window users: %w", err)
+	}
+
+	snapshot := velocitySnapshot{
+		Version: velocitySnapshotVersion,
+		Periods: make([]velocitySnapshotPeriod, 0, len(v.Periods)),
+		Users:   make([]velocitySnapshotUser, 0, len(users)),
+	}
+
+	for _, period := range v.Periods {
+		snapshot.Periods = append(snapshot.Periods, velocitySnapshotPeriod{
+			Name:      period.Name,
+			Duration:  period.Duration.String(),
+			Threshold: period.Threshold,
+		})
+	}
+
+	for _, userID := range users {
+		entries, err := v.store.Range(ctx, userID, time.Time{}, time.Time{})
+		if err != nil {
+			return nil, fmt.Errorf("range window of user %s: %w", userID, err)
+		}
+
+		user := velocitySnapshotUser{
+			UserID:       userID,
+			Transactions: make([]velocitySnapshotTransaction, 0, len(entries)),
+		}
+		for _, entry := range entries {
+			user.Transactions = append(user.Transactions, velocitySnapshotTransaction{
+				Amount:    entry.Amount.String(),
+				CreatedAt: entry.CreatedAt.Format(time.RFC3339Nano),
+			})
+		}
+		snapshot.Users = append(snapshot.Users, user)
+	}
+
+	// Stable output so identical state always produces identical snapshots
+	slices.SortFunc(snapshot.Users, func(a, b velocitySnapshotUser) int {
+		return bytes.Compare(a.UserID[:], b.UserID[:])
+	})
+
+	return json.Marshal(snapshot)
+}
+
+// Restore replaces the retained state with a snapshot and re-evaluates which users are flagged.
+// The current state is left untouched when the snapshot is rejected.
+func (v *StatefulVelocityProcessor) Restore(data []byte) error {
+	var snapshot velocitySnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return fmt.Errorf("decode velocity snapshot: %w", err)
+	}
+
+	if snapshot.Version < 1 || snapshot.Version > velocitySnapshotVersion {
+		return fmt.Errorf("unsupported velocity snapshot version %d", snapshot.Version)
+	}
+
+	snapshotPeriods := make([]VelocityPeriod, 0, len(snapshot.Periods))
+	for i, period := range snapshot.Periods {
+		duration, err := time.ParseDuration(period.Duration)
+		if err != nil {
+			return fmt.Errorf("velocity snapshot periods[%d]: %w", i, err)
+		}
+		snapshotPeriods = append(snapshotPeriods, NewNamedVelocityPeriod(period.Name, duration, period.Threshold))
+	}
+
+	retained := make(map[uuid.UUID][]Transaction, len(snapshot.Users))
+	for _, user := range snapshot.Users {
+		for i, stx := range user.Transactions {
+			amount, err := decimal.NewFromString(stx.Amount)
+			if err != nil {
+				return fmt.Errorf("velocity snapshot user %s transaction %d amount: %w", user.UserID, i, err)
+			}
+			createdAt, err := time.Parse(time.RFC3339Nano, stx.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("velocity snapshot user %s transaction %d created_at: %w", user.UserID, i, err)
+			}
+			retained[user.UserID] = append(retained[user.UserID], Transaction{UserID: user.UserID, Amount: amount, CreatedAt: createdAt})
+		}
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	retention := horizon(v.Periods)
+	if horizon(snapshotPeriods) < retention {
+		return fmt.Errorf("snapshot horizon %s, periods need %s: %w",
+			formatDuration(horizon(snapshotPeriods)), formatDuration(retention), ErrSnapshotHorizonTooShort)
+	}
+
+	ctx := context.Background()
+	if lister, ok := v.store.(windowStoreLister); ok {
+		users, err := lister.Users(ctx)
+		if err != nil {
+			return fmt.Errorf("list window users: %w", err)
+		}
+		for _, userID := range users {
+			if err := clearWindow(ctx, v.store, userID); err != nil {
+				return fmt.Errorf("clear window of user %s: %w", userID, err)
+			}
+		}
+	}
+
+	checker := newVelocityChecker(v.Periods, velocityOptions{})
+	flagged := make(map[uuid.UUID]struct{})
+	for userID, txs := range retained {
+		if violated, _ := checker.CheckUser(txs); violated {
+			flagged[userID] = struct{}{}
+		}
+
+		cutoff := txs[len(txs)-1].CreatedAt.Add(-retention)
+		entries := make([]WindowEntry, 0, len(txs))
+		for _, tx := range txs {
+			if !tx.CreatedAt.Before(cutoff) {
+				entries = append(entries, WindowEntry{CreatedAt: tx.CreatedAt, Amount: tx.Amount})
+			}
+		}
+		if err := v.store.Append(ctx, userID, entries); err != nil {
+			return fmt.Errorf("restore window of user %s: %w", userID, err)
+		}
+	}
+
+	v.flagged = flagged
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+)
+
+// MatchMode selects how transaction names are compared to watchlist entries
+type MatchMode int
+
+const (
+	// MatchExact compares names byte for byte
+	MatchExact MatchMode = iota
+	// MatchNormalized ignores case, diacritics, punctuation and spacing
+	MatchNormalized
+	// MatchFuzzy matches normalized names within MaxDistance Levenshtein edits
+	MatchFuzzy
+)
+
+// WatchlistMatch records which watchlist entry a transaction name matched
+type WatchlistMatch struct {
+	Entry       string
+	Name        string
+	Distance    int
+	Transaction Transaction
+}
+
+// WatchlistProcessor screens UserName and CounterpartyName against a sanctions-style watchlist
+type WatchlistProcessor struct {
+	Entries     []string
+	Mode        MatchMode
+	MaxDistance int
+
+	index watchlistIndex
+}
+
+// watchlistIndex maps a comparison key to the entries producing it; fuzzy lookups also bucket keys
+// by rune length so only keys within MaxDistance of a name's length are compared
+type watchlistIndex struct {
+	byKey    map[string][]string
+	byLength map[int][]string
+}
+
+func NewWatchlistProcessor(entries []string, mode MatchMode, maxDistance int) WatchlistProcessor {
+	w := WatchlistProcessor{
+		Entries:     entries,
+		Mode:        mode,
+		MaxDistance: maxDistance,
+	}
+	w.index = w.buildIndex()
+
+	return w
+}
+
+func (w WatchlistProcessor) key(name string) string {
+	if w.Mode == MatchExact {
+		return name
+	}
+
+	return normalizeName(name)
+}
+
+func (w WatchlistProcessor) buildIndex() watchlistIndex {
+	index := watchlistIndex{
+		byKey:    make(map[string][]string, len(w.Entries)),
+		byLength: make(map[int][]string),
+	}
+
+	for _, entry := range w.Entries {
+		key := w.key(entry)
+		if key == "" {
+			continue
+		}
+		if _, exists := index.byKey[key]; !exists && w.Mode == MatchFuzzy {
+			length := len([]rune(key))
+			index.byLength[length] = append(index.byLength[length], key)
+		}
+		index.byKey[key] = append(index.byKey[key], entry)
+	}
+
+	return index
+}
+
+func (w WatchlistProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for userID := range w.Matches(ctx, transactions) {
+		flaggedUsers[userID] = struct{}{}
+	}
+
+	return flaggedUsers
+}
+
+// Matches returns every watchlist match per user. Empty names are skipped.
+func (w WatchlistProcessor) Matches(_ context.Context, transactions []Transaction) map[uuid.UUID][]WatchlistMatch {
+	index := w.index
+	if index.byKey == nil {
+		index = w.buildIndex()
+	}
+
+	matches := make(map[uuid.UUID][]WatchlistMatch)
+	for _, tx := range transactions {
+		for _, name := range []string{tx.UserName, tx.CounterpartyName} {
+			key := w.key(name)
+			if key == "" {
+				continue
+			}
+
+			for _, match := range w.lookup(index, key) {
+				match.Name = name
+				match.Transaction = tx
+				matches[tx.UserID] = append(matches[tx.UserID], match)
+			}
+		}
+	}
+
+	return matches
+}
+
+func (w WatchlistProcessor) lookup(index watchlistIndex, key string) []WatchlistMatch {
+	var matches []WatchlistMatch
+	for _, entry := range index.byKey[key] {
+		matches = append(matches, WatchlistMatch{Entry: entry})
+	}
+	if w.Mode != MatchFuzzy || len(matches) > 0 {
+		return matches
+	}
+
+	length := len([]rune(key))
+	for l := length - w.MaxDistance; l <= length+w.MaxDistance; l++ {
+		for _, candidate := range index.byLength[l] {
+			if distance := levenshtein(key, candidate); distance <= w.MaxDistance {
+				for _, entry := range index.byKey[candidate] {
+					matches = append(matches, WatchlistMatch{Entry: entry, Distance: distance})
+				}
+			}
+		}
+	}
+
+	return matches
+}
+
+// diacritics folds common accented Latin letters to their ASCII base
+var diacritics = strings.NewReplacer(
+	"à", "a", "á", "a", "â", "a", "ã", "a", "ä", "a", "å", "a", "æ", "ae",
+	"ç", "c", "č", "c", "ć", "c",
+	"è", "e", "é", "e", "ê", "e", "ë", "e", "ě", "e",
+	"ì", "i", "í", "i", "î", "i", "ï", "i",
+	"ñ", "n", "ń", "n", "ň", "n",
+	"ò", "o", "ó", "o", "ô", "o", "õ", "o", "ö", "o", "ø", "o", "œ", "oe",
+	"ù", "u", "ú", "u", "û", "u", "ü", "u", "ů", "u",
+	"ý", "y", "ÿ", "y",
+	"ß", "ss", "š", "s", "ś", "s", "ž", "z", "ź", "z", "ż", "z", "ł", "l", "ř", "r", "đ", "d",
+)
+
+// normalizeName lowercases, folds diacritics and reduces punctuation and spacing to single spaces
+func normalizeName(name string) string {
+	folded := diacritics.Replace(strings.ToLower(name))
+
+	return strings.Join(strings.FieldsFunc(folded, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// levenshtein returns the number of rune insertions, deletions and substitutions turning a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func (w WatchlistProcessor) AlertDetails(ctx context.Context, userID uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	var evidence []Transaction
+	var entries []string
+	for _, match := range w.Matches(ctx, transactions)[userID] {
+		evidence = append(evidence, match.Transaction)
+		entries = append(entries, match.Entry)
+	}
+	slices.Sort(entries)
+
+	return evidence, map[string]string{"entries": strings.Join(slices.Compact(entries), ", ")}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchlistProcessor_Process(t *testing.T) {
+	userID := uuid.New()
+	watchlist := []string{"John Doe", "José Müller", "Jean-Pierre Dupont"}
+
+	tests := []struct {
+		name        string
+		mode        MatchMode
+		tx          Transaction
+		wantEntries []string
+	}{
+		{
+			name:        "exact counterparty",
+			mode:        MatchExact,
+			tx:          Transaction{UserID: userID, CounterpartyName: "John Doe"},
+			wantEntries: []string{"John Doe"},
+		},
+		{
+			name: "exact mode is case sensitive",
+			mode: MatchExact,
+			tx:   Transaction{UserID: userID, CounterpartyName: "john doe"},
+		},
+		{
+			name:        "normalized case and diacritics",
+			mode:        MatchNormalized,
+			tx:          Transaction{UserID: userID, CounterpartyName: "JOSE MULLER"},
+			wantEntries: []string{"José Müller"},
+		},
+		{
+			name:        "normalized punctuation and spacing",
+			mode:        MatchNormalized,
+			tx:          Transaction{UserID: userID, UserName: "  jean pierre   DUPONT "},
+			wantEntries: []string{"Jean-Pierre Dupont"},
+		},
+		{
+			name: "normalized mode does not tolerate typos",
+			mode: MatchNormalized,
+			tx:   Transaction{UserID: userID, CounterpartyName: "Jon Doe"},
+		},
+		{
+			name:        "fuzzy at distance 1",
+			mode:        MatchFuzzy,
+			tx:          Transaction{UserID: userID, CounterpartyName: "Jon Doe"},
+			wantEntries: []string{"John Doe"},
+		},
+		{
+			name: "fuzzy beyond the maximum distance",
+			mode: MatchFuzzy,
+			tx:   Transaction{UserID: userID, CounterpartyName: "Jan Dee"},
+		},
+		{
+			name: "empty names are skipped",
+			mode: MatchFuzzy,
+			tx:   Transaction{UserID: userID, CounterpartyName: " - "},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewWatchlistProcessor(watchlist, tt.mode, 1)
+
+			matches := processor.Matches(context.Background(), []Transaction{tt.tx})
+			var entries []string
+			for _, match := range matches[userID] {
+				entries = append(entries, match.Entry)
+			}
+			assert.Equal(t, tt.wantEntries, entries)
+
+			flaggedUsers := processor.Process(context.Background(), []Transaction{tt.tx})
+			assert.Equal(t, len(tt.wantEntries) > 0, len(flaggedUsers) == 1)
+		})
+	}
+}
+
+func TestWatchlistProcessor_LargeWatchlist(t *testing.T) {
+	watchlist := make([]string, 200000)
+	for i := range watchlist {
+		watchlist[i] = fmt.Sprintf("Sanctioned Person %d", i)
+	}
+
+	transactions := make([]Transaction, 50000)
+	for i := range transactions {
+		transactions[i] = Transaction{UserID: uuid.New(), CounterpartyName: fmt.Sprintf("Customer %d", i)}
+	}
+	flaggedUser := uuid.New()
+	transactions = append(transactions, Transaction{UserID: flaggedUser, CounterpartyName: "SANCTIONED person 199999"})
+
+	// Indexed lookups keep this at O(names + transactions) rather than 10^10 comparisons
+	processor := NewWatchlistProcessor(watchlist, MatchNormalized, 0)
+	matches := processor.Matches(context.Background(), transactions)
+
+	require.Len(t, matches, 1)
+	assert.Equal(t, "Sanctioned Person 199999", matches[flaggedUser][0].Entry)
+	assert.Equal(t, "SANCTIONED person 199999", matches[flaggedUser][0].Name)
+}
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("john doe", "john doe"))
+	assert.Equal(t, 1, levenshtein("jon doe", "john doe"))
+	assert.Equal(t, 2, levenshtein("jan dee", "jon doe"))
+	assert.Equal(t, 3, levenshtein("", "abc"))
+	assert.Equal(t, 1, levenshtein("müller", "muller"))
+}
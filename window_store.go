@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// WindowEntry is the part of a transaction the stateful velocity processor retains
+type WindowEntry struct {
+	CreatedAt time.Time
+	Amount    decimal.Decimal
+}
+
+// WindowStore holds retained velocity window entries per user. Implementations backed by an
+// external service (e.g. Redis sorted sets) let the retained state outgrow a single process.
+type WindowStore interface {
+	// Append adds entries to a user's window
+	Append(ctx context.Context, userID uuid.UUID, entries []WindowEntry) error
+	// Range returns a user's entries created within [from, to], ordered by CreatedAt. A zero to
+	// leaves the range unbounded above.
+	Range(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]WindowEntry, error)
+	// Trim removes a user's entries created before the given time
+	Trim(ctx context.Context, userID uuid.UUID, before time.Time) error
+}
+
+// windowStoreLister is implemented by stores that can enumerate their users, which Snapshot requires
+type windowStoreLister interface {
+	Users(ctx context.Context) ([]uuid.UUID, error)
+}
+
+// InMemoryWindowStore is the default WindowStore, keeping every user's entries in a map
+type InMemoryWindowStore struct {
+	mu      sync.RWMutex
+	entries map[uuid.UUID][]WindowEntry
+}
+
+func NewInMemoryWindowStore() *InMemoryWindowStore {
+	return &InMemoryWindowStore{
+		entries: make(map[uuid.UUID][]WindowEntry),
+	}
+}
+
+func (s *InMemoryWindowStore) Append(_ context.Context, userID uuid.UUID, entries []WindowEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	merged := append(s.entries[userID], entries...)
+	slices.SortStableFunc(merged, func(a, b WindowEntry) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	s.entries[userID] = merged
+
+	return nil
+}
+
+func (s *InMemoryWindowStore) Range(_ context.Context, userID uuid.UUID, from, to time.Time) ([]WindowEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []WindowEntry
+	for _, entry := range s.entries[userID] {
+		if !entry.CreatedAt.Before(from) && (to.IsZero() || !entry.CreatedAt.After(to)) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+func (s *InMemoryWindowStore) Trim(_ context.Context, userID uuid.UUID, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.entries[userID]
+	start := 0
+	for start < len(entries) && entries[start].CreatedAt.Before(before) {
+		start++
+	}
+
+	if start == len(entries) {
+		delete(s.entries, userID)
+		return nil
+	}
+	s.entries[userID] = slices.Clone(entries[start:])
+
+	return nil
+}
+
+// clearWindow removes every entry of a user, trimming just past the latest one
+func clearWindow(ctx context.Context, store WindowStore, userID uuid.UUID) error {
+	entries, err := store.Range(ctx, userID, time.Time{}, time.Time{})
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	return store.Trim(ctx, userID, entries[len(entries)-1].CreatedAt.Add(time.Nanosecond))
+}
+
+func (s *InMemoryWindowStore) Users(_ context.Context) ([]uuid.UUID, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]uuid.UUID, 0, len(s.entries))
+	for userID := range s.entries {
+		users = append(users, userID)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errStoreUnavailable = errors.New("store unavailable")
+
+// fakeWindowStore wraps an InMemoryWindowStore behind the bare WindowStore interface,
+// with injectable latency and per-user failures
+type fakeWindowStore struct {
+	inner   *InMemoryWindowStore
+	latency time.Duration
+
+	mu        sync.Mutex
+	failUsers map[uuid.UUID]struct{}
+	calls     map[string]int
+}
+
+func newFakeWindowStore(latency time.Duration) *fakeWindowStore {
+	return &fakeWindowStore{
+		inner:     NewInMemoryWindowStore(),
+		latency:   latency,
+		failUsers: make(map[uuid.UUID]struct{}),
+		calls:     make(map[string]int),
+	}
+}
+
+func (f *fakeWindowStore) call(method string, userID uuid.UUID) error {
+	time.Sleep(f.latency)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls[method]++
+	if _, fail := f.failUsers[userID]; fail {
+		return errStoreUnavailable
+	}
+
+	return nil
+}
+
+func (f *fakeWindowStore) Append(ctx context.Context, userID uuid.UUID, entries []WindowEntry) error {
+	if err := f.call("Append", userID); err != nil {
+		return err
+	}
+
+	return f.inner.Append(ctx, userID, entries)
+}
+
+func (f *fakeWindowStore) Range(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]WindowEntry, error) {
+	if err := f.call("Range", userID); err != nil {
+		return nil, err
+	}
+
+	return f.inner.Range(ctx, userID, from, to)
+}
+
+func (f *fakeWindowStore) Trim(ctx context.Context, userID uuid.UUID, before time.Time) error {
+	if err := f.call("Trim", userID); err != nil {
+		return err
+	}
+
+	return f.inner.Trim(ctx, userID, before)
+}
+
+func TestInMemoryWindowStore(t *testing.T) {
+	ctx := context.Background()
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+	store := NewInMemoryWindowStore()
+
+	require.NoError(t, store.Append(ctx, userID, []WindowEntry{
+		{CreatedAt: baseTime.Add(2 * time.Hour), Amount: decimal.NewFromInt(3)},
+		{CreatedAt: baseTime, Amount: decimal.NewFromInt(1)},
+	}))
+	require.NoError(t, store.Append(ctx, userID, []WindowEntry{
+		{CreatedAt: baseTime.Add(time.Hour), Amount: decimal.NewFromInt(2)},
+	}))
+
+	entries, err := store.Range(ctx, userID, baseTime.Add(time.Hour), baseTime.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Len(t, entries, 2)
+	assert.True(t, entries[0].Amount.Equal(decimal.NewFromInt(2)))
+
+	require.NoError(t, store.Trim(ctx, userID, baseTime.Add(90*time.Minute)))
+	entries, err = store.Range(ctx, userID, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// a zero upper bound is unbounded, reaching past 2038
+	late := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Append(ctx, userID, []WindowEntry{{CreatedAt: late, Amount: decimal.NewFromInt(4)}}))
+	entries, err = store.Range(ctx, userID, time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, late, entries[1].CreatedAt)
+
+	require.NoError(t, clearWindow(ctx, store, userID))
+	users, err := store.Users(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func TestStatefulVelocityProcessor_WindowStore(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	periods := []VelocityPeriod{NewVelocityPeriod(24*time.Hour, 3), NewVelocityPeriod(week, 8)}
+
+	users := make([]uuid.UUID, 30)
+	for i := range users {
+		users[i] = uuid.New()
+	}
+
+	// Several batches of increasing time so windows straddle batch boundaries
+	batches := make([][]Transaction, 5)
+	for b := range batches {
+		for i := 0; i < 200; i++ {
+			batches[b] = append(batches[b], Transaction{
+				UserID:    users[rng.Intn(len(users))],
+				Amount:    decimal.NewFromInt(int64(rng.Intn(1000))),
+				CreatedAt: baseTime.Add(time.Duration(b)*week + time.Duration(rng.Int63n(int64(week)))),
+			})
+		}
+	}
+
+	inMemory := NewStatefulVelocityProcessor(periods)
+	fake := newFakeWindowStore(time.Microsecond)
+	external := NewStatefulVelocityProcessorWithStore(periods, fake)
+
+	for _, batch := range batches {
+		want := inMemory.Process(context.Background(), append([]Transaction(nil), batch...))
+		got, err := external.ProcessContext(context.Background(), append([]Transaction(nil), batch...))
+
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+
+		// Store calls are batched: one of each per user present in the batch
+		userCount := len(groupTransactions(batch, velocityOptions{}, nil)[0])
+		assert.Equal(t, userCount, fake.calls["Range"])
+		assert.Equal(t, userCount, fake.calls["Append"])
+		assert.Equal(t, userCount, fake.calls["Trim"])
+		fake.calls = make(map[string]int)
+	}
+	assert.Equal(t, inMemory.Flagged(), external.Flagged())
+}
+
+func TestStatefulVelocityProcessor_WindowStore_Errors(t *testing.T) {
+	baseTime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	healthyUser := uuid.New()
+	failingUser := uuid.New()
+
+	fake := newFakeWindowStore(0)
+	fake.failUsers[failingUser] = struct{}{}
+	processor := NewStatefulVelocityProcessorWithStore([]VelocityPeriod{NewVelocityPeriod(week, 1)}, fake)
+
+	var transactions []Transaction
+	for _, userID := range []uuid.UUID{healthyUser, failingUser} {
+		transactions = append(transactions,
+			Transaction{UserID: userID, CreatedAt: baseTime},
+			Transaction{UserID: userID, CreatedAt: baseTime.Add(time.Hour)},
+		)
+	}
+
+	flaggedUsers, err := processor.ProcessContext(context.Background(), transactions)
+
+	assert.Equal(t, map[uuid.UUID]struct{}{healthyUser: {}}, flaggedUsers)
+	var userErrors UserErrors
+	require.ErrorAs(t, err, &userErrors)
+	assert.Len(t, userErrors, 1)
+	assert.ErrorIs(t, userErrors[failingUser], errStoreUnavailable)
+
+	_, err = processor.Snapshot()
+	assert.ErrorIs(t, err, ErrSnapshotUnsupported)
+}
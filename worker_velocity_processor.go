@@ -7,141 +7,334 @@
 package main
 
 import (
+	"cmp"
 	"context"
-	"sort"
+	"fmt"
+	"runtime"
+	"slices"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/uuid"
 )
 
-// WorkerVelocityProcessor uses a worker pool pattern for concurrent processing
+// WorkerVelocityProcessor uses a worker pool pattern for concurrent processing. Its workers are started
+// on the first Process call and then serve every later call, including concurrent ones, until Close;
+// copies of the processor share them.
 type WorkerVelocityProcessor struct {
 	Periods     []VelocityPeriod
 	WorkerCount int
+
+	options velocityOptions
+	pool    *velocityWorkerPool
+	// jobTransactions is the size jobs of several users aim for, derived from the batch when zero
+	jobTransactions int
 }
 
 // NewWorkerVelocityProcessor creates a new worker pool processor
-func NewWorkerVelocityProcessor(periods []VelocityPeriod, workerCount int) WorkerVelocityProcessor {
+func NewWorkerVelocityProcessor(periods []VelocityPeriod, workerCount int, opts ...VelocityOption) WorkerVelocityProcessor {
 	if workerCount <= 0 {
 		workerCount = 4 // Default to 4 workers
 	}
 	return WorkerVelocityProcessor{
 		Periods:     periods,
 		WorkerCount: workerCount,
+		options:     newVelocityOptions(opts),
+		pool:        &velocityWorkerPool{},
 	}
 }
 
+// GroupingKey returns the entity tx is grouped under, as set by WithGroupingKey
+func (v WorkerVelocityProcessor) GroupingKey(tx Transaction) uuid.UUID {
+	return v.options.key(tx)
+}
+
+func (v WorkerVelocityProcessor) groupsByUser() bool {
+	return v.options.groupingKey == nil
+}
+
 // Process processes transactions using a worker pool pattern
 func (v WorkerVelocityProcessor) Process(ctx context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
-	// Step 1: Group transactions by user (sequential - O(N))
-	userTransactions := make(map[uuid.UUID][]Transaction)
-	for _, tx := range transactions {
-		userTransactions[tx.UserID] = append(userTransactions[tx.UserID], tx)
-	}
-
-	// Step 2: Create channels for worker communication
-	userJobs := make(chan UserJob, len(userTransactions))
-	results := make(chan UserResult, len(userTransactions))
-
-	// Step 3: Start worker pool
-	var wg sync.WaitGroup
-	for i := 0; i < v.WorkerCount; i++ {
-		wg.Add(1)
-		go v.worker(ctx, &wg, userJobs, results)
-	}
-
-	// Step 4: Send jobs to workers
-	go func() {
-		defer close(userJobs)
-		for userID, txs := range userTransactions {
-			select {
-			case userJobs <- UserJob{UserID: userID, Transactions: txs}:
-			case <-ctx.Done():
-				return
-			}
+	flaggedUsers, _ := v.ProcessContext(ctx, transactions)
+	return flaggedUsers
+}
+
+// ProcessContext is Process but reports ordering errors in strict sorted-input mode, and ctx's error
+// when it is done before every user is checked. The returned set is empty in both cases.
+func (v WorkerVelocityProcessor) ProcessContext(ctx context.Context, transactions []Transaction) (map[uuid.UUID]struct{}, error) {
+	// Step 1: Group transactions by user (sequential - O(N), or sharded across goroutines)
+	buf := getIndexBuffer(len(transactions))
+	defer putIndexBuffer(buf)
+
+	groups := groupIndices(ctx, transactions, v.options, *buf)
+	if err := ctx.Err(); err != nil {
+		return make(map[uuid.UUID]struct{}), err
+	}
+
+	batch := getVelocityBatch(ctx, newVelocityChecker(v.Periods, v.options), transactions)
+	defer putVelocityBatch(batch)
+	for _, userIndices := range groups {
+		for userID, index := range userIndices {
+			batch.users = append(batch.users, userID)
+			batch.groups = append(batch.groups, index)
 		}
-	}()
-
-	// Step 5: Collect results
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
-
-	// Step 6: Aggregate results
-	flaggedUsers := make(map[uuid.UUID]struct{})
-	for result := range results {
-		if result.HasViolation {
-			flaggedUsers[result.UserID] = struct{}{}
+	}
+	target := v.jobTransactions
+	if target <= 0 {
+		target = velocityJobTarget(len(transactions), v.WorkerCount)
+	}
+	batch.planJobs(target)
+
+	// Step 2: Hand one task per worker to the pool, each taking jobs until none are left
+	batch.pending.Add(v.WorkerCount)
+	if !v.pool.submit(batch, v.WorkerCount) {
+		// closed or built without NewWorkerVelocityProcessor: workers just for this call
+		tasks := make(chan *velocityBatch, v.WorkerCount)
+		for range v.WorkerCount {
+			tasks <- batch
+			go runVelocityWorker(tasks)
 		}
+		close(tasks)
 	}
 
-	return flaggedUsers
+	// Step 3: Wait for the workers to be done with the batch
+	batch.pending.Wait()
+	if batch.err != nil {
+		return make(map[uuid.UUID]struct{}), batch.err
+	}
+	if err := ctx.Err(); err != nil {
+		// workers stopped taking jobs, the flagged set covers only some users
+		return make(map[uuid.UUID]struct{}), err
+	}
+	if batch.flagged == nil {
+		return make(map[uuid.UUID]struct{}), nil
+	}
+
+	return batch.flagged, nil
 }
 
-// worker processes user jobs concurrently
-func (v WorkerVelocityProcessor) worker(ctx context.Context, wg *sync.WaitGroup, jobs <-chan UserJob, results chan<- UserResult) {
-	defer wg.Done()
+// Close stops the processor's workers once the calls in flight are done. Later calls still work,
+// starting workers just for themselves. Workers of a processor no longer referenced stop on their own.
+func (v WorkerVelocityProcessor) Close() {
+	if v.pool != nil {
+		v.pool.close()
+	}
+}
 
-	for job := range jobs {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			// Process the user's transactions
-			result := v.processUser(job.UserID, job.Transactions)
+// velocityWorkerPool is the set of workers a WorkerVelocityProcessor shares between its calls
+type velocityWorkerPool struct {
+	mu      sync.RWMutex
+	started bool
+	closed  bool
+	queue   *velocityQueue
+}
 
-			select {
-			case results <- result:
-			case <-ctx.Done():
-				return
-			}
-		}
+// velocityQueue feeds the workers. It is kept apart from the pool so the workers, which only
+// reference the queue, do not keep the pool reachable and the pool's cleanup can stop them.
+type velocityQueue struct {
+	tasks     chan *velocityBatch
+	closeOnce sync.Once
+}
+
+func (q *velocityQueue) close() {
+	q.closeOnce.Do(func() { close(q.tasks) })
+}
+
+// submit queues tasks tasks of batch, starting the workers on first use, and reports false when the
+// pool cannot take them
+func (p *velocityWorkerPool) submit(batch *velocityBatch, tasks int) bool {
+	if p == nil {
+		return false
+	}
+
+	p.mu.RLock()
+	if !p.started && !p.closed {
+		p.mu.RUnlock()
+		p.start(tasks)
+		p.mu.RLock()
+	}
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
 	}
+
+	for range tasks {
+		p.queue.tasks <- batch
+	}
+
+	return true
 }
 
-// processUser processes a single user's transactions (the expensive part)
-func (v WorkerVelocityProcessor) processUser(userID uuid.UUID, txs []Transaction) UserResult {
-	// Sort transactions (O(T log T))
-	sort.Slice(txs, func(i, j int) bool {
-		return txs[i].CreatedAt.Before(txs[j].CreatedAt)
-	})
+func (p *velocityWorkerPool) start(workers int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started || p.closed {
+		return
+	}
 
-	// Check velocity violations (O(P × T))
-	hasViolation := v.hasViolatedVelocityPeriods(txs)
+	p.queue = &velocityQueue{tasks: make(chan *velocityBatch, workers)}
+	for range workers {
+		go runVelocityWorker(p.queue.tasks)
+	}
+	runtime.AddCleanup(p, (*velocityQueue).close, p.queue)
+	p.started = true
+}
 
-	return UserResult{
-		UserID:       userID,
-		HasViolation: hasViolation,
+func (p *velocityWorkerPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started && !p.closed {
+		p.queue.close()
 	}
+	p.closed = true
+}
+
+// velocityBatch is one call's users, shared by the workers taking its tasks. Batches are pooled
+// and cleared before reuse, so no call sees another's transactions.
+type velocityBatch struct {
+	ctx     context.Context
+	checker velocityChecker
+	// transactions is the call's batch, groups[i] listing the positions in it of users[i]'s transactions
+	transactions []Transaction
+	users        []uuid.UUID
+	groups       [][]int32
+	// jobs cut users in runs the workers take one at a time, next being the first not taken
+	jobs    []velocityJob
+	next    atomic.Int64
+	pending sync.WaitGroup
+
+	mu      sync.Mutex
+	flagged map[uuid.UUID]struct{}
+	err     error
+}
+
+var velocityBatchPool = sync.Pool{
+	New: func() any { return new(velocityBatch) },
+}
+
+func getVelocityBatch(ctx context.Context, checker velocityChecker, transactions []Transaction) *velocityBatch {
+	batch := velocityBatchPool.Get().(*velocityBatch)
+	batch.ctx, batch.checker, batch.transactions = ctx, checker, transactions
+	return batch
 }
 
-// hasViolatedVelocityPeriods checks if any of the configured periods have velocity violations
-func (v WorkerVelocityProcessor) hasViolatedVelocityPeriods(txs []Transaction) bool {
-	for _, period := range v.Periods {
-		if v.hasViolatedVelocity(txs, period) {
-			return true
+// putVelocityBatch clears batch of everything the call put in it, then returns it to the pool.
+// The flagged set now belongs to the caller.
+func putVelocityBatch(batch *velocityBatch) {
+	clear(batch.users)
+	clear(batch.groups)
+	batch.users, batch.groups, batch.jobs = batch.users[:0], batch.groups[:0], batch.jobs[:0]
+	batch.ctx, batch.checker, batch.transactions = nil, velocityChecker{}, nil
+	batch.next.Store(0)
+	batch.flagged, batch.err = nil, nil
+	velocityBatchPool.Put(batch)
+}
+
+// velocityJob is the users [start, end) of a batch, holding transactions transactions
+type velocityJob struct {
+	start, end   int
+	transactions int
+}
+
+// maxVelocityJobTransactions caps the transactions a job of several users aims for
+const maxVelocityJobTransactions = 10_000
+
+// velocityJobsPerWorker is how many jobs per worker a batch is cut into at least, so that workers
+// done early take over the jobs of those still busy
+const velocityJobsPerWorker = 8
+
+// velocityJobTarget is the size jobs of a batch of transactions aim for: small enough to give each of
+// workers velocityJobsPerWorker jobs, and at most maxVelocityJobTransactions
+func velocityJobTarget(transactions, workers int) int {
+	return max(min(transactions/(max(workers, 1)*velocityJobsPerWorker), maxVelocityJobTransactions), 1)
+}
+
+// planJobs cuts the batch's users into jobs of about target transactions, a user holding more being
+// a job of its own, and orders them largest first so that the longest jobs do not start last
+func (b *velocityBatch) planJobs(target int) {
+	job := velocityJob{}
+	for i, index := range b.groups {
+		if job.end > job.start && job.transactions+len(index) > target {
+			b.jobs = append(b.jobs, job)
+			job = velocityJob{start: i, end: i}
 		}
+		job.end++
+		job.transactions += len(index)
 	}
-	return false
+	if job.end > job.start {
+		b.jobs = append(b.jobs, job)
+	}
+
+	slices.SortFunc(b.jobs, func(a, b velocityJob) int {
+		return cmp.Compare(b.transactions, a.transactions)
+	})
 }
 
-// hasViolatedVelocity uses sliding window to check if a specific period has velocity violations
-// Time complexity: O(n) where n is the number of transactions for a user
-func (v WorkerVelocityProcessor) hasViolatedVelocity(txs []Transaction, period VelocityPeriod) bool {
-	left := 0
+// runVelocityWorker takes tasks until tasks is closed, checking jobs of the task's batch until none are left
+func runVelocityWorker(tasks <-chan *velocityBatch) {
+	for batch := range tasks {
+		batch.work()
+	}
+}
+
+// work is one worker's share of the batch, in its own "aml.velocity_worker" span
+func (b *velocityBatch) work() {
+	defer b.pending.Done()
+
+	ctx, end := startSpan(b.ctx, "aml.velocity_worker")
+	users, err := 0, error(nil)
+	defer func() { end(map[string]any{attrUsers: users}, err) }()
+	progress := ruleProgressFrom(ctx)
 
-	for right := 0; right < len(txs); right++ {
-		for left <= right && txs[right].CreatedAt.Sub(txs[left].CreatedAt) > period.Duration {
-			left++
+	for ctx.Err() == nil {
+		j := int(b.next.Add(1)) - 1
+		if j >= len(b.jobs) {
+			return
 		}
 
-		windowSize := right - left + 1
+		for i := b.jobs[j].start; i < b.jobs[j].end && ctx.Err() == nil; i++ {
+			hasViolation, userErr := b.checker.CheckIndexed(ctx, b.transactions, b.groups[i])
+			if ctx.Err() != nil {
+				// the user was not checked through
+				return
+			}
+			users++
+			progress.add(1, len(b.groups[i]))
+			if userErr != nil {
+				userErr = fmt.Errorf("%s: %w", b.users[i], userErr)
+				if err == nil {
+					err = userErr
+				}
+			}
+			if hasViolation || userErr != nil {
+				b.record(b.users[i], hasViolation, userErr)
+			}
+		}
+	}
+}
 
-		if windowSize > period.Threshold {
-			return true
+func (b *velocityBatch) record(userID uuid.UUID, hasViolation bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	if hasViolation {
+		if b.flagged == nil {
+			b.flagged = make(map[uuid.UUID]struct{})
 		}
+		b.flagged[userID] = struct{}{}
 	}
+}
+
+func (v WorkerVelocityProcessor) AlertDetails(_ context.Context, _ uuid.UUID, transactions []Transaction) ([]Transaction, map[string]string) {
+	return velocityAlertDetails(v.Periods, v.options, transactions)
+}
+
+func (v WorkerVelocityProcessor) ExplainAlert(evidence []Transaction, details map[string]string) string {
+	return velocityExplanation(evidence, details)
+}
 
-	return false
+func (v WorkerVelocityProcessor) AlertSeverity(base Severity, _ []Transaction, details map[string]string) Severity {
+	return velocitySeverity(base, details)
 }
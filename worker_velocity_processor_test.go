@@ -3,13 +3,130 @@ package main
 import (
 	"context"
 	"fmt"
+	"runtime"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/google/uuid"
-	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func TestWorkerVelocityProcessor_Process_Concurrent(t *testing.T) {
+	period := NewVelocityPeriod(hour, 3)
+	processor := NewWorkerVelocityProcessor([]VelocityPeriod{period}, 4)
+	defer processor.Close()
+
+	// every caller has its own batch and violators, so a user leaking between calls shows up in the results
+	batches := make([]GeneratedTransactions, 8)
+	for i := range batches {
+		batches[i] = NewTransactionGenerator(WithSeed(uint64(i+1)), WithUserCount(200), WithVelocityViolators(i+1, period), WithTimeOrder()).Generate()
+	}
+
+	var wg sync.WaitGroup
+	for _, batch := range batches {
+		want := NewVelocityValidator([]VelocityPeriod{period}).Process(context.Background(), append([]Transaction(nil), batch.Transactions...))
+		for _, userID := range batch.VelocityViolators {
+			require.Contains(t, want, userID)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				got, err := processor.ProcessContext(context.Background(), batch.Transactions)
+				if !assert.NoError(t, err) || !assert.Equal(t, want, got) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWorkerVelocityProcessor_Close(t *testing.T) {
+	period := NewVelocityPeriod(24*time.Hour, 2)
+	generated := NewTransactionGenerator(WithUserCount(50), WithVelocityViolators(3, period)).Generate()
+	want := NewVelocityValidator([]VelocityPeriod{period}).Process(context.Background(), generated.Transactions)
+
+	processor := NewWorkerVelocityProcessor([]VelocityPeriod{period}, 3)
+	processor.Close()
+	processor.Close()
+	assert.Equal(t, want, processor.Process(context.Background(), generated.Transactions), "calls after Close still work")
+
+	processor = NewWorkerVelocityProcessor([]VelocityPeriod{period}, 3)
+	assert.Equal(t, want, processor.Process(context.Background(), generated.Transactions))
+	processor.Close()
+	assert.Equal(t, want, processor.Process(context.Background(), generated.Transactions))
+
+	literal := WorkerVelocityProcessor{Periods: []VelocityPeriod{period}, WorkerCount: 2}
+	assert.Equal(t, want, literal.Process(context.Background(), generated.Transactions), "a processor built without the constructor has no pool")
+	literal.Close()
+}
+
+func TestWorkerVelocityProcessor_WorkersStopWhenUnreachable(t *testing.T) {
+	transactions := NewTransactionGenerator(WithUserCount(10)).Generate().Transactions
+
+	// only the queue is kept, which the workers reference too and which must not keep the pool reachable
+	var queue *velocityQueue
+	func() {
+		processor := NewWorkerVelocityProcessor([]VelocityPeriod{NewVelocityPeriod(week, 5)}, 16)
+		processor.Process(context.Background(), transactions)
+		require.True(t, processor.pool.started)
+		queue = processor.pool.queue
+	}()
+
+	// the workers drained the queue, so a receive only succeeds once the pool's cleanup closed it
+	assert.Eventually(t, func() bool {
+		runtime.GC()
+		select {
+		case _, ok := <-queue.tasks:
+			return !ok
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestVelocityBatch_PlanJobs(t *testing.T) {
+	tests := []struct {
+		name   string
+		sizes  []int
+		target int
+		want   []velocityJob
+	}{
+		{name: "empty", target: 10},
+		{name: "small users share jobs", sizes: []int{3, 3, 3, 3, 3}, target: 7, want: []velocityJob{
+			{start: 0, end: 2, transactions: 6}, {start: 2, end: 4, transactions: 6}, {start: 4, end: 5, transactions: 3},
+		}},
+		{name: "large users are alone, largest first", sizes: []int{2, 50, 2, 2, 30}, target: 10, want: []velocityJob{
+			{start: 1, end: 2, transactions: 50}, {start: 4, end: 5, transactions: 30},
+			{start: 2, end: 4, transactions: 4}, {start: 0, end: 1, transactions: 2},
+		}},
+		{name: "target of one", sizes: []int{1, 4, 2}, target: 1, want: []velocityJob{
+			{start: 1, end: 2, transactions: 4}, {start: 2, end: 3, transactions: 2}, {start: 0, end: 1, transactions: 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := &velocityBatch{}
+			for _, size := range tt.sizes {
+				batch.groups = append(batch.groups, make([]int32, size))
+			}
+			batch.planJobs(tt.target)
+			assert.Equal(t, tt.want, batch.jobs)
+		})
+	}
+}
+
+func TestVelocityJobTarget(t *testing.T) {
+	assert.Equal(t, 1, velocityJobTarget(0, 4))
+	assert.Equal(t, 100, velocityJobTarget(3200, 4))
+	assert.Equal(t, 400, velocityJobTarget(3200, 0))
+	assert.Equal(t, maxVelocityJobTransactions, velocityJobTarget(100_000_000, 4))
+}
+
 func BenchmarkWorkerVelocityProcessor_Process(b *testing.B) {
 	processor := NewWorkerVelocityProcessor([]VelocityPeriod{
 		NewVelocityPeriod(week, 5),
@@ -18,22 +135,9 @@ func BenchmarkWorkerVelocityProcessor_Process(b *testing.B) {
 	}, 4) // Use 4 workers
 
 	// Create test data
-	userCount := 1000
-	transactionsPerUser := 50
-	transactions := make([]Transaction, 0, userCount*transactionsPerUser)
-
-	baseTime := time.Now()
-	for i := 0; i < userCount; i++ {
-		userID := uuid.New()
-		for j := 0; j < transactionsPerUser; j++ {
-			transactions = append(transactions, Transaction{
-				UserID:    userID,
-				Amount:    decimal.NewFromFloat(float64(j * 100)),
-				CreatedAt: baseTime.Add(time.Duration(j) * time.Hour),
-			})
-		}
-	}
+	transactions := NewTransactionGenerator(WithUserCount(1000), WithTransactionsPerUser(FixedCount(50))).Generate().Transactions
 
+	b.ReportAllocs()
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
 		processor.Process(context.Background(), transactions)
@@ -48,26 +152,13 @@ func BenchmarkWorkerVelocityProcessor_Process_DifferentWorkerCounts(b *testing.B
 	}
 
 	// Create test data
-	userCount := 1000
-	transactionsPerUser := 50
-	transactions := make([]Transaction, 0, userCount*transactionsPerUser)
-
-	baseTime := time.Now()
-	for i := 0; i < userCount; i++ {
-		userID := uuid.New()
-		for j := 0; j < transactionsPerUser; j++ {
-			transactions = append(transactions, Transaction{
-				UserID:    userID,
-				Amount:    decimal.NewFromFloat(float64(j * 100)),
-				CreatedAt: baseTime.Add(time.Duration(j) * time.Hour),
-			})
-		}
-	}
+	transactions := NewTransactionGenerator(WithUserCount(1000), WithTransactionsPerUser(FixedCount(50))).Generate().Transactions
 
 	workerCounts := []int{1, 2, 4, 8}
 	for _, workerCount := range workerCounts {
 		b.Run(fmt.Sprintf("Workers_%d", workerCount), func(b *testing.B) {
 			processor := NewWorkerVelocityProcessor(periods, workerCount)
+			b.ReportAllocs()
 			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
 				processor.Process(context.Background(), transactions)
@@ -75,3 +166,49 @@ func BenchmarkWorkerVelocityProcessor_Process_DifferentWorkerCounts(b *testing.B
 		})
 	}
 }
+
+// BenchmarkWorkerVelocityProcessor_Process_Sustained calls Process on small batches back to back, as
+// a service evaluating every few seconds would
+func BenchmarkWorkerVelocityProcessor_Process_Sustained(b *testing.B) {
+	periods := []VelocityPeriod{NewVelocityPeriod(24*time.Hour, 5), NewVelocityPeriod(week, 20)}
+	transactions := NewTransactionGenerator(WithUserCount(2000), WithTransactionsPerUser(FixedCount(3))).Generate().Transactions
+
+	for _, workerCount := range []int{4, 16} {
+		b.Run(fmt.Sprintf("Workers_%d", workerCount), func(b *testing.B) {
+			processor := NewWorkerVelocityProcessor(periods, workerCount)
+			b.ReportAllocs()
+			for b.Loop() {
+				processor.Process(context.Background(), transactions)
+			}
+		})
+	}
+}
+
+// BenchmarkWorkerVelocityProcessor_Process_LongTail compares jobs of one user with jobs of several
+// on many light users and a few heavy ones
+func BenchmarkWorkerVelocityProcessor_Process_LongTail(b *testing.B) {
+	periods := []VelocityPeriod{NewVelocityPeriod(24*time.Hour, 5), NewVelocityPeriod(week, 20)}
+	transactions := NewTransactionGenerator(
+		WithUserCount(50_000),
+		WithTransactionsPerUser(skewedCount(2, 2000)),
+		WithSpacing(PoissonSpacing(6*time.Hour)),
+	).Generate().Transactions
+
+	for _, bm := range []struct {
+		name            string
+		jobTransactions int
+	}{
+		{name: "OneUserPerJob", jobTransactions: 1},
+		{name: "Adaptive"},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			processor := NewWorkerVelocityProcessor(periods, 4)
+			defer processor.Close()
+			processor.jobTransactions = bm.jobTransactions
+			b.ReportAllocs()
+			for b.Loop() {
+				processor.Process(context.Background(), transactions)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// ZScoreProcessor flags users with a transaction far above their own usual amounts: more than K
+// standard deviations above the mean of their other transactions
+type ZScoreProcessor struct {
+	// MinHistory is the number of other transactions a candidate needs to be evaluated against
+	MinHistory int
+	K          float64
+	// MinAmount keeps tiny accounts from being flagged, candidates below it are never flagged
+	MinAmount decimal.Decimal
+}
+
+func NewZScoreProcessor(minHistory int, k float64, minAmount decimal.Decimal) ZScoreProcessor {
+	return ZScoreProcessor{
+		MinHistory: minHistory,
+		K:          k,
+		MinAmount:  minAmount,
+	}
+}
+
+func (z ZScoreProcessor) Process(_ context.Context, transactions []Transaction) map[uuid.UUID]struct{} {
+	flaggedUsers := make(map[uuid.UUID]struct{})
+
+	for _, userTransactions := range groupTransactions(transactions, velocityOptions{}, nil) {
+		for userID, txs := range userTransactions {
+			if len(txs)-1 < z.MinHistory {
+				continue
+			}
+
+			if z.hasOutlier(txs) {
+				flaggedUsers[userID] = struct{}{}
+			}
+		}
+	}
+
+	return flaggedUsers
+}
+
+// hasOutlier compares each transaction to the mean and population standard deviation of the others.
+// Statistics use float64; only the MinAmount floor is compared as a decimal.
+// Time complexity: O(n), the leave-one-out statistics are derived from running sums
+func (z ZScoreProcessor) hasOutlier(txs []Transaction) bool {
+	amounts := make([]float64, len(txs))
+	var sum, sumSquares float64
+	for i, tx := range txs {
+		amounts[i] = tx.Amount.InexactFloat64()
+		sum += amounts[i]
+		sumSquares += amounts[i] * amounts[i]
+	}
+
+	others := float64(len(txs) - 1)
+	for i, amount := range amounts {
+		if txs[i].Amount.LessThan(z.MinAmount) {
+			continue
+		}
+
+		mean := (sum - amount) / others
+		variance := max((sumSquares-amount*amount)/others-mean*mean, 0) // clamp float rounding
+		if amount > mean+z.K*math.Sqrt(variance) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestZScoreProcessor_Process(t *testing.T) {
+	baseTime := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	userID := uuid.New()
+
+	txs := func(amounts ...int64) []Transaction {
+		transactions := make([]Transaction, len(amounts))
+		for i, amount := range amounts {
+			transactions[i] = Transaction{UserID: userID, Amount: decimal.NewFromInt(amount), CreatedAt: baseTime.Add(time.Duration(i) * time.Hour)}
+		}
+		return transactions
+	}
+
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		wantFlagged  bool
+	}{
+		{
+			name:         "uniform spender with one 10x spike",
+			transactions: txs(900, 900, 900, 900, 900, 900, 9000),
+			wantFlagged:  true,
+		},
+		{
+			name:         "slightly varying spender with one 10x spike",
+			transactions: txs(850, 950, 900, 920, 880, 910, 9000),
+			wantFlagged:  true,
+		},
+		{
+			name:         "naturally high-variance spender",
+			transactions: txs(100, 5000, 300, 9000, 50, 7000, 2000),
+			wantFlagged:  false,
+		},
+		{
+			name:         "fewer transactions than the minimum history",
+			transactions: txs(900, 900, 900, 900, 9000),
+			wantFlagged:  false,
+		},
+		{
+			name:         "spike below the absolute minimum amount",
+			transactions: txs(10, 10, 10, 10, 10, 10, 100),
+			wantFlagged:  false,
+		},
+	}
+
+	processor := NewZScoreProcessor(5, 3, decimal.NewFromInt(1000))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flaggedUsers := processor.Process(context.Background(), tt.transactions)
+
+			_, flagged := flaggedUsers[userID]
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}